@@ -2,12 +2,29 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"brutus/audit"
+	"brutus/guard"
+	"brutus/internal/text"
+	"brutus/internal/theme"
 	"brutus/provider"
+	"brutus/session"
+	"brutus/telemetry"
 	"brutus/tools"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/term"
 )
 
 // Agent is the core of BRUTUS - it runs THE LOOP.
@@ -30,6 +47,73 @@ type Agent struct {
 	verbose      bool
 	workingDir   string
 	input        *inputReader
+	out          io.Writer
+	conversation *session.Conversation
+	logger       *slog.Logger
+	sessionID    string
+	turns        int
+	onMessage    func(turn int, msg provider.Message)
+	scanOutput   bool
+	steering     Steering
+	cache        *tools.ResultCache // nil when caching is off
+	artifacts    *tools.Artifacts   // nil when archiving is off
+	plan         *tools.Plan        // nil without tools
+	audit        *audit.Log         // nil when auditing is off
+
+	// The conversation's title and summary, kept up to date by
+	// describeTurn when onDescribe is set.
+	onDescribe func(session.Info)
+	info       session.Info
+	describeMu sync.Mutex
+	describing sync.WaitGroup
+
+	// timing covers the current or last turn.
+	timing provider.Timing
+
+	// reasoning is the model's latest thinking, for /thinking.
+	reasoning string
+
+	// pager shows long responses a screen at a time; lastPage is the
+	// latest one, for /more.
+	pager    bool
+	lastPage *pager
+
+	// verifyCommands run when the model finishes a turn that used tools;
+	// turnToolCalls and verifyRounds count for the current turn.
+	verifyCommands []string
+	turnToolCalls  int
+	verifyRounds   int
+
+	// turnTimeout bounds each turn; zero is no limit.
+	turnTimeout time.Duration
+
+	// budget is Config.ContextBudget.
+	budget int
+
+	// approval is nil when every tool call runs without asking.
+	approval *ApprovalPolicy
+
+	// maxSteps is Config.MaxSteps; turnSteps counts the current turn's
+	// rounds of tool calls.
+	maxSteps  int
+	turnSteps int
+
+	// resultLimits is Config.ResultLimits.
+	resultLimits ResultLimits
+
+	// commands are the slash commands Run understands; see RegisterCommand.
+	commands []Command
+
+	// usage totals the usage of usageResponses responses, for /cost.
+	usageMu        sync.Mutex
+	usage          provider.Usage
+	usageResponses int
+
+	// live is set by Run: responses are streamed to out as they arrive.
+	// cancelTurn stops the turn in flight, for Interrupt.
+	live       bool
+	turnMu     sync.Mutex
+	cancelTurn context.CancelCauseFunc
 }
 
 // Config holds agent configuration.
@@ -40,32 +124,233 @@ type Config struct {
 	SystemPrompt string
 	Verbose      bool
 	WorkingDir   string
+
+	// Output receives tool progress lines. Defaults to os.Stdout; headless
+	// callers point it elsewhere so only the final answer reaches stdout.
+	Output io.Writer
+
+	// Logger receives structured diagnostics (turns, inference calls, tool
+	// runs). Nil discards them.
+	Logger *slog.Logger
+
+	// SessionID tags every log record and trace span. A random one is generated if empty.
+	SessionID string
+
+	// History seeds the conversation, e.g. when resuming or forking a
+	// saved session.
+	History []provider.Message
+
+	// OnMessage is called for every message added to the conversation,
+	// with the user turn it belongs to. Used to record transcripts.
+	OnMessage func(turn int, msg provider.Message)
+
+	// MaxMessages bounds how many messages are kept in memory; older
+	// turns spill to disk. Zero means session.DefaultWindow.
+	MaxMessages int
+
+	// ScanToolOutput checks tool results for text that looks like
+	// instructions to the model and warns the user before sending them on.
+	// Results are wrapped in delimited blocks either way.
+	ScanToolOutput bool
+
+	// CacheToolResults lets repeated calls of idempotent tools (read_file,
+	// code_search) reuse the earlier result while nothing they read has
+	// changed.
+	CacheToolResults bool
+
+	// OnDescribe, if set, receives a short title for the conversation after
+	// its first turn and an updated summary after each turn, for session
+	// lists. They come from a small extra model request in the background.
+	OnDescribe func(session.Info)
+
+	// Info is the existing title and summary of a resumed conversation.
+	Info session.Info
+
+	// Pager shows responses taller than the terminal in a less-like
+	// viewer instead of printing them. It only applies to Run.
+	Pager bool
+
+	// VerificationCommands are checks, such as a build and the tests, run
+	// in WorkingDir when the model finishes a turn in which it used tools.
+	// Failures are sent back to it to fix, a few times at most, before the
+	// turn ends.
+	VerificationCommands []string
+
+	// ArtifactThreshold archives tool results larger than this many bytes
+	// under .brutus/artifacts/<session> in WorkingDir, leaving an ID and a
+	// preview in the conversation, and adds read_artifact to Tools to read
+	// them back. Zero or less keeps every result whole.
+	ArtifactThreshold int
+
+	// Audit, if set, records every tool call the agent runs in a
+	// hash-chained log, under SessionID.
+	Audit *audit.Log
+
+	// Env adds environment variables to the commands bash, run_tests,
+	// format and python_exec run, and ToolEnv, keyed by tool name, to one
+	// tool's, overriding Env. They go to this agent's tools only; the
+	// process environment is untouched.
+	Env     map[string]string
+	ToolEnv map[string]map[string]string
+
+	// TurnTimeout bounds how long one user turn may take. When it runs out
+	// the request in flight is cancelled, text the model had sent and the
+	// results of tools that finished are kept, a notice is added to the
+	// conversation, and the turn ends with ErrTurnTimeout. Responses are
+	// streamed when it is set, so a stalled answer isn't lost whole. Zero
+	// is no limit.
+	TurnTimeout time.Duration
+
+	// ContextBudget is the estimated prompt size, in tokens, above which
+	// the older turns are summarized by the model into a note that
+	// replaces them, keeping the latest turns whole. Zero uses three
+	// quarters of the model's context window when the service reports
+	// it; a negative budget turns summarizing off.
+	ContextBudget int
+
+	// Approval, if set, asks the user at the terminal before running tool
+	// calls it doesn't allow by itself, such as bash and edit_file. Nil
+	// runs every call without asking, as headless runs must.
+	Approval *ApprovalPolicy
+
+	// PlanMode holds back tools that change things until the model has
+	// written a plan with todo and the user has approved it. The plan is
+	// kept under .brutus/plans in WorkingDir either way, and shown in the
+	// banner.
+	PlanMode bool
+
+	// MaxSteps bounds how many rounds of tool calls one turn may take.
+	// Once they are used up the model's next calls are refused and it is
+	// asked to answer with what it has; if it calls tools again the turn
+	// ends with ErrStepLimit. Zero is no limit.
+	MaxSteps int
+
+	// ResultLimits cuts or summarizes large tool results before they go
+	// back to the model. The zero value keeps them whole.
+	ResultLimits ResultLimits
 }
 
+// ErrTurnTimeout ends a turn that ran past Config.TurnTimeout. The
+// conversation is left ready for the next turn.
+var ErrTurnTimeout = errors.New("turn timed out")
+
+// ErrStepLimit ends a turn that used up Config.MaxSteps and still wanted
+// to call tools.
+var ErrStepLimit = errors.New("turn used up its steps")
+
+// ErrInterrupted ends a turn stopped by Interrupt. As with a timeout, the
+// work done so far is kept and the conversation can go on.
+var ErrInterrupted = errors.New("turn interrupted")
+
 // New creates a new Agent with the given configuration.
 func New(cfg Config) *Agent {
-	return &Agent{
-		provider:     cfg.Provider,
+	out := cfg.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	sessionID := cfg.SessionID
+	if sessionID == "" {
+		sessionID = session.NewID()
+	}
+	conversation := session.NewConversation(session.SpillPath(sessionID), cfg.MaxMessages)
+	turn := 0
+	for _, msg := range cfg.History {
+		if isPrompt(msg) {
+			turn++
+		}
+		if err := conversation.Append(turn, msg); err != nil {
+			logger.Warn("conversation spill failed", "error", err)
+		}
+	}
+
+	// Adapt lets a model without native tool calling use tools anyway.
+	prov := provider.Adapt(cfg.Provider)
+	logger.Debug("provider capabilities", "provider", prov.Name(), "capabilities", fmt.Sprintf("%+v", prov.Capabilities()))
+
+	var cache *tools.ResultCache
+	if cfg.CacheToolResults {
+		cache = tools.NewResultCache(tools.DefaultCacheSize)
+	}
+
+	if cfg.Tools != nil && (len(cfg.Env) > 0 || len(cfg.ToolEnv) > 0) {
+		cfg.Tools.SetEnv(cfg.Env, cfg.ToolEnv)
+	}
+
+	var artifacts *tools.Artifacts
+	if cfg.ArtifactThreshold > 0 && cfg.Tools != nil {
+		artifacts = tools.NewArtifacts(filepath.Join(cfg.WorkingDir, tools.SessionArtifactsDir(sessionID)), cfg.ArtifactThreshold)
+		cfg.Tools.Register(tools.NewReadArtifactTool(artifacts))
+	}
+
+	var plan *tools.Plan
+	if cfg.Tools != nil {
+		plan = tools.LoadPlan(filepath.Join(cfg.WorkingDir, tools.SessionPlanPath(sessionID)))
+		cfg.Tools.Register(tools.NewTodoTool(plan))
+	}
+	systemPrompt := cfg.SystemPrompt + "\n\n" + guard.SystemPromptNote
+	if cfg.PlanMode {
+		systemPrompt += "\n\n" + PlanModeNote
+	}
+
+	a := &Agent{
+		out:          out,
+		conversation: conversation,
+		turns:        turn,
+		onMessage:    cfg.OnMessage,
+		logger:       logger.With("session", sessionID),
+		sessionID:    sessionID,
+		provider:     prov,
 		getUserInput: cfg.GetUserInput,
 		tools:        cfg.Tools,
-		systemPrompt: cfg.SystemPrompt,
+		systemPrompt: systemPrompt,
+		scanOutput:   cfg.ScanToolOutput,
 		verbose:      cfg.Verbose,
 		workingDir:   cfg.WorkingDir,
 		input:        newInputReader(),
+		cache:        cache,
+		artifacts:    artifacts,
+		plan:         plan,
+		audit:        cfg.Audit,
+		onDescribe:   cfg.OnDescribe,
+		info:         cfg.Info,
+		pager:        cfg.Pager,
+
+		verifyCommands: cfg.VerificationCommands,
+		turnTimeout:    cfg.TurnTimeout,
+		budget:         cfg.ContextBudget,
+		approval:       cfg.Approval,
+		maxSteps:       cfg.MaxSteps,
+		resultLimits:   cfg.ResultLimits,
+	}
+	a.commands = builtinCommands()
+	a.input.commands = a.commandNames
+	if cfg.PlanMode && plan != nil {
+		plan.RequireApproval(a.AskUser)
+	}
+	for _, msg := range cfg.History {
+		a.addUsage(msg.Usage)
 	}
+	return a
 }
 
 // Run starts the agent loop.
 // This is THE function to understand. Everything else supports this loop.
+//
+// Responses are printed as they stream in, when the provider streams, and
+// Interrupt stops the turn in flight and returns to the prompt.
 func (a *Agent) Run(ctx context.Context) error {
-	var conversation []provider.Message
-
 	a.printBanner()
+	a.live = a.provider.Capabilities().Streaming
+	defer func() { a.live = false }()
 
 	// THE LOOP - this runs until the user exits
 	for {
 		// Step 1: Get user input (with autocomplete for commands)
-		userInput, ok := a.input.ReadLine("\033[94mYou\033[0m: ")
+		userInput, ok := a.input.ReadLine(theme.User("You") + ": ")
 		if !ok {
 			a.log("User input stream ended")
 			break
@@ -76,7 +361,7 @@ func (a *Agent) Run(ctx context.Context) error {
 			continue
 		}
 		if userInput == "quit" || userInput == "exit" {
-			fmt.Println("\033[90mGoodbye!\033[0m")
+			fmt.Println(theme.Muted("Goodbye!"))
 			break
 		}
 
@@ -90,86 +375,649 @@ func (a *Agent) Run(ctx context.Context) error {
 
 		a.log("User: %q", userInput)
 
-		// Add user message to conversation
-		conversation = append(conversation, provider.Message{
-			Role:    "user",
-			Content: userInput,
-		})
+		// Steps 2-4 happen inside turn
+		response, err := a.interactiveTurn(ctx, userInput)
+		if errors.Is(err, ErrTurnTimeout) || errors.Is(err, ErrInterrupted) {
+			// The response is what the model managed, with a notice.
+			err = nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Println(theme.Muted("Interrupted."))
+				return nil
+			}
+			return err
+		}
+
+		// Step 5: Show text response to user
+		if response.Content != "" {
+			a.printResponse(response.Content)
+		}
+		fmt.Println()
+		a.describeTurn(userInput, response)
+	}
+
+	return nil
+}
+
+// Prompt runs a single user turn without any terminal interaction and
+// returns the assistant's final text. The conversation is kept, so repeated
+// calls continue the same session.
+func (a *Agent) Prompt(ctx context.Context, input string) (string, error) {
+	response, err := a.turn(ctx, input)
+	if errors.Is(err, ErrTurnTimeout) || errors.Is(err, ErrStepLimit) {
+		return response.Content, err
+	}
+	if err != nil {
+		return "", err
+	}
+	a.describeTurn(input, response)
+	return response.Content, nil
+}
+
+// interactiveTurn runs a turn while lines typed at the terminal are queued
+// as steering notes. A note that comes in as the turn ends starts another
+// turn rather than being dropped. Interrupt stops it.
+func (a *Agent) interactiveTurn(ctx context.Context, userInput string) (provider.Message, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	a.turnMu.Lock()
+	a.cancelTurn = cancel
+	a.turnMu.Unlock()
+	defer func() {
+		a.turnMu.Lock()
+		a.cancelTurn = nil
+		a.turnMu.Unlock()
+		cancel(nil)
+	}()
 
-		// Step 2: Send to LLM for inference
-		response, err := a.provider.Chat(ctx, a.systemPrompt, conversation, a.tools.All())
+	for {
+		stop := a.input.collectLines(func(line string) {
+			a.Steer(line)
+			fmt.Fprintf(a.out, "%s noted; it goes to the model with the next request\n", theme.Muted("[steer]"))
+		})
+		response, err := a.turn(ctx, userInput)
+		stop()
 		if err != nil {
-			return fmt.Errorf("inference failed: %w", err)
+			return response, err
 		}
 
-		// Add assistant response to conversation
-		conversation = append(conversation, response)
+		userInput = a.steering.Take()
+		if userInput == "" {
+			return response, nil
+		}
+		if response.Content != "" && !a.live {
+			fmt.Printf("%s: %s\n", theme.Assistant("BRUTUS"), response.Content)
+		}
+	}
+}
 
-		// Step 3-4: Tool loop - keep going while LLM wants to use tools
-		for len(response.ToolCalls) > 0 {
-			a.log("Processing %d tool calls", len(response.ToolCalls))
+// Interrupt stops the turn Run is in the middle of, as Ctrl+C does in
+// chat, and reports whether there was one. The request in flight is
+// cancelled and no further tools are started; what the model had said and
+// the results of finished tools are kept. Safe to call from any goroutine.
+func (a *Agent) Interrupt() bool {
+	a.turnMu.Lock()
+	defer a.turnMu.Unlock()
+	if a.cancelTurn == nil {
+		return false
+	}
+	a.cancelTurn(ErrInterrupted)
+	return true
+}
 
-			var toolResults []provider.ToolResult
+// Steer queues guidance for the running turn. It is sent to the model
+// before its next request, marked as a note from the user. Safe to call
+// from any goroutine.
+func (a *Agent) Steer(note string) {
+	a.steering.Add(note)
+}
 
-			// Execute each tool the LLM requested
-			for _, tc := range response.ToolCalls {
-				fmt.Printf("\033[96m[tool]\033[0m %s\n", tc.Name)
+// turn sends one user message and keeps going until the LLM stops asking
+// for tools, returning its final response.
+func (a *Agent) turn(ctx context.Context, userInput string) (provider.Message, error) {
+	a.turns++
+	a.timing.Reset()
+	a.turnToolCalls, a.verifyRounds, a.turnSteps = 0, 0, 0
+	logger := a.logger.With("turn", a.turns)
+	start := time.Now()
+	logger.Info("turn started", "input_chars", len(userInput))
 
-				result, toolErr := a.executeTool(tc)
+	ctx = provider.WithAffinity(ctx, a.sessionID)
+	if a.turnTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, a.turnTimeout, ErrTurnTimeout)
+		defer cancel()
+	}
+	ctx, span := telemetry.Start(ctx, "agent.turn",
+		attribute.String("brutus.session.id", a.sessionID),
+		attribute.Int("brutus.turn", a.turns))
+	response, err := a.runTurn(ctx, logger, userInput)
+	if err != nil {
+		switch cause := stopCause(ctx); cause {
+		case ErrTurnTimeout:
+			logger.Warn("turn timed out", "timeout_ms", a.turnTimeout.Milliseconds(), "error", err)
+			response = a.cutShort(response, fmt.Sprintf("[Turn stopped after %s without finishing. Work done so far is kept; ask to continue.]", a.turnTimeout))
+			err = cause
+		case ErrInterrupted:
+			logger.Info("turn interrupted", "error", err)
+			response = a.cutShort(response, "[Turn interrupted by the user. Work done so far is kept; ask to continue.]")
+			err = cause
+		}
+	}
+	telemetry.End(span, err)
+	if err != nil {
+		logger.Error("turn failed", "duration_ms", time.Since(start).Milliseconds(), "error", err)
+		return response, err
+	}
+	stats := a.timing.Stats()
+	logger.Info("turn finished", "duration_ms", time.Since(start).Milliseconds(), "messages", a.conversation.Len(),
+		"first_token_ms", stats.FirstToken.Milliseconds(), "inference_ms", stats.Inference.Milliseconds(), "tool_ms", stats.Tools.Milliseconds())
+	if a.verbose {
+		fmt.Fprintf(a.out, "%s %s\n", theme.Muted("[timing]"), stats)
+	}
+	return response, nil
+}
 
-				// Show truncated result to user
-				displayResult := result
-				if len(displayResult) > 500 {
-					displayResult = displayResult[:500] + "..."
-				}
-				fmt.Printf("\033[92m[result]\033[0m %s\n", displayResult)
+func (a *Agent) runTurn(ctx context.Context, logger *slog.Logger, userInput string) (provider.Message, error) {
+	// Add user message to conversation
+	a.addMessage(provider.Message{
+		Role:    "user",
+		Content: userInput,
+	})
+
+	// Step 2: Send to LLM for inference
+	response, err := a.infer(ctx, logger)
+	if err != nil {
+		return response, err
+	}
+
+	// Add assistant response to conversation
+	a.addMessage(response)
 
-				if toolErr != nil {
-					fmt.Printf("\033[91m[error]\033[0m %s\n", toolErr.Error())
-					result = toolErr.Error()
+	// Step 3-4: Tool loop - keep going while LLM wants to use tools
+	for {
+		response, err = a.toolLoop(ctx, logger, response)
+		if err != nil {
+			return response, err
+		}
+
+		// The model is done, but the user steered after its last request
+		// or the checks fail: give it the chance to act on that.
+		note := a.steering.Take()
+		if note == "" {
+			note = a.verify(ctx, logger)
+		}
+		if note == "" {
+			return response, nil
+		}
+		if response.Content != "" && !a.live {
+			fmt.Fprintf(a.out, "%s: %s\n", theme.Assistant("BRUTUS"), response.Content)
+		}
+		a.addMessage(provider.Message{Role: "user", Content: note})
+		response, err = a.infer(ctx, logger)
+		if err != nil {
+			return response, err
+		}
+		a.addMessage(response)
+	}
+}
+
+// toolLoop executes the tools response asks for and sends the results
+// back until the model answers without tool calls.
+func (a *Agent) toolLoop(ctx context.Context, logger *slog.Logger, response provider.Message) (provider.Message, error) {
+	var err error
+	for len(response.ToolCalls) > 0 {
+		a.log("Processing %d tool calls", len(response.ToolCalls))
+		a.turnToolCalls += len(response.ToolCalls)
+		a.turnSteps++
+		if a.maxSteps > 0 && a.turnSteps > a.maxSteps+1 {
+			logger.Warn("turn used up its steps", "max_steps", a.maxSteps)
+			return response, ErrStepLimit
+		}
+		spent := a.maxSteps > 0 && a.turnSteps > a.maxSteps
+
+		var toolResults []provider.ToolResult
+
+		// Execute each tool the LLM requested
+		for i, tc := range response.ToolCalls {
+			// Let an in-flight tool finish, but don't start another one
+			// once the caller has given up.
+			if err := ctx.Err(); err != nil {
+				if cause := stopCause(ctx); cause != nil {
+					// Keep the results so far; every call needs one for
+					// the conversation to go on.
+					skip := tools.NewError(tools.ErrTimeout, "not run: the turn timed out")
+					if cause == ErrInterrupted {
+						skip = tools.NewError(tools.ErrCancelled, "not run: the user interrupted the turn")
+					}
+					for _, skipped := range response.ToolCalls[i:] {
+						toolResults = append(toolResults, provider.ToolResult{
+							ID:      skipped.ID,
+							Content: tools.ErrorResult(skip),
+							IsError: true,
+						})
+					}
+					a.addMessage(provider.Message{Role: "user", ToolResults: toolResults})
 				}
+				return provider.Message{}, err
+			}
 
+			if spent {
 				toolResults = append(toolResults, provider.ToolResult{
 					ID:      tc.ID,
-					Content: result,
-					IsError: toolErr != nil,
+					Content: fmt.Sprintf("Not run: this turn has used all %d of its steps. Answer now with what you have found and what is left to do.", a.maxSteps),
+					IsError: true,
 				})
+				continue
 			}
 
-			// Send tool results back to LLM
-			conversation = append(conversation, provider.Message{
-				Role:        "user",
-				ToolResults: toolResults,
-			})
+			fmt.Fprintf(a.out, "%s %s\n", theme.Tool("[tool]"), tc.Name)
+
+			if BlockedByPlan(a.plan, tc.Name) {
+				logger.Info("tool held back until the plan is approved", "tool", tc.Name)
+				a.recordAudit(logger, tc, audit.Denied, "", nil)
+				toolResults = append(toolResults, provider.ToolResult{
+					ID:      tc.ID,
+					Content: PlanRequired,
+					IsError: true,
+				})
+				continue
+			}
+
+			decision := audit.Auto
+			if a.approval != nil && !a.approval.Allowed(tc.Name) {
+				decision = audit.Approved
+				if !a.approve(tc) {
+					logger.Info("tool denied", "tool", tc.Name)
+					a.recordAudit(logger, tc, audit.Denied, "", nil)
+					toolResults = append(toolResults, provider.ToolResult{
+						ID:      tc.ID,
+						Content: "Tool execution was denied by user.",
+						IsError: true,
+					})
+					continue
+				}
+			}
 
-			// Get next response (might request more tools)
-			response, err = a.provider.Chat(ctx, a.systemPrompt, conversation, a.tools.All())
-			if err != nil {
-				return fmt.Errorf("inference failed: %w", err)
+			toolStart := time.Now()
+			result, toolErr := a.executeTool(ctx, tc)
+			result, images := tools.SplitImages(result)
+			a.timing.AddTool(time.Since(toolStart))
+			a.recordAudit(logger, tc, decision, result, toolErr)
+			if toolErr != nil {
+				logger.Warn("tool failed", "tool", tc.Name, "duration_ms", time.Since(toolStart).Milliseconds(), "error", toolErr)
+			} else {
+				logger.Info("tool finished", "tool", tc.Name, "duration_ms", time.Since(toolStart).Milliseconds(), "result_bytes", len(result))
 			}
-			conversation = append(conversation, response)
+
+			// Show truncated result to user
+			fmt.Fprintf(a.out, "%s %s\n", theme.Result("[result]"), text.HeadTail(result, 500))
+
+			if toolErr != nil {
+				fmt.Fprintf(a.out, "%s %s\n", theme.Error("[error]"), toolErr.Error())
+				result = tools.ErrorResult(toolErr)
+			} else {
+				result = a.guardResult(logger, tc.Name, a.shrink(ctx, logger, tc, a.archive(logger, tc.Name, result)))
+			}
+
+			toolResults = append(toolResults, provider.ToolResult{
+				ID:      tc.ID,
+				Content: result,
+				IsError: toolErr != nil,
+				Images:  provider.ToolImages(images),
+			})
 		}
 
-		// Step 5: Show text response to user
-		if response.Content != "" {
-			fmt.Printf("\033[93mBRUTUS\033[0m: %s\n", response.Content)
+		// Send tool results back to LLM, along with any steering notes
+		// the user typed while the tools ran
+		a.addMessage(provider.Message{
+			Role:        "user",
+			Content:     a.steering.Take(),
+			ToolResults: toolResults,
+		})
+
+		// Get next response (might request more tools)
+		response, err = a.infer(ctx, logger)
+		if err != nil {
+			return response, err
 		}
-		fmt.Println()
+		a.addMessage(response)
 	}
 
+	return response, nil
+}
+
+func (a *Agent) addMessage(msg provider.Message) {
+	if err := a.conversation.Append(a.turns, msg); err != nil {
+		a.logger.Warn("conversation spill failed", "error", err)
+	}
+	if a.onMessage != nil {
+		a.onMessage(a.turns, msg)
+	}
+}
+
+// archive replaces a result too large to keep in the conversation with a
+// reference to an artifact holding it. read_artifact's own results are
+// already bounded and kept whole.
+func (a *Agent) archive(logger *slog.Logger, tool, result string) string {
+	if tool == "read_artifact" {
+		return result
+	}
+	archived, err := a.artifacts.Archive(tool, result)
+	if err != nil {
+		logger.Warn("archiving tool result failed", "tool", tool, "error", err)
+	} else if archived != result {
+		logger.Info("tool result archived", "tool", tool, "result_bytes", len(result))
+	}
+	return archived
+}
+
+// shrink applies the result limits to a tool result.
+func (a *Agent) shrink(ctx context.Context, logger *slog.Logger, tc provider.ToolCall, result string) string {
+	shrunk, err := ShrinkResult(ctx, a.provider, a.resultLimits, tc, result)
+	if err != nil {
+		logger.Warn("summarizing tool result failed", "tool", tc.Name, "error", err)
+	} else if shrunk != result {
+		logger.Info("tool result shrunk", "tool", tc.Name, "result_bytes", len(result), "sent_bytes", len(shrunk))
+		fmt.Fprintf(a.out, "%s %s result cut from %d to %d bytes\n", theme.Muted("[context]"), tc.Name, len(result), len(shrunk))
+	}
+	return shrunk
+}
+
+// guardResult wraps a tool result before it goes back to the model,
+// warning the user first if it appears to contain injected instructions.
+// Results the user wrote themselves are passed through.
+func (a *Agent) guardResult(logger *slog.Logger, tool, result string) string {
+	if t, ok := a.tools.Get(tool); ok && t.FromUser {
+		return result
+	}
+	var findings []guard.Finding
+	if a.scanOutput {
+		findings = guard.Scan(result)
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(a.out, "%s %s output contains text addressed to the model; it will be marked as untrusted:\n", theme.Warning("[warning]"), tool)
+		for _, f := range findings {
+			fmt.Fprintf(a.out, "  %s %q\n", theme.Muted(f.Rule+":"), f.Excerpt)
+		}
+		logger.Warn("possible prompt injection in tool output", "tool", tool, "findings", len(findings))
+	}
+	return guard.Wrap(tool, result, findings)
+}
+
+// AskUser prompts on the terminal for the ask_user tool. Choices are
+// numbered; the reply can be a number or free text.
+func (a *Agent) AskUser(question string, choices []string) (string, bool, error) {
+	fmt.Printf("%s: %s\n", theme.Assistant("BRUTUS asks"), question)
+	for i, choice := range choices {
+		fmt.Printf("  %s %s\n", theme.Muted(strconv.Itoa(i+1)+"."), choice)
+	}
+	answer, ok := a.input.ReadLine(theme.User("You") + ": ")
+	return answer, ok, nil
+}
+
+// isPrompt reports whether msg is a user prompt, as opposed to a message
+// carrying tool results. Each prompt starts a new turn.
+func isPrompt(msg provider.Message) bool {
+	return msg.Role == "user" && len(msg.ToolResults) == 0
+}
+
+// Close gives a title request in flight a few seconds to finish, then
+// releases the conversation's spill file and any session state the tools
+// keep, such as python_exec's interpreter.
+func (a *Agent) Close() error {
+	a.waitDescribing(5 * time.Second)
+	return errors.Join(a.conversation.Close(), a.tools.Close())
+}
+
+// maxInferenceRetries bounds how many times infer retries one request.
+const maxInferenceRetries = 2
+
+// infer sends the conversation to the model, summarizing older turns
+// first if it has outgrown the context budget. A request the model rejects
+// as too long is retried with older turns spilled, one a busy service
+// turns away is retried after the wait it asks for, and one whose tool call
+// was cut off is sent again with a note asking for the whole call; other
+// failures are returned.
+func (a *Agent) infer(ctx context.Context, logger *slog.Logger) (provider.Message, error) {
+	a.compactIfNeeded(ctx, logger)
+	for attempt := 0; ; attempt++ {
+		response, err := a.request(ctx, logger)
+		if err == nil || attempt == maxInferenceRetries || !a.prepareRetry(ctx, err) {
+			return response, err
+		}
+	}
+}
+
+// prepareRetry gets ready to retry a failed request and reports whether
+// a retry could succeed.
+func (a *Agent) prepareRetry(ctx context.Context, err error) bool {
+	if errors.Is(err, provider.ErrContextLength) {
+		n, spillErr := a.conversation.Compact()
+		if n == 0 || spillErr != nil {
+			return false
+		}
+		fmt.Fprintf(a.out, "%s conversation too long for the model; retrying without the oldest %d messages\n", theme.Warning("[context]"), n)
+		return true
+	}
+	var callErr *provider.ToolCallError
+	if errors.As(err, &callErr) {
+		fmt.Fprintf(a.out, "%s the %s call was cut off; asking the model to send it again\n", theme.Warning("[retry]"), callErr.Name)
+		a.addMessage(provider.Message{Role: "user", Content: fmt.Sprintf(
+			"Your call to %s was cut off: its arguments, %q, are not complete JSON, so it was not run. Send the whole call again.",
+			callErr.Name, text.Head(callErr.Arguments, 200))})
+		return true
+	}
+	if delay, ok := provider.RetryDelay(err); ok {
+		fmt.Fprintf(a.out, "%s service busy; retrying in %s\n", theme.Warning("[retry]"), delay.Round(time.Second))
+		select {
+		case <-time.After(delay):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return false
+}
+
+// request sends the conversation to the model. If it fails, the returned
+// message holds any text that was streamed before it did.
+func (a *Agent) request(ctx context.Context, logger *slog.Logger) (provider.Message, error) {
+	start := time.Now()
+	reqCtx, done := a.timing.Request(ctx)
+	var response provider.Message
+	var err error
+	if a.live || a.turnTimeout > 0 {
+		response, err = a.stream(reqCtx)
+	} else {
+		response, err = a.provider.Chat(reqCtx, a.systemPrompt, a.conversation.Messages(), a.tools.All())
+	}
+	done()
+	if err != nil {
+		logger.Error("inference failed", "provider", a.provider.Name(), "model", a.provider.GetModel(),
+			"duration_ms", time.Since(start).Milliseconds(), "error", err)
+		return response, fmt.Errorf("inference failed: %w", err)
+	}
+	logger.Info("inference", "provider", a.provider.Name(), "model", a.provider.GetModel(),
+		"duration_ms", time.Since(start).Milliseconds(), "tool_calls", len(response.ToolCalls), "reasoning_chars", len(response.Reasoning))
+	a.addUsage(response.Usage)
+	if !a.live {
+		a.showReasoning(response.Reasoning)
+	}
+	return response, nil
+}
+
+// stream makes the request as a stream, collecting the response. On
+// failure the text received so far is returned with the error; partial
+// tool calls are dropped. When live, the text is printed as it arrives,
+// after the model's thinking, and each tool call is announced as soon as
+// its name is known.
+func (a *Agent) stream(ctx context.Context) (provider.Message, error) {
+	deltas, err := a.provider.ChatStream(ctx, a.systemPrompt, a.conversation.Messages(), a.tools.All())
+	if err != nil {
+		return provider.Message{}, err
+	}
+	var content, reasoning strings.Builder
+	printing := false // in the middle of a line of the answer
+	thought := false  // the thinking has been shown
+	announced := map[string]bool{}
+	think := func() {
+		if a.live && !thought {
+			thought = true
+			a.showReasoning(reasoning.String())
+		}
+	}
+	endLine := func() {
+		if printing {
+			printing = false
+			fmt.Fprintln(a.out)
+		}
+	}
+	defer endLine()
+
+	for delta := range deltas {
+		reasoning.WriteString(delta.Reasoning)
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			if a.live {
+				think()
+				if !printing {
+					printing = true
+					fmt.Fprintf(a.out, "%s: ", theme.Assistant("BRUTUS"))
+				}
+				fmt.Fprint(a.out, delta.Content)
+			}
+		}
+		if tc := delta.ToolCall; tc != nil && a.live && tc.Name != "" {
+			key := tc.ID
+			if key == "" {
+				key = tc.Name
+			}
+			if !announced[key] {
+				announced[key] = true
+				think()
+				endLine()
+				fmt.Fprintf(a.out, "%s %s\n", theme.Muted("[calling]"), tc.Name)
+			}
+		}
+		if delta.Error != nil {
+			return provider.Message{Role: "assistant", Content: content.String()}, delta.Error
+		}
+		if delta.Done {
+			think()
+			return provider.Message{
+				Role:      "assistant",
+				Content:   content.String(),
+				ToolCalls: delta.ToolCalls,
+				Reasoning: reasoning.String(),
+				Usage:     delta.Usage,
+			}, nil
+		}
+	}
+	// The stream closed without finishing, as it may when cancelled.
+	err = ctx.Err()
+	if err == nil {
+		err = errors.New("response stream ended early")
+	}
+	return provider.Message{Role: "assistant", Content: content.String()}, err
+}
+
+// stopCause returns ErrTurnTimeout or ErrInterrupted if ctx was cancelled
+// by one of them, and nil otherwise.
+func stopCause(ctx context.Context) error {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, ErrTurnTimeout):
+		return ErrTurnTimeout
+	case errors.Is(cause, ErrInterrupted):
+		return ErrInterrupted
+	}
 	return nil
 }
 
+// cutShort closes a turn that was stopped before it finished: the text
+// the model had sent, if any, is kept along with notice, so the
+// conversation ends on an assistant message and the next turn can follow
+// it. When live the partial text is already on screen, so only the notice
+// is printed.
+func (a *Agent) cutShort(partial provider.Message, notice string) provider.Message {
+	msg := provider.Message{Role: "assistant", Content: notice}
+	if partial.Content != "" {
+		msg.Content = partial.Content + "\n\n" + notice
+	}
+	a.addMessage(msg)
+	if a.live {
+		fmt.Fprintln(a.out, theme.Muted(notice))
+	}
+	return msg
+}
+
+// printResponse shows the final answer of a turn, in the pager when it is
+// taller than the terminal. A streamed answer is already on screen; a long
+// one is only kept for /more.
+func (a *Agent) printResponse(content string) {
+	if a.pager && a.out == os.Stdout && term.IsTerminal(int(os.Stdin.Fd())) {
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			if p := newPager(content, width, height); !p.fits() {
+				if a.live {
+					a.lastPage = p
+					fmt.Println(theme.Muted("[/more shows this response in the pager]"))
+					return
+				}
+				if err := p.run(a.out, a.input.read, a.input.poll); err == nil {
+					a.lastPage = p
+					fmt.Printf("%s: %s\n", theme.Assistant("BRUTUS"),
+						theme.Muted(fmt.Sprintf("[%d lines, shown in the pager; /more reopens it]", len(p.lines))))
+					return
+				}
+			}
+		}
+	}
+	if !a.live {
+		fmt.Printf("%s: %s\n", theme.Assistant("BRUTUS"), content)
+	}
+}
+
+// reasoningPreview is how many lines of thinking are shown before the rest
+// is folded away.
+const reasoningPreview = 3
+
+// showReasoning prints the model's thinking dimmed, folded to its first
+// few lines unless verbose. /thinking shows the latest in full.
+func (a *Agent) showReasoning(reasoning string) {
+	reasoning = strings.TrimSpace(reasoning)
+	if reasoning == "" {
+		return
+	}
+	a.reasoning = reasoning
+	lines := strings.Split(reasoning, "\n")
+	if !a.verbose && len(lines) > reasoningPreview {
+		folded := len(lines) - reasoningPreview
+		lines = append(lines[:reasoningPreview], fmt.Sprintf("… %d more lines; /thinking shows them", folded))
+	}
+	for _, line := range lines {
+		fmt.Fprintf(a.out, "%s %s\n", theme.Muted("[thinking]"), theme.Muted(line))
+	}
+}
+
 // executeTool runs a tool and returns its result.
-func (a *Agent) executeTool(tc provider.ToolCall) (string, error) {
+func (a *Agent) executeTool(ctx context.Context, tc provider.ToolCall) (result string, err error) {
+	_, span := telemetry.Start(ctx, "tool "+tc.Name, attribute.String("brutus.tool.name", tc.Name))
+	defer func() {
+		span.SetAttributes(attribute.Int("brutus.tool.result_bytes", len(result)))
+		telemetry.End(span, err)
+	}()
+
 	tool, ok := a.tools.Get(tc.Name)
 	if !ok {
-		return "", fmt.Errorf("tool '%s' not found", tc.Name)
+		return "", tools.NewError(tools.ErrNotFound, "tool '%s' not found", tc.Name).WithDetail("tool", tc.Name)
 	}
 
 	a.log("Executing tool: %s", tc.Name)
-	result, err := tool.Function(tc.Input)
-	if err != nil {
+	result, cached, err := a.cache.Execute(ctx, tool, tc.Input)
+	span.SetAttributes(attribute.Bool("brutus.tool.cached", cached))
+	if cached {
+		a.log("Tool result from cache, length: %d", len(result))
+	} else if err != nil {
 		a.log("Tool error: %v", err)
 	} else {
 		a.log("Tool success, result length: %d", len(result))
@@ -178,46 +1026,155 @@ func (a *Agent) executeTool(tc provider.ToolCall) (string, error) {
 	return result, err
 }
 
+// recordAudit adds a tool call to the audit log, if there is one, with
+// the approval decision it ran (or didn't run) under.
+func (a *Agent) recordAudit(logger *slog.Logger, tc provider.ToolCall, decision, result string, toolErr error) {
+	if toolErr != nil {
+		result = toolErr.Error()
+	}
+	entry := audit.Entry{
+		Agent:    a.sessionID,
+		Tool:     tc.Name,
+		Input:    audit.Hash(tc.Input),
+		Error:    toolErr != nil,
+		Decision: decision,
+	}
+	if decision != audit.Denied {
+		entry.Result = audit.Hash([]byte(result))
+	}
+	err := a.audit.Record(entry)
+	if err != nil {
+		logger.Warn("audit log write failed", "tool", tc.Name, "error", err)
+	}
+}
+
 func (a *Agent) log(format string, args ...interface{}) {
 	if a.verbose {
 		log.Printf(format, args...)
 	}
 }
 
-func (a *Agent) handleCommand(ctx context.Context, cmd string) bool {
-	switch cmd {
-	case "/models":
-		if err := a.handleModelsCommand(ctx); err != nil {
-			fmt.Printf("\033[91mError: %s\033[0m\n", err)
+// handleHistoryCommand lists the places in saved sessions that mention
+// query, for resuming or replaying the right one.
+func (a *Agent) handleHistoryCommand(query string) error {
+	hits, err := session.Search(session.Dir(), query, 20)
+	if err != nil {
+		return err
+	}
+	if len(hits) == 0 {
+		fmt.Println(theme.Muted(fmt.Sprintf("No past sessions mention %q.", query)))
+		return nil
+	}
+	for _, h := range hits {
+		where := fmt.Sprintf("turn %d", h.Turn)
+		if h.Turn == 0 {
+			where = "summary"
 		}
-	case "/help":
-		a.handleHelpCommand()
-	case "/clear":
-		fmt.Print("\033[2J\033[H")
-		a.printBanner()
-	case "/exit":
-		fmt.Println("\033[90mGoodbye!\033[0m")
-		return true
-	default:
-		fmt.Printf("\033[91mUnknown command: %s\033[0m\n", cmd)
-		fmt.Println("\033[90mType /help for available commands\033[0m")
+		fmt.Printf("%s %s %s\n", theme.Command(h.SessionID), h.Title, theme.Muted(fmt.Sprintf("(%s, %s)", h.Updated.Format("2006-01-02"), where)))
+		fmt.Println("  " + h.Snippet)
 	}
-	fmt.Println()
-	return false
+	return nil
 }
 
-func (a *Agent) handleHelpCommand() {
-	fmt.Println("\033[1;36mAvailable commands:\033[0m")
-	fmt.Println("  \033[93m/models\033[0m  - Select an AI model")
-	fmt.Println("  \033[93m/clear\033[0m   - Clear the screen")
-	fmt.Println("  \033[93m/help\033[0m    - Show this help")
-	fmt.Println("  \033[93m/exit\033[0m    - Exit BRUTUS")
-	fmt.Println()
-	fmt.Println("\033[90mTip: Type / and press Tab to autocomplete\033[0m")
+// handleExportCommand writes the whole conversation, including messages
+// spilled to disk, as a session file or JSONL transcript.
+func (a *Agent) handleExportCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /export <file.json|file.jsonl>")
+	}
+
+	records, err := a.conversation.History()
+	if err != nil {
+		return err
+	}
+	s := session.New()
+	s.ID = a.sessionID
+	s.Model = a.provider.GetModel()
+	s.WorkingDir = a.workingDir
+	s.Records = records
+	if len(records) > 0 {
+		s.Created = records[0].Time
+	}
+
+	tr, err := session.OpenTranscript(args[0], s)
+	if err != nil {
+		return err
+	}
+	if err := tr.Close(); err != nil {
+		return err
+	}
+	fmt.Println(theme.Success(fmt.Sprintf("Exported %d messages to %s", len(records), args[0])))
+	return nil
+}
+
+// handleForkCommand saves the conversation so far as a new session whose
+// parent is this one, so another approach can be tried from the same point
+// with chat -resume while this chat carries on.
+func (a *Agent) handleForkCommand() error {
+	current, err := a.snapshot()
+	if err != nil {
+		return err
+	}
+	fork := current.Fork(0)
+	path := filepath.Join(session.Dir(), fork.ID+".jsonl")
+	tr, err := session.OpenTranscript(path, fork)
+	if err != nil {
+		return err
+	}
+	if err := tr.Close(); err != nil {
+		return err
+	}
+	fmt.Println(theme.Success(fmt.Sprintf("Forked at turn %d as session %s", a.turns, fork.ID)))
+	fmt.Println(theme.Muted("Continue the fork with: brutus chat -resume"))
+	return nil
+}
+
+// snapshot returns the conversation so far, including messages spilled to
+// disk, as this session. It fails if nothing has been said yet.
+func (a *Agent) snapshot() (*session.Session, error) {
+	records, err := a.conversation.History()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("nothing to save yet")
+	}
+	a.describeMu.Lock()
+	info := a.info
+	a.describeMu.Unlock()
+
+	return &session.Session{
+		ID:         a.sessionID,
+		Model:      a.provider.GetModel(),
+		WorkingDir: a.workingDir,
+		Title:      info.Title,
+		Summary:    info.Summary,
+		Records:    records,
+	}, nil
+}
+
+// handleRewindCommand drops the last N turns (default 1) so the next
+// prompt continues from before them.
+func (a *Agent) handleRewindCommand(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("usage: /rewind [N], with N a positive number of turns")
+		}
+	}
+
+	turns, err := a.conversation.Rewind(n)
+	if err != nil {
+		return err
+	}
+	a.turns = turns
+	fmt.Println(theme.Success(fmt.Sprintf("Rewound to turn %d (%d messages)", turns, a.conversation.Len())))
+	return nil
 }
 
 func (a *Agent) handleModelsCommand(ctx context.Context) error {
-	fmt.Println("\033[90mFetching available models...\033[0m")
+	fmt.Println(theme.Muted("Fetching available models..."))
 
 	models, err := a.provider.ListModels(ctx)
 	if err != nil {
@@ -225,44 +1182,61 @@ func (a *Agent) handleModelsCommand(ctx context.Context) error {
 	}
 
 	if len(models) == 0 {
-		fmt.Println("\033[93mNo models available\033[0m")
+		fmt.Println(theme.Warning("No models available"))
 		return nil
 	}
 
-	// Build display list
+	// Build display list, marking the model in use
+	current := a.provider.GetModel()
 	var items []string
 	for _, m := range models {
-		items = append(items, m.ID)
+		item := m.ID
+		if m.ID == current {
+			item += " (current)"
+		}
+		items = append(items, item)
 	}
 
 	// Show picker
-	idx, err := pickFromList("Select a model", items, 15)
+	idx, err := pickFromList("Select a model", items, 15, a.input.read)
 	if err != nil {
 		return err
 	}
 
 	if idx >= 0 {
 		a.provider.SetModel(models[idx].ID)
-		fmt.Printf("\033[92mModel set to: %s\033[0m\n\n", models[idx].ID)
+		fmt.Println(theme.Success(fmt.Sprintf("Model set to: %s", models[idx].ID)))
+		fmt.Println()
 	} else {
-		fmt.Println("\033[90mCancelled\033[0m")
+		fmt.Println(theme.Muted("Cancelled"))
 	}
 
 	return nil
 }
 
 func (a *Agent) printBanner() {
-	fmt.Println("\033[1;35m" + `
+	fmt.Println(theme.Banner(`
  ____  ____  _     _____  _     ____
 /  _ \/  __\/ \ /\/__ __\/ \ /\/ ___\
 | | //|  \/|| | ||  / \  | | |||    \
 | |_\\|    /| \_/|  | |  | \_/|\___ |
 \____/\_/\_\\____/  \_/  \____/\____/
-` + "\033[0m")
-	fmt.Println("\033[1;33mCoding Agent\033[0m")
+`))
+	fmt.Println(theme.Highlight("Coding Agent"))
 	if a.workingDir != "" {
-		fmt.Printf("\033[90mWorking in: %s\033[0m\n", a.workingDir)
+		fmt.Println(theme.Muted(fmt.Sprintf("Working in: %s", a.workingDir)))
+	}
+	if a.plan.Gated() {
+		fmt.Println(theme.Warning("Plan mode: tools that change things wait for an approved plan"))
+	}
+	if plan := a.plan.String(); plan != "" {
+		status := "not approved"
+		if a.plan.Approved() {
+			status = "approved"
+		}
+		fmt.Println(theme.Highlight(fmt.Sprintf("Plan (%s):", status)))
+		fmt.Println(plan)
 	}
-	fmt.Println("\033[90mType 'quit' or 'exit' to end session\033[0m")
+	fmt.Println(theme.Muted("Type 'quit' or 'exit' to end session"))
 	fmt.Println()
 }
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+// cutCallProvider ends its first stream with a tool call cut off mid
+// argument, and answers after that.
+type cutCallProvider struct {
+	stallingProvider
+}
+
+func (p *cutCallProvider) ChatStream(ctx context.Context, systemPrompt string, messages []provider.Message, toolDefs []tools.Tool) (<-chan provider.StreamDelta, error) {
+	p.requests = append(p.requests, messages)
+	ch := make(chan provider.StreamDelta, 1)
+	if len(p.requests) == 1 {
+		ch <- provider.StreamDelta{Done: true, Error: &provider.ToolCallError{ID: "1", Name: "bash", Arguments: `{"command":"rm -rf /`}}
+	} else {
+		ch <- provider.StreamDelta{Content: "done", Done: true}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestCutOffToolCallIsSentAgain(t *testing.T) {
+	prov := &cutCallProvider{}
+	a := New(Config{
+		Provider:    prov,
+		Tools:       tools.NewRegistry(),
+		Output:      io.Discard,
+		WorkingDir:  t.TempDir(),
+		TurnTimeout: time.Minute,
+	})
+	defer a.Close()
+
+	answer, err := a.Prompt(context.Background(), "clean the build")
+	if err != nil || answer != "done" {
+		t.Fatalf("Prompt = %q, %v", answer, err)
+	}
+	if len(prov.requests) != 2 {
+		t.Fatalf("sent %d requests, want the cut-off one and a retry", len(prov.requests))
+	}
+	retry := prov.requests[1]
+	if last := retry[len(retry)-1]; last.Role != "user" || !strings.Contains(last.Content, "call to bash was cut off") {
+		t.Errorf("the retry did not tell the model its call was cut off: %+v", last)
+	}
+}
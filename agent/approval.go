@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"brutus/internal/text"
+	"brutus/internal/theme"
+	"brutus/provider"
+	"brutus/tools"
+)
+
+// AutoApprovedTools only read, keep the plan and notes, or talk to the
+// user and other agents, so they run without asking.
+var AutoApprovedTools = map[string]bool{
+	"read_file":       true,
+	"read_image":      true,
+	"list_files":      true,
+	"code_search":     true,
+	"semantic_search": true,
+	"find_symbol":     true,
+	"imports_of":      true,
+	"dependents_of":   true,
+	"agent_broadcast": true,
+	"observe_agents":  true,
+	"ask_user":        true,
+	"issue_fetch":     true,
+	"read_artifact":   true,
+	"search_history":  true,
+	"todo":            true,
+	"memory":          true,
+}
+
+// ApprovalPolicy decides which tool calls need the user's approval before
+// they run. Tools the user says to always allow are remembered for the
+// rest of the session. Safe for concurrent use.
+type ApprovalPolicy struct {
+	// AutoApprove names the tools that run without asking. Nil means
+	// AutoApprovedTools.
+	AutoApprove map[string]bool
+
+	mu     sync.Mutex
+	always map[string]bool
+}
+
+// Allowed reports whether tool may run without asking.
+func (p *ApprovalPolicy) Allowed(tool string) bool {
+	auto := p.AutoApprove
+	if auto == nil {
+		auto = AutoApprovedTools
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return auto[tool] || p.always[tool]
+}
+
+// AllowAlways lets tool run without asking from now on.
+func (p *ApprovalPolicy) AllowAlways(tool string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.always == nil {
+		p.always = make(map[string]bool)
+	}
+	p.always[tool] = true
+}
+
+// approvalPreview caps how much of a call's arguments the prompt shows.
+const approvalPreview = 600
+
+// approve asks the user whether tc may run, unless there is no policy or
+// the policy allows it. "a" (always) allows the tool for the rest of the
+// session; anything but yes or always, including Ctrl+C, denies the call.
+func (a *Agent) approve(tc provider.ToolCall) bool {
+	if a.approval == nil || a.approval.Allowed(tc.Name) {
+		return true
+	}
+	fmt.Fprintf(a.out, "%s %s %s\n", theme.Warning("[approve]"), tc.Name, theme.Muted(text.Head(tools.RedactInput(tc.Input), approvalPreview)))
+	answer, ok := a.input.ReadLine(fmt.Sprintf("Run %s? [y]es / [n]o / [a]lways: ", tc.Name))
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	case "a", "always":
+		a.approval.AllowAlways(tc.Name)
+		return true
+	}
+	return false
+}
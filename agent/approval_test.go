@@ -0,0 +1,22 @@
+package agent
+
+import "testing"
+
+func TestApprovalPolicy(t *testing.T) {
+	var p ApprovalPolicy
+	if !p.Allowed("read_file") {
+		t.Error("read-only tools should run without asking")
+	}
+	if p.Allowed("bash") {
+		t.Error("bash should need approval")
+	}
+	p.AllowAlways("bash")
+	if !p.Allowed("bash") || p.Allowed("edit_file") {
+		t.Error("always should allow only the tool it was given for")
+	}
+
+	custom := ApprovalPolicy{AutoApprove: map[string]bool{"bash": true}}
+	if !custom.Allowed("bash") || custom.Allowed("read_file") {
+		t.Error("AutoApprove should replace the default list")
+	}
+}
@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"brutus/internal/text"
+	"brutus/internal/theme"
+	"brutus/provider"
+	"brutus/session"
+)
+
+// Command is a slash command: a line typed at the chat prompt that starts
+// with "/" and its name is run by Run instead of being sent to the model.
+type Command struct {
+	Name  string // Without the slash, e.g. "save"
+	Args  string // How to give its arguments, for /help, e.g. "<name>"
+	Usage string // What it does, in a few words, for /help
+
+	// Run carries the command out with the words after its name. An
+	// error is shown to the user, unless it is ErrExit, which ends Run.
+	Run func(ctx context.Context, a *Agent, args []string) error
+}
+
+// ErrExit, returned by a Command, ends the chat session.
+var ErrExit = errors.New("exit")
+
+// RegisterCommand adds cmd to the slash commands Run understands, or
+// replaces the one with its name. Commands appear in /help and are
+// completed at the prompt in the order they were registered, the built-in
+// ones first. Register them before calling Run.
+func (a *Agent) RegisterCommand(cmd Command) {
+	cmd.Name = strings.TrimPrefix(cmd.Name, "/")
+	for i, c := range a.commands {
+		if c.Name == cmd.Name {
+			a.commands[i] = cmd
+			return
+		}
+	}
+	a.commands = append(a.commands, cmd)
+}
+
+// Commands returns the slash commands Run understands, in order.
+func (a *Agent) Commands() []Command {
+	return append([]Command(nil), a.commands...)
+}
+
+// commandNames returns the commands with their slashes, for completion.
+func (a *Agent) commandNames() []string {
+	names := make([]string, len(a.commands))
+	for i, c := range a.commands {
+		names[i] = "/" + c.Name
+	}
+	return names
+}
+
+// handleCommand runs the slash command in input and reports whether the
+// session should end.
+func (a *Agent) handleCommand(ctx context.Context, input string) bool {
+	fields := strings.Fields(input)
+	name, args := strings.TrimPrefix(fields[0], "/"), fields[1:]
+	for _, cmd := range a.commands {
+		if cmd.Name != name {
+			continue
+		}
+		err := cmd.Run(ctx, a, args)
+		if errors.Is(err, ErrExit) {
+			fmt.Println(theme.Muted("Goodbye!"))
+			return true
+		}
+		if err != nil {
+			fmt.Println(theme.Error(fmt.Sprintf("Error: %s", err)))
+		}
+		fmt.Println()
+		return false
+	}
+	fmt.Println(theme.Error(fmt.Sprintf("Unknown command: %s", fields[0])))
+	fmt.Println(theme.Muted("Type /help for available commands"))
+	fmt.Println()
+	return false
+}
+
+// builtinCommands are the commands every Agent starts with.
+func builtinCommands() []Command {
+	return []Command{
+		{Name: "models", Usage: "Select an AI model", Run: func(ctx context.Context, a *Agent, args []string) error {
+			return a.handleModelsCommand(ctx)
+		}},
+		{Name: "help", Usage: "Show this help", Run: func(ctx context.Context, a *Agent, args []string) error {
+			a.handleHelpCommand()
+			return nil
+		}},
+		{Name: "clear", Usage: "Start a fresh conversation and clear the screen", Run: func(ctx context.Context, a *Agent, args []string) error {
+			return a.handleClearCommand()
+		}},
+		{Name: "compact", Usage: "Summarize the older turns now to free up context", Run: func(ctx context.Context, a *Agent, args []string) error {
+			return a.handleCompactCommand(ctx)
+		}},
+		{Name: "tools", Usage: "List the tools the model can use", Run: func(ctx context.Context, a *Agent, args []string) error {
+			a.handleToolsCommand()
+			return nil
+		}},
+		{Name: "save", Args: "<name>", Usage: "Save the conversation as a session to resume later", Run: func(ctx context.Context, a *Agent, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("usage: /save <name>")
+			}
+			return a.handleSaveCommand(strings.Join(args, " "))
+		}},
+		{Name: "export", Args: "<file.json|file.jsonl>", Usage: "Save the full conversation to a file", Run: func(ctx context.Context, a *Agent, args []string) error {
+			return a.handleExportCommand(args)
+		}},
+		{Name: "fork", Usage: "Save a copy of the conversation to continue separately", Run: func(ctx context.Context, a *Agent, args []string) error {
+			return a.handleForkCommand()
+		}},
+		{Name: "rewind", Args: "[N]", Usage: "Undo the last turn, or the last N", Run: func(ctx context.Context, a *Agent, args []string) error {
+			return a.handleRewindCommand(args)
+		}},
+		{Name: "history", Args: "<words>", Usage: "Search past sessions", Run: func(ctx context.Context, a *Agent, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("usage: /history <words to search for>")
+			}
+			return a.handleHistoryCommand(strings.Join(args, " "))
+		}},
+		{Name: "debug", Usage: "Show goroutines, caches and in-flight requests", Run: func(ctx context.Context, a *Agent, args []string) error {
+			a.handleDebugCommand()
+			return nil
+		}},
+		{Name: "cost", Usage: "Show the tokens and cost of the session so far", Run: func(ctx context.Context, a *Agent, args []string) error {
+			a.handleCostCommand()
+			return nil
+		}},
+		{Name: "thinking", Usage: "Show the model's latest thinking in full", Run: func(ctx context.Context, a *Agent, args []string) error {
+			if a.reasoning == "" {
+				fmt.Println(theme.Muted("The model hasn't shown any thinking yet."))
+			} else {
+				fmt.Println(theme.Muted(a.reasoning))
+			}
+			return nil
+		}},
+		{Name: "more", Usage: "Reopen the last long response in the pager", Run: func(ctx context.Context, a *Agent, args []string) error {
+			if a.lastPage == nil {
+				fmt.Println(theme.Muted("No long response to reopen yet."))
+				return nil
+			}
+			return a.lastPage.run(a.out, a.input.read, a.input.poll)
+		}},
+		{Name: "exit", Usage: "Exit BRUTUS", Run: func(ctx context.Context, a *Agent, args []string) error {
+			return ErrExit
+		}},
+	}
+}
+
+func (a *Agent) handleHelpCommand() {
+	fmt.Println(theme.Title("Available commands:"))
+	width := 0
+	for _, cmd := range a.commands {
+		width = max(width, len(cmd.Name)+1)
+	}
+	for _, cmd := range a.commands {
+		usage := cmd.Usage
+		if cmd.Args != "" {
+			usage += ": /" + cmd.Name + " " + cmd.Args
+		}
+		fmt.Printf("  %s%s - %s\n", theme.Command("/"+cmd.Name), strings.Repeat(" ", width-len(cmd.Name)-1), usage)
+	}
+	fmt.Println()
+	fmt.Println(theme.Muted("Tip: Type / and press Tab to autocomplete"))
+}
+
+// handleClearCommand empties the conversation, so the next prompt starts
+// afresh with only the system prompt. Turns go on being numbered from
+// where they were, so a transcript being recorded stays in order.
+func (a *Agent) handleClearCommand() error {
+	if err := a.conversation.Reset(); err != nil {
+		return err
+	}
+	a.reasoning = ""
+	a.lastPage = nil
+	fmt.Print(theme.ClearScreen())
+	a.printBanner()
+	fmt.Println(theme.Muted("Started a fresh conversation."))
+	return nil
+}
+
+// handleCompactCommand summarizes the older turns whatever the context
+// budget, as it would be once the conversation outgrew it.
+func (a *Agent) handleCompactCommand(ctx context.Context) error {
+	before := provider.EstimateTokens(a.systemPrompt, a.conversation.Messages())
+	if n := a.compact(ctx, a.logger, "compacting on request"); n == 0 {
+		fmt.Println(theme.Muted("Nothing to compact: only the latest turn is in context."))
+		return nil
+	}
+	after := provider.EstimateTokens(a.systemPrompt, a.conversation.Messages())
+	fmt.Println(theme.Success(fmt.Sprintf("Context down from ~%d to ~%d tokens", before, after)))
+	return nil
+}
+
+// handleToolsCommand lists the tools with the first sentence of each
+// description, marking those that ask before they run.
+func (a *Agent) handleToolsCommand() {
+	if a.tools == nil || len(a.tools.All()) == 0 {
+		fmt.Println(theme.Muted("No tools available."))
+		return
+	}
+	all := a.tools.All()
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	width := 0
+	for _, t := range all {
+		width = max(width, len(t.Name))
+	}
+	fmt.Println(theme.Title("Tools:") + fmt.Sprintf(" %d", len(all)))
+	for _, t := range all {
+		summary, _, _ := strings.Cut(t.Description, "\n")
+		if i := strings.Index(summary, ". "); i >= 0 {
+			summary = summary[:i+1]
+		}
+		line := fmt.Sprintf("  %s%s  %s", theme.Command(t.Name), strings.Repeat(" ", width-len(t.Name)), text.Head(summary, 90))
+		if a.approval != nil && !a.approval.Allowed(t.Name) {
+			line += theme.Muted(" (asks first)")
+		}
+		fmt.Println(line)
+	}
+}
+
+// handleSaveCommand saves the conversation so far as a session titled
+// name, which chat -resume lists, while this chat carries on.
+func (a *Agent) handleSaveCommand(name string) error {
+	snapshot, err := a.snapshot()
+	if err != nil {
+		return err
+	}
+	saved := session.New()
+	saved.Model, saved.WorkingDir = snapshot.Model, snapshot.WorkingDir
+	saved.Title, saved.Summary = name, snapshot.Summary
+	saved.Records = snapshot.Records
+	path := filepath.Join(session.Dir(), saved.ID+".jsonl")
+	tr, err := session.OpenTranscript(path, saved)
+	if err != nil {
+		return err
+	}
+	if err := tr.Close(); err != nil {
+		return err
+	}
+	fmt.Println(theme.Success(fmt.Sprintf("Saved %q as session %s", name, saved.ID)))
+	fmt.Println(theme.Muted("Resume it with: brutus chat -resume " + saved.ID))
+	return nil
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+func TestRegisterCommand(t *testing.T) {
+	a := New(Config{Provider: &summaryProvider{}, Tools: tools.NewRegistry(), Output: io.Discard, WorkingDir: t.TempDir()})
+	defer a.Close()
+
+	var got []string
+	a.RegisterCommand(Command{Name: "/deploy", Usage: "Deploy", Run: func(ctx context.Context, a *Agent, args []string) error {
+		got = args
+		return nil
+	}})
+	a.RegisterCommand(Command{Name: "exit", Usage: "Leave", Run: func(ctx context.Context, a *Agent, args []string) error {
+		return ErrExit
+	}})
+
+	cmds := a.Commands()
+	if last := cmds[len(cmds)-1]; last.Name != "deploy" {
+		t.Errorf("last command = %q, want deploy appended", last.Name)
+	}
+	exits := 0
+	for _, c := range cmds {
+		if c.Name == "exit" {
+			exits++
+			if c.Usage != "Leave" {
+				t.Errorf("exit was not replaced: %+v", c)
+			}
+		}
+	}
+	if exits != 1 {
+		t.Errorf("%d exit commands, want 1", exits)
+	}
+
+	if a.handleCommand(context.Background(), "/deploy staging now") {
+		t.Error("/deploy ended the session")
+	}
+	if strings.Join(got, " ") != "staging now" {
+		t.Errorf("args = %q", got)
+	}
+	if a.handleCommand(context.Background(), "/nonesuch") {
+		t.Error("an unknown command ended the session")
+	}
+	if !a.handleCommand(context.Background(), "/exit") {
+		t.Error("/exit did not end the session")
+	}
+
+	if s := a.input.getSuggestion("/dep"); s != "/deploy" {
+		t.Errorf("suggestion for /dep = %q, want /deploy", s)
+	}
+}
+
+func TestClearAndCompactCommands(t *testing.T) {
+	prov := &summaryProvider{}
+	a := New(Config{Provider: prov, Tools: tools.NewRegistry(), Output: io.Discard, WorkingDir: t.TempDir()})
+	defer a.Close()
+
+	for turn := 1; turn <= 3; turn++ {
+		a.addMessage(provider.Message{Role: "user", Content: "prompt"})
+		a.addMessage(provider.Message{Role: "assistant", Content: "reply"})
+	}
+	a.handleCommand(context.Background(), "/compact")
+	if len(prov.prompts) != 1 {
+		t.Fatalf("expected one summarizing request, got %d", len(prov.prompts))
+	}
+	if msgs := a.conversation.Messages(); len(msgs) >= 6 || !strings.Contains(msgs[0].Content, "the user asked for turns 1 and 2") {
+		t.Errorf("expected older turns summarized, got %+v", msgs)
+	}
+
+	a.handleCommand(context.Background(), "/clear")
+	if msgs := a.conversation.Messages(); len(msgs) != 0 {
+		t.Errorf("after /clear, %d messages remain", len(msgs))
+	}
+}
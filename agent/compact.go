@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+	"brutus/internal/theme"
+	"brutus/provider"
+)
+
+const (
+	// compactShare is how much of the model's context window the
+	// conversation may fill before older turns are summarized, when no
+	// budget is configured.
+	compactShare = 0.75
+
+	// compactMaxTokens caps the summary.
+	compactMaxTokens = 1000
+
+	// compactTimeout bounds one summarizing request.
+	compactTimeout = 2 * time.Minute
+
+	// compactExcerpt caps how much of each message the request sees.
+	compactExcerpt = 2000
+)
+
+const compactPrompt = `You condense the earlier part of a conversation between a user and a coding agent, so the agent can carry on without it. Given the summary so far (if any) and the messages that follow it, write one summary of both that keeps what the user asked for, decisions made, files read or changed and what was learned from them, commands run and how they went, and anything left unresolved. Use short bullet points and reply with the summary only.`
+
+// contextBudget returns the estimated prompt size, in tokens, above which
+// older turns are summarized, or 0 if they never are.
+func (a *Agent) contextBudget() int {
+	if a.budget != 0 {
+		return max(a.budget, 0)
+	}
+	return int(float64(a.provider.Capabilities().MaxContext) * compactShare)
+}
+
+// compactIfNeeded summarizes the older turns into a note, through the
+// model, when the conversation has grown past the context budget. The
+// latest turns, and the tool calls and results of the current one, are
+// kept whole. If the model can't summarize them they are spilled with
+// only the list of earlier prompts, as when a request is rejected as too
+// long.
+func (a *Agent) compactIfNeeded(ctx context.Context, logger *slog.Logger) {
+	budget := a.contextBudget()
+	if budget == 0 {
+		return
+	}
+	tokens := provider.EstimateTokens(a.systemPrompt, a.conversation.Messages())
+	if tokens <= budget {
+		return
+	}
+	a.compact(ctx, logger, fmt.Sprintf("~%d tokens, over the budget of %d", tokens, budget))
+}
+
+// compact summarizes the older turns, as compactIfNeeded does, and returns
+// how many messages it set aside; reason says why, in what it prints.
+func (a *Agent) compact(ctx context.Context, logger *slog.Logger, reason string) int {
+	n, err := a.conversation.Summarize(func(previous string, older []provider.Message) (string, error) {
+		return summarizeMessages(ctx, a.provider, previous, older)
+	})
+	if err != nil {
+		logger.Warn("summarizing older turns failed", "error", err)
+		if n, err = a.conversation.Compact(); err != nil {
+			logger.Warn("conversation spill failed", "error", err)
+		}
+		if n > 0 {
+			fmt.Fprintf(a.out, "%s %s; set aside the oldest %d messages\n", theme.Warning("[context]"), reason, n)
+		}
+		return n
+	}
+	if n > 0 {
+		logger.Info("summarized older turns", "reason", reason, "messages", n)
+		fmt.Fprintf(a.out, "%s %s; summarized the oldest %d messages\n", theme.Muted("[context]"), reason, n)
+	}
+	return n
+}
+
+// summarizeMessages asks the model to fold messages into the summary so
+// far.
+func summarizeMessages(ctx context.Context, prov provider.Provider, previous string, messages []provider.Message) (string, error) {
+	ctx, cancel := context.WithTimeout(provider.WithMaxTokens(ctx, compactMaxTokens), compactTimeout)
+	defer cancel()
+
+	var b strings.Builder
+	if previous != "" {
+		fmt.Fprintf(&b, "Summary so far:\n%s\n\n", previous)
+	}
+	b.WriteString("Messages:\n")
+	for _, msg := range messages {
+		if msg.Content != "" {
+			fmt.Fprintf(&b, "\n%s: %s\n", msg.Role, text.HeadTail(msg.Content, compactExcerpt))
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&b, "\n%s called %s %s\n", msg.Role, tc.Name, text.Head(string(tc.Input), compactExcerpt/4))
+		}
+		for _, tr := range msg.ToolResults {
+			fmt.Fprintf(&b, "\ntool result: %s\n", text.HeadTail(tr.Content, compactExcerpt/2))
+		}
+	}
+
+	reply, err := prov.Chat(ctx, compactPrompt, []provider.Message{{Role: "user", Content: b.String()}}, nil)
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(reply.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+// summaryProvider answers every request with a fixed summary and records
+// the prompts it was sent.
+type summaryProvider struct {
+	stallingProvider
+	prompts []string
+}
+
+func (p *summaryProvider) Chat(ctx context.Context, systemPrompt string, messages []provider.Message, toolDefs []tools.Tool) (provider.Message, error) {
+	p.prompts = append(p.prompts, messages[0].Content)
+	return provider.Message{Role: "assistant", Content: "- the user asked for turns 1 and 2"}, nil
+}
+
+func TestCompactIfNeededSummarizesOlderTurns(t *testing.T) {
+	prov := &summaryProvider{}
+	a := New(Config{
+		Provider:      prov,
+		Tools:         tools.NewRegistry(),
+		Output:        io.Discard,
+		WorkingDir:    t.TempDir(),
+		ContextBudget: 50,
+	})
+	defer a.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	a.compactIfNeeded(context.Background(), logger)
+	if len(prov.prompts) != 0 {
+		t.Fatal("summarized a conversation under the budget")
+	}
+
+	for turn := 1; turn <= 4; turn++ {
+		a.addMessage(provider.Message{Role: "user", Content: strings.Repeat("prompt ", 20)})
+		a.addMessage(provider.Message{Role: "assistant", Content: strings.Repeat("reply ", 20)})
+	}
+	a.compactIfNeeded(context.Background(), logger)
+	if len(prov.prompts) != 1 {
+		t.Fatalf("expected one summarizing request, got %d", len(prov.prompts))
+	}
+	msgs := a.conversation.Messages()
+	if len(msgs) != 4 || !strings.Contains(msgs[0].Content, "the user asked for turns 1 and 2") {
+		t.Errorf("expected the summary before the two latest turns, got %+v", msgs)
+	}
+}
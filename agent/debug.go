@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+
+	"brutus/internal/theme"
+	"brutus/provider"
+	"brutus/tools"
+)
+
+// handleDebugCommand prints the process state that matters when a session
+// appears to hang: goroutines, caches, what the provider supports, rate
+// limiter slots and provider requests that have not returned.
+func (a *Agent) handleDebugCommand() {
+	total, states := goroutineStates()
+	fmt.Println(theme.Title("Goroutines:") + fmt.Sprintf(" %d", total))
+	for _, s := range states {
+		fmt.Printf("  %-24s %d\n", s.state, s.count)
+	}
+
+	fmt.Println(theme.Title("Caches:"))
+	fmt.Printf("  %-24s %d\n", "discovered services", provider.CachedServices())
+	fmt.Printf("  %-24s %d\n", "tool schemas", tools.CachedSchemas())
+	fmt.Printf("  %-24s %d in memory, %d spilled\n", "conversation", a.conversation.Len(), a.conversation.Spilled())
+
+	caps := a.provider.Capabilities()
+	fmt.Println(theme.Title("Provider capabilities:"))
+	fmt.Printf("  streaming %t, tools %t, vision %t, json mode %t, context %s\n",
+		caps.Streaming, caps.Tools, caps.Vision, caps.JSONMode, contextSize(caps.MaxContext))
+
+	if pool, ok := provider.Unwrap(a.provider).(*provider.SaturnPool); ok {
+		fmt.Println(theme.Title("Pool services:"))
+		for _, h := range pool.Health() {
+			state := "ok"
+			if h.Open {
+				state = fmt.Sprintf("skipped, checked again in %s", time.Until(h.CheckAt).Round(time.Second))
+			}
+			fmt.Printf("  %-24s %s, %d failures in a row, %d in all\n", h.Name, state, h.Failures, h.TotalFailures)
+		}
+	}
+
+	fmt.Println(theme.Title("Rate limiters:"))
+	limiters := provider.Limiters()
+	if len(limiters) == 0 {
+		fmt.Println(theme.Muted("  none configured"))
+	}
+	for _, l := range limiters {
+		slots := "unlimited"
+		if l.Limit.MaxConcurrent > 0 {
+			slots = fmt.Sprintf("%d/%d", l.Active, l.Limit.MaxConcurrent)
+		}
+		fmt.Printf("  %s: slots %s, %.0f requests and %.0f tokens left\n", l.Service, slots, l.Requests, l.Tokens)
+	}
+
+	fmt.Println(theme.Title("In-flight requests:"))
+	reqs := provider.InFlight()
+	if len(reqs) == 0 {
+		fmt.Println(theme.Muted("  none"))
+	}
+	for _, r := range reqs {
+		line := fmt.Sprintf("  %-8s %s", r.Kind, r.Service)
+		if r.Model != "" {
+			line += " (" + r.Model + ")"
+		}
+		fmt.Printf("%s for %s\n", line, time.Since(r.Started).Round(time.Millisecond))
+	}
+}
+
+// contextSize formats a context window for display.
+func contextSize(tokens int) string {
+	if tokens == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d tokens", tokens)
+}
+
+type goroutineState struct {
+	state string
+	count int
+}
+
+// goroutineStates counts goroutines by scheduler state ("running",
+// "select", "IO wait", ...), most common first.
+func goroutineStates() (int, []goroutineState) {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	counts := map[string]int{}
+	total := 0
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		// Headers look like "goroutine 7 [chan receive, 2 minutes]:".
+		if !bytes.HasPrefix(line, []byte("goroutine ")) {
+			continue
+		}
+		start, end := bytes.IndexByte(line, '['), bytes.IndexByte(line, ']')
+		if start < 0 || end < start {
+			continue
+		}
+		state := line[start+1 : end]
+		if i := bytes.IndexByte(state, ','); i >= 0 {
+			state = state[:i]
+		}
+		counts[string(state)]++
+		total++
+	}
+
+	states := make([]goroutineState, 0, len(counts))
+	for s, c := range counts {
+		states = append(states, goroutineState{s, c})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].count != states[j].count {
+			return states[i].count > states[j].count
+		}
+		return states[i].state < states[j].state
+	})
+	return total, states
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+	"brutus/provider"
+	"brutus/session"
+)
+
+const (
+	// describeMaxTokens keeps the titling request cheap.
+	describeMaxTokens = 200
+
+	// describeTimeout bounds one titling request.
+	describeTimeout = 30 * time.Second
+
+	// describeExcerpt caps how much of the latest turn the request sees.
+	describeExcerpt = 4000
+)
+
+const describePrompt = `You label conversations between a user and a coding agent for a list of saved sessions. Given the previous summary (if any) and the latest exchange, reply in exactly this form and nothing else:
+Title: <three to eight words naming the task, no quotes>
+Summary: <one to three sentences: what the user wants and where things stand now>`
+
+// Describe asks the model for a title and an updated summary of a
+// conversation, given its current description and the latest exchange.
+// The request is small and capped at a few hundred tokens of output.
+func Describe(ctx context.Context, prov provider.Provider, current session.Info, prompt, response string) (session.Info, error) {
+	ctx, cancel := context.WithTimeout(provider.WithMaxTokens(ctx, describeMaxTokens), describeTimeout)
+	defer cancel()
+
+	var b strings.Builder
+	if current.Summary != "" {
+		fmt.Fprintf(&b, "Previous summary: %s\n\n", current.Summary)
+	}
+	fmt.Fprintf(&b, "User: %s\n\nAgent: %s", text.HeadTail(prompt, describeExcerpt/2), text.HeadTail(response, describeExcerpt/2))
+
+	reply, err := prov.Chat(ctx, describePrompt, []provider.Message{{Role: "user", Content: b.String()}}, nil)
+	if err != nil {
+		return current, err
+	}
+	info := parseDescription(reply.Content)
+	if info.Title == "" && info.Summary == "" {
+		return current, fmt.Errorf("no title or summary in reply %q", text.Head(reply.Content, 200))
+	}
+	// Keep the first title; a session shouldn't change names as it goes.
+	if current.Title != "" || info.Title == "" {
+		info.Title = current.Title
+	}
+	if info.Summary == "" {
+		info.Summary = current.Summary
+	}
+	return info, nil
+}
+
+// parseDescription reads the "Title:" and "Summary:" lines of a reply,
+// tolerating markdown emphasis and quotes that small models like to add.
+func parseDescription(reply string) session.Info {
+	var info session.Info
+	clean := func(s string) string {
+		return strings.Trim(s, " \t*\"'`")
+	}
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "*#- ")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(clean(key)) {
+		case "title":
+			info.Title = text.Head(clean(value), 80)
+		case "summary":
+			info.Summary = text.Head(clean(value), 500)
+		}
+	}
+	return info
+}
+
+// describeTurn updates the conversation's title and summary in the
+// background after a turn, if the caller asked for them.
+func (a *Agent) describeTurn(prompt string, response provider.Message) {
+	if a.onDescribe == nil || strings.TrimSpace(response.Content) == "" {
+		return
+	}
+	a.describing.Add(1)
+	go func() {
+		defer a.describing.Done()
+		a.describeMu.Lock()
+		defer a.describeMu.Unlock()
+
+		info, err := Describe(context.Background(), a.provider, a.info, prompt, response.Content)
+		if err != nil {
+			a.logger.Warn("describing conversation failed", "error", err)
+			return
+		}
+		a.info = info
+		a.onDescribe(info)
+	}()
+}
+
+// waitDescribing gives a title request still in flight a moment to finish,
+// so a short session still gets saved with its name.
+func (a *Agent) waitDescribing(max time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		a.describing.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(max):
+	}
+}
@@ -0,0 +1,18 @@
+package agent
+
+import "testing"
+
+func TestParseDescription(t *testing.T) {
+	reply := "**Title:** \"Add retry to uploads\"\n\n- Summary: The user wants failed uploads retried. Backoff is in place.\n"
+	info := parseDescription(reply)
+	if info.Title != "Add retry to uploads" {
+		t.Errorf("unexpected title %q", info.Title)
+	}
+	if info.Summary != "The user wants failed uploads retried. Backoff is in place." {
+		t.Errorf("unexpected summary %q", info.Summary)
+	}
+
+	if info := parseDescription("I can't help with that."); info.Title != "" || info.Summary != "" {
+		t.Errorf("expected nothing parsed, got %+v", info)
+	}
+}
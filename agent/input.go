@@ -2,27 +2,124 @@ package agent
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
 	"golang.org/x/term"
+
+	"brutus/internal/theme"
 )
 
-var commands = []string{
-	"/models",
-	"/help",
-	"/clear",
-	"/exit",
-}
+// inputReader reads the terminal for both prompts and steering notes. A
+// single goroutine owns stdin, since a read in flight can't be cancelled
+// and would otherwise swallow the first keys of the next prompt.
+type inputReader struct {
+	once   sync.Once
+	chunks chan []byte // stdin reads, as they arrived; closed at EOF
 
-type inputReader struct{}
+	// onLine is set while lines are being collected, and stopCollector
+	// stops the goroutine doing it.
+	onLine        func(string)
+	stopCollector func()
+
+	// commands returns the slash commands to complete at the prompt.
+	commands func() []string
+}
 
 func newInputReader() *inputReader {
 	return &inputReader{}
 }
 
+func (r *inputReader) start() {
+	r.once.Do(func() {
+		r.chunks = make(chan []byte)
+		go func() {
+			for {
+				buf := make([]byte, 3)
+				n, err := os.Stdin.Read(buf)
+				if n > 0 {
+					r.chunks <- buf[:n]
+				}
+				if err != nil {
+					close(r.chunks)
+					return
+				}
+			}
+		}()
+	})
+}
+
+// collectLines passes every line typed at the terminal to onLine until the
+// returned stop function is called. The terminal is in its normal line
+// mode meanwhile, so it echoes and edits the line itself. stop waits for a
+// line already being delivered to finish, so its tail doesn't leak into
+// the next prompt.
+func (r *inputReader) collectLines(onLine func(string)) (stop func()) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return func() {}
+	}
+	r.start()
+	r.onLine = onLine
+	r.stopCollector = r.spawnCollector(onLine)
+	return func() {
+		r.stopCollector()
+		r.onLine, r.stopCollector = nil, nil
+	}
+}
+
+func (r *inputReader) spawnCollector(onLine func(string)) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		var line []byte
+		stopping := false
+		for {
+			var chunk []byte
+			var ok bool
+			if stopping {
+				if len(line) == 0 {
+					return
+				}
+				chunk, ok = <-r.chunks
+			} else {
+				select {
+				case chunk, ok = <-r.chunks:
+				case <-done:
+					stopping = true
+					continue
+				}
+			}
+			if !ok {
+				return
+			}
+			line = append(line, chunk...)
+			for {
+				i := bytes.IndexByte(line, '\n')
+				if i < 0 {
+					break
+				}
+				onLine(strings.TrimRight(string(line[:i]), "\r"))
+				line = line[i+1:]
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
 func (r *inputReader) ReadLine(prompt string) (string, bool) {
+	// A prompt in the middle of a turn (ask_user) takes over from steering.
+	if r.onLine != nil {
+		r.stopCollector()
+		defer func() { r.stopCollector = r.spawnCollector(r.onLine) }()
+	}
+
 	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
 	if err != nil {
 		return r.readLineSimple(prompt)
@@ -35,11 +132,11 @@ func (r *inputReader) ReadLine(prompt string) (string, bool) {
 	var lastSuggestion string
 
 	for {
-		buf := make([]byte, 3)
-		n, err := os.Stdin.Read(buf)
-		if err != nil {
+		buf, ok := r.read()
+		if !ok {
 			return "", false
 		}
+		n := len(buf)
 
 		if n == 1 {
 			ch := buf[0]
@@ -100,11 +197,28 @@ func (r *inputReader) ReadLine(prompt string) (string, bool) {
 	}
 }
 
+func (r *inputReader) read() ([]byte, bool) {
+	r.start()
+	chunk, ok := <-r.chunks
+	return chunk, ok
+}
+
+// poll returns a chunk of input that is already waiting, without blocking.
+func (r *inputReader) poll() ([]byte, bool) {
+	r.start()
+	select {
+	case chunk, ok := <-r.chunks:
+		return chunk, ok
+	default:
+		return nil, false
+	}
+}
+
 func (r *inputReader) updateGhost(input string) string {
 	suggestion := r.getSuggestion(input)
 	if suggestion != "" && len(suggestion) > len(input) {
 		ghost := suggestion[len(input):]
-		fmt.Print("\033[90m" + ghost + "\033[0m")
+		fmt.Print(theme.Muted(ghost))
 		// Move cursor back to end of actual input
 		for range ghost {
 			fmt.Print("\b")
@@ -128,11 +242,11 @@ func (r *inputReader) clearGhost(suggestion, current string) {
 }
 
 func (r *inputReader) getSuggestion(input string) string {
-	if !strings.HasPrefix(input, "/") || input == "" {
+	if !strings.HasPrefix(input, "/") || r.commands == nil {
 		return ""
 	}
 	lower := strings.ToLower(input)
-	for _, cmd := range commands {
+	for _, cmd := range r.commands() {
 		if strings.HasPrefix(cmd, lower) && cmd != input {
 			return cmd
 		}
@@ -0,0 +1,274 @@
+package agent
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"brutus/internal/theme"
+)
+
+// maxCoalescedKeys bounds how many keys already waiting are applied before
+// the pager redraws, so holding a key down doesn't queue a redraw per
+// repeat but the screen still keeps up.
+const maxCoalescedKeys = 64
+
+const pagerHelp = "q quit · space/b page · j/k line · g/G top/end · / search · n/N next/prev · c copy block"
+
+// pager shows text a screen at a time, like less. It keeps its position,
+// so a response can be reopened where it was left.
+type pager struct {
+	lines  []string
+	top    int
+	height int // text lines per screen, not counting the status line
+	width  int
+
+	search  string
+	match   int    // line of the current search match, or -1
+	message string // shown in the status line until the next key
+}
+
+// newPager wraps text to width and pages it height lines at a time,
+// keeping one line for the status bar.
+func newPager(text string, width, height int) *pager {
+	if width < 20 {
+		width = 20
+	}
+	return &pager{
+		lines:  wrapLines(strings.TrimRight(text, "\n"), width),
+		height: max(height-1, 1),
+		width:  width,
+		match:  -1,
+	}
+}
+
+// fits reports whether the text fits on one screen, so there is no need to
+// page it.
+func (p *pager) fits() bool {
+	return len(p.lines) <= p.height
+}
+
+// wrapLines splits text into lines no wider than width runes.
+func wrapLines(text string, width int) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(strings.ReplaceAll(strings.TrimRight(line, "\r"), "\t", "    "))
+		for len(runes) > width {
+			out = append(out, string(runes[:width]))
+			runes = runes[width:]
+		}
+		out = append(out, string(runes))
+	}
+	return out
+}
+
+func (p *pager) scroll(n int) {
+	p.top = max(0, min(p.top+n, len(p.lines)-p.height))
+}
+
+// find moves to the next line matching the search after (dir 1) or before
+// (dir -1) the current match, wrapping around.
+func (p *pager) find(dir int) {
+	if p.search == "" {
+		p.message = "No search yet; press / to search"
+		return
+	}
+	needle := strings.ToLower(p.search)
+	start := p.match
+	if start < 0 {
+		start = p.top - dir
+	}
+	for i := 1; i <= len(p.lines); i++ {
+		line := ((start+dir*i)%len(p.lines) + len(p.lines)) % len(p.lines)
+		if strings.Contains(strings.ToLower(p.lines[line]), needle) {
+			p.match = line
+			if line < p.top || line >= p.top+p.height {
+				p.top = line
+				p.scroll(-p.height / 3)
+			}
+			return
+		}
+	}
+	p.match = -1
+	p.message = fmt.Sprintf("Not found: %s", p.search)
+}
+
+// codeBlock returns the fenced code block on screen, the first one that
+// starts at or below the top line, or the one the top line is in.
+func (p *pager) codeBlock() (string, bool) {
+	start := -1
+	for i, line := range p.lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		if i >= p.top {
+			return strings.Join(p.lines[start+1:i], "\n"), true
+		}
+		start = -1
+	}
+	return "", false
+}
+
+// key applies one key press. readLine prompts for a line, for searches.
+// It returns true when the pager should close.
+func (p *pager) key(k []byte, readLine func(prompt string) (string, bool), copyText func(string)) bool {
+	p.message = ""
+	if len(k) == 3 && k[0] == 27 && k[1] == '[' {
+		switch k[2] {
+		case 'A':
+			p.scroll(-1)
+		case 'B':
+			p.scroll(1)
+		}
+		return false
+	}
+	if len(k) != 1 {
+		return false
+	}
+	switch k[0] {
+	case 'q', 'Q', 27, 3: // q, Escape, Ctrl-C
+		return true
+	case 'j', '\r', '\n':
+		p.scroll(1)
+	case 'k':
+		p.scroll(-1)
+	case ' ', 'f':
+		p.scroll(p.height)
+	case 'b':
+		p.scroll(-p.height)
+	case 'd':
+		p.scroll(p.height / 2)
+	case 'u':
+		p.scroll(-p.height / 2)
+	case 'g':
+		p.top = 0
+	case 'G':
+		p.scroll(len(p.lines))
+	case '/':
+		if search, ok := readLine("/"); ok && search != "" {
+			p.search, p.match = search, -1
+			p.find(1)
+		}
+	case 'n':
+		p.find(1)
+	case 'N':
+		p.find(-1)
+	case 'c':
+		block, ok := p.codeBlock()
+		if !ok {
+			p.message = "No code block on or below this screen"
+			break
+		}
+		copyText(block)
+		p.message = fmt.Sprintf("Copied %d lines to the clipboard", strings.Count(block, "\n")+1)
+	}
+	return false
+}
+
+// view renders the current screen, status line last, with "\r\n" line
+// ends for a terminal in raw mode.
+func (p *pager) view() string {
+	var b strings.Builder
+	end := min(p.top+p.height, len(p.lines))
+	for i := p.top; i < end; i++ {
+		line := p.lines[i]
+		if i == p.match {
+			line = theme.Highlight(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\x1b[K\r\n")
+	}
+	for i := end; i < p.top+p.height; i++ {
+		b.WriteString(theme.Muted("~") + "\x1b[K\r\n")
+	}
+
+	status := p.message
+	if status == "" {
+		percent := 100
+		if len(p.lines) > p.height {
+			percent = end * 100 / len(p.lines)
+		}
+		status = fmt.Sprintf("lines %d-%d of %d (%d%%)  %s", p.top+1, end, len(p.lines), percent, pagerHelp)
+	}
+	if utf8.RuneCountInString(status) > p.width {
+		status = string([]rune(status)[:p.width])
+	}
+	b.WriteString(theme.Muted(status) + "\x1b[K")
+	return b.String()
+}
+
+// run shows the pager on the terminal's alternate screen until the user
+// quits, reading keys with read. Keys already waiting, as poll reports
+// them, are applied together before the next redraw.
+func (p *pager) run(out io.Writer, read, poll func() ([]byte, bool)) error {
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, oldState)
+
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	readLine := func(prompt string) (string, bool) {
+		return p.readLine(out, prompt, read)
+	}
+	copyText := func(s string) {
+		// OSC 52 asks the terminal to set the clipboard, which also works
+		// over SSH; terminals that don't support it ignore it.
+		fmt.Fprintf(out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(s)))
+	}
+
+	for {
+		fmt.Fprint(out, "\x1b[H"+p.view())
+		k, ok := read()
+		if !ok || p.key(k, readLine, copyText) {
+			return nil
+		}
+		for range maxCoalescedKeys {
+			k, ok := poll()
+			if !ok {
+				break
+			}
+			if p.key(k, readLine, copyText) {
+				return nil
+			}
+		}
+	}
+}
+
+// readLine reads a line on the status line, for a search. Escape cancels.
+func (p *pager) readLine(out io.Writer, prompt string, read func() ([]byte, bool)) (string, bool) {
+	var line []rune
+	fmt.Fprint(out, "\x1b[?25h")
+	defer fmt.Fprint(out, "\x1b[?25l")
+	for {
+		fmt.Fprintf(out, "\r%s%s\x1b[K", prompt, string(line))
+		k, ok := read()
+		if !ok {
+			return "", false
+		}
+		switch {
+		case len(k) == 1 && (k[0] == '\r' || k[0] == '\n'):
+			return string(line), true
+		case len(k) == 1 && (k[0] == 27 || k[0] == 3):
+			return "", false
+		case len(k) == 1 && (k[0] == 127 || k[0] == 8):
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case k[0] >= 32 && k[0] != 127:
+			line = append(line, []rune(string(k))...)
+		}
+	}
+}
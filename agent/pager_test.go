@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPagerKeys(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines[69] = "the needle"
+	lines[79] = "```go"
+	lines[80] = "fmt.Println(1)"
+	lines[81] = "```"
+	p := newPager(strings.Join(lines, "\n"), 80, 11)
+	if p.fits() || p.height != 10 {
+		t.Fatalf("expected 100 lines paged 10 at a time, got height %d", p.height)
+	}
+
+	var copied string
+	press := func(keys string, search string) bool {
+		readLine := func(string) (string, bool) { return search, true }
+		for _, k := range []byte(keys) {
+			if p.key([]byte{k}, readLine, func(s string) { copied = s }) {
+				return true
+			}
+		}
+		return false
+	}
+
+	press("G", "")
+	if p.top != 90 {
+		t.Errorf("G: expected top 90, got %d", p.top)
+	}
+	press("g  b", "")
+	if p.top != 10 {
+		t.Errorf("g, two pages down, one up: expected top 10, got %d", p.top)
+	}
+
+	press("/", "NEEDLE")
+	if p.match != 69 || p.top > 69 || p.top+p.height <= 69 {
+		t.Errorf("search: expected line 69 matched and on screen, got match %d top %d", p.match, p.top)
+	}
+	press("n", "")
+	if p.match != 69 {
+		t.Errorf("n with one match: expected it to wrap back to 69, got %d", p.match)
+	}
+
+	press("c", "")
+	if copied != "fmt.Println(1)" {
+		t.Errorf("c: expected the code block below the screen copied, got %q", copied)
+	}
+	p.top = 85
+	if press("c", ""); !strings.Contains(p.message, "No code block") {
+		t.Errorf("c past the last block: expected a message, got %q", p.message)
+	}
+
+	if !press("q", "") {
+		t.Error("q should close the pager")
+	}
+}
+
+func TestWrapLines(t *testing.T) {
+	got := wrapLines("abcdefghij\n\tx", 4)
+	want := []string{"abcd", "efgh", "ij", "    ", "x"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapLines = %q, want %q", got, want)
+	}
+}
@@ -6,9 +6,24 @@ import (
 	"strings"
 
 	"golang.org/x/term"
+
+	"brutus/internal/theme"
 )
 
-func pickFromList(title string, items []string, pageSize int) (int, error) {
+// PickFromList shows items in a full-screen list and returns the index the
+// user picked, or -1 if they cancelled. It reads the terminal directly, so
+// it must not be used once an Agent is running.
+func PickFromList(title string, items []string) (int, error) {
+	return pickFromList(title, items, 15, func() ([]byte, bool) {
+		buf := make([]byte, 3)
+		n, err := os.Stdin.Read(buf)
+		return buf[:n], err == nil
+	})
+}
+
+// pickFromList is PickFromList reading keys with read, which returns false
+// once input has ended.
+func pickFromList(title string, items []string, pageSize int, read func() ([]byte, bool)) (int, error) {
 	if len(items) == 0 {
 		return -1, fmt.Errorf("no items to pick from")
 	}
@@ -37,9 +52,9 @@ func pickFromList(title string, items []string, pageSize int) (int, error) {
 
 	for {
 		// Clear screen and draw
-		fmt.Print("\033[2J\033[H")
-		fmt.Printf("\033[1;36m%s\033[0m\n", title)
-		fmt.Println("\033[90mUse ↑/↓ to navigate, Enter to select, q to cancel\033[0m")
+		fmt.Print(theme.ClearScreen())
+		fmt.Println(theme.Title(title))
+		fmt.Println(theme.Muted("Use ↑/↓ to navigate, Enter to select, q to cancel"))
 		fmt.Println()
 
 		// Calculate visible range
@@ -50,7 +65,7 @@ func pickFromList(title string, items []string, pageSize int) (int, error) {
 
 		for i := offset; i < end; i++ {
 			if i == selected {
-				fmt.Printf("\033[1;33m> %s\033[0m\n", items[i])
+				fmt.Println(theme.Highlight(fmt.Sprintf("> %s", items[i])))
 			} else {
 				fmt.Printf("  %s\n", items[i])
 			}
@@ -59,30 +74,30 @@ func pickFromList(title string, items []string, pageSize int) (int, error) {
 		// Show scroll indicators
 		fmt.Println()
 		if len(items) > pageSize {
-			fmt.Printf("\033[90m[%d/%d]", selected+1, len(items))
+			status := fmt.Sprintf("[%d/%d]", selected+1, len(items))
 			if offset > 0 {
-				fmt.Print(" ↑ more above")
+				status += " ↑ more above"
 			}
 			if end < len(items) {
-				fmt.Print(" ↓ more below")
+				status += " ↓ more below"
 			}
-			fmt.Println("\033[0m")
+			fmt.Println(theme.Muted(status))
 		}
 
 		// Read input
-		buf := make([]byte, 3)
-		n, err := os.Stdin.Read(buf)
-		if err != nil {
-			return -1, err
+		buf, ok := read()
+		if !ok {
+			return -1, fmt.Errorf("input ended")
 		}
+		n := len(buf)
 
 		if n == 1 {
 			switch buf[0] {
 			case 'q', 'Q', 27: // q, Q, or Escape
-				fmt.Print("\033[2J\033[H")
+				fmt.Print(theme.ClearScreen())
 				return -1, nil
 			case 13, 10: // Enter
-				fmt.Print("\033[2J\033[H")
+				fmt.Print(theme.ClearScreen())
 				return selected, nil
 			case 'j', 'J': // vim down
 				if selected < len(items)-1 {
@@ -0,0 +1,16 @@
+package agent
+
+import "brutus/tools"
+
+// PlanModeNote is added to the system prompt in plan mode.
+const PlanModeNote = `Plan mode is on. Before changing anything, explore as much as you need with the read-only tools, write your plan with the todo tool and submit it for the user's approval. Tools that edit files, run commands or otherwise change things are refused until the user approves the plan. Then work through it, checking off each step as it is done.`
+
+// PlanRequired is the result of a call plan mode holds back.
+const PlanRequired = "Not run: plan mode is on and the user has not approved a plan yet. Write the plan with todo (op add) and submit it (op submit); read-only tools can be used meanwhile."
+
+// BlockedByPlan reports whether plan mode holds back a call to tool: until
+// the user approves the plan, only the tools that run without asking may
+// run.
+func BlockedByPlan(plan *tools.Plan, tool string) bool {
+	return plan.Gated() && !plan.Approved() && !AutoApprovedTools[tool]
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+	"brutus/provider"
+)
+
+const (
+	// DefaultResultLimit is the size in bytes above which a tool result is
+	// cut to its head and tail, unless configured otherwise.
+	DefaultResultLimit = 100000
+
+	// defaultSummaryTokens caps a result's summary when ResultLimits
+	// doesn't.
+	defaultSummaryTokens = 500
+
+	// summarizeTimeout bounds one summarizing request.
+	summarizeTimeout = time.Minute
+)
+
+// exactResultTools are never summarized: the model needs their text as it
+// is, to quote in edits or because it is already a digest.
+var exactResultTools = map[string]bool{
+	"read_file":      true,
+	"read_artifact":  true,
+	"spawn_subagent": true,
+}
+
+const summarizeResultPrompt = `You condense the output of a tool a coding agent called, so that it takes less of the agent's context. Keep everything the agent may act on exactly as written: file paths, line numbers, identifiers, error messages, counts and failing test names. Drop repetition and noise. Reply with the condensed output only.`
+
+// ResultLimits bounds the tool results that go back to the model, after
+// results large enough to archive as artifacts have been.
+type ResultLimits struct {
+	// MaxBytes cuts longer results to their first and last lines. Zero
+	// or less keeps results whole.
+	MaxBytes int
+
+	// SummarizeBytes has the model condense results longer than this,
+	// up to MaxBytes, with a separate request; read_file and a few other
+	// tools whose text must stay exact are left alone. A result that
+	// can't be summarized is kept whole. Zero or less is off.
+	SummarizeBytes int
+
+	// SummaryTokens caps a summary. Zero is 500.
+	SummaryTokens int
+}
+
+// ShrinkResult applies limits to the result of call. The error, if any,
+// says why summarizing failed; the result is then returned unsummarized.
+func ShrinkResult(ctx context.Context, prov provider.Provider, limits ResultLimits, call provider.ToolCall, result string) (string, error) {
+	if limits.MaxBytes > 0 && len(result) > limits.MaxBytes {
+		return text.HeadTail(result, limits.MaxBytes), nil
+	}
+	if limits.SummarizeBytes <= 0 || len(result) <= limits.SummarizeBytes || exactResultTools[call.Name] {
+		return result, nil
+	}
+	summary, err := summarizeResult(ctx, prov, limits.SummaryTokens, call, result)
+	if err != nil {
+		return result, err
+	}
+	return fmt.Sprintf("[Condensed from %d bytes of %s output; call it again more narrowly for the exact text.]\n%s", len(result), call.Name, summary), nil
+}
+
+// summarizeResult asks the model to condense one tool result.
+func summarizeResult(ctx context.Context, prov provider.Provider, maxTokens int, call provider.ToolCall, result string) (string, error) {
+	if maxTokens <= 0 {
+		maxTokens = defaultSummaryTokens
+	}
+	ctx, cancel := context.WithTimeout(provider.WithMaxTokens(ctx, maxTokens), summarizeTimeout)
+	defer cancel()
+
+	request := fmt.Sprintf("Tool: %s\nInput: %s\n\nOutput:\n%s", call.Name, text.Head(string(call.Input), 500), result)
+	reply, err := prov.Chat(ctx, summarizeResultPrompt, []provider.Message{{Role: "user", Content: request}}, nil)
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(reply.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	if len(summary) >= len(result) {
+		return "", fmt.Errorf("the summary is no shorter than the result")
+	}
+	return summary, nil
+}
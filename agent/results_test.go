@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"brutus/provider"
+)
+
+func TestShrinkResult(t *testing.T) {
+	prov := &summaryProvider{}
+	limits := ResultLimits{MaxBytes: 1000, SummarizeBytes: 200}
+	ctx := context.Background()
+	search := provider.ToolCall{Name: "code_search", Input: []byte(`{"pattern":"x"}`)}
+
+	small := strings.Repeat("a", 100)
+	if got, err := ShrinkResult(ctx, prov, limits, search, small); err != nil || got != small {
+		t.Errorf("small result changed: %q, %v", got, err)
+	}
+
+	huge := strings.Repeat("line\n", 1000)
+	got, err := ShrinkResult(ctx, prov, limits, search, huge)
+	if err != nil || len(got) > 1100 || !strings.Contains(got, "omitted") {
+		t.Errorf("huge result not cut to head and tail: %d bytes, %v", len(got), err)
+	}
+	if len(prov.prompts) != 0 {
+		t.Error("a result over MaxBytes was summarized")
+	}
+
+	mid := strings.Repeat("match\n", 100)
+	got, err = ShrinkResult(ctx, prov, limits, search, mid)
+	if err != nil || !strings.Contains(got, "the user asked for turns 1 and 2") || !strings.Contains(got, "600 bytes of code_search") {
+		t.Errorf("mid-size result not summarized: %q, %v", got, err)
+	}
+	if len(prov.prompts) != 1 || !strings.Contains(prov.prompts[0], `{"pattern":"x"}`) {
+		t.Errorf("summarizing request lacks the call: %q", prov.prompts)
+	}
+
+	read := provider.ToolCall{Name: "read_file"}
+	if got, _ := ShrinkResult(ctx, prov, limits, read, mid); got != mid {
+		t.Error("read_file result was summarized")
+	}
+
+	if got, _ := ShrinkResult(ctx, prov, ResultLimits{}, search, huge); got != huge {
+		t.Error("zero limits changed the result")
+	}
+}
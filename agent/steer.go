@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+)
+
+// Steering queues notes the user sends while a turn is running. They are
+// delivered with the next request to the model in the same turn instead of
+// waiting for the turn to end. It is safe for concurrent use.
+type Steering struct {
+	mu    sync.Mutex
+	notes []string
+}
+
+// Add queues a note. Blank notes are ignored.
+func (s *Steering) Add(note string) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return
+	}
+	s.mu.Lock()
+	s.notes = append(s.notes, note)
+	s.mu.Unlock()
+}
+
+// Take removes the queued notes and returns them as one message for the
+// model, or "" if there are none. The marking tells the model the text is
+// a correction mid-task, not a new request.
+func (s *Steering) Take() string {
+	s.mu.Lock()
+	notes := s.notes
+	s.notes = nil
+	s.mu.Unlock()
+
+	if len(notes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("[Steering note from the user, sent while you were working. Take it into account from here on; it does not replace the original request.]")
+	for _, note := range notes {
+		b.WriteString("\n")
+		b.WriteString(note)
+	}
+	return b.String()
+}
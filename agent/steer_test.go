@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSteeringTake(t *testing.T) {
+	var s Steering
+	if got := s.Take(); got != "" {
+		t.Fatalf("empty Take() = %q, want \"\"", got)
+	}
+
+	s.Add("  ")
+	s.Add("use the v2 API")
+	s.Add("  and skip the docs\n")
+	got := s.Take()
+	if !strings.HasPrefix(got, "[Steering note") {
+		t.Errorf("Take() = %q, want the steering header first", got)
+	}
+	if !strings.HasSuffix(got, "]\nuse the v2 API\nand skip the docs") {
+		t.Errorf("Take() = %q, want one trimmed note per line", got)
+	}
+	if again := s.Take(); again != "" {
+		t.Errorf("second Take() = %q, want \"\"", again)
+	}
+}
@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+const (
+	// defaultSubagentSteps and maxSubagentSteps bound a subagent's rounds
+	// of tool calls.
+	defaultSubagentSteps = 20
+	maxSubagentSteps     = 50
+
+	// defaultSubagentTimeout and maxSubagentTimeout bound how long a
+	// subagent may run, in seconds.
+	defaultSubagentTimeout = 600
+	maxSubagentTimeout     = 1800
+)
+
+// subagentPrompt is a subagent's system prompt unless the caller gives one.
+const subagentPrompt = `You are a BRUTUS subagent: another agent has delegated one task to you. Do just that task with the tools you have, then reply with a self-contained report of what you found or did, including file paths and anything the other agent needs to carry on. Nobody will read your intermediate messages and you cannot ask questions; make reasonable assumptions and state them.`
+
+// subagentReadOnly are the tools a subagent gets when the caller names
+// none: those that only read.
+var subagentReadOnly = []string{"read_file", "read_image", "list_files", "code_search", "semantic_search", "find_symbol", "imports_of", "dependents_of", "issue_fetch", "search_history"}
+
+// subagentExcluded are never given to a subagent: it has nobody to ask,
+// takes no part in coordination, and may not start subagents of its own.
+var subagentExcluded = map[string]bool{
+	"spawn_subagent":  true,
+	"ask_user":        true,
+	"agent_broadcast": true,
+	"observe_agents":  true,
+}
+
+// SubagentConfig is what the subagents spawn_subagent starts share with
+// the agent that starts them.
+type SubagentConfig struct {
+	Provider provider.Provider
+
+	// Tools is the parent's registry. Subagents get the tools they are
+	// asked for from it; they run without asking for approval, since the
+	// user approved the spawn_subagent call that named them.
+	Tools *tools.Registry
+
+	SystemPrompt string // Appended to the subagent's own prompt, e.g. project instructions
+	WorkingDir   string
+	Logger       *slog.Logger
+
+	// Output receives the subagents' tool progress lines, each prefixed
+	// with the subagent's name. Nil discards them.
+	Output io.Writer
+}
+
+// SpawnSubagentInput defines the parameters for the spawn_subagent tool.
+type SpawnSubagentInput struct {
+	Task         string         `json:"task" jsonschema_description:"What the subagent should do, with all the context it needs: it sees nothing of this conversation."`
+	Tools        []string       `json:"tools,omitempty" jsonschema_description:"Names of the tools it may use. Default: the read-only tools (read_file, list_files, code_search, find_symbol...)."`
+	SystemPrompt string         `json:"system_prompt,omitempty" jsonschema_description:"Instructions that replace the default subagent prompt, e.g. a role such as reviewer or tester."`
+	MaxSteps     int            `json:"max_steps,omitempty" jsonschema_description:"Rounds of tool calls it may take before it must answer (default 20, at most 50)."`
+	Timeout      int            `json:"timeout,omitempty" jsonschema_description:"Seconds it may run (default 600, at most 1800)."`
+	Schema       map[string]any `json:"schema,omitempty" jsonschema_description:"A JSON Schema the report must match. The report is then returned as JSON, for results you will process rather than read."`
+}
+
+var subagentCount atomic.Int64
+
+// NewSubagentTool returns spawn_subagent, which runs a task to completion
+// in a child agent with its own conversation, a subset of cfg.Tools and a
+// step budget, and returns the child's final message. The parent's
+// context window only holds the task and the report.
+func NewSubagentTool(cfg SubagentConfig) tools.Tool {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return tools.NewToolCtx[SpawnSubagentInput](
+		"spawn_subagent",
+		`Delegate a self-contained subtask to a subagent, which works on it with its own fresh conversation and returns a report. Use it for research that would fill your context (surveying a large codebase, reading many files) or for independent pieces of work, then act on the report.
+The subagent sees nothing of this conversation, so describe the task fully. It gets only read-only tools unless you name others, and cannot ask the user questions.`,
+		func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args SpawnSubagentInput
+			if err := json.Unmarshal(input, &args); err != nil {
+				return "", tools.NewError(tools.ErrInvalidInput, "invalid input: %v", err)
+			}
+			args.Task = strings.TrimSpace(args.Task)
+			if args.Task == "" {
+				return "", tools.NewError(tools.ErrInvalidInput, "task is required")
+			}
+			registry, err := subagentTools(cfg.Tools, args.Tools)
+			if err != nil {
+				return "", err
+			}
+			steps := defaultSubagentSteps
+			if args.MaxSteps > 0 {
+				steps = min(args.MaxSteps, maxSubagentSteps)
+			}
+			timeout := defaultSubagentTimeout
+			if args.Timeout > 0 {
+				timeout = min(args.Timeout, maxSubagentTimeout)
+			}
+			prompt := subagentPrompt
+			if args.SystemPrompt != "" {
+				prompt = args.SystemPrompt
+			}
+			if cfg.SystemPrompt != "" {
+				prompt += "\n\n" + cfg.SystemPrompt
+			}
+
+			name := fmt.Sprintf("subagent-%d", subagentCount.Add(1))
+			out := io.Discard
+			if cfg.Output != nil {
+				out = &prefixWriter{w: cfg.Output, prefix: "  [" + name + "] "}
+			}
+			child := New(Config{
+				Provider:     cfg.Provider,
+				Tools:        registry,
+				SystemPrompt: prompt,
+				WorkingDir:   cfg.WorkingDir,
+				Output:       out,
+				Logger:       logger.With("subagent", name),
+				TurnTimeout:  time.Duration(timeout) * time.Second,
+				MaxSteps:     steps,
+			})
+			// The tools belong to the parent, which closes them; only the
+			// child's conversation is released here.
+			defer child.conversation.Close()
+
+			logger.Info("subagent started", "subagent", name, "tools", strings.Join(registry.Names(), ","), "max_steps", steps)
+			start := time.Now()
+			answer, err := child.Prompt(ctx, args.Task)
+			logger.Info("subagent finished", "subagent", name, "duration_ms", time.Since(start).Milliseconds(), "error", err)
+			switch {
+			case errors.Is(err, ErrStepLimit):
+				return fmt.Sprintf("[%s used all %d steps without finishing.]\n%s", name, steps, answer), nil
+			case errors.Is(err, ErrTurnTimeout):
+				return fmt.Sprintf("[%s stopped after %ds without finishing.]\n%s", name, timeout, answer), nil
+			case err != nil:
+				return "", tools.WrapError(err, "%s failed", name)
+			case strings.TrimSpace(answer) == "":
+				return fmt.Sprintf("[%s finished without a report.]", name), nil
+			case args.Schema != nil:
+				return structuredReport(ctx, cfg.Provider, name, args, answer), nil
+			}
+			return answer, nil
+		},
+	)
+}
+
+// structuredReport has the model restate a subagent's report as JSON
+// matching args.Schema. If it can't, the report is returned as it is,
+// with why.
+func structuredReport(ctx context.Context, p provider.Provider, name string, args SpawnSubagentInput, report string) string {
+	data, err := provider.ChatStructured(ctx, p, provider.Schema{Name: "report", Schema: args.Schema},
+		"Restate the report below, written for the task before it, as JSON. Use only what the report says.",
+		[]provider.Message{{Role: "user", Content: "Task:\n" + args.Task + "\n\nReport:\n" + report}})
+	if err != nil {
+		return fmt.Sprintf("[%s's report could not be put in the schema: %v]\n%s", name, err, report)
+	}
+	return string(data)
+}
+
+// subagentTools builds a subagent's registry from the parent's: the named
+// tools, or the read-only ones when none are named.
+func subagentTools(parent *tools.Registry, names []string) (*tools.Registry, error) {
+	registry := tools.NewRegistry()
+	if parent == nil {
+		return registry, nil
+	}
+	explicit := len(names) > 0
+	if !explicit {
+		names = subagentReadOnly
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if subagentExcluded[name] {
+			return nil, tools.NewError(tools.ErrPermissionDenied, "subagents cannot use %s", name)
+		}
+		t, ok := parent.Get(name)
+		if !ok {
+			if !explicit {
+				continue
+			}
+			return nil, tools.NewError(tools.ErrInvalidInput, "unknown tool %q (available: %s)", name, strings.Join(parent.Names(), ", "))
+		}
+		registry.Register(t)
+	}
+	return registry, nil
+}
+
+// prefixWriter starts every line written through it with prefix, so a
+// subagent's progress can be told apart from its parent's.
+type prefixWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if !p.midLine {
+			buf.WriteString(p.prefix)
+		}
+		buf.Write(line)
+		p.midLine = line[len(line)-1] != '\n'
+	}
+	if _, err := p.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+// readingProvider asks to read a file on each request until it has made
+// reads requests, then reports what the last read returned. It records
+// the tools it was offered.
+type readingProvider struct {
+	stallingProvider
+	path  string
+	reads int
+
+	mu      sync.Mutex
+	calls   int
+	offered []string
+}
+
+func (p *readingProvider) ChatStream(ctx context.Context, systemPrompt string, messages []provider.Message, toolDefs []tools.Tool) (<-chan provider.StreamDelta, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offered = nil
+	for _, t := range toolDefs {
+		p.offered = append(p.offered, t.Name)
+	}
+	ch := make(chan provider.StreamDelta, 1)
+	if p.calls < p.reads {
+		p.calls++
+		input, _ := json.Marshal(map[string]string{"path": p.path})
+		ch <- provider.StreamDelta{Done: true, ToolCalls: []provider.ToolCall{{ID: "call", Name: "read_file", Input: input}}}
+	} else {
+		last := messages[len(messages)-1]
+		report := "no results"
+		if len(last.ToolResults) > 0 {
+			report = "report: " + last.ToolResults[0].Content
+		}
+		ch <- provider.StreamDelta{Content: report, Done: true}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestSpawnSubagent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("the answer is 42"), 0644)
+
+	parent := tools.NewRegistry()
+	parent.Register(tools.ReadFileTool)
+	parent.Register(tools.EditFileTool)
+	prov := &readingProvider{path: path, reads: 1}
+	spawn := NewSubagentTool(SubagentConfig{Provider: prov, Tools: parent, WorkingDir: dir})
+
+	report, err := spawn.Function(json.RawMessage(`{"task": "Find the answer in notes.txt"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(report, "report:") || !strings.Contains(report, "the answer is 42") {
+		t.Errorf("report = %q", report)
+	}
+	// By default a subagent only gets the tools that read.
+	for _, name := range prov.offered {
+		if name == "edit_file" || name == "spawn_subagent" {
+			t.Errorf("subagent was offered %s", name)
+		}
+	}
+
+	if _, err := spawn.Function(json.RawMessage(`{"task": "x", "tools": ["ask_user"]}`)); tools.CodeOf(err) != tools.ErrPermissionDenied {
+		t.Errorf("ask_user for a subagent: %v", err)
+	}
+	if _, err := spawn.Function(json.RawMessage(`{"task": "x", "tools": ["nope"]}`)); tools.CodeOf(err) != tools.ErrInvalidInput {
+		t.Errorf("unknown tool: %v", err)
+	}
+}
+
+func TestSpawnSubagentStepLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("more"), 0644)
+
+	parent := tools.NewRegistry()
+	parent.Register(tools.ReadFileTool)
+	prov := &readingProvider{path: path, reads: 100}
+	spawn := NewSubagentTool(SubagentConfig{Provider: prov, Tools: parent, WorkingDir: dir, Output: io.Discard})
+
+	report, err := spawn.Function(json.RawMessage(`{"task": "Read forever", "max_steps": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report, "used all 2 steps") {
+		t.Errorf("report = %q", report)
+	}
+	// Two rounds of reads, then one refused round, then the turn ends.
+	if prov.calls != 4 {
+		t.Errorf("provider asked for tools %d times, want 4", prov.calls)
+	}
+}
+
+// answeringProvider is a readingProvider that answers requests for
+// structured output with reply.
+type answeringProvider struct {
+	readingProvider
+	reply string
+}
+
+func (p *answeringProvider) Chat(ctx context.Context, systemPrompt string, messages []provider.Message, toolDefs []tools.Tool) (provider.Message, error) {
+	return provider.Message{Role: "assistant", Content: p.reply}, nil
+}
+
+func TestSpawnSubagentSchema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("the answer is 42"), 0644)
+	parent := tools.NewRegistry()
+	parent.Register(tools.ReadFileTool)
+	input := json.RawMessage(`{"task": "Find the answer", "schema": {"type": "object", "properties": {"answer": {"type": "integer"}}, "required": ["answer"]}}`)
+
+	prov := &answeringProvider{readingProvider: readingProvider{path: path, reads: 1}, reply: `{"answer": 42}`}
+	report, err := NewSubagentTool(SubagentConfig{Provider: prov, Tools: parent, WorkingDir: dir}).Function(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != `{"answer": 42}` {
+		t.Errorf("report = %q", report)
+	}
+
+	// A report that can't be put in the schema comes back as it was.
+	prov = &answeringProvider{readingProvider: readingProvider{path: path, reads: 1}, reply: "forty-two"}
+	report, err = NewSubagentTool(SubagentConfig{Provider: prov, Tools: parent, WorkingDir: dir}).Function(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report, "could not be put in the schema") || !strings.Contains(report, "the answer is 42") {
+		t.Errorf("report = %q", report)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var b strings.Builder
+	w := &prefixWriter{w: &b, prefix: "> "}
+	w.Write([]byte("one\ntw"))
+	w.Write([]byte("o\nthree\n"))
+	if got := b.String(); got != "> one\n> two\n> three\n" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+// stallingProvider streams part of an answer and then stalls until the
+// request is cancelled, unless stall is off.
+type stallingProvider struct {
+	stall    bool
+	requests [][]provider.Message
+}
+
+func (p *stallingProvider) Chat(ctx context.Context, systemPrompt string, messages []provider.Message, toolDefs []tools.Tool) (provider.Message, error) {
+	return provider.Message{}, errors.New("not used")
+}
+
+func (p *stallingProvider) ChatStream(ctx context.Context, systemPrompt string, messages []provider.Message, toolDefs []tools.Tool) (<-chan provider.StreamDelta, error) {
+	p.requests = append(p.requests, messages)
+	ch := make(chan provider.StreamDelta, 2)
+	go func() {
+		defer close(ch)
+		ch <- provider.StreamDelta{Content: "half an answer"}
+		if p.stall {
+			<-ctx.Done()
+			return
+		}
+		ch <- provider.StreamDelta{Content: ", then the rest", Done: true}
+	}()
+	return ch, nil
+}
+
+func (p *stallingProvider) Name() string { return "stalling" }
+
+func (p *stallingProvider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	return nil, nil
+}
+
+func (p *stallingProvider) SetModel(model string) {}
+
+func (p *stallingProvider) GetModel() string { return "" }
+
+func (p *stallingProvider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{Streaming: true, Tools: true}
+}
+
+func TestTurnTimeoutKeepsPartialResponse(t *testing.T) {
+	prov := &stallingProvider{stall: true}
+	a := New(Config{
+		Provider:    prov,
+		Tools:       tools.NewRegistry(),
+		Output:      io.Discard,
+		WorkingDir:  t.TempDir(),
+		TurnTimeout: 50 * time.Millisecond,
+	})
+	defer a.Close()
+
+	start := time.Now()
+	answer, err := a.Prompt(context.Background(), "hello")
+	if !errors.Is(err, ErrTurnTimeout) {
+		t.Fatalf("expected ErrTurnTimeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("the turn was not stopped at its timeout")
+	}
+	if !strings.HasPrefix(answer, "half an answer") || !strings.Contains(answer, "Turn stopped") {
+		t.Errorf("expected the partial text and a notice, got %q", answer)
+	}
+
+	prov.stall = false
+	answer, err = a.Prompt(context.Background(), "go on")
+	if err != nil {
+		t.Fatalf("next turn failed: %v", err)
+	}
+	if answer != "half an answer, then the rest" {
+		t.Errorf("unexpected answer %q", answer)
+	}
+	sent := prov.requests[len(prov.requests)-1]
+	if len(sent) != 3 || sent[1].Role != "assistant" || !strings.Contains(sent[1].Content, "Turn stopped") {
+		t.Errorf("expected the notice in the conversation, got %+v", sent)
+	}
+}
+
+func TestInterruptKeepsPartialResponse(t *testing.T) {
+	prov := &stallingProvider{stall: true}
+	var out strings.Builder
+	a := New(Config{
+		Provider:   prov,
+		Tools:      tools.NewRegistry(),
+		Output:     &out,
+		WorkingDir: t.TempDir(),
+	})
+	defer a.Close()
+	a.live = true
+
+	go func() {
+		for !a.Interrupt() {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	response, err := a.interactiveTurn(context.Background(), "hello")
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+	if !strings.HasPrefix(response.Content, "half an answer") || !strings.Contains(response.Content, "interrupted") {
+		t.Errorf("expected the partial text and a notice, got %q", response.Content)
+	}
+	if !strings.Contains(out.String(), "half an answer\n") {
+		t.Errorf("expected the partial text to be streamed, got %q", out.String())
+	}
+	if a.Interrupt() {
+		t.Error("Interrupt reported a turn after it ended")
+	}
+	if n := a.conversation.Len(); n != 2 {
+		t.Errorf("expected the user message and the notice, got %d messages", n)
+	}
+}
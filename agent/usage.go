@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"fmt"
+
+	"brutus/internal/theme"
+	"brutus/provider"
+)
+
+// addUsage adds a response's usage to the session's totals.
+func (a *Agent) addUsage(u *provider.Usage) {
+	if u == nil {
+		return
+	}
+	a.usageMu.Lock()
+	defer a.usageMu.Unlock()
+	a.usage.Add(*u)
+	a.usageResponses++
+}
+
+// Usage returns the tokens and cost of the conversation's responses so
+// far, as the services reported them, including those of a resumed
+// session. Side requests, such as for summaries and titles, are not
+// counted.
+func (a *Agent) Usage() provider.Usage {
+	a.usageMu.Lock()
+	defer a.usageMu.Unlock()
+	return a.usage
+}
+
+func (a *Agent) handleCostCommand() {
+	a.usageMu.Lock()
+	u, n := a.usage, a.usageResponses
+	a.usageMu.Unlock()
+	if n == 0 {
+		fmt.Println(theme.Muted("No token usage reported yet."))
+		return
+	}
+	fmt.Println(theme.Title("Session usage:") + fmt.Sprintf(" %d responses", n))
+	fmt.Printf("  %-12s %d\n", "prompt", u.PromptTokens)
+	fmt.Printf("  %-12s %d\n", "completion", u.CompletionTokens)
+	fmt.Printf("  %-12s %d\n", "total", u.Total())
+	if u.CacheReadTokens > 0 || u.CacheWriteTokens > 0 {
+		fmt.Printf("  %-12s %d read, %d written (%.0f%% of prompt tokens read)\n", "cache",
+			u.CacheReadTokens, u.CacheWriteTokens, 100*float64(u.CacheReadTokens)/float64(max(u.PromptTokens, 1)))
+	}
+	if u.Cost > 0 {
+		fmt.Printf("  %-12s $%.4f\n", "cost", u.Cost)
+	} else {
+		fmt.Printf("  %-12s %s\n", "cost", theme.Muted("none known (local or unpriced model)"))
+	}
+}
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+// usageProvider reports the same usage for every response.
+type usageProvider struct {
+	stallingProvider
+}
+
+func (p *usageProvider) Chat(ctx context.Context, systemPrompt string, messages []provider.Message, toolDefs []tools.Tool) (provider.Message, error) {
+	return provider.Message{Role: "assistant", Content: "done", Usage: &provider.Usage{PromptTokens: 100, CompletionTokens: 10, Cost: 0.01}}, nil
+}
+
+func TestUsageTotals(t *testing.T) {
+	history := []provider.Message{
+		{Role: "user", Content: "earlier"},
+		{Role: "assistant", Content: "ok", Usage: &provider.Usage{PromptTokens: 50, CompletionTokens: 5}},
+	}
+	a := New(Config{Provider: &usageProvider{}, Tools: tools.NewRegistry(), Output: io.Discard, WorkingDir: t.TempDir(), History: history})
+	defer a.Close()
+
+	for range 2 {
+		if _, err := a.Prompt(context.Background(), "hi"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u := a.Usage()
+	if u.PromptTokens != 250 || u.CompletionTokens != 25 || u.Cost != 0.02 {
+		t.Errorf("usage = %+v, want the resumed response and two new ones", u)
+	}
+}
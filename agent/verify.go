@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+	"brutus/internal/theme"
+	"brutus/tools"
+)
+
+const (
+	// MaxVerifyRounds is how many times failing checks are sent back to
+	// the model in one turn before the turn ends with them still failing.
+	MaxVerifyRounds = 3
+
+	// verifyTimeout bounds each verification command.
+	verifyTimeout = 5 * time.Minute
+
+	// verifyExcerpt caps how much of a failing command's output the model
+	// is sent, keeping both ends.
+	verifyExcerpt = 4000
+)
+
+// VerifyResult is the outcome of one verification command.
+type VerifyResult struct {
+	Command  string
+	Output   string
+	Passed   bool
+	Duration time.Duration
+}
+
+// RunVerification runs each command in dir with the shell the bash tool
+// uses, stopping early only if ctx is cancelled.
+func RunVerification(ctx context.Context, dir string, commands []string) []VerifyResult {
+	results := make([]VerifyResult, 0, len(commands))
+	for _, command := range commands {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		cmdCtx, cancel := context.WithTimeout(ctx, verifyTimeout)
+		cmd := tools.ShellCommand(cmdCtx, command)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		cancel()
+
+		out := strings.TrimSpace(string(output))
+		if err != nil {
+			out = strings.TrimSpace(fmt.Sprintf("%s\n%v", out, err))
+		}
+		results = append(results, VerifyResult{
+			Command:  command,
+			Output:   out,
+			Passed:   err == nil,
+			Duration: time.Since(start),
+		})
+	}
+	return results
+}
+
+// VerificationReport tells the model which checks failed and asks it to
+// fix them. It returns "" if they all passed.
+func VerificationReport(results []VerifyResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("[verification] These checks failed after you finished. Fix the problems, then reply when you are done.\n")
+		}
+		fmt.Fprintf(&b, "\n$ %s\n%s\n", r.Command, text.HeadTail(r.Output, verifyExcerpt))
+	}
+	return b.String()
+}
+
+// verify runs the verification commands once the model is done with a turn
+// that used tools, and returns the report to send back to it, or "" to end
+// the turn: when the checks pass, or still fail after MaxVerifyRounds
+// attempts to fix them.
+func (a *Agent) verify(ctx context.Context, logger *slog.Logger) string {
+	if len(a.verifyCommands) == 0 || a.turnToolCalls == 0 {
+		return ""
+	}
+	a.verifyRounds++
+	fmt.Fprintf(a.out, "%s running %d check(s)\n", theme.Tool("[verify]"), len(a.verifyCommands))
+	results := RunVerification(ctx, a.workingDir, a.verifyCommands)
+
+	failed := 0
+	for _, r := range results {
+		label := theme.Success("[pass]")
+		if !r.Passed {
+			label = theme.Error("[fail]")
+			failed++
+		}
+		fmt.Fprintf(a.out, "%s %s %s\n", label, r.Command, theme.Muted(r.Duration.Round(100*time.Millisecond).String()))
+	}
+	logger.Info("verification", "round", a.verifyRounds, "checks", len(results), "failed", failed)
+
+	switch {
+	case failed == 0:
+		return ""
+	case a.verifyRounds > MaxVerifyRounds:
+		fmt.Fprintf(a.out, "%s checks still failing after %d attempts to fix them\n", theme.Warning("[verify]"), MaxVerifyRounds)
+		return ""
+	}
+	return VerificationReport(results)
+}
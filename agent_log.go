@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// agentLogSize is how many log entries each GUI agent keeps in memory.
+const agentLogSize = 1000
+
+type LogEntry struct {
+	Seq      int64     `json:"seq"`
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	Category string    `json:"category"`
+	Message  string    `json:"message"`
+}
+
+// logRing is a fixed-size ring buffer of log entries with monotonically
+// increasing sequence numbers, so clients can poll for entries they have
+// not seen yet.
+type logRing struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	start   int
+	seq     int64
+	verbose bool
+}
+
+func newLogRing(size int) *logRing {
+	return &logRing{entries: make([]LogEntry, 0, size)}
+}
+
+func (r *logRing) add(level, category, message string) (LogEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if level == "debug" && !r.verbose {
+		return LogEntry{}, false
+	}
+
+	r.seq++
+	entry := LogEntry{
+		Seq:      r.seq,
+		Time:     time.Now(),
+		Level:    level,
+		Category: category,
+		Message:  message,
+	}
+
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, entry)
+	} else {
+		r.entries[r.start] = entry
+		r.start = (r.start + 1) % len(r.entries)
+	}
+	return entry, true
+}
+
+func (r *logRing) since(seq int64) []LogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []LogEntry{}
+	for i := 0; i < len(r.entries); i++ {
+		entry := r.entries[(r.start+i)%len(r.entries)]
+		if entry.Seq > seq {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func (r *logRing) setVerbose(v bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verbose = v
+}
+
+func (r *logRing) isVerbose() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.verbose
+}
+
+func (g *GUIAgent) logf(level, category, format string, args ...interface{}) {
+	entry, ok := g.logs.add(level, category, fmt.Sprintf(format, args...))
+	if !ok {
+		return
+	}
+	runtime.EventsEmit(g.appCtx, "agent:log", map[string]interface{}{
+		"id":    g.id,
+		"entry": entry,
+	})
+}
+
+func (g *GUIAgent) GetLogs(since int64) []LogEntry {
+	return g.logs.since(since)
+}
+
+func (g *GUIAgent) SetVerbose(v bool) {
+	g.logs.setVerbose(v)
+	state := "disabled"
+	if v {
+		state = "enabled"
+	}
+	g.logf("info", "agent", "verbose logging %s", state)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// agentSlots limits how many GUI agents work on a turn at once, so agents
+// sharing one local GPU server take turns instead of all slowing down or
+// running it out of memory. Agents over the limit wait in the order they
+// asked.
+type agentSlots struct {
+	mu      sync.Mutex
+	limit   int // 0 is unlimited
+	running int
+	waiting []*slotWaiter
+
+	// notify is told each waiting agent's place in the queue, from 1,
+	// whenever it changes, and 0 when the agent gets its slot after
+	// waiting. It is called without mu held.
+	notify func(id string, position int)
+}
+
+type slotWaiter struct {
+	id    string
+	ready chan struct{}
+}
+
+type slotUpdate struct {
+	id       string
+	position int
+}
+
+func newAgentSlots(limit int, notify func(id string, position int)) *agentSlots {
+	return &agentSlots{limit: max(limit, 0), notify: notify}
+}
+
+// acquire waits until agent id may run, or ctx ends. Every successful
+// acquire must be paired with a release.
+func (s *agentSlots) acquire(ctx context.Context, id string) error {
+	s.mu.Lock()
+	if len(s.waiting) == 0 && s.freeLocked() {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	w := &slotWaiter{id: id, ready: make(chan struct{})}
+	s.waiting = append(s.waiting, w)
+	updates := s.positionsLocked()
+	s.mu.Unlock()
+	s.send(updates)
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	select {
+	case <-w.ready:
+		// Granted just as ctx ended; pass the slot on.
+		s.mu.Unlock()
+		s.release()
+		return ctx.Err()
+	default:
+	}
+	for i, other := range s.waiting {
+		if other == w {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			break
+		}
+	}
+	updates = s.positionsLocked()
+	s.mu.Unlock()
+	s.send(updates)
+	return ctx.Err()
+}
+
+// release gives a slot back, to the longest waiting agent if any.
+func (s *agentSlots) release() {
+	s.mu.Lock()
+	s.running--
+	updates := s.grantLocked()
+	s.mu.Unlock()
+	s.send(updates)
+}
+
+// setLimit changes how many agents may run at once. Raising it starts
+// waiting agents straight away; lowering it lets running ones finish.
+func (s *agentSlots) setLimit(limit int) {
+	s.mu.Lock()
+	s.limit = max(limit, 0)
+	updates := s.grantLocked()
+	s.mu.Unlock()
+	s.send(updates)
+}
+
+func (s *agentSlots) getLimit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+func (s *agentSlots) freeLocked() bool {
+	return s.limit == 0 || s.running < s.limit
+}
+
+// grantLocked hands free slots to waiting agents in order and returns the
+// changes to report.
+func (s *agentSlots) grantLocked() []slotUpdate {
+	var updates []slotUpdate
+	granted := 0
+	for len(s.waiting) > 0 && s.freeLocked() {
+		w := s.waiting[0]
+		s.waiting = s.waiting[1:]
+		s.running++
+		close(w.ready)
+		updates = append(updates, slotUpdate{w.id, 0})
+		granted++
+	}
+	if granted == 0 {
+		return nil
+	}
+	return append(updates, s.positionsLocked()...)
+}
+
+func (s *agentSlots) positionsLocked() []slotUpdate {
+	updates := make([]slotUpdate, len(s.waiting))
+	for i, w := range s.waiting {
+		updates[i] = slotUpdate{w.id, i + 1}
+	}
+	return updates
+}
+
+func (s *agentSlots) send(updates []slotUpdate) {
+	if s.notify == nil {
+		return
+	}
+	for _, u := range updates {
+		s.notify(u.id, u.position)
+	}
+}
@@ -2,11 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
+	"brutus/agent"
+	"brutus/audit"
+	"brutus/config"
 	"brutus/coordinator"
+	"brutus/provider"
+	"brutus/session"
+	"brutus/telemetry"
+	"brutus/tools"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -17,6 +26,22 @@ type App struct {
 	guiAgents  map[string]*GUIAgent
 	sessionsMu sync.RWMutex
 	ptyManager *PTYManager
+
+	// discovery, once set from the GUI, replaces the configured filter
+	// for agents created afterwards.
+	discovery *config.Discovery
+
+	// slots limits how many agents run a turn at once.
+	slots *agentSlots
+
+	// audit records every agent's tool calls, when audit_log is set.
+	audit *audit.Log
+
+	// warm is closed once the discovery started at startup has finished,
+	// so agents created before then wait for it rather than browse too.
+	warm chan struct{}
+
+	stopTracing func(context.Context) error
 }
 
 type AgentSession struct {
@@ -28,6 +53,18 @@ type AgentSession struct {
 	ServiceName string        `json:"serviceName"`
 	ServiceHost string        `json:"serviceHost"`
 	Connected   bool          `json:"connected"`
+	TokenBudget int           `json:"tokenBudget"`
+	TokensUsed  int           `json:"tokensUsed"`
+	CostBudget  float64       `json:"costBudget"`
+	Title       string        `json:"title,omitempty"`
+	Summary     string        `json:"summary,omitempty"`
+	Parent      string        `json:"parent,omitempty"` // agent this one was forked from
+	// QueuePosition is the agent's place in line for a slot while its
+	// status is "queued".
+	QueuePosition int `json:"queuePosition,omitempty"`
+	// SystemPrompt is the prompt chosen for this agent; empty means the
+	// project's BRUTUS.md.
+	SystemPrompt string `json:"systemPrompt,omitempty"`
 }
 
 type ChatMessage struct {
@@ -36,17 +73,85 @@ type ChatMessage struct {
 }
 
 func NewApp() *App {
-	return &App{
+	a := &App{
 		sessions:   make(map[string]*AgentSession),
 		guiAgents:  make(map[string]*GUIAgent),
 		ptyManager: NewPTYManager(),
 	}
+	a.slots = newAgentSlots(0, a.agentQueued)
+	return a
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.ptyManager.SetContext(ctx)
 	a.startCoordinationBroadcast()
+	a.startWarmDiscovery()
+
+	// All GUI agents share one limiter per Saturn service and the same
+	// post-edit checks, tool retry policies and tool policies.
+	if cfg, err := config.Load(); err == nil {
+		applyRateLimits(cfg)
+		tools.ConfigureDiagnostics(cfg.Diagnostics)
+		applyToolRetries(cfg)
+		applyForges(cfg)
+		tools.ConfigureSandbox(cfg.Sandbox.Root, cfg.Sandbox.Allow)
+		tools.ConfigureTools(toolPolicies(cfg))
+		a.slots.setLimit(cfg.MaxRunningAgents)
+		if cfg.AuditLog != "" {
+			if a.audit, err = audit.Open(cfg.AuditLog); err != nil {
+				log.Printf("audit log disabled: %v", err)
+			}
+		}
+	} else {
+		log.Printf("ignoring config: %v", err)
+		tools.ConfigureSandbox("", nil)
+	}
+
+	stopTracing, err := telemetry.Setup(ctx, Version)
+	if err != nil {
+		log.Printf("tracing disabled: %v", err)
+		return
+	}
+	a.stopTracing = stopTracing
+}
+
+// shutdown is the Wails OnShutdown hook. It stops every agent so their
+// coordinators unregister from mDNS, kills PTY shells, and tears down any
+// broadcasts the agents' tools started.
+func (a *App) shutdown(ctx context.Context) {
+	a.sessionsMu.RLock()
+	agents := make([]*GUIAgent, 0, len(a.guiAgents))
+	for _, guiAgent := range a.guiAgents {
+		agents = append(agents, guiAgent)
+	}
+	a.sessionsMu.RUnlock()
+
+	for _, guiAgent := range agents {
+		guiAgent.Stop()
+	}
+	a.ptyManager.Close()
+	tools.ShutdownAllBroadcasts()
+	provider.StopWarmDiscovery()
+	if a.stopTracing != nil {
+		a.stopTracing(ctx)
+	}
+}
+
+// startWarmDiscovery browses for Saturn services once, in the background,
+// and keeps the results fresh, so each new agent connects to a cached
+// service instead of spending seconds on its own discovery.
+func (a *App) startWarmDiscovery() {
+	a.warm = make(chan struct{})
+	go func() {
+		defer close(a.warm)
+		services, err := provider.WarmDiscovery(a.ctx, 3*time.Second)
+		if err != nil {
+			log.Printf("warm discovery: %v", err)
+			return
+		}
+		log.Printf("warm discovery: %d saturn services", len(services))
+	}()
 }
 
 func (a *App) startCoordinationBroadcast() {
@@ -129,6 +234,15 @@ func (a *App) NewAgent(model string) (string, error) {
 }
 
 func (a *App) NewNamedAgent(name string, model string) (string, error) {
+	return a.newAgent(name, model, nil)
+}
+
+// newAgent creates and registers an agent. init, if given, prepares it
+// before the GUI is told it exists.
+func (a *App) newAgent(name, model string, init func(*GUIAgent, *AgentSession) error) (string, error) {
+	if a.warm != nil {
+		<-a.warm
+	}
 	a.sessionsMu.Lock()
 	defer a.sessionsMu.Unlock()
 
@@ -141,16 +255,60 @@ func (a *App) NewNamedAgent(name string, model string) (string, error) {
 		return "", fmt.Errorf("agent with id '%s' already exists", id)
 	}
 
-	guiAgent, err := NewGUIAgent(a.ctx, id, model)
+	// Fall back to the model pinned in config rather than the beacon
+	// default. A pinned service applies to every agent.
+	saturnCfg := provider.SaturnConfig{Model: model, Cached: true, Retry: provider.DefaultRetryPolicy}
+	cacheTools := true
+	artifactThreshold := tools.DefaultArtifactThreshold
+	results := agent.ResultLimits{MaxBytes: agent.DefaultResultLimit}
+	scanOutput := true
+	var env map[string]string
+	var toolEnv map[string]map[string]string
+	if cfg, err := config.Load(); err == nil {
+		if saturnCfg.Model == "" {
+			saturnCfg.Model = cfg.Model
+		}
+		saturnCfg.Service = cfg.Service
+		saturnCfg.Sticky = cfg.Sticky != nil && *cfg.Sticky
+		saturnCfg.Endpoint = cfg.Endpoint
+		saturnCfg.EmbeddingModel = cfg.EmbeddingModel
+		saturnCfg.APIKey = cfg.APIKey
+		saturnCfg.Filter = discoveryFilter(cfg.Discovery)
+		saturnCfg.Scorer = serviceScorer(cfg.ServiceWeights)
+		cacheTools = cfg.ToolCache == nil || *cfg.ToolCache
+		saturnCfg.ToolCalling = cfg.ToolCalling
+		saturnCfg.ThinkingBudget = cfg.ThinkingBudget
+		saturnCfg.Retry = requestRetry(cfg)
+		saturnCfg.Options = sampling(cfg)
+		if cfg.ArtifactThreshold != 0 {
+			artifactThreshold = cfg.ArtifactThreshold
+		}
+		env, toolEnv = cfg.Env, cfg.ToolEnv
+		if cfg.ToolResults.MaxBytes != 0 {
+			results.MaxBytes = cfg.ToolResults.MaxBytes
+		}
+		results.SummarizeBytes = cfg.ToolResults.SummarizeBytes
+		results.SummaryTokens = cfg.ToolResults.SummaryTokens
+		scanOutput = cfg.InjectionCheck == nil || *cfg.InjectionCheck
+	}
+	if a.discovery != nil {
+		saturnCfg.Filter = discoveryFilter(*a.discovery)
+	}
+
+	guiAgent, err := NewGUIAgent(a.ctx, id, saturnCfg, cacheTools, artifactThreshold)
 	if err != nil {
 		return "", err
 	}
+	guiAgent.audit = a.audit
+	guiAgent.resultLimits = results
+	guiAgent.scanOutput = scanOutput
+	// Each agent has its own tools, so the variables stay with it.
+	guiAgent.tools.SetEnv(env, toolEnv)
 
 	session := &AgentSession{
 		ID:       id,
 		Model:    model,
 		Status:   "idle",
-		Cost:     0,
 		Messages: []ChatMessage{},
 	}
 
@@ -160,6 +318,13 @@ func (a *App) NewNamedAgent(name string, model string) (string, error) {
 		session.Connected = true
 	}
 
+	if init != nil {
+		if err := init(guiAgent, session); err != nil {
+			guiAgent.Stop()
+			return "", err
+		}
+	}
+
 	a.sessions[id] = session
 	a.guiAgents[id] = guiAgent
 
@@ -167,6 +332,81 @@ func (a *App) NewNamedAgent(name string, model string) (string, error) {
 	return id, nil
 }
 
+// ForkSession starts a new agent with a copy of an agent's conversation,
+// so two approaches can be tried from the same point and compared. The
+// agent must be idle, so the fork starts between turns.
+func (a *App) ForkSession(agentID string) (string, error) {
+	a.sessionsMu.RLock()
+	src, ok := a.sessions[agentID]
+	srcAgent := a.guiAgents[agentID]
+	if !ok || srcAgent == nil {
+		a.sessionsMu.RUnlock()
+		return "", fmt.Errorf("agent not found: %s", agentID)
+	}
+	model, status, prompt := src.Model, src.Status, src.SystemPrompt
+	a.sessionsMu.RUnlock()
+	if status == "running" || status == "queued" {
+		return "", fmt.Errorf("agent %s is busy; fork it between turns", agentID)
+	}
+
+	records, err := srcAgent.conversation.History()
+	if err != nil {
+		return "", err
+	}
+	info := srcAgent.Info()
+
+	return a.newAgent("", model, func(g *GUIAgent, session *AgentSession) error {
+		if err := g.LoadHistory(records, info); err != nil {
+			return err
+		}
+		if prompt != "" {
+			g.SetSystemPrompt(prompt)
+		}
+		session.Parent = agentID
+		session.SystemPrompt = prompt
+		session.Title = info.Title
+		session.Summary = info.Summary
+		session.Messages = chatMessages(records)
+		return nil
+	})
+}
+
+// chatMessages converts records to the messages an agent panel shows:
+// prompts and replies, without tool traffic.
+func chatMessages(records []session.Record) []ChatMessage {
+	messages := []ChatMessage{}
+	for _, r := range records {
+		msg := r.Message
+		if msg.Content == "" || len(msg.ToolResults) > 0 || (msg.Role != "user" && msg.Role != "assistant") {
+			continue
+		}
+		messages = append(messages, ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return messages
+}
+
+// describeAgent updates an agent's title and summary after a turn, so the
+// agent list shows what each one is working on.
+func (a *App) describeAgent(agentID string, guiAgent *GUIAgent, prompt string) {
+	info, ok := guiAgent.Describe(prompt)
+	if !ok {
+		return
+	}
+
+	a.sessionsMu.Lock()
+	if session, exists := a.sessions[agentID]; exists {
+		session.Title = info.Title
+		session.Summary = info.Summary
+	}
+	a.sessionsMu.Unlock()
+
+	runtime.EventsEmit(a.ctx, "agent:described", map[string]string{
+		"id":      agentID,
+		"title":   info.Title,
+		"summary": info.Summary,
+	})
+}
+
 func (a *App) GetAgents() []*AgentSession {
 	a.sessionsMu.RLock()
 	defer a.sessionsMu.RUnlock()
@@ -179,12 +419,31 @@ func (a *App) GetAgents() []*AgentSession {
 }
 
 func (a *App) SendMessage(agentID, message string) error {
+	run, err := a.prepareMessage(agentID, message)
+	if err != nil {
+		return err
+	}
+	go run()
+	return nil
+}
+
+// sendMessageSync behaves like SendMessage but blocks until the agent has
+// finished processing the message.
+func (a *App) sendMessageSync(agentID, message string) error {
+	run, err := a.prepareMessage(agentID, message)
+	if err != nil {
+		return err
+	}
+	return run()
+}
+
+func (a *App) prepareMessage(agentID, message string) (func() error, error) {
 	a.sessionsMu.Lock()
 	session, ok := a.sessions[agentID]
 	guiAgent := a.guiAgents[agentID]
 	if !ok || guiAgent == nil {
 		a.sessionsMu.Unlock()
-		return fmt.Errorf("agent not found: %s", agentID)
+		return nil, fmt.Errorf("agent not found: %s", agentID)
 	}
 
 	session.Messages = append(session.Messages, ChatMessage{
@@ -199,12 +458,36 @@ func (a *App) SendMessage(agentID, message string) error {
 		"status": "running",
 	})
 
-	go func() {
-		err := guiAgent.SendMessage(message)
+	return func() error {
+		err := a.slots.acquire(guiAgent.ctx, agentID)
+		if err == nil {
+			err = guiAgent.SendMessage(message)
+			a.slots.release()
+		}
 
 		a.sessionsMu.Lock()
 		session.Status = "idle"
-		if err != nil {
+		session.QueuePosition = 0
+		usage := guiAgent.GetTokenUsage()
+		session.TokensUsed = usage.Used
+		session.Cost = usage.Cost
+		if errors.Is(err, ErrBudgetExhausted) {
+			notice := fmt.Sprintf("Budget exhausted: used %d of %d tokens. Raise the budget to continue.", usage.Used, usage.Budget)
+			if usage.Budget == 0 || usage.Used < usage.Budget {
+				notice = fmt.Sprintf("Budget exhausted: spent $%.2f of $%.2f. Raise the budget to continue.", usage.Cost, usage.CostBudget)
+			}
+			session.Messages = append(session.Messages, ChatMessage{
+				Role:    "assistant",
+				Content: notice,
+			})
+			a.sessionsMu.Unlock()
+
+			runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
+				"id":      agentID,
+				"role":    "assistant",
+				"content": notice,
+			})
+		} else if err != nil {
 			errMsg := fmt.Sprintf("Error: %s", err)
 			session.Messages = append(session.Messages, ChatMessage{
 				Role:    "assistant",
@@ -218,15 +501,15 @@ func (a *App) SendMessage(agentID, message string) error {
 			})
 		} else {
 			a.sessionsMu.Unlock()
+			go a.describeAgent(agentID, guiAgent, message)
 		}
 
 		runtime.EventsEmit(a.ctx, "agent:status", map[string]string{
 			"id":     agentID,
 			"status": "idle",
 		})
-	}()
-
-	return nil
+		return err
+	}, nil
 }
 
 func (a *App) StopAgent(agentID string) error {
@@ -251,7 +534,60 @@ func (a *App) StopAgent(agentID string) error {
 	return nil
 }
 
-func (a *App) RespondToApproval(agentID, approvalID string, approved bool, reason string) error {
+func (a *App) SetTokenBudget(agentID string, budget int) error {
+	if budget < 0 {
+		return fmt.Errorf("budget must not be negative")
+	}
+
+	a.sessionsMu.Lock()
+	defer a.sessionsMu.Unlock()
+
+	guiAgent, ok := a.guiAgents[agentID]
+	if !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+
+	guiAgent.SetTokenBudget(budget)
+	if session, exists := a.sessions[agentID]; exists {
+		session.TokenBudget = budget
+	}
+	return nil
+}
+
+// SetCostBudget limits what an agent may spend, in US dollars. Zero means
+// unlimited.
+func (a *App) SetCostBudget(agentID string, budget float64) error {
+	if budget < 0 {
+		return fmt.Errorf("budget must not be negative")
+	}
+
+	a.sessionsMu.Lock()
+	defer a.sessionsMu.Unlock()
+
+	guiAgent, ok := a.guiAgents[agentID]
+	if !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+
+	guiAgent.SetCostBudget(budget)
+	if session, exists := a.sessions[agentID]; exists {
+		session.CostBudget = budget
+	}
+	return nil
+}
+
+func (a *App) GetAgentLogs(agentID string, since int64) ([]LogEntry, error) {
+	a.sessionsMu.RLock()
+	guiAgent, ok := a.guiAgents[agentID]
+	a.sessionsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("agent not found: %s", agentID)
+	}
+	return guiAgent.GetLogs(since), nil
+}
+
+func (a *App) SetAgentVerbose(agentID string, verbose bool) error {
 	a.sessionsMu.RLock()
 	guiAgent, ok := a.guiAgents[agentID]
 	a.sessionsMu.RUnlock()
@@ -259,48 +595,107 @@ func (a *App) RespondToApproval(agentID, approvalID string, approved bool, reaso
 	if !ok {
 		return fmt.Errorf("agent not found: %s", agentID)
 	}
+	guiAgent.SetVerbose(verbose)
+	return nil
+}
 
-	guiAgent.RespondToApproval(approvalID, approved, reason)
+// SetPlanMode turns an agent's plan mode on or off. While it is on, tools
+// that change things wait until the user approves the agent's plan.
+func (a *App) SetPlanMode(agentID string, on bool) error {
+	a.sessionsMu.RLock()
+	guiAgent, ok := a.guiAgents[agentID]
+	a.sessionsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+	guiAgent.SetPlanMode(on)
 	return nil
 }
 
-func (a *App) LaunchMultiAgentDemo() ([]string, error) {
-	ids := []string{}
+// GetPlan returns an agent's plan. Changes to it are also sent as
+// agent:plan events.
+func (a *App) GetPlan(agentID string) (tools.PlanState, error) {
+	a.sessionsMu.RLock()
+	guiAgent, ok := a.guiAgents[agentID]
+	a.sessionsMu.RUnlock()
 
-	id1, err := a.NewNamedAgent("Editor-1", "")
-	if err != nil {
-		return nil, err
+	if !ok {
+		return tools.PlanState{}, fmt.Errorf("agent not found: %s", agentID)
 	}
-	ids = append(ids, id1)
+	return guiAgent.Plan(), nil
+}
 
-	id2, err := a.NewNamedAgent("Editor-2", "")
-	if err != nil {
-		return nil, err
+// AttachAgentPTY makes the agent run its bash tool inside the given PTY
+// session so commands show up live in the terminal panel. An empty ptyID
+// detaches the agent again.
+func (a *App) AttachAgentPTY(agentID, ptyID string) error {
+	a.sessionsMu.RLock()
+	guiAgent, ok := a.guiAgents[agentID]
+	a.sessionsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
 	}
-	ids = append(ids, id2)
 
-	id3, err := a.NewNamedAgent("Observer", "")
-	if err != nil {
-		return nil, err
+	if ptyID == "" {
+		guiAgent.AttachShell("", nil)
+	} else {
+		if !a.ptyManager.Has(ptyID) {
+			return fmt.Errorf("session not found: %s", ptyID)
+		}
+		guiAgent.AttachShell(ptyID, func(command string, env []string) (string, error) {
+			return a.ptyManager.Exec(ptyID, command, env, ptyExecTimeout)
+		})
 	}
-	ids = append(ids, id3)
 
-	go func() {
-		time.Sleep(100 * time.Millisecond)
-		_ = a.SendMessage(id1, "Edit the file mock1.txt and add a greeting function that returns 'Hello, World!'")
-	}()
+	runtime.EventsEmit(a.ctx, "agent:pty", map[string]string{
+		"id":    agentID,
+		"ptyId": ptyID,
+	})
+	return nil
+}
 
-	go func() {
-		time.Sleep(100 * time.Millisecond)
-		_ = a.SendMessage(id2, "Edit the file mock2.txt and add a farewell function that returns 'Goodbye!'")
-	}()
+func (a *App) RespondToApproval(agentID, approvalID string, approved bool, reason string) error {
+	a.sessionsMu.RLock()
+	guiAgent, ok := a.guiAgents[agentID]
+	a.sessionsMu.RUnlock()
 
-	go func() {
-		time.Sleep(500 * time.Millisecond)
-		_ = a.SendMessage(id3, "Use the observe_agents tool to discover other agents on the network, then summarize their activity.")
-	}()
+	if !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
 
-	return ids, nil
+	guiAgent.RespondToApproval(approvalID, approved, reason)
+	return nil
+}
+
+// AnswerQuestion delivers the user's reply to an ask_user question. An
+// empty answer dismisses it.
+func (a *App) AnswerQuestion(agentID, questionID, answer string) error {
+	a.sessionsMu.RLock()
+	guiAgent, ok := a.guiAgents[agentID]
+	a.sessionsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+
+	guiAgent.AnswerQuestion(questionID, answer)
+	return nil
+}
+
+// SteerAgent sends a note to an agent in the middle of a turn.
+func (a *App) SteerAgent(agentID, message string) error {
+	a.sessionsMu.RLock()
+	guiAgent, ok := a.guiAgents[agentID]
+	a.sessionsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+
+	guiAgent.Steer(message)
+	return nil
 }
 
 func (a *App) PTYSpawn(shell string) (string, error) {
@@ -318,3 +713,43 @@ func (a *App) PTYKill(id string) error {
 func (a *App) PTYList() []string {
 	return a.ptyManager.List()
 }
+
+// agentQueued records an agent's place in line for a slot, or that it got
+// one (position 0), and tells the GUI.
+func (a *App) agentQueued(agentID string, position int) {
+	status := "running"
+	if position > 0 {
+		status = "queued"
+	}
+	a.sessionsMu.Lock()
+	session, ok := a.sessions[agentID]
+	if ok {
+		session.Status = status
+		session.QueuePosition = position
+	}
+	a.sessionsMu.Unlock()
+	if !ok {
+		return
+	}
+
+	runtime.EventsEmit(a.ctx, "agent:status", map[string]string{
+		"id":     agentID,
+		"status": status,
+	})
+	runtime.EventsEmit(a.ctx, "agent:queue", map[string]interface{}{
+		"id":       agentID,
+		"position": position,
+	})
+}
+
+// SetMaxRunningAgents limits how many agents work on a turn at once, for
+// this run of the GUI; agents over the limit wait for a slot. Zero is no
+// limit.
+func (a *App) SetMaxRunningAgents(limit int) {
+	a.slots.setLimit(limit)
+}
+
+// GetMaxRunningAgents returns the limit on agents working at once.
+func (a *App) GetMaxRunningAgents() int {
+	return a.slots.getLimit()
+}
@@ -0,0 +1,206 @@
+// Package audit keeps a tamper-evident record of the tool calls agents
+// run.
+//
+// The log is a JSONL file with one Entry per tool call. Each entry carries
+// the hash of the one before it and a hash of itself, so editing, removing
+// or reordering any entry breaks the chain from that point on, which
+// Verify reports. Inputs and results are recorded only as hashes: the log
+// proves what ran without copying file contents or secrets into it.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Approval decisions.
+const (
+	Auto     = "auto"     // The tool needs no approval
+	Approved = "approved" // The user allowed the call
+	Denied   = "denied"   // The user refused the call; it did not run
+)
+
+// tailSize is how much of the end of the log is read to find the last
+// entry; entries are far smaller.
+const tailSize = 64 << 10
+
+// Entry is one tool call.
+type Entry struct {
+	Seq      int       `json:"seq"`
+	Time     time.Time `json:"time"`
+	Agent    string    `json:"agent"`
+	Tool     string    `json:"tool"`
+	Input    string    `json:"input_sha256"`
+	Result   string    `json:"result_sha256,omitempty"` // Empty when the call was denied
+	Error    bool      `json:"error,omitempty"`
+	Decision string    `json:"decision"`
+	Prev     string    `json:"prev"` // Hash of the previous entry; empty for the first
+	Hash     string    `json:"hash"`
+}
+
+// sum returns the hash of e with its Hash field left out.
+func (e Entry) sum() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return Hash(data), nil
+}
+
+// Hash returns the hex SHA-256 of data, as recorded for inputs and
+// results.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Log appends entries to an audit log file. A nil *Log records nothing.
+// It is safe for concurrent use, and processes sharing a log file each
+// chain onto its last line when they write.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Open returns a Log writing to path, creating the file and its directory
+// if needed.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return &Log{path: path}, nil
+}
+
+// Record appends a tool call to the log. Seq, Time, Prev and Hash are
+// filled in.
+func (l *Log) Record(e Entry) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	last, err := lastEntry(f)
+	if err != nil {
+		return fmt.Errorf("reading audit log: %w", err)
+	}
+	e.Seq = 1
+	e.Prev = ""
+	if last != nil {
+		e.Seq = last.Seq + 1
+		e.Prev = last.Hash
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	if e.Hash, err = e.sum(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// lastEntry returns the last entry in f, or nil if it has none.
+func lastEntry(f *os.File) (*Entry, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	start := max(info.Size()-tailSize, 0)
+	buf := make([]byte, info.Size()-start)
+	if _, err := f.ReadAt(buf, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	buf = bytes.TrimRight(buf, "\n")
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	line := buf[bytes.LastIndexByte(buf, '\n')+1:]
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return nil, fmt.Errorf("last entry is damaged: %w", err)
+	}
+	return &e, nil
+}
+
+// Summary describes a log that verified.
+type Summary struct {
+	Entries int
+	Head    string // Hash of the last entry
+}
+
+// ErrBroken is wrapped by Verify's error when the chain does not hold.
+var ErrBroken = errors.New("audit log chain broken")
+
+// Verify checks every entry of the log at path: that each hash matches
+// its entry, that each entry points at the one before, and that sequence
+// numbers run on without gaps. The first problem is returned, naming the
+// line it is on.
+//
+// A chain cannot show that entries were cut from the end, so the head
+// hash is returned for comparing with one noted earlier.
+func Verify(path string) (Summary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer f.Close()
+
+	var s Summary
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return s, fmt.Errorf("%w: line %d is not an entry: %v", ErrBroken, line, err)
+		}
+		sum, err := e.sum()
+		if err != nil {
+			return s, err
+		}
+		switch {
+		case e.Hash != sum:
+			return s, fmt.Errorf("%w: line %d (seq %d) was modified; its hash does not match", ErrBroken, line, e.Seq)
+		case e.Prev != s.Head:
+			return s, fmt.Errorf("%w: line %d (seq %d) does not follow the entry before it", ErrBroken, line, e.Seq)
+		case e.Seq != s.Entries+1:
+			return s, fmt.Errorf("%w: line %d has seq %d, want %d", ErrBroken, line, e.Seq, s.Entries+1)
+		}
+		s.Entries++
+		s.Head = e.Hash
+	}
+	return s, scanner.Err()
+}
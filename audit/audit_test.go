@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLog(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		err := log.Record(Entry{Agent: "a1", Tool: "bash", Input: Hash([]byte("ls")), Result: Hash([]byte("out")), Decision: Approved})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestVerifyIntactLog(t *testing.T) {
+	path := writeLog(t, 3)
+
+	// A second Log on the same file continues the chain.
+	log, _ := Open(path)
+	if err := log.Record(Entry{Agent: "a2", Tool: "read_file", Decision: Auto}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Entries != 4 || s.Head == "" {
+		t.Errorf("Verify = %+v, want 4 entries and a head hash", s)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+		want   string
+	}{
+		{"edit", func(l []string) []string {
+			l[1] = strings.Replace(l[1], `"tool":"bash"`, `"tool":"read_file"`, 1)
+			return l
+		}, "line 2 (seq 2) was modified"},
+		{"delete", func(l []string) []string { return append(l[:1], l[2:]...) }, "line 2 (seq 3) does not follow"},
+		{"reorder", func(l []string) []string { l[0], l[1] = l[1], l[0]; return l }, "line 1 (seq 2) does not follow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeLog(t, 3)
+			data, _ := os.ReadFile(path)
+			lines := tt.tamper(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+			_, err := Verify(path)
+			if !errors.Is(err, ErrBroken) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilLogRecordsNothing(t *testing.T) {
+	var log *Log
+	if err := log.Record(Entry{Tool: "bash"}); err != nil {
+		t.Errorf("nil Log: %v", err)
+	}
+}
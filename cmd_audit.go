@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"brutus/audit"
+	"brutus/config"
+)
+
+// runAudit checks the tool call audit log: `brutus audit verify [file]`.
+// Without a file it verifies the log audit_log names in the config.
+func runAudit(args []string) {
+	if len(args) == 0 || args[0] != "verify" || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "Usage: brutus audit verify [file]")
+		os.Exit(1)
+	}
+
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	} else if cfg, err := config.Load(); err == nil {
+		path = cfg.AuditLog
+	}
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "Error: no audit log given and audit_log is not set in the config")
+		os.Exit(1)
+	}
+
+	summary, err := audit.Verify(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		if summary.Entries > 0 {
+			fmt.Fprintf(os.Stderr, "The first %d entries are intact.\n", summary.Entries)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d entries, chain intact\n", path, summary.Entries)
+	if summary.Entries > 0 {
+		// The chain can't show entries cut from the end; a head hash
+		// noted earlier can.
+		fmt.Printf("Head: %s\n", summary.Head)
+	}
+}
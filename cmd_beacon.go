@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"brutus/provider"
+)
+
+// runBeacon announces a local OpenAI-compatible server (llama.cpp, vLLM,
+// Ollama, ...) as a Saturn service, so BRUTUS clients on the network find
+// it without the Saturn project installed.
+func runBeacon(args []string) {
+	fs := flag.NewFlagSet("beacon", flag.ExitOnError)
+	opts := registerBeaconFlags(fs)
+	fs.Parse(args)
+
+	cfg := provider.BeaconConfig{
+		Name:          *opts.name,
+		Port:          *opts.port,
+		APIBase:       *opts.apiBase,
+		Priority:      *opts.priority,
+		Features:      splitList(*opts.features),
+		MaxConcurrent: *opts.maxConcurrent,
+		CurrentLoad:   *opts.load,
+		GPU:           *opts.gpu,
+		VRAMGb:        *opts.vram,
+	}
+	if *opts.apiBase != "" {
+		cfg.Port = 0
+	}
+
+	cfg.Models = splitList(*opts.models)
+	if len(cfg.Models) == 0 {
+		found, err := serverModels(beaconURL(cfg))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not list the server's models (%v); announcing none. Pass -models to set them.\n", err)
+		}
+		cfg.Models = found
+	}
+
+	beacon, err := provider.StartBeacon(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	onShutdown(beacon.Close)
+
+	fmt.Printf("Announcing %s (%s) as a Saturn service, priority %d\n", cfg.Name, beaconURL(cfg), cfg.Priority)
+	if len(cfg.Models) > 0 {
+		fmt.Printf("Models: %s\n", strings.Join(cfg.Models, ", "))
+	}
+	fmt.Println("Press Ctrl+C to stop.")
+
+	<-signalContext().Done()
+}
+
+// beaconFlags are the flags of beacon.
+type beaconFlags struct {
+	name          *string
+	port          *int
+	apiBase       *string
+	priority      *int
+	models        *string
+	features      *string
+	maxConcurrent *int
+	load          *int
+	gpu           *string
+	vram          *int
+}
+
+func registerBeaconFlags(fs *flag.FlagSet) *beaconFlags {
+	return &beaconFlags{
+		name:          fs.String("name", defaultBeaconName(), "Service name to announce"),
+		port:          fs.Int("port", 8080, "Port the server listens on, on this host"),
+		apiBase:       fs.String("api-base", "", "Announce this base URL instead of this host and -port"),
+		priority:      fs.Int("priority", 50, "Service priority; lower is preferred"),
+		models:        fs.String("models", "", "Comma-separated models to announce (default: ask the server)"),
+		features:      fs.String("features", "", "Comma-separated features, e.g. streaming,tools"),
+		maxConcurrent: fs.Int("max-concurrent", 0, "Requests the server handles at once"),
+		load:          fs.Int("load", 0, "Current load to announce"),
+		gpu:           fs.String("gpu", "", "GPU to announce"),
+		vram:          fs.Int("vram", 0, "GPU memory in GB to announce"),
+	}
+}
+
+func defaultBeaconName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "brutus-beacon"
+	}
+	return strings.TrimSuffix(host, ".local")
+}
+
+// beaconURL is the base URL clients will use, as seen from this host.
+func beaconURL(cfg provider.BeaconConfig) string {
+	return provider.SaturnService{Host: "127.0.0.1", Port: cfg.Port, APIBase: cfg.APIBase}.URL()
+}
+
+// serverModels asks an OpenAI-compatible server which models it serves.
+func serverModels(baseURL string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /v1/models: %s", resp.Status)
+	}
+
+	var body struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	var models []string
+	for _, m := range body.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
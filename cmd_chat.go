@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"brutus/agent"
+	"brutus/internal/text"
+	"brutus/internal/theme"
+	"brutus/provider"
+	"brutus/session"
+	"brutus/tools"
+)
+
+// runChat starts the interactive agent session.
+func runChat(args []string) {
+	fs := flag.NewFlagSet("chat", flag.ExitOnError)
+	flags := registerAgentFlags(fs)
+	opts := registerChatFlags(fs)
+	fs.Parse(args)
+	flags.picker = true
+	flags.repick = *opts.pick
+	if *opts.approve != "ask" && *opts.approve != "auto" {
+		fmt.Fprintf(os.Stderr, "Error: -approve must be ask or auto, not %q\n", *opts.approve)
+		os.Exit(1)
+	}
+	flags.approve = *opts.approve == "ask"
+	flags.plan = *opts.plan
+
+	if *opts.version {
+		fmt.Println(versionString())
+		os.Exit(0)
+	}
+
+	if *opts.resume {
+		var sess *session.Session
+		var path string
+		var err error
+		if id := fs.Arg(0); id != "" {
+			sess, path, err = findSession(id)
+		} else {
+			sess, path, err = pickSession()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if sess == nil {
+			return
+		}
+		startChat(flags, sess, path, *opts.pager)
+		return
+	}
+
+	sess := session.New()
+	if *opts.transcript == "" && *opts.save {
+		*opts.transcript = filepath.Join(session.Dir(), sess.ID+".jsonl")
+	}
+	startChat(flags, sess, *opts.transcript, *opts.pager)
+}
+
+// chatFlags are chat's own flags, besides the agent flags.
+type chatFlags struct {
+	version    *bool
+	transcript *string
+	save       *bool
+	resume     *bool
+	pager      *bool
+	approve    *string
+	plan       *bool
+	pick       *bool
+}
+
+func registerChatFlags(fs *flag.FlagSet) *chatFlags {
+	return &chatFlags{
+		version:    fs.Bool("version", false, "Print version and exit"),
+		transcript: fs.String("transcript", "", "Record the conversation to this file (.jsonl for a JSONL transcript, otherwise a JSON session file)"),
+		save:       fs.Bool("save", true, "Without -transcript, save the conversation to ~/.brutus/sessions so -resume can pick it up"),
+		resume:     fs.Bool("resume", false, "Continue a saved session from ~/.brutus/sessions: the one whose ID follows, or one picked from a list"),
+		pager:      fs.Bool("pager", true, "Show responses taller than the terminal in a pager (/more reopens the last one)"),
+		approve:    fs.String("approve", "ask", "Which tool calls run without asking: ask (only read-only tools) or auto (all)"),
+		plan:       fs.Bool("plan", false, "Plan mode: tools that change things are refused until you approve the agent's plan"),
+		pick:       fs.Bool("pick", false, "Choose the service and model from a list, replacing the one remembered for this project"),
+	}
+}
+
+// pickSession lets the user choose one of the saved sessions, listed by
+// title. It returns a nil session if they cancel.
+func pickSession() (*session.Session, string, error) {
+	entries, err := session.List(session.Dir())
+	if err != nil {
+		return nil, "", err
+	}
+	if len(entries) == 0 {
+		return nil, "", fmt.Errorf("no saved sessions in %s", session.Dir())
+	}
+
+	items := make([]string, len(entries))
+	for i, e := range entries {
+		title := e.Title
+		if e.Parent != "" {
+			title = "(fork) " + title
+		}
+		items[i] = fmt.Sprintf("%-50s  %3d turns  %s", text.Head(title, 50), e.Turns, e.Updated.Format("Jan _2 15:04"))
+	}
+	idx, err := agent.PickFromList("Resume a session", items)
+	if err != nil || idx < 0 {
+		return nil, "", err
+	}
+
+	return loadSession(entries[idx].Path)
+}
+
+// findSession loads the saved session with the given ID, or the only one
+// whose ID starts with it.
+func findSession(id string) (*session.Session, string, error) {
+	entry, err := session.Find(session.Dir(), id)
+	if err != nil {
+		return nil, "", err
+	}
+	return loadSession(entry.Path)
+}
+
+// loadSession loads a saved session to resume and moves into the
+// directory it was working in, if that still exists.
+func loadSession(path string) (*session.Session, string, error) {
+	sess, err := session.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	fmt.Println(theme.Muted(fmt.Sprintf("Resuming %q (%d turns)", sess.Label(), sess.Turns())))
+	if sess.Summary != "" {
+		fmt.Println(theme.Muted(sess.Summary))
+	}
+	if cwd, _ := os.Getwd(); sess.WorkingDir != "" && sess.WorkingDir != cwd {
+		if err := os.Chdir(sess.WorkingDir); err != nil {
+			fmt.Println(theme.Warning(fmt.Sprintf("Cannot return to %s (%v); working in %s", sess.WorkingDir, err, cwd)))
+		} else {
+			fmt.Println(theme.Muted("Working in " + sess.WorkingDir))
+		}
+	}
+	return sess, path, nil
+}
+
+// startChat connects to Saturn and runs an interactive session. Any records
+// already in sess become the conversation history, so the same path serves
+// new chats and forks of saved ones.
+func startChat(flags *agentFlags, sess *session.Session, transcriptPath string, pager bool) {
+	prov := flags.setup()
+
+	registry := cliTools()
+	registerSemanticSearch(registry, prov)
+	registerSubagents(registry, prov, flags, os.Stdout)
+	if *flags.verbose {
+		log.Printf("Registered %d tools: %v", len(registry.All()), registry.Names())
+	}
+
+	// Create input reader
+	scanner := bufio.NewScanner(os.Stdin)
+	getUserInput := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		return scanner.Text(), true
+	}
+
+	// Get absolute path of working directory for display
+	absWorkDir, _ := os.Getwd()
+
+	sess.Model = prov.GetModel()
+	sess.WorkingDir = absWorkDir
+
+	var onMessage func(int, provider.Message)
+	var onDescribe func(session.Info)
+	if transcriptPath != "" {
+		tr, err := session.OpenTranscript(transcriptPath, sess)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot write transcript: %v\n", err)
+			os.Exit(1)
+		}
+		onShutdown(func() { tr.Close() })
+		onMessage = func(turn int, msg provider.Message) {
+			if err := tr.Record(turn, msg); err != nil {
+				log.Printf("transcript write failed: %v", err)
+			}
+		}
+		onDescribe = func(info session.Info) {
+			if err := tr.Describe(info); err != nil {
+				log.Printf("transcript write failed: %v", err)
+			}
+		}
+	}
+
+	var approval *agent.ApprovalPolicy
+	if flags.approve {
+		approval = &agent.ApprovalPolicy{}
+	}
+
+	a := agent.New(agent.Config{
+		Provider:         prov,
+		GetUserInput:     getUserInput,
+		Tools:            registry,
+		SystemPrompt:     loadSystemPrompt(),
+		Verbose:          *flags.verbose,
+		WorkingDir:       absWorkDir,
+		Logger:           flags.logger,
+		SessionID:        sess.ID,
+		History:          session.Messages(sess.Records),
+		OnMessage:        onMessage,
+		OnDescribe:       onDescribe,
+		Info:             session.Info{Title: sess.Title, Summary: sess.Summary},
+		MaxMessages:      *flags.maxMsgs,
+		ScanToolOutput:   *flags.injection,
+		CacheToolResults: *flags.toolCache,
+		Pager:            pager,
+
+		VerificationCommands: flags.verify,
+		Env:                  flags.env,
+		ToolEnv:              flags.toolEnv,
+		ArtifactThreshold:    *flags.artifacts,
+		Audit:                flags.openAudit(),
+		TurnTimeout:          *flags.turnLimit,
+		ContextBudget:        *flags.budget,
+		ResultLimits:         flags.resultLimits(),
+		Approval:             approval,
+		PlanMode:             flags.plan,
+	})
+	// Only an interactive chat has someone to answer.
+	registry.Register(tools.NewAskUserTool(a.AskUser))
+	onShutdown(func() { a.Close() })
+	// Ctrl+C during a turn stops just that turn.
+	onInterrupt(a.Interrupt)
+
+	if err := a.Run(signalContext()); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		exit(1)
+	}
+	if filepath.Dir(transcriptPath) == session.Dir() && len(sess.Records) > 0 {
+		fmt.Println(theme.Muted("Continue this session with: brutus chat -resume " + sess.ID))
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"brutus/internal/testcli"
+	"brutus/sdk"
+)
+
+// completionSpec describes one subcommand for completion scripts. Keep it
+// in step with the dispatch in main and the flags each command registers.
+type completionSpec struct {
+	name       string
+	summary    string
+	agentFlags bool     // accepts the shared -model/-timeout/... flags
+	flags      []string // additional flags, without the leading dash
+	words      []string // fixed positional words (sub-subcommands, shells)
+}
+
+var completionSpecs = []completionSpec{
+	{name: "chat", summary: "Interactive session", agentFlags: true, flags: []string{"version", "transcript", "save", "resume", "pager", "pick", "approve", "plan"}},
+	{name: "run", summary: "Run a single prompt headlessly", agentFlags: true, flags: []string{"transcript", "tools"}},
+	{name: "tools", summary: "List or execute tools"},
+	{name: "serve", summary: "Headless HTTP server", agentFlags: true, flags: []string{"addr"}},
+	{name: "discover", summary: "List Saturn services", flags: []string{"timeout", "json", "watch", "interval"}},
+	{name: "beacon", summary: "Announce a local server as a Saturn service", flags: []string{"name", "port", "api-base", "priority", "models", "features", "max-concurrent", "load", "gpu", "vram"}},
+	{name: "models", summary: "List or pin models", agentFlags: true, flags: []string{"pool", "json"}, words: []string{"set", "unset"}},
+	{name: "replay", summary: "Replay a saved session", agentFlags: true, flags: []string{"timing", "speed", "from-turn", "fork", "transcript"}},
+	{name: "audit", summary: "Verify the tool call audit log", words: []string{"verify"}},
+	{name: "doctor", summary: "Diagnose environment problems", flags: []string{"timeout"}},
+	{name: "test", summary: "Testing SDK commands"},
+	{name: "completion", summary: "Print a shell completion script", words: []string{"bash", "zsh", "fish"}},
+	{name: "version", summary: "Print version and build info"},
+	{name: "help", summary: "Show help"},
+}
+
+func (c completionSpec) flagNames() []string {
+	var names []string
+	if c.agentFlags {
+		fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		registerAgentFlags(fs)
+		fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	}
+	names = append(names, c.flags...)
+	names = append(names, "no-color")
+	sort.Strings(names)
+
+	for i, n := range names {
+		names[i] = "-" + n
+	}
+	return names
+}
+
+// runCompletion prints a completion script for the requested shell.
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: brutus completion bash|zsh|fish")
+		os.Exit(1)
+	}
+
+	cliToolNames := cliTools().Names()
+	sort.Strings(cliToolNames)
+	testToolNames := sdk.DefaultToolRunner().ListTools()
+	sort.Strings(testToolNames)
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(cliToolNames, testToolNames))
+	case "zsh":
+		// zsh can run bash completion functions through bashcompinit.
+		fmt.Print("#compdef brutus brutus-test\n\nautoload -U +X bashcompinit && bashcompinit\n\n")
+		fmt.Print(bashCompletion(cliToolNames, testToolNames))
+	case "fish":
+		fmt.Print(fishCompletion(cliToolNames, testToolNames))
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported shell: %s (want bash, zsh or fish)\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func bashCompletion(cliToolNames, testToolNames []string) string {
+	var b strings.Builder
+	var names []string
+	for _, c := range completionSpecs {
+		names = append(names, c.name)
+	}
+
+	b.WriteString("# brutus completion for bash. Load with: source <(brutus completion bash)\n\n")
+
+	// Completes brutus-test style arguments starting at word $1, shared by
+	// `brutus test ...` and the standalone brutus-test binary.
+	b.WriteString("_brutus_test_words() {\n")
+	b.WriteString("    local base=$1 cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    if [[ $COMP_CWORD -eq $base ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(testcli.Commands, " "))
+	b.WriteString("    elif [[ ${COMP_WORDS[$base]} == tool && $COMP_CWORD -eq $((base+1)) ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(testToolNames, " "))
+	b.WriteString("    else\n")
+	b.WriteString("        COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n\n")
+
+	b.WriteString("_brutus() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    if [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, c := range completionSpecs {
+		switch c.name {
+		case "test":
+			b.WriteString("        test) _brutus_test_words 2 ;;\n")
+			continue
+		case "tools":
+			b.WriteString("        tools)\n")
+			b.WriteString("            if [[ $COMP_CWORD -eq 2 ]]; then\n")
+			fmt.Fprintf(&b, "                COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(cliToolNames, " "))
+			b.WriteString("            fi ;;\n")
+			continue
+		}
+		words := append(c.flagNames(), c.words...)
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", c.name, strings.Join(words, " "))
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+
+	b.WriteString("_brutus_test() { _brutus_test_words 1; }\n\n")
+	b.WriteString("complete -F _brutus brutus\n")
+	b.WriteString("complete -F _brutus_test brutus-test\n")
+	return b.String()
+}
+
+func fishCompletion(cliToolNames, testToolNames []string) string {
+	var b strings.Builder
+	b.WriteString("# brutus completion for fish. Load with: brutus completion fish | source\n\n")
+	b.WriteString("complete -c brutus -f\n")
+	b.WriteString("complete -c brutus-test -f\n\n")
+
+	for _, c := range completionSpecs {
+		fmt.Fprintf(&b, "complete -c brutus -n __fish_use_subcommand -a %s -d %q\n", c.name, c.summary)
+	}
+	b.WriteString("\n")
+
+	for _, c := range completionSpecs {
+		cond := fmt.Sprintf("'__fish_seen_subcommand_from %s'", c.name)
+		for _, f := range c.flagNames() {
+			fmt.Fprintf(&b, "complete -c brutus -n %s -o %s\n", cond, strings.TrimPrefix(f, "-"))
+		}
+		if len(c.words) > 0 {
+			fmt.Fprintf(&b, "complete -c brutus -n %s -a %q\n", cond, strings.Join(c.words, " "))
+		}
+	}
+	fmt.Fprintf(&b, "complete -c brutus -n '__fish_seen_subcommand_from tools' -a %q\n", strings.Join(cliToolNames, " "))
+	fmt.Fprintf(&b, "complete -c brutus -n '__fish_seen_subcommand_from test; and not __fish_seen_subcommand_from %s' -a %q\n",
+		strings.Join(testcli.Commands, " "), strings.Join(testcli.Commands, " "))
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "complete -c brutus-test -n __fish_use_subcommand -a %q\n", strings.Join(testcli.Commands, " "))
+	for _, prog := range []string{"brutus", "brutus-test"} {
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from tool' -a %q\n", prog, strings.Join(testToolNames, " "))
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from scenario multi-agent live-multi-agent' -F\n", prog)
+	}
+	return b.String()
+}
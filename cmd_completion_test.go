@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+// TestCompletionSpecsCoverFlags builds each command's flags as the command
+// does and checks that completion offers every one of them.
+func TestCompletionSpecsCoverFlags(t *testing.T) {
+	register := map[string]func(fs *flag.FlagSet){
+		"chat":     func(fs *flag.FlagSet) { registerChatFlags(fs) },
+		"run":      func(fs *flag.FlagSet) { registerRunFlags(fs) },
+		"serve":    func(fs *flag.FlagSet) { registerServeFlags(fs) },
+		"models":   func(fs *flag.FlagSet) { registerModelsFlags(fs) },
+		"replay":   func(fs *flag.FlagSet) { registerReplayFlags(fs) },
+		"discover": func(fs *flag.FlagSet) { registerDiscoverFlags(fs) },
+		"beacon":   func(fs *flag.FlagSet) { registerBeaconFlags(fs) },
+		"doctor":   func(fs *flag.FlagSet) { registerDoctorFlags(fs) },
+	}
+	for name, fn := range register {
+		i := slices.IndexFunc(completionSpecs, func(c completionSpec) bool { return c.name == name })
+		if i < 0 {
+			t.Errorf("no completion spec for %s", name)
+			continue
+		}
+		spec := completionSpecs[i]
+		offered := spec.flagNames()
+
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		if spec.agentFlags {
+			registerAgentFlags(fs)
+		}
+		fn(fs)
+		fs.VisitAll(func(f *flag.Flag) {
+			if !slices.Contains(offered, "-"+f.Name) {
+				t.Errorf("%s: -%s is not completed", name, f.Name)
+			}
+		})
+	}
+}
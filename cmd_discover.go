@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"brutus/internal/theme"
+	"brutus/provider"
+)
+
+// discoveredService is the stable, machine-readable form of a Saturn
+// service printed by `brutus discover -json`.
+type discoveredService struct {
+	Name          string   `json:"name"`
+	Host          string   `json:"host"`
+	Port          int      `json:"port"`
+	URL           string   `json:"url"`
+	Priority      int      `json:"priority"`
+	APIType       string   `json:"api_type,omitempty"`
+	Models        []string `json:"models,omitempty"`
+	CurrentLoad   int      `json:"current_load"`
+	MaxConcurrent int      `json:"max_concurrent"`
+	GPU           string   `json:"gpu,omitempty"`
+	VRAMGb        int      `json:"vram_gb,omitempty"`
+	Features      []string `json:"features,omitempty"`
+	Version       string   `json:"version,omitempty"`
+}
+
+func toDiscovered(svc provider.SaturnService) discoveredService {
+	return discoveredService{
+		Name:          svc.Name,
+		Host:          svc.Host,
+		Port:          svc.Port,
+		URL:           svc.URL(),
+		Priority:      svc.Priority,
+		APIType:       svc.APIType,
+		Models:        svc.Models,
+		CurrentLoad:   svc.CurrentLoad,
+		MaxConcurrent: svc.MaxConcurrent,
+		GPU:           svc.GPU,
+		VRAMGb:        svc.VRAMGb,
+		Features:      svc.Features,
+		Version:       svc.SaturnVersion,
+	}
+}
+
+// runDiscover prints the Saturn services visible from this machine.
+func runDiscover(args []string) {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	opts := registerDiscoverFlags(fs)
+	fs.Parse(args)
+
+	if *opts.watch {
+		watchServices(*opts.timeout, *opts.interval, *opts.asJSON)
+		return
+	}
+
+	services, err := discoverServices(context.Background(), *opts.timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nRun 'brutus doctor' to check your network.\n", err)
+		os.Exit(1)
+	}
+	if *opts.asJSON {
+		printServicesJSON(services)
+		return
+	}
+	if len(services) == 0 {
+		fmt.Fprintln(os.Stderr, "No Saturn services found. Run 'brutus doctor' to check your network.")
+		os.Exit(1)
+	}
+	printServicesTable(services)
+}
+
+// discoverFlags are the flags of discover.
+type discoverFlags struct {
+	timeout  *time.Duration
+	asJSON   *bool
+	watch    *bool
+	interval *time.Duration
+}
+
+func registerDiscoverFlags(fs *flag.FlagSet) *discoverFlags {
+	return &discoverFlags{
+		timeout:  fs.Duration("timeout", 3*time.Second, "How long to browse for services"),
+		asJSON:   fs.Bool("json", false, "Print services as JSON"),
+		watch:    fs.Bool("watch", false, "Keep browsing and report services as they appear and disappear"),
+		interval: fs.Duration("interval", 5*time.Second, "Time between browses in -watch mode"),
+	}
+}
+
+// discoverServices browses for services, ordered by priority then name.
+// Finding none is not an error; failing to browse at all is.
+func discoverServices(ctx context.Context, timeout time.Duration) ([]provider.SaturnService, error) {
+	// Each call browses afresh; a cache would hide services that went away.
+	services, err := provider.CreateDiscoverer(nil).Discover(ctx, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("saturn discovery failed: %w", err)
+	}
+	sort.SliceStable(services, func(i, j int) bool {
+		if services[i].Priority != services[j].Priority {
+			return services[i].Priority < services[j].Priority
+		}
+		return services[i].Name < services[j].Name
+	})
+	return services, nil
+}
+
+// watchServices browses repeatedly until interrupted. Text mode redraws the
+// table and lists what changed; JSON mode emits one event object per line.
+// A browse that fails changes nothing: the services are compared again
+// after the next one that works.
+func watchServices(timeout, interval time.Duration, asJSON bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// wait sleeps until the next browse, reporting false if interrupted.
+	wait := func() bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
+			return true
+		}
+	}
+
+	known := map[string]provider.SaturnService{}
+	for {
+		services, err := discoverServices(ctx, timeout)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v; trying again in %s\n", theme.Warning("[discover]"), err, interval)
+			if !wait() {
+				return
+			}
+			continue
+		}
+
+		current := map[string]provider.SaturnService{}
+		for _, svc := range services {
+			current[svc.Name] = svc
+		}
+
+		var changes []string
+		enc := json.NewEncoder(os.Stdout)
+		for name, svc := range current {
+			if _, ok := known[name]; !ok {
+				changes = append(changes, "+ "+name+" ("+svc.URL()+")")
+				if asJSON {
+					enc.Encode(map[string]interface{}{"event": "added", "service": toDiscovered(svc)})
+				}
+			}
+		}
+		for name, svc := range known {
+			if _, ok := current[name]; !ok {
+				changes = append(changes, "- "+name)
+				if asJSON {
+					enc.Encode(map[string]interface{}{"event": "removed", "service": toDiscovered(svc)})
+				}
+			}
+		}
+		known = current
+
+		if !asJSON {
+			fmt.Print(theme.ClearScreen())
+			fmt.Printf("Saturn services (%s, every %s, Ctrl+C to stop)\n\n", time.Now().Format("15:04:05"), interval)
+			if len(services) == 0 {
+				fmt.Println("No services found.")
+			} else {
+				printServicesTable(services)
+			}
+			if len(changes) > 0 {
+				sort.Strings(changes)
+				fmt.Println()
+				for _, c := range changes {
+					fmt.Println(c)
+				}
+			}
+		}
+
+		if !wait() {
+			return
+		}
+	}
+}
+
+func printServicesJSON(services []provider.SaturnService) {
+	out := make([]discoveredService, 0, len(services))
+	for _, svc := range services {
+		out = append(out, toDiscovered(svc))
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(out)
+}
+
+func printServicesTable(services []provider.SaturnService) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS\tPRIO\tMODELS\tLOAD\tGPU/VRAM\tFEATURES")
+	for _, svc := range services {
+		address := svc.APIBase
+		if address == "" {
+			address = svc.Host + ":" + strconv.Itoa(svc.Port)
+		}
+
+		load := "-"
+		if svc.MaxConcurrent > 0 {
+			load = fmt.Sprintf("%d/%d", svc.CurrentLoad, svc.MaxConcurrent)
+		}
+
+		gpu := orDash(svc.GPU)
+		if svc.VRAMGb > 0 {
+			gpu = fmt.Sprintf("%s/%dGB", gpu, svc.VRAMGb)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			svc.Name, address, svc.Priority, summarizeList(svc.Models, 3), load, gpu, orDash(strings.Join(svc.Features, ",")))
+	}
+	w.Flush()
+}
+
+// summarizeList joins up to max items and notes how many were left out.
+func summarizeList(items []string, max int) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	if len(items) <= max {
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprintf("%s (+%d)", strings.Join(items[:max], ","), len(items)-max)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"golang.org/x/term"
+
+	"brutus/config"
+	"brutus/internal/theme"
+	"brutus/provider"
+)
+
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+type checkResult struct {
+	name   string
+	status checkStatus
+	detail string
+	fix    string
+}
+
+// runDoctor checks the local environment for the problems that most often
+// make BRUTUS fail silently on first run, and prints how to fix them.
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	opts := registerDoctorFlags(fs)
+	fs.Parse(args)
+
+	fmt.Printf("BRUTUS v%s doctor (%s/%s, %s)\n\n", Version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	var results []checkResult
+	report := func(r checkResult) {
+		results = append(results, r)
+		printCheck(r)
+	}
+
+	report(checkConfig())
+	report(checkRipgrep())
+	report(checkDNSSD())
+	report(checkMulticast())
+	report(checkTerminal())
+
+	services, r := checkBeacons(*opts.timeout)
+	report(r)
+	for _, svc := range services {
+		report(checkService(svc))
+	}
+
+	failed := 0
+	warned := 0
+	for _, r := range results {
+		switch r.status {
+		case checkFail:
+			failed++
+		case checkWarn:
+			warned++
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Println(theme.Error(fmt.Sprintf("%d problem(s), %d warning(s)", failed, warned)))
+		os.Exit(1)
+	}
+	if warned > 0 {
+		fmt.Println(theme.Warning(fmt.Sprintf("No problems, %d warning(s)", warned)))
+		return
+	}
+	fmt.Println(theme.Success("Everything looks good"))
+}
+
+// doctorFlags are the flags of doctor.
+type doctorFlags struct {
+	timeout *time.Duration
+}
+
+func registerDoctorFlags(fs *flag.FlagSet) *doctorFlags {
+	return &doctorFlags{
+		timeout: fs.Duration("timeout", 5*time.Second, "Saturn discovery timeout"),
+	}
+}
+
+func printCheck(r checkResult) {
+	label := map[checkStatus]string{
+		checkOK:   theme.Success("  ok "),
+		checkWarn: theme.Warning(" warn"),
+		checkFail: theme.Error(" FAIL"),
+	}[r.status]
+
+	fmt.Printf("[%s] %-22s %s\n", label, r.name, r.detail)
+	if r.fix != "" && r.status != checkOK {
+		fmt.Println("        " + theme.Muted("-> "+r.fix))
+	}
+}
+
+func checkConfig() checkResult {
+	r := checkResult{name: "config"}
+	var found []string
+	for _, path := range config.Paths() {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if _, err := config.LoadFile(path); err != nil {
+			r.status = checkFail
+			r.detail = err.Error()
+			r.fix = "Fix the JSON in " + path + " or remove unknown fields"
+			return r
+		}
+		found = append(found, path)
+	}
+
+	if len(found) == 0 {
+		r.detail = "no config files (using defaults)"
+	} else {
+		r.detail = fmt.Sprintf("valid: %v", found)
+	}
+	return r
+}
+
+func checkRipgrep() checkResult {
+	r := checkResult{name: "ripgrep"}
+	path, err := exec.LookPath("rg")
+	if err != nil {
+		r.status = checkWarn
+		r.detail = "rg not found; code_search uses its slower built-in search"
+		r.fix = "Install ripgrep: https://github.com/BurntSushi/ripgrep#installation"
+		return r
+	}
+	r.detail = path
+	return r
+}
+
+func checkDNSSD() checkResult {
+	r := checkResult{name: "dns-sd"}
+	if path, err := exec.LookPath("dns-sd"); err == nil {
+		r.detail = path
+		return r
+	}
+
+	// NewSaturn browses with dns-sd first; without it discovery falls back
+	// to UDP broadcast, which finds only beacons that answer broadcasts.
+	r.status = checkWarn
+	r.detail = "dns-sd not found; chat, run and serve will discover Saturn by UDP broadcast instead"
+	switch runtime.GOOS {
+	case "darwin":
+		r.fix = "dns-sd ships with macOS; check that /usr/bin is on your PATH"
+	case "windows":
+		r.fix = "Install Bonjour Print Services for Windows to get dns-sd.exe"
+	case "linux":
+		r.fix = "Install avahi with its dns-sd compatibility tools (e.g. avahi-daemon, libavahi-compat-libdnssd)"
+	}
+	return r
+}
+
+// mdnsGroup is the IPv4 multicast group and port mDNS listens on.
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+func checkMulticast() checkResult {
+	r := checkResult{name: "multicast"}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		r.status = checkFail
+		r.detail = err.Error()
+		return r
+	}
+
+	var usable []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagMulticast != 0 && iface.Flags&net.FlagLoopback == 0 {
+			usable = append(usable, iface.Name)
+		}
+	}
+	if len(usable) == 0 {
+		r.status = checkFail
+		r.detail = "no active multicast-capable network interface"
+		r.fix = "Connect to the same LAN as your Saturn server; VPNs and containers often block multicast, so there use -endpoint or $SATURN_ENDPOINT"
+		return r
+	}
+
+	// Joining the mDNS multicast group fails when the OS or a firewall
+	// forbids it. zeroconf's resolver can't be closed once created, so the
+	// group is joined directly and left again right away.
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		r.status = checkFail
+		r.detail = fmt.Sprintf("cannot join mDNS group on %v: %v", usable, err)
+		r.fix = "Allow UDP port 5353 (mDNS) through your firewall"
+		return r
+	}
+	conn.Close()
+
+	r.detail = fmt.Sprintf("mDNS available on %v", usable)
+	return r
+}
+
+func checkTerminal() checkResult {
+	r := checkResult{name: "terminal"}
+	stdin := term.IsTerminal(int(os.Stdin.Fd()))
+	stdout := term.IsTerminal(int(os.Stdout.Fd()))
+	termEnv := os.Getenv("TERM")
+
+	switch {
+	case !stdin || !stdout:
+		r.status = checkWarn
+		r.detail = fmt.Sprintf("not a TTY (stdin=%v, stdout=%v); interactive chat features are limited", stdin, stdout)
+		r.fix = "Use 'brutus run' for scripts, or run chat from an interactive terminal"
+	case termEnv == "dumb":
+		r.status = checkWarn
+		r.detail = "TERM=dumb; colors and autocomplete will not render"
+		r.fix = "Set TERM to a capable terminal type such as xterm-256color"
+	default:
+		width, height, _ := term.GetSize(int(os.Stdout.Fd()))
+		r.detail = fmt.Sprintf("TTY %dx%d, TERM=%s", width, height, termEnv)
+	}
+	return r
+}
+
+func checkBeacons(timeout time.Duration) ([]provider.SaturnService, checkResult) {
+	r := checkResult{name: "saturn beacons"}
+
+	ctx := context.Background()
+	services, err := provider.CreateDiscoverer(nil).Discover(ctx, timeout)
+	if err != nil || len(services) == 0 {
+		r.status = checkFail
+		r.detail = "no _saturn._tcp services found via mDNS or UDP broadcast"
+		if err != nil {
+			r.detail += ": " + err.Error()
+		}
+		r.fix = "Start a Saturn server or beacon on this network (https://github.com/jperrello/Saturn), or raise -timeout"
+		return nil, r
+	}
+
+	r.detail = fmt.Sprintf("found %d service(s)", len(services))
+	return services, r
+}
+
+func checkService(svc provider.SaturnService) checkResult {
+	r := checkResult{name: "  " + svc.Name}
+
+	if svc.APIBase == "" {
+		addr := net.JoinHostPort(svc.Host, strconv.Itoa(svc.Port))
+		conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+		if err != nil {
+			r.status = checkFail
+			r.detail = fmt.Sprintf("%s unreachable: %v", addr, err)
+			r.fix = "Check that the server is running and that no firewall blocks port " + strconv.Itoa(svc.Port)
+			return r
+		}
+		conn.Close()
+	}
+
+	if err := svc.CheckHealth(); err != nil {
+		r.status = checkWarn
+		r.detail = fmt.Sprintf("%s reachable but unhealthy: %v", svc.URL(), err)
+		r.fix = "Check the server logs; BRUTUS will skip it in favour of healthy services"
+		return r
+	}
+
+	r.detail = fmt.Sprintf("%s healthy (priority %d)", svc.URL(), svc.Priority)
+	return r
+}
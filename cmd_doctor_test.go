@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckDNSSD(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+
+	// Discovery falls back to UDP broadcast without dns-sd, so its absence
+	// is only a warning.
+	r := checkDNSSD()
+	if r.status != checkWarn || !strings.Contains(r.detail, "broadcast") {
+		t.Errorf("without dns-sd got status %d, %q; want a warning naming the broadcast fallback", r.status, r.detail)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	path := filepath.Join(dir, "dns-sd")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if r := checkDNSSD(); r.status != checkOK || r.detail != path {
+		t.Errorf("with dns-sd got status %d, %q; want ok with its path", r.status, r.detail)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"brutus/config"
+	"brutus/provider"
+)
+
+// runModels lists the models offered by Saturn, or pins one for the current
+// project with `brutus models set <id>`.
+func runModels(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "set":
+			setPinnedModel(args[1:])
+			return
+		case "unset":
+			unsetPinnedModel()
+			return
+		}
+	}
+
+	fs := flag.NewFlagSet("models", flag.ExitOnError)
+	flags := registerAgentFlags(fs)
+	opts := registerModelsFlags(fs)
+	fs.Parse(args)
+
+	var prov provider.Provider
+	if *opts.pool {
+		setupLogging(*flags.verbose)
+		flags.applyConfig()
+		p, err := provider.NewSaturnPool(context.Background(), provider.SaturnPoolConfig{
+			DiscoveryTimeout: *flags.timeout,
+			Model:            *flags.model,
+			Service:          *flags.service,
+			Filter:           flags.discovery.filter(),
+			Scorer:           flags.scorer,
+			Retry:            flags.retry,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		prov = p
+	} else {
+		prov = flags.setup()
+	}
+
+	models, err := listAllModels(context.Background(), prov, *opts.pool)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *opts.asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(models)
+		return
+	}
+
+	current := prov.GetModel()
+	if len(models) == 0 {
+		fmt.Println("No models reported by", prov.Name())
+		return
+	}
+	for _, m := range models {
+		marker := " "
+		if m.ID == current {
+			marker = "*"
+		}
+		if m.Name != "" && m.Name != m.ID {
+			fmt.Printf("%s %s (%s)\n", marker, m.ID, m.Name)
+		} else {
+			fmt.Printf("%s %s\n", marker, m.ID)
+		}
+	}
+	if current == "" {
+		fmt.Println("\nNo model pinned; the server picks its default. Pin one with 'brutus models set <id>'.")
+	}
+}
+
+// modelsFlags are models' own flags, besides the agent flags.
+type modelsFlags struct {
+	pool   *bool
+	asJSON *bool
+}
+
+func registerModelsFlags(fs *flag.FlagSet) *modelsFlags {
+	return &modelsFlags{
+		pool:   fs.Bool("pool", false, "List models from every discovered service instead of the selected one"),
+		asJSON: fs.Bool("json", false, "Print models as JSON"),
+	}
+}
+
+// listAllModels returns the provider's models. For a pool it asks each
+// service in turn, since ListModels on the pool only queries the next one.
+func listAllModels(ctx context.Context, prov provider.Provider, all bool) ([]provider.ModelInfo, error) {
+	pool, ok := prov.(*provider.SaturnPool)
+	if !all || !ok {
+		return prov.ListModels(ctx)
+	}
+
+	seen := map[string]bool{}
+	var models []provider.ModelInfo
+	var lastErr error
+	for _, svc := range pool.GetServices() {
+		list, err := pool.ListServiceModels(ctx, &svc)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, m := range list {
+			if !seen[m.ID] {
+				seen[m.ID] = true
+				models = append(models, m)
+			}
+		}
+	}
+	if len(models) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return models, nil
+}
+
+func setPinnedModel(args []string) {
+	if len(args) != 1 || args[0] == "" {
+		fmt.Fprintln(os.Stderr, "Usage: brutus models set <model-id>")
+		os.Exit(1)
+	}
+
+	path := config.ProjectPath()
+	if err := config.SetValue(path, "model", args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Pinned model %s in %s\n", args[0], path)
+}
+
+func unsetPinnedModel() {
+	path := config.ProjectPath()
+	if err := config.SetValue(path, "model", nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed pinned model from %s\n", path)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+	"brutus/internal/theme"
+	"brutus/provider"
+	"brutus/session"
+	"brutus/tools"
+)
+
+// maxReplayGap caps how long -timing waits between two messages, so idle
+// time in the original session doesn't stall the replay.
+const maxReplayGap = 3 * time.Second
+
+// runReplay re-renders a saved session or transcript, optionally forking it
+// into a live chat from a given turn.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags := registerAgentFlags(fs)
+	opts := registerReplayFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: brutus replay [flags] <session.json|transcript.jsonl>")
+		os.Exit(1)
+	}
+
+	src, err := session.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	turns := src.Turns()
+	if *opts.fromTurn > turns {
+		fmt.Fprintf(os.Stderr, "Error: session has only %d turn(s)\n", turns)
+		os.Exit(1)
+	}
+
+	records := src.UpToTurn(*opts.fromTurn)
+	fmt.Println(theme.Muted(fmt.Sprintf("Session %s: %d turn(s), %d message(s), started %s",
+		src.ID, turns, len(src.Records), src.Created.Format(time.RFC1123))))
+
+	var prev time.Time
+	lastTurn := 0
+	for _, r := range records {
+		if *opts.timing && !prev.IsZero() && *opts.speed > 0 {
+			gap := time.Duration(float64(r.Time.Sub(prev)) / *opts.speed)
+			if gap > maxReplayGap {
+				gap = maxReplayGap
+			}
+			if gap > 0 {
+				time.Sleep(gap)
+			}
+		}
+		prev = r.Time
+
+		if r.Turn != lastTurn {
+			lastTurn = r.Turn
+			fmt.Println()
+			fmt.Println(theme.Muted(fmt.Sprintf("--- turn %d · %s ---", r.Turn, r.Time.Format("15:04:05"))))
+		}
+		renderMessage(r.Message)
+	}
+	fmt.Println()
+
+	if !*opts.fork {
+		return
+	}
+
+	forked := src.Fork(*opts.fromTurn)
+	fmt.Println(theme.Muted(fmt.Sprintf("Forked %s at turn %d as session %s", src.ID, lastTurn, forked.ID)))
+	startChat(flags, forked, *opts.transcript, true)
+}
+
+// replayFlags are replay's own flags, besides the agent flags.
+type replayFlags struct {
+	timing     *bool
+	speed      *float64
+	fromTurn   *int
+	fork       *bool
+	transcript *string
+}
+
+func registerReplayFlags(fs *flag.FlagSet) *replayFlags {
+	return &replayFlags{
+		timing:     fs.Bool("timing", false, "Reproduce the original pacing between messages (gaps capped at 3s)"),
+		speed:      fs.Float64("speed", 1, "Playback speed multiplier for -timing"),
+		fromTurn:   fs.Int("from-turn", 0, "Replay only up to and including this turn"),
+		fork:       fs.Bool("fork", false, "After replaying, continue the conversation live from that point"),
+		transcript: fs.String("transcript", "", "With -fork, record the forked session to this file"),
+	}
+}
+
+// renderMessage prints a stored message the way the live chat shows it.
+func renderMessage(msg provider.Message) {
+	switch {
+	case len(msg.ToolResults) > 0:
+		for _, tr := range msg.ToolResults {
+			label := theme.Result("[result]")
+			if tr.IsError {
+				label = theme.Error("[error]")
+			}
+			fmt.Printf("%s %s\n", label, clip(tr.Content, 500))
+		}
+	case msg.Role == "user":
+		fmt.Printf("%s: %s\n", theme.User("You"), msg.Content)
+	default:
+		if msg.Content != "" {
+			fmt.Printf("%s: %s\n", theme.Assistant("BRUTUS"), msg.Content)
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Printf("%s %s %s\n", theme.Tool("[tool]"), tc.Name, theme.Muted(clip(tools.RedactInput(tc.Input), 200)))
+		}
+	}
+}
+
+func clip(s string, max int) string {
+	return text.Head(strings.TrimSpace(s), max)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"brutus/agent"
+	"brutus/provider"
+	"brutus/session"
+)
+
+// runPrompt answers a single prompt without an interactive session. The
+// prompt comes from the arguments, or from stdin when none are given, and
+// only the final answer is written to stdout.
+func runPrompt(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	flags := registerAgentFlags(fs)
+	opts := registerRunFlags(fs)
+	fs.Parse(args)
+
+	prompt := strings.Join(fs.Args(), " ")
+	if prompt == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading prompt: %v\n", err)
+			os.Exit(1)
+		}
+		prompt = strings.TrimSpace(string(data))
+	}
+	if prompt == "" {
+		fmt.Fprintln(os.Stderr, "Usage: brutus run [flags] <prompt>")
+		os.Exit(1)
+	}
+
+	prov := flags.setup()
+	// Keep stdout for the answer so the command can be piped.
+	if *flags.logFile == "" {
+		log.SetOutput(os.Stderr)
+	}
+
+	// -tools limits subagents too: they are given tools from this registry.
+	registry := cliTools()
+	registerSemanticSearch(registry, prov)
+	registerSubagents(registry, prov, flags, os.Stderr)
+	if *opts.allowed != "" {
+		if err := restrictTools(registry, strings.Split(*opts.allowed, ",")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
+
+	absWorkDir, _ := os.Getwd()
+	sess := session.New()
+	sess.Model = prov.GetModel()
+	sess.WorkingDir = absWorkDir
+
+	var onMessage func(int, provider.Message)
+	var onDescribe func(session.Info)
+	if *opts.transcript != "" {
+		tr, err := session.OpenTranscript(*opts.transcript, sess)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot write transcript: %v\n", err)
+			exit(1)
+		}
+		onShutdown(func() { tr.Close() })
+		onMessage = func(turn int, msg provider.Message) {
+			if err := tr.Record(turn, msg); err != nil {
+				log.Printf("transcript write failed: %v", err)
+			}
+		}
+		onDescribe = func(info session.Info) {
+			if err := tr.Describe(info); err != nil {
+				log.Printf("transcript write failed: %v", err)
+			}
+		}
+	}
+
+	a := agent.New(agent.Config{
+		Provider:         prov,
+		Tools:            registry,
+		SystemPrompt:     loadSystemPrompt(),
+		Verbose:          *flags.verbose,
+		WorkingDir:       absWorkDir,
+		Logger:           flags.logger,
+		SessionID:        sess.ID,
+		OnMessage:        onMessage,
+		OnDescribe:       onDescribe,
+		Output:           os.Stderr,
+		MaxMessages:      *flags.maxMsgs,
+		ScanToolOutput:   *flags.injection,
+		CacheToolResults: *flags.toolCache,
+
+		VerificationCommands: flags.verify,
+		Env:                  flags.env,
+		ToolEnv:              flags.toolEnv,
+		ArtifactThreshold:    *flags.artifacts,
+		Audit:                flags.openAudit(),
+		TurnTimeout:          *flags.turnLimit,
+		ContextBudget:        *flags.budget,
+		ResultLimits:         flags.resultLimits(),
+	})
+	onShutdown(func() { a.Close() })
+
+	answer, err := a.Prompt(signalContext(), prompt)
+	if err != nil {
+		// A timed out turn still has its partial answer to show.
+		if answer != "" {
+			fmt.Println(answer)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(1)
+	}
+	fmt.Println(answer)
+}
+
+// runFlags are run's own flags, besides the agent flags.
+type runFlags struct {
+	transcript *string
+	allowed    *string
+}
+
+func registerRunFlags(fs *flag.FlagSet) *runFlags {
+	return &runFlags{
+		transcript: fs.String("transcript", "", "Record the conversation to this file (.jsonl for a JSONL transcript, otherwise a JSON session file)"),
+		allowed:    fs.String("tools", "", "Comma-separated tools the agent may use (default all)"),
+	}
+}
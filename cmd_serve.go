@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"brutus/agent"
+	"brutus/audit"
+	"brutus/config"
+	"brutus/provider"
+	"brutus/scheduler"
+)
+
+type serveRequest struct {
+	Prompt string `json:"prompt"`
+}
+
+type serveResponse struct {
+	Response string `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// runServe starts a headless HTTP server. Each POST /run gets a fresh agent
+// that answers the prompt and returns the final text as JSON. Tasks from
+// the config run on their cron schedules while the server is up.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags := registerAgentFlags(fs)
+	opts := registerServeFlags(fs)
+	fs.Parse(args)
+
+	prov := flags.setup()
+	srv := &server{
+		provider:     prov,
+		systemPrompt: loadSystemPrompt(),
+		verbose:      *flags.verbose,
+		logger:       flags.logger,
+		scanOutput:   *flags.injection,
+		cacheTools:   *flags.toolCache,
+		verify:       flags.verify,
+		env:          flags.env,
+		toolEnv:      flags.toolEnv,
+		artifacts:    *flags.artifacts,
+		audit:        flags.openAudit(),
+		turnLimit:    *flags.turnLimit,
+		budget:       *flags.budget,
+		results:      flags.resultLimits(),
+		scheduler:    scheduler.New(flags.logger),
+	}
+
+	// setup has already warned about a config that doesn't load.
+	cfg, _ := config.Load()
+	if cfg != nil && len(cfg.Tasks) > 0 {
+		runner, err := newTaskRunner(fs, flags.logger)
+		if err == nil {
+			err = scheduleTasks(srv.scheduler, runner, cfg.Tasks)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", srv.handleHealth)
+	mux.HandleFunc("/run", srv.handleRun)
+	mux.HandleFunc("/tasks", srv.handleTasks)
+
+	httpSrv := &http.Server{Addr: *opts.addr, Handler: mux}
+	ctx := signalContext()
+	schedulerDone := make(chan struct{})
+	go func() {
+		srv.scheduler.Run(ctx)
+		close(schedulerDone)
+	}()
+	for _, job := range srv.scheduler.Jobs() {
+		log.Printf("Task %s scheduled (%s), next run %s", job.Name, job.Spec, job.Next.Format(time.RFC1123))
+	}
+	go func() {
+		<-ctx.Done()
+		// Stop accepting requests and let in-flight runs finish.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		httpSrv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("BRUTUS serving on http://%s", *opts.addr)
+	if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Server error: %v", err)
+		exit(1)
+	}
+	<-schedulerDone
+	log.Printf("Server stopped")
+}
+
+// serveFlags are serve's own flags, besides the agent flags.
+type serveFlags struct {
+	addr *string
+}
+
+func registerServeFlags(fs *flag.FlagSet) *serveFlags {
+	return &serveFlags{
+		addr: fs.String("addr", "127.0.0.1:8420", "Address to listen on"),
+	}
+}
+
+type server struct {
+	provider     provider.Provider
+	systemPrompt string
+	verbose      bool
+	logger       *slog.Logger
+	scanOutput   bool
+	cacheTools   bool
+	verify       []string
+	env          map[string]string
+	toolEnv      map[string]map[string]string
+	artifacts    int
+	audit        *audit.Log
+	turnLimit    time.Duration
+	budget       int
+	results      agent.ResultLimits
+	scheduler    *scheduler.Scheduler
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":   "ok",
+		"version":  Version,
+		"provider": s.provider.Name(),
+	})
+}
+
+// handleTasks lists the scheduled tasks with their next and last runs.
+func (s *server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.scheduler.Jobs())
+}
+
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, serveResponse{Error: "use POST"})
+		return
+	}
+
+	var req serveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, serveResponse{Error: "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.Prompt == "" {
+		writeJSON(w, http.StatusBadRequest, serveResponse{Error: "prompt is required"})
+		return
+	}
+
+	answer, err := s.run(r.Context(), req.Prompt)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, serveResponse{Response: answer, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, serveResponse{Response: answer})
+}
+
+func (s *server) run(ctx context.Context, prompt string) (string, error) {
+	absWorkDir, _ := os.Getwd()
+	a := agent.New(agent.Config{
+		Provider:         s.provider,
+		Tools:            cliTools(),
+		SystemPrompt:     s.systemPrompt,
+		Verbose:          s.verbose,
+		WorkingDir:       absWorkDir,
+		Output:           io.Discard,
+		Logger:           s.logger,
+		ScanToolOutput:   s.scanOutput,
+		CacheToolResults: s.cacheTools,
+
+		VerificationCommands: s.verify,
+		Env:                  s.env,
+		ToolEnv:              s.toolEnv,
+		ArtifactThreshold:    s.artifacts,
+		Audit:                s.audit,
+		TurnTimeout:          s.turnLimit,
+		ContextBudget:        s.budget,
+		ResultLimits:         s.results,
+	})
+	defer a.Close()
+	return a.Prompt(ctx, prompt)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"brutus/config"
+	"brutus/tools"
+)
+
+// runTools lists the CLI tools, or executes one when given a name and JSON
+// input. Tools the config disables are left out, as in chat.
+func runTools(args []string) {
+	if cfg, err := config.Load(); err == nil {
+		tools.ConfigureTools(toolPolicies(cfg))
+	}
+	registry := cliTools()
+
+	if len(args) == 0 {
+		fmt.Println("Available tools:")
+		fmt.Println()
+		for _, name := range registry.Names() {
+			tool, _ := registry.Get(name)
+			fmt.Printf("  %-15s %s\n", name, tool.Description)
+		}
+		return
+	}
+
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: brutus tools <name> <json-input>")
+		os.Exit(1)
+	}
+
+	tool, ok := registry.Get(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: tool '%s' not found\n", args[0])
+		os.Exit(1)
+	}
+
+	input := strings.Join(args[1:], " ")
+	if !json.Valid([]byte(input)) {
+		fmt.Fprintf(os.Stderr, "Error: invalid JSON input: %s\n", input)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	result, err := tool.Call(ctx, json.RawMessage(input))
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", tools.CodeOf(err), err)
+		os.Exit(1)
+	}
+	fmt.Println(result)
+}
@@ -0,0 +1,481 @@
+// Package config loads BRUTUS settings from JSON files.
+//
+// Two files are read, later ones overriding earlier ones:
+//
+//	~/.brutus/config.json   (global)
+//	.brutus/config.json     (project, relative to the working directory)
+//
+// String values may reference environment variables as {env:NAME}.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Config holds user-configurable settings. Zero values mean "not set".
+type Config struct {
+	Model     string `json:"model,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+
+	// Service pins every request to the Saturn service with this name.
+	Service string `json:"service,omitempty"`
+
+	// Sticky spreads conversations over every Saturn service discovered,
+	// keeping each on the service that answered it last.
+	Sticky *bool `json:"sticky,omitempty"`
+
+	// BaseURL is an OpenAI-compatible endpoint (OpenRouter, vLLM, LM
+	// Studio) to use instead of discovering Saturn services, and APIKey
+	// its key. Use {env:NAME} rather than writing a key into the file.
+	BaseURL string `json:"base_url,omitempty"`
+	APIKey  string `json:"api_key,omitempty"`
+
+	// Endpoint is a Saturn server's address, such as http://host:8080,
+	// to use instead of discovering one where multicast DNS is blocked.
+	// APIKey is its key too.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// EmbeddingModel is the model semantic_search indexes code with, on
+	// Saturn services and OpenAI-compatible endpoints. By default it is
+	// a model the service advertises with "embed" in its name.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+
+	// Discovery restricts which discovered Saturn services may be used.
+	Discovery Discovery `json:"discovery,omitempty"`
+
+	// ServiceWeights, if set, chooses between the services discovery
+	// finds by a weighted score instead of by priority alone.
+	ServiceWeights *ServiceWeights `json:"service_weights,omitempty"`
+
+	// RateLimit applies to every Saturn service; RateLimits overrides it
+	// for individual services, keyed by service name.
+	RateLimit  RateLimit            `json:"rate_limit,omitempty"`
+	RateLimits map[string]RateLimit `json:"rate_limits,omitempty"`
+
+	// InjectionCheck turns the prompt-injection scan of tool output on or
+	// off. It is a pointer because the default is on.
+	InjectionCheck *bool `json:"injection_check,omitempty"`
+
+	// ToolCache lets repeated read_file and code_search calls reuse earlier
+	// results within a session. A pointer because the default is on.
+	ToolCache *bool `json:"tool_cache,omitempty"`
+
+	// ToolCalling is "auto", "native" or "emulated": whether tools are sent
+	// in the request or described in the system prompt. Auto follows what
+	// the Saturn service advertises.
+	ToolCalling string `json:"tool_calling,omitempty"`
+
+	// ThinkingBudget lets models that support extended thinking spend up
+	// to this many tokens on it. Zero leaves thinking off.
+	ThinkingBudget int `json:"thinking_budget,omitempty"`
+
+	// VerificationCommands are checks (build, tests, lint) run when the
+	// model finishes a turn in which it used tools; failures go back to it
+	// to fix before the turn ends.
+	VerificationCommands []string `json:"verification_commands,omitempty"`
+
+	// ArtifactThreshold is the size in bytes above which tool results are
+	// archived under .brutus/artifacts and read back with read_artifact.
+	// Negative keeps every result whole.
+	ArtifactThreshold int `json:"artifact_threshold,omitempty"`
+
+	// ToolResults cuts or summarizes the large tool results that are not
+	// archived before the model sees them.
+	ToolResults ToolResults `json:"tool_results,omitempty"`
+
+	// MaxRunningAgents limits how many GUI agents work on a turn at once;
+	// the rest wait for a slot. Zero is no limit.
+	MaxRunningAgents int `json:"max_running_agents,omitempty"`
+
+	// AuditLog is a file every tool call agents run is recorded in, with
+	// hash chaining so later edits show; see `brutus audit verify`. Empty
+	// is off.
+	AuditLog string `json:"audit_log,omitempty"`
+
+	// TurnTimeout stops a turn that runs longer than this duration ("10m"),
+	// keeping what the model and tools produced so far. Empty is no limit.
+	TurnTimeout string `json:"turn_timeout,omitempty"`
+
+	// ContextBudget is the estimated prompt size, in tokens, above which
+	// older turns are summarized into a note. Zero is three quarters of
+	// the model's context window when known; negative is off.
+	ContextBudget int `json:"context_budget,omitempty"`
+
+	// Env adds environment variables to the commands agents run through
+	// bash, run_tests, format and python_exec, such as a test database's
+	// DATABASE_URL. BRUTUS's own environment is left as it is.
+	Env map[string]string `json:"env,omitempty"`
+
+	// ToolEnv adds variables for one tool only, keyed by tool name,
+	// overriding Env.
+	ToolEnv map[string]map[string]string `json:"tool_env,omitempty"`
+
+	// Diagnostics maps file extensions (".go") to the command run after
+	// edit_file changes such a file. An empty command disables the check.
+	Diagnostics map[string]string `json:"diagnostics,omitempty"`
+
+	// Retry is how requests to the model are retried when they fail for a
+	// reason that may pass, such as a busy or restarting server. Unset is
+	// three attempts.
+	Retry *RequestRetry `json:"retry,omitempty"`
+
+	// Sampling sets the sampling parameters sent with model requests;
+	// those left out are the service's defaults.
+	Sampling *Sampling `json:"sampling,omitempty"`
+
+	// ToolRetries overrides how failed calls of a tool are retried, keyed
+	// by tool name.
+	ToolRetries map[string]ToolRetry `json:"tool_retries,omitempty"`
+
+	// Tools disables tools or limits them, keyed by tool name. The key
+	// "*" applies to every tool, under the tool's own settings.
+	Tools map[string]ToolConfig `json:"tools,omitempty"`
+
+	// Forges holds API access for the pull request and issue tools, keyed
+	// by host ("github.com", "gitlab.example.com").
+	Forges map[string]Forge `json:"forges,omitempty"`
+
+	// Sandbox sets where the file tools may read and write.
+	Sandbox Sandbox `json:"sandbox,omitempty"`
+
+	// Tasks are headless runs that `brutus serve` starts on a schedule,
+	// keyed by task name.
+	Tasks map[string]Task `json:"tasks,omitempty"`
+}
+
+// Forge is API access to a GitHub or GitLab host.
+type Forge struct {
+	Token  string `json:"token"`
+	Type   string `json:"type,omitempty"`    // "github" or "gitlab"; guessed from the host
+	APIURL string `json:"api_url,omitempty"` // for self-hosted instances
+}
+
+// Sandbox confines the file tools to a workspace.
+type Sandbox struct {
+	Root  string   `json:"root,omitempty"`  // default the working directory; "/" turns confinement off
+	Allow []string `json:"allow,omitempty"` // further directories the tools may reach, relative to Root if not absolute
+}
+
+// ToolResults bounds the tool results sent to the model. Zero fields are
+// the defaults.
+type ToolResults struct {
+	MaxBytes       int `json:"max_bytes,omitempty"`       // cut longer results to head and tail; negative is off
+	SummarizeBytes int `json:"summarize_bytes,omitempty"` // have the model condense longer results; default off
+	SummaryTokens  int `json:"summary_tokens,omitempty"`  // cap on a summary
+}
+
+// ToolConfig is the policy for one tool. Zero fields leave it as it is.
+type ToolConfig struct {
+	Disabled    bool   `json:"disabled,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`      // stop a call after this long, e.g. "2m"
+	MaxOutput   int    `json:"max_output,omitempty"`   // characters of a result kept
+	SandboxRoot string `json:"sandbox_root,omitempty"` // directory its path arguments must lie in
+}
+
+// Task is a scheduled headless run.
+type Task struct {
+	Cron      string   `json:"cron"` // five-field cron expression or @daily style macro
+	Prompt    string   `json:"prompt"`
+	Workspace string   `json:"workspace,omitempty"` // directory to run in; default the server's
+	Tools     []string `json:"tools,omitempty"`     // tools the run may use; default all
+	Webhook   string   `json:"webhook,omitempty"`   // URL each run's result is POSTed to
+}
+
+// RequestRetry is the retry policy for model requests.
+type RequestRetry struct {
+	MaxAttempts      int   `json:"max_attempts"`                 // the first included; 1 is no retries
+	BaseDelayMS      int   `json:"base_delay_ms,omitempty"`      // before the first retry, doubling after; default 1000
+	MaxDelayMS       int   `json:"max_delay_ms,omitempty"`       // cap on a wait; default 60000
+	StatusCodes      []int `json:"status_codes,omitempty"`       // default 429, 500, 502, 503, 504 and 529
+	IgnoreRetryAfter bool  `json:"ignore_retry_after,omitempty"` // back off as usual even when told how long to wait
+}
+
+// Sampling holds sampling parameters for model requests. A low
+// temperature and a fixed seed make coding sessions more repeatable.
+type Sampling struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"` // not sent to the Anthropic API
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`  // nor this
+}
+
+// ToolRetry is the retry policy for one tool.
+type ToolRetry struct {
+	MaxRetries int      `json:"max_retries"`
+	DelayMS    int      `json:"delay_ms,omitempty"`
+	On         []string `json:"on,omitempty"` // error codes; default internal and timeout
+}
+
+// Discovery filters Saturn services. Zero fields don't filter.
+type Discovery struct {
+	MinPriority   int    `json:"min_priority,omitempty"` // lower is preferred
+	RequiredModel string `json:"required_model,omitempty"`
+	RequireGPU    bool   `json:"require_gpu,omitempty"`
+	MinVRAMGb     int    `json:"min_vram_gb,omitempty"`
+	LocalOnly     bool   `json:"local_only,omitempty"` // skip services proxying a remote API
+}
+
+// ServiceWeights weigh what services are chosen on. Each input is scaled
+// to 0..1, 1 being best, and the service with the highest weighted sum is
+// used. Negative weights count against a service.
+type ServiceWeights struct {
+	Priority float64 `json:"priority,omitempty"` // Lower announced priority
+	Load     float64 `json:"load,omitempty"`     // More free capacity
+	Latency  float64 `json:"latency,omitempty"`  // Faster health checks
+	Model    float64 `json:"model,omitempty"`    // Announces the requested model
+	GPU      float64 `json:"gpu,omitempty"`      // Announces a GPU
+	Local    float64 `json:"local,omitempty"`    // Serves models itself rather than proxying a remote API
+}
+
+// RateLimit caps requests to a Saturn service. Zero fields are unlimited.
+type RateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	MaxConcurrent     int `json:"max_concurrent,omitempty"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty"`
+}
+
+// Dir is the per-project directory BRUTUS keeps its files in.
+const Dir = ".brutus"
+
+const fileName = "config.json"
+
+var envVarRe = regexp.MustCompile(`\{env:([^}]+)\}`)
+
+// GlobalPath returns the path of the user-wide config file.
+func GlobalPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, Dir, fileName)
+}
+
+// ProjectPath returns the path of the config file for the current project.
+func ProjectPath() string {
+	return filepath.Join(Dir, fileName)
+}
+
+// Paths returns the config files in the order they are applied.
+func Paths() []string {
+	var paths []string
+	if global := GlobalPath(); global != "" {
+		paths = append(paths, global)
+	}
+	return append(paths, ProjectPath())
+}
+
+// Load reads and merges every config file that exists. Missing files are
+// not an error; malformed ones are.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	for _, path := range Paths() {
+		fileCfg, err := LoadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return cfg, err
+		}
+		cfg.merge(fileCfg)
+	}
+	return cfg, nil
+}
+
+// LoadFile reads a single config file, interpolating {env:NAME} references
+// and rejecting unknown fields.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func parse(data []byte) (*Config, error) {
+	data = envVarRe.ReplaceAllFunc(data, func(match []byte) []byte {
+		name := envVarRe.FindSubmatch(match)[1]
+		value, _ := json.Marshal(os.Getenv(string(name)))
+		// Strip the quotes: the reference already sits inside a JSON string.
+		return value[1 : len(value)-1]
+	})
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) merge(other *Config) {
+	if other.Model != "" {
+		c.Model = other.Model
+	}
+	if other.MaxTokens != 0 {
+		c.MaxTokens = other.MaxTokens
+	}
+	if other.Service != "" {
+		c.Service = other.Service
+	}
+	if other.Sticky != nil {
+		c.Sticky = other.Sticky
+	}
+	if other.BaseURL != "" {
+		c.BaseURL = other.BaseURL
+	}
+	if other.APIKey != "" {
+		c.APIKey = other.APIKey
+	}
+	if other.Endpoint != "" {
+		c.Endpoint = other.Endpoint
+	}
+	if other.EmbeddingModel != "" {
+		c.EmbeddingModel = other.EmbeddingModel
+	}
+	if other.Discovery != (Discovery{}) {
+		c.Discovery = other.Discovery
+	}
+	if other.ServiceWeights != nil {
+		c.ServiceWeights = other.ServiceWeights
+	}
+	if other.RateLimit != (RateLimit{}) {
+		c.RateLimit = other.RateLimit
+	}
+	if other.InjectionCheck != nil {
+		c.InjectionCheck = other.InjectionCheck
+	}
+	if other.ToolCache != nil {
+		c.ToolCache = other.ToolCache
+	}
+	if other.ToolCalling != "" {
+		c.ToolCalling = other.ToolCalling
+	}
+	if other.ThinkingBudget != 0 {
+		c.ThinkingBudget = other.ThinkingBudget
+	}
+	if other.VerificationCommands != nil {
+		c.VerificationCommands = other.VerificationCommands
+	}
+	if other.ArtifactThreshold != 0 {
+		c.ArtifactThreshold = other.ArtifactThreshold
+	}
+	if other.Retry != nil {
+		c.Retry = other.Retry
+	}
+	if other.Sampling != nil {
+		c.Sampling = other.Sampling
+	}
+	if other.ToolResults != (ToolResults{}) {
+		c.ToolResults = other.ToolResults
+	}
+	if other.MaxRunningAgents != 0 {
+		c.MaxRunningAgents = other.MaxRunningAgents
+	}
+	if other.AuditLog != "" {
+		c.AuditLog = other.AuditLog
+	}
+	if other.TurnTimeout != "" {
+		c.TurnTimeout = other.TurnTimeout
+	}
+	if other.ContextBudget != 0 {
+		c.ContextBudget = other.ContextBudget
+	}
+	for key, value := range other.Env {
+		if c.Env == nil {
+			c.Env = make(map[string]string)
+		}
+		c.Env[key] = value
+	}
+	for name, env := range other.ToolEnv {
+		if c.ToolEnv == nil {
+			c.ToolEnv = make(map[string]map[string]string)
+		}
+		c.ToolEnv[name] = env
+	}
+	for ext, cmd := range other.Diagnostics {
+		if c.Diagnostics == nil {
+			c.Diagnostics = make(map[string]string)
+		}
+		c.Diagnostics[ext] = cmd
+	}
+	for name, retry := range other.ToolRetries {
+		if c.ToolRetries == nil {
+			c.ToolRetries = make(map[string]ToolRetry)
+		}
+		c.ToolRetries[name] = retry
+	}
+	for name, tool := range other.Tools {
+		if c.Tools == nil {
+			c.Tools = make(map[string]ToolConfig)
+		}
+		c.Tools[name] = tool
+	}
+	for host, forge := range other.Forges {
+		if c.Forges == nil {
+			c.Forges = make(map[string]Forge)
+		}
+		c.Forges[host] = forge
+	}
+	if other.Sandbox.Root != "" {
+		c.Sandbox.Root = other.Sandbox.Root
+	}
+	if other.Sandbox.Allow != nil {
+		c.Sandbox.Allow = other.Sandbox.Allow
+	}
+	for name, task := range other.Tasks {
+		if c.Tasks == nil {
+			c.Tasks = make(map[string]Task)
+		}
+		c.Tasks[name] = task
+	}
+	for name, limit := range other.RateLimits {
+		if c.RateLimits == nil {
+			c.RateLimits = make(map[string]RateLimit)
+		}
+		c.RateLimits[name] = limit
+	}
+}
+
+// SetValue writes key into the config file at path, creating it if needed.
+// Other keys are preserved verbatim, including {env:NAME} references. A nil
+// value removes the key.
+func SetValue(path, key string, value interface{}) error {
+	raw := map[string]interface{}{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	if value == nil {
+		delete(raw, key)
+	} else {
+		raw[key] = value
+	}
+
+	out, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0644)
+}
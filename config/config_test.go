@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseInterpolatesEnv(t *testing.T) {
+	t.Setenv("BRUTUS_TEST_MODEL", `llama "3"`)
+
+	cfg, err := parse([]byte(`{"model": "{env:BRUTUS_TEST_MODEL}"}`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if cfg.Model != `llama "3"` {
+		t.Errorf("expected interpolated model, got %q", cfg.Model)
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	if _, err := parse([]byte(`{"modle": "x"}`)); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestLoadProjectOverridesGlobal(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	writeFile(t, filepath.Join(home, Dir, fileName), `{"model": "global", "max_tokens": 100}`)
+	writeFile(t, filepath.Join(project, Dir, fileName), `{"model": "project"}`)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Model != "project" {
+		t.Errorf("expected project model, got %q", cfg.Model)
+	}
+	if cfg.MaxTokens != 100 {
+		t.Errorf("expected global max_tokens to survive, got %d", cfg.MaxTokens)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetValuePreservesOtherKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), Dir, fileName)
+	writeFile(t, path, `{"max_tokens": 100, "model": "{env:OLD_MODEL}"}`)
+
+	if err := SetValue(path, "model", "llama3"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Model != "llama3" || cfg.MaxTokens != 100 {
+		t.Errorf("unexpected config after SetValue: %+v", cfg)
+	}
+}
+
+func TestLoadDiscovery(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	writeFile(t, filepath.Join(home, Dir, fileName), `{"discovery": {"min_priority": 10, "require_gpu": true}}`)
+	writeFile(t, filepath.Join(project, Dir, fileName), `{"discovery": {"required_model": "big", "min_vram_gb": 24, "local_only": true}}`)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	// The project's filter replaces the global one as a whole.
+	want := Discovery{RequiredModel: "big", MinVRAMGb: 24, LocalOnly: true}
+	if cfg.Discovery != want {
+		t.Errorf("discovery = %+v, want %+v", cfg.Discovery, want)
+	}
+
+	if _, err := parse([]byte(`{"discovery": {"min_vram": 24}}`)); err == nil {
+		t.Error("expected error for unknown discovery field")
+	}
+}
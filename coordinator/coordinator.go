@@ -36,8 +36,23 @@ type Coordinator struct {
 	mu             sync.RWMutex
 	messageHandler func(AgentMessage)
 	stopCh         chan struct{}
+	stopOnce       sync.Once
+
+	// publish announces TXT records (server.SetText once registered).
+	// Changes are coalesced so it runs at most once per txtUpdateInterval.
+	publish      func([]string)
+	publishTimer *time.Timer
+	lastPublish  time.Time
 }
 
+// txtUpdateInterval is the minimum time between TXT record announcements.
+// Agents update their status several times per tool call; announcing each
+// change would flood the LAN with mDNS traffic.
+const txtUpdateInterval = 500 * time.Millisecond
+
+// maxTXTMessages is how many recent messages are carried in TXT records.
+const maxTXTMessages = 5
+
 func NewCoordinator(agentID string) *Coordinator {
 	return &Coordinator{
 		agentID: agentID,
@@ -70,7 +85,11 @@ func (c *Coordinator) Start(ctx context.Context, port int) error {
 		return fmt.Errorf("failed to register agent: %w", err)
 	}
 
+	c.mu.Lock()
 	c.server = server
+	c.publish = server.SetText
+	c.lastPublish = time.Now()
+	c.mu.Unlock()
 
 	go c.listenForAgents(ctx)
 
@@ -78,11 +97,20 @@ func (c *Coordinator) Start(ctx context.Context, port int) error {
 	return nil
 }
 
+// Stop unregisters the agent. It is safe to call more than once.
 func (c *Coordinator) Stop() {
-	close(c.stopCh)
-	if c.server != nil {
-		c.server.Shutdown()
-	}
+	c.stopOnce.Do(func() {
+		close(c.stopCh)
+		c.mu.Lock()
+		if c.publishTimer != nil {
+			c.publishTimer.Stop()
+		}
+		c.publish = nil
+		c.mu.Unlock()
+		if c.server != nil {
+			c.server.Shutdown()
+		}
+	})
 }
 
 func (c *Coordinator) UpdateStatus(status, task, action string) {
@@ -99,10 +127,7 @@ func (c *Coordinator) UpdateStatus(status, task, action string) {
 		c.status.LastAction = action
 	}
 	c.status.UpdatedAt = time.Now()
-
-	if c.server != nil {
-		c.server.SetText(c.buildTXTRecords())
-	}
+	c.scheduleTXTUpdate()
 }
 
 func (c *Coordinator) Broadcast(msgType, content string) error {
@@ -116,12 +141,9 @@ func (c *Coordinator) Broadcast(msgType, content string) error {
 
 	c.mu.Lock()
 	c.messages = append(c.messages, msg)
+	c.scheduleTXTUpdate()
 	c.mu.Unlock()
 
-	if c.server != nil {
-		c.server.SetText(c.buildTXTRecords())
-	}
-
 	return nil
 }
 
@@ -136,12 +158,9 @@ func (c *Coordinator) SendMessage(to, msgType, content string) error {
 
 	c.mu.Lock()
 	c.messages = append(c.messages, msg)
+	c.scheduleTXTUpdate()
 	c.mu.Unlock()
 
-	if c.server != nil {
-		c.server.SetText(c.buildTXTRecords())
-	}
-
 	return nil
 }
 
@@ -236,10 +255,40 @@ func (c *Coordinator) DiscoverMessages(ctx context.Context, timeout time.Duratio
 	return allMessages, nil
 }
 
+// scheduleTXTUpdate arranges for the current state to be announced. The
+// first change after a quiet period goes out right away; later ones within
+// txtUpdateInterval are batched into a single announcement at the end of
+// it. The caller must hold c.mu.
+func (c *Coordinator) scheduleTXTUpdate() {
+	if c.publish == nil || c.publishTimer != nil {
+		return
+	}
+	wait := max(0, txtUpdateInterval-time.Since(c.lastPublish))
+	c.publishTimer = time.AfterFunc(wait, c.flushTXT)
+}
+
+func (c *Coordinator) flushTXT() {
+	c.mu.Lock()
+	c.publishTimer = nil
+	publish := c.publish
+	if publish == nil {
+		c.mu.Unlock()
+		return
+	}
+	c.lastPublish = time.Now()
+	records := c.buildTXTRecordsLocked()
+	c.mu.Unlock()
+
+	publish(records)
+}
+
 func (c *Coordinator) buildTXTRecords() []string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.buildTXTRecordsLocked()
+}
 
+func (c *Coordinator) buildTXTRecordsLocked() []string {
 	records := []string{
 		fmt.Sprintf("agent_id=%s", c.status.AgentID),
 		fmt.Sprintf("status=%s", c.status.Status),
@@ -248,10 +297,10 @@ func (c *Coordinator) buildTXTRecords() []string {
 		fmt.Sprintf("updated=%d", c.status.UpdatedAt.Unix()),
 	}
 
-	for i, msg := range c.messages {
-		if i >= 5 {
-			break
-		}
+	// Carry the most recent messages; older ones have had their chance to
+	// be seen by peers polling every couple of seconds.
+	recent := c.messages[max(0, len(c.messages)-maxTXTMessages):]
+	for i, msg := range recent {
 		msgJSON, _ := json.Marshal(msg)
 		records = append(records, fmt.Sprintf("msg%d=%s", i, string(msgJSON)))
 	}
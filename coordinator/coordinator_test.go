@@ -0,0 +1,84 @@
+package coordinator
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder captures published TXT records in place of an mDNS server.
+type recorder struct {
+	mu      sync.Mutex
+	records [][]string
+}
+
+func (r *recorder) publish(txt []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, txt)
+}
+
+func (r *recorder) snapshot() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string(nil), r.records...)
+}
+
+func newTestCoordinator() (*Coordinator, *recorder) {
+	c := NewCoordinator("test")
+	r := &recorder{}
+	c.publish = r.publish
+	return c, r
+}
+
+func TestUpdateStatusCoalesced(t *testing.T) {
+	c, r := newTestCoordinator()
+
+	for i := 0; i < 20; i++ {
+		c.UpdateStatus("working", "task", "step")
+	}
+	c.UpdateStatus("idle", "", "done")
+
+	time.Sleep(txtUpdateInterval + 200*time.Millisecond)
+
+	published := r.snapshot()
+	if len(published) == 0 || len(published) > 2 {
+		t.Fatalf("21 updates produced %d announcements, want 1 or 2", len(published))
+	}
+	last := strings.Join(published[len(published)-1], " ")
+	if !strings.Contains(last, "status=idle") || !strings.Contains(last, "action=done") {
+		t.Errorf("last announcement does not reflect the final status: %s", last)
+	}
+}
+
+func TestMessagesBatchedAndRecent(t *testing.T) {
+	c, r := newTestCoordinator()
+
+	for i := 0; i < 8; i++ {
+		c.Broadcast("info", string(rune('a'+i)))
+	}
+	time.Sleep(txtUpdateInterval + 200*time.Millisecond)
+
+	published := r.snapshot()
+	if len(published) == 0 || len(published) > 2 {
+		t.Fatalf("8 messages produced %d announcements, want 1 or 2", len(published))
+	}
+	last := strings.Join(published[len(published)-1], " ")
+	if !strings.Contains(last, `"content":"h"`) || strings.Contains(last, `"content":"a"`) {
+		t.Errorf("announcement should carry the most recent messages: %s", last)
+	}
+}
+
+func TestStopCancelsPendingUpdate(t *testing.T) {
+	c, r := newTestCoordinator()
+	c.lastPublish = time.Now()
+
+	c.UpdateStatus("working", "", "")
+	c.Stop()
+	time.Sleep(txtUpdateInterval + 100*time.Millisecond)
+
+	if n := len(r.snapshot()); n != 0 {
+		t.Errorf("%d announcements after Stop, want 0", n)
+	}
+}
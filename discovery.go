@@ -0,0 +1,102 @@
+package main
+
+import (
+	"brutus/config"
+	"brutus/provider"
+)
+
+// discoveryFlags restrict which Saturn services a command may use.
+type discoveryFlags struct {
+	minPriority   *int
+	requiredModel *string
+	requireGPU    *bool
+	minVRAM       *int
+	localOnly     *bool
+}
+
+// applyConfig fills in filters from the config for flags not given on the
+// command line. set holds the names of the flags that were.
+func (d discoveryFlags) applyConfig(cfg config.Discovery, set map[string]bool) {
+	if !set["min-priority"] && cfg.MinPriority != 0 {
+		*d.minPriority = cfg.MinPriority
+	}
+	if !set["require-model"] && cfg.RequiredModel != "" {
+		*d.requiredModel = cfg.RequiredModel
+	}
+	if !set["require-gpu"] && cfg.RequireGPU {
+		*d.requireGPU = true
+	}
+	if !set["min-vram"] && cfg.MinVRAMGb != 0 {
+		*d.minVRAM = cfg.MinVRAMGb
+	}
+	if !set["local-only"] && cfg.LocalOnly {
+		*d.localOnly = true
+	}
+}
+
+// filter returns the provider filter, or nil if nothing is restricted.
+func (d discoveryFlags) filter() *provider.DiscoveryFilter {
+	return discoveryFilter(config.Discovery{
+		MinPriority:   *d.minPriority,
+		RequiredModel: *d.requiredModel,
+		RequireGPU:    *d.requireGPU,
+		MinVRAMGb:     *d.minVRAM,
+		LocalOnly:     *d.localOnly,
+	})
+}
+
+// discoveryFilter converts configured filters for the provider, returning
+// nil when none are set.
+func discoveryFilter(d config.Discovery) *provider.DiscoveryFilter {
+	if d == (config.Discovery{}) {
+		return nil
+	}
+	return &provider.DiscoveryFilter{
+		MinPriority:   d.MinPriority,
+		RequiredModel: d.RequiredModel,
+		RequiredGPU:   d.RequireGPU,
+		MinVRAM:       d.MinVRAMGb,
+		LocalOnly:     d.LocalOnly,
+	}
+}
+
+// serviceScorer returns the provider scorer for configured weights, or
+// nil, which selects by priority, when none are set.
+func serviceScorer(w *config.ServiceWeights) provider.Scorer {
+	if w == nil {
+		return nil
+	}
+	return provider.ScoreWeights(*w)
+}
+
+// DiscoveryFilterOptions is the discovery filter as the GUI edits it.
+type DiscoveryFilterOptions struct {
+	MinPriority   int    `json:"minPriority"`
+	RequiredModel string `json:"requiredModel"`
+	RequireGPU    bool   `json:"requireGpu"`
+	MinVRAMGb     int    `json:"minVramGb"`
+	LocalOnly     bool   `json:"localOnly"`
+}
+
+// SetDiscoveryFilter restricts which Saturn services agents created from
+// now on may use, overriding the config file. Running agents keep theirs.
+func (a *App) SetDiscoveryFilter(opts DiscoveryFilterOptions) {
+	a.sessionsMu.Lock()
+	defer a.sessionsMu.Unlock()
+	d := config.Discovery(opts)
+	a.discovery = &d
+}
+
+// GetDiscoveryFilter returns the filter new agents will use.
+func (a *App) GetDiscoveryFilter() DiscoveryFilterOptions {
+	a.sessionsMu.RLock()
+	d := a.discovery
+	a.sessionsMu.RUnlock()
+	if d != nil {
+		return DiscoveryFilterOptions(*d)
+	}
+	if cfg, err := config.Load(); err == nil {
+		return DiscoveryFilterOptions(cfg.Discovery)
+	}
+	return DiscoveryFilterOptions{}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"brutus/config"
+	"brutus/provider"
+)
+
+// TestDiscoveryFlagsOverConfig checks that discovery flags given on the
+// command line win over the config, which fills in the rest.
+func TestDiscoveryFlagsOverConfig(t *testing.T) {
+	fs := flag.NewFlagSet("chat", flag.ContinueOnError)
+	f := registerAgentFlags(fs)
+	if err := fs.Parse([]string{"-min-priority", "5", "-require-gpu"}); err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	f.discovery.applyConfig(config.Discovery{MinPriority: 10, RequiredModel: "big", LocalOnly: true}, set)
+	want := provider.DiscoveryFilter{MinPriority: 5, RequiredModel: "big", RequiredGPU: true, LocalOnly: true}
+	if got := f.discovery.filter(); got == nil || *got != want {
+		t.Errorf("filter = %+v, want %+v", got, want)
+	}
+
+	fs = flag.NewFlagSet("chat", flag.ContinueOnError)
+	f = registerAgentFlags(fs)
+	fs.Parse(nil)
+	f.discovery.applyConfig(config.Discovery{}, map[string]bool{})
+	if got := f.discovery.filter(); got != nil {
+		t.Errorf("with nothing set the filter is %+v, want nil", got)
+	}
+}
+
+// TestSetDiscoveryFilter checks that the GUI's filter replaces the
+// configured one for new agents.
+func TestSetDiscoveryFilter(t *testing.T) {
+	project := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	path := filepath.Join(project, config.Dir, "config.json")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(`{"discovery": {"min_vram_gb": 24}}`), 0644)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &App{}
+	if got := a.GetDiscoveryFilter(); got != (DiscoveryFilterOptions{MinVRAMGb: 24}) {
+		t.Errorf("before SetDiscoveryFilter got %+v, want the config's", got)
+	}
+
+	opts := DiscoveryFilterOptions{RequiredModel: "big", LocalOnly: true}
+	a.SetDiscoveryFilter(opts)
+	if got := a.GetDiscoveryFilter(); got != opts {
+		t.Errorf("got %+v, want %+v", got, opts)
+	}
+	want := provider.DiscoveryFilter{RequiredModel: "big", LocalOnly: true}
+	if got := discoveryFilter(*a.discovery); got == nil || *got != want {
+		t.Errorf("new agents get %+v, want %+v", got, want)
+	}
+}
@@ -1,6 +1,6 @@
 import { useState, useEffect, useRef, useCallback, useMemo } from 'react';
 import './App.css';
-import { NewAgent, GetAgents, SendMessage, GetVersion, StopAgent, RespondToApproval, LaunchMultiAgentDemo } from "../wailsjs/go/main/App";
+import { NewAgent, GetAgents, SendMessage, GetVersion, StopAgent, RespondToApproval, AnswerQuestion, SteerAgent, LaunchMultiAgentDemo, ListScenarios, LaunchScenario, SetTokenBudget, SetCostBudget, GetAgentLogs, SetAgentVerbose, AttachAgentPTY, PTYList, ForkSession, ListPrompts, SetSystemPrompt, GetPlan, SetPlanMode } from "../wailsjs/go/main/App";
 import { EventsOn } from "../wailsjs/runtime/runtime";
 import { DiffEditor } from '@monaco-editor/react';
 import { CommandPalette } from './components/CommandPalette';
@@ -15,11 +15,21 @@ interface Agent {
   serviceName?: string;
   serviceHost?: string;
   connected?: boolean;
+  tokenBudget?: number;
+  tokensUsed?: number;
+  costBudget?: number;
+  title?: string;
+  summary?: string;
+  parent?: string;
+  queuePosition?: number;
+  systemPrompt?: string;
+  messages?: Message[];
 }
 
 interface Message {
   role: string;
   content: string;
+  reasoning?: string;
   isTool?: boolean;
   isStreaming?: boolean;
 }
@@ -31,6 +41,13 @@ interface ApprovalRequest {
   arguments: string;
 }
 
+interface UserQuestion {
+  id: string;
+  agentId: string;
+  question: string;
+  choices: string[];
+}
+
 interface PendingDiff {
   id: string;
   file: string;
@@ -38,6 +55,50 @@ interface PendingDiff {
   modified: string;
 }
 
+interface LogEntry {
+  seq: number;
+  time: string;
+  level: string;
+  category: string;
+  message: string;
+}
+
+interface PromptInfo {
+  name: string;
+  description: string;
+  source: string;
+  text: string;
+}
+
+interface TodoItem {
+  id: number;
+  text: string;
+  status: string;
+}
+
+interface PlanState {
+  items: TodoItem[];
+  approved: boolean;
+}
+
+interface TurnTiming {
+  firstTokenMs: number;
+  inferenceMs: number;
+  toolMs: number;
+  requests: number;
+}
+
+function formatMs(ms: number): string {
+  return ms < 1000 ? `${ms}ms` : `${(ms / 1000).toFixed(1)}s`;
+}
+
+interface ScenarioInfo {
+  name: string;
+  description: string;
+  path: string;
+  agentCount: number;
+}
+
 interface CoordinationStatus {
   agent_id: string;
   status: string;
@@ -85,6 +146,61 @@ function ToolApprovalModal({ request, onApprove, onDeny }: {
   );
 }
 
+function QuestionModal({ question, onAnswer }: {
+  question: UserQuestion;
+  onAnswer: (answer: string) => void;
+}) {
+  const [answer, setAnswer] = useState('');
+
+  const handleKeyDown = (e: React.KeyboardEvent) => {
+    if (e.key === 'Enter' && answer.trim()) {
+      e.preventDefault();
+      onAnswer(answer.trim());
+    } else if (e.key === 'Escape') {
+      onAnswer('');
+    }
+  };
+
+  return (
+    <div className="approval-modal-overlay">
+      <div className="approval-modal question-modal">
+        <div className="approval-header">
+          <span className="approval-icon">❓</span>
+          <span className="approval-title">{question.agentId} has a question</span>
+        </div>
+        <div className="approval-content">
+          <p className="question-text">{question.question}</p>
+          {question.choices.length > 0 && (
+            <div className="question-choices">
+              {question.choices.map(choice => (
+                <button key={choice} className="question-choice" onClick={() => onAnswer(choice)}>
+                  {choice}
+                </button>
+              ))}
+            </div>
+          )}
+          <input
+            className="question-input"
+            autoFocus
+            value={answer}
+            onChange={e => setAnswer(e.target.value)}
+            onKeyDown={handleKeyDown}
+            placeholder={question.choices.length > 0 ? 'Or type your own answer...' : 'Type your answer...'}
+          />
+        </div>
+        <div className="approval-actions">
+          <button className="btn-deny" onClick={() => onAnswer('')}>
+            Skip (Esc)
+          </button>
+          <button className="btn-approve" disabled={!answer.trim()} onClick={() => onAnswer(answer.trim())}>
+            Answer
+          </button>
+        </div>
+      </div>
+    </div>
+  );
+}
+
 function DiffModal({ diff, onAccept, onReject }: {
   diff: PendingDiff;
   onAccept: () => void;
@@ -129,9 +245,20 @@ function AgentPanel({ agent, onSend, onStop }: {
   onStop: () => void;
 }) {
   const [input, setInput] = useState('');
-  const [messages, setMessages] = useState<Message[]>([]);
+  // A forked agent starts with the conversation it was copied from.
+  const [messages, setMessages] = useState<Message[]>(() => agent.messages || []);
   const [streamingContent, setStreamingContent] = useState('');
+  const [streamingReasoning, setStreamingReasoning] = useState('');
   const [approvalRequest, setApprovalRequest] = useState<ApprovalRequest | null>(null);
+  const [question, setQuestion] = useState<UserQuestion | null>(null);
+  const [showLogs, setShowLogs] = useState(false);
+  const [logs, setLogs] = useState<LogEntry[]>([]);
+  const [verbose, setVerbose] = useState(false);
+  const [ptyId, setPtyId] = useState('');
+  const [timing, setTiming] = useState<TurnTiming | null>(null);
+  const [plan, setPlan] = useState<PlanState | null>(null);
+  const [planMode, setPlanMode] = useState(false);
+  const [prompts, setPrompts] = useState<PromptInfo[]>([]);
   const messagesEndRef = useRef<HTMLDivElement>(null);
 
   const scrollToBottom = useCallback(() => {
@@ -140,7 +267,7 @@ function AgentPanel({ agent, onSend, onStop }: {
 
   useEffect(() => {
     scrollToBottom();
-  }, [messages, streamingContent, scrollToBottom]);
+  }, [messages, streamingContent, streamingReasoning, scrollToBottom]);
 
   useEffect(() => {
     const unsubStream = EventsOn('agent:stream', (data: { id: string; content: string }) => {
@@ -149,18 +276,26 @@ function AgentPanel({ agent, onSend, onStop }: {
       }
     });
 
-    const unsubMessage = EventsOn('agent:message', (data: { id: string; role: string; content: string }) => {
+    const unsubReasoning = EventsOn('agent:reasoning', (data: { id: string; content: string }) => {
+      if (data.id === agent.id) {
+        setStreamingReasoning(prev => prev + data.content);
+      }
+    });
+
+    const unsubMessage = EventsOn('agent:message', (data: { id: string; role: string; content: string; reasoning?: string }) => {
       if (data.id === agent.id) {
         setStreamingContent('');
-        setMessages(prev => [...prev, { role: data.role, content: data.content }]);
+        setStreamingReasoning('');
+        setMessages(prev => [...prev, { role: data.role, content: data.content, reasoning: data.reasoning }]);
       }
     });
 
     const unsubTool = EventsOn('agent:tool', (data: { id: string; tool: string }) => {
       if (data.id === agent.id) {
-        if (streamingContent) {
-          setMessages(prev => [...prev, { role: 'assistant', content: streamingContent }]);
+        if (streamingContent || streamingReasoning) {
+          setMessages(prev => [...prev, { role: 'assistant', content: streamingContent, reasoning: streamingReasoning }]);
           setStreamingContent('');
+          setStreamingReasoning('');
         }
         setMessages(prev => [...prev, { role: 'tool', content: `[${data.tool}]`, isTool: true }]);
       }
@@ -182,22 +317,89 @@ function AgentPanel({ agent, onSend, onStop }: {
       }
     });
 
+    const unsubQuestion = EventsOn('agent:question', (data: UserQuestion) => {
+      if (data.agentId === agent.id) {
+        setQuestion(data);
+      }
+    });
+
+    const unsubBudget = EventsOn('agent:budget', (data: { id: string; kind: string; used: number; budget: number; level: string }) => {
+      if (data.id === agent.id) {
+        const label = data.kind === 'cost' ? 'Cost budget' : 'Token budget';
+        const amount = data.kind === 'cost'
+          ? `$${data.used.toFixed(2)}/$${data.budget.toFixed(2)}`
+          : `${data.used}/${data.budget}`;
+        const text = data.level === 'exhausted'
+          ? `${label} exhausted (${amount})`
+          : `${label} ${Math.round(data.used / data.budget * 100)}% used (${amount})`;
+        setMessages(prev => [...prev, { role: 'error', content: text }]);
+      }
+    });
+
+    const unsubPTY = EventsOn('agent:pty', (data: { id: string; ptyId: string }) => {
+      if (data.id === agent.id) {
+        setPtyId(data.ptyId);
+      }
+    });
+
+    const unsubTiming = EventsOn('agent:timing', (data: TurnTiming & { id: string }) => {
+      if (data.id === agent.id) {
+        setTiming(data);
+      }
+    });
+
+    const unsubPlan = EventsOn('agent:plan', (data: { id: string; plan: PlanState }) => {
+      if (data.id === agent.id) {
+        setPlan(data.plan);
+      }
+    });
+
     const unsubError = EventsOn('agent:error', (data: { id: string; error: string }) => {
       if (data.id === agent.id) {
         setStreamingContent('');
+        setStreamingReasoning('');
         setMessages(prev => [...prev, { role: 'error', content: data.error }]);
       }
     });
 
     return () => {
       unsubStream();
+      unsubReasoning();
       unsubMessage();
       unsubTool();
       unsubToolResult();
       unsubApproval();
+      unsubQuestion();
+      unsubBudget();
+      unsubPTY();
+      unsubTiming();
+      unsubPlan();
       unsubError();
     };
-  }, [agent.id, streamingContent]);
+  }, [agent.id, streamingContent, streamingReasoning]);
+
+  useEffect(() => {
+    if (!showLogs) return;
+    GetAgentLogs(agent.id, 0).then(entries => setLogs(entries || []));
+    const unsubLog = EventsOn('agent:log', (data: { id: string; entry: LogEntry }) => {
+      if (data.id === agent.id) {
+        setLogs(prev => [...prev.slice(-999), data.entry]);
+      }
+    });
+    return () => unsubLog();
+  }, [agent.id, showLogs]);
+
+  useEffect(() => {
+    GetPlan(agent.id).then(p => setPlan(p)).catch(() => {});
+  }, [agent.id]);
+
+  const togglePlanMode = () => {
+    SetPlanMode(agent.id, !planMode).then(() => setPlanMode(!planMode));
+  };
+
+  const toggleVerbose = () => {
+    SetAgentVerbose(agent.id, !verbose).then(() => setVerbose(!verbose));
+  };
 
   useEffect(() => {
     const handleKeyDown = (e: KeyboardEvent) => {
@@ -228,7 +430,89 @@ function AgentPanel({ agent, onSend, onStop }: {
     }
   };
 
+  const handleAnswer = (answer: string) => {
+    if (question) {
+      AnswerQuestion(agent.id, question.id, answer);
+      setMessages(prev => [...prev, { role: 'user', content: answer || '(question skipped)' }]);
+      setQuestion(null);
+    }
+  };
+
+  const handleSetBudget = () => {
+    const value = window.prompt('Token budget for this agent (0 = unlimited)', String(agent.tokenBudget || 0));
+    if (value === null) return;
+    const budget = parseInt(value, 10);
+    if (!isNaN(budget) && budget >= 0) {
+      SetTokenBudget(agent.id, budget);
+    }
+  };
+
+  const handleSetCostBudget = () => {
+    const value = window.prompt('Cost budget for this agent in US dollars (0 = unlimited)', String(agent.costBudget || 0));
+    if (value === null) return;
+    const budget = parseFloat(value);
+    if (!isNaN(budget) && budget >= 0) {
+      SetCostBudget(agent.id, budget);
+    }
+  };
+
+  const handleAttachPTY = async () => {
+    if (ptyId) {
+      AttachAgentPTY(agent.id, '');
+      return;
+    }
+    const sessions = await PTYList();
+    if (!sessions || sessions.length === 0) {
+      setMessages(prev => [...prev, { role: 'error', content: 'No terminal sessions open' }]);
+      return;
+    }
+    const value = window.prompt(`Run bash in terminal session (${sessions.join(', ')})`, sessions[0]);
+    if (!value) return;
+    AttachAgentPTY(agent.id, value).catch((err: Error) => {
+      setMessages(prev => [...prev, { role: 'error', content: `Failed to attach: ${err.message || err}` }]);
+    });
+  };
+
+  // A queued agent has its turn waiting for a slot; input steers it too.
+  const running = agent.status === 'running' || agent.status === 'queued';
+
+  useEffect(() => {
+    ListPrompts().then(list => setPrompts(list || [])).catch(() => setPrompts([]));
+  }, []);
+
+  // An agent's prompt is stored as text; show the library entry it came from.
+  const currentPrompt = prompts.find(p => p.text === (agent.systemPrompt || ''));
+
+  const handlePromptChange = (name: string) => {
+    const chosen = prompts.find(p => p.name === name);
+    if (!chosen) return;
+    SetSystemPrompt(agent.id, chosen.text).catch((err: Error) => {
+      setMessages(prev => [...prev, { role: 'error', content: `Failed to set prompt: ${err.message || err}` }]);
+    });
+  };
+
+  const handleFork = () => {
+    ForkSession(agent.id).catch((err: Error) => {
+      setMessages(prev => [...prev, { role: 'error', content: `Failed to fork: ${err.message || err}` }]);
+    });
+  };
+
+  // While a turn runs, input steers it instead of queueing a new turn.
+  const handleSteer = () => {
+    if (!input.trim()) return;
+    const note = input;
+    setMessages(prev => [...prev, { role: 'user', content: `(steering) ${note}` }]);
+    setInput('');
+    SteerAgent(agent.id, note).catch((err: Error) => {
+      setMessages(prev => [...prev, { role: 'error', content: `Failed to steer: ${err.message || err}` }]);
+    });
+  };
+
   const handleSend = () => {
+    if (running) {
+      handleSteer();
+      return;
+    }
     if (!input.trim()) return;
     const message = input;
     setMessages(prev => [...prev, { role: 'user', content: message }]);
@@ -255,29 +539,111 @@ function AgentPanel({ agent, onSend, onStop }: {
         />
       )}
 
+      {question && (
+        <QuestionModal question={question} onAnswer={handleAnswer} />
+      )}
+
       <div className="agent-header">
-        <span className="agent-title">{agent.id}</span>
+        <span className="agent-title" title={agent.summary || agent.id}>{agent.title || agent.id}</span>
+        {agent.parent && <span className="agent-fork" title={`Forked from ${agent.parent}`}>⑂ {agent.parent}</span>}
         <span className="agent-model">{agent.model || 'default'}</span>
+        <select
+          className="agent-prompt"
+          value={currentPrompt ? currentPrompt.name : ''}
+          onChange={e => handlePromptChange(e.target.value)}
+          disabled={running}
+          title={currentPrompt ? currentPrompt.description : 'Custom system prompt'}
+        >
+          {!currentPrompt && <option value="">custom</option>}
+          {prompts.map(p => (
+            <option key={p.name} value={p.name} title={p.description}>
+              {p.source === 'builtin' ? p.name : `${p.name} (${p.source})`}
+            </option>
+          ))}
+        </select>
         {agent.serviceName && (
           <span className="agent-service" title={`Host: ${agent.serviceHost || 'unknown'}`}>
             <span className={`service-indicator ${agent.connected ? 'connected' : 'disconnected'}`} />
             {agent.serviceName}
           </span>
         )}
-        <span className={`agent-status status-${agent.status}`}>{agent.status}</span>
-        <span className="agent-cost">${agent.cost.toFixed(2)}</span>
+        <span className={`agent-status status-${agent.status}`} title={agent.status === 'queued' ? 'Waiting for another agent to finish; see Settings → Agent' : undefined}>
+          {agent.status === 'queued' ? `waiting for a slot (#${agent.queuePosition || 1})` : agent.status}
+        </span>
+        <button className="agent-logs-btn" onClick={() => setShowLogs(!showLogs)} title="Backend logs">
+          {showLogs ? 'Chat' : 'Logs'}
+        </button>
+        <button className="agent-logs-btn" onClick={handleFork} disabled={running} title="Copy this conversation into a new agent to try another approach">
+          Fork
+        </button>
+        <button className={`agent-logs-btn ${planMode ? 'active' : ''}`} onClick={togglePlanMode} title={planMode ? 'Plan mode: tools that change things wait for an approved plan; click to turn off' : 'Make the agent get its plan approved before changing anything'}>
+          {planMode ? 'Plan mode: on' : 'Plan mode'}
+        </button>
+        <button className="agent-logs-btn" onClick={handleAttachPTY} title={ptyId ? `Bash runs in ${ptyId}; click to detach` : 'Run bash in a terminal session'}>
+          {ptyId ? `Shell: ${ptyId}` : 'Shell'}
+        </button>
+        {timing && (
+          <span className="agent-timing" title={`Last turn: first token after ${formatMs(timing.firstTokenMs)}, ${formatMs(timing.inferenceMs)} on the model over ${timing.requests} request(s), ${formatMs(timing.toolMs)} running tools`}>
+            {formatMs(timing.firstTokenMs)} · model {formatMs(timing.inferenceMs)} · tools {formatMs(timing.toolMs)}
+          </span>
+        )}
+        <span className="agent-budget" onClick={handleSetBudget} title="Set token budget">
+          {agent.tokensUsed || 0}{agent.tokenBudget ? `/${agent.tokenBudget}` : ''} tok
+        </span>
+        <span className="agent-cost" onClick={handleSetCostBudget} title="Set cost budget">
+          ${agent.cost.toFixed(2)}{agent.costBudget ? `/$${agent.costBudget.toFixed(2)}` : ''}
+        </span>
       </div>
 
-      <div className="agent-messages">
+      {showLogs && (
+        <div className="agent-logs">
+          <label className="agent-logs-verbose">
+            <input type="checkbox" checked={verbose} onChange={toggleVerbose} /> verbose
+          </label>
+          {logs.map(entry => (
+            <div key={entry.seq} className={`log-entry log-${entry.level}`}>
+              <span className="log-time">{new Date(entry.time).toLocaleTimeString()}</span>
+              <span className="log-category">[{entry.category}]</span>
+              <span className="log-message">{entry.message}</span>
+            </div>
+          ))}
+        </div>
+      )}
+
+      {plan && plan.items && plan.items.length > 0 && !showLogs && (
+        <div className="agent-plan">
+          <div className="agent-plan-title">Plan {plan.approved ? '(approved)' : planMode ? '(awaiting approval)' : ''}</div>
+          {plan.items.map(item => (
+            <div key={item.id} className={`plan-item plan-${item.status}`}>
+              <span className="plan-mark">{item.status === 'done' ? '✓' : item.status === 'in_progress' ? '▸' : '○'}</span>
+              {item.id}. {item.text}
+            </div>
+          ))}
+        </div>
+      )}
+
+      <div className="agent-messages" style={showLogs ? { display: 'none' } : undefined}>
         {messages.map((msg, i) => (
           <div key={i} className={`message message-${msg.role}`}>
             <span className="message-role">{msg.role}</span>
+            {msg.reasoning && (
+              <details className="message-reasoning">
+                <summary>Thinking</summary>
+                {msg.reasoning}
+              </details>
+            )}
             <span className="message-content">{msg.content}</span>
           </div>
         ))}
-        {streamingContent && (
+        {(streamingContent || streamingReasoning) && (
           <div className="message message-assistant streaming">
             <span className="message-role">assistant</span>
+            {streamingReasoning && (
+              <details className="message-reasoning" open={!streamingContent}>
+                <summary>Thinking</summary>
+                {streamingReasoning}
+              </details>
+            )}
             <span className="message-content">{streamingContent}<span className="cursor">▌</span></span>
           </div>
         )}
@@ -289,14 +655,18 @@ function AgentPanel({ agent, onSend, onStop }: {
           value={input}
           onChange={e => setInput(e.target.value)}
           onKeyDown={handleKeyDown}
-          placeholder="Enter message..."
+          placeholder={running ? 'Steer the running agent...' : 'Enter message...'}
           rows={2}
-          disabled={agent.status === 'running'}
         />
-        {agent.status === 'running' ? (
-          <button className="btn-stop" onClick={onStop}>
-            Stop
-          </button>
+        {running ? (
+          <>
+            <button onClick={handleSteer} disabled={!input.trim()} title="Send a note the agent reads before its next step">
+              Steer
+            </button>
+            <button className="btn-stop" onClick={onStop}>
+              Stop
+            </button>
+          </>
         ) : (
           <button onClick={handleSend}>
             Send
@@ -345,6 +715,7 @@ function App() {
   const [showSettings, setShowSettings] = useState(false);
   const [showShortcutsHelp, setShowShortcutsHelp] = useState(false);
   const [settings, setSettings] = useState<Settings>(DEFAULT_SETTINGS);
+  const [scenarios, setScenarios] = useState<ScenarioInfo[]>([]);
   const agentRefs = useRef<Map<string, HTMLDivElement>>(new Map());
 
   const { registerShortcut, formatShortcut } = useKeyboardShortcuts();
@@ -462,11 +833,18 @@ function App() {
     { id: 'focus-1', label: 'Focus Agent 1', shortcut: 'Ctrl+1', category: 'Navigation', action: () => focusAgent(0) },
     { id: 'focus-2', label: 'Focus Agent 2', shortcut: 'Ctrl+2', category: 'Navigation', action: () => focusAgent(1) },
     { id: 'focus-3', label: 'Focus Agent 3', shortcut: 'Ctrl+3', category: 'Navigation', action: () => focusAgent(2) },
-  ], [focusAgent]);
+    ...scenarios.map(sc => ({
+      id: `scenario-${sc.path}`,
+      label: `Launch Scenario: ${sc.name} (${sc.agentCount} agents)`,
+      category: 'Scenarios',
+      action: () => handleLaunchScenario(sc.path),
+    })),
+  ], [focusAgent, scenarios]);
 
   useEffect(() => {
     GetVersion().then(setVersion);
     GetAgents().then(setAgents);
+    ListScenarios().then(list => setScenarios(list || []));
 
     EventsOn('agent:created', () => {
       GetAgents().then(setAgents);
@@ -479,6 +857,14 @@ function App() {
       });
     });
 
+    EventsOn('agent:described', () => {
+      GetAgents().then(setAgents);
+    });
+
+    EventsOn('agent:prompt', () => {
+      GetAgents().then(setAgents);
+    });
+
     EventsOn('coordination:status', (statuses: CoordinationStatus[]) => {
       setCoordinationStatuses(statuses || []);
     });
@@ -494,6 +880,12 @@ function App() {
     });
   };
 
+  const handleLaunchScenario = (path: string) => {
+    LaunchScenario(path).then(() => {
+      GetAgents().then(setAgents);
+    });
+  };
+
   const handleSendMessage = (agentId: string, message: string): Promise<void> => {
     return SendMessage(agentId, message);
   };
@@ -1,4 +1,6 @@
 import { useState, useEffect } from 'react';
+import { GetDiscoveryFilter, SetDiscoveryFilter, GetMaxRunningAgents, SetMaxRunningAgents } from '../../wailsjs/go/main/App';
+import { main } from '../../wailsjs/go/models';
 import './SettingsPanel.css';
 
 export interface Settings {
@@ -59,11 +61,27 @@ type SettingsTab = 'appearance' | 'behavior' | 'agent' | 'shortcuts' | 'about';
 export function SettingsPanel({ isOpen, onClose, settings, onSettingsChange }: SettingsPanelProps) {
   const [activeTab, setActiveTab] = useState<SettingsTab>('appearance');
   const [localSettings, setLocalSettings] = useState<Settings>(settings);
+  const [discovery, setDiscovery] = useState<main.DiscoveryFilterOptions | null>(null);
+  const [maxRunning, setMaxRunning] = useState(0);
 
   useEffect(() => {
     setLocalSettings(settings);
   }, [settings]);
 
+  useEffect(() => {
+    if (isOpen) {
+      GetDiscoveryFilter().then(setDiscovery).catch(() => setDiscovery(null));
+      GetMaxRunningAgents().then(setMaxRunning).catch(() => setMaxRunning(0));
+    }
+  }, [isOpen]);
+
+  const updateDiscovery = <K extends keyof main.DiscoveryFilterOptions>(key: K, value: main.DiscoveryFilterOptions[K]) => {
+    if (!discovery) return;
+    const updated = main.DiscoveryFilterOptions.createFrom({ ...discovery, [key]: value });
+    setDiscovery(updated);
+    SetDiscoveryFilter(updated);
+  };
+
   useEffect(() => {
     const handleKeyDown = (e: KeyboardEvent) => {
       if (e.key === 'Escape' && isOpen) {
@@ -241,6 +259,87 @@ export function SettingsPanel({ isOpen, onClose, settings, onSettingsChange }: S
                   />
                   <span className="setting-hint">Automatically approve read_file, list_files, etc.</span>
                 </div>
+
+                <div className="setting-item">
+                  <label className="setting-label">Max Running Agents</label>
+                  <input
+                    type="number"
+                    min="0"
+                    className="setting-input"
+                    placeholder="no limit"
+                    value={maxRunning || ''}
+                    onChange={e => {
+                      const limit = Math.max(0, Number(e.target.value) || 0);
+                      setMaxRunning(limit);
+                      SetMaxRunningAgents(limit);
+                    }}
+                  />
+                  <span className="setting-hint">Others wait for a slot, so agents sharing one GPU server take turns</span>
+                </div>
+
+                {discovery && (
+                  <>
+                    <h3 className="setting-group-title">Service Discovery</h3>
+                    <span className="setting-hint">Applies to agents created after the change.</span>
+
+                    <div className="setting-item">
+                      <label className="setting-label">Required Model</label>
+                      <input
+                        type="text"
+                        className="setting-input"
+                        placeholder="any"
+                        value={discovery.requiredModel}
+                        onChange={e => updateDiscovery('requiredModel', e.target.value)}
+                      />
+                    </div>
+
+                    <div className="setting-item">
+                      <label className="setting-label">Min Priority</label>
+                      <input
+                        type="number"
+                        min="0"
+                        className="setting-input"
+                        placeholder="any"
+                        value={discovery.minPriority || ''}
+                        onChange={e => updateDiscovery('minPriority', Number(e.target.value))}
+                      />
+                      <span className="setting-hint">Only services with this priority number or lower</span>
+                    </div>
+
+                    <div className="setting-item">
+                      <label className="setting-label">Min VRAM (GB)</label>
+                      <input
+                        type="number"
+                        min="0"
+                        className="setting-input"
+                        placeholder="any"
+                        value={discovery.minVramGb || ''}
+                        onChange={e => updateDiscovery('minVramGb', Number(e.target.value))}
+                      />
+                    </div>
+
+                    <div className="setting-item">
+                      <label className="setting-label">Require GPU</label>
+                      <input
+                        type="checkbox"
+                        className="setting-checkbox"
+                        checked={discovery.requireGpu}
+                        onChange={e => updateDiscovery('requireGpu', e.target.checked)}
+                      />
+                    </div>
+
+                    <div className="setting-item">
+                      <label className="setting-label">Local Only</label>
+                      <input
+                        type="checkbox"
+                        className="setting-checkbox"
+                        checked={discovery.localOnly}
+                        onChange={e => updateDiscovery('localOnly', e.target.checked)}
+                      />
+                      <span className="setting-hint">Skip services that proxy a remote API</span>
+                    </div>
+                  </>
+                )}
               </div>
             )}
 
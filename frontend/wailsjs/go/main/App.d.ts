@@ -1,15 +1,36 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
+import {tools} from '../models';
+
+export function AnswerQuestion(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function AttachAgentPTY(arg1:string,arg2:string):Promise<void>;
+
+export function ForkSession(arg1:string):Promise<string>;
+
+export function GetAgentLogs(arg1:string,arg2:number):Promise<Array<main.LogEntry>>;
 
 export function GetAgents():Promise<Array<main.AgentSession>>;
 
 export function GetCoordinationStatuses():Promise<Array<main.CoordinationStatus>>;
 
+export function GetDiscoveryFilter():Promise<main.DiscoveryFilterOptions>;
+
+export function GetMaxRunningAgents():Promise<number>;
+
+export function GetPlan(arg1:string):Promise<tools.PlanState>;
+
 export function GetVersion():Promise<string>;
 
 export function LaunchMultiAgentDemo():Promise<Array<string>>;
 
+export function LaunchScenario(arg1:string):Promise<Array<string>>;
+
+export function ListPrompts():Promise<Array<main.PromptInfo>>;
+
+export function ListScenarios():Promise<Array<main.ScenarioInfo>>;
+
 export function NewAgent(arg1:string):Promise<string>;
 
 export function NewNamedAgent(arg1:string,arg2:string):Promise<string>;
@@ -26,4 +47,20 @@ export function RespondToApproval(arg1:string,arg2:string,arg3:boolean,arg4:stri
 
 export function SendMessage(arg1:string,arg2:string):Promise<void>;
 
+export function SetAgentVerbose(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetCostBudget(arg1:string,arg2:number):Promise<void>;
+
+export function SetDiscoveryFilter(arg1:main.DiscoveryFilterOptions):Promise<void>;
+
+export function SetMaxRunningAgents(arg1:number):Promise<void>;
+
+export function SetPlanMode(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetSystemPrompt(arg1:string,arg2:string):Promise<void>;
+
+export function SetTokenBudget(arg1:string,arg2:number):Promise<void>;
+
+export function SteerAgent(arg1:string,arg2:string):Promise<void>;
+
 export function StopAgent(arg1:string):Promise<void>;
@@ -2,6 +2,22 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AnswerQuestion(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AnswerQuestion'](arg1, arg2, arg3);
+}
+
+export function AttachAgentPTY(arg1, arg2) {
+  return window['go']['main']['App']['AttachAgentPTY'](arg1, arg2);
+}
+
+export function ForkSession(arg1) {
+  return window['go']['main']['App']['ForkSession'](arg1);
+}
+
+export function GetAgentLogs(arg1, arg2) {
+  return window['go']['main']['App']['GetAgentLogs'](arg1, arg2);
+}
+
 export function GetAgents() {
   return window['go']['main']['App']['GetAgents']();
 }
@@ -10,6 +26,18 @@ export function GetCoordinationStatuses() {
   return window['go']['main']['App']['GetCoordinationStatuses']();
 }
 
+export function GetDiscoveryFilter() {
+  return window['go']['main']['App']['GetDiscoveryFilter']();
+}
+
+export function GetMaxRunningAgents() {
+  return window['go']['main']['App']['GetMaxRunningAgents']();
+}
+
+export function GetPlan(arg1) {
+  return window['go']['main']['App']['GetPlan'](arg1);
+}
+
 export function GetVersion() {
   return window['go']['main']['App']['GetVersion']();
 }
@@ -18,6 +46,18 @@ export function LaunchMultiAgentDemo() {
   return window['go']['main']['App']['LaunchMultiAgentDemo']();
 }
 
+export function LaunchScenario(arg1) {
+  return window['go']['main']['App']['LaunchScenario'](arg1);
+}
+
+export function ListPrompts() {
+  return window['go']['main']['App']['ListPrompts']();
+}
+
+export function ListScenarios() {
+  return window['go']['main']['App']['ListScenarios']();
+}
+
 export function NewAgent(arg1) {
   return window['go']['main']['App']['NewAgent'](arg1);
 }
@@ -50,6 +90,38 @@ export function SendMessage(arg1, arg2) {
   return window['go']['main']['App']['SendMessage'](arg1, arg2);
 }
 
+export function SetAgentVerbose(arg1, arg2) {
+  return window['go']['main']['App']['SetAgentVerbose'](arg1, arg2);
+}
+
+export function SetCostBudget(arg1, arg2) {
+  return window['go']['main']['App']['SetCostBudget'](arg1, arg2);
+}
+
+export function SetDiscoveryFilter(arg1) {
+  return window['go']['main']['App']['SetDiscoveryFilter'](arg1);
+}
+
+export function SetMaxRunningAgents(arg1) {
+  return window['go']['main']['App']['SetMaxRunningAgents'](arg1);
+}
+
+export function SetPlanMode(arg1, arg2) {
+  return window['go']['main']['App']['SetPlanMode'](arg1, arg2);
+}
+
+export function SetSystemPrompt(arg1, arg2) {
+  return window['go']['main']['App']['SetSystemPrompt'](arg1, arg2);
+}
+
+export function SetTokenBudget(arg1, arg2) {
+  return window['go']['main']['App']['SetTokenBudget'](arg1, arg2);
+}
+
+export function SteerAgent(arg1, arg2) {
+  return window['go']['main']['App']['SteerAgent'](arg1, arg2);
+}
+
 export function StopAgent(arg1) {
   return window['go']['main']['App']['StopAgent'](arg1);
 }
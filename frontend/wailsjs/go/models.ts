@@ -23,6 +23,14 @@ export namespace main {
 	    serviceName: string;
 	    serviceHost: string;
 	    connected: boolean;
+	    tokenBudget: number;
+	    tokensUsed: number;
+	    costBudget: number;
+	    title?: string;
+	    summary?: string;
+	    parent?: string;
+	    queuePosition?: number;
+	    systemPrompt?: string;
 
 	    static createFrom(source: any = {}) {
 	        return new AgentSession(source);
@@ -38,6 +46,14 @@ export namespace main {
 	        this.serviceName = source["serviceName"];
 	        this.serviceHost = source["serviceHost"];
 	        this.connected = source["connected"];
+	        this.tokenBudget = source["tokenBudget"];
+	        this.tokensUsed = source["tokensUsed"];
+	        this.costBudget = source["costBudget"];
+	        this.title = source["title"];
+	        this.summary = source["summary"];
+	        this.parent = source["parent"];
+	        this.queuePosition = source["queuePosition"];
+	        this.systemPrompt = source["systemPrompt"];
 	    }
 
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -79,5 +95,134 @@ export namespace main {
 	        this.is_remote = source["is_remote"];
 	    }
 	}
+	export class ScenarioInfo {
+	    name: string;
+	    description: string;
+	    path: string;
+	    agentCount: number;
+
+	    static createFrom(source: any = {}) {
+	        return new ScenarioInfo(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.path = source["path"];
+	        this.agentCount = source["agentCount"];
+	    }
+	}
+	export class LogEntry {
+	    seq: number;
+	    time: any;
+	    level: string;
+	    category: string;
+	    message: string;
+
+	    static createFrom(source: any = {}) {
+	        return new LogEntry(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.time = source["time"];
+	        this.level = source["level"];
+	        this.category = source["category"];
+	        this.message = source["message"];
+	    }
+	}
+	export class DiscoveryFilterOptions {
+	    minPriority: number;
+	    requiredModel: string;
+	    requireGpu: boolean;
+	    minVramGb: number;
+	    localOnly: boolean;
+
+	    static createFrom(source: any = {}) {
+	        return new DiscoveryFilterOptions(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.minPriority = source["minPriority"];
+	        this.requiredModel = source["requiredModel"];
+	        this.requireGpu = source["requireGpu"];
+	        this.minVramGb = source["minVramGb"];
+	        this.localOnly = source["localOnly"];
+	    }
+	}
+	export class PromptInfo {
+	    name: string;
+	    description: string;
+	    source: string;
+	    text: string;
+
+	    static createFrom(source: any = {}) {
+	        return new PromptInfo(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.source = source["source"];
+	        this.text = source["text"];
+	    }
+	}
+
+}
+
+export namespace tools {
+
+	export class TodoItem {
+	    id: number;
+	    text: string;
+	    status: string;
+
+	    static createFrom(source: any = {}) {
+	        return new TodoItem(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.text = source["text"];
+	        this.status = source["status"];
+	    }
+	}
+	export class PlanState {
+	    items: TodoItem[];
+	    approved: boolean;
+
+	    static createFrom(source: any = {}) {
+	        return new PlanState(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.items = this.convertValues(source["items"], TodoItem);
+	        this.approved = source["approved"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
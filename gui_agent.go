@@ -3,14 +3,22 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"brutus/agent"
+	"brutus/audit"
 	"brutus/coordinator"
+	"brutus/guard"
+	"brutus/index"
+	"brutus/internal/text"
 	"brutus/provider"
+	"brutus/session"
 	"brutus/tools"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -18,6 +26,8 @@ import (
 
 var guiAgentPortCounter int32 = 9000
 
+var questionCounter int64
+
 type ToolApprovalRequest struct {
 	ID        string `json:"id"`
 	AgentID   string `json:"agentId"`
@@ -30,54 +40,144 @@ type ToolApprovalResponse struct {
 	Reason   string `json:"reason"`
 }
 
-var autoApproveTools = map[string]bool{
-	"read_file":       true,
-	"list_files":      true,
-	"code_search":     true,
-	"agent_broadcast": true,
-	"observe_agents":  true,
+// UserQuestion is sent to the frontend when the agent calls ask_user.
+type UserQuestion struct {
+	ID       string   `json:"id"`
+	AgentID  string   `json:"agentId"`
+	Question string   `json:"question"`
+	Choices  []string `json:"choices"`
+}
+
+// ErrBudgetExhausted is returned from SendMessage when the agent's token
+// or cost budget has been used up.
+var ErrBudgetExhausted = errors.New("budget exhausted")
+
+// budgetWarnFraction is the share of the budget at which a warning is emitted.
+const budgetWarnFraction = 0.8
+
+type TokenUsage struct {
+	Used       int     `json:"used"`
+	Budget     int     `json:"budget"`
+	Cost       float64 `json:"cost"`       // US dollars, where the model's price is known
+	CostBudget float64 `json:"costBudget"` // US dollars; zero means unlimited
 }
 
+// autoApproveTools run without an approval request.
+var autoApproveTools = agent.AutoApprovedTools
+
 type GUIAgent struct {
 	id              string
 	provider        provider.Provider
 	tools           *tools.Registry
 	systemPrompt    string
-	conversation    []provider.Message
+	learnedPrompt   string // learned commands and memories, appended to whichever system prompt is chosen
+	conversation    *session.Conversation
+	turns           int
 	ctx             context.Context
 	appCtx          context.Context
 	cancel          context.CancelFunc
 	mu              sync.Mutex
 	pendingApproval map[string]chan ToolApprovalResponse
+	pendingQuestion map[string]chan string
 	approvalMu      sync.Mutex
 	coordinator     *coordinator.Coordinator
-}
+	steering        agent.Steering
+	cache           *tools.ResultCache // nil when caching is off
+	artifacts       *tools.Artifacts   // nil when archiving is off
+	plan            *tools.Plan
 
-func NewGUIAgent(appCtx context.Context, id string, model string) (*GUIAgent, error) {
-	systemPrompt, err := os.ReadFile("BRUTUS.md")
-	if err != nil {
-		systemPrompt = []byte("You are BRUTUS, a coding agent.")
-	}
+	infoMu sync.Mutex
+	info   session.Info
+
+	// timing covers the current or last turn.
+	timing provider.Timing
+
+	logs *logRing
+
+	// audit records the agent's tool calls; nil when auditing is off.
+	audit *audit.Log
 
+	// resultLimits cuts or summarizes large tool results.
+	resultLimits agent.ResultLimits
+
+	// scanOutput looks for prompt injection in tool results.
+	scanOutput bool
+
+	shellMu    sync.Mutex
+	shellExec  func(command string, env []string) (string, error)
+	shellLabel string
+
+	budgetMu     sync.Mutex
+	tokenBudget  int
+	tokensUsed   int
+	cost         float64
+	costBudget   float64
+	budgetWarned bool
+	costWarned   bool
+}
+
+// NewGUIAgent connects a new agent to Saturn. cfg.MaxTokens defaults to 4096.
+// cacheTools lets repeated read_file and code_search calls reuse results;
+// results over artifactThreshold bytes are archived for read_artifact
+// unless it is zero or less.
+func NewGUIAgent(appCtx context.Context, id string, cfg provider.SaturnConfig, cacheTools bool, artifactThreshold int) (*GUIAgent, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	prov, err := provider.NewSaturn(ctx, provider.SaturnConfig{
-		Model:     model,
-		MaxTokens: 4096,
-	})
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = 4096
+	}
+	var saturn provider.Provider
+	var err error
+	if cfg.Sticky {
+		saturn, err = provider.NewSaturnSticky(ctx, cfg)
+	} else {
+		saturn, err = provider.NewSaturn(ctx, cfg)
+	}
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to connect to Saturn: %w", err)
 	}
+	// Adapt falls back to plain requests for services that can't stream,
+	// and describes the tools in the prompt to models that can't call them.
+	prov := provider.Adapt(saturn)
+
+	// A damaged learned-commands file starts over rather than failing.
+	learned, _ := tools.LoadLearned(tools.LearnedPath)
+	memories, _ := tools.LoadMemories(tools.MemoryPath("."))
 
 	registry := tools.NewRegistry()
 	registry.Register(tools.ReadFileTool)
+	registry.Register(tools.ReadImageTool)
 	registry.Register(tools.ListFilesTool)
 	registry.Register(tools.EditFileTool)
-	registry.Register(tools.BashTool)
+	registry.Register(tools.MultiEditTool)
+	registry.Register(tools.ApplyPatchTool)
+	registry.Register(tools.NewBashTool(learned))
+	registry.Register(tools.NewRunTestsTool(learned))
+	registry.Register(tools.NewFormatTool(learned))
+	registry.Register(tools.GitTool)
+	registry.Register(tools.HTTPRequestTool)
 	registry.Register(tools.CodeSearchTool)
+	registry.Register(tools.FindSymbolTool)
+	registry.Register(tools.ImportsOfTool)
+	registry.Register(tools.DependentsOfTool)
 	registry.Register(tools.BroadcastTool)
 	registry.Register(tools.ObserveAgentsTool)
+	registry.Register(tools.IssueFetchTool)
+	registry.Register(tools.NewSearchHistoryTool(session.SearchText))
+	registry.Register(tools.PRCreateTool)
+	registry.Register(tools.PRCommentTool)
+	registry.Register(tools.NewMemoryTool(memories))
+	workDir, _ := os.Getwd()
+	if embedder, ok := provider.EmbedderOf(prov); ok {
+		registry.Register(index.NewSearchTool(index.Open(workDir, index.Path(workDir), embedder)))
+	}
+	registry.Register(agent.NewSubagentTool(agent.SubagentConfig{
+		Provider:     prov,
+		Tools:        registry,
+		SystemPrompt: projectPrompt(),
+		WorkingDir:   workDir,
+	}))
 
 	coord := coordinator.NewCoordinator(id)
 
@@ -87,23 +187,100 @@ func NewGUIAgent(appCtx context.Context, id string, model string) (*GUIAgent, er
 		return nil, fmt.Errorf("failed to start coordinator: %w", err)
 	}
 
-	return &GUIAgent{
+	sessionID := session.NewID()
+	g := &GUIAgent{
 		id:              id,
 		provider:        prov,
 		tools:           registry,
-		systemPrompt:    string(systemPrompt),
+		learnedPrompt:   strings.TrimSpace(learned.Prompt() + "\n\n" + memories.Prompt()),
 		appCtx:          appCtx,
 		ctx:             ctx,
 		cancel:          cancel,
 		pendingApproval: make(map[string]chan ToolApprovalResponse),
+		pendingQuestion: make(map[string]chan string),
 		coordinator:     coord,
-	}, nil
+		logs:            newLogRing(agentLogSize),
+		scanOutput:      true,
+		conversation:    session.NewConversation(session.SpillPath(sessionID), 0),
+	}
+	g.systemPrompt = g.composePrompt("")
+	if cacheTools {
+		g.cache = tools.NewResultCache(tools.DefaultCacheSize)
+	}
+	if artifactThreshold > 0 {
+		g.artifacts = tools.NewArtifacts(tools.SessionArtifactsDir(sessionID), artifactThreshold)
+		registry.Register(tools.NewReadArtifactTool(g.artifacts))
+	}
+
+	g.plan = tools.LoadPlan(tools.SessionPlanPath(sessionID))
+	g.plan.OnChange(func(state tools.PlanState) {
+		runtime.EventsEmit(appCtx, "agent:plan", map[string]any{"id": id, "plan": state})
+	})
+	registry.Register(tools.NewTodoTool(g.plan))
+
+	registry.Register(tools.NewAskUserTool(g.askUser))
+	registry.Register(tools.NewPythonExecTool())
+	registry.Register(tools.NewClipboardGetTool(func() (string, error) {
+		return runtime.ClipboardGetText(appCtx)
+	}))
+	registry.Register(tools.NewClipboardSetTool(func(content string) error {
+		return runtime.ClipboardSetText(appCtx, content)
+	}))
+
+	coord.OnMessage(func(msg coordinator.AgentMessage) {
+		g.logf("info", "coordinator", "message from %s (%s): %s", msg.From, msg.Type, msg.Content)
+	})
+
+	g.logf("info", "provider", "connected to %s", prov.Name())
+	g.logf("debug", "provider", "capabilities: %+v", prov.Capabilities())
+	g.logf("info", "coordinator", "registered on port %d", port)
+	return g, nil
 }
 
 func (g *GUIAgent) Stop() {
 	g.updateStatusWithBroadcast("stopped", "", "Agent stopped")
 	g.coordinator.Stop()
 	g.cancel()
+	if err := g.conversation.Close(); err != nil {
+		g.logf("warn", "agent", "removing conversation spill file: %v", err)
+	}
+	if err := g.tools.Close(); err != nil {
+		g.logf("warn", "tool", "closing tool sessions: %v", err)
+	}
+}
+
+// addMessage appends msg to the conversation. A failed spill only costs
+// history beyond the in-memory window, so it is logged rather than fatal.
+func (g *GUIAgent) addMessage(msg provider.Message) {
+	if err := g.conversation.Append(g.turns, msg); err != nil {
+		g.logf("warn", "agent", "conversation spill failed: %v", err)
+	}
+}
+
+// archive replaces a result too large to keep in the conversation with a
+// reference to an artifact holding it.
+func (g *GUIAgent) archive(tool, result string) string {
+	if tool == "read_artifact" {
+		return result
+	}
+	archived, err := g.artifacts.Archive(tool, result)
+	if err != nil {
+		g.logf("warn", "tool", "archiving %s result: %v", tool, err)
+	} else if archived != result {
+		g.logf("info", "tool", "%s result archived (%d bytes)", tool, len(result))
+	}
+	return archived
+}
+
+// shrink applies the result limits to a tool result.
+func (g *GUIAgent) shrink(tc provider.ToolCall, result string) string {
+	shrunk, err := agent.ShrinkResult(g.ctx, g.provider, g.resultLimits, tc, result)
+	if err != nil {
+		g.logf("warn", "tool", "summarizing %s result: %v", tc.Name, err)
+	} else if shrunk != result {
+		g.logf("info", "tool", "%s result cut from %d to %d bytes", tc.Name, len(result), len(shrunk))
+	}
+	return shrunk
 }
 
 func (g *GUIAgent) GetCoordinatorStatus() coordinator.AgentStatus {
@@ -111,17 +288,160 @@ func (g *GUIAgent) GetCoordinatorStatus() coordinator.AgentStatus {
 }
 
 func (g *GUIAgent) GetServiceInfo() *provider.SaturnService {
-	if saturn, ok := g.provider.(*provider.Saturn); ok {
+	if saturn, ok := provider.Unwrap(g.provider).(*provider.Saturn); ok {
 		return saturn.GetService()
 	}
 	return nil
 }
 
+// SetTokenBudget limits the total estimated tokens this agent may consume.
+// A budget of zero disables enforcement.
+func (g *GUIAgent) SetTokenBudget(budget int) {
+	g.budgetMu.Lock()
+	defer g.budgetMu.Unlock()
+	g.tokenBudget = budget
+	g.budgetWarned = budget > 0 && float64(g.tokensUsed) >= float64(budget)*budgetWarnFraction
+}
+
+// SetCostBudget limits the total US dollars this agent may spend. Only
+// responses from models with a known price count toward it. A budget of
+// zero disables enforcement.
+func (g *GUIAgent) SetCostBudget(budget float64) {
+	g.budgetMu.Lock()
+	defer g.budgetMu.Unlock()
+	g.costBudget = budget
+	g.costWarned = budget > 0 && g.cost >= budget*budgetWarnFraction
+}
+
+func (g *GUIAgent) GetTokenUsage() TokenUsage {
+	g.budgetMu.Lock()
+	defer g.budgetMu.Unlock()
+	return TokenUsage{Used: g.tokensUsed, Budget: g.tokenBudget, Cost: g.cost, CostBudget: g.costBudget}
+}
+
+func (g *GUIAgent) checkBudget() error {
+	g.budgetMu.Lock()
+	defer g.budgetMu.Unlock()
+	if g.tokenBudget > 0 && g.tokensUsed >= g.tokenBudget {
+		return fmt.Errorf("%w: used %d of %d tokens", ErrBudgetExhausted, g.tokensUsed, g.tokenBudget)
+	}
+	if g.costBudget > 0 && g.cost >= g.costBudget {
+		return fmt.Errorf("%w: spent $%.2f of $%.2f", ErrBudgetExhausted, g.cost, g.costBudget)
+	}
+	return nil
+}
+
+// budgetLevel reports "exhausted" when used crosses limit, or "warning" the
+// first time it reaches budgetWarnFraction of it.
+func budgetLevel(prev, used, limit float64, warned *bool) string {
+	if limit <= 0 {
+		return ""
+	}
+	if used >= limit && prev < limit {
+		return "exhausted"
+	}
+	if !*warned && used >= limit*budgetWarnFraction {
+		*warned = true
+		return "warning"
+	}
+	return ""
+}
+
+// recordUsage counts a response against the token and cost budgets.
+func (g *GUIAgent) recordUsage(u provider.Usage) {
+	g.budgetMu.Lock()
+	prevTokens, prevCost := g.tokensUsed, g.cost
+	g.tokensUsed += u.Total()
+	g.cost += u.Cost
+	usage := TokenUsage{Used: g.tokensUsed, Budget: g.tokenBudget, Cost: g.cost, CostBudget: g.costBudget}
+	tokenLevel := budgetLevel(float64(prevTokens), float64(usage.Used), float64(usage.Budget), &g.budgetWarned)
+	costLevel := budgetLevel(prevCost, usage.Cost, usage.CostBudget, &g.costWarned)
+	g.budgetMu.Unlock()
+
+	if tokenLevel != "" {
+		runtime.EventsEmit(g.appCtx, "agent:budget", map[string]interface{}{
+			"id":     g.id,
+			"kind":   "tokens",
+			"used":   usage.Used,
+			"budget": usage.Budget,
+			"level":  tokenLevel,
+		})
+	}
+	if costLevel != "" {
+		runtime.EventsEmit(g.appCtx, "agent:budget", map[string]interface{}{
+			"id":     g.id,
+			"kind":   "cost",
+			"used":   usage.Cost,
+			"budget": usage.CostBudget,
+			"level":  costLevel,
+		})
+	}
+}
+
+// SetSystemPrompt replaces the agent's system prompt for the rest of the
+// conversation. An empty prompt restores the project's. It waits for a
+// running turn to finish.
+func (g *GUIAgent) SetSystemPrompt(prompt string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.systemPrompt = g.composePrompt(prompt)
+}
+
+// requestPrompt is the system prompt sent with each request.
+func (g *GUIAgent) requestPrompt() string {
+	prompt := g.systemPrompt + "\n\n" + guard.SystemPromptNote
+	if g.plan.Gated() {
+		prompt += "\n\n" + agent.PlanModeNote
+	}
+	return prompt
+}
+
+// SetPlanMode turns plan mode on or off: while it is on, tools that change
+// things are refused until the user approves the plan the agent submits
+// with todo.
+func (g *GUIAgent) SetPlanMode(on bool) {
+	if on {
+		g.plan.RequireApproval(g.askUser)
+		g.logf("info", "agent", "plan mode on")
+	} else {
+		g.plan.RequireApproval(nil)
+		g.logf("info", "agent", "plan mode off")
+	}
+}
+
+// Plan returns the agent's plan.
+func (g *GUIAgent) Plan() tools.PlanState {
+	return g.plan.State()
+}
+
+// composePrompt builds the full system prompt from a chosen one, or the
+// project's when prompt is empty, and the learned commands.
+func (g *GUIAgent) composePrompt(prompt string) string {
+	if prompt == "" {
+		prompt = projectPrompt()
+	}
+	if g.learnedPrompt != "" {
+		prompt += "\n\n" + g.learnedPrompt
+	}
+	return prompt
+}
+
+// projectPrompt is the system prompt agents start with: BRUTUS.md in the
+// working directory, or a minimal one.
+func projectPrompt() string {
+	prompt, err := os.ReadFile("BRUTUS.md")
+	if err != nil {
+		return "You are BRUTUS, a coding agent."
+	}
+	return string(prompt)
+}
+
 func (g *GUIAgent) GetCoordinator() *coordinator.Coordinator {
 	return g.coordinator
 }
 
 func (g *GUIAgent) updateStatusWithBroadcast(status, task, action string) {
+	g.logf("debug", "coordinator", "status=%s task=%q action=%q", status, task, action)
 	g.coordinator.UpdateStatus(status, task, action)
 
 	broadcastInput := tools.BroadcastInput{
@@ -142,18 +462,77 @@ func (g *GUIAgent) SendMessage(message string) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	g.conversation = append(g.conversation, provider.Message{
+	g.turns++
+	g.timing.Reset()
+	g.addMessage(provider.Message{
 		Role:    "user",
 		Content: message,
 	})
 
-	return g.runInferenceLoop()
+	err := g.runInferenceLoop()
+	g.emitTiming()
+	return err
+}
+
+// emitTiming reports where the last turn's time went: waiting for the
+// model's first token, on the model overall, and running tools. Time spent
+// waiting for approval is not counted.
+func (g *GUIAgent) emitTiming() {
+	stats := g.timing.Stats()
+	if stats.Requests == 0 {
+		return
+	}
+	g.logf("info", "agent", "turn timing: %s", stats)
+	runtime.EventsEmit(g.appCtx, "agent:timing", map[string]interface{}{
+		"id":           g.id,
+		"firstTokenMs": stats.FirstToken.Milliseconds(),
+		"inferenceMs":  stats.Inference.Milliseconds(),
+		"toolMs":       stats.Tools.Milliseconds(),
+		"requests":     stats.Requests,
+	})
+}
+
+// maxRequestRetries bounds how many times one request is retried after a
+// failure the agent can do something about.
+const maxRequestRetries = 2
+
+// prepareRetry gets ready to retry a failed request, by spilling older
+// turns when the conversation is too long for the model or by waiting out
+// a busy service, and reports whether a retry could succeed.
+func (g *GUIAgent) prepareRetry(err error) bool {
+	if errors.Is(err, provider.ErrContextLength) {
+		n, spillErr := g.conversation.Compact()
+		if n == 0 || spillErr != nil {
+			return false
+		}
+		g.logf("warn", "agent", "conversation too long for the model; retrying without the oldest %d messages", n)
+		return true
+	}
+	var callErr *provider.ToolCallError
+	if errors.As(err, &callErr) {
+		g.logf("warn", "agent", "the %s call was cut off; asking the model to send it again", callErr.Name)
+		g.addMessage(provider.Message{Role: "user", Content: fmt.Sprintf(
+			"Your call to %s was cut off: its arguments, %q, are not complete JSON, so it was not run. Send the whole call again.",
+			callErr.Name, text.Head(callErr.Arguments, 200))})
+		return true
+	}
+	if delay, ok := provider.RetryDelay(err); ok {
+		g.updateStatusWithBroadcast("working", "Service busy", fmt.Sprintf("Retrying in %s", delay.Round(time.Second)))
+		select {
+		case <-time.After(delay):
+			return true
+		case <-g.ctx.Done():
+			return false
+		}
+	}
+	return false
 }
 
 func (g *GUIAgent) runInferenceLoop() error {
 	g.updateStatusWithBroadcast("working", "Processing request", "Starting inference")
 	defer g.updateStatusWithBroadcast("idle", "", "Inference complete")
 
+	retries := 0
 	for {
 		select {
 		case <-g.ctx.Done():
@@ -161,17 +540,43 @@ func (g *GUIAgent) runInferenceLoop() error {
 		default:
 		}
 
-		stream, err := g.provider.ChatStream(g.ctx, g.systemPrompt, g.conversation, g.tools.All())
+		if err := g.checkBudget(); err != nil {
+			return err
+		}
+
+		messages := g.conversation.Messages()
+		promptTokens := provider.EstimateTokens(g.systemPrompt, messages)
+		g.logf("debug", "provider", "request: %d messages, ~%d prompt tokens, model=%q", len(messages), promptTokens, g.provider.GetModel())
+		callStart := time.Now()
+		reqCtx, done := g.timing.Request(provider.WithAffinity(g.ctx, g.id))
+		stream, err := g.provider.ChatStream(reqCtx, g.requestPrompt(), messages, g.tools.All())
 		if err != nil {
+			done()
+			g.logf("error", "provider", "request failed: %v", err)
+			if retries < maxRequestRetries && g.prepareRetry(err) {
+				retries++
+				continue
+			}
 			return fmt.Errorf("inference failed: %w", err)
 		}
 
-		var contentBuilder strings.Builder
+		var contentBuilder, reasoningBuilder strings.Builder
 		var toolCalls []provider.ToolCall
+		var usage *provider.Usage
+		var streamErr error
 
 		for delta := range stream {
 			if delta.Error != nil {
-				return delta.Error
+				streamErr = delta.Error
+				break
+			}
+
+			if delta.Reasoning != "" {
+				reasoningBuilder.WriteString(delta.Reasoning)
+				runtime.EventsEmit(g.appCtx, "agent:reasoning", map[string]string{
+					"id":      g.id,
+					"content": delta.Reasoning,
+				})
 			}
 
 			if delta.Content != "" {
@@ -182,33 +587,57 @@ func (g *GUIAgent) runInferenceLoop() error {
 				})
 			}
 
-			if delta.ToolCall != nil {
-				toolCalls = append(toolCalls, *delta.ToolCall)
-			}
-
 			if delta.Done {
+				toolCalls = delta.ToolCalls
+				usage = delta.Usage
 				break
 			}
 		}
 
+		done()
+		if streamErr != nil {
+			g.logf("error", "provider", "stream failed: %v", streamErr)
+			if retries < maxRequestRetries && g.prepareRetry(streamErr) {
+				retries++
+				continue
+			}
+			return streamErr
+		}
+		retries = 0
+
 		response := provider.Message{
 			Role:      "assistant",
 			Content:   contentBuilder.String(),
 			ToolCalls: toolCalls,
+			Reasoning: reasoningBuilder.String(),
+			Usage:     usage,
 		}
 
-		g.conversation = append(g.conversation, response)
+		g.addMessage(response)
+		g.logf("info", "provider", "response in %s: %d chars, %d tool calls", time.Since(callStart).Round(time.Millisecond), len(response.Content), len(response.ToolCalls))
+		// Services that don't report usage are counted by estimate.
+		if usage == nil {
+			usage = &provider.Usage{PromptTokens: promptTokens, CompletionTokens: provider.EstimateMessageTokens(response)}
+		}
+		g.recordUsage(*usage)
 
-		if response.Content != "" {
+		if response.Content != "" || response.Reasoning != "" {
 			runtime.EventsEmit(g.appCtx, "agent:message", map[string]string{
-				"id":      g.id,
-				"role":    "assistant",
-				"content": response.Content,
+				"id":        g.id,
+				"role":      "assistant",
+				"content":   response.Content,
+				"reasoning": response.Reasoning,
 			})
 		}
 
 		if len(response.ToolCalls) == 0 {
-			return nil
+			note := g.steering.Take()
+			if note == "" {
+				return nil
+			}
+			g.logf("info", "agent", "delivering steering note as a new message")
+			g.addMessage(provider.Message{Role: "user", Content: note})
+			continue
 		}
 
 		var toolResults []provider.ToolResult
@@ -221,12 +650,25 @@ func (g *GUIAgent) runInferenceLoop() error {
 				"tool": tc.Name,
 			})
 
+			if agent.BlockedByPlan(g.plan, tc.Name) {
+				g.logf("info", "tool", "%s held back until the plan is approved", tc.Name)
+				g.recordAudit(tc, audit.Denied, "", nil)
+				toolResults = append(toolResults, provider.ToolResult{
+					ID:      tc.ID,
+					Content: agent.PlanRequired,
+					IsError: true,
+				})
+				continue
+			}
+
 			approved, err := g.requestApproval(tc)
 			if err != nil {
 				return err
 			}
 
 			if !approved {
+				g.logf("info", "tool", "%s denied by user", tc.Name)
+				g.recordAudit(tc, audit.Denied, "", nil)
 				toolResults = append(toolResults, provider.ToolResult{
 					ID:      tc.ID,
 					Content: "Tool execution was denied by user.",
@@ -235,33 +677,97 @@ func (g *GUIAgent) runInferenceLoop() error {
 				continue
 			}
 
+			g.logf("debug", "tool", "%s input: %s", tc.Name, text.Head(tools.RedactInput(tc.Input), 500))
+			toolStart := time.Now()
 			result, toolErr := g.executeTool(tc)
+			result, images := tools.SplitImages(result)
+			g.timing.AddTool(time.Since(toolStart))
+			decision := audit.Approved
+			if autoApproveTools[tc.Name] {
+				decision = audit.Auto
+			}
+			g.recordAudit(tc, decision, result, toolErr)
 
+			content := result
 			if toolErr != nil {
-				result = toolErr.Error()
+				g.logf("error", "tool", "%s failed after %s: %v", tc.Name, time.Since(toolStart).Round(time.Millisecond), toolErr)
+				result = tools.ErrorResult(toolErr)
+				content = result
+			} else {
+				g.logf("info", "tool", "%s completed in %s (%d bytes)", tc.Name, time.Since(toolStart).Round(time.Millisecond), len(result))
+				content = g.guardResult(tc.Name, g.shrink(tc, g.archive(tc.Name, result)))
 			}
 
 			toolResults = append(toolResults, provider.ToolResult{
 				ID:      tc.ID,
-				Content: result,
+				Content: content,
 				IsError: toolErr != nil,
+				Images:  provider.ToolImages(images),
 			})
 
 			runtime.EventsEmit(g.appCtx, "agent:tool_result", map[string]interface{}{
 				"id":      g.id,
 				"tool":    tc.Name,
-				"result":  truncate(result, 500),
+				"result":  text.HeadTail(result, 500),
 				"isError": toolErr != nil,
 			})
 		}
 
-		g.conversation = append(g.conversation, provider.Message{
+		note := g.steering.Take()
+		if note != "" {
+			g.logf("info", "agent", "delivering steering note with the tool results")
+		}
+		g.addMessage(provider.Message{
 			Role:        "user",
+			Content:     note,
 			ToolResults: toolResults,
 		})
 	}
 }
 
+// recordAudit adds a tool call to the audit log, if there is one. Denied
+// calls are recorded too, without a result.
+func (g *GUIAgent) recordAudit(tc provider.ToolCall, decision, result string, toolErr error) {
+	e := audit.Entry{
+		Agent:    g.id,
+		Tool:     tc.Name,
+		Input:    audit.Hash(tc.Input),
+		Error:    toolErr != nil,
+		Decision: decision,
+	}
+	if toolErr != nil {
+		result = toolErr.Error()
+	}
+	if decision != audit.Denied {
+		e.Result = audit.Hash([]byte(result))
+	}
+	if err := g.audit.Record(e); err != nil {
+		g.logf("warn", "audit", "audit log write failed: %v", err)
+	}
+}
+
+// guardResult wraps a tool result for the model. If scanning is on and it
+// appears to contain injected instructions the frontend is warned before
+// the next request. Results the user wrote themselves are passed through.
+func (g *GUIAgent) guardResult(tool, result string) string {
+	if t, ok := g.tools.Get(tool); ok && t.FromUser {
+		return result
+	}
+	var findings []guard.Finding
+	if g.scanOutput {
+		findings = guard.Scan(result)
+	}
+	if len(findings) > 0 {
+		g.logf("warn", "guard", "%s output contains %d passage(s) addressed to the model, first: %q", tool, len(findings), findings[0].Excerpt)
+		runtime.EventsEmit(g.appCtx, "agent:injection_warning", map[string]interface{}{
+			"id":       g.id,
+			"tool":     tool,
+			"findings": findings,
+		})
+	}
+	return guard.Wrap(tool, result, findings)
+}
+
 func (g *GUIAgent) requestApproval(tc provider.ToolCall) (bool, error) {
 	if autoApproveTools[tc.Name] {
 		return true, nil
@@ -284,7 +790,7 @@ func (g *GUIAgent) requestApproval(tc provider.ToolCall) (bool, error) {
 		ID:        approvalID,
 		AgentID:   g.id,
 		Tool:      tc.Name,
-		Arguments: string(tc.Input),
+		Arguments: tools.RedactInput(tc.Input),
 	})
 
 	select {
@@ -305,18 +811,159 @@ func (g *GUIAgent) RespondToApproval(approvalID string, approved bool, reason st
 	}
 }
 
+// askUser shows an ask_user question in a modal and waits for the answer.
+// An empty answer means the user dismissed it.
+func (g *GUIAgent) askUser(question string, choices []string) (string, bool, error) {
+	questionID := fmt.Sprintf("%s-q%d", g.id, atomic.AddInt64(&questionCounter, 1))
+	answerChan := make(chan string, 1)
+
+	g.approvalMu.Lock()
+	g.pendingQuestion[questionID] = answerChan
+	g.approvalMu.Unlock()
+
+	defer func() {
+		g.approvalMu.Lock()
+		delete(g.pendingQuestion, questionID)
+		g.approvalMu.Unlock()
+	}()
+
+	if choices == nil {
+		choices = []string{}
+	}
+	g.logf("info", "tool", "asking user: %s", question)
+	runtime.EventsEmit(g.appCtx, "agent:question", UserQuestion{
+		ID:       questionID,
+		AgentID:  g.id,
+		Question: question,
+		Choices:  choices,
+	})
+
+	select {
+	case <-g.ctx.Done():
+		return "", false, g.ctx.Err()
+	case answer := <-answerChan:
+		return answer, answer != "", nil
+	}
+}
+
+func (g *GUIAgent) AnswerQuestion(questionID, answer string) {
+	g.approvalMu.Lock()
+	ch, ok := g.pendingQuestion[questionID]
+	g.approvalMu.Unlock()
+
+	if ok {
+		ch <- answer
+	}
+}
+
+// Info returns the agent's current title and summary.
+func (g *GUIAgent) Info() session.Info {
+	g.infoMu.Lock()
+	defer g.infoMu.Unlock()
+	return g.info
+}
+
+// LoadHistory replaces the agent's conversation with records, as when it
+// is forked from another agent, and continues from their last turn.
+func (g *GUIAgent) LoadHistory(records []session.Record, info session.Info) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.turns = 0
+	for _, r := range records {
+		if err := g.conversation.Append(r.Turn, r.Message); err != nil {
+			return err
+		}
+		g.turns = max(g.turns, r.Turn)
+	}
+	g.infoMu.Lock()
+	g.info = info
+	g.infoMu.Unlock()
+	g.logf("info", "agent", "loaded %d messages over %d turns", len(records), g.turns)
+	return nil
+}
+
+// Describe updates the agent's title and summary from its latest turn,
+// prompted by prompt. ok is false if that failed or there was nothing to
+// describe.
+func (g *GUIAgent) Describe(prompt string) (info session.Info, ok bool) {
+	messages := g.conversation.Messages()
+	var response string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" && messages[i].Content != "" {
+			response = messages[i].Content
+			break
+		}
+	}
+	if response == "" {
+		return session.Info{}, false
+	}
+
+	g.infoMu.Lock()
+	defer g.infoMu.Unlock()
+	info, err := agent.Describe(g.ctx, g.provider, g.info, prompt, response)
+	if err != nil {
+		g.logf("warn", "agent", "describing conversation failed: %v", err)
+		return session.Info{}, false
+	}
+	g.info = info
+	g.logf("debug", "agent", "titled %q", info.Title)
+	return info, true
+}
+
+// Steer queues a note from the user for the running turn. It reaches the
+// model with the next request, after any tool calls in flight finish.
+func (g *GUIAgent) Steer(note string) {
+	g.steering.Add(note)
+	g.logf("info", "agent", "steering note queued: %s", text.Head(note, 200))
+}
+
+// AttachShell routes the agent's bash tool through exec instead of a fresh
+// subprocess, giving it the tool's environment variables (KEY=VALUE) to
+// set. Passing a nil exec restores the default behaviour.
+func (g *GUIAgent) AttachShell(label string, exec func(command string, env []string) (string, error)) {
+	g.shellMu.Lock()
+	g.shellExec = exec
+	g.shellLabel = label
+	g.shellMu.Unlock()
+
+	if exec == nil {
+		g.logf("info", "tool", "bash detached from terminal")
+	} else {
+		g.logf("info", "tool", "bash attached to terminal %s", label)
+	}
+}
+
+func (g *GUIAgent) AttachedShell() string {
+	g.shellMu.Lock()
+	defer g.shellMu.Unlock()
+	return g.shellLabel
+}
+
 func (g *GUIAgent) executeTool(tc provider.ToolCall) (string, error) {
 	tool, ok := g.tools.Get(tc.Name)
 	if !ok {
-		return "", fmt.Errorf("tool '%s' not found", tc.Name)
+		return "", tools.NewError(tools.ErrNotFound, "tool '%s' not found", tc.Name).WithDetail("tool", tc.Name)
 	}
 
-	return tool.Function(json.RawMessage(tc.Input))
-}
+	ctx := g.ctx
+	if tc.Name == "bash" {
+		g.shellMu.Lock()
+		shellExec := g.shellExec
+		g.shellMu.Unlock()
+
+		// The attached terminal stands in for a fresh shell, under the
+		// same policy, environment and middleware as any other call.
+		if shellExec != nil {
+			ctx = tools.WithShell(ctx, func(_ context.Context, command string, vars []string) (string, error) {
+				return shellExec(command, vars)
+			})
+		}
+	}
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	result, cached, err := g.cache.Execute(ctx, tool, json.RawMessage(tc.Input))
+	if cached {
+		g.logf("debug", "tool", "%s result reused from cache", tc.Name)
 	}
-	return s[:maxLen] + "..."
+	return result, err
 }
@@ -0,0 +1,459 @@
+// Package index keeps an embedding index of a project's code, so the agent
+// can find code by what it does rather than by the words in it.
+//
+// Files are split into overlapping chunks of lines, and each chunk is
+// embedded with the provider's embedding model. The vectors are kept in a
+// JSON file outside the project, one per working directory, and brought
+// up to date before each search: only files whose size or modification
+// time changed are read again, and only those whose content changed are
+// embedded again. A search embeds the query and returns the chunks whose
+// vectors are closest to it.
+package index
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"brutus/provider"
+	"brutus/tools"
+)
+
+const (
+	// chunkLines is how many lines a chunk holds, and chunkOverlap how
+	// many of them it shares with the one before, so code that straddles
+	// a boundary is whole in one of them.
+	chunkLines   = 40
+	chunkOverlap = 10
+
+	// maxChunkBytes cuts chunks of very long lines short; embedding
+	// models read only so many tokens.
+	maxChunkBytes = 4000
+
+	// embedBatch is about how many chunks are embedded at a time; a
+	// file's chunks are never split between batches.
+	embedBatch = 64
+
+	// maxFileSize and maxFiles bound what is indexed: larger files are
+	// usually generated or data, and a project with more files than
+	// maxFiles is indexed only up to it.
+	maxFileSize = 256 * 1024
+	maxFiles    = 5000
+
+	// binaryProbe is how much of a file is checked for NUL bytes.
+	binaryProbe = 8000
+)
+
+// Dir returns the directory indexes are kept in, one file per working
+// directory.
+func Dir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "brutus-index")
+	}
+	return filepath.Join(home, ".brutus", "index")
+}
+
+var nameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Path returns the file the index of the project in workDir is kept in,
+// named like its memories: the directory's base name and a hash of its
+// absolute path.
+func Path(workDir string) string {
+	abs, err := filepath.Abs(workDir)
+	if err != nil {
+		abs = workDir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	base := nameRe.ReplaceAllString(filepath.Base(abs), "_")
+	return filepath.Join(Dir(), base+"-"+hex.EncodeToString(sum[:])[:12]+".json")
+}
+
+// Vector is an embedding, normalized to unit length so the dot product of
+// two is their cosine similarity. It is stored as base64 of little-endian
+// float32s, a third the size of a JSON array of numbers.
+type Vector []float32
+
+func (v Vector) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf))
+}
+
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(buf)%4 != 0 {
+		return fmt.Errorf("invalid vector")
+	}
+	*v = make(Vector, len(buf)/4)
+	for i := range *v {
+		(*v)[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return nil
+}
+
+// normalize returns v scaled to unit length.
+func normalize(v []float32) Vector {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	norm := math.Sqrt(sum)
+	out := make(Vector, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+func dot(a, b Vector) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// Chunk is a run of lines of one file and its embedding.
+type Chunk struct {
+	StartLine int    `json:"start_line"` // 1-based
+	EndLine   int    `json:"end_line"`   // Inclusive
+	Vector    Vector `json:"vector"`
+}
+
+// file is what the index holds about one file: enough to tell whether it
+// changed, and its chunks.
+type file struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"` // sha256 of the content
+	Chunks  []Chunk   `json:"chunks"`
+}
+
+// indexFile is the on-disk form of an Index.
+type indexFile struct {
+	Model string           `json:"model"`
+	Files map[string]*file `json:"files"` // By slash-separated path below the root
+}
+
+// Index is the embedding index of one project. It is safe for concurrent
+// use.
+type Index struct {
+	root     string
+	path     string
+	embedder provider.Embedder
+
+	mu    sync.Mutex
+	files map[string]*file // Nil until loaded
+}
+
+// Open returns the index of the project at root kept in path, using
+// embedder for its vectors. Nothing is read or embedded until Update or
+// Search, so a session that never searches pays nothing for it.
+func Open(root, path string, embedder provider.Embedder) *Index {
+	return &Index{root: root, path: path, embedder: embedder}
+}
+
+// loadLocked reads the index's file the first time it is needed. An index
+// made with another embedding model, or one that can't be read, starts
+// over.
+func (ix *Index) loadLocked() {
+	if ix.files != nil {
+		return
+	}
+	ix.files = map[string]*file{}
+	data, err := os.ReadFile(ix.path)
+	if err != nil {
+		return
+	}
+	var f indexFile
+	if json.Unmarshal(data, &f) == nil && f.Model == ix.embedder.EmbeddingModel() && f.Files != nil {
+		ix.files = f.Files
+	}
+}
+
+// Stats describes what an Update did.
+type Stats struct {
+	Files    int // Files in the index
+	Chunks   int // Chunks in the index
+	Embedded int // Files embedded by this update
+	Removed  int // Files dropped because they are gone or no longer indexed
+}
+
+// pending is a changed file waiting to be embedded.
+type pending struct {
+	rel    string
+	entry  *file
+	texts  []string
+	chunks []Chunk
+}
+
+// Update brings the index up to date with the files below the root and
+// saves it. Files are embedded a few at a time, so a large first update
+// that fails or is cancelled keeps what it finished.
+func (ix *Index) Update(ctx context.Context) (Stats, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.loadLocked()
+
+	var stats Stats
+	seen := map[string]bool{}
+	var changed []pending
+	err := tools.WalkProject(ix.root, func(path string, d fs.DirEntry) error {
+		if len(seen) == maxFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize || info.Size() == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(ix.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		old := ix.files[rel]
+		if old != nil && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			seen[rel] = true
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(content[:min(len(content), binaryProbe)], 0) >= 0 {
+			return nil
+		}
+		seen[rel] = true
+		entry := &file{Size: info.Size(), ModTime: info.ModTime(), Hash: hash(content)}
+		if old != nil && old.Hash == entry.Hash {
+			entry.Chunks = old.Chunks
+			ix.files[rel] = entry
+			return nil
+		}
+		p := pending{rel: rel, entry: entry}
+		for _, c := range chunk(content) {
+			p.chunks = append(p.chunks, Chunk{StartLine: c.start, EndLine: c.end})
+			p.texts = append(p.texts, rel+"\n"+c.text)
+		}
+		changed = append(changed, p)
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to walk %s: %w", ix.root, err)
+	}
+
+	for rel := range ix.files {
+		if !seen[rel] {
+			delete(ix.files, rel)
+			stats.Removed++
+		}
+	}
+
+	// Batches of whole files, so a file is never half embedded.
+	var embedErr error
+	for start := 0; start < len(changed) && embedErr == nil; {
+		end, texts := start, []string(nil)
+		for end < len(changed) && (end == start || len(texts)+len(changed[end].texts) <= embedBatch) {
+			texts = append(texts, changed[end].texts...)
+			end++
+		}
+		var vectors [][]float32
+		if vectors, embedErr = ix.embedder.Embeddings(ctx, texts); embedErr == nil && len(vectors) != len(texts) {
+			embedErr = fmt.Errorf("got %d embeddings for %d chunks", len(vectors), len(texts))
+		}
+		if embedErr != nil {
+			break
+		}
+		for _, p := range changed[start:end] {
+			for i := range p.chunks {
+				p.chunks[i].Vector = normalize(vectors[0])
+				vectors = vectors[1:]
+			}
+			p.entry.Chunks = p.chunks
+			ix.files[p.rel] = p.entry
+			stats.Embedded++
+		}
+		start = end
+	}
+
+	for _, f := range ix.files {
+		stats.Files++
+		stats.Chunks += len(f.Chunks)
+	}
+	if err := ix.save(); err != nil {
+		return stats, err
+	}
+	if embedErr != nil {
+		return stats, fmt.Errorf("failed to embed: %w", embedErr)
+	}
+	return stats, nil
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// save writes the index to its file, through a temporary file so a crash
+// can't leave half of one.
+func (ix *Index) save() error {
+	data, err := json.Marshal(indexFile{Model: ix.embedder.EmbeddingModel(), Files: ix.files})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0755); err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
+
+// textChunk is a chunk before it is embedded.
+type textChunk struct {
+	start, end int
+	text       string
+}
+
+// chunk splits content into runs of chunkLines lines, each overlapping
+// the one before by chunkOverlap, cut short at maxChunkBytes. Runs of
+// only blank lines are left out.
+func chunk(content []byte) []textChunk {
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	var chunks []textChunk
+	for start := 0; start < len(lines); {
+		end := min(start+chunkLines, len(lines))
+		size := 0
+		for i := start; i < end; i++ {
+			if size += len(lines[i]) + 1; size > maxChunkBytes && i > start {
+				end = i
+				break
+			}
+		}
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, textChunk{start: start + 1, end: end, text: text[:min(len(text), maxChunkBytes)]})
+		}
+		if end == len(lines) {
+			break
+		}
+		// Chunks cut short by size don't overlap, so the window always
+		// moves on.
+		if end-start == chunkLines {
+			start = end - chunkOverlap
+		} else {
+			start = end
+		}
+	}
+	return chunks
+}
+
+// Result is a chunk a search found.
+type Result struct {
+	Path      string  // Slash-separated, below the root
+	StartLine int     // 1-based
+	EndLine   int     // Inclusive
+	Score     float64 // Cosine similarity to the query, at most 1
+	Text      string  // The chunk's lines as they are now
+}
+
+// Search updates the index, then returns the limit chunks closest in
+// meaning to query, best first, from files whose path starts with prefix
+// when it isn't empty. Overlapping chunks of one file are returned once.
+func (ix *Index) Search(ctx context.Context, query, prefix string, limit int) ([]Result, Stats, error) {
+	stats, err := ix.Update(ctx)
+	if err != nil && stats.Chunks == 0 {
+		return nil, stats, err
+	}
+	vectors, err := ix.embedder.Embeddings(ctx, []string{query})
+	if err != nil {
+		return nil, stats, fmt.Errorf("failed to embed the query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, stats, fmt.Errorf("got %d embeddings for the query", len(vectors))
+	}
+	q := normalize(vectors[0])
+
+	ix.mu.Lock()
+	var found []Result
+	for rel, f := range ix.files {
+		if prefix != "" && !strings.HasPrefix(rel, prefix) {
+			continue
+		}
+		for _, c := range f.Chunks {
+			found = append(found, Result{Path: rel, StartLine: c.StartLine, EndLine: c.EndLine, Score: dot(q, c.Vector)})
+		}
+	}
+	ix.mu.Unlock()
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Score != found[j].Score {
+			return found[i].Score > found[j].Score
+		}
+		if found[i].Path != found[j].Path {
+			return found[i].Path < found[j].Path
+		}
+		return found[i].StartLine < found[j].StartLine
+	})
+
+	var results []Result
+	for _, r := range found {
+		if len(results) == limit {
+			break
+		}
+		if overlaps(results, r) {
+			continue
+		}
+		r.Text = readLines(filepath.Join(ix.root, filepath.FromSlash(r.Path)), r.StartLine, r.EndLine)
+		results = append(results, r)
+	}
+	return results, stats, nil
+}
+
+// overlaps reports whether r shares lines with a result already taken.
+func overlaps(results []Result, r Result) bool {
+	for _, o := range results {
+		if o.Path == r.Path && o.StartLine <= r.EndLine && r.StartLine <= o.EndLine {
+			return true
+		}
+	}
+	return false
+}
+
+// readLines returns lines start to end of path, or "" if it can't be read.
+func readLines(path string, start, end int) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(content), "\n")
+	if start > len(lines) {
+		return ""
+	}
+	return strings.Join(lines[start-1:min(end, len(lines))], "\n")
+}
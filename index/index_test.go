@@ -0,0 +1,151 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wordEmbedder embeds text as counts of its words, hashed into a few
+// dimensions, so texts sharing words are close. It counts the texts it
+// embeds.
+type wordEmbedder struct {
+	model    string
+	embedded int
+}
+
+func (e *wordEmbedder) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	e.embedded += len(texts)
+	var vectors [][]float32
+	for _, text := range texts {
+		v := make([]float32, 64)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return r < 'a' || r > 'z' }) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			v[h.Sum32()%64]++
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+func (e *wordEmbedder) EmbeddingModel() string { return e.model }
+
+func writeProject(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"retry.go":          "package x\n\n// backoff waits longer after each failed request\nfunc backoff() {}\n",
+		"parse.go":          "package x\n\n// parse reads the config file\nfunc parse() {}\n",
+		"sub/render.go":     "package sub\n\n// render draws the page\nfunc render() {}\n",
+		"node_modules/x.js": "backoff backoff backoff",
+		"logo.png":          "\x89PNG\x00\x00backoff",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestSearch(t *testing.T) {
+	root := writeProject(t)
+	e := &wordEmbedder{model: "words"}
+	ix := Open(root, filepath.Join(t.TempDir(), "index.json"), e)
+
+	results, stats, err := ix.Search(context.Background(), "backoff after a failed request", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 3 || stats.Embedded != 3 {
+		t.Errorf("stats = %+v, want the 3 source files indexed", stats)
+	}
+	if len(results) != 2 || results[0].Path != "retry.go" || results[0].StartLine != 1 || !strings.Contains(results[0].Text, "func backoff") {
+		t.Fatalf("results = %+v", results)
+	}
+
+	results, _, err = ix.Search(context.Background(), "draws the page", "sub/", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "sub/render.go" {
+		t.Errorf("results below sub/ = %+v", results)
+	}
+}
+
+func TestUpdateIncremental(t *testing.T) {
+	root := writeProject(t)
+	path := filepath.Join(t.TempDir(), "index.json")
+	e := &wordEmbedder{model: "words"}
+	if _, err := Open(root, path, e).Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new session reads the index back and embeds only what changed.
+	e.embedded = 0
+	later := time.Now().Add(time.Minute)
+	os.WriteFile(filepath.Join(root, "parse.go"), []byte("package x\n\nfunc parse() { panic(1) }\n"), 0644)
+	os.Chtimes(filepath.Join(root, "parse.go"), later, later)
+	os.Chtimes(filepath.Join(root, "retry.go"), later, later) // Touched, not changed
+	os.Remove(filepath.Join(root, "sub", "render.go"))
+	stats, err := Open(root, path, e).Update(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Embedded != 1 || stats.Removed != 1 || stats.Files != 2 || e.embedded != 1 {
+		t.Errorf("stats = %+v after embedding %d chunks, want only parse.go embedded", stats, e.embedded)
+	}
+
+	// Vectors from another model are not reused.
+	other := &wordEmbedder{model: "other"}
+	if stats, _ := Open(root, path, other).Update(context.Background()); stats.Embedded != 2 {
+		t.Errorf("with another model, embedded %d files, want 2", stats.Embedded)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprint("line ", i))
+	}
+	chunks := chunk([]byte(strings.Join(lines, "\n") + "\n"))
+	var spans []string
+	for _, c := range chunks {
+		spans = append(spans, fmt.Sprintf("%d-%d", c.start, c.end))
+	}
+	if got := strings.Join(spans, " "); got != "1-40 31-70 61-100" {
+		t.Errorf("chunks = %s", got)
+	}
+
+	long := strings.Repeat(strings.Repeat("x", 1000)+"\n", 10)
+	for _, c := range chunk([]byte(long)) {
+		if len(c.text) > maxChunkBytes {
+			t.Errorf("chunk %d-%d is %d bytes", c.start, c.end, len(c.text))
+		}
+	}
+}
+
+func TestSearchTool(t *testing.T) {
+	root := writeProject(t)
+	tool := NewSearchTool(Open(root, filepath.Join(t.TempDir(), "index.json"), &wordEmbedder{model: "words"}))
+
+	out, err := tool.Function(json.RawMessage(`{"query": "reads the config file", "limit": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Indexed 3 changed files") || !strings.Contains(out, "parse.go:1-4") || !strings.Contains(out, "     4\tfunc parse() {}") {
+		t.Errorf("output = %q", out)
+	}
+	if _, err := tool.Function(json.RawMessage(`{"query": " "}`)); err == nil {
+		t.Error("empty query accepted")
+	}
+}
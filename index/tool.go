@@ -0,0 +1,75 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"brutus/tools"
+)
+
+const (
+	defaultResults = 5
+	maxResults     = 20
+)
+
+// SearchInput defines the parameters for the semantic_search tool.
+type SearchInput struct {
+	Query string `json:"query" jsonschema_description:"What the code you are looking for does, in words, e.g. 'retry failed HTTP requests with backoff'."`
+	Path  string `json:"path,omitempty" jsonschema_description:"Only search files below this directory, relative to the working directory."`
+	Limit int    `json:"limit,omitempty" jsonschema_description:"How many results to return (default 5, at most 20)."`
+}
+
+// NewSearchTool returns semantic_search, which finds code in ix by
+// meaning.
+func NewSearchTool(ix *Index) tools.Tool {
+	return tools.NewToolCtx[SearchInput](
+		"semantic_search",
+		`Find code by what it does rather than by the words in it: describe the behaviour you are looking for and get the closest matching chunks of code, with their paths and line numbers. Use it when you don't know what the code is called; use code_search or find_symbol when you know a name or exact text.
+The first search in a project indexes it, which may take a while; later ones only re-index changed files.`,
+		func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args SearchInput
+			if err := json.Unmarshal(input, &args); err != nil {
+				return "", tools.NewError(tools.ErrInvalidInput, "invalid input: %v", err)
+			}
+			args.Query = strings.TrimSpace(args.Query)
+			if args.Query == "" {
+				return "", tools.NewError(tools.ErrInvalidInput, "query is required")
+			}
+			limit := defaultResults
+			if args.Limit > 0 {
+				limit = min(args.Limit, maxResults)
+			}
+			prefix := strings.TrimPrefix(strings.Trim(strings.ReplaceAll(args.Path, "\\", "/"), "/"), "./")
+			if prefix == "." {
+				prefix = ""
+			} else if prefix != "" {
+				prefix += "/"
+			}
+
+			results, stats, err := ix.Search(ctx, args.Query, prefix, limit)
+			if err != nil {
+				return "", tools.WrapError(err, "semantic search failed")
+			}
+			var b strings.Builder
+			if stats.Embedded > 0 {
+				fmt.Fprintf(&b, "(Indexed %d changed files; %d files in the index.)\n\n", stats.Embedded, stats.Files)
+			}
+			if len(results) == 0 {
+				b.WriteString("No matching code found.")
+				return b.String(), nil
+			}
+			for i, r := range results {
+				if i > 0 {
+					b.WriteString("\n\n")
+				}
+				fmt.Fprintf(&b, "%s:%d-%d (similarity %.2f)\n", r.Path, r.StartLine, r.EndLine, r.Score)
+				for n, line := range strings.Split(r.Text, "\n") {
+					fmt.Fprintf(&b, "%6d\t%s\n", r.StartLine+n, line)
+				}
+			}
+			return strings.TrimRight(b.String(), "\n"), nil
+		},
+	)
+}
@@ -0,0 +1,232 @@
+package testcli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"brutus/agent"
+	"brutus/internal/text"
+	"brutus/internal/theme"
+	"brutus/provider"
+	"brutus/sdk"
+)
+
+// allModels, as a model parameter, stands for every model the services on
+// the network announce.
+const allModels = "*"
+
+// runMatrix runs a live scenario once per run for every combination of its
+// parameters and reports the pass rate of each, for comparing models (or
+// turn limits, or fixtures) on the same task. Runs go one at a time, since
+// each one works in the process's directory.
+func runMatrix(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	models := fs.String("models", "", "Comma-separated models to run with, replacing the scenario's; * is every model on the network")
+	maxTurns := fs.String("max-turns", "", "Comma-separated turn limits, replacing the scenario's")
+	runs := fs.Int("runs", 0, "Runs per combination (default: the scenario's runs, or 1)")
+	timeout := fs.Int("timeout", 5, "Saturn discovery timeout in seconds")
+	verbose := fs.Bool("v", false, "Verbose output")
+	fs.Parse(args)
+
+	remaining := fs.Args()
+	if len(remaining) < 1 {
+		fmt.Println("Usage: " + progName + " run [flags] <file>")
+		fmt.Println("\nFlags:")
+		fs.PrintDefaults()
+		fmt.Println("\nNote: Requires a Saturn beacon on the network!")
+		os.Exit(1)
+	}
+
+	scenario, err := loadLiveScenario(remaining[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	space := scenario.Parameters
+	if *models != "" {
+		space.Model = splitList(*models)
+	}
+	if *maxTurns != "" {
+		space.MaxTurns = nil
+		for _, item := range splitList(*maxTurns) {
+			n, err := strconv.Atoi(item)
+			if err != nil || n <= 0 {
+				fmt.Printf("Error: invalid -max-turns value %q\n", item)
+				os.Exit(1)
+			}
+			space.MaxTurns = append(space.MaxTurns, n)
+		}
+	}
+	if *runs <= 0 {
+		*runs = max(scenario.Runs, 1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	discoveryTimeout := time.Duration(*timeout) * time.Second
+	fmt.Println(theme.Warning("Discovering Saturn services..."))
+	services, err := provider.CreateDiscoverer(nil).Discover(ctx, discoveryTimeout)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	requested := len(space.Model)
+	space.Model = expandModels(space.Model, services)
+	if len(space.Model) == 0 && requested > 0 {
+		fmt.Println("Error: no service announces its models; list them with -models")
+		os.Exit(1)
+	}
+
+	combos := space.Combinations()
+	fmt.Printf("Running scenario %s: %d combination(s) x %d run(s)\n", scenario.Name, len(combos), *runs)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	results := sdk.RunMatrix(ctx, combos, *runs, func(ctx context.Context, p sdk.Params) error {
+		fmt.Printf("\n--- %s\n", p)
+		err := runCombination(ctx, scenario, p, services, discoveryTimeout, *verbose)
+		// A run that fails mid-way must not leave the next one in its
+		// workspace.
+		os.Chdir(cwd)
+		if err != nil {
+			fmt.Printf("%s %s\n", theme.Error("FAIL"), err)
+		} else {
+			fmt.Println(theme.Success("PASS"))
+		}
+		return err
+	})
+
+	fmt.Println("\n=== Pass rates ===")
+	fmt.Print(sdk.FormatMatrix(results))
+}
+
+// runCombination runs every agent of the scenario with p's parameters, in
+// a fresh copy of p's workspace if it has one, then runs the scenario's
+// checks there.
+func runCombination(ctx context.Context, scenario *LiveScenario, p sdk.Params, services []provider.SaturnService, timeout time.Duration, verbose bool) error {
+	if p.Workspace != "" {
+		dir, cleanup, err := sdk.PrepareWorkspace(p.Workspace)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if err := os.Chdir(dir); err != nil {
+			return err
+		}
+	}
+
+	saturnCfg := provider.SaturnConfig{
+		DiscoveryTimeout: timeout,
+		Model:            p.Model,
+	}
+	if p.Model != "" && announces(services, p.Model) {
+		saturnCfg.Filter = &provider.DiscoveryFilter{RequiredModel: p.Model}
+	}
+	harness := sdk.NewLiveMultiAgentHarness(saturnCfg).
+		WithDefaultTools().
+		WithVerbose(verbose)
+	if p.MaxTurns > 0 {
+		harness.WithMaxTurns(p.MaxTurns)
+	}
+
+	var agents []sdk.LiveAgentConfig
+	for _, a := range scenario.Agents {
+		agents = append(agents, sdk.LiveAgentConfig{ID: a.ID, SystemPrompt: a.SystemPrompt, InitialTask: a.InitialTask})
+	}
+	results, err := harness.RunSequential(ctx, agents)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if !result.Success {
+			return fmt.Errorf("agent %s: %v", result.AgentID, result.Error)
+		}
+		if verbose && result.FinalMessage != "" {
+			fmt.Printf("[%s] %s\n", result.AgentID, text.Head(result.FinalMessage, 300))
+		}
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	for _, check := range agent.RunVerification(ctx, dir, scenario.Checks) {
+		if !check.Passed {
+			return fmt.Errorf("check %q failed: %s", check.Command, text.Head(strings.TrimSpace(check.Output), 300))
+		}
+	}
+	return nil
+}
+
+// expandModels replaces "*" in models with every model the services
+// announce, keeping the order and dropping repeats.
+func expandModels(models []string, services []provider.SaturnService) []string {
+	var expanded []string
+	seen := map[string]bool{}
+	add := func(model string) {
+		if !seen[model] {
+			seen[model] = true
+			expanded = append(expanded, model)
+		}
+	}
+	for _, model := range models {
+		if model != allModels {
+			add(model)
+			continue
+		}
+		var announced []string
+		for _, svc := range services {
+			announced = append(announced, svc.Models...)
+		}
+		sort.Strings(announced)
+		for _, m := range announced {
+			add(m)
+		}
+	}
+	return expanded
+}
+
+func announces(services []provider.SaturnService, model string) bool {
+	for _, svc := range services {
+		for _, m := range svc.Models {
+			if m == model {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func loadLiveScenario(filename string) (*LiveScenario, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario file: %w", err)
+	}
+	var scenario LiveScenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("parsing scenario file: %w", err)
+	}
+	return &scenario, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+// Package text clips long strings for display and for the LLM without
+// splitting multibyte characters.
+package text
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"brutus/internal/token"
+)
+
+// Ellipsis marks where Head cut a string.
+const Ellipsis = "..."
+
+// Head returns the first max runes of s followed by Ellipsis, or s itself if
+// it is already short enough.
+func Head(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	if max <= 0 {
+		return Ellipsis
+	}
+	n := 0
+	for i := range s {
+		if n == max {
+			return s[:i] + Ellipsis
+		}
+		n++
+	}
+	return s
+}
+
+// HeadTail keeps the first and last runes of s, max in total, and replaces
+// the middle with a note saying how much was left out. Command output and
+// logs usually carry their most useful lines at the start (what ran) and at
+// the end (how it finished), so both are preserved.
+func HeadTail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	total := utf8.RuneCountInString(s)
+	if total <= max {
+		return s
+	}
+	if max <= 0 {
+		return fmt.Sprintf("[%d characters omitted]", total)
+	}
+
+	headRunes := max / 2
+	tailRunes := max - headRunes
+
+	head, tail := 0, len(s)
+	n := 0
+	for i := range s {
+		if n == headRunes {
+			head = i
+			break
+		}
+		n++
+	}
+	for n = 0; n < tailRunes; n++ {
+		_, size := utf8.DecodeLastRuneInString(s[:tail])
+		tail -= size
+	}
+
+	return fmt.Sprintf("%s\n... [%d characters omitted] ...\n%s", s[:head], total-max, s[tail:])
+}
+
+// HeadTailTokens is HeadTail with the budget given as an estimated token
+// count, using the same ratio as token.Estimate.
+func HeadTailTokens(s string, maxTokens int) string {
+	return HeadTail(s, maxTokens*token.CharsPerToken)
+}
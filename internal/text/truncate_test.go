@@ -0,0 +1,56 @@
+package text
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHead(t *testing.T) {
+	tests := []struct {
+		input string
+		max   int
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello world", 5, "hello..."},
+		{"héllo wörld", 5, "héllo..."},
+		{"日本語のテキスト", 3, "日本語..."},
+		{"abc", 0, "..."},
+	}
+	for _, tt := range tests {
+		if got := Head(tt.input, tt.max); got != tt.want {
+			t.Errorf("Head(%q, %d) = %q, want %q", tt.input, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestHeadTail(t *testing.T) {
+	if got := HeadTail("short", 10); got != "short" {
+		t.Errorf("HeadTail left a short string changed: %q", got)
+	}
+	// Five runes but more than five bytes: nothing to cut.
+	if got := HeadTail("ééééé", 5); got != "ééééé" {
+		t.Errorf("HeadTail cut a string within the rune limit: %q", got)
+	}
+
+	got := HeadTail("start-"+strings.Repeat("ü", 100)+"-finish", 16)
+	if !strings.HasPrefix(got, "start-üü\n") || !strings.HasSuffix(got, "\nü-finish") {
+		t.Errorf("HeadTail did not keep head and tail: %q", got)
+	}
+	if !strings.Contains(got, "[97 characters omitted]") {
+		t.Errorf("HeadTail omission note wrong: %q", got)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("HeadTail produced invalid UTF-8: %q", got)
+	}
+}
+
+func TestHeadTailTokens(t *testing.T) {
+	s := strings.Repeat("x", 100)
+	got := HeadTailTokens(s, 5)
+	if !strings.HasPrefix(got, strings.Repeat("x", 10)+"\n") || !strings.Contains(got, "[80 characters omitted]") {
+		t.Errorf("HeadTailTokens(100 chars, 5) = %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+// Package theme centralises the terminal colors used by BRUTUS's CLI output.
+//
+// Colors are emitted only when stdout is a terminal, NO_COLOR is unset and
+// TERM is not "dumb"; otherwise every helper returns its input unchanged so
+// piped output and log captures stay free of escape sequences.
+package theme
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Theme maps each semantic role to an ANSI SGR parameter string such as
+// "94" or "1;36".
+type Theme struct {
+	User      string
+	Assistant string
+	Tool      string
+	Result    string
+	Error     string
+	Warning   string
+	Success   string
+	Muted     string
+	Title     string
+	Highlight string
+	Command   string
+	Banner    string
+}
+
+// Default is the palette BRUTUS has always used.
+var Default = Theme{
+	User:      "94",
+	Assistant: "93",
+	Tool:      "96",
+	Result:    "92",
+	Error:     "91",
+	Warning:   "93",
+	Success:   "92",
+	Muted:     "90",
+	Title:     "1;36",
+	Highlight: "1;33",
+	Command:   "93",
+	Banner:    "1;35",
+}
+
+var (
+	current = Default
+	tty     = term.IsTerminal(int(os.Stdout.Fd()))
+	enabled = detect()
+)
+
+// detect reports whether color should be on by default.
+func detect() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return tty
+}
+
+// Configure applies the --no-color flag on top of environment detection.
+func Configure(noColor bool) {
+	enabled = !noColor && detect()
+}
+
+// Set replaces the active theme.
+func Set(t Theme) {
+	current = t
+}
+
+// Enabled reports whether ANSI escapes are being emitted.
+func Enabled() bool {
+	return enabled
+}
+
+func paint(code, s string) string {
+	if !enabled || code == "" {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+func User(s string) string      { return paint(current.User, s) }
+func Assistant(s string) string { return paint(current.Assistant, s) }
+func Tool(s string) string      { return paint(current.Tool, s) }
+func Result(s string) string    { return paint(current.Result, s) }
+func Error(s string) string     { return paint(current.Error, s) }
+func Warning(s string) string   { return paint(current.Warning, s) }
+func Success(s string) string   { return paint(current.Success, s) }
+func Muted(s string) string     { return paint(current.Muted, s) }
+func Title(s string) string     { return paint(current.Title, s) }
+func Highlight(s string) string { return paint(current.Highlight, s) }
+func Command(s string) string   { return paint(current.Command, s) }
+func Banner(s string) string    { return paint(current.Banner, s) }
+
+// ClearScreen returns the sequence that clears the terminal and homes the
+// cursor, or nothing when output is not a terminal. It is cursor control
+// rather than color, so NO_COLOR does not suppress it.
+func ClearScreen() string {
+	if !tty {
+		return ""
+	}
+	return "\033[2J\033[H"
+}
+
+// StripNoColorFlag removes -no-color/--no-color from args wherever it
+// appears, disabling color if it was present, so every subcommand accepts
+// it without declaring it.
+func StripNoColorFlag(args []string) []string {
+	out := args[:0:0]
+	noColor := false
+	for _, a := range args {
+		if a == "-no-color" || a == "--no-color" {
+			noColor = true
+			continue
+		}
+		out = append(out, a)
+	}
+	if noColor {
+		Configure(true)
+	}
+	return out
+}
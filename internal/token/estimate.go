@@ -0,0 +1,23 @@
+package token
+
+// CharsPerToken is the rough characters-per-token ratio used for estimates.
+// It errs on the side of overestimating for code, which is what we want when
+// guarding budgets and context windows.
+const CharsPerToken = 4
+
+// Estimate returns an approximate token count for s.
+func Estimate(s string) int {
+	if len(s) == 0 {
+		return 0
+	}
+	return (len(s) + CharsPerToken - 1) / CharsPerToken
+}
+
+// EstimateMessages sums Estimate over several strings.
+func EstimateMessages(messages []string) int {
+	total := 0
+	for _, m := range messages {
+		total += Estimate(m)
+	}
+	return total
+}
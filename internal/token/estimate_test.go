@@ -0,0 +1,30 @@
+package token
+
+import "testing"
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"abcdefgh", 2},
+		{"abcdefghi", 3},
+	}
+	for _, tt := range tests {
+		got := Estimate(tt.input)
+		if got != tt.expected {
+			t.Errorf("Estimate(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestEstimateMessages(t *testing.T) {
+	got := EstimateMessages([]string{"abcd", "abcde", ""})
+	if got != 3 {
+		t.Errorf("EstimateMessages() = %d, want 3", got)
+	}
+}
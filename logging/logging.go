@@ -0,0 +1,147 @@
+// Package logging sets up BRUTUS's structured diagnostic log.
+//
+// Diagnostics go to a file (never to the chat on stdout) as text or JSON
+// lines via log/slog, and the file is rotated once it grows past a size
+// limit.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Options configures Setup.
+type Options struct {
+	File       string // Log file path; empty disables file logging
+	Format     string // "text" (default) or "json"
+	Verbose    bool   // Include debug-level records
+	MaxSizeMB  int    // Rotate when the file exceeds this size (default 10)
+	MaxBackups int    // Rotated files to keep (default 3)
+}
+
+// Setup creates the logger described by opts, installs it as the slog and
+// log package default, and returns a closer for the underlying file. With
+// no file configured it returns a logger that discards everything and
+// leaves the log package untouched.
+func Setup(opts Options) (*slog.Logger, io.Closer, error) {
+	if opts.File == "" {
+		return slog.New(slog.DiscardHandler), io.NopCloser(nil), nil
+	}
+
+	w, err := NewRotatingFile(opts.File, int64(opts.MaxSizeMB)*1024*1024, opts.MaxBackups)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	level := slog.LevelInfo
+	if opts.Verbose {
+		level = slog.LevelDebug
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case "", "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		w.Close()
+		return nil, nil, fmt.Errorf("unknown log format %q (want text or json)", opts.Format)
+	}
+
+	logger := slog.New(handler)
+	// Routes the log package through the handler too, so existing
+	// log.Printf diagnostics end up in the file as structured records.
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+	return logger, w, nil
+}
+
+// RotatingFile is an io.WriteCloser that renames the file to path.1,
+// path.2, ... once it exceeds maxSize bytes.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewRotatingFile opens (or appends to) path.
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = 10 * 1024 * 1024
+	}
+	if maxBackups <= 0 {
+		maxBackups = 3
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxBackups))
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileRotatesAndKeepsBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brutus.log")
+	w, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"first-1\n", "second\n", "third-3\n", "fourth\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	expect := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third-3\n",
+		path + ".2": "second\n",
+	}
+	for file, want := range expect {
+		got, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading %s: %v", file, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected only 2 backups to be kept")
+	}
+}
+
+func TestSetupJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brutus.log")
+	logger, closer, err := Setup(Options{File: path, Format: "json"})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	logger.Info("tool", "name", "read_file", "duration_ms", 12)
+	closer.Close()
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"name":"read_file"`) {
+		t.Errorf("expected JSON record, got %q", data)
+	}
+}
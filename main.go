@@ -1,18 +1,29 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"brutus/agent"
+	"brutus/audit"
+	"brutus/config"
+	"brutus/index"
+	"brutus/internal/testcli"
+	"brutus/internal/theme"
+	"brutus/logging"
 	"brutus/provider"
+	"brutus/session"
+	"brutus/telemetry"
 	"brutus/tools"
 )
 
@@ -22,22 +33,242 @@ var embeddedPrompt string
 const Version = "2.0.0"
 
 func main() {
-	verbose := flag.Bool("verbose", false, "Enable verbose logging")
-	version := flag.Bool("version", false, "Print version and exit")
-	model := flag.String("model", "", "Model to request from Saturn server")
-	maxTokens := flag.Int("max-tokens", 8192, "Maximum tokens for responses")
-	timeout := flag.Duration("timeout", 5*time.Second, "Saturn discovery timeout")
-	cwd := flag.String("cwd", "", "Working directory (defaults to current directory)")
-	flag.Parse()
+	args := theme.StripNoColorFlag(os.Args[1:])
 
-	if *version {
-		fmt.Printf("BRUTUS v%s\n", Version)
-		os.Exit(0)
+	// Agent broadcasts register mDNS services that would otherwise outlive
+	// the process. os.Exit skips deferred calls, so commands that fail after
+	// acquiring resources call exit instead.
+	onShutdown(tools.ShutdownAllBroadcasts)
+	defer runCleanups()
+
+	// No subcommand (or only flags) means an interactive chat, so plain
+	// `brutus` and `brutus -model x` keep working.
+	if len(args) == 0 || args[0][0] == '-' {
+		runChat(args)
+		return
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "chat":
+		runChat(args)
+	case "run":
+		runPrompt(args)
+	case "tools":
+		runTools(args)
+	case "serve":
+		runServe(args)
+	case "discover":
+		runDiscover(args)
+	case "beacon":
+		runBeacon(args)
+	case "models":
+		runModels(args)
+	case "replay":
+		runReplay(args)
+	case "audit":
+		runAudit(args)
+	case "doctor":
+		runDoctor(args)
+	case "test":
+		testcli.Main("brutus test", args)
+	case "completion":
+		runCompletion(args)
+	case "version":
+		fmt.Println(versionString())
+	case "help":
+		printUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println(`brutus - Saturn-powered coding agent
+
+Usage:
+  brutus [command] [flags]
+
+Commands:
+  chat                 Interactive session (default)
+  run <prompt>         Run a single prompt headlessly and print the answer
+  tools                List tools
+  tools <name> <json>  Execute a tool with JSON input
+  serve                Run a headless HTTP server that accepts prompts
+  discover             List Saturn services on the network
+  beacon               Announce a local OpenAI-compatible server as a Saturn service
+  models               List models; 'models set <id>' pins one for this project
+  replay <file>        Re-render a saved session; -from-turn N -fork to continue it
+  audit verify [file]  Check the tool call audit log for tampering
+  doctor               Diagnose environment and network problems
+  test <command>       Testing SDK commands (scenarios, harness, ...)
+  completion <shell>   Print a bash, zsh or fish completion script
+  version              Print version and build info
+  help                 Show this help
+
+Every command accepts -no-color; color is also off when NO_COLOR is set
+or output is not a terminal.
+
+Run 'brutus <command> -h' for command flags.`)
+}
+
+// agentFlags are the options shared by every command that talks to Saturn.
+type agentFlags struct {
+	fs        *flag.FlagSet
+	verbose   *bool
+	model     *string
+	maxTokens *int
+	service   *string
+	sticky    *bool
+	discovery discoveryFlags
+	timeout   *time.Duration
+	cwd       *string
+	logFile   *string
+	logFormat *string
+	logSize   *int
+	maxMsgs   *int
+	pprof     *string
+	injection *bool
+	toolCache *bool
+	toolCalls *string
+	thinking  *int
+	artifacts *int
+	auditLog  *string
+	turnLimit *time.Duration
+	budget    *int
+	baseURL   *string
+	endpoint  *string
+	embedding *string
+	apiKey    *string
+	disabled  *string
+	toolLimit *time.Duration
+	resultMax *int
+	summarize *int
+	temp      *float64
+	topP      *float64
+	seed      *int
+
+	// approve is set by chat to ask before tools that change things, and
+	// plan to hold them back until the user approves a plan.
+	approve bool
+	plan    bool
+
+	// verify comes from the config only; commands don't fit in a flag.
+	verify []string
+
+	// env and toolEnv, also from the config only, are the variables the
+	// agent's commands run with.
+	env     map[string]string
+	toolEnv map[string]map[string]string
+
+	// summaryTokens caps summaries of tool results; config only.
+	summaryTokens int
+
+	// scorer chooses between discovered services; nil is by priority.
+	scorer provider.Scorer
+
+	// retry is how failed model requests are retried; config only.
+	retry provider.RetryPolicy
+
+	// options are the sampling parameters, from the config and the
+	// flags that set them.
+	options provider.ChatOptions
+
+	// picker lets setup ask which service and model to use (interactive
+	// chat only); repick asks even when a choice is remembered.
+	picker bool
+	repick bool
+
+	// logger is set by setup once the log file is open.
+	logger *slog.Logger
+}
+
+func registerAgentFlags(fs *flag.FlagSet) *agentFlags {
+	return &agentFlags{
+		fs:        fs,
+		verbose:   fs.Bool("verbose", false, "Enable verbose logging"),
+		model:     fs.String("model", "", "Model to request from Saturn server"),
+		maxTokens: fs.Int("max-tokens", 8192, "Maximum tokens for responses"),
+		service:   fs.String("service", "", "Use only the Saturn service with this name"),
+		sticky:    fs.Bool("sticky", false, "Spread conversations over every Saturn service found, keeping each on one service"),
+		discovery: discoveryFlags{
+			minPriority:   fs.Int("min-priority", 0, "Only use services with this priority or better (lower)"),
+			requiredModel: fs.String("require-model", "", "Only use services that offer this model"),
+			requireGPU:    fs.Bool("require-gpu", false, "Only use services that report a GPU"),
+			minVRAM:       fs.Int("min-vram", 0, "Only use services with at least this many GB of VRAM"),
+			localOnly:     fs.Bool("local-only", false, "Skip services that proxy a remote API"),
+		},
+		timeout:   fs.Duration("timeout", 5*time.Second, "Saturn discovery timeout"),
+		cwd:       fs.String("cwd", "", "Working directory (defaults to current directory)"),
+		logFile:   fs.String("log-file", "", "Write structured diagnostics to this file instead of the terminal"),
+		logFormat: fs.String("log-format", "text", "Log file format: text or json"),
+		logSize:   fs.Int("log-max-size", 10, "Rotate the log file after this many megabytes"),
+		maxMsgs:   fs.Int("max-messages", session.DefaultWindow, "Messages kept in memory; older ones spill to ~/.brutus/sessions"),
+		pprof:     fs.String("pprof", "", "Serve net/http/pprof on this localhost port or address"),
+		injection: fs.Bool("injection-check", true, "Warn when tool output contains text that looks like instructions to the model"),
+		toolCache: fs.Bool("tool-cache", true, "Reuse read_file and code_search results while the files they read are unchanged"),
+		toolCalls: fs.String("tool-calling", provider.ToolCallingAuto, "How the model calls tools: auto, native, or emulated (described in the prompt)"),
+		thinking:  fs.Int("thinking-budget", 0, "Tokens models that support extended thinking may spend on it; 0 is off"),
+		artifacts: fs.Int("artifact-threshold", tools.DefaultArtifactThreshold, "Archive tool results over this many bytes for read_artifact; 0 is off"),
+		auditLog:  fs.String("audit-log", "", "Record every tool call in this hash-chained audit log"),
+		turnLimit: fs.Duration("turn-timeout", 0, "Stop a turn that runs longer than this, keeping its partial results; 0 is no limit"),
+		budget:    fs.Int("context-budget", 0, "Summarize older turns once the conversation passes this many estimated tokens; 0 is 3/4 of the model's context, -1 is off"),
+		baseURL:   fs.String("base-url", "", "Use this OpenAI-compatible endpoint instead of discovering Saturn services"),
+		endpoint:  fs.String("endpoint", "", "Use the Saturn server at this address, e.g. http://host:8080, without discovery (default: $SATURN_ENDPOINT)"),
+		embedding: fs.String("embedding-model", "", "Model semantic_search indexes code with (default: one the service advertises with \"embed\" in its name)"),
+		apiKey:    fs.String("api-key", "", "API key for -base-url or -endpoint (default: $OPENAI_API_KEY or $SATURN_API_KEY)"),
+		disabled:  fs.String("disable-tools", "", "Comma-separated tools to leave out, in addition to those the config disables"),
+		toolLimit: fs.Duration("tool-timeout", 0, "Stop a tool call that runs longer than this, unless the config sets the tool's own timeout; 0 is no limit"),
+		resultMax: fs.Int("result-limit", agent.DefaultResultLimit, "Cut tool results over this many bytes to their first and last lines; 0 is off"),
+		summarize: fs.Int("summarize-results", 0, "Have the model condense tool results over this many bytes; 0 is off"),
+		temp:      fs.Float64("temperature", 0, "Sampling temperature (default: the server's)"),
+		topP:      fs.Float64("top-p", 0, "Nucleus sampling probability mass (default: the server's)"),
+		seed:      fs.Int("seed", 0, "Sampling seed, for repeatable responses where the server supports it"),
 	}
+}
 
-	setupLogging(*verbose)
+// setup applies logging and working-directory flags and connects to Saturn.
+// It exits the process on failure since every caller is a CLI command.
+func (f *agentFlags) setup() provider.Provider {
+	setupLogging(*f.verbose)
 
-	workDir := getWorkingDir(*cwd)
+	logger, logCloser, err := logging.Setup(logging.Options{
+		File:      *f.logFile,
+		Format:    *f.logFormat,
+		Verbose:   *f.verbose,
+		MaxSizeMB: *f.logSize,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot open log file: %v\n", err)
+		os.Exit(1)
+	}
+	f.logger = logger
+	onShutdown(func() { logCloser.Close() })
+
+	stopTracing, err := telemetry.Setup(context.Background(), Version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot set up tracing: %v\n", err)
+		os.Exit(1)
+	}
+	onShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopTracing(ctx)
+	})
+
+	// Started before discovery so hangs there can be profiled.
+	if *f.pprof != "" {
+		url, err := startPprof(*f.pprof)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot start pprof: %v\n", err)
+			os.Exit(1)
+		}
+		log.Printf("pprof listening on %s", url)
+	}
+
+	workDir := getWorkingDir(*f.cwd)
 	if workDir != "." {
 		if err := os.Chdir(workDir); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: cannot change to directory %s: %v\n", workDir, err)
@@ -45,66 +276,410 @@ func main() {
 		}
 	}
 
-	// Initialize tools
-	registry := tools.NewRegistry()
-	registry.Register(tools.ReadFileTool)
-	registry.Register(tools.ListFilesTool)
-	registry.Register(tools.BashTool)
-	registry.Register(tools.EditFileTool)
-	registry.Register(tools.CodeSearchTool)
+	f.applyConfig()
 
-	if *verbose {
-		log.Printf("Registered %d tools: %v", len(registry.All()), registry.Names())
+	if *f.baseURL != "" {
+		return f.openAI()
 	}
 
-	// Discover Saturn services - this is the ONLY way to get AI
-	log.Println("Discovering Saturn services on network...")
-
-	prov, err := provider.NewSaturn(context.Background(), provider.SaturnConfig{
-		DiscoveryTimeout: *timeout,
-		Model:            *model,
-		MaxTokens:        *maxTokens,
+	if *f.endpoint == "" {
+		*f.endpoint = os.Getenv(provider.SaturnEndpointEnv)
+	}
+	discovered := false
+	if *f.endpoint == "" {
+		log.Println("Discovering Saturn services on network...")
+		discovered = f.picker && f.pickService()
+	}
+	// A model picked for Saturn is usually not a Claude model.
+	claude := *f.model
+	if !strings.HasPrefix(claude, "claude") {
+		claude = ""
+	}
+	prov, err := provider.NewAuto(context.Background(), provider.AutoConfig{
+		Saturn: provider.SaturnConfig{
+			DiscoveryTimeout: *f.timeout,
+			Model:            *f.model,
+			MaxTokens:        *f.maxTokens,
+			Service:          *f.service,
+			Sticky:           *f.sticky,
+			Filter:           f.discovery.filter(),
+			ToolCalling:      *f.toolCalls,
+			ThinkingBudget:   *f.thinking,
+			Cached:           discovered,
+			Scorer:           f.scorer,
+			Retry:            f.retry,
+			Options:          f.options,
+			Endpoint:         *f.endpoint,
+			APIKey:           *f.apiKey,
+			EmbeddingModel:   *f.embedding,
+		},
+		Anthropic: provider.AnthropicConfig{Model: claude, MaxTokens: *f.maxTokens, Options: f.options},
+		OpenAI: provider.OpenAIConfig{
+			MaxTokens:      *f.maxTokens,
+			ToolCalling:    *f.toolCalls,
+			Retry:          f.retry,
+			Options:        f.options,
+			EmbeddingModel: *f.embedding,
+		},
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "BRUTUS requires a Saturn server on your network.")
-		fmt.Fprintln(os.Stderr, "Start a Saturn beacon or server, then try again.")
-		fmt.Fprintln(os.Stderr, "See: https://github.com/jperrello/Saturn")
+		if errors.Is(err, provider.ErrNoServices) {
+			fmt.Fprintln(os.Stderr, "")
+			fmt.Fprintln(os.Stderr, "BRUTUS requires a Saturn server on your network.")
+			fmt.Fprintln(os.Stderr, "Start a Saturn beacon or server (or 'brutus beacon' in front of a local one), then try again.")
+			fmt.Fprintln(os.Stderr, "Or set ANTHROPIC_API_KEY, OPENAI_API_KEY or OPENAI_BASE_URL to use one of those APIs instead.")
+			fmt.Fprintln(os.Stderr, "See: https://github.com/jperrello/Saturn")
+		}
 		os.Exit(1)
 	}
 
+	var chain []string
+	for _, p := range prov.Providers() {
+		chain = append(chain, p.Name())
+	}
+	if len(chain) > 1 {
+		log.Printf("Connected to: %s (falling back to %s)", chain[0], strings.Join(chain[1:], ", "))
+	} else {
+		log.Printf("Connected to: %s", chain[0])
+	}
+	prov.OnSwitch(func(from, to provider.Provider, err error) {
+		log.Printf("%s failed (%v); continuing with %s", from.Name(), err, to.Name())
+	})
+	return prov
+}
+
+// openAI connects to the endpoint given by -base-url, skipping discovery.
+func (f *agentFlags) openAI() provider.Provider {
+	prov, err := provider.NewOpenAI(provider.OpenAIConfig{
+		BaseURL:        *f.baseURL,
+		APIKey:         *f.apiKey,
+		Model:          *f.model,
+		MaxTokens:      *f.maxTokens,
+		ToolCalling:    *f.toolCalls,
+		ThinkingBudget: *f.thinking,
+		Retry:          f.retry,
+		Options:        f.options,
+		EmbeddingModel: *f.embedding,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	log.Printf("Connected to: %s", prov.Name())
+	return prov
+}
+
+// applyConfig fills in options from the config files for any flag the user
+// did not set explicitly. It runs after -cwd so the project config is the
+// one in the target directory.
+func (f *agentFlags) applyConfig() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config: %v\n", err)
+		tools.ConfigureSandbox("", nil)
+		f.applyToolPolicies(&config.Config{})
+		f.retry = requestRetry(&config.Config{})
+		f.options = sampling(&config.Config{}).Merge(f.sampleFlags())
+		return
+	}
+
+	set := map[string]bool{}
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	if !set["model"] && cfg.Model != "" {
+		*f.model = cfg.Model
+	}
+	if !set["max-tokens"] && cfg.MaxTokens != 0 {
+		*f.maxTokens = cfg.MaxTokens
+	}
+	if !set["service"] && cfg.Service != "" {
+		*f.service = cfg.Service
+	}
+	if !set["sticky"] && cfg.Sticky != nil {
+		*f.sticky = *cfg.Sticky
+	}
+	if !set["base-url"] && cfg.BaseURL != "" {
+		*f.baseURL = cfg.BaseURL
+	}
+	if !set["endpoint"] && cfg.Endpoint != "" {
+		*f.endpoint = cfg.Endpoint
+	}
+	if !set["embedding-model"] && cfg.EmbeddingModel != "" {
+		*f.embedding = cfg.EmbeddingModel
+	}
+	if !set["api-key"] && cfg.APIKey != "" {
+		*f.apiKey = cfg.APIKey
+	}
+	f.discovery.applyConfig(cfg.Discovery, set)
+	if !set["injection-check"] && cfg.InjectionCheck != nil {
+		*f.injection = *cfg.InjectionCheck
+	}
+	if !set["tool-cache"] && cfg.ToolCache != nil {
+		*f.toolCache = *cfg.ToolCache
+	}
+	if !set["tool-calling"] && cfg.ToolCalling != "" {
+		*f.toolCalls = cfg.ToolCalling
+	}
+	if !set["thinking-budget"] && cfg.ThinkingBudget != 0 {
+		*f.thinking = cfg.ThinkingBudget
+	}
+	if !set["artifact-threshold"] && cfg.ArtifactThreshold != 0 {
+		*f.artifacts = cfg.ArtifactThreshold
+	}
+	if !set["audit-log"] && cfg.AuditLog != "" {
+		*f.auditLog = cfg.AuditLog
+	}
+	if !set["turn-timeout"] && cfg.TurnTimeout != "" {
+		if d, err := time.ParseDuration(cfg.TurnTimeout); err == nil {
+			*f.turnLimit = d
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring turn_timeout: %v\n", err)
+		}
+	}
+	if !set["context-budget"] && cfg.ContextBudget != 0 {
+		*f.budget = cfg.ContextBudget
+	}
+	if !set["result-limit"] && cfg.ToolResults.MaxBytes != 0 {
+		*f.resultMax = cfg.ToolResults.MaxBytes
+	}
+	if !set["summarize-results"] && cfg.ToolResults.SummarizeBytes != 0 {
+		*f.summarize = cfg.ToolResults.SummarizeBytes
+	}
+	f.summaryTokens = cfg.ToolResults.SummaryTokens
+	f.verify = cfg.VerificationCommands
+	f.env, f.toolEnv = cfg.Env, cfg.ToolEnv
+	f.scorer = serviceScorer(cfg.ServiceWeights)
+	f.retry = requestRetry(cfg)
+	f.options = sampling(cfg).Merge(f.sampleFlags())
+	applyRateLimits(cfg)
+	tools.ConfigureDiagnostics(cfg.Diagnostics)
+	applyToolRetries(cfg)
+	applyForges(cfg)
+	tools.ConfigureSandbox(cfg.Sandbox.Root, cfg.Sandbox.Allow)
+	f.applyToolPolicies(cfg)
+}
+
+// applyToolPolicies configures tools from cfg with -disable-tools and
+// -tool-timeout on top.
+func (f *agentFlags) applyToolPolicies(cfg *config.Config) {
+	policies := toolPolicies(cfg)
+	for _, name := range strings.Split(*f.disabled, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			p := policies[name]
+			p.Disabled = true
+			policies[name] = p
+		}
+	}
+	if *f.toolLimit > 0 {
+		p := policies[tools.AllTools]
+		p.Timeout = *f.toolLimit
+		policies[tools.AllTools] = p
+	}
+	tools.ConfigureTools(policies)
+}
+
+// resultLimits returns the limits on tool results the flags set.
+func (f *agentFlags) resultLimits() agent.ResultLimits {
+	return agent.ResultLimits{
+		MaxBytes:       *f.resultMax,
+		SummarizeBytes: *f.summarize,
+		SummaryTokens:  f.summaryTokens,
+	}
+}
+
+// openAudit opens the audit log, or returns nil if there is none. It exits
+// on failure: a run that was meant to be audited must not go unrecorded.
+func (f *agentFlags) openAudit() *audit.Log {
+	if *f.auditLog == "" {
+		return nil
+	}
+	log, err := audit.Open(*f.auditLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot open audit log: %v\n", err)
+		os.Exit(1)
+	}
+	return log
+}
 
-	// Load system prompt
-	systemPrompt := loadSystemPrompt()
+// applyRateLimits hands the configured request limits to the provider
+// package, which shares them between all agents in the process.
+func applyRateLimits(cfg *config.Config) {
+	perService := make(map[string]provider.RateLimit, len(cfg.RateLimits))
+	for name, limit := range cfg.RateLimits {
+		perService[name] = provider.RateLimit(limit)
+	}
+	provider.ConfigureRateLimits(provider.RateLimit(cfg.RateLimit), perService)
+}
 
-	// Create input reader
-	scanner := bufio.NewScanner(os.Stdin)
-	getUserInput := func() (string, bool) {
-		if !scanner.Scan() {
-			return "", false
+// requestRetry converts the configured retry policy for model requests,
+// or returns the default if there is none.
+func requestRetry(cfg *config.Config) provider.RetryPolicy {
+	r := cfg.Retry
+	if r == nil {
+		return provider.DefaultRetryPolicy
+	}
+	return provider.RetryPolicy{
+		MaxAttempts:      r.MaxAttempts,
+		BaseDelay:        time.Duration(r.BaseDelayMS) * time.Millisecond,
+		MaxDelay:         time.Duration(r.MaxDelayMS) * time.Millisecond,
+		StatusCodes:      r.StatusCodes,
+		IgnoreRetryAfter: r.IgnoreRetryAfter,
+	}
+}
+
+// sampling returns the configured sampling parameters.
+func sampling(cfg *config.Config) provider.ChatOptions {
+	s := cfg.Sampling
+	if s == nil {
+		return provider.ChatOptions{}
+	}
+	return provider.ChatOptions{
+		Temperature:      s.Temperature,
+		TopP:             s.TopP,
+		Stop:             s.Stop,
+		Seed:             s.Seed,
+		FrequencyPenalty: s.FrequencyPenalty,
+		PresencePenalty:  s.PresencePenalty,
+	}
+}
+
+// sampleFlags returns the sampling parameters given on the command line.
+func (f *agentFlags) sampleFlags() provider.ChatOptions {
+	var opts provider.ChatOptions
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "temperature":
+			opts.Temperature = f.temp
+		case "top-p":
+			opts.TopP = f.topP
+		case "seed":
+			opts.Seed = f.seed
 		}
-		return scanner.Text(), true
+	})
+	return opts
+}
+
+// applyToolRetries hands configured retry policies to the tools package,
+// where they override each tool's default.
+func applyToolRetries(cfg *config.Config) {
+	policies := make(map[string]tools.RetryPolicy, len(cfg.ToolRetries))
+	for name, retry := range cfg.ToolRetries {
+		policy := tools.RetryPolicy{
+			MaxRetries: retry.MaxRetries,
+			Delay:      time.Duration(retry.DelayMS) * time.Millisecond,
+		}
+		for _, code := range retry.On {
+			policy.On = append(policy.On, tools.ErrorCode(code))
+		}
+		policies[name] = policy
 	}
+	tools.ConfigureRetries(policies)
+}
 
-	// Get absolute path of working directory for display
-	absWorkDir, _ := os.Getwd()
+// toolPolicies converts the configured tool policies for the tools
+// package, which applies them as tools are registered.
+func toolPolicies(cfg *config.Config) map[string]tools.ToolPolicy {
+	policies := make(map[string]tools.ToolPolicy, len(cfg.Tools))
+	for name, tc := range cfg.Tools {
+		policy := tools.ToolPolicy{
+			Disabled:    tc.Disabled,
+			MaxOutput:   tc.MaxOutput,
+			SandboxRoot: tc.SandboxRoot,
+		}
+		if tc.Timeout != "" {
+			if d, err := time.ParseDuration(tc.Timeout); err == nil {
+				policy.Timeout = d
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring the timeout of tool %s: %v\n", name, err)
+			}
+		}
+		policies[name] = policy
+	}
+	return policies
+}
 
-	// Create and run agent
-	a := agent.New(agent.Config{
+// applyForges hands the configured GitHub and GitLab tokens to the tools
+// package.
+func applyForges(cfg *config.Config) {
+	forges := make(map[string]tools.Forge, len(cfg.Forges))
+	for host, forge := range cfg.Forges {
+		forges[host] = tools.Forge{Type: forge.Type, Token: forge.Token, APIURL: forge.APIURL}
+	}
+	tools.ConfigureForges(forges)
+}
+
+// cliTools returns the tools available to CLI agents.
+func cliTools() *tools.Registry {
+	learned := loadLearned()
+	registry := tools.NewRegistry()
+	registry.Register(tools.ReadFileTool)
+	registry.Register(tools.ReadImageTool)
+	registry.Register(tools.ListFilesTool)
+	registry.Register(tools.NewBashTool(learned))
+	registry.Register(tools.NewRunTestsTool(learned))
+	registry.Register(tools.NewFormatTool(learned))
+	registry.Register(tools.EditFileTool)
+	registry.Register(tools.MultiEditTool)
+	registry.Register(tools.ApplyPatchTool)
+	registry.Register(tools.GitTool)
+	registry.Register(tools.HTTPRequestTool)
+	registry.Register(tools.CodeSearchTool)
+	registry.Register(tools.FindSymbolTool)
+	registry.Register(tools.ImportsOfTool)
+	registry.Register(tools.DependentsOfTool)
+	registry.Register(tools.IssueFetchTool)
+	registry.Register(tools.NewSearchHistoryTool(session.SearchText))
+	registry.Register(tools.PRCreateTool)
+	registry.Register(tools.PRCommentTool)
+	registry.Register(tools.NewPythonExecTool())
+	registry.Register(tools.NewMemoryTool(loadMemories()))
+	return registry
+}
+
+// registerSemanticSearch adds semantic_search to registry if prov can
+// embed text, over an index of the working directory.
+func registerSemanticSearch(registry *tools.Registry, prov provider.Provider) {
+	embedder, ok := provider.EmbedderOf(prov)
+	if !ok {
+		return
+	}
+	workDir, _ := os.Getwd()
+	registry.Register(index.NewSearchTool(index.Open(workDir, index.Path(workDir), embedder)))
+}
+
+// registerSubagents adds spawn_subagent to registry. Subagents use prov,
+// the project's instructions and the tools in registry, and print their
+// progress to out.
+func registerSubagents(registry *tools.Registry, prov provider.Provider, f *agentFlags, out io.Writer) {
+	workDir, _ := os.Getwd()
+	registry.Register(agent.NewSubagentTool(agent.SubagentConfig{
 		Provider:     prov,
-		GetUserInput: getUserInput,
 		Tools:        registry,
-		SystemPrompt: systemPrompt,
-		Verbose:      *verbose,
-		WorkingDir:   absWorkDir,
-	})
+		SystemPrompt: loadSystemPrompt(),
+		WorkingDir:   workDir,
+		Logger:       f.logger,
+		Output:       out,
+	}))
+}
 
-	if err := a.Run(context.Background()); err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-		os.Exit(1)
+// restrictTools removes every tool not in allowed from registry. Unknown
+// names are an error so a typo doesn't silently leave a run with no tools.
+func restrictTools(registry *tools.Registry, allowed []string) error {
+	keep := map[string]bool{}
+	for _, name := range allowed {
+		name = strings.TrimSpace(name)
+		if _, ok := registry.Get(name); !ok {
+			return fmt.Errorf("unknown tool %q (available: %s)", name, strings.Join(registry.Names(), ", "))
+		}
+		keep[name] = true
+	}
+	for _, name := range registry.Names() {
+		if !keep[name] {
+			registry.Unregister(name)
+		}
 	}
+	return nil
 }
 
 func setupLogging(verbose bool) {
@@ -131,11 +706,39 @@ func getWorkingDir(cwd string) string {
 }
 
 func loadSystemPrompt() string {
+	prompt := embeddedPrompt
 	promptFiles := []string{"BRUTUS.md", "CLAUDE.md", "AGENTS.md"}
 	for _, filename := range promptFiles {
 		if content, err := os.ReadFile(filename); err == nil {
-			return string(content)
+			prompt = string(content)
+			break
 		}
 	}
-	return embeddedPrompt
+	if learned := loadLearned().Prompt(); learned != "" {
+		prompt += "\n\n" + learned
+	}
+	if memories := loadMemories().Prompt(); memories != "" {
+		prompt += "\n\n" + memories
+	}
+	return prompt
+}
+
+// loadLearned reads the build and test commands learned in this project.
+// A damaged file is reported and then starts over.
+func loadLearned() *tools.LearnedCommands {
+	learned, err := tools.LoadLearned(tools.LearnedPath)
+	if err != nil {
+		log.Printf("Warning: ignoring learned commands: %v", err)
+	}
+	return learned
+}
+
+// loadMemories reads the notes the agent has kept about this project. A
+// damaged file is reported and then starts over.
+func loadMemories() *tools.Memories {
+	memories, err := tools.LoadMemories(tools.MemoryPath("."))
+	if err != nil {
+		log.Printf("Warning: ignoring saved memories: %v", err)
+	}
+	return memories
 }
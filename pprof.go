@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// startPprof serves the net/http/pprof handlers on addr, which is a port or
+// host:port. Only loopback hosts are accepted since the profiles expose
+// stack traces and command lines.
+func startPprof(addr string) (string, error) {
+	if !strings.Contains(addr, ":") {
+		addr = "localhost:" + addr
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid -pprof address %q: %w", addr, err)
+	}
+	if host == "" {
+		addr = "localhost" + addr
+	} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", fmt.Errorf("-pprof must listen on localhost, not %s", host)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("pprof server stopped: %v", err)
+		}
+	}()
+	onShutdown(func() { srv.Close() })
+	return "http://" + ln.Addr().String() + "/debug/pprof/", nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"brutus/config"
+)
+
+// PromptInfo is a system prompt an agent can be given. The default prompt
+// has empty Text, which stands for the project's BRUTUS.md.
+type PromptInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Source      string `json:"source"` // "builtin", "user" or "project"
+	Text        string `json:"text"`
+}
+
+// builtinPrompts are the roles every agent can take without any prompt
+// files.
+var builtinPrompts = []PromptInfo{
+	{
+		Name:        "default",
+		Description: "The project's BRUTUS.md",
+	},
+	{
+		Name:        "reviewer",
+		Description: "Reviews changes without editing them",
+		Text:        `You are BRUTUS, acting as a code reviewer. Read the code and changes you are pointed at and report problems: bugs, missing error handling, races, unclear names, missing tests. Quote file and line for each finding and say how serious it is. Do not edit files; suggest changes instead.`,
+	},
+	{
+		Name:        "tester",
+		Description: "Writes and runs tests",
+		Text:        `You are BRUTUS, acting as a test engineer. Find the behavior that is untested, write focused tests in the project's existing style and layout, and run them. When a test fails, decide whether the test or the code is wrong before changing either, and say which.`,
+	},
+	{
+		Name:        "planner",
+		Description: "Breaks work into steps before any code",
+		Text:        `You are BRUTUS, acting as a planner. Read enough of the code to understand the task, then write a short numbered plan: the files to change, what changes in each, and how to check the result. Point out risks and open questions. Do not edit files.`,
+	},
+	{
+		Name:        "docs",
+		Description: "Writes and updates documentation",
+		Text:        `You are BRUTUS, acting as a technical writer. Keep the README, doc comments and guides in step with the code. Read the code before describing it, match the tone of the existing docs, and prefer short examples to long explanations.`,
+	},
+}
+
+type promptDir struct {
+	source string
+	path   string
+}
+
+// promptDirs returns the directories prompt files are read from, user-wide
+// then project, so a project prompt replaces a user one of the same name.
+func promptDirs() []promptDir {
+	var dirs []promptDir
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, promptDir{"user", filepath.Join(home, config.Dir, "prompts")})
+	}
+	return append(dirs, promptDir{"project", filepath.Join(config.Dir, "prompts")})
+}
+
+// loadPromptLibrary returns the built-in prompts followed by the .md files
+// in ~/.brutus/prompts and .brutus/prompts, named after the file. A file's
+// first line, less any leading #, describes it; a file named after a
+// built-in prompt replaces it.
+func loadPromptLibrary() ([]PromptInfo, error) {
+	files := map[string]PromptInfo{}
+	for _, dir := range promptDirs() {
+		entries, err := os.ReadDir(dir.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompts: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir.path, entry.Name()))
+			if err != nil {
+				continue
+			}
+			text := strings.TrimSpace(string(data))
+			first, _, _ := strings.Cut(text, "\n")
+			name := strings.TrimSuffix(entry.Name(), ".md")
+			files[name] = PromptInfo{
+				Name:        name,
+				Description: strings.TrimSpace(strings.TrimLeft(first, "#")),
+				Source:      dir.source,
+				Text:        text,
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	library := append([]PromptInfo{}, builtinPrompts...)
+	for i, p := range library {
+		if file, ok := files[p.Name]; ok {
+			library[i] = file
+			delete(files, p.Name)
+		}
+	}
+	for _, name := range names {
+		if file, ok := files[name]; ok {
+			library = append(library, file)
+		}
+	}
+	return library, nil
+}
+
+// ListPrompts returns the prompt library for the agent prompt picker.
+func (a *App) ListPrompts() ([]PromptInfo, error) {
+	return loadPromptLibrary()
+}
+
+// SetSystemPrompt gives an agent its own system prompt, usually one from
+// the prompt library, in place of the project's. Empty text restores the
+// project's. The choice is kept with the agent's session and carried into
+// forks.
+func (a *App) SetSystemPrompt(agentID, text string) error {
+	a.sessionsMu.Lock()
+	session, ok := a.sessions[agentID]
+	guiAgent := a.guiAgents[agentID]
+	if !ok || guiAgent == nil {
+		a.sessionsMu.Unlock()
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+	if session.Status == "running" || session.Status == "queued" {
+		a.sessionsMu.Unlock()
+		return fmt.Errorf("agent %s is busy; change its prompt between turns", agentID)
+	}
+	session.SystemPrompt = text
+	a.sessionsMu.Unlock()
+
+	guiAgent.SetSystemPrompt(text)
+	runtime.EventsEmit(a.ctx, "agent:prompt", agentID)
+	return nil
+}
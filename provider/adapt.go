@@ -0,0 +1,302 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"brutus/tools"
+)
+
+// Adapt wraps p so requests stay within its capabilities, checked on every
+// request since they change with the model:
+//
+//   - without streaming, ChatStream makes a plain request and delivers the
+//     response as a single delta;
+//   - without tool calling, the tools are described in the system prompt
+//     and calls are parsed out of the reply's <tool_call> blocks, or fenced
+//     JSON naming one of the tools;
+//   - with tool calling, a reply that has no calls but writes them as
+//     <tool_call> text, as Hermes-style models do when the server doesn't
+//     parse them, has them parsed out;
+//   - without vision, images are replaced by a note that they were left
+//     out.
+//
+// Everything else passes through to p.
+func Adapt(p Provider) Provider {
+	if _, ok := p.(*adapted); ok {
+		return p
+	}
+	return &adapted{Provider: p}
+}
+
+// Unwrap returns the provider Adapt wrapped, or p itself, and for a
+// Fallback, its active provider.
+func Unwrap(p Provider) Provider {
+	if a, ok := p.(*adapted); ok {
+		p = a.Provider
+	}
+	if f, ok := p.(*Fallback); ok {
+		p = f.Active()
+	}
+	return p
+}
+
+type adapted struct {
+	Provider
+}
+
+func (a *adapted) Chat(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (Message, error) {
+	if !a.Capabilities().Vision {
+		messages = withoutImages(messages)
+	}
+	if len(toolDefs) == 0 {
+		return a.Provider.Chat(ctx, systemPrompt, messages, toolDefs)
+	}
+	if a.Capabilities().Tools {
+		reply, err := a.Provider.Chat(ctx, systemPrompt, messages, toolDefs)
+		if err != nil || len(reply.ToolCalls) > 0 {
+			return reply, err
+		}
+		return parseToolCalls(reply, nil), nil
+	}
+	reply, err := a.Provider.Chat(ctx, systemPrompt+"\n\n"+toolPrompt(toolDefs), emulateToolMessages(messages), nil)
+	if err != nil {
+		return Message{}, err
+	}
+	return parseToolCalls(reply, toolDefs), nil
+}
+
+func (a *adapted) ChatStream(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (<-chan StreamDelta, error) {
+	caps := a.Capabilities()
+	if !caps.Vision {
+		messages = withoutImages(messages)
+	}
+	if caps.Streaming && len(toolDefs) == 0 {
+		return a.Provider.ChatStream(ctx, systemPrompt, messages, toolDefs)
+	}
+	if caps.Streaming && caps.Tools {
+		stream, err := a.Provider.ChatStream(ctx, systemPrompt, messages, toolDefs)
+		if err != nil {
+			return nil, err
+		}
+		return relayTextToolCalls(stream), nil
+	}
+
+	ch := make(chan StreamDelta, 2)
+	go func() {
+		defer close(ch)
+		msg, err := a.Chat(ctx, systemPrompt, messages, toolDefs)
+		if err != nil {
+			ch <- StreamDelta{Error: err, Done: true}
+			return
+		}
+		if msg.Reasoning != "" {
+			ch <- StreamDelta{Reasoning: msg.Reasoning}
+		}
+		if msg.Content != "" {
+			ch <- StreamDelta{Content: msg.Content}
+		}
+		ch <- StreamDelta{ToolCalls: msg.ToolCalls, Usage: msg.Usage, Done: true}
+	}()
+	return ch, nil
+}
+
+// imageOmitted stands in for an image sent to a model that can't see it.
+const imageOmitted = "[An image was left out: this model can't take images.]"
+
+// withoutImages returns messages with their images replaced by notes, or
+// messages itself if there are none.
+func withoutImages(messages []Message) []Message {
+	var result []Message
+	for i, msg := range messages {
+		images := len(msg.Images) > 0
+		for _, tr := range msg.ToolResults {
+			images = images || len(tr.Images) > 0
+		}
+		if !images {
+			if result != nil {
+				result = append(result, msg)
+			}
+			continue
+		}
+		if result == nil {
+			result = append(make([]Message, 0, len(messages)), messages[:i]...)
+		}
+		if len(msg.Images) > 0 {
+			msg.Content = strings.TrimSpace(msg.Content + "\n" + strings.Repeat(imageOmitted+"\n", len(msg.Images)))
+			msg.Images = nil
+		}
+		msg.ToolResults = slices.Clone(msg.ToolResults)
+		for j, tr := range msg.ToolResults {
+			if len(tr.Images) > 0 {
+				tr.Content = strings.TrimSpace(tr.Content + "\n" + strings.Repeat(imageOmitted+"\n", len(tr.Images)))
+				tr.Images = nil
+				msg.ToolResults[j] = tr
+			}
+		}
+		result = append(result, msg)
+	}
+	if result == nil {
+		return messages
+	}
+	return result
+}
+
+// relayTextToolCalls passes a stream on, and if it ends without tool calls
+// but its text holds <tool_call> blocks, delivers those as the calls.
+func relayTextToolCalls(stream <-chan StreamDelta) <-chan StreamDelta {
+	ch := make(chan StreamDelta, cap(stream))
+	go func() {
+		defer close(ch)
+		var content strings.Builder
+		for delta := range stream {
+			content.WriteString(delta.Content)
+			if delta.Done && delta.Error == nil && len(delta.ToolCalls) == 0 {
+				delta.ToolCalls = parseToolCalls(Message{Content: content.String()}, nil).ToolCalls
+			}
+			ch <- delta
+		}
+	}()
+	return ch
+}
+
+// toolPrompt describes the tools to a model that can't be given them
+// natively, and how to call them.
+func toolPrompt(toolDefs []tools.Tool) string {
+	var b strings.Builder
+	b.WriteString("# Tools\n\nYou can call these tools. Each takes a JSON object matching its schema.\n\n")
+	for _, t := range toolDefs {
+		fmt.Fprintf(&b, "## %s\n%s\nSchema: %s\n\n", t.Name, t.Description, t.Parameters())
+	}
+	b.WriteString("To call tools, end your reply with one block per call, exactly like this:\n" +
+		`<tool_call>{"name": "tool_name", "arguments": {"param": "value"}}</tool_call>` + "\n" +
+		"Write nothing after the blocks. Results come back in <tool_result> blocks in the next message; " +
+		"when you need no more tools, reply normally without any <tool_call> block.")
+	return b.String()
+}
+
+// emulatedCall is the JSON inside a <tool_call> block. Models trained on
+// other formats use the alternative keys.
+type emulatedCall struct {
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Tool       string          `json:"tool,omitempty"`
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// decodeCall reads a tool call from the JSON body of a block.
+func decodeCall(body string) (name string, args json.RawMessage, ok bool) {
+	body, ok = repairArguments(strings.TrimSpace(body))
+	var call emulatedCall
+	if !ok || json.Unmarshal([]byte(body), &call) != nil {
+		return "", nil, false
+	}
+	name, args = call.Name, call.Arguments
+	if name == "" {
+		name = call.Tool
+	}
+	if len(args) == 0 {
+		args = call.Parameters
+	}
+	// Some models send the arguments as a JSON-encoded string.
+	var quoted string
+	if json.Unmarshal(args, &quoted) == nil {
+		args = json.RawMessage(quoted)
+	}
+	repaired, ok := repairArguments(string(args))
+	return name, json.RawMessage(repaired), ok && name != ""
+}
+
+// emulateToolMessages rewrites tool calls and results as text, for a model
+// that has no tool-calling format of its own.
+func emulateToolMessages(messages []Message) []Message {
+	result := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.ToolCalls) == 0 && len(msg.ToolResults) == 0 {
+			result = append(result, msg)
+			continue
+		}
+		var blocks []string
+		images := msg.Images
+		for _, part := range msg.Parts() {
+			switch {
+			case part.Image != nil:
+				// Sent as the message's images below.
+			case part.ToolCall != nil:
+				call, _ := json.Marshal(emulatedCall{Name: part.ToolCall.Name, Arguments: part.ToolCall.Input})
+				blocks = append(blocks, "<tool_call>"+string(call)+"</tool_call>")
+			case part.ToolResult != nil:
+				status := ""
+				if part.ToolResult.IsError {
+					status = ` error="true"`
+				}
+				blocks = append(blocks, fmt.Sprintf("<tool_result%s>\n%s\n</tool_result>", status, part.ToolResult.Content))
+				images = append(slices.Clip(images), part.ToolResult.Images...)
+			default:
+				blocks = append(blocks, part.Text)
+			}
+		}
+		result = append(result, Message{Role: msg.Role, Content: strings.Join(blocks, "\n"), Images: images})
+	}
+	return result
+}
+
+// toolCallBlock matches a <tool_call> block; an unclosed last block, cut
+// off by the token limit, runs to the end of the reply.
+var toolCallBlock = regexp.MustCompile(`(?s)<tool_call>(.*?)(?:</tool_call>|$)`)
+
+// fencedBlock matches a fenced code block, the other way small models
+// write calls when asked for JSON.
+var fencedBlock = regexp.MustCompile("(?s)```(?:json|tool_call)?[ \t]*\n(.*?)```")
+
+// emulatedCallID numbers emulated tool calls, which have no IDs of their
+// own, uniquely within the process.
+var emulatedCallID atomic.Uint64
+
+// parseToolCalls moves the <tool_call> blocks of reply into ToolCalls.
+// Blocks that aren't usable calls are left in the text for the model to
+// see when it reads its reply back. If there are none and toolDefs is
+// given, fenced JSON blocks that name one of the tools count as calls too.
+func parseToolCalls(reply Message, toolDefs []tools.Tool) Message {
+	reply = extractCalls(reply, toolCallBlock, nil)
+	if len(reply.ToolCalls) == 0 && len(toolDefs) > 0 {
+		known := make(map[string]bool, len(toolDefs))
+		for _, t := range toolDefs {
+			known[t.Name] = true
+		}
+		reply = extractCalls(reply, fencedBlock, known)
+	}
+	return reply
+}
+
+// extractCalls moves the blocks block matches into ToolCalls, if they are
+// calls and, when known is given, name a known tool.
+func extractCalls(reply Message, block *regexp.Regexp, known map[string]bool) Message {
+	matches := block.FindAllStringSubmatchIndex(reply.Content, -1)
+	if len(matches) == 0 {
+		return reply
+	}
+	var text strings.Builder
+	last := 0
+	for _, m := range matches {
+		name, args, ok := decodeCall(reply.Content[m[2]:m[3]])
+		if !ok || (known != nil && !known[name]) {
+			continue
+		}
+		text.WriteString(reply.Content[last:m[0]])
+		last = m[1]
+		reply.ToolCalls = append(reply.ToolCalls, ToolCall{
+			ID:    fmt.Sprintf("call_emulated_%d", emulatedCallID.Add(1)),
+			Name:  name,
+			Input: args,
+		})
+	}
+	text.WriteString(reply.Content[last:])
+	reply.Content = strings.TrimSpace(text.String())
+	return reply
+}
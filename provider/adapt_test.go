@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"brutus/tools"
+)
+
+func TestServiceCapabilities(t *testing.T) {
+	if c := ServiceCapabilities(SaturnService{}); !c.Streaming || !c.Tools || c.Vision {
+		t.Errorf("a service without features should get OpenAI-compatible defaults, got %+v", c)
+	}
+	c := ServiceCapabilities(SaturnService{Features: []string{"streaming", " Vision", "context=32768"}})
+	want := Capabilities{Streaming: true, Vision: true, MaxContext: 32768}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	var meta modelMetadata
+	meta.remember([]ModelInfo{{ID: "m", ContextLength: 128000, SupportedParameters: []string{"tools", "response_format"}}})
+	c = meta.apply(c, "m")
+	want = Capabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true, MaxContext: 128000}
+	if c != want {
+		t.Errorf("after model metadata got %+v, want %+v", c, want)
+	}
+}
+
+func TestAdaptEmulatesTools(t *testing.T) {
+	var req openAIRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		reply := "Let me look.\n<tool_call>{\"name\": \"read_file\", \"arguments\": {\"path\": \"go.mod\"}}</tool_call>"
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": reply}}}})
+	}))
+	defer srv.Close()
+
+	// Advertises neither streaming nor tools.
+	p := Adapt(&Saturn{service: &SaturnService{Name: "s", APIBase: srv.URL + "/v1", Features: []string{"chat"}}, httpClient: http.DefaultClient})
+	history := []Message{
+		{Role: "user", Content: "what module is this?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Name: "list_files", Input: json.RawMessage(`{}`)}}},
+		{Role: "user", ToolResults: []ToolResult{{ID: "1", Content: "go.mod"}}},
+	}
+	stream, err := p.ChatStream(context.Background(), "system", history, []tools.Tool{tools.ReadFileTool, tools.ListFilesTool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content string
+	var calls []ToolCall
+	for delta := range stream {
+		if delta.Error != nil {
+			t.Fatal(delta.Error)
+		}
+		content += delta.Content
+		calls = append(calls, delta.ToolCalls...)
+	}
+
+	if req.Stream || len(req.Tools) != 0 {
+		t.Errorf("expected a plain request without tools, got stream=%t tools=%d", req.Stream, len(req.Tools))
+	}
+	if system, _ := req.Messages[0].Content.(string); !strings.Contains(system, "## read_file") {
+		t.Errorf("expected the tools described in the system prompt, got %q", system)
+	}
+	if got, _ := req.Messages[3].Content.(string); !strings.Contains(got, "<tool_result>\ngo.mod\n</tool_result>") {
+		t.Errorf("expected the tool result as text, got %q", got)
+	}
+	if content != "Let me look." {
+		t.Errorf("unexpected content %q", content)
+	}
+	if len(calls) != 1 || calls[0].Name != "read_file" || string(calls[0].Input) != `{"path": "go.mod"}` {
+		t.Errorf("unexpected tool calls %+v", calls)
+	}
+}
+
+func TestParseToolCalls(t *testing.T) {
+	toolDefs := []tools.Tool{tools.ReadFileTool}
+
+	fenced := Message{Content: "I'll read it.\n```json\n{\"tool\": \"read_file\", \"parameters\": \"{\\\"path\\\": \\\"a.go\\\"}\"}\n```"}
+	got := parseToolCalls(fenced, toolDefs)
+	if len(got.ToolCalls) != 1 || got.ToolCalls[0].Name != "read_file" || string(got.ToolCalls[0].Input) != `{"path": "a.go"}` {
+		t.Errorf("expected the fenced call parsed, got %+v", got.ToolCalls)
+	}
+	if got.Content != "I'll read it." {
+		t.Errorf("expected the block removed from the text, got %q", got.Content)
+	}
+
+	example := Message{Content: "Config looks like:\n```json\n{\"name\": \"server\", \"arguments\": {}}\n```"}
+	if got := parseToolCalls(example, toolDefs); len(got.ToolCalls) != 0 || got.Content != example.Content {
+		t.Errorf("fenced JSON naming no tool should stay text, got %+v", got)
+	}
+
+	// Native replies only have <tool_call> blocks parsed.
+	if got := parseToolCalls(fenced, nil); len(got.ToolCalls) != 0 {
+		t.Errorf("expected fenced JSON ignored without tool definitions, got %+v", got.ToolCalls)
+	}
+	truncated := Message{Content: `<tool_call>{"name": "read_file", "arguments": {"paths": ["a.go", "b.go"`}
+	if got := parseToolCalls(truncated, nil); len(got.ToolCalls) != 0 {
+		t.Errorf("expected a truncated block not to run, got %+v", got.ToolCalls)
+	}
+}
+
+func TestToolCallingMode(t *testing.T) {
+	svc := &SaturnService{Features: []string{"streaming", "tools"}}
+	if (&Saturn{service: svc, toolCalling: ToolCallingEmulated}).Capabilities().Tools {
+		t.Error("emulated mode should report no native tools")
+	}
+	if !(&Saturn{service: &SaturnService{Features: []string{"streaming"}}, toolCalling: ToolCallingNative}).Capabilities().Tools {
+		t.Error("native mode should report native tools")
+	}
+	if _, err := NewSaturn(context.Background(), SaturnConfig{ToolCalling: "sometimes"}); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,37 @@
+package provider
+
+import "context"
+
+type affinityKey struct{}
+
+// WithAffinity tags requests made with ctx as belonging to one
+// conversation. A sticky SaturnPool sends every request with the same key
+// to the same service, so the backend's KV cache for that conversation
+// stays warm.
+func WithAffinity(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, affinityKey{}, key)
+}
+
+// affinityFrom returns the key set by WithAffinity, or "" if none was.
+func affinityFrom(ctx context.Context) string {
+	key, _ := ctx.Value(affinityKey{}).(string)
+	return key
+}
+
+type maxTokensKey struct{}
+
+// WithMaxTokens lowers the response limit of requests made with ctx below
+// the provider's configured maximum, for small side requests such as
+// titling a conversation.
+func WithMaxTokens(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxTokensKey{}, n)
+}
+
+// maxTokensFrom returns the smaller of configured and the limit set by
+// WithMaxTokens.
+func maxTokensFrom(ctx context.Context, configured int) int {
+	if n, ok := ctx.Value(maxTokensKey{}).(int); ok && n > 0 && (configured <= 0 || n < configured) {
+		return n
+	}
+	return configured
+}
@@ -0,0 +1,368 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"brutus/telemetry"
+	"brutus/tools"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultAnthropicModel is the model an Anthropic provider requests when
+// none is configured.
+const DefaultAnthropicModel = string(anthropic.ModelClaudeSonnet4_0)
+
+// anthropicContext is the context window of current Claude models.
+const anthropicContext = 200000
+
+// AnthropicConfig configures a provider for the Anthropic API.
+type AnthropicConfig struct {
+	APIKey    string // Defaults to ANTHROPIC_API_KEY
+	Model     string // Defaults to DefaultAnthropicModel
+	MaxTokens int    // Defaults to 4096
+	BaseURL   string // For a proxy or gateway; defaults to the public API
+	Options   ChatOptions
+}
+
+// Anthropic talks to the Anthropic Messages API directly, for when there
+// is no Saturn service on the network but an API key is at hand.
+type Anthropic struct {
+	client    anthropic.Client
+	model     string
+	maxTokens int
+	options   ChatOptions
+	meta      modelMetadata
+}
+
+// NewAnthropic creates a provider for the Anthropic API. It fails with
+// ErrAuth if no API key is configured or set in ANTHROPIC_API_KEY.
+func NewAnthropic(cfg AnthropicConfig) (*Anthropic, error) {
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("%w: ANTHROPIC_API_KEY is not set", ErrAuth)
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultAnthropicModel
+	}
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = 4096
+	}
+	opts := []option.RequestOption{option.WithAPIKey(cfg.APIKey)}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+	return &Anthropic{
+		client:    anthropic.NewClient(opts...),
+		model:     cfg.Model,
+		maxTokens: cfg.MaxTokens,
+		options:   cfg.Options,
+	}, nil
+}
+
+func (a *Anthropic) Name() string {
+	return "anthropic"
+}
+
+func (a *Anthropic) GetModel() string {
+	return a.model
+}
+
+func (a *Anthropic) SetModel(model string) {
+	a.model = model
+}
+
+// Capabilities reports what the Messages API supports. Extended thinking
+// is left off: it needs the model's signed thinking blocks sent back with
+// tool results, which Message does not keep.
+func (a *Anthropic) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, Tools: true, Vision: true, MaxContext: anthropicContext}
+}
+
+func (a *Anthropic) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	if models, ok := a.meta.cached(); ok {
+		return models, nil
+	}
+	defer track("models", "anthropic", a.model)()
+
+	var models []ModelInfo
+	pager := a.client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+	for pager.Next() {
+		m := pager.Current()
+		models = append(models, ModelInfo{ID: m.ID, Name: m.DisplayName, ContextLength: anthropicContext})
+	}
+	if err := pager.Err(); err != nil {
+		return nil, anthropicError(err)
+	}
+	a.meta.remember(models)
+	return models, nil
+}
+
+// params builds a Messages API request.
+func (a *Anthropic) params(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) anthropic.MessageNewParams {
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.Model(a.model),
+		MaxTokens: int64(maxTokensFrom(ctx, a.maxTokens)),
+		Messages:  convertToAnthropicMessages(messages),
+	}
+	if systemPrompt != "" {
+		params.System = []anthropic.TextBlockParam{{Text: systemPrompt}}
+	}
+	for _, t := range toolDefs {
+		params.Tools = append(params.Tools, t.ToAnthropic())
+	}
+	if schema, ok := schemaFrom(ctx); ok {
+		forceSchemaTool(&params, schema)
+	}
+	cacheBreakpoints(&params)
+	opts := optionsFrom(ctx, a.options)
+	if opts.Temperature != nil {
+		params.Temperature = anthropic.Float(*opts.Temperature)
+	}
+	if opts.TopP != nil {
+		params.TopP = anthropic.Float(*opts.TopP)
+	}
+	params.StopSequences = opts.Stop
+	return params
+}
+
+// forceSchemaTool has the model answer by calling a tool whose input is
+// the structured response, which is how the Messages API constrains
+// output. Tool inputs are objects, so a schema for anything else is left
+// to the prompt.
+func forceSchemaTool(params *anthropic.MessageNewParams, schema Schema) {
+	if schema.Schema["type"] != "object" {
+		return
+	}
+	input := anthropic.ToolInputSchemaParam{
+		Properties:  schema.Schema["properties"],
+		Required:    schemaTypes(schema.Schema["required"]),
+		ExtraFields: map[string]any{},
+	}
+	for k, v := range schema.Schema {
+		if k != "type" && k != "properties" && k != "required" {
+			input.ExtraFields[k] = v
+		}
+	}
+	params.Tools = append(params.Tools, anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+		Name:        schema.Name,
+		Description: anthropic.String("Give your response as this tool's input."),
+		InputSchema: input,
+	}})
+	params.ToolChoice = anthropic.ToolChoiceParamOfTool(schema.Name)
+}
+
+// cacheBreakpoints marks the ends of the tool definitions, the system
+// prompt and the conversation for the prompt cache, which comes before them
+// in that order. The next request reads the prefix up to the last
+// breakpoint that still matches back from the cache, at a tenth of the
+// price, and an agent's requests differ only by the turns added since.
+// Prompts shorter than the model's minimum, about 1024 tokens, aren't cached
+// and cost nothing extra.
+func cacheBreakpoints(params *anthropic.MessageNewParams) {
+	if n := len(params.Tools); n > 0 {
+		if cc := params.Tools[n-1].GetCacheControl(); cc != nil {
+			*cc = anthropic.NewCacheControlEphemeralParam()
+		}
+	}
+	if n := len(params.System); n > 0 {
+		params.System[n-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+	if n := len(params.Messages); n > 0 {
+		content := params.Messages[n-1].Content
+		// Thinking blocks can't be breakpoints; GetCacheControl is nil.
+		for i := len(content) - 1; i >= 0; i-- {
+			if cc := content[i].GetCacheControl(); cc != nil {
+				*cc = anthropic.NewCacheControlEphemeralParam()
+				break
+			}
+		}
+	}
+}
+
+// Chat implements the Provider interface using the Messages API.
+func (a *Anthropic) Chat(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (_ Message, err error) {
+	params := a.params(ctx, systemPrompt, messages, toolDefs)
+	ctx, span := a.startSpan(ctx, params)
+	defer func() { telemetry.End(span, err) }()
+	defer track("chat", "anthropic", a.model)()
+
+	resp, err := a.client.Messages.New(ctx, params)
+	if err != nil {
+		return Message{}, anthropicError(err)
+	}
+	firstTokenFrom(ctx)()
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", int(resp.Usage.InputTokens)),
+		attribute.Int("gen_ai.usage.output_tokens", int(resp.Usage.OutputTokens)))
+	msg := convertFromAnthropicMessage(resp)
+	msg.Usage = anthropicUsage(a.model, resp.Usage)
+	return msg, nil
+}
+
+// startSpan starts the span for one Messages API request.
+func (a *Anthropic) startSpan(ctx context.Context, params anthropic.MessageNewParams) (context.Context, trace.Span) {
+	return telemetry.Start(ctx, "chat "+a.model,
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.system", "anthropic"),
+		attribute.String("gen_ai.request.model", a.model),
+		attribute.Int("gen_ai.request.max_tokens", int(params.MaxTokens)))
+}
+
+// ChatStream is Chat with the response streamed. Its span ends when the
+// stream does.
+func (a *Anthropic) ChatStream(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (<-chan StreamDelta, error) {
+	params := a.params(ctx, systemPrompt, messages, toolDefs)
+	ctx, span := a.startSpan(ctx, params)
+	done := track("stream", "anthropic", a.model)
+	stream := a.client.Messages.NewStreaming(ctx, params)
+
+	// The request is sent on the first read; reading the opening event
+	// here lets errors such as rate limits reach the caller as a failed
+	// request rather than a failed stream.
+	if !stream.Next() {
+		err := stream.Err()
+		stream.Close()
+		done()
+		if err == nil {
+			err = errors.New("anthropic: empty response stream")
+		}
+		err = anthropicError(err)
+		telemetry.End(span, err)
+		return nil, err
+	}
+
+	ch := make(chan StreamDelta, 10)
+	go func() {
+		defer done()
+		defer stream.Close()
+		defer close(ch)
+
+		firstToken := firstTokenFrom(ctx)
+		var acc anthropic.Message
+		for first := true; first || stream.Next(); first = false {
+			event := stream.Current()
+			if err := acc.Accumulate(event); err != nil {
+				ch <- StreamDelta{Error: err, Done: true}
+				return
+			}
+			if event.Type != "content_block_delta" {
+				continue
+			}
+			firstToken()
+			switch event.Delta.Type {
+			case "text_delta":
+				ch <- StreamDelta{Content: event.Delta.Text}
+			case "thinking_delta":
+				ch <- StreamDelta{Reasoning: event.Delta.Thinking}
+			}
+		}
+		if err := stream.Err(); err != nil {
+			ch <- StreamDelta{Error: anthropicError(err), Done: true}
+			return
+		}
+		ch <- finalDelta(convertFromAnthropicMessage(&acc).ToolCalls, anthropicUsage(a.model, acc.Usage))
+	}()
+	return traceStream(span, ch), nil
+}
+
+// anthropicToolResult builds a tool result block, with the text first and
+// then any images the tool returned.
+func anthropicToolResult(tr ToolResult) anthropic.ContentBlockParamUnion {
+	block := anthropic.NewToolResultBlock(tr.ID, tr.Content, tr.IsError)
+	for _, img := range tr.Images {
+		image := anthropic.NewImageBlockBase64(img.MediaType, img.Data)
+		block.OfToolResult.Content = append(block.OfToolResult.Content, anthropic.ToolResultBlockParamContentUnion{OfImage: image.OfImage})
+	}
+	return block
+}
+
+// convertFromAnthropicMessage turns a Messages API response into a
+// Message.
+func convertFromAnthropicMessage(resp *anthropic.Message) Message {
+	msg := Message{Role: "assistant"}
+	var text, thinking strings.Builder
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		case "tool_use":
+			input := block.Input
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Input: input})
+		}
+	}
+	msg.Content = text.String()
+	msg.Reasoning = thinking.String()
+	return msg
+}
+
+// anthropicUsage converts the Messages API's counts. Tokens written to and
+// read from the prompt cache are prompt tokens too, though billed at their
+// own rates.
+func anthropicUsage(model string, u anthropic.Usage) *Usage {
+	return priced(model, Usage{
+		PromptTokens:     int(u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens),
+		CompletionTokens: int(u.OutputTokens),
+		CacheReadTokens:  int(u.CacheReadInputTokens),
+		CacheWriteTokens: int(u.CacheCreationInputTokens),
+	})
+}
+
+// anthropicError classifies an error from the SDK like any other API
+// error, so retries and context compaction work the same as with Saturn.
+func anthropicError(err error) error {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		return newAPIError(apiErr.Response, []byte(apiErr.RawJSON()))
+	}
+	return err
+}
+
+// convertToAnthropicMessages builds Messages API params for a service that
+// speaks Anthropic's format. Unlike OpenAI's, it keeps each message whole:
+// every Part becomes one content block, in order. The system prompt is a
+// separate request field there, so it is not part of the result.
+func convertToAnthropicMessages(messages []Message) []anthropic.MessageParam {
+	result := make([]anthropic.MessageParam, 0, len(messages))
+	for _, msg := range messages {
+		var blocks []anthropic.ContentBlockParamUnion
+		for _, part := range msg.Parts() {
+			switch {
+			case part.ToolResult != nil:
+				blocks = append(blocks, anthropicToolResult(*part.ToolResult))
+			case part.Image != nil:
+				blocks = append(blocks, anthropic.NewImageBlockBase64(part.Image.MediaType, part.Image.Data))
+			case part.ToolCall != nil:
+				var input any = json.RawMessage(part.ToolCall.Input)
+				if len(part.ToolCall.Input) == 0 {
+					input = map[string]any{}
+				}
+				blocks = append(blocks, anthropic.NewToolUseBlock(part.ToolCall.ID, input, part.ToolCall.Name))
+			default:
+				blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+			}
+		}
+
+		if msg.Role == "assistant" {
+			result = append(result, anthropic.NewAssistantMessage(blocks...))
+		} else {
+			result = append(result, anthropic.NewUserMessage(blocks...))
+		}
+	}
+	return result
+}
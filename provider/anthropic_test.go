@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"brutus/tools"
+)
+
+func newTestAnthropic(t *testing.T, handler http.HandlerFunc) *Anthropic {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	a, err := NewAnthropic(AnthropicConfig{APIKey: "test-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAnthropicChat(t *testing.T) {
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("X-Api-Key") != "test-key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-0",
+			"content":[{"type":"text","text":"Reading it."},{"type":"tool_use","id":"toolu_1","name":"read_file","input":{"path":"main.go"}}],
+			"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`)
+	})
+
+	msg, err := a.Chat(context.Background(), "be brief", []Message{{Role: "user", Content: "read main.go"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "Reading it." || len(msg.ToolCalls) != 1 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if tc := msg.ToolCalls[0]; tc.ID != "toolu_1" || tc.Name != "read_file" || string(tc.Input) != `{"path":"main.go"}` {
+		t.Errorf("unexpected tool call %+v", tc)
+	}
+}
+
+func TestAnthropicPromptCache(t *testing.T) {
+	var body map[string]any
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-0",
+			"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":100,"cache_creation_input_tokens":1000,"cache_read_input_tokens":9000,"output_tokens":10}}`)
+	})
+
+	history := []Message{
+		{Role: "user", Content: "list the files"},
+		{Role: "assistant", Content: "Listing.", ToolCalls: []ToolCall{{ID: "t1", Name: "list_files", Input: []byte(`{}`)}}},
+		{Role: "user", ToolResults: []ToolResult{{ID: "t1", Content: "main.go"}}},
+	}
+	msg, err := a.Chat(context.Background(), "be brief", history, []tools.Tool{tools.ReadFileTool, tools.ListFilesTool})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marked := func(block any) bool {
+		m, _ := block.(map[string]any)
+		cc, _ := m["cache_control"].(map[string]any)
+		return cc["type"] == "ephemeral"
+	}
+	toolDefs := body["tools"].([]any)
+	system := body["system"].([]any)
+	messages := body["messages"].([]any)
+	last := messages[len(messages)-1].(map[string]any)["content"].([]any)
+	if marked(toolDefs[0]) || !marked(toolDefs[1]) || !marked(system[0]) || !marked(last[len(last)-1]) {
+		t.Errorf("want breakpoints on the last tool, the system prompt and the last message, got %v", body)
+	}
+	if first := messages[0].(map[string]any)["content"].([]any); marked(first[0]) {
+		t.Error("earlier message marked as a breakpoint")
+	}
+
+	u := msg.Usage
+	if u == nil || u.PromptTokens != 10100 || u.CacheReadTokens != 9000 || u.CacheWriteTokens != 1000 {
+		t.Fatalf("usage = %+v", u)
+	}
+	// 100 tokens at $3, 9000 at $0.30 and 1000 at $3.75 per million, and
+	// 10 completion tokens at $15.
+	if want := (100*3 + 9000*0.3 + 1000*3.75 + 10*15) / 1e6; math.Abs(u.Cost-want) > 1e-12 {
+		t.Errorf("cost = %v, want %v", u.Cost, want)
+	}
+}
+
+func TestAnthropicChatStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-0","content":[],"usage":{"input_tokens":10,"output_tokens":0}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"bash","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"command\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"ls\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+		`{"type":"message_stop"}`,
+	}
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			kind := strings.SplitN(strings.TrimPrefix(e, `{"type":"`), `"`, 2)[0]
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, e)
+		}
+	})
+
+	ch, err := a.ChatStream(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content strings.Builder
+	var final StreamDelta
+	for delta := range ch {
+		if delta.Error != nil {
+			t.Fatal(delta.Error)
+		}
+		content.WriteString(delta.Content)
+		if delta.Done {
+			final = delta
+		}
+	}
+	if content.String() != "Hello" {
+		t.Errorf("streamed %q, want Hello", content.String())
+	}
+	if len(final.ToolCalls) != 1 || final.ToolCalls[0].Name != "bash" || string(final.ToolCalls[0].Input) != `{"command":"ls"}` {
+		t.Errorf("unexpected tool calls %+v", final.ToolCalls)
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	})
+
+	_, err := a.Chat(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil)
+	if !errors.Is(err, ErrAuth) || !strings.Contains(err.Error(), "invalid x-api-key") {
+		t.Errorf("Chat: expected an auth error with the message, got %v", err)
+	}
+	if _, err := a.ChatStream(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil); !errors.Is(err, ErrAuth) {
+		t.Errorf("ChatStream: expected an auth error, got %v", err)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := NewAnthropic(AnthropicConfig{}); !errors.Is(err, ErrAuth) {
+		t.Errorf("NewAnthropic without a key: got %v", err)
+	}
+}
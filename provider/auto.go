@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"brutus/tools"
+)
+
+// DefaultOpenAIModel is the model NewAuto asks the OpenAI API for when
+// neither the config nor OPENAI_MODEL names one.
+const DefaultOpenAIModel = "gpt-4.1"
+
+// openAIAPI is the OpenAI API's base URL, used by NewAuto when only
+// OPENAI_API_KEY is set.
+const openAIAPI = "https://api.openai.com/v1"
+
+// AutoConfig configures each provider NewAuto may set up. Models are given
+// per provider, as their names differ between them.
+type AutoConfig struct {
+	Saturn    SaturnConfig
+	Anthropic AnthropicConfig
+
+	// OpenAI's BaseURL defaults to OPENAI_BASE_URL, then to the OpenAI API
+	// if OPENAI_API_KEY is set, and its Model to OPENAI_MODEL, then, for
+	// the OpenAI API, DefaultOpenAIModel.
+	OpenAI OpenAIConfig
+}
+
+// NewAuto sets up every provider it can, in order of preference: the Saturn
+// services discovery finds, the Anthropic API if ANTHROPIC_API_KEY is set,
+// then an OpenAI-compatible endpoint if OPENAI_BASE_URL or OPENAI_API_KEY
+// is, and returns them as a Fallback. It fails only if none can be set
+// up, with each one's reason; the error wraps ErrNoServices when discovery
+// found none.
+func NewAuto(ctx context.Context, cfg AutoConfig) (*Fallback, error) {
+	var providers []Provider
+	var errs []error
+
+	if saturn, err := newSaturnAuto(ctx, cfg.Saturn); err == nil {
+		providers = append(providers, saturn)
+	} else {
+		errs = append(errs, fmt.Errorf("saturn: %w", err))
+	}
+
+	if cfg.Anthropic.APIKey != "" || os.Getenv("ANTHROPIC_API_KEY") != "" {
+		if a, err := NewAnthropic(cfg.Anthropic); err == nil {
+			providers = append(providers, a)
+		} else {
+			errs = append(errs, fmt.Errorf("anthropic: %w", err))
+		}
+	}
+
+	openAI := cfg.OpenAI
+	if openAI.BaseURL == "" {
+		openAI.BaseURL = os.Getenv("OPENAI_BASE_URL")
+	}
+	if openAI.BaseURL == "" && (openAI.APIKey != "" || os.Getenv("OPENAI_API_KEY") != "") {
+		openAI.BaseURL = openAIAPI
+	}
+	if openAI.BaseURL != "" {
+		if openAI.Model == "" {
+			openAI.Model = os.Getenv("OPENAI_MODEL")
+		}
+		if strings.TrimSuffix(openAI.BaseURL, "/") == openAIAPI {
+			if openAI.Model == "" {
+				openAI.Model = DefaultOpenAIModel
+			}
+			if openAI.Features == nil {
+				openAI.Features = []string{"streaming", "tools", "vision", "json"}
+			}
+		}
+		if o, err := NewOpenAI(openAI); err == nil {
+			providers = append(providers, o)
+		} else {
+			errs = append(errs, fmt.Errorf("openai: %w", err))
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return NewFallback(providers...), nil
+}
+
+// newSaturnAuto connects to Saturn with NewSaturnSticky if cfg asks for
+// sticky routing, and NewSaturn otherwise.
+func newSaturnAuto(ctx context.Context, cfg SaturnConfig) (Provider, error) {
+	if cfg.Sticky {
+		return NewSaturnSticky(ctx, cfg)
+	}
+	return NewSaturn(ctx, cfg)
+}
+
+// Fallback sends requests to the first of its providers, and when one
+// fails, tries the others in turn, staying with the one that answers for
+// the requests after. A request too long for the model, or one the
+// caller gave up on, is not passed on. A stream is only passed on if it
+// fails to start.
+//
+// Name, Capabilities and the model methods are the active provider's.
+type Fallback struct {
+	providers []Provider
+
+	mu       sync.Mutex
+	active   int
+	onSwitch func(from, to Provider, err error)
+}
+
+// NewFallback returns a Fallback over providers, in order of preference.
+// There must be at least one.
+func NewFallback(providers ...Provider) *Fallback {
+	return &Fallback{providers: providers}
+}
+
+// Providers returns the providers in the chain, in order.
+func (f *Fallback) Providers() []Provider {
+	return append([]Provider(nil), f.providers...)
+}
+
+// Active returns the provider requests go to first.
+func (f *Fallback) Active() Provider {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.providers[f.active]
+}
+
+// OnSwitch sets a function called when requests move to another provider
+// because the active one failed with err.
+func (f *Fallback) OnSwitch(fn func(from, to Provider, err error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onSwitch = fn
+}
+
+// order returns the providers' indexes, the active one first.
+func (f *Fallback) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	order := make([]int, len(f.providers))
+	for i := range order {
+		order[i] = (f.active + i) % len(f.providers)
+	}
+	return order
+}
+
+// settle makes provider to the active one, after from failed with err.
+func (f *Fallback) settle(from, to int, err error) {
+	if from == to {
+		return
+	}
+	f.mu.Lock()
+	f.active = to
+	onSwitch := f.onSwitch
+	f.mu.Unlock()
+	if onSwitch != nil {
+		onSwitch(f.providers[from], f.providers[to], err)
+	}
+}
+
+// passOn reports whether a request that failed with err may succeed with
+// another provider.
+func passOn(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !failsEverywhere(err)
+}
+
+func (f *Fallback) Chat(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (Message, error) {
+	order := f.order()
+	var firstErr error
+	for n, i := range order {
+		msg, err := f.providers[i].Chat(ctx, systemPrompt, messages, toolDefs)
+		if err == nil {
+			f.settle(order[0], i, firstErr)
+			return msg, nil
+		}
+		if n == len(order)-1 || !passOn(ctx, err) {
+			return msg, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	panic("unreachable")
+}
+
+func (f *Fallback) ChatStream(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (<-chan StreamDelta, error) {
+	order := f.order()
+	var firstErr error
+	for n, i := range order {
+		stream, err := f.providers[i].ChatStream(ctx, systemPrompt, messages, toolDefs)
+		if err == nil {
+			f.settle(order[0], i, firstErr)
+			return stream, nil
+		}
+		if n == len(order)-1 || !passOn(ctx, err) {
+			return nil, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	panic("unreachable")
+}
+
+func (f *Fallback) Name() string {
+	return f.Active().Name()
+}
+
+func (f *Fallback) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return f.Active().ListModels(ctx)
+}
+
+func (f *Fallback) SetModel(model string) {
+	f.Active().SetModel(model)
+}
+
+func (f *Fallback) GetModel() string {
+	return f.Active().GetModel()
+}
+
+func (f *Fallback) Capabilities() Capabilities {
+	return f.Active().Capabilities()
+}
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// endpoint serves chat completions, failing with status while it is not
+// 200, and counts requests.
+func endpoint(t *testing.T, status *int, calls *int) *OpenAI {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if *status != http.StatusOK {
+			w.WriteHeader(*status)
+			message := "upstream unavailable"
+			if *status == http.StatusBadRequest {
+				message = "prompt is too long"
+			}
+			fmt.Fprintf(w, `{"error":{"message":%q}}`, message)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	o, err := NewOpenAI(OpenAIConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestFallback(t *testing.T) {
+	firstStatus, secondStatus := http.StatusBadGateway, http.StatusOK
+	var firstCalls, secondCalls int
+	first, second := endpoint(t, &firstStatus, &firstCalls), endpoint(t, &secondStatus, &secondCalls)
+	f := NewFallback(first, second)
+	var switched error
+	f.OnSwitch(func(from, to Provider, err error) { switched = err })
+	chat := func() error {
+		_, err := f.Chat(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil)
+		return err
+	}
+
+	if err := chat(); err != nil || firstCalls != 1 || secondCalls != 1 {
+		t.Fatalf("got %v after %d and %d calls, want the second to answer", err, firstCalls, secondCalls)
+	}
+	if f.Active() != Provider(second) || switched == nil {
+		t.Errorf("did not switch to the second provider: active %s, switch error %v", f.Active().Name(), switched)
+	}
+	if err := chat(); err != nil || firstCalls != 1 || secondCalls != 2 {
+		t.Errorf("the next request did not stay with the second: %v, %d and %d calls", err, firstCalls, secondCalls)
+	}
+
+	// A prompt too long for one model is too long to pass on.
+	secondStatus = http.StatusBadRequest
+	if err := chat(); !errors.Is(err, ErrContextLength) || firstCalls != 1 {
+		t.Errorf("got %v after %d calls to the first, want ErrContextLength from the second alone", err, firstCalls)
+	}
+
+	// When the active one fails, the ones before it are tried again.
+	firstStatus, secondStatus = http.StatusOK, http.StatusServiceUnavailable
+	if _, err := f.ChatStream(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil); err != nil || f.Active() != Provider(first) {
+		t.Errorf("stream: got %v, active %s", err, f.Active().Name())
+	}
+}
+
+func TestNewAutoWithoutProviders(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Whatever discovery finds, no service has this name.
+	_, err := NewAuto(context.Background(), AutoConfig{Saturn: SaturnConfig{DiscoveryTimeout: 10 * time.Millisecond, Service: "none"}})
+	if err == nil {
+		t.Fatal("expected an error with no provider available")
+	}
+
+	t.Setenv("OPENAI_BASE_URL", srv.URL+"/v1")
+	f, err := NewAuto(context.Background(), AutoConfig{Saturn: SaturnConfig{DiscoveryTimeout: 10 * time.Millisecond, Service: "none"}})
+	if err != nil || len(f.Providers()) != 1 {
+		t.Fatalf("with OPENAI_BASE_URL set: %v", err)
+	}
+	if _, ok := f.Active().(*OpenAI); !ok {
+		t.Errorf("active provider is %T, want *OpenAI", f.Active())
+	}
+}
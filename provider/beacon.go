@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// BeaconConfig describes the OpenAI-compatible server a Beacon announces.
+type BeaconConfig struct {
+	Name          string
+	Port          int    // port the server listens on, on this host
+	APIBase       string // announced instead of host:port when the server is elsewhere
+	Priority      int    // lower is preferred
+	Models        []string
+	Features      []string
+	MaxConcurrent int
+	CurrentLoad   int
+	GPU           string
+	VRAMGb        int
+	EphemeralKey  string
+}
+
+// TXT returns the TXT records for the service, with the keys discovery
+// reads. Unset fields are left out.
+func (c BeaconConfig) TXT() map[string]string {
+	txt := map[string]string{
+		"priority": strconv.Itoa(c.Priority),
+		"api":      "openai",
+	}
+	set := func(key, value string) {
+		if value != "" {
+			txt[key] = value
+		}
+	}
+	set("api_base", c.APIBase)
+	set("ephemeral_key", c.EphemeralKey)
+	set("models", strings.Join(c.Models, ","))
+	set("features", strings.Join(c.Features, ","))
+	set("gpu", c.GPU)
+	if c.MaxConcurrent > 0 {
+		txt["max_concurrent"] = strconv.Itoa(c.MaxConcurrent)
+	}
+	if c.VRAMGb > 0 {
+		txt["vram_gb"] = strconv.Itoa(c.VRAMGb)
+	}
+	txt["current_load"] = strconv.Itoa(c.CurrentLoad)
+	return txt
+}
+
+// Beacon announces a server as a Saturn service, over mDNS and by
+// answering broadcast probes, so BRUTUS clients discover it as they would
+// a Saturn server.
+type Beacon struct {
+	mu     sync.Mutex
+	config BeaconConfig
+	server *zeroconf.Server // nil when mDNS is off
+	conn   *net.UDPConn     // nil when broadcast is off
+	once   sync.Once
+}
+
+// StartBeacon registers cfg as a _saturn._tcp service and answers
+// broadcast probes on BroadcastPort until Close.
+func StartBeacon(cfg BeaconConfig) (*Beacon, error) {
+	return startBeacon(cfg, true, &net.UDPAddr{Port: BroadcastPort})
+}
+
+// startBeacon starts a beacon that registers over mDNS if mdns is set and
+// answers probes on broadcast if it is not nil.
+func startBeacon(cfg BeaconConfig, mdns bool, broadcast *net.UDPAddr) (*Beacon, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("beacon needs a service name")
+	}
+	if cfg.Port <= 0 && cfg.APIBase == "" {
+		return nil, fmt.Errorf("beacon needs a port or an API base URL")
+	}
+	b := &Beacon{config: cfg}
+
+	if mdns {
+		server, err := zeroconf.Register(cfg.Name, "_saturn._tcp", "local.", b.port(), txtRecords(cfg.TXT()), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register %s over mDNS: %w", cfg.Name, err)
+		}
+		b.server = server
+	}
+
+	if broadcast != nil {
+		conn, err := net.ListenUDP("udp4", broadcast)
+		if err != nil {
+			b.Close()
+			return nil, fmt.Errorf("failed to listen for broadcast probes: %w", err)
+		}
+		b.conn = conn
+		go b.answerProbes()
+	}
+	return b, nil
+}
+
+// port is the port to register. mDNS needs one even when clients use
+// api_base instead.
+func (b *Beacon) port() int {
+	if b.config.Port > 0 {
+		return b.config.Port
+	}
+	return 443
+}
+
+// Addr is the address the beacon answers broadcast probes on, or nil if
+// it doesn't.
+func (b *Beacon) Addr() *net.UDPAddr {
+	if b.conn == nil {
+		return nil
+	}
+	return b.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// SetLoad updates the announced current_load.
+func (b *Beacon) SetLoad(load int) {
+	b.mu.Lock()
+	b.config.CurrentLoad = load
+	txt := b.config.TXT()
+	b.mu.Unlock()
+	if b.server != nil {
+		b.server.SetText(txtRecords(txt))
+	}
+}
+
+// Close unregisters the service and stops answering probes. It is safe to
+// call more than once.
+func (b *Beacon) Close() {
+	b.once.Do(func() {
+		if b.server != nil {
+			b.server.Shutdown()
+		}
+		if b.conn != nil {
+			b.conn.Close()
+		}
+	})
+}
+
+func (b *Beacon) answerProbes() {
+	buf := make([]byte, maxBroadcastPacket)
+	for {
+		n, from, err := b.conn.ReadFromUDP(buf)
+		if err != nil {
+			return // closed
+		}
+		var probe BroadcastMessage
+		if json.Unmarshal(buf[:n], &probe) != nil || probe.Saturn != "probe" {
+			continue
+		}
+		b.mu.Lock()
+		announce := BroadcastMessage{
+			Saturn:  "announce",
+			Version: broadcastVersion,
+			Name:    b.config.Name,
+			Port:    b.config.Port,
+			TXT:     b.config.TXT(),
+		}
+		b.mu.Unlock()
+		packet, _ := json.Marshal(announce)
+		b.conn.WriteToUDP(packet, from)
+	}
+}
+
+// txtRecords turns a TXT map into key=value records, in a stable order.
+func txtRecords(txt map[string]string) []string {
+	records := make([]string, 0, len(txt))
+	for key, value := range txt {
+		records = append(records, key+"="+value)
+	}
+	sort.Strings(records)
+	return records
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBeaconAnswersProbes(t *testing.T) {
+	b, err := startBeacon(BeaconConfig{
+		Name:          "local-llama",
+		Port:          8080,
+		Priority:      20,
+		Models:        []string{"llama3.1:8b", "qwen3:14b"},
+		Features:      []string{"streaming", "tools"},
+		MaxConcurrent: 4,
+	}, false, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.SetLoad(2)
+
+	services, err := discoverBroadcast(context.Background(), 300*time.Millisecond, []*net.UDPAddr{b.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("got %d services, want 1: %+v", len(services), services)
+	}
+	svc := services[0]
+	if svc.Name != "local-llama" || svc.URL() != "http://127.0.0.1:8080" {
+		t.Errorf("service = %s at %s, want local-llama at http://127.0.0.1:8080", svc.Name, svc.URL())
+	}
+	if svc.Priority != 20 || len(svc.Models) != 2 || len(svc.Features) != 2 {
+		t.Errorf("TXT fields not announced: %+v", svc)
+	}
+	if svc.MaxConcurrent != 4 || svc.CurrentLoad != 2 {
+		t.Errorf("load = %d/%d, want 2/4", svc.CurrentLoad, svc.MaxConcurrent)
+	}
+}
+
+func TestStartBeaconNeedsAddress(t *testing.T) {
+	if _, err := startBeacon(BeaconConfig{Name: "nowhere"}, false, nil); err == nil {
+		t.Error("expected an error for a beacon with neither port nor API base")
+	}
+}
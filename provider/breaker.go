@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 3
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// BreakerConfig tunes a SaturnPool's circuit breaker, which stops sending
+// requests to a service that keeps failing. Zero fields are the defaults.
+type BreakerConfig struct {
+	// Threshold is how many failed requests in a row open a service's
+	// circuit. Zero is 3; negative turns the breaker off.
+	Threshold int
+
+	// Cooldown is how long a service with an open circuit is skipped
+	// before it is health checked, and skipped again if it fails the
+	// check. Zero is 30 seconds.
+	Cooldown time.Duration
+}
+
+// SaturnServiceHealth is how one of a pool's services has been doing.
+type SaturnServiceHealth struct {
+	Name          string
+	Failures      int       // Failed requests since the last success
+	TotalFailures int       // Failed requests in all
+	Open          bool      // Skipped until it passes a health check
+	CheckAt       time.Time // When an open service is next checked
+}
+
+// breaker tracks the health of a pool's services. The zero value is ready
+// to use with the default config.
+type breaker struct {
+	cfg   BreakerConfig
+	check func(SaturnService) error // nil is healthCheck
+	now   func() time.Time          // nil is time.Now
+
+	mu       sync.Mutex
+	services map[string]*SaturnServiceHealth
+	checking map[string]bool
+}
+
+// state returns the health record for name, creating it. b.mu is held.
+func (b *breaker) state(name string) *SaturnServiceHealth {
+	if b.services == nil {
+		b.services = map[string]*SaturnServiceHealth{}
+		b.checking = map[string]bool{}
+	}
+	s, ok := b.services[name]
+	if !ok {
+		s = &SaturnServiceHealth{Name: name}
+		b.services[name] = s
+	}
+	return s
+}
+
+func (b *breaker) time() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *breaker) cooldown() time.Duration {
+	if b.cfg.Cooldown > 0 {
+		return b.cfg.Cooldown
+	}
+	return defaultBreakerCooldown
+}
+
+// allow reports whether a request may go to svc. An open service whose
+// cooldown is over is health checked first, by one caller at a time, and
+// closed again if it passes.
+func (b *breaker) allow(svc SaturnService) bool {
+	b.mu.Lock()
+	s := b.state(svc.Name)
+	if !s.Open {
+		b.mu.Unlock()
+		return true
+	}
+	if b.checking[svc.Name] || b.time().Before(s.CheckAt) {
+		b.mu.Unlock()
+		return false
+	}
+	b.checking[svc.Name] = true
+	b.mu.Unlock()
+
+	check := b.check
+	if check == nil {
+		check = healthCheck
+	}
+	err := check(svc)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checking[svc.Name] = false
+	if err != nil {
+		s.CheckAt = b.time().Add(b.cooldown())
+		return false
+	}
+	s.Open = false
+	s.Failures = 0
+	return true
+}
+
+// succeeded records a request svc answered.
+func (b *breaker) succeeded(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.state(name)
+	s.Failures = 0
+	s.Open = false
+}
+
+// failed records a request svc failed, opening its circuit at the
+// threshold.
+func (b *breaker) failed(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.state(name)
+	s.Failures++
+	s.TotalFailures++
+	threshold := b.cfg.Threshold
+	if threshold == 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if threshold > 0 && s.Failures >= threshold && !s.Open {
+		b.open(s)
+	}
+}
+
+// trip opens name's circuit straight away, as for a service that fails its
+// first health check.
+func (b *breaker) trip(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.Threshold >= 0 {
+		b.open(b.state(name))
+	}
+}
+
+// open opens s's circuit. b.mu is held.
+func (b *breaker) open(s *SaturnServiceHealth) {
+	s.Open = true
+	s.CheckAt = b.time().Add(b.cooldown())
+}
+
+// health returns the record for each of services, in their order.
+func (b *breaker) health(services []SaturnService) []SaturnServiceHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result := make([]SaturnServiceHealth, len(services))
+	for i, svc := range services {
+		result[i] = *b.state(svc.Name)
+	}
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 )
@@ -19,6 +20,49 @@ func CreateDiscoverer(cache *ServiceCache) Discoverer {
 	return NewZeroconfDiscoverer(cache)
 }
 
+// WarmDiscovery browses for services once, fills the shared service cache
+// and keeps refreshing it in the background until StopWarmDiscovery, so
+// providers created with SaturnConfig.Cached connect straight away instead
+// of each browsing the network. Services that appear later are picked up
+// by the refresh.
+func WarmDiscovery(ctx context.Context, timeout time.Duration) ([]SaturnService, error) {
+	browse := func(ctx context.Context) ([]SaturnService, error) {
+		return NewZeroconfDiscoverer(nil).Discover(ctx, timeout)
+	}
+	services, err := browse(ctx)
+	if err == nil {
+		globalServiceCache.SetAll(services)
+	}
+	globalServiceCache.StartBackgroundRefresh(browse)
+	return services, err
+}
+
+// DiscoverShared browses for services, or returns the ones already in the
+// shared cache, and leaves them there for providers created with
+// SaturnConfig.Cached, so a caller that looks at the services first
+// doesn't make the provider browse again.
+func DiscoverShared(ctx context.Context, timeout time.Duration) ([]SaturnService, error) {
+	return CreateDiscoverer(globalServiceCache).Discover(ctx, timeout)
+}
+
+// StopWarmDiscovery stops the refresh WarmDiscovery started.
+func StopWarmDiscovery() {
+	globalServiceCache.StopBackgroundRefresh()
+}
+
+// WarmServices returns the unexpired services in the shared cache, best
+// priority first.
+func WarmServices() []SaturnService {
+	services := globalServiceCache.GetAll()
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Priority != services[j].Priority {
+			return services[i].Priority < services[j].Priority
+		}
+		return services[i].Name < services[j].Name
+	})
+	return services
+}
+
 func createPooledTransport() *http.Transport {
 	return &http.Transport{
 		MaxIdleConns:        100,
@@ -223,7 +267,9 @@ func (c *ServiceCache) refreshIfNeeded() {
 		}
 	}
 
-	if !hasValidEntries && len(c.services) > 0 {
+	// An empty or fully expired cache is refreshed too, so services that
+	// start later are found.
+	if !hasValidEntries {
 		needsRefresh = true
 	}
 	c.mu.RUnlock()
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Capabilities describes what a provider's service and current model
+// support, so callers can adapt instead of failing mid-request (see Adapt).
+type Capabilities struct {
+	Streaming  bool // ChatStream yields deltas as they are generated
+	Tools      bool // native tool calling
+	Vision     bool // image input
+	JSONMode   bool // response_format: json_object and json_schema
+	Reasoning  bool // accepts a thinking budget
+	MaxContext int  // context window in tokens; 0 when unknown
+}
+
+// Tool-calling modes, for SaturnConfig.ToolCalling. The empty string is
+// ToolCallingAuto.
+const (
+	ToolCallingAuto     = "auto"     // as the service advertises
+	ToolCallingNative   = "native"   // always send tools in the request
+	ToolCallingEmulated = "emulated" // always describe tools in the prompt
+)
+
+// ValidToolCalling reports whether mode is a tool-calling mode.
+func ValidToolCalling(mode string) bool {
+	switch mode {
+	case "", ToolCallingAuto, ToolCallingNative, ToolCallingEmulated:
+		return true
+	}
+	return false
+}
+
+// withToolCalling overrides what was detected about tool calling when
+// mode forces it, for servers that claim support their model lacks, such
+// as llama.cpp without a tool-aware chat template.
+func (c Capabilities) withToolCalling(mode string) Capabilities {
+	switch mode {
+	case ToolCallingNative:
+		c.Tools = true
+	case ToolCallingEmulated:
+		c.Tools = false
+	}
+	return c
+}
+
+// ServiceCapabilities reads a service's capabilities from the features its
+// beacon advertises, e.g. "streaming,tools,vision,json,reasoning,context=32768".
+// A service that advertises no features is assumed to be a plain
+// OpenAI-compatible server: streaming and tools, nothing else.
+func ServiceCapabilities(svc SaturnService) Capabilities {
+	if len(svc.Features) == 0 {
+		return Capabilities{Streaming: true, Tools: true}
+	}
+	var c Capabilities
+	for _, feature := range svc.Features {
+		key, value, _ := strings.Cut(strings.ToLower(strings.TrimSpace(feature)), "=")
+		switch key {
+		case "streaming", "stream":
+			c.Streaming = true
+		case "tools", "tool_calling", "function_calling", "functions":
+			c.Tools = true
+		case "vision", "images":
+			c.Vision = true
+		case "json", "json_mode", "response_format":
+			c.JSONMode = true
+		case "reasoning", "thinking":
+			c.Reasoning = true
+		case "context", "max_context", "context_length":
+			c.MaxContext, _ = strconv.Atoi(value)
+		}
+	}
+	return c
+}
+
+// modelListTTL is how long ListModels answers from the list it fetched
+// last, so opening /models again or checking capabilities doesn't ask the
+// service each time, while models loaded since still show up soon.
+const modelListTTL = time.Minute
+
+// modelMetadata remembers what ListModels reported about each model, for
+// Capabilities, and the list itself for modelListTTL.
+type modelMetadata struct {
+	mu      sync.Mutex
+	models  map[string]ModelInfo
+	list    []ModelInfo
+	fetched time.Time
+}
+
+func (m *modelMetadata) remember(models []ModelInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.models == nil {
+		m.models = make(map[string]ModelInfo, len(models))
+	}
+	for _, model := range models {
+		m.models[model.ID] = model
+	}
+	m.list = slices.Clone(models)
+	m.fetched = time.Now()
+}
+
+// cached returns the list remember was given, if it was less than
+// modelListTTL ago.
+func (m *modelMetadata) cached() ([]ModelInfo, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fetched.IsZero() || time.Since(m.fetched) > modelListTTL {
+		return nil, false
+	}
+	return slices.Clone(m.list), true
+}
+
+// apply refines c with what is known about model. Servers that list a
+// model's input modalities or supported parameters know better than the
+// beacon's features, which cover every model the service hosts.
+func (m *modelMetadata) apply(c Capabilities, model string) Capabilities {
+	m.mu.Lock()
+	info, ok := m.models[model]
+	m.mu.Unlock()
+	if !ok {
+		return c
+	}
+	if info.ContextLength > 0 {
+		c.MaxContext = info.ContextLength
+	}
+	if len(info.InputModalities) > 0 {
+		c.Vision = slices.Contains(info.InputModalities, "image")
+	}
+	if len(info.SupportedParameters) > 0 {
+		c.Tools = slices.Contains(info.SupportedParameters, "tools")
+		c.JSONMode = slices.Contains(info.SupportedParameters, "response_format")
+		c.Reasoning = slices.Contains(info.SupportedParameters, "reasoning")
+	}
+	return c
+}
+
+// Capabilities reports what the service advertises, refined by metadata
+// about the current model once ListModels has fetched it.
+func (s *Saturn) Capabilities() Capabilities {
+	return s.meta.apply(ServiceCapabilities(*s.service), s.model).withToolCalling(s.toolCalling)
+}
+
+// Capabilities reports what every service in the pool supports, since a
+// request may go to any of them.
+func (p *SaturnPool) Capabilities() Capabilities {
+	services := p.GetServices()
+	if len(services) == 0 {
+		return Capabilities{}
+	}
+	c := ServiceCapabilities(services[0])
+	for _, svc := range services[1:] {
+		other := ServiceCapabilities(svc)
+		c.Streaming = c.Streaming && other.Streaming
+		c.Tools = c.Tools && other.Tools
+		c.Vision = c.Vision && other.Vision
+		c.JSONMode = c.JSONMode && other.JSONMode
+		c.Reasoning = c.Reasoning && other.Reasoning
+		if c.MaxContext == 0 || (other.MaxContext > 0 && other.MaxContext < c.MaxContext) {
+			c.MaxContext = other.MaxContext
+		}
+	}
+	return p.meta.apply(c, p.model).withToolCalling(p.toolCalling)
+}
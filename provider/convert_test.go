@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var mixedConversation = []Message{
+	{Role: "user", Content: "list the files"},
+	{Role: "assistant", Content: "Let me look.", ToolCalls: []ToolCall{
+		{ID: "call_1", Name: "list_files", Input: json.RawMessage(`{"path":"."}`)},
+	}},
+	{Role: "user", Content: "also check README", ToolResults: []ToolResult{
+		{ID: "call_1", Content: `["main.go"]`},
+	}},
+	{Role: "user", ToolResults: []ToolResult{
+		{ID: "call_2", Content: "boom", IsError: true},
+	}},
+}
+
+func TestMessageParts(t *testing.T) {
+	parts := mixedConversation[2].Parts()
+	if len(parts) != 2 || parts[0].ToolResult == nil || parts[1].Text != "also check README" {
+		t.Errorf("user parts = %+v, want tool result then text", parts)
+	}
+	parts = mixedConversation[1].Parts()
+	if len(parts) != 2 || parts[0].Text != "Let me look." || parts[1].ToolCall == nil {
+		t.Errorf("assistant parts = %+v, want text then tool call", parts)
+	}
+	if parts := (Message{Role: "assistant"}).Parts(); len(parts) != 0 {
+		t.Errorf("empty message parts = %+v", parts)
+	}
+}
+
+func TestConvertToOpenAIMessagesMixedContent(t *testing.T) {
+	got := convertToOpenAIMessages("sys", mixedConversation)
+
+	type row struct{ role, content, toolCallID string }
+	var rows []row
+	for _, m := range got {
+		content, _ := m.Content.(string)
+		rows = append(rows, row{m.Role, content, m.ToolCallID})
+	}
+	want := []row{
+		{"system", "sys", ""},
+		{"user", "list the files", ""},
+		{"assistant", "Let me look.", ""},
+		{"tool", `["main.go"]`, "call_1"},
+		{"user", "also check README", ""},
+		{"tool", "boom", "call_2"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("messages =\n%+v\nwant\n%+v", rows, want)
+	}
+	if calls := got[2].ToolCalls; len(calls) != 1 || calls[0].Function.Name != "list_files" || calls[0].Function.Arguments != `{"path":"."}` {
+		t.Errorf("assistant tool calls = %+v", calls)
+	}
+}
+
+func TestConvertToAnthropicMessages(t *testing.T) {
+	data, err := json.Marshal(convertToAnthropicMessages(mixedConversation))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []struct {
+		Role    string `json:"role"`
+		Content []struct {
+			Type      string          `json:"type"`
+			Text      string          `json:"text"`
+			ID        string          `json:"id"`
+			Input     json.RawMessage `json:"input"`
+			ToolUseID string          `json:"tool_use_id"`
+			IsError   bool            `json:"is_error"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d messages, want 4: %s", len(got), data)
+	}
+
+	assistant := got[1]
+	if assistant.Role != "assistant" || len(assistant.Content) != 2 ||
+		assistant.Content[0].Type != "text" || assistant.Content[1].Type != "tool_use" ||
+		string(assistant.Content[1].Input) != `{"path":"."}` {
+		t.Errorf("assistant message = %+v", assistant)
+	}
+
+	mixed := got[2]
+	if mixed.Role != "user" || len(mixed.Content) != 2 ||
+		mixed.Content[0].Type != "tool_result" || mixed.Content[0].ToolUseID != "call_1" ||
+		mixed.Content[1].Type != "text" || mixed.Content[1].Text != "also check README" {
+		t.Errorf("mixed user message = %+v", mixed)
+	}
+
+	if errResult := got[3].Content[0]; !errResult.IsError {
+		t.Errorf("error result lost is_error: %+v", errResult)
+	}
+}
+
+func TestConvertImages(t *testing.T) {
+	shot := Image{MediaType: "image/png", Data: "iVBORw0KGgo="}
+	messages := []Message{
+		{Role: "user", Content: "what is wrong here?", Images: []Image{shot}},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "read_image", Input: json.RawMessage(`{"path":"ui.png"}`)}}},
+		{Role: "user", ToolResults: []ToolResult{{ID: "call_1", Content: "ui.png is attached.", Images: []Image{shot}}}},
+	}
+
+	// OpenAI: images as content parts, and a tool's images in a user
+	// message after its result, since tool messages only take text.
+	got := convertToOpenAIMessages("", messages)
+	data, _ := json.Marshal(got)
+	var openAI []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	json.Unmarshal(data, &openAI)
+	if len(openAI) != 5 || openAI[3].Role != "tool" || openAI[4].Role != "user" {
+		t.Fatalf("messages = %s", data)
+	}
+	wantFirst := `[{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}},{"type":"text","text":"what is wrong here?"}]`
+	if string(openAI[1].Content) != wantFirst {
+		t.Errorf("user message content = %s", openAI[1].Content)
+	}
+	if c := string(openAI[4].Content); !strings.Contains(c, `"image_url"`) || !strings.Contains(c, "images the tools above returned") {
+		t.Errorf("tool images message = %s", c)
+	}
+
+	// Anthropic: an image block before the text, and the tool's image
+	// inside its result.
+	data, _ = json.Marshal(convertToAnthropicMessages(messages))
+	var anthropic []struct {
+		Content []struct {
+			Type    string `json:"type"`
+			Content []struct {
+				Type string `json:"type"`
+			} `json:"content"`
+		} `json:"content"`
+	}
+	json.Unmarshal(data, &anthropic)
+	if first := anthropic[0].Content; len(first) != 2 || first[0].Type != "image" || first[1].Type != "text" {
+		t.Errorf("first message = %s", data)
+	}
+	if result := anthropic[2].Content[0]; result.Type != "tool_result" || len(result.Content) != 2 || result.Content[1].Type != "image" {
+		t.Errorf("tool result = %s", data)
+	}
+
+	// Without vision, images become notes and the originals are untouched.
+	stripped := withoutImages(messages)
+	if stripped[0].Images != nil || !strings.Contains(stripped[0].Content, "left out") ||
+		stripped[2].ToolResults[0].Images != nil || !strings.Contains(stripped[2].ToolResults[0].Content, "left out") {
+		t.Errorf("stripped = %+v", stripped)
+	}
+	if messages[0].Images == nil || messages[2].ToolResults[0].Images == nil {
+		t.Error("withoutImages changed the caller's messages")
+	}
+}
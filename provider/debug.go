@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Request is a provider call that has not finished yet.
+type Request struct {
+	Kind    string // "chat", "stream", "models" or "discover"
+	Service string // service name, or the discovery method for "discover"
+	Model   string
+	Started time.Time
+}
+
+var inFlight = struct {
+	sync.Mutex
+	next uint64
+	reqs map[uint64]Request
+}{reqs: map[uint64]Request{}}
+
+// track records a request as in flight until the returned function is
+// called. It is safe to call the function more than once.
+func track(kind, service, model string) (done func()) {
+	inFlight.Lock()
+	inFlight.next++
+	id := inFlight.next
+	inFlight.reqs[id] = Request{Kind: kind, Service: service, Model: model, Started: time.Now()}
+	inFlight.Unlock()
+
+	return func() {
+		inFlight.Lock()
+		delete(inFlight.reqs, id)
+		inFlight.Unlock()
+	}
+}
+
+// InFlight returns the requests currently in progress, oldest first.
+func InFlight() []Request {
+	inFlight.Lock()
+	reqs := make([]Request, 0, len(inFlight.reqs))
+	for _, r := range inFlight.reqs {
+		reqs = append(reqs, r)
+	}
+	inFlight.Unlock()
+
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].Started.Before(reqs[j].Started) })
+	return reqs
+}
+
+// LimiterState is a snapshot of the shared rate limiter for one service.
+type LimiterState struct {
+	Service  string
+	Limit    RateLimit
+	Active   int     // requests holding a concurrency slot
+	Requests float64 // request budget left as of the last request
+	Tokens   float64 // token budget left as of the last request
+}
+
+// Limiters returns the state of every rate-limited service contacted so far.
+func Limiters() []LimiterState {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+
+	states := []LimiterState{}
+	for key, l := range activeLimiters {
+		if l == nil {
+			continue
+		}
+		l.mu.Lock()
+		state := LimiterState{
+			Service:  key,
+			Limit:    l.limit,
+			Active:   len(l.slots),
+			Requests: l.requests,
+			Tokens:   l.tokens,
+		}
+		l.mu.Unlock()
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Service < states[j].Service })
+	return states
+}
+
+// CachedServices returns how many discovered services are cached.
+func CachedServices() int {
+	return globalServiceCache.Size()
+}
@@ -9,6 +9,10 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"brutus/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type SaturnService struct {
@@ -49,10 +53,10 @@ func (s SaturnService) LoadFraction() float64 {
 	return float64(s.CurrentLoad) / float64(s.MaxConcurrent)
 }
 
+// SelectBestService returns the healthy service that balances priority
+// and load best, or nil if there is none.
 func SelectBestService(services []SaturnService) *SaturnService {
-	if len(services) == 0 {
-		return nil
-	}
+	weights := ScoreWeights{Priority: 0.6, Load: 0.4}
 
 	var best *SaturnService
 	bestScore := -1.0
@@ -64,14 +68,7 @@ func SelectBestService(services []SaturnService) *SaturnService {
 			continue
 		}
 
-		priorityScore := float64(100-svc.Priority) / 100.0
-		loadScore := 1.0 - svc.LoadFraction()
-		if loadScore < 0 {
-			loadScore = 0
-		}
-
-		score := priorityScore*0.6 + loadScore*0.4
-
+		score := weights.Score(scoreInput(*svc, ""))
 		if score > bestScore {
 			bestScore = score
 			best = svc
@@ -88,7 +85,14 @@ func (s SaturnService) URL() string {
 	return fmt.Sprintf("http://%s:%d", s.Host, s.Port)
 }
 
-func DiscoverSaturn(ctx context.Context, timeout time.Duration) ([]SaturnService, error) {
+func DiscoverSaturn(ctx context.Context, timeout time.Duration) (services []SaturnService, err error) {
+	ctx, span := telemetry.Start(ctx, "saturn.discover", attribute.String("brutus.discovery.method", "dns-sd"))
+	defer func() {
+		span.SetAttributes(attribute.Int("brutus.discovery.services", len(services)))
+		telemetry.End(span, err)
+	}()
+	defer track("discover", "dns-sd", "")()
+
 	browseCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -100,10 +104,9 @@ func DiscoverSaturn(ctx context.Context, timeout time.Duration) ([]SaturnService
 
 	instances := parseBrowseOutput(stdout.String())
 	if len(instances) == 0 {
-		return nil, fmt.Errorf("no Saturn services found")
+		return nil, ErrNoServices
 	}
 
-	var services []SaturnService
 	for _, instance := range instances {
 		svc, err := resolveInstance(ctx, instance)
 		if err != nil {
@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"brutus/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// BroadcastPort is the UDP port of the broadcast discovery protocol, the
+// fallback for hosts where mDNS is unavailable (Windows without Bonjour,
+// networks that drop multicast). Beacons listen on it for probes.
+const BroadcastPort = 5354
+
+// BroadcastMessage is a packet of the broadcast discovery protocol. Clients
+// broadcast a probe; each beacon answers the sender with one announce per
+// service. TXT carries the same keys as the mDNS TXT records.
+type BroadcastMessage struct {
+	Saturn  string            `json:"saturn"` // "probe" or "announce"
+	Version int               `json:"version"`
+	Name    string            `json:"name,omitempty"`
+	Port    int               `json:"port,omitempty"`
+	TXT     map[string]string `json:"txt,omitempty"`
+}
+
+const broadcastVersion = 1
+
+// maxBroadcastPacket bounds an announce; they fit comfortably in one datagram.
+const maxBroadcastPacket = 8192
+
+// DiscoverBroadcast finds Saturn services by broadcasting a probe on the
+// local networks and collecting announces until timeout.
+func DiscoverBroadcast(ctx context.Context, timeout time.Duration) ([]SaturnService, error) {
+	return discoverBroadcast(ctx, timeout, broadcastTargets(BroadcastPort))
+}
+
+func discoverBroadcast(ctx context.Context, timeout time.Duration, targets []*net.UDPAddr) (services []SaturnService, err error) {
+	ctx, span := telemetry.Start(ctx, "saturn.discover", attribute.String("brutus.discovery.method", "broadcast"))
+	defer func() {
+		span.SetAttributes(attribute.Int("brutus.discovery.services", len(services)))
+		telemetry.End(span, err)
+	}()
+	defer track("discover", "broadcast", "")()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open broadcast socket: %w", err)
+	}
+	defer conn.Close() // the net package sets SO_BROADCAST on UDP sockets
+
+	probe, _ := json.Marshal(BroadcastMessage{Saturn: "probe", Version: broadcastVersion})
+	sent := 0
+	for _, addr := range targets {
+		if _, err := conn.WriteToUDP(probe, addr); err == nil {
+			sent++
+		}
+	}
+	if sent == 0 {
+		return nil, fmt.Errorf("failed to send broadcast probe")
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	seen := make(map[string]bool)
+	buf := make([]byte, maxBroadcastPacket)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("broadcast discovery failed: %w", err)
+		}
+		svc, ok := parseAnnounce(buf[:n], from)
+		if !ok || seen[svc.Name] {
+			continue
+		}
+		seen[svc.Name] = true
+		services = append(services, svc)
+	}
+
+	if len(services) == 0 {
+		return nil, fmt.Errorf("%w via broadcast", ErrNoServices)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Priority < services[j].Priority
+	})
+
+	return services, nil
+}
+
+// parseAnnounce turns an announce packet into a service. The host is the
+// address the announce came from, which is the beacon as seen from here.
+func parseAnnounce(packet []byte, from *net.UDPAddr) (SaturnService, bool) {
+	var msg BroadcastMessage
+	if err := json.Unmarshal(packet, &msg); err != nil || msg.Saturn != "announce" || msg.Name == "" {
+		return SaturnService{}, false
+	}
+
+	svc := SaturnService{
+		Name:     msg.Name,
+		Host:     from.IP.String(),
+		Port:     msg.Port,
+		Priority: 100,
+		APIType:  "openai",
+	}
+	for key, value := range msg.TXT {
+		switch key {
+		case "priority":
+			svc.Priority, _ = strconv.Atoi(value)
+		case "api":
+			svc.APIType = value
+		case "api_base":
+			svc.APIBase = value
+		case "ephemeral_key":
+			svc.EphemeralKey = value
+		case "features":
+			svc.Features = strings.Split(value, ",")
+		case "version", "saturn_version":
+			svc.SaturnVersion = value
+		case "max_concurrent":
+			svc.MaxConcurrent, _ = strconv.Atoi(value)
+		case "current_load":
+			svc.CurrentLoad, _ = strconv.Atoi(value)
+		case "security":
+			svc.Security = value
+		case "health_endpoint":
+			svc.HealthEndpoint = value
+		case "models":
+			svc.Models = strings.Split(value, ",")
+		case "gpu":
+			svc.GPU = value
+		case "vram_gb":
+			svc.VRAMGb, _ = strconv.Atoi(value)
+		}
+	}
+
+	if svc.APIBase == "" && svc.Port == 0 {
+		return SaturnService{}, false
+	}
+	return svc, true
+}
+
+// broadcastTargets returns the limited broadcast address plus the directed
+// broadcast address of every IPv4 interface. Windows sends limited
+// broadcasts out of one interface only, so both are needed on multi-homed
+// hosts.
+func broadcastTargets(port int) []*net.UDPAddr {
+	targets := []*net.UDPAddr{{IP: net.IPv4bcast, Port: port}}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return targets
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip := ipnet.IP.To4()
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+			mask := ipnet.Mask
+			if len(mask) == net.IPv6len {
+				mask = mask[12:]
+			}
+			bcast := make(net.IP, net.IPv4len)
+			for i := range ip {
+				bcast[i] = ip[i] | ^mask[i]
+			}
+			targets = append(targets, &net.UDPAddr{IP: bcast, Port: port})
+		}
+	}
+	return targets
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeBeacon answers broadcast probes on a loopback port with the given
+// announces, each sent twice as a beacon on two interfaces might.
+func fakeBeacon(t *testing.T, announces ...BroadcastMessage) *net.UDPAddr {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, maxBroadcastPacket)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			var probe BroadcastMessage
+			if json.Unmarshal(buf[:n], &probe) != nil || probe.Saturn != "probe" {
+				continue
+			}
+			conn.WriteToUDP([]byte("not json"), from)
+			for _, msg := range announces {
+				msg.Saturn, msg.Version = "announce", broadcastVersion
+				packet, _ := json.Marshal(msg)
+				conn.WriteToUDP(packet, from)
+				conn.WriteToUDP(packet, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestDiscoverBroadcast(t *testing.T) {
+	addr := fakeBeacon(t,
+		BroadcastMessage{Name: "fallback", Port: 8080, TXT: map[string]string{"priority": "50"}},
+		BroadcastMessage{Name: "primary", Port: 11434, TXT: map[string]string{
+			"priority": "10",
+			"features": "streaming,tools",
+			"models":   "llama3.1:8b",
+			"vram_gb":  "24",
+		}},
+		BroadcastMessage{Name: "incomplete"},
+	)
+
+	services, err := discoverBroadcast(context.Background(), 300*time.Millisecond, []*net.UDPAddr{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("got %d services, want 2: %+v", len(services), services)
+	}
+
+	primary := services[0]
+	if primary.Name != "primary" || services[1].Name != "fallback" {
+		t.Fatalf("services not sorted by priority: %+v", services)
+	}
+	if primary.Host != "127.0.0.1" || primary.Port != 11434 {
+		t.Errorf("address = %s:%d, want 127.0.0.1:11434", primary.Host, primary.Port)
+	}
+	if len(primary.Features) != 2 || primary.Models[0] != "llama3.1:8b" || primary.VRAMGb != 24 {
+		t.Errorf("TXT fields not applied: %+v", primary)
+	}
+	if primary.APIType != "openai" {
+		t.Errorf("APIType = %q, want default openai", primary.APIType)
+	}
+}
+
+func TestDiscoverBroadcastNoBeacons(t *testing.T) {
+	addr := fakeBeacon(t)
+	start := time.Now()
+	_, err := discoverBroadcast(context.Background(), 100*time.Millisecond, []*net.UDPAddr{addr})
+	if err == nil {
+		t.Fatal("expected an error when no service answers")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("discovery took %v, want about the timeout", elapsed)
+	}
+}
@@ -10,6 +10,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"brutus/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type LegacyDiscoverer struct {
@@ -48,7 +52,14 @@ func (d *LegacyDiscoverer) DiscoverFiltered(ctx context.Context, timeout time.Du
 	return FilterServices(services, filter), nil
 }
 
-func discoverSaturnDNSSD(ctx context.Context, timeout time.Duration) ([]SaturnService, error) {
+func discoverSaturnDNSSD(ctx context.Context, timeout time.Duration) (services []SaturnService, err error) {
+	ctx, span := telemetry.Start(ctx, "saturn.discover", attribute.String("brutus.discovery.method", "dns-sd"))
+	defer func() {
+		span.SetAttributes(attribute.Int("brutus.discovery.services", len(services)))
+		telemetry.End(span, err)
+	}()
+	defer track("discover", "dns-sd", "")()
+
 	browseCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -60,10 +71,9 @@ func discoverSaturnDNSSD(ctx context.Context, timeout time.Duration) ([]SaturnSe
 
 	instances := parseBrowseOutput(stdout.String())
 	if len(instances) == 0 {
-		return nil, fmt.Errorf("no Saturn services found")
+		return nil, ErrNoServices
 	}
 
-	var services []SaturnService
 	for _, instance := range instances {
 		svc, err := resolveInstance(ctx, instance)
 		if err != nil {
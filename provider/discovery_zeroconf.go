@@ -8,7 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"brutus/telemetry"
+
 	"github.com/grandcat/zeroconf"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ZeroconfDiscoverer struct {
@@ -33,6 +36,10 @@ func (d *ZeroconfDiscoverer) Discover(ctx context.Context, timeout time.Duration
 	services, err := d.discoverZeroconf(ctx, timeout)
 	if err != nil {
 		services, err = d.fallback.Discover(ctx, timeout)
+	}
+	if err != nil {
+		// Neither mDNS route works: no multicast, or Windows without Bonjour.
+		services, err = DiscoverBroadcast(ctx, timeout)
 		if err != nil {
 			return nil, err
 		}
@@ -54,14 +61,20 @@ func (d *ZeroconfDiscoverer) DiscoverFiltered(ctx context.Context, timeout time.
 	return FilterServices(services, filter), nil
 }
 
-func (d *ZeroconfDiscoverer) discoverZeroconf(ctx context.Context, timeout time.Duration) ([]SaturnService, error) {
+func (d *ZeroconfDiscoverer) discoverZeroconf(ctx context.Context, timeout time.Duration) (services []SaturnService, err error) {
+	ctx, span := telemetry.Start(ctx, "saturn.discover", attribute.String("brutus.discovery.method", "zeroconf"))
+	defer func() {
+		span.SetAttributes(attribute.Int("brutus.discovery.services", len(services)))
+		telemetry.End(span, err)
+	}()
+	defer track("discover", "zeroconf", "")()
+
 	resolver, err := zeroconf.NewResolver(nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create zeroconf resolver: %w", err)
 	}
 
 	entries := make(chan *zeroconf.ServiceEntry, 10)
-	var services []SaturnService
 
 	browseCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -86,7 +99,7 @@ func (d *ZeroconfDiscoverer) discoverZeroconf(ctx context.Context, timeout time.
 	<-done
 
 	if len(services) == 0 {
-		return nil, fmt.Errorf("no Saturn services found via zeroconf")
+		return nil, fmt.Errorf("%w via zeroconf", ErrNoServices)
 	}
 
 	sort.Slice(services, func(i, j int) bool {
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultEmbeddingModel is the model embeddings are asked of when none is
+// configured and the service advertises no embedding model of its own.
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// embeddingBatch is how many texts go in one embeddings request.
+const embeddingBatch = 64
+
+// Embedder is implemented by providers whose service can turn text into
+// vectors, with texts of similar meaning near each other. Saturn services
+// and OpenAI-compatible endpoints can; the Anthropic API has no
+// embeddings. Use EmbedderOf to find one behind Adapt or a Fallback.
+type Embedder interface {
+	// Embeddings returns a vector for each of texts, in order.
+	Embeddings(ctx context.Context, texts []string) ([][]float32, error)
+
+	// EmbeddingModel names the model the vectors come from. Vectors from
+	// different models can't be compared.
+	EmbeddingModel() string
+}
+
+// EmbedderOf returns the Embedder p is or wraps. For a Fallback it is the
+// Fallback itself if any of its providers embeds.
+func EmbedderOf(p Provider) (Embedder, bool) {
+	if a, ok := p.(*adapted); ok {
+		p = a.Provider
+	}
+	if f, ok := p.(*Fallback); ok {
+		if _, ok := f.embedder(); !ok {
+			return nil, false
+		}
+		return f, true
+	}
+	e, ok := p.(Embedder)
+	return e, ok
+}
+
+// EmbeddingModel is the configured embedding model, or the first model the
+// service advertises with "embed" in its name, or DefaultEmbeddingModel.
+func (s *Saturn) EmbeddingModel() string {
+	if s.embeddingModel != "" {
+		return s.embeddingModel
+	}
+	for _, m := range s.service.Models {
+		if strings.Contains(strings.ToLower(m), "embed") {
+			return m
+		}
+	}
+	return DefaultEmbeddingModel
+}
+
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embeddings implements Embedder with the OpenAI-compatible embeddings
+// endpoint, in batches.
+func (s *Saturn) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	model := s.EmbeddingModel()
+	defer track("embeddings", s.service.Name, model)()
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatch {
+		batch := texts[start:min(start+embeddingBatch, len(texts))]
+		body, err := json.Marshal(openAIEmbeddingRequest{Model: model, Input: batch})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := send(ctx, s.httpClient, s.retry, func() (*http.Request, error) {
+			httpReq, err := http.NewRequestWithContext(ctx, "POST", s.service.URL()+"/v1/embeddings", bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			httpReq.Header.Set("Content-Type", "application/json")
+			if s.service.EphemeralKey != "" {
+				httpReq.Header.Set("Authorization", "Bearer "+s.service.EphemeralKey)
+			}
+			return httpReq, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		var embResp openAIEmbeddingResponse
+		err = json.NewDecoder(resp.Body).Decode(&embResp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(embResp.Data) != len(batch) {
+			return nil, fmt.Errorf("embeddings: asked for %d, got %d", len(batch), len(embResp.Data))
+		}
+		sort.Slice(embResp.Data, func(i, j int) bool { return embResp.Data[i].Index < embResp.Data[j].Index })
+		for _, d := range embResp.Data {
+			vectors = append(vectors, d.Embedding)
+		}
+	}
+	return vectors, nil
+}
+
+// embedder returns a provider for the pool's first service. Embeddings
+// always go there, as vectors from another service might come from a
+// different model.
+func (p *SaturnPool) embedder() *Saturn {
+	p.mu.RLock()
+	svc := p.services[0]
+	p.mu.RUnlock()
+	return &Saturn{service: &svc, httpClient: p.httpClient, embeddingModel: p.embeddingModel, retry: p.retry}
+}
+
+func (p *SaturnPool) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	return p.embedder().Embeddings(ctx, texts)
+}
+
+func (p *SaturnPool) EmbeddingModel() string {
+	return p.embedder().EmbeddingModel()
+}
+
+// embedder returns the first provider in the chain that embeds. Unlike
+// chat requests, embeddings are not passed on when it fails: vectors from
+// another model would not compare with those it made before.
+func (f *Fallback) embedder() (Embedder, bool) {
+	for _, p := range f.providers {
+		if e, ok := EmbedderOf(p); ok {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+func (f *Fallback) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	e, ok := f.embedder()
+	if !ok {
+		return nil, errors.New("no provider can embed text")
+	}
+	return e.Embeddings(ctx, texts)
+}
+
+func (f *Fallback) EmbeddingModel() string {
+	if e, ok := f.embedder(); ok {
+		return e.EmbeddingModel()
+	}
+	return ""
+}
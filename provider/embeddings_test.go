@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSaturnEmbeddings(t *testing.T) {
+	var inputs []int
+	var model string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		var req openAIEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		model = req.Model
+		inputs = append(inputs, len(req.Input))
+		// Out of order, as some servers answer.
+		fmt.Fprint(w, `{"data":[`)
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			fmt.Fprintf(w, `{"index":%d,"embedding":[%d,1]}`, i, len(req.Input[i]))
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+		}
+		fmt.Fprint(w, `]}`)
+	}))
+	defer srv.Close()
+
+	s := &Saturn{
+		service:    &SaturnService{Name: "s", APIBase: srv.URL + "/v1", Models: []string{"llama3.1:8b", "nomic-embed-text"}},
+		httpClient: http.DefaultClient,
+	}
+	texts := make([]string, embeddingBatch+1)
+	for i := range texts {
+		texts[i] = fmt.Sprint(i)
+	}
+	vectors, err := s.Embeddings(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != len(texts) || vectors[10][0] != 2 || vectors[embeddingBatch][0] != 2 {
+		t.Errorf("vectors not in order: %v", vectors)
+	}
+	if fmt.Sprint(inputs) != fmt.Sprint([]int{embeddingBatch, 1}) || model != "nomic-embed-text" {
+		t.Errorf("sent batches %v with model %q", inputs, model)
+	}
+
+	s.embeddingModel = "bge-m3"
+	if s.EmbeddingModel() != "bge-m3" {
+		t.Errorf("configured embedding model not used")
+	}
+}
+
+func TestEmbedderOf(t *testing.T) {
+	saturn := &Saturn{service: &SaturnService{Name: "s"}}
+	anthropic := &Anthropic{}
+	if _, ok := EmbedderOf(Adapt(anthropic)); ok {
+		t.Error("the Anthropic API can't embed")
+	}
+	e, ok := EmbedderOf(Adapt(NewFallback(anthropic, saturn)))
+	if !ok || e.EmbeddingModel() != DefaultEmbeddingModel {
+		t.Errorf("a Fallback with Saturn in it should embed with Saturn, got %v, %v", e, ok)
+	}
+}
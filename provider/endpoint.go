@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Variables NewSaturn reads a Saturn server's address and key from when its
+// config gives none, for containers, CI and networks that block multicast.
+const (
+	SaturnEndpointEnv = "SATURN_ENDPOINT"
+	SaturnAPIKeyEnv   = "SATURN_API_KEY"
+)
+
+// EndpointService describes the Saturn server at endpoint, such as
+// http://10.0.0.5:8080 or 10.0.0.5:8080, so it can be used without
+// discovery. An endpoint with a path, or over HTTPS, is taken to be an API
+// base such as https://example.com/v1, as a beacon would announce it.
+func EndpointService(endpoint, apiKey string) (SaturnService, error) {
+	raw := strings.TrimSuffix(strings.TrimSpace(endpoint), "/")
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return SaturnService{}, fmt.Errorf("invalid saturn endpoint %q: want e.g. http://host:8080", endpoint)
+	}
+
+	svc := SaturnService{
+		Name:         u.Host,
+		Host:         u.Hostname(),
+		APIType:      "openai",
+		EphemeralKey: apiKey,
+	}
+	if u.Scheme == "https" || u.Path != "" {
+		svc.APIBase = u.String()
+		return svc, nil
+	}
+	svc.Port = 80
+	if p := u.Port(); p != "" {
+		if svc.Port, err = strconv.Atoi(p); err != nil {
+			return SaturnService{}, fmt.Errorf("invalid saturn endpoint %q: bad port", endpoint)
+		}
+	}
+	return svc, nil
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The kinds of provider failure callers handle differently. Errors from
+// discovery and from services wrap one of these where the kind is known,
+// so callers test with errors.Is rather than matching error text.
+var (
+	ErrNoServices       = errors.New("no Saturn services found")
+	ErrAuth             = errors.New("authentication failed")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrContextLength    = errors.New("context length exceeded")
+	ErrModelNotFound    = errors.New("model not found")
+	ErrServerOverloaded = errors.New("server overloaded")
+)
+
+const (
+	// defaultRetryDelay is how long RetryDelay waits when a service is
+	// busy but didn't say for how long.
+	defaultRetryDelay = 2 * time.Second
+
+	// maxRetryDelay caps RetryDelay; a longer wait is better spent on
+	// another service or reported to the user.
+	maxRetryDelay = time.Minute
+)
+
+// APIError is an error response from a service.
+type APIError struct {
+	StatusCode int
+	Message    string        // The error message from the body, or the whole body
+	Kind       error         // One of the Err values above, or nil if unknown
+	RetryAfter time.Duration // From the Retry-After header; 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// newAPIError reads the kind of failure from a non-200 response and its
+// body. Servers word errors differently (OpenAI, vLLM, llama.cpp and
+// Ollama all do), so the status code decides where it can and the
+// message is checked for the rest.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    errorMessage(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	msg := strings.ToLower(e.Message)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		e.Kind = ErrAuth
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	case resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == 529 || strings.Contains(msg, "overloaded"):
+		e.Kind = ErrServerOverloaded
+	case isContextLength(msg):
+		e.Kind = ErrContextLength
+	case strings.Contains(msg, "model") && (strings.Contains(msg, "not found") || strings.Contains(msg, "does not exist")):
+		e.Kind = ErrModelNotFound
+	}
+	return e
+}
+
+// errorMessage pulls the message out of the common JSON error shapes,
+// falling back to the raw body.
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Detail  string          `json:"detail"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		var plain string
+		switch {
+		case json.Unmarshal(parsed.Error, &nested) == nil && nested.Message != "":
+			return nested.Message
+		case json.Unmarshal(parsed.Error, &plain) == nil && plain != "":
+			return plain
+		case parsed.Message != "":
+			return parsed.Message
+		case parsed.Detail != "":
+			return parsed.Detail
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func isContextLength(msg string) bool {
+	for _, phrase := range []string{
+		"context_length_exceeded",
+		"context length",
+		"context window",
+		"maximum context",
+		"prompt is too long",
+		"too many tokens",
+	} {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as an HTTP
+// date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// RetryDelay reports whether err is worth retrying after a wait, because
+// the service was rate limiting or overloaded, and how long to wait. The
+// delay is the service's Retry-After if it gave one, capped at a minute.
+func RetryDelay(err error) (time.Duration, bool) {
+	if !errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrServerOverloaded) {
+		return 0, false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, maxRetryDelay), true
+	}
+	return defaultRetryDelay, true
+}
+
+// failsEverywhere reports whether err would recur on any service, so a pool
+// should not try the request elsewhere.
+func failsEverywhere(err error) bool {
+	return errors.Is(err, ErrContextLength)
+}
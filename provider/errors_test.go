@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewAPIErrorKinds(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{401, `{"error":{"message":"invalid api key"}}`, ErrAuth},
+		{429, `{"error":"slow down"}`, ErrRateLimited},
+		{503, `Service Unavailable`, ErrServerOverloaded},
+		{500, `{"detail":"engine overloaded"}`, ErrServerOverloaded},
+		{400, `{"error":{"message":"This model's maximum context length is 8192 tokens","code":"context_length_exceeded"}}`, ErrContextLength},
+		{400, `{"error":"the request exceeds the available context size, try increasing the context window"}`, ErrContextLength},
+		{404, `{"error":"model \"llama9\" not found, try pulling it first"}`, ErrModelNotFound},
+		{500, `internal error`, nil},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		err := newAPIError(resp, []byte(tt.body))
+		if err.Kind != tt.want {
+			t.Errorf("%d %s: kind %v, want %v", tt.status, tt.body, err.Kind, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	resp := &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": {"7"}}}
+	err := error(newAPIError(resp, nil))
+	if delay, ok := RetryDelay(err); !ok || delay != 7*time.Second {
+		t.Errorf("RetryDelay = %v, %v; want 7s", delay, ok)
+	}
+
+	resp.Header.Set("Retry-After", "3600")
+	if delay, _ := RetryDelay(newAPIError(resp, nil)); delay != maxRetryDelay {
+		t.Errorf("RetryDelay = %v, want the %v cap", delay, maxRetryDelay)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now); got != 30*time.Second {
+		t.Errorf("parseRetryAfter(date) = %v, want 30s", got)
+	}
+
+	if _, ok := RetryDelay(&APIError{StatusCode: 401, Kind: ErrAuth}); ok {
+		t.Error("RetryDelay allows retrying an auth failure")
+	}
+}
+
+func TestPoolStopsOnContextLength(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"prompt is too long"}}`))
+	}))
+	defer srv.Close()
+
+	p := &SaturnPool{
+		services: []SaturnService{
+			{Name: "a", APIBase: srv.URL + "/v1"},
+			{Name: "b", APIBase: srv.URL + "/v1"},
+		},
+		httpClient: srv.Client(),
+	}
+	_, err := p.Chat(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil)
+	if !errors.Is(err, ErrContextLength) {
+		t.Fatalf("err = %v, want ErrContextLength", err)
+	}
+	if calls != 1 {
+		t.Errorf("%d services tried, want 1", calls)
+	}
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Pool routing modes, for SaturnPoolConfig.Routing.
+const (
+	RouteLoad       = "load"        // to the service with the most free capacity; the default
+	RouteRoundRobin = "round-robin" // to each service in turn
+)
+
+// loadRefreshInterval is how often a pool routing by load health checks
+// its services for their current load.
+const loadRefreshInterval = 10 * time.Second
+
+var loads = struct {
+	sync.Mutex
+	byService map[string]reportedLoad
+}{byService: map[string]reportedLoad{}}
+
+type reportedLoad struct {
+	current, max int
+}
+
+// recordLoad notes the load a service reported from its health endpoint.
+func recordLoad(service string, current, max int) {
+	loads.Lock()
+	defer loads.Unlock()
+	loads.byService[service] = reportedLoad{current, max}
+}
+
+// ReportedLoad returns the requests the named service last said it was
+// serving and its capacity, 0 if it didn't say, from a health check in this
+// process.
+func ReportedLoad(service string) (current, max int, ok bool) {
+	loads.Lock()
+	defer loads.Unlock()
+	l, ok := loads.byService[service]
+	return l.current, l.max, ok
+}
+
+// readHealthLoad records the load in a health check's body, for servers
+// that report it as current_load and max_concurrent, like the TXT record.
+func readHealthLoad(service string, body io.Reader) {
+	var health struct {
+		CurrentLoad   *int `json:"current_load"`
+		MaxConcurrent int  `json:"max_concurrent"`
+	}
+	if json.NewDecoder(io.LimitReader(body, 64<<10)).Decode(&health) == nil && health.CurrentLoad != nil {
+		recordLoad(service, *health.CurrentLoad, health.MaxConcurrent)
+	}
+}
+
+// poolLoad counts a pool's own requests in flight on each service and when
+// the services' loads were last refreshed.
+type poolLoad struct {
+	mu         sync.Mutex
+	inFlight   map[string]int
+	refreshed  time.Time
+	refreshing bool
+}
+
+// start counts a request to the named service until done is called.
+func (l *poolLoad) start(name string) (done func()) {
+	l.mu.Lock()
+	if l.inFlight == nil {
+		l.inFlight = map[string]int{}
+	}
+	l.inFlight[name]++
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.inFlight[name]--
+			l.mu.Unlock()
+		})
+	}
+}
+
+// count returns the requests in flight to the named service.
+func (l *poolLoad) count(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight[name]
+}
+
+// until passes stream on and calls done once it closes.
+func until(stream <-chan StreamDelta, done func()) <-chan StreamDelta {
+	ch := make(chan StreamDelta, cap(stream))
+	go func() {
+		defer close(ch)
+		defer done()
+		for delta := range stream {
+			ch <- delta
+		}
+	}()
+	return ch
+}
+
+// refresh health checks services in the background, recording the loads
+// they report, unless that was done in the last loadRefreshInterval or is
+// under way. Services behind a remote API aren't checked.
+func (l *poolLoad) refresh(services []*SaturnService) {
+	l.mu.Lock()
+	if l.refreshing || time.Since(l.refreshed) < loadRefreshInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.refreshing = true
+	l.mu.Unlock()
+
+	checked := make([]SaturnService, len(services))
+	for i, svc := range services {
+		checked[i] = *svc
+	}
+	go func() {
+		for _, svc := range checked {
+			healthCheck(svc)
+		}
+		l.mu.Lock()
+		l.refreshed = time.Now()
+		l.refreshing = false
+		l.mu.Unlock()
+	}()
+}
+
+// freeCapacity is how many more requests svc can take by the freshest
+// account of its load: what its health endpoint reported, else the cached
+// TXT record, else what it announced when discovered, and never less than
+// the pool's own requests to it. A service that doesn't give its capacity
+// is taken to serve one request at a time, as llama.cpp does by default.
+func (l *poolLoad) freeCapacity(svc SaturnService) int {
+	current, capacity := svc.CurrentLoad, svc.MaxConcurrent
+	if cached, ok := globalServiceCache.Get(svc.Name); ok && cached.MaxConcurrent > 0 {
+		current, capacity = cached.CurrentLoad, cached.MaxConcurrent
+	}
+	if c, m, ok := ReportedLoad(svc.Name); ok {
+		current = c
+		if m > 0 {
+			capacity = m
+		}
+	}
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return capacity - max(current, l.count(svc.Name))
+}
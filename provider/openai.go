@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenAIConfig points an OpenAI provider at an endpoint.
+type OpenAIConfig struct {
+	BaseURL        string // e.g. "https://openrouter.ai/api/v1" or "http://localhost:1234/v1"
+	APIKey         string // Defaults to OPENAI_API_KEY; local servers usually need none
+	Model          string
+	MaxTokens      int
+	ToolCalling    string      // ToolCallingAuto, ToolCallingNative or ToolCallingEmulated
+	ThinkingBudget int         // Tokens the model may think for, where supported; 0 is off
+	Features       []string    // What the endpoint supports, as a beacon would announce it; empty is streaming and tools
+	Retry          RetryPolicy // How failed requests are retried; the zero value doesn't
+	Options        ChatOptions // Sampling parameters; WithOptions overrides them per request
+	EmbeddingModel string      // Model for Embeddings; see Saturn.EmbeddingModel
+}
+
+// OpenAI talks to any OpenAI-compatible endpoint given by URL: OpenRouter,
+// vLLM, LM Studio, llama.cpp and the like, with no discovery involved. It
+// is a Saturn provider with the endpoint standing in for a discovered
+// service, so requests, streaming and capabilities work the same.
+type OpenAI struct {
+	*Saturn
+}
+
+// NewOpenAI creates a provider for the endpoint at cfg.BaseURL.
+func NewOpenAI(cfg OpenAIConfig) (*OpenAI, error) {
+	if !ValidToolCalling(cfg.ToolCalling) {
+		return nil, fmt.Errorf("unknown tool calling mode %q (want auto, native or emulated)", cfg.ToolCalling)
+	}
+	u, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: want e.g. http://localhost:1234/v1", cfg.BaseURL)
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	// URL() appends /v1 to a service's address, so the endpoint's base
+	// goes in APIBase, which has it trimmed.
+	svc := SaturnService{
+		Name:         u.Host,
+		Host:         u.Hostname(),
+		APIType:      "openai",
+		EphemeralKey: cfg.APIKey,
+		APIBase:      u.String(),
+		Features:     cfg.Features,
+	}
+	return &OpenAI{&Saturn{
+		service:    &svc,
+		httpClient: &http.Client{Timeout: 120 * time.Second},
+		model:      cfg.Model,
+		maxTokens:  cfg.MaxTokens,
+
+		toolCalling:    cfg.ToolCalling,
+		thinkingBudget: cfg.ThinkingBudget,
+		retry:          cfg.Retry,
+		options:        cfg.Options,
+		embeddingModel: cfg.EmbeddingModel,
+	}}, nil
+}
+
+func (o *OpenAI) Name() string {
+	return fmt.Sprintf("openai(%s)", o.service.Name)
+}
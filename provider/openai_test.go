@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIBaseURL(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hi there"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	for _, base := range []string{srv.URL, srv.URL + "/v1", srv.URL + "/v1/"} {
+		o, err := NewOpenAI(OpenAIConfig{BaseURL: base, APIKey: "sk-test", Model: "m"})
+		if err != nil {
+			t.Fatalf("%s: %v", base, err)
+		}
+		msg, err := o.Chat(context.Background(), "", []Message{{Role: "user", Content: "hello"}}, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", base, err)
+		}
+		if msg.Content != "hi there" {
+			t.Errorf("%s: got %q", base, msg.Content)
+		}
+		if gotPath != "/v1/chat/completions" || gotAuth != "Bearer sk-test" {
+			t.Errorf("%s: request went to %s with %q", base, gotPath, gotAuth)
+		}
+	}
+
+	if _, err := NewOpenAI(OpenAIConfig{BaseURL: "localhost:1234"}); err == nil {
+		t.Error("expected an error for a base URL without a scheme")
+	}
+}
+
+func TestSaturnEndpoint(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hi there"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	t.Setenv(SaturnAPIKeyEnv, "sk-env")
+	t.Setenv(SaturnEndpointEnv, srv.URL)
+	s, err := NewSaturn(context.Background(), SaturnConfig{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Chat(context.Background(), "", []Message{{Role: "user", Content: "hello"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/v1/chat/completions" || gotAuth != "Bearer sk-env" {
+		t.Errorf("request went to %s with %q", gotPath, gotAuth)
+	}
+
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"10.0.0.5:8080", "http://10.0.0.5:8080"},
+		{"http://saturn.lan/", "http://saturn.lan:80"},
+		{"https://example.com/api/v1", "https://example.com/api"},
+	}
+	for _, tt := range tests {
+		svc, err := EndpointService(tt.endpoint, "")
+		if err != nil {
+			t.Errorf("%s: %v", tt.endpoint, err)
+		} else if svc.URL() != tt.want {
+			t.Errorf("%s: URL() = %q, want %q", tt.endpoint, svc.URL(), tt.want)
+		}
+	}
+	for _, bad := range []string{"", "ftp://host", "http://host:port"} {
+		if _, err := EndpointService(bad, ""); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package provider
+
+import "context"
+
+// ChatOptions are the sampling parameters sent with a request. Nil and
+// empty fields are left out, so the service's defaults for the model apply.
+// The Anthropic API takes no seed or penalties; they are dropped there.
+type ChatOptions struct {
+	Temperature      *float64
+	TopP             *float64
+	Stop             []string // Sequences that end the response
+	Seed             *int     // For repeatable sampling, where the service supports it
+	FrequencyPenalty *float64
+	PresencePenalty  *float64
+}
+
+// Merge returns o with the fields set in other in place of its own.
+func (o ChatOptions) Merge(other ChatOptions) ChatOptions {
+	if other.Temperature != nil {
+		o.Temperature = other.Temperature
+	}
+	if other.TopP != nil {
+		o.TopP = other.TopP
+	}
+	if other.Stop != nil {
+		o.Stop = other.Stop
+	}
+	if other.Seed != nil {
+		o.Seed = other.Seed
+	}
+	if other.FrequencyPenalty != nil {
+		o.FrequencyPenalty = other.FrequencyPenalty
+	}
+	if other.PresencePenalty != nil {
+		o.PresencePenalty = other.PresencePenalty
+	}
+	return o
+}
+
+type optionsKey struct{}
+
+// WithOptions sets sampling parameters for requests made with ctx, over
+// those the provider was configured with and any set on ctx before.
+func WithOptions(ctx context.Context, opts ChatOptions) context.Context {
+	return context.WithValue(ctx, optionsKey{}, optionsFrom(ctx, ChatOptions{}).Merge(opts))
+}
+
+// optionsFrom returns configured with the options set by WithOptions over it.
+func optionsFrom(ctx context.Context, configured ChatOptions) ChatOptions {
+	if opts, ok := ctx.Value(optionsKey{}).(ChatOptions); ok {
+		return configured.Merge(opts)
+	}
+	return configured
+}
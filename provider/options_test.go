@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSaturnChatOptions(t *testing.T) {
+	var req map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = nil
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	zero, low, seed := 0.0, 0.2, 7
+	s := &Saturn{
+		service:    &SaturnService{Name: "s", APIBase: srv.URL + "/v1"},
+		httpClient: http.DefaultClient,
+		options:    ChatOptions{Temperature: &low, Seed: &seed, Stop: []string{"END"}},
+	}
+	chat := func(ctx context.Context) {
+		t.Helper()
+		if _, err := s.Chat(ctx, "", []Message{{Role: "user", Content: "hi"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chat(context.Background())
+	if req["temperature"] != 0.2 || req["seed"] != 7.0 || fmt.Sprint(req["stop"]) != "[END]" {
+		t.Errorf("configured options not sent: %v", req)
+	}
+	if _, ok := req["top_p"]; ok {
+		t.Errorf("unset top_p was sent: %v", req)
+	}
+
+	chat(WithOptions(context.Background(), ChatOptions{Temperature: &zero}))
+	if temp, ok := req["temperature"]; !ok || temp != 0.0 || req["seed"] != 7.0 {
+		t.Errorf("want temperature 0 from the context over the configured seed, got %v", req)
+	}
+}
@@ -23,7 +23,8 @@ type Provider interface {
 	// Name returns the provider name for logging.
 	Name() string
 
-	// ListModels returns available models from the provider.
+	// ListModels returns available models from the provider. The
+	// built-in providers reuse the list for a minute after fetching it.
 	ListModels(ctx context.Context) ([]ModelInfo, error)
 
 	// SetModel changes the active model.
@@ -31,43 +32,124 @@ type Provider interface {
 
 	// GetModel returns the current model.
 	GetModel() string
+
+	// Capabilities reports what the service and current model support.
+	Capabilities() Capabilities
 }
 
 // ModelInfo describes an available model.
 type ModelInfo struct {
 	ID   string
 	Name string
+
+	// Metadata some servers report; zero when they don't.
+	ContextLength       int      // context window in tokens
+	InputModalities     []string // e.g. "text", "image"
+	SupportedParameters []string // request parameters, e.g. "tools", "response_format"
 }
 
 // Message represents a conversation message.
 // This is a simplified, provider-agnostic format.
 type Message struct {
-	Role        string       // "user" or "assistant"
-	Content     string       // Text content
-	ToolCalls   []ToolCall   // Tools the assistant wants to use
-	ToolResults []ToolResult // Results from tool execution
+	Role        string       `json:"role"`                   // "user" or "assistant"
+	Content     string       `json:"content,omitempty"`      // Text content
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`   // Tools the assistant wants to use
+	ToolResults []ToolResult `json:"tool_results,omitempty"` // Results from tool execution
+
+	// Reasoning is the model's thinking before its answer. It is shown to
+	// the user and kept in transcripts but never sent back to the model.
+	Reasoning string `json:"reasoning,omitempty"`
+
+	// Usage is what the response that produced this message took, when
+	// the service reported it. Only set on assistant messages.
+	Usage *Usage `json:"usage,omitempty"`
+
+	// Images are pictures sent with a user message, such as a screenshot.
+	Images []Image `json:"images,omitempty"`
+}
+
+// Image is a picture in a message, base64 encoded. It converts to and from
+// tools.Image, which tools return images as.
+type Image struct {
+	MediaType string `json:"media_type"` // image/png, image/jpeg, image/gif or image/webp
+	Data      string `json:"data"`
+}
+
+// ToolImages converts the images a tool returned.
+func ToolImages(images []tools.Image) []Image {
+	var result []Image
+	for _, img := range images {
+		result = append(result, Image(img))
+	}
+	return result
+}
+
+// DataURL is the image as a data: URL, the form OpenAI-style APIs take.
+func (img Image) DataURL() string {
+	return "data:" + img.MediaType + ";base64," + img.Data
 }
 
 // ToolCall represents a request from the LLM to execute a tool.
 type ToolCall struct {
-	ID    string          // Unique identifier for this call
-	Name  string          // Tool name
-	Input json.RawMessage // Tool input as JSON
+	ID    string          `json:"id"`    // Unique identifier for this call
+	Name  string          `json:"name"`  // Tool name
+	Input json.RawMessage `json:"input"` // Tool input as JSON
 }
 
 // ToolResult contains the output of a tool execution.
 type ToolResult struct {
-	ID      string // Matches ToolCall.ID
-	Content string // Tool output
-	IsError bool   // Whether the result is an error
+	ID      string  `json:"id"`                 // Matches ToolCall.ID
+	Content string  `json:"content"`            // Tool output
+	IsError bool    `json:"is_error,omitempty"` // Whether the result is an error
+	Images  []Image `json:"images,omitempty"`   // Images the tool returned, such as read_image's
+}
+
+// Part is one block of a message's content: text, an image, a tool call
+// or a tool result. Exactly one field is set.
+type Part struct {
+	Text       string
+	Image      *Image
+	ToolCall   *ToolCall
+	ToolResult *ToolResult
+}
+
+// Parts returns the message's content as ordered blocks. Tool results come
+// before text in a user message and text before tool calls in an assistant
+// message, which is the order both the OpenAI and Anthropic APIs require
+// for a message that mixes them. Images come just before the text, as
+// Anthropic recommends.
+func (m Message) Parts() []Part {
+	parts := make([]Part, 0, 1+len(m.ToolCalls)+len(m.ToolResults)+len(m.Images))
+	for i := range m.ToolResults {
+		parts = append(parts, Part{ToolResult: &m.ToolResults[i]})
+	}
+	for i := range m.Images {
+		parts = append(parts, Part{Image: &m.Images[i]})
+	}
+	if m.Content != "" {
+		parts = append(parts, Part{Text: m.Content})
+	}
+	for i := range m.ToolCalls {
+		parts = append(parts, Part{ToolCall: &m.ToolCalls[i]})
+	}
+	return parts
 }
 
 // StreamDelta represents a chunk from streaming responses.
 type StreamDelta struct {
 	Content  string    // Text content chunk
+	Reasoning string   // Thinking text chunk, kept apart from the answer
 	ToolCall *ToolCall // Partial tool call (accumulated)
-	Error    error     // Error if streaming failed
+	Error    error     // Error if streaming failed; *ToolCallError for unusable arguments
 	Done     bool      // True when stream is complete
+
+	// ToolCalls holds the finished tool calls, with arguments validated
+	// and repaired where needed. Set on the final delta.
+	ToolCalls []ToolCall
+
+	// Usage is what the response took, when the service reported it. Set
+	// on the final delta.
+	Usage *Usage
 }
 
 // DiscoveryFilter specifies criteria for filtering discovered services.
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit caps the requests this process sends to one Saturn service.
+// Zero fields are unlimited.
+type RateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	MaxConcurrent     int `json:"max_concurrent,omitempty"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty"` // Estimated prompt tokens
+}
+
+// IsZero reports whether the limit leaves requests unrestricted.
+func (r RateLimit) IsZero() bool {
+	return r.RequestsPerMinute <= 0 && r.MaxConcurrent <= 0 && r.TokensPerMinute <= 0
+}
+
+// Limiter enforces a RateLimit. Callers over the limit queue in Wait
+// instead of failing, so many agents sharing one GPU server take turns
+// rather than stampeding it.
+type Limiter struct {
+	limit RateLimit
+	slots chan struct{} // nil when concurrency is unlimited
+
+	mu       sync.Mutex
+	requests float64 // request budget available now
+	tokens   float64 // token budget available now
+	updated  time.Time
+}
+
+// NewLimiter creates a limiter starting with a full minute's budget.
+func NewLimiter(limit RateLimit) *Limiter {
+	l := &Limiter{
+		limit:    limit,
+		requests: float64(limit.RequestsPerMinute),
+		tokens:   float64(limit.TokensPerMinute),
+		updated:  time.Now(),
+	}
+	if limit.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	return l
+}
+
+// Wait blocks until a request estimated at tokens prompt tokens may be
+// sent, or ctx is done. The returned function must be called once the
+// request has finished to free its concurrency slot. A nil Limiter never
+// waits.
+func (l *Limiter) Wait(ctx context.Context, tokens int) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	release = func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-l.slots }) }
+	}
+
+	for {
+		delay := l.reserve(tokens)
+		if delay == 0 {
+			return release, nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserve takes budget for one request if enough has accumulated, and
+// otherwise returns how long until it will have.
+func (l *Limiter) reserve(tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(l.updated).Minutes()
+	l.updated = now
+
+	rpm, tpm := float64(l.limit.RequestsPerMinute), float64(l.limit.TokensPerMinute)
+	// A request bigger than a whole minute's budget would wait forever;
+	// let it through once the budget is full.
+	need := min(float64(tokens), tpm)
+
+	var delay time.Duration
+	if rpm > 0 {
+		l.requests = min(rpm, l.requests+elapsed*rpm)
+		if l.requests < 1 {
+			delay = max(delay, minutes((1-l.requests)/rpm))
+		}
+	}
+	if tpm > 0 {
+		l.tokens = min(tpm, l.tokens+elapsed*tpm)
+		if l.tokens < need {
+			delay = max(delay, minutes((need-l.tokens)/tpm))
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	if rpm > 0 {
+		l.requests--
+	}
+	if tpm > 0 {
+		l.tokens -= need
+	}
+	return 0
+}
+
+func minutes(m float64) time.Duration {
+	return max(time.Millisecond, time.Duration(m*float64(time.Minute)))
+}
+
+var (
+	limitsMu       sync.Mutex
+	defaultLimit   RateLimit
+	serviceLimits  map[string]RateLimit
+	activeLimiters = map[string]*Limiter{}
+)
+
+// ConfigureRateLimits sets the limits for requests from this process.
+// perService is keyed by Saturn service name; def applies to every other
+// service. Limiters are shared by everything talking to the same service,
+// such as several GUI agents, and are rebuilt by each call.
+func ConfigureRateLimits(def RateLimit, perService map[string]RateLimit) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	defaultLimit = def
+	serviceLimits = perService
+	activeLimiters = map[string]*Limiter{}
+}
+
+// limiterFor returns the shared limiter for svc, or nil if it is unlimited.
+func limiterFor(svc *SaturnService) *Limiter {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+
+	key := svc.URL()
+	if l, ok := activeLimiters[key]; ok {
+		return l
+	}
+
+	limit, ok := serviceLimits[svc.Name]
+	if !ok {
+		limit = defaultLimit
+	}
+	var l *Limiter
+	if !limit.IsZero() {
+		l = NewLimiter(limit)
+	}
+	activeLimiters[key] = l
+	return l
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterRequestsPerMinute(t *testing.T) {
+	l := NewLimiter(RateLimit{RequestsPerMinute: 60})
+
+	for i := 0; i < 60; i++ {
+		if d := l.reserve(0); d != 0 {
+			t.Fatalf("request %d delayed by %v within the first minute's budget", i, d)
+		}
+	}
+	d := l.reserve(0)
+	if d <= 0 || d > time.Second {
+		t.Errorf("request over budget delayed by %v, want (0, 1s]", d)
+	}
+}
+
+func TestLimiterTokensPerMinute(t *testing.T) {
+	l := NewLimiter(RateLimit{TokensPerMinute: 1000})
+
+	if d := l.reserve(800); d != 0 {
+		t.Fatalf("first request delayed by %v", d)
+	}
+	if d := l.reserve(800); d < 30*time.Second {
+		t.Errorf("second request delayed by %v, want about 36s", d)
+	}
+
+	// Oversized requests are capped at a full budget rather than waiting
+	// forever.
+	big := NewLimiter(RateLimit{TokensPerMinute: 100})
+	if d := big.reserve(5000); d != 0 {
+		t.Errorf("oversized request on a fresh limiter delayed by %v", d)
+	}
+}
+
+func TestLimiterMaxConcurrentQueues(t *testing.T) {
+	l := NewLimiter(RateLimit{MaxConcurrent: 1})
+	ctx := context.Background()
+
+	release, err := l.Wait(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		r, err := l.Wait(ctx, 0)
+		if err == nil {
+			r()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second request ran while the only slot was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	release() // Releasing twice must not free a second slot.
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("queued request never ran after release")
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	l := NewLimiter(RateLimit{MaxConcurrent: 1, RequestsPerMinute: 1})
+	release, err := l.Wait(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx, 0); err == nil {
+		t.Fatal("Wait succeeded past the request budget")
+	}
+
+	// The cancelled waiter must have given its slot back.
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		t.Error("cancelled Wait kept its concurrency slot")
+	}
+}
+
+func TestLimiterForSharedPerService(t *testing.T) {
+	ConfigureRateLimits(RateLimit{MaxConcurrent: 2}, map[string]RateLimit{"free": {}})
+	defer ConfigureRateLimits(RateLimit{}, nil)
+
+	a := &SaturnService{Name: "gpu", Host: "10.0.0.1", Port: 8080}
+	if limiterFor(a) == nil || limiterFor(a) != limiterFor(&SaturnService{Name: "gpu", Host: "10.0.0.1", Port: 8080}) {
+		t.Error("agents talking to the same service should share a limiter")
+	}
+	if limiterFor(&SaturnService{Name: "free", Host: "10.0.0.2", Port: 8080}) != nil {
+		t.Error("service with an empty override should be unlimited")
+	}
+}
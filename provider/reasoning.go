@@ -0,0 +1,122 @@
+package provider
+
+import "strings"
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// openAIReasoning asks for extended thinking, in the form OpenRouter and
+// other OpenAI-compatible servers that support it accept.
+type openAIReasoning struct {
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// reasoningRequest returns the reasoning option for a request limited to
+// maxTokens, or nil when thinking is off, unsupported, or wouldn't leave
+// room for an answer, as with small side requests.
+func reasoningRequest(budget, maxTokens int, caps Capabilities) *openAIReasoning {
+	if budget <= 0 || !caps.Reasoning || (maxTokens > 0 && budget >= maxTokens) {
+		return nil
+	}
+	return &openAIReasoning{MaxTokens: budget}
+}
+
+// splitThinking separates a <think> block that opens a reply, as models
+// such as DeepSeek-R1 and Qwen3 write when the server doesn't parse it out,
+// from the answer that follows.
+func splitThinking(content string) (answer, reasoning string) {
+	rest := strings.TrimLeft(content, " \t\r\n")
+	if !strings.HasPrefix(rest, thinkOpen) {
+		return content, ""
+	}
+	rest = rest[len(thinkOpen):]
+	reasoning, answer, found := strings.Cut(rest, thinkClose)
+	if !found {
+		return "", strings.TrimSpace(rest)
+	}
+	return strings.TrimLeft(answer, " \t\r\n"), strings.TrimSpace(reasoning)
+}
+
+// thinkSplitter does splitThinking for a stream, where the tags may arrive
+// split across chunks.
+type thinkSplitter struct {
+	state   int    // one of the states below
+	pending string // text held back until it is clear which side it is on
+}
+
+const (
+	thinkUndecided = iota // nothing but whitespace or part of <think> yet
+	thinkInside           // in the <think> block
+	thinkAfter            // after </think>, dropping whitespace before the answer
+	thinkAnswer
+)
+
+// feed takes the next chunk of content and returns what of it, and of
+// earlier chunks held back, is answer and what is reasoning.
+func (t *thinkSplitter) feed(chunk string) (answer, reasoning string) {
+	t.pending += chunk
+	for {
+		switch t.state {
+		case thinkUndecided:
+			rest := strings.TrimLeft(t.pending, " \t\r\n")
+			switch {
+			case strings.HasPrefix(rest, thinkOpen):
+				t.state, t.pending = thinkInside, rest[len(thinkOpen):]
+				continue
+			case rest == "" || strings.HasPrefix(thinkOpen, rest):
+				return answer, reasoning // not enough to tell yet
+			}
+			t.state = thinkAnswer
+			continue
+
+		case thinkInside:
+			if i := strings.Index(t.pending, thinkClose); i >= 0 {
+				reasoning += t.pending[:i]
+				t.state = thinkAfter
+				t.pending = t.pending[i+len(thinkClose):]
+				continue
+			}
+			// Hold back what could be the start of the closing tag.
+			keep := partialSuffix(t.pending, thinkClose)
+			reasoning += t.pending[:len(t.pending)-keep]
+			t.pending = t.pending[len(t.pending)-keep:]
+			return answer, reasoning
+
+		case thinkAfter:
+			t.pending = strings.TrimLeft(t.pending, " \t\r\n")
+			if t.pending == "" {
+				return answer, reasoning
+			}
+			t.state = thinkAnswer
+			continue
+
+		default:
+			answer += t.pending
+			t.pending = ""
+			return answer, reasoning
+		}
+	}
+}
+
+// flush returns whatever is still held back when the stream ends.
+func (t *thinkSplitter) flush() (answer, reasoning string) {
+	pending := t.pending
+	t.pending = ""
+	if t.state == thinkInside {
+		return "", pending
+	}
+	return pending, ""
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag.
+func partialSuffix(s, tag string) int {
+	for n := min(len(s), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
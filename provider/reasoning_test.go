@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestThinkSplitter(t *testing.T) {
+	for _, chunks := range [][]string{
+		{"<think>plan the change</think>\n\nDone."},
+		{" <thi", "nk>plan the ", "change</th", "ink>", "\nDone."},
+	} {
+		var splitter thinkSplitter
+		var answer, reasoning string
+		for _, chunk := range append(chunks, "") {
+			a, r := splitter.feed(chunk)
+			answer, reasoning = answer+a, reasoning+r
+		}
+		a, r := splitter.flush()
+		answer, reasoning = answer+a, reasoning+r
+		if answer != "Done." || reasoning != "plan the change" {
+			t.Errorf("%q: got answer %q, reasoning %q", chunks, answer, reasoning)
+		}
+	}
+
+	var splitter thinkSplitter
+	if a, r := splitter.feed("<b>bold</b> answer"); a != "<b>bold</b> answer" || r != "" {
+		t.Errorf("text that doesn't open with <think> is all answer, got %q / %q", a, r)
+	}
+}
+
+func TestSaturnReasoning(t *testing.T) {
+	var req openAIRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"reasoning_content":"The user wants "}}]}`,
+			`{"choices":[{"delta":{"reasoning_content":"a greeting."}}]}`,
+			`{"choices":[{"delta":{"content":"Hello!"},"finish_reason":"stop"}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	}))
+	defer srv.Close()
+
+	s := &Saturn{
+		service:        &SaturnService{Name: "s", APIBase: srv.URL + "/v1", Features: []string{"streaming", "reasoning"}},
+		httpClient:     http.DefaultClient,
+		maxTokens:      4096,
+		thinkingBudget: 1024,
+	}
+	history := []Message{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "Hi.", Reasoning: "Earlier thinking."},
+		{Role: "user", Content: "hello"},
+	}
+	stream, err := s.ChatStream(context.Background(), "", history, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content, reasoning string
+	for delta := range stream {
+		content += delta.Content
+		reasoning += delta.Reasoning
+	}
+
+	if content != "Hello!" || reasoning != "The user wants a greeting." {
+		t.Errorf("got content %q, reasoning %q", content, reasoning)
+	}
+	if req.Reasoning == nil || req.Reasoning.MaxTokens != 1024 {
+		t.Errorf("expected a thinking budget of 1024 in the request, got %+v", req.Reasoning)
+	}
+	body, _ := json.Marshal(req.Messages)
+	if strings.Contains(string(body), "Earlier thinking") {
+		t.Errorf("earlier reasoning was sent back to the model: %s", body)
+	}
+}
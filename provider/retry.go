@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RetryPolicy says how a Saturn provider retries a request that failed for
+// a reason that may pass: a status such as 429 or 503, or no response at
+// all from a server that is restarting. The zero value doesn't retry.
+type RetryPolicy struct {
+	// MaxAttempts counts every attempt, the first included; 0 and 1 make
+	// one.
+	MaxAttempts int
+
+	// BaseDelay is the wait before the first retry, doubled for each one
+	// after it. Each wait is jittered, between half of it and all of it,
+	// so clients that failed together don't retry together. Zero is a
+	// second.
+	BaseDelay time.Duration
+
+	// MaxDelay caps a wait, including one the service asked for. Zero is
+	// a minute.
+	MaxDelay time.Duration
+
+	// StatusCodes are the statuses worth retrying; nil is
+	// DefaultRetryStatusCodes.
+	StatusCodes []int
+
+	// IgnoreRetryAfter backs off as usual even when the service said how
+	// long to wait.
+	IgnoreRetryAfter bool
+}
+
+// DefaultRetryStatusCodes are the statuses a RetryPolicy retries unless
+// told otherwise: rate limiting, and servers that are failing, overloaded
+// or behind a proxy that lost them.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+	529, // Anthropic-style "overloaded"
+}
+
+// DefaultRetryPolicy is what the CLI and GUI use unless configured
+// otherwise.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3}
+
+const defaultBaseDelay = time.Second
+
+// delay reports whether another attempt should follow the given one, which
+// failed with err, and how long to wait before it.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = maxRetryDelay
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		codes := p.StatusCodes
+		if codes == nil {
+			codes = DefaultRetryStatusCodes
+		}
+		if !slices.Contains(codes, apiErr.StatusCode) {
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 && !p.IgnoreRetryAfter {
+			return min(apiErr.RetryAfter, maxDelay), true
+		}
+	}
+
+	base := p.BaseDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+	backoff := base << min(attempt-1, 16)
+	if backoff <= 0 || backoff > maxDelay {
+		backoff = maxDelay
+	}
+	return backoff/2 + rand.N(backoff/2+1), true
+}
+
+// send makes the request newRequest builds until it gets a 200 response or
+// the policy gives up, and returns the response or the last error, an
+// *APIError if the service answered. Each attempt gets a new request, as a
+// body can only be read once.
+func send(ctx context.Context, client *http.Client, policy RetryPolicy, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			if resp.StatusCode == http.StatusOK {
+				return resp, nil
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			err = newAPIError(resp, body)
+		}
+
+		wait, ok := policy.delay(attempt, err)
+		if !ok {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
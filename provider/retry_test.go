@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSaturnRetry(t *testing.T) {
+	var calls atomic.Int32
+	failures := int32(2)
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":{"message":"loading model"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	newSaturn := func(policy RetryPolicy) *Saturn {
+		calls.Store(0)
+		return &Saturn{
+			service:    &SaturnService{Name: "s", APIBase: srv.URL + "/v1"},
+			httpClient: http.DefaultClient,
+			retry:      policy,
+		}
+	}
+	chat := func(s *Saturn) error {
+		_, err := s.Chat(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil)
+		return err
+	}
+
+	if err := chat(newSaturn(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})); err != nil || calls.Load() != 3 {
+		t.Errorf("got %v after %d calls, want success on the third", err, calls.Load())
+	}
+	if err := chat(newSaturn(RetryPolicy{})); !errors.Is(err, ErrServerOverloaded) || calls.Load() != 1 {
+		t.Errorf("the zero policy: got %v after %d calls", err, calls.Load())
+	}
+	if err := chat(newSaturn(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, StatusCodes: []int{502}})); err == nil || calls.Load() != 1 {
+		t.Errorf("a status not listed was retried: %v after %d calls", err, calls.Load())
+	}
+
+	// The stream is retried until it starts.
+	stream, err := newSaturn(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}).ChatStream(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil || calls.Load() != 3 {
+		t.Fatalf("stream: got %v after %d calls", err, calls.Load())
+	}
+	for range stream {
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	busy := &APIError{StatusCode: 429, Kind: ErrRateLimited, RetryAfter: 30 * time.Second}
+	p := RetryPolicy{MaxAttempts: 30, BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+	if d, ok := p.delay(1, busy); !ok || d != 10*time.Second {
+		t.Errorf("Retry-After over the cap: got %v, %t", d, ok)
+	}
+	p.IgnoreRetryAfter = true
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 20: 10 * time.Second} {
+		d, ok := p.delay(attempt, busy)
+		if !ok || d < want/2 || d > want {
+			t.Errorf("attempt %d: got %v, want between %v and %v", attempt, d, want/2, want)
+		}
+	}
+	if _, ok := p.delay(30, busy); ok {
+		t.Error("retried past MaxAttempts")
+	}
+	if _, ok := p.delay(1, &APIError{StatusCode: 400}); ok {
+		t.Error("retried a bad request")
+	}
+	if _, ok := p.delay(1, context.Canceled); ok {
+		t.Error("retried a cancelled request")
+	}
+	if _, ok := p.delay(1, errors.New("connection refused")); !ok {
+		t.Error("did not retry a request that got no response")
+	}
+}
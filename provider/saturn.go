@@ -8,10 +8,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"brutus/telemetry"
 	"brutus/tools"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Saturn implements Provider using Saturn-discovered services.
@@ -22,6 +29,13 @@ type Saturn struct {
 	httpClient *http.Client
 	model      string
 	maxTokens  int
+	meta       modelMetadata
+
+	toolCalling    string
+	thinkingBudget int
+	retry          RetryPolicy
+	options        ChatOptions
+	embeddingModel string
 }
 
 // SaturnConfig holds configuration for Saturn discovery.
@@ -29,25 +43,91 @@ type SaturnConfig struct {
 	DiscoveryTimeout time.Duration // How long to search for services
 	Model            string        // Model to request (if supported)
 	MaxTokens        int
+	Service          string // Use only the service with this name
+	Filter           *DiscoveryFilter
+	ToolCalling      string      // ToolCallingAuto, ToolCallingNative or ToolCallingEmulated
+	ThinkingBudget   int         // Tokens the model may think for, where supported; 0 is off
+	Cached           bool        // Use the services WarmDiscovery found, if any, instead of browsing
+	Scorer           Scorer      // Chooses between the services found; nil is DefaultScorer
+	Retry            RetryPolicy // How failed requests are retried; the zero value doesn't
+	Options          ChatOptions // Sampling parameters; WithOptions overrides them per request
+	EmbeddingModel   string      // Model for Embeddings; see Saturn.EmbeddingModel
+	Sticky           bool        // NewAuto: spread conversations over every service found; see NewSaturnSticky
+
+	// Endpoint is a Saturn server's address, used instead of discovering
+	// services; it defaults to $SATURN_ENDPOINT. APIKey is its key, if it
+	// needs one, defaulting to $SATURN_API_KEY.
+	Endpoint string
+	APIKey   string
 }
 
-// NewSaturn discovers Saturn services and creates a provider.
+// NewSaturn discovers Saturn services and creates a provider, or uses the
+// configured endpoint without discovery.
 // Returns error if no services are found.
 func NewSaturn(ctx context.Context, cfg SaturnConfig) (*Saturn, error) {
+	if !ValidToolCalling(cfg.ToolCalling) {
+		return nil, fmt.Errorf("unknown tool calling mode %q (want auto, native or emulated)", cfg.ToolCalling)
+	}
 	if cfg.DiscoveryTimeout == 0 {
 		cfg.DiscoveryTimeout = 3 * time.Second
 	}
 
-	services, err := DiscoverSaturn(ctx, cfg.DiscoveryTimeout)
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = os.Getenv(SaturnEndpointEnv)
+	}
+	if cfg.Endpoint != "" {
+		return newSaturnEndpoint(cfg)
+	}
+
+	services, err := discoverServices(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("saturn discovery failed: %w", err)
+		return nil, err
+	}
+	return newSaturnFrom(services, cfg), nil
+}
+
+// discoverServices finds the services cfg allows, best first.
+func discoverServices(ctx context.Context, cfg SaturnConfig) ([]SaturnService, error) {
+	var services []SaturnService
+	var err error
+	if cfg.Cached {
+		services = WarmServices()
+	}
+	if len(services) == 0 {
+		services, err = DiscoverSaturn(ctx, cfg.DiscoveryTimeout)
+		if err != nil {
+			services, err = DiscoverBroadcast(ctx, cfg.DiscoveryTimeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("saturn discovery failed: %w", err)
+		}
 	}
 
 	if len(services) == 0 {
-		return nil, fmt.Errorf("no saturn services found on network")
+		return nil, fmt.Errorf("%w on network", ErrNoServices)
 	}
 
-	// Use highest priority (lowest number) service
+	if cfg.Filter != nil {
+		services, err = applyFilter(services, *cfg.Filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Service != "" {
+		services, err = pinService(services, cfg.Service)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Best scoring first, by default the highest priority (lowest number)
+	return RankServices(services, cfg.Scorer, cfg.Model), nil
+}
+
+// newSaturnFrom creates a provider for the first of services that is
+// healthy, or the first if none is.
+func newSaturnFrom(services []SaturnService, cfg SaturnConfig) *Saturn {
 	svc := services[0]
 
 	// Verify service is healthy
@@ -66,9 +146,61 @@ func NewSaturn(ctx context.Context, cfg SaturnConfig) (*Saturn, error) {
 		httpClient: &http.Client{Timeout: 120 * time.Second},
 		model:      cfg.Model,
 		maxTokens:  cfg.MaxTokens,
+
+		toolCalling:    cfg.ToolCalling,
+		thinkingBudget: cfg.ThinkingBudget,
+		retry:          cfg.Retry,
+		options:        cfg.Options,
+		embeddingModel: cfg.EmbeddingModel,
+	}
+}
+
+// newSaturnEndpoint creates a provider for the server at cfg.Endpoint. It is
+// not health checked: a server that isn't up yet, as in a container started
+// alongside it, fails the first request and is retried like any other.
+func newSaturnEndpoint(cfg SaturnConfig) (*Saturn, error) {
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv(SaturnAPIKeyEnv)
+	}
+	svc, err := EndpointService(cfg.Endpoint, cfg.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Saturn{
+		service:    &svc,
+		httpClient: &http.Client{Timeout: 120 * time.Second},
+		model:      cfg.Model,
+		maxTokens:  cfg.MaxTokens,
+
+		toolCalling:    cfg.ToolCalling,
+		thinkingBudget: cfg.ThinkingBudget,
+		retry:          cfg.Retry,
+		options:        cfg.Options,
+		embeddingModel: cfg.EmbeddingModel,
 	}, nil
 }
 
+// applyFilter narrows services to those matching filter.
+func applyFilter(services []SaturnService, filter DiscoveryFilter) ([]SaturnService, error) {
+	matched := FilterServices(services, filter)
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("none of the %d saturn services found match the discovery filter", len(services))
+	}
+	return matched, nil
+}
+
+// pinService narrows services to the one called name.
+func pinService(services []SaturnService, name string) ([]SaturnService, error) {
+	var names []string
+	for _, svc := range services {
+		if svc.Name == name {
+			return []SaturnService{svc}, nil
+		}
+		names = append(names, svc.Name)
+	}
+	return nil, fmt.Errorf("saturn service %q not found (discovered: %s)", name, strings.Join(names, ", "))
+}
+
 func (s *Saturn) Name() string {
 	return fmt.Sprintf("saturn(%s)", s.service.Name)
 }
@@ -86,6 +218,11 @@ func (s *Saturn) GetService() *SaturnService {
 }
 
 func (s *Saturn) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	if models, ok := s.meta.cached(); ok {
+		return models, nil
+	}
+	defer track("models", s.service.Name, s.model)()
+
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", s.service.URL()+"/v1/models", nil)
 	if err != nil {
 		return nil, err
@@ -103,13 +240,23 @@ func (s *Saturn) ListModels(ctx context.Context) ([]ModelInfo, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp, body)
 	}
 
 	var modelsResp struct {
 		Data []struct {
 			ID   string `json:"id"`
 			Name string `json:"name"`
+
+			// OpenRouter's fields, then vLLM's and others' for the
+			// context window.
+			ContextLength int `json:"context_length"`
+			MaxModelLen   int `json:"max_model_len"`
+			ContextWindow int `json:"context_window"`
+			Architecture  struct {
+				InputModalities []string `json:"input_modalities"`
+			} `json:"architecture"`
+			SupportedParameters []string `json:"supported_parameters"`
 		} `json:"data"`
 	}
 
@@ -123,20 +270,43 @@ func (s *Saturn) ListModels(ctx context.Context) ([]ModelInfo, error) {
 		if name == "" {
 			name = m.ID
 		}
-		models = append(models, ModelInfo{ID: m.ID, Name: name})
+		models = append(models, ModelInfo{
+			ID:                  m.ID,
+			Name:                name,
+			ContextLength:       max(m.ContextLength, m.MaxModelLen, m.ContextWindow),
+			InputModalities:     m.Architecture.InputModalities,
+			SupportedParameters: m.SupportedParameters,
+		})
 	}
 
+	s.meta.remember(models)
 	return models, nil
 }
 
 // Chat implements the Provider interface using OpenAI-compatible API.
-func (s *Saturn) Chat(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (Message, error) {
+func (s *Saturn) Chat(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (_ Message, err error) {
+	maxTokens := maxTokensFrom(ctx, s.maxTokens)
+	ctx, span := s.startSpan(ctx, maxTokens)
+	defer func() { telemetry.End(span, err) }()
+	defer track("chat", s.service.Name, s.model)()
+
+	release, err := limiterFor(s.service).Wait(ctx, EstimateTokens(systemPrompt, messages))
+	if err != nil {
+		return Message{}, err
+	}
+	defer release()
+
 	// Build OpenAI-format request
 	req := openAIRequest{
 		Model:     s.model,
-		MaxTokens: s.maxTokens,
+		MaxTokens: maxTokens,
 		Messages:  convertToOpenAIMessages(systemPrompt, messages),
 		Tools:     convertToOpenAITools(toolDefs),
+		Reasoning: reasoningRequest(s.thinkingBudget, maxTokens, s.Capabilities()),
+	}
+	req.sample(optionsFrom(ctx, s.options))
+	if schema, ok := schemaFrom(ctx); ok && s.Capabilities().JSONMode {
+		req.ResponseFormat = &openAIResponseFormat{Type: "json_schema", JSONSchema: &openAIJSONSchema{Name: schema.Name, Schema: schema.Schema}}
 	}
 
 	// Make the API call
@@ -145,80 +315,122 @@ func (s *Saturn) Chat(ctx context.Context, systemPrompt string, messages []Messa
 		return Message{}, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST",
-		s.service.URL()+"/v1/chat/completions",
-		bytes.NewReader(body))
-	if err != nil {
-		return Message{}, err
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	// Use ephemeral key from beacon if available
-	if s.service.EphemeralKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+s.service.EphemeralKey)
-	}
+	// The response isn't streamed, so its first byte is the closest thing
+	// to a first token.
+	traceCtx := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{GotFirstResponseByte: firstTokenFrom(ctx)})
+	resp, err := send(ctx, s.httpClient, s.retry, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(traceCtx, "POST",
+			s.service.URL()+"/v1/chat/completions",
+			bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.httpClient.Do(httpReq)
+		// Use ephemeral key from beacon if available
+		if s.service.EphemeralKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+s.service.EphemeralKey)
+		}
+		return httpReq, nil
+	})
 	if err != nil {
 		return Message{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return Message{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
 	var openAIResp openAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
 		return Message{}, err
 	}
+	if openAIResp.Usage != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", openAIResp.Usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", openAIResp.Usage.CompletionTokens))
+	}
 
-	return convertFromOpenAIResponse(openAIResp), nil
+	msg := convertFromOpenAIResponse(openAIResp)
+	msg.Usage = openAIResp.Usage.toUsage(s.model)
+	return msg, nil
 }
 
-func (s *Saturn) ChatStream(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (<-chan StreamDelta, error) {
+// startSpan starts the span for one chat request to the service.
+func (s *Saturn) startSpan(ctx context.Context, maxTokens int) (context.Context, trace.Span) {
+	return telemetry.Start(ctx, "chat "+s.model,
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.system", "saturn"),
+		attribute.String("gen_ai.request.model", s.model),
+		attribute.Int("gen_ai.request.max_tokens", maxTokens),
+		attribute.String("server.address", s.service.Host),
+		attribute.Int("server.port", s.service.Port),
+		attribute.String("brutus.saturn.service", s.service.Name))
+}
+
+// ChatStream is Chat with the response streamed. Its span ends when the
+// stream does.
+func (s *Saturn) ChatStream(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (_ <-chan StreamDelta, err error) {
+	maxTokens := maxTokensFrom(ctx, s.maxTokens)
+	ctx, span := s.startSpan(ctx, maxTokens)
+	defer func() {
+		if err != nil {
+			telemetry.End(span, err)
+		}
+	}()
 	req := openAIRequest{
 		Model:     s.model,
-		MaxTokens: s.maxTokens,
+		MaxTokens: maxTokens,
 		Messages:  convertToOpenAIMessages(systemPrompt, messages),
 		Tools:     convertToOpenAITools(toolDefs),
 		Stream:    true,
+		Reasoning: reasoningRequest(s.thinkingBudget, maxTokens, s.Capabilities()),
+
+		// Without this, OpenAI and vLLM leave token counts out of streams.
+		StreamOptions: &openAIStreamOptions{IncludeUsage: true},
 	}
+	req.sample(optionsFrom(ctx, s.options))
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST",
-		s.service.URL()+"/v1/chat/completions",
-		bytes.NewReader(body))
+	// The slot is held until the stream finishes, not just until headers
+	// arrive, since that is how long the server is busy.
+	done := track("stream", s.service.Name, s.model)
+	release, err := limiterFor(s.service).Wait(ctx, EstimateTokens(systemPrompt, messages))
 	if err != nil {
+		done()
 		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "text/event-stream")
-	if s.service.EphemeralKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+s.service.EphemeralKey)
-	}
-
-	resp, err := s.httpClient.Do(httpReq)
+	// Only getting the stream started is retried; once text has been
+	// sent, a retry would repeat it.
+	resp, err := send(ctx, s.httpClient, s.retry, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST",
+			s.service.URL()+"/v1/chat/completions",
+			bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "text/event-stream")
+		if s.service.EphemeralKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+s.service.EphemeralKey)
+		}
+		return httpReq, nil
+	})
 	if err != nil {
+		release()
+		done()
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
 	ch := make(chan StreamDelta, 10)
-	go s.processStream(ctx, resp, ch)
-	return ch, nil
+	go func() {
+		defer done()
+		defer release()
+		s.processStream(ctx, resp, ch)
+	}()
+	return traceStream(span, ch), nil
 }
 
 func (s *Saturn) processStream(ctx context.Context, resp *http.Response, ch chan<- StreamDelta) {
@@ -227,6 +439,28 @@ func (s *Saturn) processStream(ctx context.Context, resp *http.Response, ch chan
 
 	reader := bufio.NewReader(resp.Body)
 	var accumulatedToolCalls []ToolCall
+	firstToken := firstTokenFrom(ctx)
+	var thinking thinkSplitter
+
+	// Usage comes in a chunk of its own after the one with the finish
+	// reason, when at all, so the stream is read on until it arrives or
+	// the stream ends.
+	var usage *Usage
+	finished := false
+
+	// send passes content on, with any <think> block routed to Reasoning.
+	send := func(answer, reasoning string) {
+		if reasoning != "" {
+			ch <- StreamDelta{Reasoning: reasoning}
+		}
+		if answer != "" {
+			ch <- StreamDelta{Content: answer}
+		}
+	}
+	finish := func() {
+		send(thinking.flush())
+		ch <- finalDelta(accumulatedToolCalls, usage)
+	}
 
 	for {
 		select {
@@ -241,7 +475,7 @@ func (s *Saturn) processStream(ctx context.Context, resp *http.Response, ch chan
 			if err != io.EOF {
 				ch <- StreamDelta{Error: err, Done: true}
 			} else {
-				ch <- StreamDelta{Done: true}
+				finish()
 			}
 			return
 		}
@@ -257,7 +491,7 @@ func (s *Saturn) processStream(ctx context.Context, resp *http.Response, ch chan
 
 		data := strings.TrimPrefix(line, "data: ")
 		if data == "[DONE]" {
-			ch <- StreamDelta{Done: true}
+			finish()
 			return
 		}
 
@@ -265,15 +499,29 @@ func (s *Saturn) processStream(ctx context.Context, resp *http.Response, ch chan
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.toUsage(s.model)
+			if finished {
+				finish()
+				return
+			}
+		}
 
-		if len(chunk.Choices) == 0 {
+		if len(chunk.Choices) == 0 || finished {
 			continue
 		}
 
 		delta := chunk.Choices[0].Delta
+		reasoning := delta.ReasoningContent + delta.Reasoning
+		if delta.Content != "" || reasoning != "" || len(delta.ToolCalls) > 0 {
+			firstToken()
+		}
 
+		if reasoning != "" {
+			ch <- StreamDelta{Reasoning: reasoning}
+		}
 		if delta.Content != "" {
-			ch <- StreamDelta{Content: delta.Content}
+			send(thinking.feed(delta.Content))
 		}
 
 		for _, tc := range delta.ToolCalls {
@@ -290,49 +538,114 @@ func (s *Saturn) processStream(ctx context.Context, resp *http.Response, ch chan
 				current := string(accumulatedToolCalls[tc.Index].Input)
 				accumulatedToolCalls[tc.Index].Input = json.RawMessage(current + tc.Function.Arguments)
 			}
-			ch <- StreamDelta{ToolCall: &accumulatedToolCalls[tc.Index]}
+			// Send a copy: the accumulated call keeps changing after this.
+			partial := accumulatedToolCalls[tc.Index]
+			ch <- StreamDelta{ToolCall: &partial}
 		}
 
 		if chunk.Choices[0].FinishReason != "" {
-			ch <- StreamDelta{Done: true}
-			return
+			if usage != nil {
+				finish()
+				return
+			}
+			finished = true
 		}
 	}
 }
 
+// finalDelta ends a stream with its finished tool calls and usage, or with
+// a *ToolCallError if one of the calls has arguments that cannot be used.
+func finalDelta(calls []ToolCall, usage *Usage) StreamDelta {
+	finished, err := finishToolCalls(calls)
+	if err != nil {
+		return StreamDelta{Error: err, Done: true}
+	}
+	return StreamDelta{ToolCalls: finished, Usage: usage, Done: true}
+}
+
+// CheckHealth queries the service's health endpoint.
+func (s SaturnService) CheckHealth() error {
+	return healthCheck(s)
+}
+
 func healthCheck(svc SaturnService) error {
 	if svc.APIBase != "" {
 		return nil // Remote APIs don't have health endpoints
 	}
 
 	client := &http.Client{Timeout: 2 * time.Second}
+	start := time.Now()
 	resp, err := client.Get(svc.URL() + "/v1/health")
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	recordLatency(svc.Name, time.Since(start))
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("health check failed: %d", resp.StatusCode)
 	}
+	readHealthLoad(svc.Name, resp.Body)
 	return nil
 }
 
 // OpenAI-compatible types
 
 type openAIRequest struct {
-	Model     string          `json:"model,omitempty"`
-	MaxTokens int             `json:"max_tokens,omitempty"`
-	Messages  []openAIMessage `json:"messages"`
-	Tools     []openAITool    `json:"tools,omitempty"`
-	Stream    bool            `json:"stream,omitempty"`
+	Model     string           `json:"model,omitempty"`
+	MaxTokens int              `json:"max_tokens,omitempty"`
+	Messages  []openAIMessage  `json:"messages"`
+	Tools     []openAITool     `json:"tools,omitempty"`
+	Stream    bool             `json:"stream,omitempty"`
+	Reasoning *openAIReasoning `json:"reasoning,omitempty"`
+
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIResponseFormat constrains a response to JSON matching a schema.
+type openAIResponseFormat struct {
+	Type       string            `json:"type"` // "json_schema"
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+// sample sets the request's sampling parameters.
+func (r *openAIRequest) sample(opts ChatOptions) {
+	r.Temperature = opts.Temperature
+	r.TopP = opts.TopP
+	r.Stop = opts.Stop
+	r.Seed = opts.Seed
+	r.FrequencyPenalty = opts.FrequencyPenalty
+	r.PresencePenalty = opts.PresencePenalty
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openAIMessage struct {
 	Role       string           `json:"role"`
-	Content    any              `json:"content,omitempty"` // string or []contentPart
+	Content    any              `json:"content,omitempty"` // string or []openAIContentPart
 	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string           `json:"tool_call_id,omitempty"`
+
+	// Thinking in responses: reasoning_content from DeepSeek, vLLM and
+	// llama.cpp, reasoning from OpenRouter. Never set in requests.
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	Reasoning        string `json:"reasoning,omitempty"`
 }
 
 type openAIToolCall struct {
@@ -357,13 +670,42 @@ type openAIResponse struct {
 	Choices []struct {
 		Message openAIMessage `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"` // OpenRouter's, in credits worth a dollar each
+
+	// OpenAI and OpenRouter count the prompt tokens served from their
+	// prompt cache, which reuses a prefix seen in earlier requests.
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// toUsage converts the counts, pricing them for model if the service
+// didn't. It returns nil if there were none.
+func (u *openAIUsage) toUsage(model string) *Usage {
+	if u == nil {
+		return nil
+	}
+	return priced(model, Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		CacheReadTokens:  u.PromptTokensDetails.CachedTokens,
+		Cost:             u.Cost,
+	})
 }
 
 type openAIStreamChunk struct {
 	Choices []struct {
 		Delta struct {
-			Content   string `json:"content"`
-			ToolCalls []struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+			ToolCalls        []struct {
 				Index    int    `json:"index"`
 				ID       string `json:"id"`
 				Function struct {
@@ -374,6 +716,7 @@ type openAIStreamChunk struct {
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
 }
 
 func convertToOpenAIMessages(systemPrompt string, messages []Message) []openAIMessage {
@@ -383,61 +726,86 @@ func convertToOpenAIMessages(systemPrompt string, messages []Message) []openAIMe
 	}}
 
 	for _, msg := range messages {
-		if len(msg.ToolResults) > 0 {
-			// Tool results
-			for _, tr := range msg.ToolResults {
+		// Tool results become separate "tool" messages, which must directly
+		// follow the assistant message that called them; text in the same
+		// message is sent after them as a regular message.
+		//
+		// Tool messages can only hold text, so images tools returned go
+		// in that message too, after the message's own.
+		var toolCalls []openAIToolCall
+		var toolImages []Image
+		for _, part := range msg.Parts() {
+			switch {
+			case part.ToolResult != nil:
 				result = append(result, openAIMessage{
 					Role:       "tool",
-					Content:    tr.Content,
-					ToolCallID: tr.ID,
+					Content:    part.ToolResult.Content,
+					ToolCallID: part.ToolResult.ID,
 				})
+				toolImages = append(toolImages, part.ToolResult.Images...)
+			case part.ToolCall != nil:
+				toolCalls = append(toolCalls, convertToOpenAIToolCall(*part.ToolCall))
 			}
-		} else if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
-			// Assistant with tool calls
-			var toolCalls []openAIToolCall
-			for _, tc := range msg.ToolCalls {
-				toolCalls = append(toolCalls, openAIToolCall{
-					ID:   tc.ID,
-					Type: "function",
-					Function: struct {
-						Name      string `json:"name"`
-						Arguments string `json:"arguments"`
-					}{
-						Name:      tc.Name,
-						Arguments: string(tc.Input),
-					},
-				})
+		}
+		// Only a message of nothing but tool results is fully covered above.
+		if msg.Content != "" || len(toolCalls) > 0 || len(msg.ToolResults) == 0 || len(toolImages) > 0 {
+			var content any = msg.Content
+			if images := slices.Concat(msg.Images, toolImages); len(images) > 0 {
+				text := msg.Content
+				if text == "" && len(toolImages) > 0 {
+					text = "(The images the tools above returned.)"
+				}
+				content = openAIContent(text, images)
 			}
 			result = append(result, openAIMessage{
-				Role:      "assistant",
-				Content:   msg.Content,
+				Role:      msg.Role,
+				Content:   content,
 				ToolCalls: toolCalls,
 			})
-		} else {
-			// Regular message
-			result = append(result, openAIMessage{
-				Role:    msg.Role,
-				Content: msg.Content,
-			})
 		}
 	}
 
 	return result
 }
 
+// openAIContentPart is one part of a message's content, which is sent as
+// parts rather than a string when it has images.
+type openAIContentPart struct {
+	Type     string          `json:"type"` // "text" or "image_url"
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// openAIContent returns images and then text as content parts.
+func openAIContent(text string, images []Image) []openAIContentPart {
+	parts := make([]openAIContentPart, 0, len(images)+1)
+	for _, img := range images {
+		parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: img.DataURL()}})
+	}
+	if text != "" {
+		parts = append(parts, openAIContentPart{Type: "text", Text: text})
+	}
+	return parts
+}
+
+func convertToOpenAIToolCall(tc ToolCall) openAIToolCall {
+	call := openAIToolCall{ID: tc.ID, Type: "function"}
+	call.Function.Name = tc.Name
+	call.Function.Arguments = string(tc.Input)
+	return call
+}
+
 func convertToOpenAITools(toolDefs []tools.Tool) []openAITool {
 	result := make([]openAITool, 0, len(toolDefs))
 	for _, t := range toolDefs {
-		// Convert Anthropic schema to OpenAI format
-		params, _ := json.Marshal(map[string]any{
-			"type":       "object",
-			"properties": t.InputSchema.Properties,
-		})
-
 		tool := openAITool{Type: "function"}
 		tool.Function.Name = t.Name
 		tool.Function.Description = t.Description
-		tool.Function.Parameters = params
+		tool.Function.Parameters = t.Parameters()
 		result = append(result, tool)
 	}
 	return result
@@ -457,6 +825,10 @@ func convertFromOpenAIResponse(resp openAIResponse) Message {
 	if content, ok := choice.Content.(string); ok {
 		msg.Content = content
 	}
+	msg.Reasoning = choice.ReasoningContent + choice.Reasoning
+	if msg.Reasoning == "" {
+		msg.Content, msg.Reasoning = splitThinking(msg.Content)
+	}
 
 	// Handle tool calls
 	for _, tc := range choice.ToolCalls {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,20 +18,67 @@ type SaturnPool struct {
 	httpClient *http.Client
 	model      string
 	maxTokens  int
+	meta       modelMetadata
+
+	// lists caches each service's model list, by service name, since the
+	// services of a pool needn't offer the same models.
+	lists map[string]*modelMetadata
+
+	toolCalling    string
+	thinkingBudget int
+	options        ChatOptions
+	embeddingModel string
+	retry          RetryPolicy
 
 	current atomic.Uint32
 	mu      sync.RWMutex
+
+	// sticky pools remember which service last answered each affinity key
+	// (see WithAffinity) and send that conversation there first.
+	sticky   bool
+	affinity map[string]string // key -> service name
+
+	// breaker skips services that keep failing until they recover.
+	breaker breaker
+
+	// routing is RouteLoad or RouteRoundRobin; load is what routing by
+	// load goes on.
+	routing string
+	load    poolLoad
 }
 
+// maxAffinities bounds the affinity map; it is cleared when full, which
+// only costs each conversation one cache-cold request.
+const maxAffinities = 1024
+
 type SaturnPoolConfig struct {
 	DiscoveryTimeout time.Duration
 	Model            string
 	MaxTokens        int
 	Filter           *DiscoveryFilter
 	MinServices      int
+	Service          string // Use only the service with this name
+	Sticky           bool   // Keep each conversation on one service
+	ToolCalling      string // ToolCallingAuto, ToolCallingNative or ToolCallingEmulated
+	ThinkingBudget   int    // Tokens the model may think for, where supported; 0 is off
+	Scorer           Scorer // Orders the services, best first; nil is DefaultScorer
+	Breaker          BreakerConfig
+	Routing          string // RouteLoad or RouteRoundRobin; empty is RouteLoad
+	Options          ChatOptions
+	EmbeddingModel   string // Model for Embeddings; see Saturn.EmbeddingModel
+	Retry            RetryPolicy
 }
 
 func NewSaturnPool(ctx context.Context, cfg SaturnPoolConfig) (*SaturnPool, error) {
+	if !ValidToolCalling(cfg.ToolCalling) {
+		return nil, fmt.Errorf("unknown tool calling mode %q (want auto, native or emulated)", cfg.ToolCalling)
+	}
+	if cfg.Routing == "" {
+		cfg.Routing = RouteLoad
+	}
+	if cfg.Routing != RouteLoad && cfg.Routing != RouteRoundRobin {
+		return nil, fmt.Errorf("unknown routing %q (want load or round-robin)", cfg.Routing)
+	}
 	if cfg.DiscoveryTimeout == 0 {
 		cfg.DiscoveryTimeout = 3 * time.Second
 	}
@@ -50,33 +99,97 @@ func NewSaturnPool(ctx context.Context, cfg SaturnPoolConfig) (*SaturnPool, erro
 	}
 
 	if len(services) == 0 {
-		return nil, fmt.Errorf("no saturn services found on network")
+		return nil, fmt.Errorf("%w on network", ErrNoServices)
+	}
+
+	if cfg.Service != "" {
+		services, err = pinService(services, cfg.Service)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if cfg.MinServices > 0 && len(services) < cfg.MinServices {
 		return nil, fmt.Errorf("found %d services, need at least %d", len(services), cfg.MinServices)
 	}
+	return newSaturnPool(services, cfg), nil
+}
 
-	var healthy []SaturnService
-	for _, svc := range services {
-		if healthCheck(svc) == nil {
-			healthy = append(healthy, svc)
-		}
+// NewSaturnSticky connects as NewSaturn does, except that when discovery
+// finds more than one service it returns a sticky SaturnPool over them
+// all rather than settling on the best: conversations are spread over the
+// services, each kept on the one that answered it last (see WithAffinity)
+// so that service's prompt cache stays warm. With an endpoint, or a single
+// service, it returns NewSaturn's provider.
+func NewSaturnSticky(ctx context.Context, cfg SaturnConfig) (Provider, error) {
+	if cfg.Endpoint != "" || os.Getenv(SaturnEndpointEnv) != "" {
+		return NewSaturn(ctx, cfg)
+	}
+	if !ValidToolCalling(cfg.ToolCalling) {
+		return nil, fmt.Errorf("unknown tool calling mode %q (want auto, native or emulated)", cfg.ToolCalling)
 	}
+	if cfg.DiscoveryTimeout == 0 {
+		cfg.DiscoveryTimeout = 3 * time.Second
+	}
+	services, err := discoverServices(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 1 {
+		return newSaturnFrom(services, cfg), nil
+	}
+	return newSaturnPool(services, SaturnPoolConfig{
+		Model:          cfg.Model,
+		MaxTokens:      cfg.MaxTokens,
+		Sticky:         true,
+		ToolCalling:    cfg.ToolCalling,
+		ThinkingBudget: cfg.ThinkingBudget,
+		Scorer:         cfg.Scorer,
+		Routing:        RouteLoad,
+		Options:        cfg.Options,
+		EmbeddingModel: cfg.EmbeddingModel,
+		Retry:          cfg.Retry,
+	}), nil
+}
 
-	if len(healthy) == 0 {
-		healthy = services
+// newSaturnPool creates a pool over services, health checking each.
+func newSaturnPool(services []SaturnService, cfg SaturnPoolConfig) *SaturnPool {
+	// Services that fail their first health check are kept, with their
+	// circuits open, so they join in once they recover.
+	services = RankServices(services, cfg.Scorer, cfg.Model)
+	var down []string
+	for _, svc := range services {
+		if healthCheck(svc) != nil {
+			down = append(down, svc.Name)
+		}
 	}
 
-	return &SaturnPool{
-		services: healthy,
+	p := &SaturnPool{
+		services: services,
 		httpClient: &http.Client{
 			Timeout:   120 * time.Second,
 			Transport: createPooledTransport(),
 		},
 		model:     cfg.Model,
 		maxTokens: cfg.MaxTokens,
-	}, nil
+		sticky:    cfg.Sticky,
+		affinity:  map[string]string{},
+
+		toolCalling:    cfg.ToolCalling,
+		thinkingBudget: cfg.ThinkingBudget,
+		options:        cfg.Options,
+		embeddingModel: cfg.EmbeddingModel,
+		retry:          cfg.Retry,
+		breaker:        breaker{cfg: cfg.Breaker},
+		routing:        cfg.Routing,
+	}
+	p.load.refreshed = time.Now() // the health checks above were the first refresh
+	if len(down) < len(services) {
+		for _, name := range down {
+			p.breaker.trip(name)
+		}
+	}
+	return p
 }
 
 func (p *SaturnPool) Name() string {
@@ -130,23 +243,111 @@ func (p *SaturnPool) nextN(start int, count int) []*SaturnService {
 	return result
 }
 
+// Health returns how each service has been doing, in the pool's order.
+func (p *SaturnPool) Health() []SaturnServiceHealth {
+	return p.breaker.health(p.GetServices())
+}
+
+// candidates returns the services a request should try, in order: most
+// free capacity first when routing by load, with ties taken in turn, or
+// round-robin; a sticky pool puts the service ctx's conversation last used
+// first either way. Services with open circuits are left out, unless every
+// one is open, when all are tried rather than none.
+func (p *SaturnPool) candidates(ctx context.Context) []*SaturnService {
+	startIdx := int(p.current.Add(1) - 1)
+	all := p.nextN(startIdx, p.ServiceCount())
+	if p.routing != RouteRoundRobin {
+		p.load.refresh(all)
+		free := make(map[*SaturnService]int, len(all))
+		for _, svc := range all {
+			free[svc] = p.load.freeCapacity(*svc)
+		}
+		sort.SliceStable(all, func(i, j int) bool { return free[all[i]] > free[all[j]] })
+	}
+	services := make([]*SaturnService, 0, len(all))
+	for _, svc := range all {
+		if p.breaker.allow(*svc) {
+			services = append(services, svc)
+		}
+	}
+	if len(services) == 0 {
+		services = all
+	}
+	if !p.sticky {
+		return services
+	}
+
+	p.mu.RLock()
+	name, ok := p.affinity[affinityFrom(ctx)]
+	p.mu.RUnlock()
+	if !ok {
+		return services
+	}
+	for i, svc := range services {
+		if svc.Name == name {
+			ordered := append([]*SaturnService{svc}, services[:i]...)
+			return append(ordered, services[i+1:]...)
+		}
+	}
+	return services
+}
+
+// remember records that svc answered for ctx's conversation.
+func (p *SaturnPool) remember(ctx context.Context, svc *SaturnService) {
+	if !p.sticky {
+		return
+	}
+	key := affinityFrom(ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.affinity[key]; !ok && len(p.affinity) >= maxAffinities {
+		p.affinity = map[string]string{}
+	}
+	p.affinity[key] = svc.Name
+}
+
+// ListModels lists the models of the next service in turn.
 func (p *SaturnPool) ListModels(ctx context.Context) ([]ModelInfo, error) {
 	svc := p.next()
 	if svc == nil {
 		return nil, fmt.Errorf("no services available")
 	}
+	return p.ListServiceModels(ctx, svc)
+}
+
+// ListServiceModels lists the models of svc, one of the pool's services,
+// from its own cache for modelListTTL. It leaves the pool's turn alone.
+func (p *SaturnPool) ListServiceModels(ctx context.Context, svc *SaturnService) ([]ModelInfo, error) {
+	p.mu.Lock()
+	if p.lists == nil {
+		p.lists = map[string]*modelMetadata{}
+	}
+	meta, ok := p.lists[svc.Name]
+	if !ok {
+		meta = &modelMetadata{}
+		p.lists[svc.Name] = meta
+	}
+	p.mu.Unlock()
+	if models, ok := meta.cached(); ok {
+		return models, nil
+	}
 
 	single := &Saturn{
 		service:    svc,
 		httpClient: p.httpClient,
 		model:      p.model,
+		retry:      p.retry,
 	}
-	return single.ListModels(ctx)
+	models, err := single.ListModels(ctx)
+	if err == nil {
+		meta.remember(models)
+		p.meta.remember(models)
+	}
+	return models, err
 }
 
 func (p *SaturnPool) Chat(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (Message, error) {
-	startIdx := int(p.current.Add(1) - 1)
-	services := p.nextN(startIdx, len(p.services))
+	services := p.candidates(ctx)
 
 	var lastErr error
 	for _, svc := range services {
@@ -155,12 +356,25 @@ func (p *SaturnPool) Chat(ctx context.Context, systemPrompt string, messages []M
 			httpClient: p.httpClient,
 			model:      p.model,
 			maxTokens:  p.maxTokens,
+
+			thinkingBudget: p.thinkingBudget,
+			options:        p.options,
+			retry:          p.retry,
 		}
 
+		done := p.load.start(svc.Name)
 		msg, err := single.Chat(ctx, systemPrompt, messages, toolDefs)
+		done()
 		if err == nil {
+			p.remember(ctx, svc)
+			p.breaker.succeeded(svc.Name)
 			return msg, nil
 		}
+		// A request too long for one service is too long for all of them.
+		if failsEverywhere(err) {
+			return Message{}, err
+		}
+		p.failed(ctx, svc)
 		lastErr = err
 	}
 
@@ -168,8 +382,7 @@ func (p *SaturnPool) Chat(ctx context.Context, systemPrompt string, messages []M
 }
 
 func (p *SaturnPool) ChatStream(ctx context.Context, systemPrompt string, messages []Message, toolDefs []tools.Tool) (<-chan StreamDelta, error) {
-	startIdx := int(p.current.Add(1) - 1)
-	services := p.nextN(startIdx, len(p.services))
+	services := p.candidates(ctx)
 
 	var lastErr error
 	for _, svc := range services {
@@ -178,14 +391,34 @@ func (p *SaturnPool) ChatStream(ctx context.Context, systemPrompt string, messag
 			httpClient: p.httpClient,
 			model:      p.model,
 			maxTokens:  p.maxTokens,
+
+			thinkingBudget: p.thinkingBudget,
+			options:        p.options,
+			retry:          p.retry,
 		}
 
+		done := p.load.start(svc.Name)
 		ch, err := single.ChatStream(ctx, systemPrompt, messages, toolDefs)
 		if err == nil {
-			return ch, nil
+			p.remember(ctx, svc)
+			p.breaker.succeeded(svc.Name)
+			return until(ch, done), nil
 		}
+		done()
+		if failsEverywhere(err) {
+			return nil, err
+		}
+		p.failed(ctx, svc)
 		lastErr = err
 	}
 
 	return nil, fmt.Errorf("all %d services failed, last error: %w", len(services), lastErr)
 }
+
+// failed counts a failed request against svc, unless it was the caller
+// that gave up on it.
+func (p *SaturnPool) failed(ctx context.Context, svc *SaturnService) {
+	if ctx.Err() == nil {
+		p.breaker.failed(svc.Name)
+	}
+}
@@ -0,0 +1,437 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers chat completions and counts requests per service.
+func countingServer(t *testing.T, name string, counts map[string]int, mu *sync.Mutex) SaturnService {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		counts[name]++
+		mu.Unlock()
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return SaturnService{Name: name, APIBase: srv.URL + "/v1"}
+}
+
+func testPool(t *testing.T, sticky bool) (*SaturnPool, map[string]int) {
+	counts := map[string]int{}
+	mu := &sync.Mutex{}
+	return &SaturnPool{
+		services: []SaturnService{
+			countingServer(t, "a", counts, mu),
+			countingServer(t, "b", counts, mu),
+			countingServer(t, "c", counts, mu),
+		},
+		httpClient: http.DefaultClient,
+		sticky:     sticky,
+		affinity:   map[string]string{},
+	}, counts
+}
+
+func TestSaturnPoolRoundRobin(t *testing.T) {
+	pool, counts := testPool(t, false)
+	ctx := WithAffinity(context.Background(), "session-1")
+	for range 3 {
+		if _, err := pool.Chat(ctx, "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counts["a"] != 1 || counts["b"] != 1 || counts["c"] != 1 {
+		t.Errorf("requests per service = %v, want one each", counts)
+	}
+}
+
+func TestSaturnPoolStickyPerConversation(t *testing.T) {
+	pool, counts := testPool(t, true)
+	one := WithAffinity(context.Background(), "session-1")
+	two := WithAffinity(context.Background(), "session-2")
+
+	for range 3 {
+		if _, err := pool.Chat(one, "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counts["a"] != 3 {
+		t.Fatalf("session-1 requests = %v, want all on a", counts)
+	}
+
+	before := map[string]int{"a": counts["a"], "b": counts["b"], "c": counts["c"]}
+	for range 2 {
+		if _, err := pool.Chat(two, "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := pool.affinity["session-2"]; counts[got]-before[got] != 2 {
+		t.Errorf("session-2 on %q, counts = %v; want both requests on one service", got, counts)
+	}
+	if pool.affinity["session-1"] != "a" {
+		t.Errorf("session-1 moved to %q", pool.affinity["session-1"])
+	}
+}
+
+func TestSaturnPoolStickyFailsOver(t *testing.T) {
+	pool, counts := testPool(t, true)
+	ctx := WithAffinity(context.Background(), "s")
+	pool.affinity["s"] = "a"
+	pool.services[0].APIBase = "http://127.0.0.1:1/v1" // a is down
+
+	for range 2 {
+		if _, err := pool.Chat(ctx, "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := pool.affinity["s"]; got == "a" || counts[got] != 2 {
+		t.Errorf("affinity = %q, counts = %v; want both requests on the failover service", got, counts)
+	}
+}
+
+func TestPinService(t *testing.T) {
+	services := []SaturnService{{Name: "a"}, {Name: "b"}}
+	got, err := pinService(services, "b")
+	if err != nil || len(got) != 1 || got[0].Name != "b" {
+		t.Errorf("pinService = %v, %v", got, err)
+	}
+	if _, err := pinService(services, "z"); err == nil {
+		t.Error("pinService accepted an unknown service")
+	}
+}
+
+func TestSaturnPoolCircuitBreaker(t *testing.T) {
+	pool, counts := testPool(t, false)
+	pool.services[0].APIBase = "http://127.0.0.1:1/v1" // a is down
+	now := time.Now()
+	healthy := false
+	pool.breaker = breaker{
+		cfg: BreakerConfig{Threshold: 2, Cooldown: time.Minute},
+		now: func() time.Time { return now },
+		check: func(svc SaturnService) error {
+			if !healthy {
+				return errors.New("down")
+			}
+			return nil
+		},
+	}
+	chat := func() {
+		t.Helper()
+		if _, err := pool.Chat(context.Background(), "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Round-robin puts a first every third request; two failures open it.
+	for range 6 {
+		chat()
+	}
+	if h := pool.Health()[0]; !h.Open || h.Failures != 2 {
+		t.Fatalf("a after two failures: %+v", h)
+	}
+	for range 6 {
+		chat()
+	}
+	if h := pool.Health()[0]; h.TotalFailures != 2 {
+		t.Errorf("a was tried while open: %+v", h)
+	}
+
+	// Past the cooldown a failed check keeps it out, a passed one lets it
+	// back in.
+	now = now.Add(2 * time.Minute)
+	for range 3 {
+		chat()
+	}
+	if h := pool.Health()[0]; !h.Open {
+		t.Errorf("a was let back in without passing a check: %+v", h)
+	}
+	now = now.Add(2 * time.Minute)
+	healthy = true
+	pool.services[0] = countingServer(t, "a", counts, &sync.Mutex{})
+	for range 3 {
+		chat()
+	}
+	if h := pool.Health()[0]; h.Open || counts["a"] != 1 {
+		t.Errorf("a not readmitted after recovering: %+v, counts %v", h, counts)
+	}
+}
+
+func TestSaturnPoolAllOpen(t *testing.T) {
+	pool, counts := testPool(t, false)
+	for _, svc := range pool.services {
+		pool.breaker.trip(svc.Name)
+	}
+	if _, err := pool.Chat(context.Background(), "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+		t.Fatalf("with every circuit open: %v", err)
+	}
+	if counts["a"]+counts["b"]+counts["c"] != 1 {
+		t.Errorf("counts = %v, want one request", counts)
+	}
+}
+
+func TestSaturnPoolRoutesByLoad(t *testing.T) {
+	pool, counts := testPool(t, false)
+	pool.services[0].MaxConcurrent, pool.services[0].CurrentLoad = 4, 4 // a is full
+	pool.services[1].MaxConcurrent, pool.services[1].CurrentLoad = 8, 2 // b has six free
+	pool.services[2].MaxConcurrent, pool.services[2].CurrentLoad = 4, 1 // c has three
+
+	for range 3 {
+		if _, err := pool.Chat(context.Background(), "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counts["b"] != 3 {
+		t.Errorf("counts = %v, want every request on b", counts)
+	}
+
+	// The pool's own requests count against a service.
+	done := pool.load.start("b")
+	defer done()
+	for range 5 {
+		pool.load.start("b")
+	}
+	if got := pool.candidates(context.Background()); got[0].Name != "c" {
+		t.Errorf("with b busy, first candidate = %s, want c", got[0].Name)
+	}
+
+	recordLoad("c", 4, 4)
+	t.Cleanup(func() {
+		loads.Lock()
+		delete(loads.byService, "c")
+		loads.Unlock()
+	})
+	if got := pool.candidates(context.Background()); got[0].Name != "b" {
+		t.Errorf("with c reporting itself full, first candidate = %s, want b", got[0].Name)
+	}
+}
+
+func TestSaturnPoolStickySkipsOpenCircuit(t *testing.T) {
+	pool, counts := testPool(t, true)
+	ctx := WithAffinity(context.Background(), "s")
+	pool.affinity["s"] = "a"
+	pool.breaker.trip("a")
+
+	if _, err := pool.Chat(ctx, "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := pool.affinity["s"]; counts["a"] != 0 || got == "a" || counts[got] != 1 {
+		t.Errorf("affinity = %q, counts = %v; want the request and the affinity moved off a", got, counts)
+	}
+}
+
+func TestListModelsCached(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"data":[{"id":"llama3.1:8b","max_model_len":8192}]}`)
+	}))
+	defer srv.Close()
+	s := &Saturn{service: &SaturnService{Name: "s", APIBase: srv.URL + "/v1"}, httpClient: http.DefaultClient, model: "llama3.1:8b"}
+
+	for range 2 {
+		models, err := s.ListModels(context.Background())
+		if err != nil || len(models) != 1 {
+			t.Fatalf("ListModels = %v, %v", models, err)
+		}
+		models[0].ID = "changed by the caller"
+	}
+	if requests != 1 {
+		t.Errorf("fetched the list %d times within the TTL, want 1", requests)
+	}
+	if c := s.Capabilities(); c.MaxContext != 8192 {
+		t.Errorf("cached list lost the metadata: %+v", c)
+	}
+
+	s.meta.fetched = time.Now().Add(-modelListTTL - time.Second)
+	if models, _ := s.ListModels(context.Background()); requests != 2 || models[0].ID != "llama3.1:8b" {
+		t.Errorf("after the TTL: %d requests, models %v", requests, models)
+	}
+}
+
+func TestSaturnPoolListModelsPerService(t *testing.T) {
+	requests := map[string]int{}
+	serve := func(name, model string) SaturnService {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests[name]++
+			fmt.Fprintf(w, `{"data":[{"id":%q}]}`, model)
+		}))
+		t.Cleanup(srv.Close)
+		return SaturnService{Name: name, APIBase: srv.URL + "/v1"}
+	}
+	pool := &SaturnPool{
+		services:   []SaturnService{serve("a", "llama"), serve("b", "qwen")},
+		httpClient: http.DefaultClient,
+	}
+
+	var got []string
+	for range 4 {
+		models, err := pool.ListModels(context.Background())
+		if err != nil || len(models) != 1 {
+			t.Fatalf("ListModels = %v, %v", models, err)
+		}
+		got = append(got, models[0].ID)
+	}
+	if fmt.Sprint(got) != "[llama qwen llama qwen]" {
+		t.Errorf("lists in turn = %v, want each service's own", got)
+	}
+	if requests["a"] != 1 || requests["b"] != 1 {
+		t.Errorf("requests per service = %v, want one each within the TTL", requests)
+	}
+}
+
+func TestSaturnPoolListServiceModels(t *testing.T) {
+	pool, _ := testPool(t, false)
+	for _, svc := range pool.GetServices() {
+		pool.ListServiceModels(context.Background(), &svc)
+	}
+	if n := pool.current.Load(); n != 0 {
+		t.Errorf("listing each service moved the pool's turn to %d", n)
+	}
+}
+
+func TestNewSaturnSticky(t *testing.T) {
+	t.Cleanup(globalServiceCache.Clear)
+	counts := map[string]int{}
+	mu := &sync.Mutex{}
+	a, b := countingServer(t, "a", counts, mu), countingServer(t, "b", counts, mu)
+
+	globalServiceCache.SetAll([]SaturnService{a})
+	p, err := NewSaturnSticky(context.Background(), SaturnConfig{Cached: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*Saturn); !ok {
+		t.Errorf("with one service got %T, want *Saturn", p)
+	}
+
+	globalServiceCache.SetAll([]SaturnService{a, b})
+	p, err = NewSaturnSticky(context.Background(), SaturnConfig{Cached: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, ok := p.(*SaturnPool)
+	if !ok || !pool.sticky || pool.ServiceCount() != 2 {
+		t.Fatalf("with two services got %T %+v, want a sticky pool over both", p, p)
+	}
+	ctx := WithAffinity(context.Background(), "session-1")
+	var first string
+	for i := range 3 {
+		if _, err := pool.Chat(ctx, "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = pool.affinity["session-1"]
+		} else if got := pool.affinity["session-1"]; got != first {
+			t.Errorf("request %d went to %s, the conversation started on %s", i+1, got, first)
+		}
+	}
+}
+
+// TestStickyAgentSkipsOpenCircuit follows the path an agent's requests
+// take with -sticky: a pool from NewSaturnSticky behind Adapt, each
+// request tagged with the session's affinity.
+func TestStickyAgentSkipsOpenCircuit(t *testing.T) {
+	t.Cleanup(globalServiceCache.Clear)
+	counts := map[string]int{}
+	mu := &sync.Mutex{}
+	globalServiceCache.SetAll([]SaturnService{countingServer(t, "a", counts, mu), countingServer(t, "b", counts, mu)})
+	p, err := NewSaturnSticky(context.Background(), SaturnConfig{Cached: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := p.(*SaturnPool)
+	prov := Adapt(p)
+	ctx := WithAffinity(context.Background(), "session-1")
+	chat := func() {
+		t.Helper()
+		if _, err := prov.Chat(ctx, "", []Message{{Role: "user", Content: "x"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chat()
+	home := pool.affinity["session-1"]
+	other := map[string]string{"a": "b", "b": "a"}[home]
+
+	// Other conversations' requests fail on home until its circuit opens.
+	for range defaultBreakerThreshold {
+		pool.breaker.failed(home)
+	}
+	chat()
+	chat()
+	mu.Lock()
+	defer mu.Unlock()
+	if counts[home] != 1 || counts[other] != 2 {
+		t.Errorf("requests per service = %v, want the open circuit on %s skipped", counts, home)
+	}
+	if got := pool.affinity["session-1"]; got != other {
+		t.Errorf("conversation is on %q, want it moved to %s", got, other)
+	}
+}
+
+// flakyServer fails its first request with a 503 and answers the rest,
+// streamed or not as asked.
+func flakyServer(t *testing.T, name string, calls *atomic.Int32) SaturnService {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return SaturnService{Name: name, APIBase: srv.URL + "/v1"}
+}
+
+func TestSaturnPoolRetries(t *testing.T) {
+	t.Cleanup(globalServiceCache.Clear)
+	retry := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	msgs := []Message{{Role: "user", Content: "x"}}
+
+	var a, b atomic.Int32
+	globalServiceCache.SetAll([]SaturnService{flakyServer(t, "a", &a), flakyServer(t, "b", &b)})
+	p, err := NewSaturnSticky(context.Background(), SaturnConfig{Cached: true, Retry: retry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := p.(*SaturnPool)
+	ctx := WithAffinity(context.Background(), "session-1")
+	if msg, err := pool.Chat(ctx, "", msgs, nil); err != nil || msg.Content != "hi" {
+		t.Fatalf("Chat = %q, %v; want the retry to succeed", msg.Content, err)
+	}
+	// The retry stayed on the service, rather than the pool failing over.
+	if got := a.Load() + b.Load(); got != 2 {
+		t.Errorf("%d requests, want the failed one and its retry", got)
+	}
+
+	var c atomic.Int32
+	pool = newSaturnPool([]SaturnService{flakyServer(t, "c", &c)}, SaturnPoolConfig{Retry: retry})
+	stream, err := pool.ChatStream(context.Background(), "", msgs, nil)
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	var content string
+	for d := range stream {
+		content += d.Content
+	}
+	if content != "hi" || c.Load() != 2 {
+		t.Errorf("stream = %q after %d requests", content, c.Load())
+	}
+}
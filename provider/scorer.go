@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ScoreInput is what a Scorer judges a service on.
+type ScoreInput struct {
+	Priority   int           // Announced priority; lower is preferred
+	Load       float64       // Share of its capacity in use, 0 to 1; 1 if it doesn't say
+	Latency    time.Duration // Last measured health check round trip; 0 if never measured
+	ModelMatch bool          // It announces the requested model
+	GPU        bool          // It announces a GPU
+	Remote     bool          // It proxies a remote API rather than serving locally
+}
+
+// Scorer rates services for selection; the highest score is tried first.
+type Scorer interface {
+	Score(in ScoreInput) float64
+}
+
+// ScorerFunc adapts a function to Scorer.
+type ScorerFunc func(in ScoreInput) float64
+
+func (f ScorerFunc) Score(in ScoreInput) float64 {
+	return f(in)
+}
+
+// DefaultScorer prefers the service with the lowest priority number, as
+// selection always has.
+var DefaultScorer Scorer = ScorerFunc(func(in ScoreInput) float64 {
+	return -float64(in.Priority)
+})
+
+// ScoreWeights scores a service as a weighted sum of its inputs, each
+// scaled to 0..1 with 1 the most preferred: priority 0 scores 1 and 100 or
+// more scores 0; an idle service scores 1 on load; a round trip of a second
+// or more scores 0 on latency, and an unmeasured one 0.5. Model, GPU and
+// Local score 1 when the service matches the model, has a GPU and does not
+// proxy a remote API.
+type ScoreWeights struct {
+	Priority float64
+	Load     float64
+	Latency  float64
+	Model    float64
+	GPU      float64
+	Local    float64
+}
+
+func (w ScoreWeights) Score(in ScoreInput) float64 {
+	priority := clamp01(float64(100-in.Priority) / 100)
+	load := clamp01(1 - in.Load)
+	latency := 0.5
+	if in.Latency > 0 {
+		latency = clamp01(1 - float64(in.Latency)/float64(time.Second))
+	}
+	return w.Priority*priority +
+		w.Load*load +
+		w.Latency*latency +
+		w.Model*boolScore(in.ModelMatch) +
+		w.GPU*boolScore(in.GPU) +
+		w.Local*boolScore(!in.Remote)
+}
+
+func clamp01(v float64) float64 {
+	return min(max(v, 0), 1)
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// scoreInput gathers what is known about svc for a request for model.
+func scoreInput(svc SaturnService, model string) ScoreInput {
+	in := ScoreInput{
+		Priority: svc.Priority,
+		Load:     svc.LoadFraction(),
+		GPU:      svc.GPU != "",
+		Remote:   svc.APIBase != "",
+	}
+	in.Latency, _ = MeasuredLatency(svc.Name)
+	for _, m := range svc.Models {
+		if m == model {
+			in.ModelMatch = true
+			break
+		}
+	}
+	return in
+}
+
+// RankServices returns services ordered by scorer, best first, for a
+// request for model. Ties keep their order. A nil scorer is DefaultScorer.
+func RankServices(services []SaturnService, scorer Scorer, model string) []SaturnService {
+	if scorer == nil {
+		scorer = DefaultScorer
+	}
+	type scored struct {
+		svc   SaturnService
+		score float64
+	}
+	all := make([]scored, len(services))
+	for i, svc := range services {
+		all[i] = scored{svc, scorer.Score(scoreInput(svc, model))}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+
+	ranked := make([]SaturnService, len(all))
+	for i, s := range all {
+		ranked[i] = s.svc
+	}
+	return ranked
+}
+
+var latencies = struct {
+	sync.Mutex
+	byService map[string]time.Duration
+}{byService: map[string]time.Duration{}}
+
+// recordLatency notes a health check round trip to a service.
+func recordLatency(service string, d time.Duration) {
+	latencies.Lock()
+	defer latencies.Unlock()
+	latencies.byService[service] = d
+}
+
+// MeasuredLatency returns the last health check round trip to the named
+// service, if one has been made in this process.
+func MeasuredLatency(service string) (time.Duration, bool) {
+	latencies.Lock()
+	defer latencies.Unlock()
+	d, ok := latencies.byService[service]
+	return d, ok
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRankServicesDefault(t *testing.T) {
+	services := []SaturnService{
+		{Name: "b", Priority: 20},
+		{Name: "a", Priority: 10},
+		{Name: "c", Priority: 20},
+	}
+	ranked := RankServices(services, nil, "")
+	if got := names(ranked); got != "a,b,c" {
+		t.Errorf("default order = %s, want a,b,c", got)
+	}
+	if services[0].Name != "b" {
+		t.Error("RankServices reordered its argument")
+	}
+}
+
+func TestRankServicesWeights(t *testing.T) {
+	services := []SaturnService{
+		{Name: "cloud", Priority: 1, APIBase: "https://api.example.com/v1", Models: []string{"big"}},
+		{Name: "onprem", Priority: 50, GPU: "A100"},
+		{Name: "match", Priority: 50, Models: []string{"big"}},
+	}
+
+	local := ScoreWeights{Priority: 1, Local: 5}
+	if got := names(RankServices(services, local, "")); got != "onprem,match,cloud" {
+		t.Errorf("preferring local: %s", got)
+	}
+	model := ScoreWeights{Priority: 1, Model: 5}
+	if got := names(RankServices(services, model, "big")); got != "cloud,match,onprem" {
+		t.Errorf("preferring the model: %s", got)
+	}
+	gpu := ScoreWeights{GPU: 1}
+	if got := RankServices(services, gpu, "")[0].Name; got != "onprem" {
+		t.Errorf("preferring a GPU: best is %s", got)
+	}
+}
+
+func TestScoreWeightsLatency(t *testing.T) {
+	w := ScoreWeights{Latency: 1}
+	fast := w.Score(ScoreInput{Latency: 50 * time.Millisecond})
+	slow := w.Score(ScoreInput{Latency: 800 * time.Millisecond})
+	unknown := w.Score(ScoreInput{})
+	if !(fast > unknown && unknown > slow) {
+		t.Errorf("latency scores fast=%v unknown=%v slow=%v", fast, unknown, slow)
+	}
+	if got := w.Score(ScoreInput{Latency: 3 * time.Second}); got != 0 {
+		t.Errorf("a 3s round trip scored %v, want 0", got)
+	}
+}
+
+func names(services []SaturnService) string {
+	s := ""
+	for i, svc := range services {
+		if i > 0 {
+			s += ","
+		}
+		s += svc.Name
+	}
+	return s
+}
+
+func TestDiscoverServicesFilter(t *testing.T) {
+	t.Cleanup(globalServiceCache.Clear)
+	globalServiceCache.SetAll([]SaturnService{
+		{Name: "cloud", Priority: 1, APIBase: "https://api.example.com/v1", Models: []string{"big"}},
+		{Name: "gpu", Priority: 30, GPU: "A100", VRAMGb: 80, Models: []string{"big", "small"}},
+		{Name: "laptop", Priority: 20, Models: []string{"small"}},
+	})
+
+	for _, tc := range []struct {
+		filter DiscoveryFilter
+		want   string
+	}{
+		{DiscoveryFilter{}, "cloud,laptop,gpu"},
+		{DiscoveryFilter{MinPriority: 20}, "cloud,laptop"},
+		{DiscoveryFilter{RequiredModel: "big"}, "cloud,gpu"},
+		{DiscoveryFilter{RequiredGPU: true}, "gpu"},
+		{DiscoveryFilter{MinVRAM: 40}, "gpu"},
+		{DiscoveryFilter{LocalOnly: true}, "laptop,gpu"},
+		{DiscoveryFilter{LocalOnly: true, RequiredModel: "big"}, "gpu"},
+	} {
+		services, err := discoverServices(context.Background(), SaturnConfig{Cached: true, Filter: &tc.filter})
+		if err != nil || names(services) != tc.want {
+			t.Errorf("%+v: got %s, %v; want %s", tc.filter, names(services), err, tc.want)
+		}
+	}
+
+	_, err := discoverServices(context.Background(), SaturnConfig{Cached: true, Filter: &DiscoveryFilter{RequiredModel: "huge"}})
+	if err == nil || errors.Is(err, ErrNoServices) {
+		t.Errorf("a filter nothing matches: got %v, want an error naming the filter", err)
+	}
+}
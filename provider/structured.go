@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema describes the JSON a structured response must be.
+type Schema struct {
+	// Name identifies the schema to the service, which some require;
+	// letters, digits, underscores and dashes.
+	Name string
+
+	// Schema is a JSON Schema. ChatStructured checks type, enum, const,
+	// properties, required, additionalProperties, items, minItems,
+	// maxItems and anyOf; other keywords are only passed to the model.
+	Schema map[string]any
+}
+
+// SchemaError reports a structured response that doesn't match its schema
+// even after the model was asked to fix it.
+type SchemaError struct {
+	Response string   // The model's last reply
+	Problems []string // e.g. "$.files[0]: want string, got number"
+}
+
+func (e *SchemaError) Error() string {
+	return "response does not match the schema: " + strings.Join(e.Problems, "; ")
+}
+
+type schemaKey struct{}
+
+// withSchema asks providers that can to constrain the response made with
+// ctx to schema: Saturn services with JSON mode by response_format, the
+// Anthropic API by a tool call it must make.
+func withSchema(ctx context.Context, schema Schema) context.Context {
+	return context.WithValue(ctx, schemaKey{}, schema)
+}
+
+// schemaFrom returns the schema set by withSchema, if any.
+func schemaFrom(ctx context.Context) (Schema, bool) {
+	schema, ok := ctx.Value(schemaKey{}).(Schema)
+	return schema, ok
+}
+
+// structuredPrompt tells models the service can't constrain what to reply
+// with; for those it can, it does no harm.
+const structuredPrompt = "Reply with a single JSON value matching this JSON Schema and nothing else, no prose and no code fence:\n"
+
+// structuredAttempts is how many replies ChatStructured gets: the first,
+// and one more with the problems pointed out.
+const structuredAttempts = 2
+
+// ChatStructured asks p for a response that is JSON matching schema, and
+// returns it once it does. Services that can constrain their output are
+// asked to (see Capabilities.JSONMode); every model is also told the
+// schema, and its reply is read out of any code fence or text around it.
+// A reply that doesn't match is sent back once with its problems; if the
+// next doesn't match either, the error is a *SchemaError.
+func ChatStructured(ctx context.Context, p Provider, schema Schema, systemPrompt string, messages []Message) (json.RawMessage, error) {
+	if schema.Name == "" {
+		schema.Name = "response"
+	}
+	// Round-tripped, a schema built in Go has the types a decoded
+	// response does, float64 for int and []any for []string.
+	rendered, err := json.Marshal(schema.Schema)
+	var normalized map[string]any
+	if err == nil {
+		err = json.Unmarshal(rendered, &normalized)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	schema.Schema = normalized
+	systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + structuredPrompt + string(rendered))
+	ctx = withSchema(ctx, schema)
+	messages = slices.Clip(messages)
+
+	var problems []string
+	var reply string
+	for range structuredAttempts {
+		msg, err := p.Chat(ctx, systemPrompt, messages, nil)
+		if err != nil {
+			return nil, err
+		}
+		reply = msg.Content
+		for _, tc := range msg.ToolCalls {
+			if tc.Name == schema.Name {
+				reply = string(tc.Input)
+			}
+		}
+
+		var value any
+		data, ok := extractJSON(reply)
+		if !ok || json.Unmarshal(data, &value) != nil {
+			problems = []string{"the reply is not JSON"}
+		} else if problems = validateSchema(normalized, value, "$"); len(problems) == 0 {
+			return data, nil
+		}
+		messages = append(messages,
+			Message{Role: "assistant", Content: reply},
+			Message{Role: "user", Content: "That doesn't match the schema: " + strings.Join(problems, "; ") + ". Reply again with the corrected JSON only."})
+	}
+	return nil, &SchemaError{Response: reply, Problems: problems}
+}
+
+// extractJSON returns the JSON value in reply: all of it, the inside of a
+// code fence, or the first object or array in the text.
+func extractJSON(reply string) (json.RawMessage, bool) {
+	reply, _ = splitThinking(strings.TrimSpace(reply))
+	if json.Valid([]byte(reply)) {
+		return json.RawMessage(reply), true
+	}
+	if m := fencedBlock.FindStringSubmatch(reply); m != nil && json.Valid([]byte(strings.TrimSpace(m[1]))) {
+		return json.RawMessage(strings.TrimSpace(m[1])), true
+	}
+	if i := strings.IndexAny(reply, "{["); i >= 0 {
+		dec := json.NewDecoder(strings.NewReader(reply[i:]))
+		var raw json.RawMessage
+		if dec.Decode(&raw) == nil {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
+// validateSchema checks value against schema, both decoded from JSON, and
+// returns what doesn't match, each problem prefixed with its path.
+func validateSchema(schema map[string]any, value any, path string) []string {
+	if schema == nil {
+		return nil
+	}
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if alternatives, ok := schema["anyOf"].([]any); ok {
+		matched := false
+		for _, alt := range alternatives {
+			if sub, ok := alt.(map[string]any); ok && len(validateSchema(sub, value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("matches none of anyOf")
+		}
+	}
+	if want, ok := schema["const"]; ok && !jsonEqual(want, value) {
+		fail("want %v", want)
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(v any) bool { return jsonEqual(v, value) }) {
+		fail("want one of %v, got %v", enum, value)
+	}
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return isJSONType(value, t) }) {
+		fail("want %s, got %s", strings.Join(types, " or "), jsonType(value))
+		return problems
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		for _, name := range schemaTypes(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if sub, ok := props[k].(map[string]any); ok {
+				problems = append(problems, validateSchema(sub, v[k], path+"."+k)...)
+			} else if schema["additionalProperties"] == false {
+				fail("unexpected property %q", k)
+			}
+		}
+	case []any:
+		if n, ok := schema["minItems"].(float64); ok && float64(len(v)) < n {
+			fail("want at least %v items, got %d", n, len(v))
+		}
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(v)) > n {
+			fail("want at most %v items, got %d", n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				problems = append(problems, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+// schemaTypes reads a keyword that is a string or a list of them, such as
+// type and required.
+func schemaTypes(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var result []string
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func isJSONType(value any, typ string) bool {
+	if typ == "integer" {
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return jsonType(value) == typ
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual compares two decoded JSON values.
+func jsonEqual(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errors.Join(errA, errB) == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var fileListSchema = Schema{Name: "files", Schema: map[string]any{
+	"type":                 "object",
+	"properties":           map[string]any{"files": map[string]any{"type": "array", "items": map[string]any{"type": "string"}}},
+	"required":             []string{"files"},
+	"additionalProperties": false,
+}}
+
+// structuredSaturn returns a Saturn whose server answers with replies in
+// turn, and the requests it was sent.
+func structuredSaturn(t *testing.T, features []string, replies ...string) (*Saturn, *[]map[string]any) {
+	t.Helper()
+	var reqs []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		reqs = append(reqs, req)
+		content, _ := json.Marshal(replies[min(len(reqs), len(replies))-1])
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s},"finish_reason":"stop"}]}`, content)
+	}))
+	t.Cleanup(srv.Close)
+	return &Saturn{
+		service:    &SaturnService{Name: "s", APIBase: srv.URL + "/v1", Features: features},
+		httpClient: http.DefaultClient,
+	}, &reqs
+}
+
+func TestChatStructuredResponseFormat(t *testing.T) {
+	s, reqs := structuredSaturn(t, []string{"streaming", "tools", "json"}, `{"files":["main.go"]}`)
+	got, err := ChatStructured(context.Background(), s, fileListSchema, "be brief", []Message{{Role: "user", Content: "list the files"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"files":["main.go"]}` {
+		t.Errorf("got %s", got)
+	}
+	format, _ := (*reqs)[0]["response_format"].(map[string]any)
+	spec, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || spec["name"] != "files" || spec["schema"] == nil {
+		t.Errorf("want a json_schema response_format, got %v", (*reqs)[0]["response_format"])
+	}
+}
+
+func TestChatStructuredPromptFallback(t *testing.T) {
+	s, reqs := structuredSaturn(t, []string{"streaming"}, "Here you go:\n```json\n{\"files\": [\"a.go\", \"b.go\"]}\n```")
+	got, err := ChatStructured(context.Background(), s, fileListSchema, "", []Message{{Role: "user", Content: "list the files"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"files": ["a.go", "b.go"]}` {
+		t.Errorf("got %s", got)
+	}
+	req := (*reqs)[0]
+	if _, ok := req["response_format"]; ok {
+		t.Error("response_format sent to a service without JSON mode")
+	}
+	system := req["messages"].([]any)[0].(map[string]any)
+	if !strings.Contains(fmt.Sprint(system["content"]), `"additionalProperties":false`) {
+		t.Errorf("schema not in the system prompt: %v", system)
+	}
+}
+
+func TestChatStructuredRetry(t *testing.T) {
+	s, reqs := structuredSaturn(t, nil, `{"files":"main.go"}`, `{"files":["main.go"]}`)
+	got, err := ChatStructured(context.Background(), s, fileListSchema, "", []Message{{Role: "user", Content: "list the files"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"files":["main.go"]}` || len(*reqs) != 2 {
+		t.Fatalf("got %s after %d requests", got, len(*reqs))
+	}
+	messages := (*reqs)[1]["messages"].([]any)
+	fix := messages[len(messages)-1].(map[string]any)
+	if !strings.Contains(fmt.Sprint(fix["content"]), "$.files: want array, got string") {
+		t.Errorf("retry does not name the problem: %v", fix)
+	}
+}
+
+func TestChatStructuredSchemaError(t *testing.T) {
+	s, reqs := structuredSaturn(t, nil, "no files", `{"files":[1],"dirs":[]}`)
+	_, err := ChatStructured(context.Background(), s, fileListSchema, "", []Message{{Role: "user", Content: "list the files"}})
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || len(*reqs) != 2 {
+		t.Fatalf("want a SchemaError after 2 requests, got %v after %d", err, len(*reqs))
+	}
+	want := []string{`$: unexpected property "dirs"`, "$.files[0]: want string, got number"}
+	if fmt.Sprint(schemaErr.Problems) != fmt.Sprint(want) {
+		t.Errorf("problems = %q, want %q", schemaErr.Problems, want)
+	}
+}
+
+func TestAnthropicStructured(t *testing.T) {
+	var body map[string]any
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-0",
+			"content":[{"type":"tool_use","id":"toolu_1","name":"files","input":{"files":["main.go"]}}],
+			"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`)
+	})
+
+	got, err := ChatStructured(context.Background(), a, fileListSchema, "", []Message{{Role: "user", Content: "list the files"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"files":["main.go"]}` {
+		t.Errorf("got %s", got)
+	}
+	choice, _ := body["tool_choice"].(map[string]any)
+	if choice["type"] != "tool" || choice["name"] != "files" {
+		t.Errorf("want tool_choice forcing the schema tool, got %v", body["tool_choice"])
+	}
+	toolDefs, _ := body["tools"].([]any)
+	if len(toolDefs) != 1 {
+		t.Fatalf("want the schema tool, got %v", body["tools"])
+	}
+	input := toolDefs[0].(map[string]any)["input_schema"].(map[string]any)
+	if fmt.Sprint(input["required"]) != "[files]" || input["additionalProperties"] != false {
+		t.Errorf("schema not carried into the tool: %v", input)
+	}
+}
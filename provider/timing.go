@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type firstTokenKey struct{}
+
+// WithFirstToken has requests made with ctx call fn when the first token of
+// the response arrives: the first streamed delta, or for a request that
+// isn't streamed, the first byte of the response. fn may be called more
+// than once and from any goroutine.
+func WithFirstToken(ctx context.Context, fn func()) context.Context {
+	return context.WithValue(ctx, firstTokenKey{}, fn)
+}
+
+// firstTokenFrom returns the hook set by WithFirstToken, or a no-op.
+func firstTokenFrom(ctx context.Context) func() {
+	if fn, ok := ctx.Value(firstTokenKey{}).(func()); ok {
+		return fn
+	}
+	return func() {}
+}
+
+// Timing breaks a turn's time down into waiting on the model and running
+// tools, so a slow turn can be pinned on one or the other. The zero value
+// is ready to use and safe for concurrent use.
+type Timing struct {
+	mu         sync.Mutex
+	firstToken time.Duration
+	inference  time.Duration
+	tools      time.Duration
+	requests   int
+}
+
+// TimingStats is a snapshot of a Timing.
+type TimingStats struct {
+	FirstToken time.Duration // first request of the turn until its first token
+	Inference  time.Duration // all model requests, start to finish
+	Tools      time.Duration // all tool executions
+	Requests   int
+}
+
+// Request starts timing a model request. Make the request with the
+// returned context, which notes when the first token arrives, and call done
+// when the response is complete. Providers that can't tell when the first
+// token arrived count the whole request.
+func (t *Timing) Request(ctx context.Context) (_ context.Context, done func()) {
+	start := time.Now()
+	var first atomic.Int64
+	ctx = WithFirstToken(ctx, func() {
+		first.CompareAndSwap(0, int64(max(time.Since(start), 1)))
+	})
+	return ctx, func() {
+		total := time.Since(start)
+		firstToken := time.Duration(first.Load())
+		if firstToken == 0 {
+			firstToken = total
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.requests == 0 {
+			t.firstToken = firstToken
+		}
+		t.requests++
+		t.inference += total
+	}
+}
+
+// AddTool records time spent running a tool.
+func (t *Timing) AddTool(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tools += d
+}
+
+// Stats returns the times recorded so far.
+func (t *Timing) Stats() TimingStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return TimingStats{FirstToken: t.firstToken, Inference: t.inference, Tools: t.tools, Requests: t.requests}
+}
+
+// Reset clears the recorded times, for the next turn.
+func (t *Timing) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.firstToken, t.inference, t.tools, t.requests = 0, 0, 0, 0
+}
+
+// String formats the stats for a status line, e.g.
+// "first token 1.2s, model 8.4s over 3 requests, tools 2.1s".
+func (s TimingStats) String() string {
+	requests := "1 request"
+	if s.Requests != 1 {
+		requests = fmt.Sprintf("%d requests", s.Requests)
+	}
+	return fmt.Sprintf("first token %s, model %s over %s, tools %s",
+		roundDuration(s.FirstToken), roundDuration(s.Inference), requests, roundDuration(s.Tools))
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTiming(t *testing.T) {
+	var timing Timing
+
+	ctx, done := timing.Request(context.Background())
+	firstTokenFrom(ctx)()
+	time.Sleep(20 * time.Millisecond)
+	firstTokenFrom(ctx)() // later tokens don't move the first
+	done()
+
+	// A provider that never reports a first token counts the whole request.
+	_, done = timing.Request(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	done()
+	timing.AddTool(time.Second)
+
+	stats := timing.Stats()
+	if stats.Requests != 2 {
+		t.Errorf("expected 2 requests, got %d", stats.Requests)
+	}
+	if stats.FirstToken >= 20*time.Millisecond {
+		t.Errorf("first token should come from the first request's first hook call, got %s", stats.FirstToken)
+	}
+	if stats.Inference < 30*time.Millisecond {
+		t.Errorf("expected at least 30ms of inference, got %s", stats.Inference)
+	}
+	if stats.Tools != time.Second {
+		t.Errorf("expected 1s of tools, got %s", stats.Tools)
+	}
+
+	timing.Reset()
+	if stats := timing.Stats(); stats != (TimingStats{}) {
+		t.Errorf("expected zero stats after Reset, got %+v", stats)
+	}
+}
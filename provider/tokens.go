@@ -0,0 +1,30 @@
+package provider
+
+import "brutus/internal/token"
+
+// EstimateTokens approximates the prompt size of a request built from the
+// system prompt and conversation, including tool calls and tool results.
+func EstimateTokens(systemPrompt string, messages []Message) int {
+	total := token.Estimate(systemPrompt)
+	for _, msg := range messages {
+		total += EstimateMessageTokens(msg)
+	}
+	return total
+}
+
+// imageTokens is what an image is taken to cost: about what a screenshot
+// scaled to the Anthropic API's largest size does. Images are billed by
+// size, not by their encoded length.
+const imageTokens = 1600
+
+// EstimateMessageTokens approximates the token cost of a single message.
+func EstimateMessageTokens(msg Message) int {
+	total := token.Estimate(msg.Content) + imageTokens*len(msg.Images)
+	for _, tc := range msg.ToolCalls {
+		total += token.Estimate(tc.Name) + token.Estimate(string(tc.Input))
+	}
+	for _, tr := range msg.ToolResults {
+		total += token.Estimate(tr.Content) + imageTokens*len(tr.Images)
+	}
+	return total
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToolCallError reports streamed tool-call arguments that were not valid
+// JSON and could not be repaired.
+type ToolCallError struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+func (e *ToolCallError) Error() string {
+	return fmt.Sprintf("tool call %s (%s) has malformed arguments: %s", e.ID, e.Name, e.Arguments)
+}
+
+// finishToolCalls validates the arguments accumulated for each streamed
+// tool call, repairing them where possible.
+func finishToolCalls(calls []ToolCall) ([]ToolCall, error) {
+	finished := make([]ToolCall, 0, len(calls))
+	for _, tc := range calls {
+		args, ok := repairArguments(string(tc.Input))
+		if !ok {
+			return nil, &ToolCallError{ID: tc.ID, Name: tc.Name, Arguments: string(tc.Input)}
+		}
+		tc.Input = json.RawMessage(args)
+		finished = append(finished, tc)
+	}
+	return finished, nil
+}
+
+// repairArguments returns args as valid JSON, or false if it cannot.
+// Streams that repeat the final chunk or add a stray brace are common, so
+// it keeps the first object when that one is complete. Arguments that were
+// cut off are never completed: closing a string, number or list would run
+// the tool on less than the model meant, such as "rm -rf /tmp/build" cut
+// to "rm -rf /" or a list of paths missing its last entries, so the model
+// is told to send them again instead. Empty arguments mean a tool without
+// parameters; anything other than an object is rejected.
+func repairArguments(args string) (string, bool) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return "{}", true
+	}
+	if args[0] != '{' {
+		return "", false
+	}
+	if json.Valid([]byte(args)) {
+		return args, true
+	}
+
+	return firstObject(args)
+}
+
+// firstObject returns the leading JSON object of s if it is complete, as
+// when a stream repeats the arguments or adds a stray brace.
+func firstObject(s string) (string, bool) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return "", false
+	}
+	return string(raw), true
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRepairArguments(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"valid", `{"path":"a.go"}`, `{"path":"a.go"}`},
+		{"empty", "  ", `{}`},
+		{"brace in string", `{"s":"}{"}`, `{"s":"}{"}`},
+		{"repeated", `{"a":1}{"a":1}`, `{"a":1}`},
+		{"extra brace", `{"a":1}}`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := repairArguments(tt.in)
+			if !ok || got != tt.want {
+				t.Errorf("repairArguments(%q) = %q, %v; want %q", tt.in, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairArgumentsGivesUp(t *testing.T) {
+	for _, in := range []string{
+		`{"path`, `not json`, `[1,2`, `"text"`,
+		// Cut off, which closing would turn into a different call.
+		`{"command":"rm -rf /`,
+		`{"path":"a.g`,
+		`{"cmd":"echo \`,
+		`{"limit":12`,
+		`{"paths":["a","b"`,
+		`{"paths":["a","b",`,
+		`{"a":[1,{"b":"c"`,
+		`{"path":"a.go"`,
+		`{"force":true`,
+		`{"a":1,`,
+		`{"a":`,
+	} {
+		if got, ok := repairArguments(in); ok {
+			t.Errorf("repairArguments(%q) = %q, want failure", in, got)
+		}
+	}
+}
+
+func streamResponse(events ...string) *http.Response {
+	var b strings.Builder
+	for _, e := range events {
+		b.WriteString("data: " + e + "\n\n")
+	}
+	return &http.Response{Body: io.NopCloser(strings.NewReader(b.String()))}
+}
+
+func collect(t *testing.T, resp *http.Response) []StreamDelta {
+	t.Helper()
+	ch := make(chan StreamDelta, 100)
+	(&Saturn{}).processStream(context.Background(), resp, ch)
+	var deltas []StreamDelta
+	for d := range ch {
+		deltas = append(deltas, d)
+	}
+	if len(deltas) == 0 || !deltas[len(deltas)-1].Done {
+		t.Fatalf("stream did not end with a Done delta: %+v", deltas)
+	}
+	return deltas
+}
+
+func TestProcessStreamJoinsToolCall(t *testing.T) {
+	deltas := collect(t, streamResponse(
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"read_file","arguments":"{\"path\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"main.go\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+	))
+
+	final := deltas[len(deltas)-1]
+	if final.Error != nil {
+		t.Fatalf("unexpected error: %v", final.Error)
+	}
+	if len(final.ToolCalls) != 1 || string(final.ToolCalls[0].Input) != `{"path":"main.go"}` || final.ToolCalls[0].Name != "read_file" {
+		t.Errorf("final tool calls = %+v", final.ToolCalls)
+	}
+	// Partial deltas are snapshots and must not change afterwards.
+	if first := deltas[0].ToolCall; first == nil || string(first.Input) != `{"path":` {
+		t.Errorf("first partial = %+v", first)
+	}
+}
+
+func TestProcessStreamMalformedToolCall(t *testing.T) {
+	deltas := collect(t, streamResponse(
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"bash","arguments":"{\"comm"}}]}}]}`,
+		`[DONE]`,
+	))
+
+	var tcErr *ToolCallError
+	if err := deltas[len(deltas)-1].Error; !errors.As(err, &tcErr) || tcErr.Name != "bash" || tcErr.ID != "call_1" {
+		t.Errorf("final error = %v, want *ToolCallError for bash", err)
+	}
+}
+
+func TestProcessStreamToolCallCutInArray(t *testing.T) {
+	// The stream ends before the list does; running the tool on the
+	// paths that arrived would quietly skip the rest.
+	deltas := collect(t, streamResponse(
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"read_files","arguments":"{\"paths\":[\"a\",\"b\""}}]}}]}`,
+		`[DONE]`,
+	))
+
+	var tcErr *ToolCallError
+	final := deltas[len(deltas)-1]
+	if !errors.As(final.Error, &tcErr) || len(final.ToolCalls) != 0 {
+		t.Errorf("final delta = %+v, want a *ToolCallError and no tool calls", final)
+	}
+}
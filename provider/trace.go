@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"brutus/telemetry"
+)
+
+// traceStream passes a stream on, recording its token counts and any error
+// on span and ending span when the stream closes.
+func traceStream(span trace.Span, in <-chan StreamDelta) <-chan StreamDelta {
+	out := make(chan StreamDelta, cap(in))
+	go func() {
+		defer close(out)
+		var err error
+		for delta := range in {
+			if delta.Error != nil {
+				err = delta.Error
+			}
+			if delta.Usage != nil {
+				span.SetAttributes(
+					attribute.Int("gen_ai.usage.input_tokens", delta.Usage.PromptTokens),
+					attribute.Int("gen_ai.usage.output_tokens", delta.Usage.CompletionTokens))
+			}
+			out <- delta
+		}
+		telemetry.End(span, err)
+	}()
+	return out
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestChatStreamSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	s := &Saturn{service: &SaturnService{Name: "s", APIBase: srv.URL + "/v1"}, httpClient: http.DefaultClient, model: "m"}
+	stream, err := s.ChatStream(context.Background(), "", []Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Ended()) != 0 {
+		t.Fatal("span ended before the stream did")
+	}
+	for range stream {
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "chat m" {
+		t.Fatalf("spans = %v", spans)
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range spans[0].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["gen_ai.usage.input_tokens"].AsInt64() != 10 || attrs["gen_ai.usage.output_tokens"].AsInt64() != 2 {
+		t.Errorf("attributes = %v", spans[0].Attributes())
+	}
+}
@@ -0,0 +1,96 @@
+package provider
+
+import "strings"
+
+// Usage is the tokens one response, or a run of them, took and what they
+// cost. Providers fill it in from the service's own counts.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+
+	// CacheReadTokens and CacheWriteTokens are the prompt tokens read
+	// from and written to the service's prompt cache, where it says; they
+	// are counted in PromptTokens too.
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+
+	// Cost is in US dollars: what the service reported, else the model's
+	// list price. Zero for local models and models without a known price.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// Add adds another response's usage to u.
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.Cost += other.Cost
+}
+
+// Total is the prompt and completion tokens together.
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// price is a model's list price in dollars per million tokens. Prompt
+// tokens read from or written to the cache are billed at their own rates;
+// zero is the prompt rate.
+type price struct {
+	prompt, completion    float64
+	cacheRead, cacheWrite float64
+}
+
+// prices holds list prices by model name prefix; the longest matching
+// prefix wins, so dated releases share their family's price.
+var prices = map[string]price{
+	"claude-opus-4-5":   {5, 25, 0.5, 6.25},
+	"claude-opus-4":     {15, 75, 1.5, 18.75},
+	"claude-sonnet-4":   {3, 15, 0.3, 3.75},
+	"claude-3-7-sonnet": {3, 15, 0.3, 3.75},
+	"claude-3-5-sonnet": {3, 15, 0.3, 3.75},
+	"claude-haiku-4-5":  {1, 5, 0.1, 1.25},
+	"claude-3-5-haiku":  {0.8, 4, 0.08, 1},
+	"claude-3-haiku":    {0.25, 1.25, 0.03, 0.3},
+	"gpt-4o-mini":       {0.15, 0.6, 0.075, 0},
+	"gpt-4o":            {2.5, 10, 1.25, 0},
+	"gpt-4.1-nano":      {0.1, 0.4, 0.025, 0},
+	"gpt-4.1-mini":      {0.4, 1.6, 0.1, 0},
+	"gpt-4.1":           {2, 8, 0.5, 0},
+}
+
+// ListCost prices u at model's list price, or returns 0 if the model is
+// unknown. A vendor prefix such as OpenRouter's "anthropic/" is ignored.
+func ListCost(model string, u Usage) float64 {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	var best string
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return 0
+	}
+	p := prices[best]
+	if p.cacheRead == 0 {
+		p.cacheRead = p.prompt
+	}
+	if p.cacheWrite == 0 {
+		p.cacheWrite = p.prompt
+	}
+	uncached := u.PromptTokens - u.CacheReadTokens - u.CacheWriteTokens
+	return (float64(uncached)*p.prompt + float64(u.CacheReadTokens)*p.cacheRead +
+		float64(u.CacheWriteTokens)*p.cacheWrite + float64(u.CompletionTokens)*p.completion) / 1e6
+}
+
+// priced returns u with its cost filled in from the list price when the
+// service didn't report one.
+func priced(model string, u Usage) *Usage {
+	if u.Cost == 0 {
+		u.Cost = ListCost(model, u)
+	}
+	return &u
+}
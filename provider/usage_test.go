@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListCost(t *testing.T) {
+	u := Usage{PromptTokens: 1000000, CompletionTokens: 100000}
+	for model, want := range map[string]float64{
+		"claude-sonnet-4-20250514":  4.5,
+		"anthropic/claude-sonnet-4": 4.5,
+		"claude-opus-4-5-20251101":  7.5,
+		"claude-opus-4-1":           22.5,
+		"gpt-4o-mini":               0.21,
+		"llama-3.1-8b-instruct":     0,
+		"":                          0,
+	} {
+		if got := ListCost(model, u); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%q: got %v, want %v", model, got, want)
+		}
+	}
+	// Cached prompt tokens are billed at the cache read rate: OpenAI's
+	// half price for gpt-4o.
+	cached := Usage{PromptTokens: 1000000, CacheReadTokens: 600000}
+	if got := ListCost("gpt-4o", cached); math.Abs(got-(0.4*2.5+0.6*1.25)) > 1e-9 {
+		t.Errorf("cached gpt-4o: got %v", got)
+	}
+}
+
+func TestProcessStreamUsage(t *testing.T) {
+	// The usage chunk follows the one with the finish reason, as OpenAI
+	// and vLLM send it.
+	s := &Saturn{model: "gpt-4o"}
+	ch := make(chan StreamDelta, 10)
+	s.processStream(context.Background(), streamResponse(
+		`{"choices":[{"delta":{"content":"hi"},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":1000,"completion_tokens":100,"prompt_tokens_details":{"cached_tokens":400}}}`,
+		`[DONE]`,
+	), ch)
+	var final StreamDelta
+	for d := range ch {
+		final = d
+	}
+	if u := final.Usage; u == nil || u.PromptTokens != 1000 || u.CacheReadTokens != 400 || u.CompletionTokens != 100 || math.Abs(u.Cost-0.003) > 1e-9 {
+		t.Errorf("final usage = %+v", final.Usage)
+	}
+
+	// Without one, the stream still ends.
+	deltas := collect(t, streamResponse(`{"choices":[{"delta":{"content":"hi"},"finish_reason":"stop"}]}`))
+	if deltas[len(deltas)-1].Usage != nil {
+		t.Error("usage reported for a stream without any")
+	}
+}
+
+func TestChatUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":10,"completion_tokens":2,"cost":0.5}}`)
+	}))
+	defer srv.Close()
+
+	o, err := NewOpenAI(OpenAIConfig{BaseURL: srv.URL, Model: "claude-sonnet-4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := o.Chat(context.Background(), "", []Message{{Role: "user", Content: "hello"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The service's own cost wins over the list price.
+	if u := msg.Usage; u == nil || u.Total() != 12 || u.Cost != 0.5 {
+		t.Errorf("usage = %+v", msg.Usage)
+	}
+}
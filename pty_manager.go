@@ -6,20 +6,35 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	goruntime "runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type PTYSession struct {
 	ID      string
+	shell   string
 	cmd     *exec.Cmd
 	stdin   io.WriteCloser
 	stdout  io.ReadCloser
 	cancel  context.CancelFunc
 	running bool
 	mu      sync.Mutex
+	capture *ptyCapture
+}
+
+// ptyCapture collects session output for a command run via Exec until the
+// completion marker (followed by the exit code) shows up in the stream.
+type ptyCapture struct {
+	marker string
+	buf    strings.Builder
+	done   chan struct{}
+	closed bool
 }
 
 type PTYManager struct {
@@ -84,6 +99,7 @@ func (m *PTYManager) Spawn(shell string) (string, error) {
 
 	session := &PTYSession{
 		ID:      id,
+		shell:   shell,
 		cmd:     cmd,
 		stdin:   stdin,
 		stdout:  stdout,
@@ -116,6 +132,7 @@ func (m *PTYManager) readOutput(session *PTYSession) {
 				"id":   session.ID,
 				"data": data,
 			})
+			session.feedCapture(data)
 		}
 		if err != nil {
 			break
@@ -128,6 +145,10 @@ func (m *PTYManager) waitForExit(session *PTYSession) {
 
 	session.mu.Lock()
 	session.running = false
+	if c := session.capture; c != nil && !c.closed {
+		c.closed = true
+		close(c.done)
+	}
 	session.mu.Unlock()
 
 	wailsRuntime.EventsEmit(m.ctx, "pty:exit", map[string]any{
@@ -156,6 +177,147 @@ func (m *PTYManager) Write(id string, data string) error {
 	return err
 }
 
+// ptyExecTimeout bounds how long an agent command may run in a PTY session.
+const ptyExecTimeout = 2 * time.Minute
+
+func (m *PTYManager) Has(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.sessions[id]
+	return ok
+}
+
+// Exec runs a command inside an existing session, so anyone watching the
+// terminal sees it execute, and returns the command's output once a
+// completion marker is echoed back by the shell. env, KEY=VALUE, is set in
+// the session's shell before the command runs.
+func (m *PTYManager) Exec(id string, command string, env []string, timeout time.Duration) (string, error) {
+	m.mu.RLock()
+	session, ok := m.sessions[id]
+	m.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("session not found: %s", id)
+	}
+
+	marker := fmt.Sprintf("__BRUTUS_DONE_%d__", time.Now().UnixNano())
+	capture := &ptyCapture{marker: marker, done: make(chan struct{})}
+
+	session.mu.Lock()
+	if !session.running {
+		session.mu.Unlock()
+		return "", fmt.Errorf("session not running: %s", id)
+	}
+	if session.capture != nil {
+		session.mu.Unlock()
+		return "", fmt.Errorf("session %s is busy running another agent command", id)
+	}
+	session.capture = capture
+	session.mu.Unlock()
+
+	defer func() {
+		session.mu.Lock()
+		session.capture = nil
+		session.mu.Unlock()
+	}()
+
+	// The shell reads from a pipe and does not echo input, so echo the
+	// command ourselves to make it visible in the terminal panel.
+	wailsRuntime.EventsEmit(m.ctx, "pty:data", map[string]string{
+		"id":   id,
+		"data": "$ " + command + "\r\n",
+	})
+
+	script := envCommands(session.shell, env) + command + "\n" + markerCommand(session.shell, marker) + "\n"
+	if err := m.Write(id, script); err != nil {
+		return "", err
+	}
+
+	select {
+	case <-capture.done:
+	case <-time.After(timeout):
+		return "", fmt.Errorf("command timed out after %s", timeout)
+	}
+
+	session.mu.Lock()
+	raw := capture.buf.String()
+	session.mu.Unlock()
+
+	if !strings.Contains(raw, marker) {
+		return "", fmt.Errorf("session exited before command completed\nOutput: %s", strings.TrimSpace(raw))
+	}
+
+	output, exitCode := splitMarker(raw, marker)
+	if exitCode != 0 {
+		return fmt.Sprintf("Command failed: exit status %d\nOutput: %s", exitCode, output), nil
+	}
+	return output, nil
+}
+
+func (s *PTYSession) feedCapture(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.capture
+	if c == nil || c.closed {
+		return
+	}
+	c.buf.WriteString(data)
+
+	text := c.buf.String()
+	idx := strings.Index(text, c.marker)
+	if idx >= 0 && strings.Contains(text[idx:], "\n") {
+		c.closed = true
+		close(c.done)
+	}
+}
+
+// markerCommand prints the completion marker and the previous command's
+// exit code in the syntax of the given shell.
+func markerCommand(shell, marker string) string {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(shell), filepath.Ext(shell)))
+	switch base {
+	case "powershell", "pwsh":
+		return fmt.Sprintf("Write-Output \"%s$LASTEXITCODE\"", marker)
+	case "cmd":
+		return fmt.Sprintf("echo %s%%ERRORLEVEL%%", marker)
+	default:
+		return fmt.Sprintf("echo \"%s$?\"", marker)
+	}
+}
+
+// envCommands returns the lines that set env in shell, quoted for it.
+func envCommands(shell string, env []string) string {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(shell), filepath.Ext(shell)))
+	var b strings.Builder
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		switch base {
+		case "powershell", "pwsh":
+			fmt.Fprintf(&b, "$env:%s = '%s'\n", key, strings.ReplaceAll(value, "'", "''"))
+		case "cmd":
+			fmt.Fprintf(&b, "set \"%s=%s\"\n", key, value)
+		default:
+			fmt.Fprintf(&b, "export %s='%s'\n", key, strings.ReplaceAll(value, "'", `'\''`))
+		}
+	}
+	return b.String()
+}
+
+func splitMarker(raw, marker string) (string, int) {
+	idx := strings.Index(raw, marker)
+	output := strings.TrimSpace(raw[:idx])
+	rest := raw[idx+len(marker):]
+	if nl := strings.IndexAny(rest, "\r\n"); nl >= 0 {
+		rest = rest[:nl]
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(rest))
+	if err != nil {
+		return output, 0
+	}
+	return output, code
+}
+
 func (m *PTYManager) Kill(id string) error {
 	m.mu.Lock()
 	session, ok := m.sessions[id]
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"brutus/sdk"
+)
+
+var scenarioDir = filepath.Join(".brutus", "scenarios")
+
+type ScenarioInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
+	AgentCount  int    `json:"agentCount"`
+}
+
+func defaultDemoScenario() *sdk.MultiAgentScenario {
+	return &sdk.MultiAgentScenario{
+		Name:        "Multi-Agent Demo",
+		Description: "Two editors working on separate files while an observer watches the network.",
+		Agents: []sdk.MultiAgentScenarioAgent{
+			{
+				ID:           "Editor-1",
+				UserMessages: []string{"Edit the file mock1.txt and add a greeting function that returns 'Hello, World!'"},
+				StartDelayMs: 100,
+			},
+			{
+				ID:           "Editor-2",
+				UserMessages: []string{"Edit the file mock2.txt and add a farewell function that returns 'Goodbye!'"},
+				StartDelayMs: 100,
+			},
+			{
+				ID:           "Observer",
+				UserMessages: []string{"Use the observe_agents tool to discover other agents on the network, then summarize their activity."},
+				StartDelayMs: 500,
+			},
+		},
+	}
+}
+
+func (a *App) ListScenarios() ([]ScenarioInfo, error) {
+	entries, err := os.ReadDir(scenarioDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ScenarioInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to read scenarios: %w", err)
+	}
+
+	infos := []ScenarioInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		path := filepath.Join(scenarioDir, entry.Name())
+		scenario, err := sdk.LoadMultiAgentScenario(path)
+		if err != nil {
+			continue
+		}
+
+		name := scenario.Name
+		if name == "" {
+			name = strings.TrimSuffix(entry.Name(), ".json")
+		}
+		infos = append(infos, ScenarioInfo{
+			Name:        name,
+			Description: scenario.Description,
+			Path:        path,
+			AgentCount:  len(scenario.Agents),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+func (a *App) LaunchScenario(path string) ([]string, error) {
+	scenario, err := sdk.LoadMultiAgentScenario(path)
+	if err != nil {
+		return nil, err
+	}
+	return a.launchScenario(scenario)
+}
+
+func (a *App) LaunchMultiAgentDemo() ([]string, error) {
+	return a.launchScenario(defaultDemoScenario())
+}
+
+func (a *App) launchScenario(scenario *sdk.MultiAgentScenario) ([]string, error) {
+	if len(scenario.Agents) == 0 {
+		return nil, fmt.Errorf("scenario %q defines no agents", scenario.Name)
+	}
+
+	ids := []string{}
+	for _, agentCfg := range scenario.Agents {
+		id, err := a.NewNamedAgent(agentCfg.ID, agentCfg.Model)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+
+		if agentCfg.SystemPrompt != "" {
+			if err := a.SetSystemPrompt(id, agentCfg.SystemPrompt); err != nil {
+				return ids, err
+			}
+		}
+	}
+
+	for i, agentCfg := range scenario.Agents {
+		go func(id string, cfg sdk.MultiAgentScenarioAgent) {
+			time.Sleep(time.Duration(cfg.StartDelayMs) * time.Millisecond)
+			for _, msg := range cfg.UserMessages {
+				if err := a.sendMessageSync(id, msg); err != nil {
+					return
+				}
+			}
+		}(ids[i], agentCfg)
+	}
+
+	return ids, nil
+}
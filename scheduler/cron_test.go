@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2026, 3, 4, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * * *", "2026-03-04 10:31"},
+		{"*/15 * * * *", "2026-03-04 10:45"},
+		{"0 2 * * *", "2026-03-05 02:00"},
+		{"@daily", "2026-03-05 00:00"},
+		{"@hourly", "2026-03-04 11:00"},
+		{"30 9 * * mon-fri", "2026-03-05 09:30"},
+		{"0 0 * * 7", "2026-03-08 00:00"},
+		{"0 0 1 jan *", "2027-01-01 00:00"},
+		{"5,35 10 * * *", "2026-03-04 10:35"},
+		{"10/20 * * * *", "2026-03-04 10:50"},
+		// Both day fields restricted: either one matching is enough.
+		{"0 12 15 * fri", "2026-03-06 12:00"},
+		{"0 0 29 2 *", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from).Format("2006-01-02 15:04"); got != tt.want {
+			t.Errorf("Parse(%q).Next = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next = %v, want zero time for February 30", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is a named function run on a schedule.
+type Job struct {
+	Name     string
+	Spec     string // the cron expression, as configured
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+}
+
+// JobStatus describes a job's schedule and its most recent run.
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Spec      string    `json:"cron"`
+	Next      time.Time `json:"next"`
+	Running   bool      `json:"running"`
+	LastStart time.Time `json:"last_start,omitempty"`
+	LastEnd   time.Time `json:"last_end,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Scheduler starts jobs when their schedules come due. A job that is still
+// running when it comes due again is skipped rather than run twice, so a
+// slow nightly task can't pile up copies of itself.
+type Scheduler struct {
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	jobs   map[string]*entry
+	wg     sync.WaitGroup
+	wakeup chan struct{}
+}
+
+type entry struct {
+	job    Job
+	next   time.Time
+	status JobStatus
+}
+
+// New creates a scheduler. A nil logger discards its output.
+func New(logger *slog.Logger) *Scheduler {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Scheduler{
+		logger: logger,
+		now:    time.Now,
+		jobs:   make(map[string]*entry),
+		wakeup: make(chan struct{}, 1),
+	}
+}
+
+// Add schedules run under name with the cron expression spec.
+func (s *Scheduler) Add(name, spec string, run func(ctx context.Context) error) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %q already scheduled", name)
+	}
+	e := &entry{job: Job{Name: name, Spec: spec, Schedule: schedule, Run: run}}
+	e.next = schedule.Next(s.now())
+	e.status = JobStatus{Name: name, Spec: spec}
+	s.jobs[name] = e
+
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run starts due jobs until ctx is cancelled, then waits for running jobs
+// to return. Jobs get a context that is cancelled along with ctx.
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.wg.Wait()
+	for {
+		wait := s.startDue(ctx)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wakeup:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// maxWait caps how long Run sleeps at once, so it notices clock changes
+// such as a laptop waking from sleep.
+const maxWait = time.Minute
+
+// startDue launches every job whose time has come and returns how long to
+// sleep until the next one.
+func (s *Scheduler) startDue(ctx context.Context) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	wait := maxWait
+	for _, e := range s.jobs {
+		if e.next.IsZero() {
+			continue
+		}
+		if !e.next.After(now) {
+			if e.status.Running {
+				s.logger.Warn("scheduled job skipped, previous run still going", "job", e.job.Name)
+			} else {
+				s.start(ctx, e, now)
+			}
+			e.next = e.job.Schedule.Next(now)
+		}
+		if !e.next.IsZero() {
+			wait = min(wait, e.next.Sub(now))
+		}
+	}
+	return max(wait, time.Second)
+}
+
+// start runs e in its own goroutine. s.mu must be held.
+func (s *Scheduler) start(ctx context.Context, e *entry, now time.Time) {
+	e.status.Running = true
+	e.status.LastStart = now
+	s.logger.Info("scheduled job started", "job", e.job.Name)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := e.job.Run(ctx)
+
+		s.mu.Lock()
+		e.status.Running = false
+		e.status.LastEnd = s.now()
+		e.status.LastError = ""
+		if err != nil {
+			e.status.LastError = err.Error()
+		}
+		duration := e.status.LastEnd.Sub(e.status.LastStart)
+		s.mu.Unlock()
+
+		if err != nil {
+			s.logger.Error("scheduled job failed", "job", e.job.Name, "duration_ms", duration.Milliseconds(), "error", err)
+		} else {
+			s.logger.Info("scheduled job finished", "job", e.job.Name, "duration_ms", duration.Milliseconds())
+		}
+	}()
+}
+
+// Jobs returns the status of every job, sorted by name.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]JobStatus, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		status := e.status
+		status.Next = e.next
+		jobs = append(jobs, status)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
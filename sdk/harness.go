@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
+	"brutus/agent"
+	"brutus/internal/text"
 	"brutus/provider"
 	"brutus/tools"
 )
@@ -23,6 +26,10 @@ type TestHarness struct {
 	toolCalls    []provider.ToolCall
 	toolResults  []provider.ToolResult
 	errors       []error
+	timing       provider.Timing
+
+	verifyCommands []string
+	verification   []agent.VerifyResult
 }
 
 func NewHarness() *TestHarness {
@@ -48,6 +55,14 @@ func (h *TestHarness) WithVerbose(v bool) *TestHarness {
 	return h
 }
 
+// WithVerification has Run check the model's work with commands, run in
+// the working directory, once it finishes a run in which it called tools.
+// Failures are sent back to it as a new message, like agent.Agent does.
+func (h *TestHarness) WithVerification(commands ...string) *TestHarness {
+	h.verifyCommands = commands
+	return h
+}
+
 func (h *TestHarness) WithTool(t tools.Tool) *TestHarness {
 	h.registry.Register(t)
 	return h
@@ -95,13 +110,45 @@ func (h *TestHarness) Run(ctx context.Context) error {
 		return fmt.Errorf("no user messages to process")
 	}
 
-	response, err := h.provider.Chat(ctx, h.systemPrompt, h.conversation, h.registry.All())
+	response, err := h.chat(ctx)
 	if err != nil {
 		h.errors = append(h.errors, err)
 		return err
 	}
 	h.conversation = append(h.conversation, response)
 
+	callsBefore := len(h.toolCalls)
+	for round := 1; ; round++ {
+		if err := h.runTools(ctx, &response); err != nil {
+			return err
+		}
+
+		if len(h.verifyCommands) == 0 || len(h.toolCalls) == callsBefore {
+			return nil
+		}
+		h.verification = agent.RunVerification(ctx, h.workingDir, h.verifyCommands)
+		report := agent.VerificationReport(h.verification)
+		if report == "" {
+			return nil
+		}
+		if round > agent.MaxVerifyRounds {
+			err := fmt.Errorf("verification still failing after %d rounds", agent.MaxVerifyRounds)
+			h.errors = append(h.errors, err)
+			return err
+		}
+		h.conversation = append(h.conversation, provider.Message{Role: "user", Content: report})
+		if response, err = h.chat(ctx); err != nil {
+			h.errors = append(h.errors, err)
+			return err
+		}
+		h.conversation = append(h.conversation, response)
+	}
+}
+
+// runTools executes the tool calls in response and sends the results back
+// until the model replies without any, leaving that reply in response.
+func (h *TestHarness) runTools(ctx context.Context, response *provider.Message) error {
+	var err error
 	for len(response.ToolCalls) > 0 {
 		var toolResults []provider.ToolResult
 
@@ -116,7 +163,7 @@ func (h *TestHarness) Run(ctx context.Context) error {
 			if !ok {
 				result := provider.ToolResult{
 					ID:      tc.ID,
-					Content: fmt.Sprintf("tool '%s' not found", tc.Name),
+					Content: tools.ErrorResult(tools.NewError(tools.ErrNotFound, "tool '%s' not found", tc.Name)),
 					IsError: true,
 				}
 				toolResults = append(toolResults, result)
@@ -124,24 +171,22 @@ func (h *TestHarness) Run(ctx context.Context) error {
 				continue
 			}
 
-			output, toolErr := tool.Function(tc.Input)
+			toolStart := time.Now()
+			output, toolErr := tool.Call(ctx, tc.Input)
+			h.timing.AddTool(time.Since(toolStart))
 			result := provider.ToolResult{
 				ID:      tc.ID,
 				Content: output,
 				IsError: toolErr != nil,
 			}
 			if toolErr != nil {
-				result.Content = toolErr.Error()
+				result.Content = tools.ErrorResult(toolErr)
 			}
 			toolResults = append(toolResults, result)
 			h.toolResults = append(h.toolResults, result)
 
 			if h.verbose {
-				if len(output) > 200 {
-					fmt.Printf("[harness] result: %s...\n", output[:200])
-				} else {
-					fmt.Printf("[harness] result: %s\n", output)
-				}
+				fmt.Printf("[harness] result: %s\n", text.Head(output, 200))
 			}
 		}
 
@@ -150,17 +195,23 @@ func (h *TestHarness) Run(ctx context.Context) error {
 			ToolResults: toolResults,
 		})
 
-		response, err = h.provider.Chat(ctx, h.systemPrompt, h.conversation, h.registry.All())
+		*response, err = h.chat(ctx)
 		if err != nil {
 			h.errors = append(h.errors, err)
 			return err
 		}
-		h.conversation = append(h.conversation, response)
+		h.conversation = append(h.conversation, *response)
 	}
 
 	return nil
 }
 
+func (h *TestHarness) chat(ctx context.Context) (provider.Message, error) {
+	reqCtx, done := h.timing.Request(ctx)
+	defer done()
+	return h.provider.Chat(reqCtx, h.systemPrompt, h.conversation, h.registry.All())
+}
+
 func (h *TestHarness) RunMultiple(ctx context.Context, messages []string) error {
 	for _, msg := range messages {
 		h.SendUserMessage(msg)
@@ -211,6 +262,34 @@ func (h *TestHarness) Reset() {
 	h.toolCalls = nil
 	h.toolResults = nil
 	h.errors = nil
+	h.verification = nil
+	h.timing.Reset()
+}
+
+// Verification returns the results of the latest verification run, or nil
+// if none has run.
+func (h *TestHarness) Verification() []agent.VerifyResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.verification
+}
+
+// Verified reports whether verification ran and every check passed.
+func (h *TestHarness) Verified() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.verification {
+		if !r.Passed {
+			return false
+		}
+	}
+	return len(h.verification) > 0
+}
+
+// Timing returns the time spent on model requests and tools since the
+// harness was created or last reset.
+func (h *TestHarness) Timing() provider.TimingStats {
+	return h.timing.Stats()
 }
 
 func (h *TestHarness) ToolWasCalled(name string) bool {
@@ -281,6 +360,31 @@ func (h *TestHarness) GetToolResult(name string) (string, bool) {
 	return "", false
 }
 
+// GetToolError returns the structured error from the first failed call to
+// the named tool.
+func (h *TestHarness) GetToolError(name string) (*tools.ToolError, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, tc := range h.toolCalls {
+		if tc.Name == name && i < len(h.toolResults) && h.toolResults[i].IsError {
+			return tools.ParseErrorResult(h.toolResults[i].Content)
+		}
+	}
+	return nil, false
+}
+
+// AssertToolErrorCode checks that a call to the named tool failed with code.
+func (h *TestHarness) AssertToolErrorCode(name string, code tools.ErrorCode) error {
+	toolErr, ok := h.GetToolError(name)
+	if !ok {
+		return fmt.Errorf("no failed call to tool '%s'", name)
+	}
+	if toolErr.Code != code {
+		return fmt.Errorf("tool '%s' failed with %s, want %s", name, toolErr.Code, code)
+	}
+	return nil
+}
+
 func (h *TestHarness) AssertConversationContains(substring string) error {
 	for _, msg := range h.conversation {
 		if strings.Contains(msg.Content, substring) {
@@ -25,6 +25,7 @@ type LiveAgentResult struct {
 	Conversation []provider.Message
 	Error        error
 	Duration     time.Duration
+	Timing       provider.TimingStats
 }
 
 type LiveMultiAgentHarness struct {
@@ -105,6 +106,12 @@ func (h *LiveMultiAgentHarness) RunSequential(ctx context.Context, agents []Live
 
 func (h *LiveMultiAgentHarness) runSingleAgent(ctx context.Context, cfg LiveAgentConfig) LiveAgentResult {
 	start := time.Now()
+	var timing provider.Timing
+	defer func() {
+		if h.verbose {
+			fmt.Printf("[%s] Timing: %s\n", cfg.ID, timing.Stats())
+		}
+	}()
 
 	result := LiveAgentResult{
 		AgentID: cfg.ID,
@@ -131,10 +138,13 @@ func (h *LiveMultiAgentHarness) runSingleAgent(ctx context.Context, cfg LiveAgen
 			fmt.Printf("[%s] Turn %d: sending to LLM\n", cfg.ID, turn)
 		}
 
-		response, err := p.Chat(ctx, cfg.SystemPrompt, conversation, h.registry.All())
+		reqCtx, done := timing.Request(ctx)
+		response, err := p.Chat(reqCtx, cfg.SystemPrompt, conversation, h.registry.All())
+		done()
 		if err != nil {
 			result.Error = fmt.Errorf("chat failed on turn %d: %w", turn, err)
 			result.Duration = time.Since(start)
+			result.Timing = timing.Stats()
 			result.Conversation = conversation
 			return result
 		}
@@ -158,20 +168,22 @@ func (h *LiveMultiAgentHarness) runSingleAgent(ctx context.Context, cfg LiveAgen
 			if !ok {
 				toolResults = append(toolResults, provider.ToolResult{
 					ID:      tc.ID,
-					Content: fmt.Sprintf("tool '%s' not found", tc.Name),
+					Content: tools.ErrorResult(tools.NewError(tools.ErrNotFound, "tool '%s' not found", tc.Name)),
 					IsError: true,
 				})
 				continue
 			}
 
-			output, toolErr := tool.Function(tc.Input)
+			toolStart := time.Now()
+			output, toolErr := tool.Call(ctx, tc.Input)
+			timing.AddTool(time.Since(toolStart))
 			tr := provider.ToolResult{
 				ID:      tc.ID,
 				Content: output,
 				IsError: toolErr != nil,
 			}
 			if toolErr != nil {
-				tr.Content = toolErr.Error()
+				tr.Content = tools.ErrorResult(toolErr)
 			}
 			toolResults = append(toolResults, tr)
 		}
@@ -184,6 +196,7 @@ func (h *LiveMultiAgentHarness) runSingleAgent(ctx context.Context, cfg LiveAgen
 
 	result.Success = result.Error == nil
 	result.Duration = time.Since(start)
+	result.Timing = timing.Stats()
 	result.Conversation = conversation
 
 	return result
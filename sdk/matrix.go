@@ -0,0 +1,175 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Params is one combination of scenario parameters. Zero fields leave the
+// runner's default in place.
+type Params struct {
+	Model     string `json:"model,omitempty"`
+	MaxTurns  int    `json:"max_turns,omitempty"`
+	Workspace string `json:"workspace,omitempty"` // Fixture directory copied fresh for each run
+}
+
+func (p Params) String() string {
+	var parts []string
+	if p.Model != "" {
+		parts = append(parts, "model="+p.Model)
+	}
+	if p.MaxTurns > 0 {
+		parts = append(parts, fmt.Sprintf("max_turns=%d", p.MaxTurns))
+	}
+	if p.Workspace != "" {
+		parts = append(parts, "workspace="+p.Workspace)
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParamSpace lists the values a scenario may be run with. A matrix run
+// tries every combination; an empty list leaves that parameter at its
+// default.
+type ParamSpace struct {
+	Model     []string `json:"model,omitempty"`
+	MaxTurns  []int    `json:"max_turns,omitempty"`
+	Workspace []string `json:"workspace,omitempty"`
+}
+
+// Combinations returns every combination of the space's values, models
+// varying slowest. An empty space has one combination, the defaults.
+func (s ParamSpace) Combinations() []Params {
+	models := s.Model
+	if len(models) == 0 {
+		models = []string{""}
+	}
+	turns := s.MaxTurns
+	if len(turns) == 0 {
+		turns = []int{0}
+	}
+	workspaces := s.Workspace
+	if len(workspaces) == 0 {
+		workspaces = []string{""}
+	}
+
+	var combos []Params
+	for _, model := range models {
+		for _, maxTurns := range turns {
+			for _, workspace := range workspaces {
+				combos = append(combos, Params{Model: model, MaxTurns: maxTurns, Workspace: workspace})
+			}
+		}
+	}
+	return combos
+}
+
+// MatrixResult is how one combination of parameters fared over its runs.
+type MatrixResult struct {
+	Params   Params
+	Runs     int
+	Passed   int
+	Duration time.Duration // Total over all runs
+	Failures []string      // Why each failed run failed
+}
+
+// PassRate returns the fraction of runs that passed.
+func (r MatrixResult) PassRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(r.Runs)
+}
+
+// RunFunc runs a scenario once with the given parameters. A non-nil error
+// means the run failed and says why.
+type RunFunc func(ctx context.Context, p Params) error
+
+// RunMatrix runs each combination runs times, one run at a time, and
+// returns the results in combination order. It stops early if ctx is
+// cancelled, returning what has been run so far.
+func RunMatrix(ctx context.Context, combos []Params, runs int, run RunFunc) []MatrixResult {
+	runs = max(runs, 1)
+	var results []MatrixResult
+	for _, p := range combos {
+		result := MatrixResult{Params: p}
+		for i := 0; i < runs && ctx.Err() == nil; i++ {
+			start := time.Now()
+			err := run(ctx, p)
+			result.Duration += time.Since(start)
+			result.Runs++
+			if err != nil {
+				result.Failures = append(result.Failures, err.Error())
+				continue
+			}
+			result.Passed++
+		}
+		if result.Runs > 0 {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// FormatMatrix tabulates pass rates per combination.
+func FormatMatrix(results []MatrixResult) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARAMETERS\tPASSED\tRATE\tAVG TIME")
+	for _, r := range results {
+		avg := r.Duration / time.Duration(max(r.Runs, 1))
+		fmt.Fprintf(w, "%s\t%d/%d\t%.0f%%\t%s\n", r.Params, r.Passed, r.Runs, r.PassRate()*100, avg.Round(100*time.Millisecond))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// PrepareWorkspace copies the fixture directory to a new temporary
+// directory, so each run starts from the same files, and returns it along
+// with a function that removes it.
+func PrepareWorkspace(fixture string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "brutus-workspace-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	err = filepath.WalkDir(fixture, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(fixture, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy workspace %s: %w", fixture, err)
+	}
+	return dir, cleanup, nil
+}
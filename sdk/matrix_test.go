@@ -0,0 +1,65 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParamSpaceCombinations(t *testing.T) {
+	if got := (ParamSpace{}).Combinations(); len(got) != 1 || got[0] != (Params{}) {
+		t.Errorf("empty space = %v, want just the defaults", got)
+	}
+
+	got := ParamSpace{Model: []string{"a", "b"}, MaxTurns: []int{5, 10, 20}}.Combinations()
+	if len(got) != 6 {
+		t.Fatalf("%d combinations, want 6", len(got))
+	}
+	if got[0] != (Params{Model: "a", MaxTurns: 5}) || got[5] != (Params{Model: "b", MaxTurns: 20}) {
+		t.Errorf("combinations out of order: %v", got)
+	}
+}
+
+func TestRunMatrixPassRates(t *testing.T) {
+	combos := ParamSpace{Model: []string{"good", "flaky"}}.Combinations()
+	calls := 0
+	results := RunMatrix(context.Background(), combos, 4, func(ctx context.Context, p Params) error {
+		calls++
+		if p.Model == "flaky" && calls%2 == 0 {
+			return errors.New("wrong answer")
+		}
+		return nil
+	})
+
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	if r := results[0]; r.Runs != 4 || r.PassRate() != 1 {
+		t.Errorf("good: %d/%d passed", r.Passed, r.Runs)
+	}
+	if r := results[1]; r.PassRate() != 0.5 || len(r.Failures) != 2 {
+		t.Errorf("flaky: %d/%d passed, failures %v", r.Passed, r.Runs, r.Failures)
+	}
+}
+
+func TestPrepareWorkspace(t *testing.T) {
+	fixture := t.TempDir()
+	os.MkdirAll(filepath.Join(fixture, "sub"), 0755)
+	os.WriteFile(filepath.Join(fixture, "sub", "a.txt"), []byte("original"), 0644)
+
+	dir, cleanup, err := PrepareWorkspace(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("edited"), 0644)
+	if data, _ := os.ReadFile(filepath.Join(fixture, "sub", "a.txt")); string(data) != "original" {
+		t.Errorf("editing the workspace changed the fixture: %q", data)
+	}
+
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("workspace still exists after cleanup")
+	}
+}
@@ -102,11 +102,16 @@ func (m *MockProvider) ChatStream(ctx context.Context, systemPrompt string, mess
 			ch <- provider.StreamDelta{Error: err, Done: true}
 			return
 		}
-		ch <- provider.StreamDelta{Content: msg.Content, Done: true}
+		ch <- provider.StreamDelta{Content: msg.Content, Reasoning: msg.Reasoning, ToolCalls: msg.ToolCalls, Usage: msg.Usage, Done: true}
 	}()
 	return ch, nil
 }
 
+// Capabilities reports full support, so requests reach the mock unchanged.
+func (m *MockProvider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true, Reasoning: true}
+}
+
 func (m *MockProvider) Name() string {
 	return "mock"
 }
@@ -140,3 +145,10 @@ func (m *MockProvider) Reset() {
 	m.responseIndex = 0
 	m.calls = nil
 }
+
+// Pending returns the queued responses that no call has used yet.
+func (m *MockProvider) Pending() []provider.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]provider.Message(nil), m.responses[m.responseIndex:]...)
+}
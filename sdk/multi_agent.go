@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"brutus/internal/text"
 	"brutus/provider"
 	"brutus/tools"
 )
@@ -17,6 +18,10 @@ type AgentConfig struct {
 	ID           string
 	SystemPrompt string
 	WorkingDir   string
+
+	// VerificationCommands check the agent's work after each run that
+	// calls tools; see TestHarness.WithVerification.
+	VerificationCommands []string
 }
 
 type AgentResult struct {
@@ -26,6 +31,7 @@ type AgentResult struct {
 	ToolCalls        []provider.ToolCall
 	Error            error
 	Duration         time.Duration
+	Timing           provider.TimingStats
 }
 
 type MultiAgentHarness struct {
@@ -61,6 +67,9 @@ func (m *MultiAgentHarness) AddAgent(cfg AgentConfig) *MultiAgentHarness {
 	if cfg.WorkingDir != "" {
 		harness.WithWorkingDir(cfg.WorkingDir)
 	}
+	if len(cfg.VerificationCommands) > 0 {
+		harness.WithVerification(cfg.VerificationCommands...)
+	}
 
 	m.agents[cfg.ID] = harness
 	m.configs[cfg.ID] = cfg
@@ -129,6 +138,7 @@ func (m *MultiAgentHarness) RunSequential(ctx context.Context, messages map[stri
 			ToolCalls:    harness.GetToolCalls(),
 			Error:        lastErr,
 			Duration:     time.Since(start),
+			Timing:       harness.Timing(),
 		})
 	}
 
@@ -176,6 +186,7 @@ func (m *MultiAgentHarness) RunConcurrent(ctx context.Context, messages map[stri
 				ToolCalls:    harness.GetToolCalls(),
 				Error:        lastErr,
 				Duration:     time.Since(start),
+				Timing:       harness.Timing(),
 			}
 		}(agentID, msgs)
 	}
@@ -206,10 +217,7 @@ func (m *MultiAgentHarness) Summary() string {
 		sb.WriteString(fmt.Sprintf("  Tool calls: %d\n", len(harness.GetToolCalls())))
 		sb.WriteString(fmt.Sprintf("  Errors: %d\n", len(harness.GetErrors())))
 		if msg := harness.LastAssistantMessage(); msg != "" {
-			if len(msg) > 100 {
-				msg = msg[:100] + "..."
-			}
-			sb.WriteString(fmt.Sprintf("  Last message: %s\n", msg))
+			sb.WriteString(fmt.Sprintf("  Last message: %s\n", text.Head(msg, 100)))
 		}
 		sb.WriteString("\n")
 	}
@@ -241,9 +249,15 @@ type MultiAgentScenario struct {
 
 type MultiAgentScenarioAgent struct {
 	ID            string         `json:"id"`
+	Model         string         `json:"model,omitempty"`
 	SystemPrompt  string         `json:"system_prompt"`
 	UserMessages  []string       `json:"user_messages"`
 	MockResponses []MockResponse `json:"mock_responses"`
+	StartDelayMs  int            `json:"start_delay_ms,omitempty"`
+
+	// VerificationCommands are run after the agent finishes work that
+	// used tools, with failures sent back to it to fix.
+	VerificationCommands []string `json:"verification_commands,omitempty"`
 }
 
 type MultiAgentAssertion struct {
@@ -269,8 +283,9 @@ func LoadMultiAgentScenario(filename string) (*MultiAgentScenario, error) {
 func (m *MultiAgentHarness) RunScenario(ctx context.Context, scenario *MultiAgentScenario, concurrent bool) ([]AgentResult, error) {
 	for _, agentCfg := range scenario.Agents {
 		m.AddAgent(AgentConfig{
-			ID:           agentCfg.ID,
-			SystemPrompt: agentCfg.SystemPrompt,
+			ID:                   agentCfg.ID,
+			SystemPrompt:         agentCfg.SystemPrompt,
+			VerificationCommands: agentCfg.VerificationCommands,
 		})
 
 		for _, resp := range agentCfg.MockResponses {
@@ -321,6 +336,11 @@ func (m *MultiAgentHarness) ValidateAssertions(results []AgentResult, assertions
 				errors = append(errors, fmt.Errorf("agent %s: expected message to contain '%s'", 
 					assertion.AgentID, assertion.Value))
 			}
+		case "verified":
+			if !harness.Verified() {
+				errors = append(errors, fmt.Errorf("agent %s: expected verification to pass, got %+v",
+					assertion.AgentID, harness.Verification()))
+			}
 		case "success":
 			if !result.Success {
 				errors = append(errors, fmt.Errorf("agent %s: expected success but got error: %v", 
@@ -43,6 +43,9 @@ func TestMultiAgentHarness_RunSequential(t *testing.T) {
 		if !result.Success {
 			t.Errorf("Agent %s failed: %v", result.AgentID, result.Error)
 		}
+		if result.Timing.Requests != 1 || result.Timing.FirstToken > result.Timing.Inference {
+			t.Errorf("Agent %s: unexpected timing %+v", result.AgentID, result.Timing)
+		}
 	}
 }
 
@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -44,13 +45,18 @@ func (r *ToolRunner) RegisterAll(toolList ...tools.Tool) *ToolRunner {
 }
 
 func (r *ToolRunner) Execute(toolName string, inputJSON string) (string, error) {
+	return r.ExecuteContext(context.Background(), toolName, inputJSON)
+}
+
+// ExecuteContext is Execute with a context that cancels the tool.
+func (r *ToolRunner) ExecuteContext(ctx context.Context, toolName string, inputJSON string) (string, error) {
 	tool, ok := r.registry.Get(toolName)
 	if !ok {
-		return "", fmt.Errorf("tool '%s' not found in registry", toolName)
+		return "", tools.NewError(tools.ErrNotFound, "tool '%s' not found in registry", toolName)
 	}
 
 	input := json.RawMessage(inputJSON)
-	result, err := tool.Function(input)
+	result, err := tool.Call(ctx, input)
 
 	r.calls = append(r.calls, ToolExecution{
 		ToolName: toolName,
@@ -2,6 +2,8 @@ package sdk
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"brutus/tools"
@@ -115,6 +117,74 @@ func TestHarness_ToolCallCount(t *testing.T) {
 	}
 }
 
+func TestHarness_ToolErrorCode(t *testing.T) {
+	ctx := context.Background()
+	harness := NewHarness().
+		WithDefaultTools().
+		QueueToolCall("read_file", map[string]interface{}{"path": "does-not-exist.txt"}).
+		QueueToolCall("no_such_tool", map[string]interface{}{}).
+		QueueTextResponse("That file does not exist.")
+
+	harness.SendUserMessage("Read does-not-exist.txt")
+
+	if err := harness.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := harness.AssertToolErrorCode("read_file", tools.ErrNotFound); err != nil {
+		t.Error(err)
+	}
+	if err := harness.AssertToolErrorCode("no_such_tool", tools.ErrNotFound); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHarness_Verification(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "built")
+
+	harness := NewHarness().
+		WithDefaultTools().
+		WithWorkingDir(dir).
+		WithVerification("test -f built").
+		QueueToolCall("bash", map[string]interface{}{"command": "true"}).
+		QueueTextResponse("Done.").
+		QueueToolCall("bash", map[string]interface{}{"command": "touch " + marker}).
+		QueueTextResponse("Fixed the build.")
+
+	harness.SendUserMessage("Build it")
+	if err := harness.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !harness.Verified() {
+		t.Errorf("expected verification to pass after the fix, got %+v", harness.Verification())
+	}
+	if harness.LastAssistantMessage() != "Fixed the build." {
+		t.Errorf("unexpected last message: %s", harness.LastAssistantMessage())
+	}
+	reported := false
+	for _, msg := range harness.GetConversation() {
+		if msg.Role == "user" && strings.HasPrefix(msg.Content, "[verification]") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Error("expected the failing check to be reported to the model")
+	}
+
+	// A model that never fixes the problem gets a few tries, then the run fails.
+	stubborn := NewHarness().
+		WithDefaultTools().
+		WithWorkingDir(t.TempDir()).
+		WithVerification("false").
+		QueueToolCall("bash", map[string]interface{}{"command": "true"})
+	stubborn.SendUserMessage("Build it")
+	if err := stubborn.Run(ctx); err == nil || stubborn.Verified() {
+		t.Errorf("expected verification to fail the run, got %v", err)
+	}
+}
+
 func TestDefaultToolRunner(t *testing.T) {
 	runner := DefaultToolRunner()
 	
@@ -0,0 +1,88 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"brutus/provider"
+)
+
+// stateVersion is bumped when harnessState changes in a way older files
+// can't be read as.
+const stateVersion = 1
+
+// harnessState is what SaveState writes: everything a later test needs to
+// carry on from the same point. Tool registrations, the working directory
+// and verification commands are set up by the test itself and are left
+// out.
+type harnessState struct {
+	Version      int                   `json:"version"`
+	SystemPrompt string                `json:"system_prompt,omitempty"`
+	Conversation []provider.Message    `json:"conversation"`
+	ToolCalls    []provider.ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults  []provider.ToolResult `json:"tool_results,omitempty"`
+	Pending      []provider.Message    `json:"pending_responses,omitempty"` // Queued mock responses not yet used
+	Errors       []string              `json:"errors,omitempty"`
+}
+
+// SaveState writes the conversation, the tool call history and the mock
+// responses still queued to path as JSON, so a long scenario can be
+// continued by another test with LoadState, or a failing state kept as a
+// fixture.
+func (h *TestHarness) SaveState(path string) error {
+	h.mu.Lock()
+	state := harnessState{
+		Version:      stateVersion,
+		SystemPrompt: h.systemPrompt,
+		Conversation: h.conversation,
+		ToolCalls:    h.toolCalls,
+		ToolResults:  h.toolResults,
+		Pending:      h.provider.Pending(),
+	}
+	for _, err := range h.errors {
+		state.Errors = append(state.Errors, err.Error())
+	}
+	h.mu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadState resets the harness to a state written by SaveState. Responses
+// queued before the call are dropped in favour of the saved ones; more can
+// be queued afterwards. A saved system prompt replaces the harness's.
+func (h *TestHarness) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var state harnessState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("parsing harness state: %w", err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("harness state %s has version %d, want %d", path, state.Version, stateVersion)
+	}
+
+	h.Reset()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if state.SystemPrompt != "" {
+		h.systemPrompt = state.SystemPrompt
+	}
+	h.conversation = state.Conversation
+	h.toolCalls = state.ToolCalls
+	h.toolResults = state.ToolResults
+	for _, msg := range state.Errors {
+		h.errors = append(h.errors, errors.New(msg))
+	}
+	for _, msg := range state.Pending {
+		h.provider.QueueResponse(msg)
+	}
+	return nil
+}
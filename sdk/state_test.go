@@ -0,0 +1,50 @@
+package sdk
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestHarness_SaveLoadState(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	h := NewHarness().WithDefaultTools().WithSystemPrompt("be brief")
+	h.QueueToolCallWithFollowup("list_files", map[string]interface{}{"path": "."}, "Listed.")
+	h.QueueTextResponse("Second answer")
+	h.SendUserMessage("list the files")
+	if err := h.Run(ctx); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if err := h.SaveState(path); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	resumed := NewHarness().WithDefaultTools()
+	resumed.QueueTextResponse("dropped on load")
+	if err := resumed.LoadState(path); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if got, want := len(resumed.GetConversation()), len(h.GetConversation()); got != want {
+		t.Errorf("conversation has %d messages, want %d", got, want)
+	}
+	if resumed.ToolCallCount("list_files") != 1 {
+		t.Errorf("expected the list_files call to be restored")
+	}
+	if _, ok := resumed.GetToolResult("list_files"); !ok {
+		t.Error("expected the list_files result to be restored")
+	}
+
+	resumed.SendUserMessage("again")
+	if err := resumed.Run(ctx); err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+	if got := resumed.LastAssistantMessage(); got != "Second answer" {
+		t.Errorf("expected the saved queued response, got %q", got)
+	}
+	calls := resumed.GetProvider().GetCalls()
+	if len(calls) != 1 || calls[0].SystemPrompt != "be brief" {
+		t.Errorf("expected one call with the saved system prompt, got %+v", calls)
+	}
+}
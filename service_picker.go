@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+
+	"brutus/agent"
+	"brutus/config"
+	"brutus/internal/theme"
+	"brutus/provider"
+)
+
+// serviceChoice is one line of the startup picker: a service, and one of
+// its models unless it announces none.
+type serviceChoice struct {
+	service string
+	model   string
+}
+
+// pickService lets the user choose the service and model for an
+// interactive chat when discovery finds more than one choice and neither
+// was given by flag or remembered for the project, then remembers the
+// choice in the project config. With repick it asks even if a choice was
+// remembered. It reports whether it discovered services, which the
+// provider can then reuse instead of browsing again.
+func (f *agentFlags) pickService() bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return false
+	}
+	set := map[string]bool{}
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	if set["model"] || set["service"] || (!f.repick && (*f.model != "" || *f.service != "")) {
+		return false
+	}
+
+	services, err := provider.DiscoverShared(context.Background(), *f.timeout)
+	if err != nil {
+		// Connecting reports it.
+		return false
+	}
+	if filter := f.discovery.filter(); filter != nil {
+		services = provider.FilterServices(services, *filter)
+	}
+	choices, labels := serviceChoices(services)
+	if len(choices) < 2 {
+		return true
+	}
+
+	idx, err := agent.PickFromList("Choose a Saturn service and model", labels)
+	if err != nil || idx < 0 {
+		fmt.Println(theme.Muted("No choice made; using the highest priority service."))
+		return true
+	}
+	choice := choices[idx]
+	*f.service = choice.service
+	*f.model = choice.model
+
+	path := config.ProjectPath()
+	var model interface{}
+	if choice.model != "" {
+		model = choice.model
+	}
+	err = config.SetValue(path, "service", choice.service)
+	if err == nil {
+		err = config.SetValue(path, "model", model)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not remember the choice: %v\n", err)
+		return true
+	}
+	fmt.Println(theme.Muted(fmt.Sprintf("Using %s; remembered in %s ('brutus chat -pick' chooses again).", strings.Join(choice.names(), " with "), path)))
+	return true
+}
+
+func (c serviceChoice) names() []string {
+	if c.model == "" {
+		return []string{c.service}
+	}
+	return []string{c.service, c.model}
+}
+
+// serviceChoices lists every service and model pair, best priority first,
+// with aligned labels showing load and GPU.
+func serviceChoices(services []provider.SaturnService) ([]serviceChoice, []string) {
+	services = append([]provider.SaturnService(nil), services...)
+	sort.SliceStable(services, func(i, j int) bool { return services[i].Priority < services[j].Priority })
+
+	var choices []serviceChoice
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, svc := range services {
+		load := "-"
+		if svc.MaxConcurrent > 0 {
+			load = fmt.Sprintf("%d/%d", svc.CurrentLoad, svc.MaxConcurrent)
+		}
+		gpu := orDash(svc.GPU)
+		if svc.VRAMGb > 0 {
+			gpu = fmt.Sprintf("%s/%dGB", gpu, svc.VRAMGb)
+		}
+
+		models := svc.Models
+		if len(models) == 0 {
+			models = []string{""}
+		}
+		for _, model := range models {
+			choices = append(choices, serviceChoice{service: svc.Name, model: model})
+			label := model
+			if label == "" {
+				label = "(server default)"
+			}
+			fmt.Fprintf(w, "%s\t%s\tload %s\tgpu %s\tprio %d\n", svc.Name, label, load, gpu, svc.Priority)
+		}
+	}
+	w.Flush()
+	return choices, strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
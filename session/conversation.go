@@ -0,0 +1,347 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"brutus/internal/text"
+	"brutus/provider"
+)
+
+// DefaultWindow is how many messages a Conversation keeps in memory when
+// no window is given.
+const DefaultWindow = 200
+
+// maxSummaryPrompts caps how many spilled user prompts the summary lists.
+const maxSummaryPrompts = 20
+
+// Dir returns the directory BRUTUS keeps session data in.
+func Dir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "brutus-sessions")
+	}
+	return filepath.Join(home, ".brutus", "sessions")
+}
+
+// SpillPath returns where a Conversation for session id spills to.
+func SpillPath(id string) string {
+	return filepath.Join(Dir(), id+".spill.jsonl")
+}
+
+// Conversation is a chat history with bounded memory use. It keeps the
+// most recent messages in memory and appends older ones, whole turns at a
+// time, to a JSONL spill file in the transcript format. The model is sent
+// the recent messages prefixed with a short summary of what was spilled;
+// the full history is read back from disk only when asked for.
+//
+// A Conversation is safe for concurrent use.
+type Conversation struct {
+	mu        sync.Mutex
+	window    int
+	spillPath string
+	spill     *os.File // opened on first spill
+	spilled   int
+	prompts   []string // spilled user prompts, newest last
+	digest    string   // model-written summary of the spilled messages, if any
+	recent    []Record
+}
+
+// NewConversation creates an empty conversation that keeps up to window
+// messages in memory (DefaultWindow if window <= 0) and spills the rest to
+// spillPath.
+func NewConversation(spillPath string, window int) *Conversation {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Conversation{window: window, spillPath: spillPath}
+}
+
+// Append adds a message belonging to the given user turn. An error means
+// spilling to disk failed; the message is still kept in memory.
+func (c *Conversation) Append(turn int, msg provider.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.append(Record{Time: time.Now(), Turn: turn, Message: msg})
+}
+
+func (c *Conversation) append(r Record) error {
+	c.recent = append(c.recent, r)
+	if len(c.recent) <= c.window {
+		return nil
+	}
+
+	// Spill at a turn boundary so a tool call is never separated from its
+	// result. If the current turn alone exceeds the window, wait for the
+	// next one.
+	cut := -1
+	for i := len(c.recent) - c.window; i < len(c.recent); i++ {
+		if isPrompt(c.recent[i].Message) {
+			cut = i
+			break
+		}
+	}
+	if cut <= 0 {
+		return nil
+	}
+	return c.spillRecords(cut)
+}
+
+// spillRecords moves the first n in-memory records to disk.
+func (c *Conversation) spillRecords(n int) error {
+	if c.spill == nil {
+		if err := os.MkdirAll(filepath.Dir(c.spillPath), 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(c.spillPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		c.spill = f
+	}
+
+	var buf strings.Builder
+	for _, r := range c.recent[:n] {
+		if err := writeRecord(&buf, r); err != nil {
+			return err
+		}
+	}
+	if _, err := c.spill.WriteString(buf.String()); err != nil {
+		return err
+	}
+
+	for _, r := range c.recent[:n] {
+		if isPrompt(r.Message) {
+			c.prompts = append(c.prompts, text.Head(strings.Join(strings.Fields(r.Message.Content), " "), 120))
+		}
+	}
+	if len(c.prompts) > maxSummaryPrompts {
+		c.prompts = c.prompts[len(c.prompts)-maxSummaryPrompts:]
+	}
+	c.spilled += n
+	// Copy so the spilled records' backing array can be freed.
+	c.recent = append([]Record(nil), c.recent[n:]...)
+	return nil
+}
+
+// Compact spills the older half of the in-memory messages, at a turn
+// boundary, for when the model rejects the conversation as too long. It
+// returns how many messages were spilled, 0 if the current turn is all
+// that is left.
+func (c *Conversation) Compact() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cut := c.compactCut()
+	if cut == 0 {
+		return 0, nil
+	}
+	return cut, c.spillRecords(cut)
+}
+
+// Summarize spills the same messages Compact would, and has summarize
+// write what the model is told about spilled messages in place of the
+// list of earlier prompts. summarize is given the summary so far, empty
+// the first time, and the messages being spilled; it must not use the
+// conversation. If it fails nothing is spilled.
+func (c *Conversation) Summarize(summarize func(previous string, older []provider.Message) (string, error)) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cut := c.compactCut()
+	if cut == 0 {
+		return 0, nil
+	}
+	older := make([]provider.Message, cut)
+	for i, r := range c.recent[:cut] {
+		older[i] = r.Message
+	}
+	previous := c.digest
+	if c.spilled > 0 {
+		previous = c.summary()
+	}
+	digest, err := summarize(previous, older)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.spillRecords(cut); err != nil {
+		return 0, err
+	}
+	// The summary covers the prompts spilled so far.
+	c.digest, c.prompts = digest, nil
+	return cut, nil
+}
+
+// compactCut returns how many in-memory messages Compact spills: up to
+// the first turn in the newer half, or failing that the start of the
+// latest turn. Zero means there is nothing to spill.
+func (c *Conversation) compactCut() int {
+	cut := 0
+	for i := len(c.recent) - 1; i > 0; i-- {
+		if !isPrompt(c.recent[i].Message) {
+			continue
+		}
+		if cut == 0 || i >= len(c.recent)/2 {
+			cut = i
+		}
+		if i < len(c.recent)/2 {
+			break
+		}
+	}
+	return cut
+}
+
+// Messages returns what should be sent to the model: the in-memory
+// messages, with a summary of spilled ones folded into the first of them.
+func (c *Conversation) Messages() []provider.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msgs := make([]provider.Message, len(c.recent))
+	for i, r := range c.recent {
+		msgs[i] = r.Message
+	}
+	if c.spilled > 0 && len(msgs) > 0 {
+		// Folded in rather than sent as its own message: some chat
+		// templates reject two user messages in a row.
+		msgs[0].Content = c.summary() + "\n\n" + msgs[0].Content
+	}
+	return msgs
+}
+
+func (c *Conversation) summary() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[The first %d messages of this conversation are no longer shown.", c.spilled)
+	if c.digest != "" {
+		sb.WriteString(" Summary of them:\n" + c.digest)
+	}
+	if len(c.prompts) > 0 {
+		if c.digest != "" {
+			sb.WriteString("\nLater requests from the user, oldest first:")
+		} else {
+			sb.WriteString(" Earlier requests from the user, oldest first:")
+		}
+		for _, p := range c.prompts {
+			sb.WriteString("\n- " + p)
+		}
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// Len returns the total number of messages, including spilled ones.
+func (c *Conversation) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spilled + len(c.recent)
+}
+
+// Spilled returns how many messages have been moved to disk.
+func (c *Conversation) Spilled() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spilled
+}
+
+// History returns every record, reading spilled ones back from disk.
+func (c *Conversation) History() ([]Record, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.history()
+}
+
+func (c *Conversation) history() ([]Record, error) {
+	var records []Record
+	if c.spilled > 0 {
+		s, err := loadTranscript(c.spillPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading spilled history: %w", err)
+		}
+		records = s.Records
+	}
+	return append(records, c.recent...), nil
+}
+
+// Rewind drops the last n user turns and everything after them, and
+// returns the number of turns left.
+func (c *Conversation) Rewind(n int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records, err := c.history()
+	if err != nil {
+		return 0, err
+	}
+	last := 0
+	if len(records) > 0 {
+		last = records[len(records)-1].Turn
+	}
+	keep := max(0, last-n)
+
+	cut := len(records)
+	for i, r := range records {
+		if r.Turn > keep {
+			cut = i
+			break
+		}
+	}
+
+	// Rebuild from scratch; rewinding is rare and this keeps the spill file
+	// and summary consistent.
+	if err := c.reset(); err != nil {
+		return 0, err
+	}
+	for _, r := range records[:cut] {
+		if err := c.append(r); err != nil {
+			return 0, err
+		}
+	}
+	return keep, nil
+}
+
+// Reset empties the conversation, spilled messages and summary included,
+// for starting afresh.
+func (c *Conversation) Reset() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reset()
+}
+
+// reset empties the conversation and truncates the spill file.
+func (c *Conversation) reset() error {
+	c.recent = nil
+	c.prompts = nil
+	c.digest = ""
+	c.spilled = 0
+	if c.spill != nil {
+		if err := c.spill.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := c.spill.Seek(0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the spill file and removes it. Sessions meant to outlive
+// the process are saved separately (see Transcript).
+func (c *Conversation) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.spill == nil {
+		return nil
+	}
+	err := c.spill.Close()
+	c.spill = nil
+	os.Remove(c.spillPath)
+	return err
+}
+
+// isPrompt reports whether msg starts a user turn, as opposed to carrying
+// tool results.
+func isPrompt(msg provider.Message) bool {
+	return msg.Role == "user" && len(msg.ToolResults) == 0
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"brutus/provider"
+)
+
+// addTurn appends a prompt, a tool call, its result and a reply.
+func addTurn(t *testing.T, c *Conversation, turn int) {
+	t.Helper()
+	msgs := []provider.Message{
+		{Role: "user", Content: fmt.Sprintf("prompt %d", turn)},
+		{Role: "assistant", ToolCalls: []provider.ToolCall{{ID: "t", Name: "bash"}}},
+		{Role: "user", ToolResults: []provider.ToolResult{{ID: "t", Content: "ok"}}},
+		{Role: "assistant", Content: fmt.Sprintf("reply %d", turn)},
+	}
+	for _, m := range msgs {
+		if err := c.Append(turn, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConversationSpillsWholeTurns(t *testing.T) {
+	spill := filepath.Join(t.TempDir(), "s.spill.jsonl")
+	c := NewConversation(spill, 6)
+	defer c.Close()
+
+	for turn := 1; turn <= 5; turn++ {
+		addTurn(t, c, turn)
+	}
+
+	if c.Len() != 20 {
+		t.Errorf("Len() = %d, want 20", c.Len())
+	}
+	msgs := c.Messages()
+	if len(msgs) > 6 {
+		t.Errorf("%d messages in memory, window is 6", len(msgs))
+	}
+	if msgs[0].Role != "user" || len(msgs[0].ToolResults) != 0 {
+		t.Errorf("in-memory window does not start at a prompt: %+v", msgs[0])
+	}
+	if !strings.Contains(msgs[0].Content, "no longer shown") || !strings.Contains(msgs[0].Content, "- prompt 1") {
+		t.Errorf("first message lacks the spill summary: %q", msgs[0].Content)
+	}
+	if !strings.HasSuffix(msgs[0].Content, "prompt 5") {
+		t.Errorf("summary replaced the prompt instead of prefixing it: %q", msgs[0].Content)
+	}
+
+	history, err := c.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 20 || history[0].Message.Content != "prompt 1" || history[19].Message.Content != "reply 5" {
+		t.Errorf("History() lost messages: %d records", len(history))
+	}
+}
+
+func TestConversationRewind(t *testing.T) {
+	c := NewConversation(filepath.Join(t.TempDir(), "s.spill.jsonl"), 4)
+	defer c.Close()
+	for turn := 1; turn <= 4; turn++ {
+		addTurn(t, c, turn)
+	}
+
+	left, err := c.Rewind(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left != 1 || c.Len() != 4 || c.Spilled() != 0 {
+		t.Errorf("after Rewind(3): turns=%d len=%d spilled=%d, want 1, 4, 0", left, c.Len(), c.Spilled())
+	}
+	if msgs := c.Messages(); msgs[0].Content != "prompt 1" {
+		t.Errorf("first message after rewind = %q", msgs[0].Content)
+	}
+
+	addTurn(t, c, 2)
+	addTurn(t, c, 3)
+	history, _ := c.History()
+	if len(history) != 12 || history[11].Message.Content != "reply 3" {
+		t.Errorf("history after continuing = %d records", len(history))
+	}
+}
+
+func TestConversationCloseRemovesSpill(t *testing.T) {
+	spill := filepath.Join(t.TempDir(), "s.spill.jsonl")
+	c := NewConversation(spill, 4)
+	addTurn(t, c, 1)
+	addTurn(t, c, 2)
+
+	if _, err := os.Stat(spill); err != nil {
+		t.Fatalf("spill file not written: %v", err)
+	}
+	c.Close()
+	if _, err := os.Stat(spill); !os.IsNotExist(err) {
+		t.Error("spill file left behind after Close")
+	}
+}
+
+func TestConversationCompact(t *testing.T) {
+	c := NewConversation(filepath.Join(t.TempDir(), "s.spill.jsonl"), 0)
+	defer c.Close()
+
+	for turn := 1; turn <= 4; turn++ {
+		addTurn(t, c, turn)
+	}
+	n, err := c.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 8 || c.Spilled() != 8 {
+		t.Errorf("Compact() = %d, spilled %d; want 8", n, c.Spilled())
+	}
+	if msgs := c.Messages(); !strings.HasSuffix(msgs[0].Content, "prompt 3") {
+		t.Errorf("window does not start at turn 3: %q", msgs[0].Content)
+	}
+
+	// Down to one turn, there is nothing left to spill.
+	c.Compact()
+	if n, _ := c.Compact(); n != 0 {
+		t.Errorf("Compact() of a single turn spilled %d messages", n)
+	}
+}
+
+func TestConversationSummarize(t *testing.T) {
+	c := NewConversation(filepath.Join(t.TempDir(), "s.spill.jsonl"), 0)
+	defer c.Close()
+
+	for turn := 1; turn <= 4; turn++ {
+		addTurn(t, c, turn)
+	}
+	if _, err := c.Summarize(func(string, []provider.Message) (string, error) {
+		return "", fmt.Errorf("model unavailable")
+	}); err == nil || c.Spilled() != 0 {
+		t.Fatalf("a failed summary spilled %d messages (err %v)", c.Spilled(), err)
+	}
+
+	var older []provider.Message
+	n, err := c.Summarize(func(previous string, msgs []provider.Message) (string, error) {
+		older = msgs
+		return "- fixed turns 1 and 2", nil
+	})
+	if err != nil || n != 8 || len(older) != 8 {
+		t.Fatalf("Summarize() = %d, %v; summarized %d messages", n, err, len(older))
+	}
+	first := c.Messages()[0].Content
+	if !strings.Contains(first, "- fixed turns 1 and 2") || strings.Contains(first, "prompt 1") || !strings.HasSuffix(first, "prompt 3") {
+		t.Errorf("unexpected summary note: %q", first)
+	}
+
+	addTurn(t, c, 5)
+	var previous string
+	c.Summarize(func(p string, _ []provider.Message) (string, error) {
+		previous = p
+		return "- fixed turns 1 to 4", nil
+	})
+	if !strings.Contains(previous, "- fixed turns 1 and 2") {
+		t.Errorf("the next summary was not given the last one: %q", previous)
+	}
+}
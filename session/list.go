@@ -0,0 +1,114 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+)
+
+// Entry is a saved session as a session list shows it.
+type Entry struct {
+	Path    string
+	ID      string
+	Title   string
+	Summary string
+	Parent  string // ID of the session this one was forked from, if any
+	Updated time.Time
+	Turns   int
+}
+
+// List returns the sessions saved directly in dir, most recently updated
+// first. Spill files and files that don't load are skipped.
+func List(dir string) ([]Entry, error) {
+	paths, err := savedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, path := range paths {
+		s, err := Load(path)
+		if err != nil || len(s.Records) == 0 {
+			continue
+		}
+		entries = append(entries, Entry{
+			Path:    path,
+			ID:      s.ID,
+			Title:   s.Label(),
+			Summary: s.Summary,
+			Parent:  s.Parent,
+			Updated: s.Updated,
+			Turns:   s.Turns(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Updated.After(entries[j].Updated) })
+	return entries, nil
+}
+
+// Find returns the session saved in dir whose ID is id, or starts with
+// it, as long as only one does.
+func Find(dir, id string) (Entry, error) {
+	entries, err := List(dir)
+	if err != nil {
+		return Entry{}, err
+	}
+	var matches []Entry
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+		if strings.HasPrefix(e.ID, id) {
+			matches = append(matches, e)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Entry{}, fmt.Errorf("no saved session %q in %s", id, dir)
+	case 1:
+		return matches[0], nil
+	}
+	return Entry{}, fmt.Errorf("session ID %q is ambiguous: it matches %s and %s", id, matches[0].ID, matches[1].ID)
+}
+
+// savedFiles returns the session files directly in dir, without spill
+// files. A missing dir has none.
+func savedFiles(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var paths []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasSuffix(name, ".spill.jsonl") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if ext := strings.ToLower(filepath.Ext(name)); ext != ".jsonl" && ext != ".json" {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	return paths, nil
+}
+
+// Label returns the session's title, or for sessions that don't have one
+// yet, the start of the first prompt.
+func (s *Session) Label() string {
+	if s.Title != "" {
+		return s.Title
+	}
+	for _, r := range s.Records {
+		if r.Message.Role == "user" && len(r.Message.ToolResults) == 0 && r.Message.Content != "" {
+			return text.Head(strings.Join(strings.Fields(r.Message.Content), " "), 60)
+		}
+	}
+	return s.ID
+}
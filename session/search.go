@@ -0,0 +1,137 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// snippetRadius is how much text around a match a hit shows on each
+	// side.
+	snippetRadius = 120
+
+	// maxHitsPerSession keeps one long session from crowding out the rest.
+	maxHitsPerSession = 3
+)
+
+// Hit is a place in a saved session that matches a search.
+type Hit struct {
+	SessionID string
+	Title     string
+	Updated   time.Time
+	Turn      int    // 0 for a match in the title or summary
+	Role      string // "user", "assistant", "tool" or "summary"
+	Snippet   string
+}
+
+// Search finds messages in the sessions saved in dir that contain every
+// word of query, ignoring case, and returns up to limit of them, most
+// recent sessions first. Session titles and summaries are searched too, so
+// a session can be found by what it was about. Spill files and files that
+// don't load are skipped.
+func Search(dir, query string, limit int) ([]Hit, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty search")
+	}
+	paths, err := savedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, path := range paths {
+		s, err := Load(path)
+		if err != nil || len(s.Records) == 0 {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+
+	var hits []Hit
+	for _, s := range sessions {
+		found := 0
+		add := func(turn int, role, content string) {
+			snippet, ok := match(content, terms)
+			if !ok || found >= maxHitsPerSession {
+				return
+			}
+			found++
+			hits = append(hits, Hit{SessionID: s.ID, Title: s.Label(), Updated: s.Updated, Turn: turn, Role: role, Snippet: snippet})
+		}
+
+		add(0, "summary", s.Title+"\n"+s.Summary)
+		turn := 0
+		for _, r := range s.Records {
+			msg := r.Message
+			if msg.Role == "user" && len(msg.ToolResults) == 0 {
+				turn++
+			}
+			add(turn, msg.Role, msg.Content)
+			for _, tr := range msg.ToolResults {
+				add(turn, "tool", tr.Content)
+			}
+		}
+		if limit > 0 && len(hits) >= limit {
+			return hits[:limit], nil
+		}
+	}
+	return hits, nil
+}
+
+// match reports whether content contains every term and returns the text
+// around the first one, on one line.
+func match(content string, terms []string) (string, bool) {
+	lower := strings.ToLower(content)
+	for _, term := range terms {
+		if !strings.Contains(lower, term) {
+			return "", false
+		}
+	}
+	// Lowercasing can change byte lengths outside ASCII; fall back to the
+	// start of the message rather than cut a character in half.
+	at := strings.Index(lower, terms[0])
+	if len(lower) != len(content) {
+		at = 0
+	}
+	start := max(0, at-snippetRadius)
+	end := min(len(content), at+len(terms[0])+snippetRadius)
+	snippet := strings.Join(strings.Fields(strings.ToValidUTF8(content[start:end], "")), " ")
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(content) {
+		snippet += "..."
+	}
+	return snippet, true
+}
+
+// FormatHits lists hits for the model or the terminal, one paragraph each.
+func FormatHits(query string, hits []Hit) string {
+	if len(hits) == 0 {
+		return fmt.Sprintf("No past sessions mention %q.", query)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d match(es) for %q in past sessions:\n", len(hits), query)
+	for _, h := range hits {
+		where := fmt.Sprintf("turn %d, %s", h.Turn, h.Role)
+		if h.Turn == 0 {
+			where = "title/summary"
+		}
+		fmt.Fprintf(&b, "\n[%s] %s (%s; %s)\n%s\n", h.SessionID, h.Title, h.Updated.Format("2006-01-02"), where, h.Snippet)
+	}
+	return b.String()
+}
+
+// SearchText searches the saved sessions in Dir and formats the hits, for
+// the search_history tool.
+func SearchText(query string, limit int) (string, error) {
+	hits, err := Search(Dir(), query, limit)
+	if err != nil {
+		return "", err
+	}
+	return FormatHits(query, hits), nil
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"brutus/provider"
+)
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, info Info, msgs ...provider.Message) {
+		s := New()
+		s.ID = name
+		tr, err := OpenTranscript(filepath.Join(dir, name+".jsonl"), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, msg := range msgs {
+			tr.Record(i/2+1, msg)
+		}
+		tr.Describe(info)
+		tr.Close()
+	}
+
+	write("old", Info{Title: "Fix flaky TestServe", Summary: "Port reuse made TestServe flaky; now listens on :0."},
+		provider.Message{Role: "user", Content: "TestServe fails randomly in CI"},
+		provider.Message{Role: "assistant", Content: "The flaky test binds a fixed port."},
+	)
+	write("other", Info{Title: "Add pager"},
+		provider.Message{Role: "user", Content: "page long responses"},
+		provider.Message{Role: "assistant", ToolCalls: []provider.ToolCall{{ID: "1", Name: "bash"}}},
+		provider.Message{Role: "user", ToolResults: []provider.ToolResult{{ID: "1", Content: "--- FAIL: TestPager (flaky timing)"}}},
+	)
+
+	hits, err := Search(dir, "FLAKY test", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySession := map[string][]Hit{}
+	for _, h := range hits {
+		bySession[h.SessionID] = append(bySession[h.SessionID], h)
+	}
+	if len(bySession["old"]) != 2 || bySession["old"][0].Role != "summary" || bySession["old"][1].Role != "assistant" {
+		t.Errorf("expected the summary and the reply of old to match, got %+v", bySession["old"])
+	}
+	if len(bySession["other"]) != 1 || bySession["other"][0].Role != "tool" {
+		t.Errorf("expected the tool result of other to match, got %+v", bySession["other"])
+	}
+
+	out := FormatHits("flaky test", hits)
+	if !strings.Contains(out, "[old] Fix flaky TestServe") || !strings.Contains(out, "binds a fixed port") {
+		t.Errorf("FormatHits missing session or snippet:\n%s", out)
+	}
+
+	if hits, _ := Search(dir, "no such words", 10); len(hits) != 0 {
+		t.Errorf("expected no hits, got %+v", hits)
+	}
+}
+
+func TestMatchSnippet(t *testing.T) {
+	content := strings.Repeat("a ", 200) + "needle" + strings.Repeat(" b", 200)
+	snippet, ok := match(content, []string{"needle"})
+	if !ok || !strings.HasPrefix(snippet, "...") || !strings.HasSuffix(snippet, "...") || !strings.Contains(snippet, "needle") {
+		t.Errorf("match = %q, %v", snippet, ok)
+	}
+	if len(snippet) > 2*snippetRadius+20 {
+		t.Errorf("snippet too long: %d", len(snippet))
+	}
+}
@@ -0,0 +1,308 @@
+// Package session persists BRUTUS conversations.
+//
+// A conversation can be stored two ways:
+//
+//   - a JSONL transcript (*.jsonl), one Record per line, appended as the
+//     conversation happens so it survives crashes;
+//   - a session file (any other extension), a single JSON Session document
+//     rewritten after every message.
+//
+// Load reads either form.
+package session
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"brutus/provider"
+)
+
+// Record is one message in a conversation, tagged with the user turn it
+// belongs to. In JSONL transcripts a record may instead carry Info, an
+// updated title and summary, which Load applies to the Session.
+type Record struct {
+	Time    time.Time        `json:"time"`
+	Turn    int              `json:"turn"`
+	Message provider.Message `json:"message"`
+	Info    *Info            `json:"info,omitempty"`
+}
+
+// Info describes a conversation for session lists. In transcripts it also
+// carries the model and working directory, so a resumed session starts
+// where it left off.
+type Info struct {
+	Title      string `json:"title,omitempty"`
+	Summary    string `json:"summary,omitempty"`
+	Parent     string `json:"parent,omitempty"` // ID of the session this one was forked from
+	Model      string `json:"model,omitempty"`
+	WorkingDir string `json:"working_dir,omitempty"`
+}
+
+// Session is a whole conversation plus the context it ran in.
+type Session struct {
+	ID         string    `json:"id"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+	Model      string    `json:"model,omitempty"`
+	WorkingDir string    `json:"working_dir,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Parent     string    `json:"parent,omitempty"`
+	Records    []Record  `json:"records"`
+}
+
+// New creates an empty session with a fresh ID.
+func New() *Session {
+	now := time.Now()
+	return &Session{ID: NewID(), Created: now, Updated: now}
+}
+
+// NewID returns a short random session identifier.
+func NewID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// IsTranscript reports whether path uses the JSONL transcript format.
+func IsTranscript(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".jsonl")
+}
+
+// Load reads a session file or JSONL transcript.
+func Load(path string) (*Session, error) {
+	if IsTranscript(path) {
+		return loadTranscript(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+func loadTranscript(path string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &Session{ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(text), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if r.Info != nil {
+			s.Title, s.Summary = r.Info.Title, r.Info.Summary
+			if r.Info.Parent != "" {
+				s.Parent = r.Info.Parent
+			}
+			if r.Info.Model != "" {
+				s.Model = r.Info.Model
+			}
+			if r.Info.WorkingDir != "" {
+				s.WorkingDir = r.Info.WorkingDir
+			}
+			continue
+		}
+		s.Records = append(s.Records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(s.Records) > 0 {
+		s.Created = s.Records[0].Time
+		s.Updated = s.Records[len(s.Records)-1].Time
+	}
+	return s, nil
+}
+
+// Save writes the session as a single JSON document.
+func (s *Session) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Turns returns the number of user turns in the session.
+func (s *Session) Turns() int {
+	turns := 0
+	for _, r := range s.Records {
+		if r.Turn > turns {
+			turns = r.Turn
+		}
+	}
+	return turns
+}
+
+// UpToTurn returns the records belonging to turns 1..n. A non-positive n
+// returns every record.
+func (s *Session) UpToTurn(n int) []Record {
+	if n <= 0 {
+		return s.Records
+	}
+	var out []Record
+	for _, r := range s.Records {
+		if r.Turn <= n {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Fork starts a new session from this one's turns 1..n (every turn if n
+// is non-positive), keeping its title and summary and recording it as the
+// parent, so two approaches can be tried from the same point.
+func (s *Session) Fork(n int) *Session {
+	f := New()
+	f.Parent = s.ID
+	f.Model = s.Model
+	f.WorkingDir = s.WorkingDir
+	f.Title = s.Title
+	f.Summary = s.Summary
+	f.Records = append([]Record(nil), s.UpToTurn(n)...)
+	return f
+}
+
+// Messages extracts the conversation from records.
+func Messages(records []Record) []provider.Message {
+	msgs := make([]provider.Message, 0, len(records))
+	for _, r := range records {
+		msgs = append(msgs, r.Message)
+	}
+	return msgs
+}
+
+// Transcript records a live conversation to disk as it happens.
+type Transcript struct {
+	mu      sync.Mutex // Describe may run alongside Record
+	path    string
+	session *Session
+	file    *os.File // open only for JSONL transcripts
+}
+
+// OpenTranscript starts recording s to path, writing out any records s
+// already holds. An existing file at path is replaced.
+func OpenTranscript(path string, s *Session) (*Transcript, error) {
+	t := &Transcript{path: path, session: s}
+
+	if !IsTranscript(path) {
+		return t, s.Save(path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	t.file = f
+	for _, r := range s.Records {
+		if err := t.writeLine(r); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if info := s.info(); info != (Info{}) {
+		if err := t.writeLine(Record{Time: s.Updated, Info: &info}); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Session returns the session being recorded.
+func (t *Transcript) Session() *Session {
+	return t.session
+}
+
+// Record appends a message to the session and persists it.
+func (t *Transcript) Record(turn int, msg provider.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := Record{Time: time.Now(), Turn: turn, Message: msg}
+	t.session.Records = append(t.session.Records, r)
+	t.session.Updated = r.Time
+
+	if t.file != nil {
+		return t.writeLine(r)
+	}
+	return t.session.Save(t.path)
+}
+
+// Describe sets the session's title and summary and persists them.
+func (t *Transcript) Describe(info Info) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.session.Title, t.session.Summary = info.Title, info.Summary
+	info = t.session.info()
+	if t.file != nil {
+		return t.writeLine(Record{Time: time.Now(), Turn: t.session.Turns(), Info: &info})
+	}
+	return t.session.Save(t.path)
+}
+
+// info returns the session's Info record.
+func (s *Session) info() Info {
+	return Info{Title: s.Title, Summary: s.Summary, Parent: s.Parent, Model: s.Model, WorkingDir: s.WorkingDir}
+}
+
+func (t *Transcript) writeLine(r Record) error {
+	return writeRecord(t.file, r)
+}
+
+// writeRecord writes r as one JSONL line.
+func writeRecord(w io.Writer, r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Close flushes and closes the transcript.
+func (t *Transcript) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file != nil {
+		return t.file.Close()
+	}
+	return nil
+}
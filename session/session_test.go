@@ -0,0 +1,146 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+
+	"brutus/provider"
+)
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	for _, name := range []string{"chat.jsonl", "chat.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			tr, err := OpenTranscript(path, New())
+			if err != nil {
+				t.Fatalf("OpenTranscript failed: %v", err)
+			}
+
+			tr.Record(1, provider.Message{Role: "user", Content: "hello"})
+			tr.Record(1, provider.Message{Role: "assistant", Content: "hi"})
+			tr.Record(2, provider.Message{Role: "user", Content: "list files"})
+			tr.Close()
+
+			s, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if s.Turns() != 2 {
+				t.Errorf("expected 2 turns, got %d", s.Turns())
+			}
+			if got := Messages(s.UpToTurn(1)); len(got) != 2 || got[1].Content != "hi" {
+				t.Errorf("unexpected messages for turn 1: %+v", got)
+			}
+		})
+	}
+}
+
+func TestTranscriptInfo(t *testing.T) {
+	dir := t.TempDir()
+	tr, err := OpenTranscript(filepath.Join(dir, "a.jsonl"), New())
+	if err != nil {
+		t.Fatalf("OpenTranscript failed: %v", err)
+	}
+	tr.Record(1, provider.Message{Role: "user", Content: "fix the   flaky\ntest"})
+	tr.Record(1, provider.Message{Role: "assistant", Content: "done"})
+	tr.Describe(Info{Title: "Fix flaky test", Summary: "First summary."})
+	tr.Describe(Info{Title: "Fix flaky test", Summary: "Second summary."})
+	tr.Close()
+
+	untitled, _ := OpenTranscript(filepath.Join(dir, "b.jsonl"), New())
+	untitled.Record(1, provider.Message{Role: "user", Content: "fix the   flaky\ntest"})
+	untitled.Close()
+
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	byTitle := map[string]Entry{}
+	for _, e := range entries {
+		byTitle[e.Title] = e
+	}
+	if e := byTitle["Fix flaky test"]; e.Summary != "Second summary." || e.Turns != 1 {
+		t.Errorf("unexpected titled entry: %+v", e)
+	}
+	if _, ok := byTitle["fix the flaky test"]; !ok {
+		t.Errorf("expected the untitled session labelled by its prompt, got %+v", entries)
+	}
+}
+
+func TestResumeByID(t *testing.T) {
+	dir := t.TempDir()
+	s := New()
+	s.ID = "abc123"
+	s.Model = "qwen"
+	s.WorkingDir = "/work/project"
+	tr, err := OpenTranscript(filepath.Join(dir, s.ID+".jsonl"), s)
+	if err != nil {
+		t.Fatalf("OpenTranscript failed: %v", err)
+	}
+	tr.Record(1, provider.Message{Role: "user", Content: "hello"})
+	tr.Describe(Info{Title: "Greeting"})
+	tr.Close()
+
+	other, _ := OpenTranscript(filepath.Join(dir, "abd456.jsonl"), New())
+	other.Record(1, provider.Message{Role: "user", Content: "hi"})
+	other.Close()
+
+	entry, err := Find(dir, "abc")
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	loaded, err := Load(entry.Path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.WorkingDir != "/work/project" || loaded.Model != "qwen" || loaded.Title != "Greeting" {
+		t.Errorf("session context not restored: %+v", loaded)
+	}
+	if _, err := Find(dir, "ab"); err == nil {
+		t.Error("expected an ambiguous prefix to fail")
+	}
+	if _, err := Find(dir, "zzz"); err == nil {
+		t.Error("expected an unknown ID to fail")
+	}
+}
+
+func TestFork(t *testing.T) {
+	dir := t.TempDir()
+	src := New()
+	tr, err := OpenTranscript(filepath.Join(dir, src.ID+".jsonl"), src)
+	if err != nil {
+		t.Fatalf("OpenTranscript failed: %v", err)
+	}
+	tr.Record(1, provider.Message{Role: "user", Content: "hello"})
+	tr.Record(1, provider.Message{Role: "assistant", Content: "hi"})
+	tr.Record(2, provider.Message{Role: "user", Content: "try one way"})
+	tr.Describe(Info{Title: "Greeting"})
+	tr.Close()
+
+	fork := src.Fork(1)
+	if fork.ID == src.ID || fork.Parent != src.ID || fork.Title != "Greeting" || len(fork.Records) != 2 {
+		t.Fatalf("unexpected fork: %+v", fork)
+	}
+	ftr, err := OpenTranscript(filepath.Join(dir, fork.ID+".jsonl"), fork)
+	if err != nil {
+		t.Fatalf("OpenTranscript failed: %v", err)
+	}
+	ftr.Record(2, provider.Message{Role: "user", Content: "try another way"})
+	ftr.Describe(Info{Title: "Greeting", Summary: "Trying another way."})
+	ftr.Close()
+
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	parents := map[string]string{}
+	for _, e := range entries {
+		parents[e.ID] = e.Parent
+	}
+	if parents[fork.ID] != src.ID || parents[src.ID] != "" {
+		t.Errorf("expected %s to list %s as its parent, got %+v", fork.ID, src.ID, entries)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// shutdownGrace is how long an interrupted command gets to finish its
+// in-flight tool call and clean up before the process exits anyway.
+const shutdownGrace = 10 * time.Second
+
+var (
+	cleanupMu   sync.Mutex
+	cleanups    []func()
+	cleanupOnce sync.Once
+)
+
+// interruptHandler, if set, gets the first say on Ctrl+C: when it reports
+// that it dealt with the signal, the command carries on.
+var interruptHandler atomic.Pointer[func() bool]
+
+// onInterrupt lets fn handle Ctrl+C in place of shutting down, as chat
+// does to stop only the current turn. fn returns false to shut down after
+// all.
+func onInterrupt(fn func() bool) {
+	interruptHandler.Store(&fn)
+}
+
+// interrupted reports whether sig was handled by the interrupt handler.
+func interrupted(sig os.Signal) bool {
+	fn := interruptHandler.Load()
+	return sig == os.Interrupt && fn != nil && (*fn)()
+}
+
+// onShutdown registers fn to run before the process exits, whether it ends
+// normally, through exit, or because of a signal. Cleanups run in reverse
+// registration order.
+func onShutdown(fn func()) {
+	cleanupMu.Lock()
+	defer cleanupMu.Unlock()
+	cleanups = append(cleanups, fn)
+}
+
+// runCleanups runs the registered cleanups once.
+func runCleanups() {
+	cleanupOnce.Do(func() {
+		cleanupMu.Lock()
+		fns := cleanups
+		cleanupMu.Unlock()
+
+		for i := len(fns) - 1; i >= 0; i-- {
+			fns[i]()
+		}
+	})
+}
+
+// exit runs cleanups and terminates the process. Use it instead of os.Exit
+// once a command holds resources (transcripts, mDNS registrations, ...).
+func exit(code int) {
+	runCleanups()
+	os.Exit(code)
+}
+
+// signalContext returns a context that is cancelled on the first SIGINT or
+// SIGTERM, letting the command wind down on its own. A second signal, or
+// the grace period running out, runs cleanups and exits immediately. A
+// SIGINT the interrupt handler deals with is passed over.
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		for interrupted(<-sigs) {
+		}
+		fmt.Fprintln(os.Stderr, "\nShutting down (press Ctrl+C again to force)...")
+		cancel()
+
+		select {
+		case <-sigs:
+		case <-time.After(shutdownGrace):
+			fmt.Fprintln(os.Stderr, "Timed out waiting for in-flight work")
+		}
+		exit(130)
+	}()
+
+	return ctx
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"brutus/config"
+	"brutus/internal/text"
+	"brutus/scheduler"
+	"brutus/session"
+)
+
+// serveOnlyFlags are the serve flags that are not passed on to task runs.
+var serveOnlyFlags = map[string]bool{
+	"addr": true, "cwd": true, "pprof": true,
+	"log-file": true, "log-format": true, "log-max-size": true,
+}
+
+// webhookTimeout bounds each webhook delivery.
+const webhookTimeout = 10 * time.Second
+
+// taskResult is what a task run's webhook receives.
+type taskResult struct {
+	Task     string    `json:"task"`
+	Status   string    `json:"status"` // "ok" or "error"
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Response string    `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Session  string    `json:"session"` // transcript path, for 'brutus replay'
+}
+
+// taskRunner runs scheduled tasks, each as a 'brutus run' child process.
+// Tools resolve paths against the process's working directory, so giving a
+// task its own workspace takes a process of its own.
+type taskRunner struct {
+	exe    string
+	flags  []string // the server's agent flags, passed on to every run
+	logger *slog.Logger
+	client *http.Client
+}
+
+func newTaskRunner(fs *flag.FlagSet, logger *slog.Logger) (*taskRunner, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate brutus executable: %w", err)
+	}
+	var flags []string
+	fs.Visit(func(fl *flag.Flag) {
+		if !serveOnlyFlags[fl.Name] {
+			flags = append(flags, "-"+fl.Name+"="+fl.Value.String())
+		}
+	})
+	return &taskRunner{
+		exe:    exe,
+		flags:  flags,
+		logger: logger,
+		client: &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+// scheduleTasks checks every configured task and adds it to sched.
+// Workspaces are resolved against the server's working directory.
+func scheduleTasks(sched *scheduler.Scheduler, runner *taskRunner, tasks map[string]config.Task) error {
+	for name, task := range tasks {
+		if strings.TrimSpace(task.Prompt) == "" {
+			return fmt.Errorf("task %q: prompt is required", name)
+		}
+		if len(task.Tools) > 0 {
+			if err := restrictTools(cliTools(), task.Tools); err != nil {
+				return fmt.Errorf("task %q: %w", name, err)
+			}
+		}
+		if task.Workspace == "" {
+			task.Workspace = "."
+		}
+		workspace, err := filepath.Abs(task.Workspace)
+		if err != nil {
+			return fmt.Errorf("task %q: %w", name, err)
+		}
+		if info, err := os.Stat(workspace); err != nil || !info.IsDir() {
+			return fmt.Errorf("task %q: workspace %s is not a directory", name, workspace)
+		}
+		task.Workspace = workspace
+
+		if err := sched.Add(name, task.Cron, func(ctx context.Context) error {
+			return runner.run(ctx, name, task)
+		}); err != nil {
+			return fmt.Errorf("task %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// run executes one run of task, records it as a session under
+// ~/.brutus/sessions/tasks and notifies the task's webhook.
+func (r *taskRunner) run(ctx context.Context, name string, task config.Task) error {
+	started := time.Now()
+	transcript := filepath.Join(session.Dir(), "tasks", name+"-"+started.Format("20060102-150405")+".jsonl")
+
+	args := append([]string{"run"}, r.flags...)
+	args = append(args, "-cwd", task.Workspace, "-transcript", transcript)
+	if len(task.Tools) > 0 {
+		args = append(args, "-tools", strings.Join(task.Tools, ","))
+	}
+	args = append(args, "--", task.Prompt)
+
+	cmd := exec.CommandContext(ctx, r.exe, args...)
+	// Give an interrupted run the same chance to finish its tool and close
+	// its transcript as an interactive one.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = shutdownGrace
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	result := taskResult{
+		Task:     name,
+		Status:   "ok",
+		Started:  started,
+		Finished: time.Now(),
+		Response: strings.TrimSpace(stdout.String()),
+		Session:  transcript,
+	}
+	if err != nil {
+		result.Status = "error"
+		result.Error = fmt.Sprintf("%v: %s", err, text.HeadTail(strings.TrimSpace(stderr.String()), 2000))
+		err = fmt.Errorf("%s", result.Error)
+	}
+
+	if task.Webhook != "" {
+		if hookErr := r.notify(task.Webhook, result); hookErr != nil {
+			r.logger.Warn("task webhook failed", "task", name, "url", task.Webhook, "error", hookErr)
+		}
+	}
+	return err
+}
+
+// notify POSTs result to url as JSON.
+func (r *taskRunner) notify(url string, result taskResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
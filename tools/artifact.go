@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"brutus/internal/text"
+)
+
+// ArtifactsDir holds archived tool results, one directory per session,
+// relative to the project root.
+var ArtifactsDir = filepath.Join(".brutus", "artifacts")
+
+// DefaultArtifactThreshold is the result size in bytes above which tool
+// results are archived rather than kept whole in the conversation.
+const DefaultArtifactThreshold = 16000
+
+const (
+	// artifactPreview is how much of an archived result, split between
+	// its start and end, stays in the conversation.
+	artifactPreview = 2000
+
+	// artifactReadLines and artifactReadMax bound one read_artifact call.
+	artifactReadLines = 200
+	artifactReadMax   = 20000
+)
+
+var artifactIDRe = regexp.MustCompile(`^art-[0-9]+$`)
+
+// Artifacts archives large tool results for one session. The conversation
+// keeps an ID and a preview, and read_artifact brings back any part of the
+// full output when it is needed, so a long build log doesn't occupy the
+// context for the rest of the session. A nil *Artifacts archives nothing.
+// It is safe for concurrent use.
+type Artifacts struct {
+	mu        sync.Mutex
+	dir       string
+	threshold int
+	next      int
+}
+
+// NewArtifacts archives results larger than threshold bytes in dir, which
+// is created on first use.
+func NewArtifacts(dir string, threshold int) *Artifacts {
+	return &Artifacts{dir: dir, threshold: threshold, next: 1}
+}
+
+// SessionArtifactsDir is where a session's artifacts are kept.
+func SessionArtifactsDir(sessionID string) string {
+	return filepath.Join(ArtifactsDir, sessionID)
+}
+
+// Archive saves result if it is over the threshold and returns what the
+// conversation should hold instead: a reference to the artifact and a
+// preview of it. Smaller results, and results that can't be saved, are
+// returned unchanged.
+func (a *Artifacts) Archive(tool, result string) (string, error) {
+	if a == nil || len(result) <= a.threshold {
+		return result, nil
+	}
+
+	a.mu.Lock()
+	id := fmt.Sprintf("art-%d", a.next)
+	a.next++
+	a.mu.Unlock()
+
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return result, err
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, id+".txt"), []byte(result), 0644); err != nil {
+		return result, err
+	}
+
+	lines := strings.Count(result, "\n") + 1
+	return fmt.Sprintf("[%s output archived as artifact %s: %d bytes, %d lines. Only the start and end are shown; call read_artifact with id %q to read the rest.]\n%s",
+		tool, id, len(result), lines, id, text.HeadTail(result, artifactPreview)), nil
+}
+
+// Read returns lines [offset, offset+limit) of an artifact, 1-based, and
+// the artifact's line count.
+func (a *Artifacts) Read(id string, offset, limit int) (string, int, error) {
+	if !artifactIDRe.MatchString(id) {
+		return "", 0, NewError(ErrInvalidInput, "%q is not an artifact ID; they look like art-3", id)
+	}
+	data, err := os.ReadFile(filepath.Join(a.dir, id+".txt"))
+	if os.IsNotExist(err) {
+		return "", 0, NewError(ErrNotFound, "no artifact %s in this session", id)
+	}
+	if err != nil {
+		return "", 0, WrapError(err, "failed to read artifact")
+	}
+
+	lines := strings.Split(string(data), "\n")
+	offset = max(offset, 1)
+	if limit <= 0 {
+		limit = artifactReadLines
+	}
+	if offset > len(lines) {
+		return "", len(lines), NewError(ErrInvalidInput, "artifact %s has %d lines", id, len(lines))
+	}
+	end := min(offset-1+limit, len(lines))
+	return strings.Join(lines[offset-1:end], "\n"), len(lines), nil
+}
+
+// ReadArtifactInput defines the parameters for read_artifact.
+type ReadArtifactInput struct {
+	ID     string `json:"id" jsonschema_description:"Artifact ID from an archived tool result, such as art-3."`
+	Offset int    `json:"offset,omitempty" jsonschema_description:"First line to return, starting at 1. Defaults to 1."`
+	Limit  int    `json:"limit,omitempty" jsonschema_description:"Number of lines to return. Defaults to 200."`
+}
+
+// NewReadArtifactTool returns read_artifact, which reads back the tool
+// results archived in artifacts.
+func NewReadArtifactTool(artifacts *Artifacts) Tool {
+	return NewTool[ReadArtifactInput](
+		"read_artifact",
+		"Read part of a large tool result that was archived as an artifact. Results over the size limit are replaced by an artifact ID and their first and last lines; use this to see the lines in between.",
+		func(input json.RawMessage) (string, error) {
+			var args ReadArtifactInput
+			if err := decodeInput(input, &args); err != nil {
+				return "", err
+			}
+			content, total, err := artifacts.Read(args.ID, args.Offset, args.Limit)
+			if err != nil {
+				return "", err
+			}
+			first := max(args.Offset, 1)
+			last := first + strings.Count(content, "\n")
+			return fmt.Sprintf("[%s lines %d-%d of %d]\n%s", args.ID, first, last, total, text.Head(content, artifactReadMax)), nil
+		},
+	)
+}
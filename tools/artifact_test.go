@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArtifacts(t *testing.T) {
+	a := NewArtifacts(filepath.Join(t.TempDir(), "sess"), 100)
+
+	if got, _ := a.Archive("bash", "short"); got != "short" {
+		t.Errorf("small result changed: %q", got)
+	}
+
+	var lines []string
+	for i := 1; i <= 500; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	ref, err := a.Archive("bash", strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ref, "artifact art-1") || !strings.Contains(ref, "500 lines") {
+		t.Errorf("reference missing the ID or size:\n%s", ref)
+	}
+	if !strings.Contains(ref, "line 1\n") || !strings.Contains(ref, "line 500") || strings.Contains(ref, "line 250\n") {
+		t.Errorf("preview should keep the start and end only:\n%s", ref)
+	}
+
+	tool := NewReadArtifactTool(a)
+	out, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"art-1","offset":250,"limit":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "[art-1 lines 250-251 of 500]\nline 250\nline 251" {
+		t.Errorf("read_artifact = %q", out)
+	}
+
+	for _, input := range []string{`{"id":"art-2"}`, `{"id":"../secret"}`, `{"id":"art-1","offset":900}`} {
+		if _, err := tool.Execute(context.Background(), json.RawMessage(input)); err == nil {
+			t.Errorf("read_artifact %s: expected an error", input)
+		}
+	}
+
+	var none *Artifacts
+	if got, _ := none.Archive("bash", strings.Repeat("x", 1000)); len(got) != 1000 {
+		t.Error("a nil *Artifacts should archive nothing")
+	}
+}
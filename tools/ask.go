@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// AskUserInput defines the parameters for the ask_user tool.
+type AskUserInput struct {
+	Question string   `json:"question" jsonschema_description:"The question to ask. Make it specific and self-contained; the user may not have followed every step."`
+	Choices  []string `json:"choices,omitempty" jsonschema_description:"Optional answers for the user to pick from. They can still reply in their own words."`
+}
+
+// AskFunc puts a question to the human driving the agent and returns the
+// reply. ok is false if they dismissed the question without answering.
+type AskFunc func(question string, choices []string) (answer string, ok bool, err error)
+
+// NewAskUserTool returns the ask_user tool, answered by ask. Only
+// interactive front ends register it; headless runs have nobody to ask.
+func NewAskUserTool(ask AskFunc) Tool {
+	t := NewTool[AskUserInput](
+		"ask_user",
+		"Ask the user a question and wait for the reply. Use this when the request is ambiguous or a decision is theirs to make (naming, trade-offs, destructive changes) rather than guessing. Offer choices when the likely answers are known.",
+		func(input json.RawMessage) (string, error) {
+			var args AskUserInput
+			if err := decodeInput(input, &args); err != nil {
+				return "", err
+			}
+			args.Question = strings.TrimSpace(args.Question)
+			if args.Question == "" {
+				return "", NewError(ErrInvalidInput, "question is required")
+			}
+
+			answer, ok, err := ask(args.Question, args.Choices)
+			if err != nil {
+				return "", WrapError(err, "failed to ask the user")
+			}
+			answer = resolveChoice(strings.TrimSpace(answer), args.Choices)
+			if !ok || answer == "" {
+				return "The user did not answer. Continue with your best judgement and say what you assumed.", nil
+			}
+			return answer, nil
+		},
+	)
+	t.FromUser = true
+	return t
+}
+
+// resolveChoice maps a reply of "2" to the second choice, so terminal users
+// can answer by number. Anything else is returned as typed.
+func resolveChoice(answer string, choices []string) string {
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(choices) {
+		return choices[n-1]
+	}
+	return answer
+}
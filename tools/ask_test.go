@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAskUser(t *testing.T) {
+	var asked string
+	var offered []string
+	reply, answered := "", true
+	tool := NewAskUserTool(func(question string, choices []string) (string, bool, error) {
+		asked, offered = question, choices
+		return reply, answered, nil
+	})
+
+	tests := []struct {
+		name     string
+		input    string
+		reply    string
+		answered bool
+		want     string
+	}{
+		{"free text", `{"question":"Which database?"}`, " Postgres ", true, "Postgres"},
+		{"choice by number", `{"question":"Which database?","choices":["SQLite","Postgres"]}`, "2", true, "Postgres"},
+		{"number out of range", `{"question":"How many?","choices":["one"]}`, "3", true, "3"},
+		{"dismissed", `{"question":"Which database?"}`, "", false, "The user did not answer. Continue with your best judgement and say what you assumed."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, answered = tt.reply, tt.answered
+			got, err := tool.Function(json.RawMessage(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+			if asked == "" {
+				t.Error("question was not passed to the asker")
+			}
+			if want := len(decodeChoices(t, tt.input)); len(offered) != want {
+				t.Errorf("asker got %d choices, want %d", len(offered), want)
+			}
+		})
+	}
+
+	if _, err := tool.Function(json.RawMessage(`{"question":"  "}`)); CodeOf(err) != ErrInvalidInput {
+		t.Errorf("empty question: got %v, want invalid_input", err)
+	}
+	if !tool.FromUser {
+		t.Error("ask_user results must be marked as coming from the user")
+	}
+}
+
+func decodeChoices(t *testing.T, input string) []string {
+	t.Helper()
+	var args AskUserInput
+	if err := json.Unmarshal([]byte(input), &args); err != nil {
+		t.Fatal(err)
+	}
+	return args.Choices
+}
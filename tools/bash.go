@@ -1,45 +1,79 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 )
 
+// shellWaitDelay is how long a cancelled shell command's output is read
+// after it is killed, in case something it started still holds it open.
+const shellWaitDelay = 2 * time.Second
+
 // BashInput defines parameters for the bash tool.
 type BashInput struct {
 	Command string `json:"command" jsonschema_description:"The shell command to execute."`
 }
 
+// ShellFunc runs a command line in place of a fresh shell, for instance in
+// a terminal the user is watching. vars are the KEY=VALUE variables the
+// tool was given with SetEnv, to be set for the command.
+type ShellFunc func(ctx context.Context, command string, vars []string) (string, error)
+
+type shellKey struct{}
+
+// WithShell makes the bash tool run the commands of calls made with the
+// returned context through fn. The call still goes through the tool as
+// the registry returns it, so its policy and middleware apply as usual.
+func WithShell(ctx context.Context, fn ShellFunc) context.Context {
+	return context.WithValue(ctx, shellKey{}, fn)
+}
+
+func shellFrom(ctx context.Context) ShellFunc {
+	fn, _ := ctx.Value(shellKey{}).(ShellFunc)
+	return fn
+}
+
 // Bash executes a shell command and returns its output.
 // This is powerful - it lets the agent run builds, tests, git commands, etc.
-// Platform-aware: uses cmd.exe on Windows, bash elsewhere.
-func Bash(input json.RawMessage) (string, error) {
+// Platform-aware: uses cmd.exe on Windows, bash elsewhere. Cancelling ctx
+// kills the command and everything it started.
+func Bash(ctx context.Context, input json.RawMessage) (string, error) {
 	var args BashInput
-	if err := json.Unmarshal(input, &args); err != nil {
+	if err := decodeInput(input, &args); err != nil {
 		return "", err
 	}
+	if strings.TrimSpace(args.Command) == "" {
+		return "", NewError(ErrInvalidInput, "command is required")
+	}
+	if shell := shellFrom(ctx); shell != nil {
+		return shell(ctx, args.Command, nil)
+	}
+
+	output, _, err := runShell(ctx, args.Command, nil)
+	return output, err
+}
 
+// ShellCommand returns the command that runs a shell command line the way
+// the bash tool does: with bash, or cmd.exe on Windows. Cancelling ctx
+// kills the command along with the processes it started.
+func ShellCommand(ctx context.Context, command string) *exec.Cmd {
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", args.Command)
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
 	} else {
-		cmd = exec.Command("bash", "-c", args.Command)
+		cmd = exec.CommandContext(ctx, "bash", "-c", command)
 	}
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// Return both the error and output - often useful for debugging
-		return fmt.Sprintf("Command failed: %s\nOutput: %s", err.Error(), string(output)), nil
-	}
-
-	return strings.TrimSpace(string(output)), nil
+	killGroupOnCancel(cmd)
+	cmd.WaitDelay = shellWaitDelay
+	return cmd
 }
 
 // BashTool is the tool definition for shell execution.
-var BashTool = NewTool[BashInput](
+var BashTool = NewToolCtx[BashInput](
 	"bash",
 	"Execute a shell command and return its output. Use this for running builds, tests, git commands, or any other shell operations.",
 	Bash,
@@ -2,8 +2,21 @@
 
 package tools
 
-import "os/exec"
+import (
+	"os/exec"
+	"syscall"
+)
 
 func hideCommandWindow(cmd *exec.Cmd) {
 	// No-op on non-Windows platforms
 }
+
+// killGroupOnCancel starts cmd in a process group of its own and has
+// cancelling it kill the whole group, so a build or server the shell
+// started dies with it instead of holding its output open.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
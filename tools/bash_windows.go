@@ -13,3 +13,7 @@ func hideCommandWindow(cmd *exec.Cmd) {
 		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
 	}
 }
+
+// killGroupOnCancel leaves cancelling to exec, which kills cmd.exe; the
+// WaitDelay ShellCommand sets stops waiting on anything it started.
+func killGroupOnCancel(cmd *exec.Cmd) {}
@@ -40,15 +40,15 @@ var (
 
 func broadcastFunc(input json.RawMessage) (string, error) {
 	var params BroadcastInput
-	if err := json.Unmarshal(input, &params); err != nil {
-		return "", fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &params); err != nil {
+		return "", err
 	}
 
 	if params.AgentID == "" {
-		return "", fmt.Errorf("agent_id is required")
+		return "", NewError(ErrInvalidInput, "agent_id is required")
 	}
 	if params.Status == "" {
-		return "", fmt.Errorf("status is required")
+		return "", NewError(ErrInvalidInput, "status is required")
 	}
 
 	if params.UseTXT {
@@ -62,7 +62,7 @@ func broadcastViaFile(params BroadcastInput) (string, error) {
 	defer broadcastLock.Unlock()
 
 	if err := os.MkdirAll(broadcastDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create broadcast directory: %w", err)
+		return "", WrapError(err, "failed to create broadcast directory")
 	}
 
 	statusData := map[string]interface{}{
@@ -76,12 +76,12 @@ func broadcastViaFile(params BroadcastInput) (string, error) {
 
 	data, err := json.MarshalIndent(statusData, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal status: %w", err)
+		return "", WrapError(err, "failed to marshal status")
 	}
 
 	statusFile := filepath.Join(broadcastDir, fmt.Sprintf("agent-%s.json", params.AgentID))
 	if err := os.WriteFile(statusFile, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write status file: %w", err)
+		return "", WrapError(err, "failed to write status file")
 	}
 
 	return fmt.Sprintf("Status broadcast (file): agent=%s status=%s task=%s",
@@ -136,8 +136,8 @@ func broadcastViaTXT(params BroadcastInput) (string, error) {
 
 func observeAgentsFunc(input json.RawMessage) (string, error) {
 	var params ObserveInput
-	if err := json.Unmarshal(input, &params); err != nil {
-		return "", fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &params); err != nil {
+		return "", err
 	}
 
 	if params.UseTXT {
@@ -157,7 +157,7 @@ func observeViaFile(params ObserveInput) (string, error) {
 		if os.IsNotExist(err) {
 			return "No agent broadcasts found", nil
 		}
-		return "", fmt.Errorf("failed to read directory: %w", err)
+		return "", WrapError(err, "failed to read directory")
 	}
 
 	var agents []map[string]interface{}
@@ -194,7 +194,7 @@ func observeViaFile(params ObserveInput) (string, error) {
 
 	result, err := json.MarshalIndent(agents, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal results: %w", err)
+		return "", WrapError(err, "failed to marshal results")
 	}
 
 	return string(result), nil
@@ -248,7 +248,7 @@ func observeViaTXT(params ObserveInput) (string, error) {
 
 	result, err := json.MarshalIndent(agentsCopy, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal results: %w", err)
+		return "", WrapError(err, "failed to marshal results")
 	}
 
 	return fmt.Sprintf("Discovered %d agents via TXT records:\n%s", len(agentsCopy), string(result)), nil
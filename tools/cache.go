@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheKeyFunc derives the cache key of a call to an idempotent tool. The
+// key must change whenever the result could, so it folds in the state the
+// tool reads (a file's mtime, the repository's). ok is false when that
+// state can't be determined and the call must not be cached.
+type CacheKeyFunc func(input json.RawMessage) (key string, ok bool)
+
+// WithCache returns a copy of t whose results a ResultCache may reuse.
+func (t Tool) WithCache(key CacheKeyFunc) Tool {
+	t.CacheKey = key
+	return t
+}
+
+// DefaultCacheSize is the result bytes a session's cache holds before it
+// evicts the least recently used entries.
+const DefaultCacheSize = 16 << 20
+
+// ResultCache memoizes results of tools that have a CacheKey, for one
+// session. Models often repeat a call they made a few steps earlier; a hit
+// skips re-reading the file or re-running the search. Only successful
+// results are kept. A nil *ResultCache runs every call. It is safe for
+// concurrent use.
+type ResultCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	entries  map[string]*list.Element
+	order    *list.List // most recently used first
+	hits     int
+	misses   int
+}
+
+type cacheEntry struct {
+	key    string
+	result string
+}
+
+// NewResultCache returns an empty cache holding up to maxBytes of results.
+func NewResultCache(maxBytes int) *ResultCache {
+	return &ResultCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Execute runs t through the cache. cached reports whether the result was
+// reused rather than produced by this call.
+func (c *ResultCache) Execute(ctx context.Context, t Tool, input json.RawMessage) (result string, cached bool, err error) {
+	if c == nil || t.CacheKey == nil {
+		result, err = t.Execute(ctx, input)
+		return result, false, err
+	}
+	key, ok := t.CacheKey(input)
+	if !ok {
+		result, err = t.Execute(ctx, input)
+		return result, false, err
+	}
+	key = t.Name + "\x00" + key
+
+	if result, ok := c.get(key); ok {
+		return result, true, nil
+	}
+	result, err = t.Execute(ctx, input)
+	if err == nil {
+		c.put(key, result)
+	}
+	return result, false, err
+}
+
+func (c *ResultCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return "", false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).result, true
+}
+
+func (c *ResultCache) put(key, result string) {
+	if len(result) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.size -= len(elem.Value.(*cacheEntry).result)
+		c.order.Remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result})
+	c.size += len(result)
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= len(entry.result)
+	}
+}
+
+// Stats returns how many cacheable calls were answered from the cache and
+// how many had to run.
+func (c *ResultCache) Stats() (hits, misses int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// fileStateKey identifies the current version of the file at path by its
+// absolute path, size and modification time.
+func fileStateKey(path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	info, err := os.Stat(abs)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return fmt.Sprintf("%s\x00%d\x00%d", abs, info.Size(), info.ModTime().UnixNano()), true
+}
+
+// readFileCacheKey keys read_file by the file's path and version and the
+// lines asked for.
+func readFileCacheKey(input json.RawMessage) (string, bool) {
+	var args ReadFileInput
+	if decodeInput(input, &args) != nil || args.Path == "" {
+		return "", false
+	}
+	key, ok := fileStateKey(args.Path)
+	return fmt.Sprintf("%s\x00%d\x00%d", key, args.Offset, args.Limit), ok
+}
+
+// codeSearchCacheKey keys code_search by its arguments and the state of the
+// git repository searched. Outside a repository nothing is cached, since
+// there is no cheap way to tell whether any file changed.
+func codeSearchCacheKey(input json.RawMessage) (string, bool) {
+	var args CodeSearchInput
+	if decodeInput(input, &args) != nil || args.Pattern == "" {
+		return "", false
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+	abs, err := filepath.Abs(args.Path)
+	if err != nil {
+		return "", false
+	}
+	args.Path = abs
+
+	dir := abs
+	if info, err := os.Stat(abs); err != nil {
+		return "", false
+	} else if !info.IsDir() {
+		dir = filepath.Dir(abs)
+	}
+	state, ok := repoState(dir)
+	if !ok {
+		return "", false
+	}
+	normalized, _ := json.Marshal(args)
+	return string(normalized) + "\x00" + state, true
+}
+
+// repoStateTimeout bounds the git commands behind repoState; a search
+// that can't be keyed quickly just runs.
+const repoStateTimeout = 5 * time.Second
+
+// repoState fingerprints the working tree of the repository containing
+// dir: its HEAD commit, its status, and the size and mtime of every file
+// the status lists. Status alone would miss a modified file that is edited
+// again, since it stays " M".
+func repoState(dir string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), repoStateTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-toplevel", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) != 2 {
+		return "", false
+	}
+	root, head := lines[0], lines[1]
+
+	status, err := exec.CommandContext(ctx, "git", "-C", root, "status", "--porcelain=v1", "-z", "--untracked-files=all").Output()
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	h.Write([]byte(head))
+	h.Write(status)
+	for _, field := range strings.Split(string(status), "\x00") {
+		// Entries are "XY path"; the original path of a rename follows
+		// as a bare field, and stat-ing it harmlessly fails.
+		if len(field) < 4 {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(root, field[3:])); err == nil {
+			fmt.Fprintf(h, "\x00%s\x00%d\x00%d", field[3:], info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
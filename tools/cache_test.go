@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultCacheReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	tool := ReadFileTool
+	tool.Function = func(input json.RawMessage) (string, error) {
+		calls++
+		return ReadFile(input)
+	}
+
+	cache := NewResultCache(DefaultCacheSize)
+	input, _ := json.Marshal(ReadFileInput{Path: path})
+	read := func() (string, bool) {
+		t.Helper()
+		result, cached, err := cache.Execute(context.Background(), tool, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, cached
+	}
+
+	if result, cached := read(); result != "     1\tone\n" || cached {
+		t.Fatalf("first read = %q, cached %v", result, cached)
+	}
+	if result, cached := read(); result != "     1\tone\n" || !cached || calls != 1 {
+		t.Fatalf("second read = %q, cached %v after %d calls; want a hit", result, cached, calls)
+	}
+
+	// A rewrite with the same size still moves the mtime.
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("two"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+	if result, cached := read(); result != "     1\ttwo\n" || cached {
+		t.Fatalf("read after edit = %q, cached %v; want the new content", result, cached)
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("Stats() = %d hits, %d misses; want 1, 2", hits, misses)
+	}
+}
+
+func TestResultCacheSkipsErrorsAndUncacheableTools(t *testing.T) {
+	cache := NewResultCache(DefaultCacheSize)
+	missing, _ := json.Marshal(ReadFileInput{Path: filepath.Join(t.TempDir(), "missing")})
+	if _, cached, err := cache.Execute(context.Background(), ReadFileTool, missing); err == nil || cached {
+		t.Errorf("missing file: cached %v, err %v", cached, err)
+	}
+
+	calls := 0
+	plain := NewTool[ReadFileInput]("plain", "", func(json.RawMessage) (string, error) {
+		calls++
+		return "ok", nil
+	})
+	for range 2 {
+		cache.Execute(context.Background(), plain, nil)
+	}
+	if calls != 2 {
+		t.Errorf("tool without a cache key ran %d times, want 2", calls)
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewResultCache(10)
+	cache.put("a", "12345")
+	cache.put("b", "12345")
+	cache.get("a")
+	cache.put("c", "12345")
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("a was used most recently and should be kept")
+	}
+	cache.put("huge", "12345678901")
+	if _, ok := cache.get("huge"); ok {
+		t.Error("a result larger than the cache should not be stored")
+	}
+}
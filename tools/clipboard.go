@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"brutus/internal/text"
+)
+
+// ClipboardGetInput defines the parameters for the clipboard_get tool.
+type ClipboardGetInput struct{}
+
+// ClipboardSetInput defines the parameters for the clipboard_set tool.
+type ClipboardSetInput struct {
+	Text string `json:"text" jsonschema_description:"The text to put on the clipboard, replacing what is there."`
+}
+
+// maxClipboardText caps how much of the clipboard clipboard_get returns.
+const maxClipboardText = 100000
+
+// NewClipboardGetTool returns the clipboard_get tool, which reads the
+// system clipboard through get. Only the GUI registers it; the terminal
+// has no clipboard it can reach portably.
+func NewClipboardGetTool(get func() (string, error)) Tool {
+	return NewTool[ClipboardGetInput](
+		"clipboard_get",
+		"Read the text on the user's clipboard, e.g. an error message or log excerpt they just copied. The user approves each read.",
+		func(input json.RawMessage) (string, error) {
+			content, err := get()
+			if err != nil {
+				return "", WrapError(err, "failed to read the clipboard")
+			}
+			if content == "" {
+				return "The clipboard is empty or holds no text.", nil
+			}
+			return text.HeadTail(content, maxClipboardText), nil
+		},
+	)
+}
+
+// NewClipboardSetTool returns the clipboard_set tool, which writes the
+// system clipboard through set.
+func NewClipboardSetTool(set func(string) error) Tool {
+	return NewTool[ClipboardSetInput](
+		"clipboard_set",
+		"Put text on the user's clipboard so they can paste it elsewhere, such as a generated snippet or command. The user approves each write.",
+		func(input json.RawMessage) (string, error) {
+			var args ClipboardSetInput
+			if err := decodeInput(input, &args); err != nil {
+				return "", err
+			}
+			if args.Text == "" {
+				return "", NewError(ErrInvalidInput, "text is required")
+			}
+			if err := set(args.Text); err != nil {
+				return "", WrapError(err, "failed to write the clipboard")
+			}
+			return fmt.Sprintf("Copied %d characters to the clipboard.", len([]rune(args.Text))), nil
+		},
+	)
+}
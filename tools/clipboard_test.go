@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestClipboardTools(t *testing.T) {
+	var clipboard string
+	get := NewClipboardGetTool(func() (string, error) { return clipboard, nil })
+	set := NewClipboardSetTool(func(s string) error { clipboard = s; return nil })
+
+	if result, err := get.Function(json.RawMessage(`{}`)); err != nil || result != "The clipboard is empty or holds no text." {
+		t.Errorf("empty clipboard_get = %q, %v", result, err)
+	}
+	if result, err := set.Function(json.RawMessage(`{"text":"go test ./..."}`)); err != nil || result != "Copied 13 characters to the clipboard." {
+		t.Errorf("clipboard_set = %q, %v", result, err)
+	}
+	if result, err := get.Function(json.RawMessage(`{}`)); err != nil || result != "go test ./..." {
+		t.Errorf("clipboard_get = %q, %v", result, err)
+	}
+	if _, err := set.Function(json.RawMessage(`{"text":""}`)); CodeOf(err) != ErrInvalidInput {
+		t.Errorf("empty clipboard_set err = %v, want invalid_input", err)
+	}
+
+	broken := NewClipboardGetTool(func() (string, error) { return "", errors.New("no display") })
+	if _, err := broken.Function(json.RawMessage(`{}`)); err == nil {
+		t.Error("clipboard_get should report a failing clipboard")
+	}
+}
@@ -0,0 +1,614 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DepsInput defines the parameters for the imports_of and dependents_of tools.
+type DepsInput struct {
+	Target     string `json:"target" jsonschema_description:"A Go import path or package directory, a Python module (pkg.mod), or a source file (.go, .py, .js, .ts, ...), relative to root. dependents_of also accepts an external package such as 'github.com/foo/bar', 'requests' or 'react'."`
+	Transitive bool   `json:"transitive,omitempty" jsonschema_description:"Follow the graph all the way instead of stopping at direct imports."`
+	Root       string `json:"root,omitempty" jsonschema_description:"Project root to analyse. Defaults to the current directory."`
+}
+
+const (
+	// depsTimeout bounds go list, which may need to load a large module.
+	depsTimeout = 2 * time.Minute
+
+	// maxDepsEntries caps how many packages or files a result lists.
+	maxDepsEntries = 300
+
+	// maxDepsFiles caps how many Python or JavaScript files are parsed.
+	maxDepsFiles = 20000
+
+	// maxScanFileSize is the largest file parsed for imports or symbols;
+	// bigger ones are generated code or data.
+	maxScanFileSize = 1 << 20
+)
+
+// depGraph is the import graph of a project. Nodes are Go import paths or,
+// for Python and JavaScript, file paths relative to the root. Imports that
+// don't resolve to a node are kept under their package name.
+type depGraph struct {
+	ecosystem string
+	// imports maps a node to what it imports; the value is true for
+	// imports that only its tests make.
+	imports map[string]map[string]bool
+	// external names the kind of an import that is not a node.
+	external func(name string) string
+	// dirs maps Go package directories to import paths.
+	dirs map[string]string
+}
+
+func newDepGraph(ecosystem string, external func(string) string) *depGraph {
+	return &depGraph{ecosystem: ecosystem, imports: make(map[string]map[string]bool), external: external}
+}
+
+func (g *depGraph) addNode(node string) {
+	if g.imports[node] == nil {
+		g.imports[node] = make(map[string]bool)
+	}
+}
+
+func (g *depGraph) addEdge(from, to string, testOnly bool) {
+	g.addNode(from)
+	if from == to {
+		return
+	}
+	if existing, ok := g.imports[from][to]; ok {
+		testOnly = testOnly && existing
+	}
+	g.imports[from][to] = testOnly
+}
+
+func (g *depGraph) kind(name string) string {
+	if _, ok := g.imports[name]; ok {
+		return "project"
+	}
+	return g.external(name)
+}
+
+// depEntry is one package or file in a result.
+type depEntry struct {
+	name     string
+	indirect bool
+	testOnly bool
+}
+
+// walk returns the nodes reachable from start through next, direct ones
+// first, each list sorted.
+func (g *depGraph) walk(start string, transitive bool, next func(string) map[string]bool) []depEntry {
+	direct := next(start)
+	seen := map[string]bool{start: true}
+	var entries, indirect []depEntry
+	var queue []string
+	for _, name := range sortedKeys(direct) {
+		seen[name] = true
+		entries = append(entries, depEntry{name: name, testOnly: direct[name]})
+		if !direct[name] {
+			queue = append(queue, name)
+		}
+	}
+	for transitive && len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for name, testOnly := range next(node) {
+			// Another package's test imports don't reach us.
+			if seen[name] || testOnly {
+				continue
+			}
+			seen[name] = true
+			indirect = append(indirect, depEntry{name: name, indirect: true})
+			queue = append(queue, name)
+		}
+	}
+	sort.Slice(indirect, func(i, j int) bool { return indirect[i].name < indirect[j].name })
+	return append(entries, indirect...)
+}
+
+// dependents inverts the graph.
+func (g *depGraph) dependents() map[string]map[string]bool {
+	reverse := make(map[string]map[string]bool)
+	for from, imports := range g.imports {
+		for to, testOnly := range imports {
+			if reverse[to] == nil {
+				reverse[to] = make(map[string]bool)
+			}
+			reverse[to][from] = testOnly
+		}
+	}
+	return reverse
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ImportsOf lists what a package or file imports.
+func ImportsOf(ctx context.Context, input json.RawMessage) (string, error) {
+	args, g, target, err := loadDeps(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := g.imports[target]; !ok {
+		return "", NewError(ErrNotFound, "%s is not part of the %s project at %s", target, g.ecosystem, args.Root).WithDetail("target", target)
+	}
+
+	entries := g.walk(target, args.Transitive, func(node string) map[string]bool { return g.imports[node] })
+	if len(entries) == 0 {
+		return fmt.Sprintf("%s imports nothing", target), nil
+	}
+
+	groups := map[string][]string{}
+	var kinds []string
+	for _, e := range entries {
+		kind := g.kind(e.name)
+		if groups[kind] == nil {
+			kinds = append(kinds, kind)
+		}
+		groups[kind] = append(groups[kind], describeDep(e))
+	}
+	sort.Strings(kinds)
+
+	var b strings.Builder
+	scope := "directly"
+	if args.Transitive {
+		scope = "directly and indirectly"
+	}
+	fmt.Fprintf(&b, "%s (%s) imports %d %s:\n", target, g.ecosystem, len(entries), scope)
+	writeDepGroups(&b, kinds, groups)
+	return b.String(), nil
+}
+
+// DependentsOf lists the packages or files that import a target.
+func DependentsOf(ctx context.Context, input json.RawMessage) (string, error) {
+	args, g, target, err := loadDeps(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	reverse := g.dependents()
+	entries := g.walk(target, args.Transitive, func(node string) map[string]bool { return reverse[node] })
+	if len(entries) == 0 {
+		return fmt.Sprintf("Nothing in the %s project at %s imports %s", g.ecosystem, args.Root, target), nil
+	}
+
+	groups := map[string][]string{}
+	for _, e := range entries {
+		kind := "directly"
+		if e.indirect {
+			kind = "indirectly"
+		}
+		groups[kind] = append(groups[kind], describeDep(e))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d imported %s (%s):\n", len(entries), target, g.ecosystem)
+	writeDepGroups(&b, []string{"directly", "indirectly"}, groups)
+	return b.String(), nil
+}
+
+func describeDep(e depEntry) string {
+	if e.testOnly {
+		return e.name + " (tests only)"
+	}
+	return e.name
+}
+
+func writeDepGroups(b *strings.Builder, kinds []string, groups map[string][]string) {
+	written := 0
+	for _, kind := range kinds {
+		names := groups[kind]
+		if len(names) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "%s (%d):\n", kind, len(names))
+		for _, name := range names {
+			if written == maxDepsEntries {
+				fmt.Fprintf(b, "  ... more omitted\n")
+				return
+			}
+			fmt.Fprintf(b, "  %s\n", name)
+			written++
+		}
+	}
+}
+
+// loadDeps decodes the input, builds the graph of the ecosystem the target
+// belongs to and resolves the target to a node name.
+func loadDeps(ctx context.Context, input json.RawMessage) (DepsInput, *depGraph, string, error) {
+	var args DepsInput
+	if err := decodeInput(input, &args); err != nil {
+		return args, nil, "", err
+	}
+	args.Target = strings.TrimSpace(args.Target)
+	if args.Target == "" {
+		return args, nil, "", NewError(ErrInvalidInput, "target is required")
+	}
+	if args.Root == "" {
+		args.Root = "."
+	}
+	if err := checkPath(args.Root); err != nil {
+		return args, nil, "", err
+	}
+	root, err := filepath.Abs(args.Root)
+	if err != nil {
+		return args, nil, "", WrapError(err, "bad root").WithDetail("root", args.Root)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return args, nil, "", NewError(ErrNotFound, "root %s is not a directory", args.Root).WithDetail("root", args.Root)
+	}
+
+	graphCtx, cancel := context.WithTimeout(ctx, depsTimeout)
+	defer cancel()
+
+	switch ecosystem := depsEcosystem(root, args.Target); ecosystem {
+	case "go":
+		g, err := goDepGraph(graphCtx, root)
+		if ctx.Err() != nil {
+			return args, nil, "", interrupted(ctx, "go list")
+		}
+		if err != nil {
+			return args, nil, "", err
+		}
+		return args, g, resolveGoTarget(g, root, args.Target), nil
+	case "python", "javascript":
+		g, err := fileDepGraph(root, ecosystem)
+		if err != nil {
+			return args, nil, "", err
+		}
+		return args, g, resolveFileTarget(g, root, args.Target), nil
+	default:
+		return args, nil, "", NewError(ErrInvalidInput, "cannot tell which language %q belongs to; pass a source file", args.Target)
+	}
+}
+
+var jsExtensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"}
+
+// depsEcosystem picks the language to analyse from the target's extension,
+// falling back to the project's manifest files.
+func depsEcosystem(root, target string) string {
+	switch ext := filepath.Ext(target); {
+	case ext == ".go":
+		return "go"
+	case ext == ".py":
+		return "python"
+	case containsString(jsExtensions, ext):
+		return "javascript"
+	}
+	path := target
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if _, err := os.Stat(filepath.Join(path, "__init__.py")); err == nil {
+		return "python"
+	}
+	for _, m := range []struct{ file, ecosystem string }{
+		{"go.mod", "go"},
+		{"package.json", "javascript"},
+		{"pyproject.toml", "python"},
+		{"setup.py", "python"},
+		{"requirements.txt", "python"},
+	} {
+		if _, err := os.Stat(filepath.Join(root, m.file)); err == nil {
+			return m.ecosystem
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// goPackage holds the fields of go list -json that the graph needs.
+type goPackage struct {
+	ImportPath   string
+	Dir          string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// goDepGraph loads the packages of the module at root with go list.
+func goDepGraph(ctx context.Context, root string) (*depGraph, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil, NewError(ErrNotFound, "go is not installed")
+	}
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-json=ImportPath,Dir,Imports,TestImports,XTestImports", "./...")
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, NewError(ErrTimeout, "go list did not finish within %s", depsTimeout)
+	}
+	if err != nil && len(out) == 0 {
+		return nil, NewError(ErrInternal, "go list failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []goPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var p goPackage
+		if err := dec.Decode(&p); err != nil {
+			return nil, NewError(ErrInternal, "unexpected go list output: %v", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+
+	g := newDepGraph("go", func(name string) string {
+		if first, _, _ := strings.Cut(name, "/"); !strings.Contains(first, ".") {
+			return "standard library"
+		}
+		return "third-party"
+	})
+	g.dirs = make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		g.dirs[filepath.Clean(p.Dir)] = p.ImportPath
+		g.addNode(p.ImportPath)
+		for _, imp := range p.Imports {
+			g.addEdge(p.ImportPath, imp, false)
+		}
+		for _, imp := range append(p.TestImports, p.XTestImports...) {
+			g.addEdge(p.ImportPath, imp, true)
+		}
+	}
+	return g, nil
+}
+
+// resolveGoTarget turns a file or directory into its package's import
+// path. Anything else is taken to be an import path already.
+func resolveGoTarget(g *depGraph, root, target string) string {
+	path := target
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if info, err := os.Stat(path); err == nil {
+		if !info.IsDir() {
+			path = filepath.Dir(path)
+		}
+		if importPath, ok := g.dirs[filepath.Clean(path)]; ok {
+			return importPath
+		}
+	}
+	return target
+}
+
+var (
+	pyImportRe     = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+([\w.]+(?:[ \t]+as[ \t]+\w+)?(?:[ \t]*,[ \t]*[\w.]+(?:[ \t]+as[ \t]+\w+)?)*)`)
+	pyFromImportRe = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+(\.*[\w.]*)[ \t]+import[ \t]+\(?([\w, \t]+)`)
+	jsImportRe     = regexp.MustCompile(`(?:\b(?:import|export)\s[^'"]*?\bfrom\s*|\bimport\s*\(?\s*|\brequire\s*\(\s*)['"]([^'"\n]+)['"]`)
+)
+
+// fileDepGraph parses the imports of every Python or JavaScript/TypeScript
+// file under root.
+func fileDepGraph(root, ecosystem string) (*depGraph, error) {
+	exts := []string{".py"}
+	if ecosystem == "javascript" {
+		exts = jsExtensions
+	}
+
+	var files []string
+	errTooMany := errors.New("too many files")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (skipDirs[name] || strings.HasPrefix(name, ".") || name == "dist" || name == "build") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if containsString(exts, filepath.Ext(path)) {
+			if len(files) == maxDepsFiles {
+				return errTooMany
+			}
+			rel, _ := filepath.Rel(root, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if errors.Is(err, errTooMany) {
+		return nil, NewError(ErrTooLarge, "more than %d %s files under %s; pass a narrower root", maxDepsFiles, ecosystem, root)
+	}
+
+	g := newDepGraph(ecosystem, func(string) string { return "external" })
+	known := map[string]bool{}
+	for _, f := range files {
+		g.addNode(f)
+		known[f] = true
+	}
+
+	modules := map[string]string{} // Python module name -> file
+	if ecosystem == "python" {
+		for _, f := range files {
+			for _, name := range pyModuleNames(f) {
+				modules[name] = f
+			}
+		}
+	}
+
+	for _, f := range files {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(f)))
+		if err != nil || len(content) > maxScanFileSize {
+			continue
+		}
+		if ecosystem == "python" {
+			for _, imp := range pyImports(f, string(content), modules) {
+				g.addEdge(f, imp, false)
+			}
+		} else {
+			for _, m := range jsImportRe.FindAllStringSubmatch(string(content), -1) {
+				g.addEdge(f, resolveJSImport(f, m[1], known), false)
+			}
+		}
+	}
+	return g, nil
+}
+
+// pyModuleNames returns the names a Python file can be imported as:
+// "pkg/mod.py" is pkg.mod, "pkg/__init__.py" is pkg, and files under src/
+// are also importable without the prefix.
+func pyModuleNames(file string) []string {
+	name := strings.TrimSuffix(file, ".py")
+	name = strings.TrimSuffix(name, "/__init__")
+	name = strings.ReplaceAll(name, "/", ".")
+	names := []string{name}
+	if rest, ok := strings.CutPrefix(name, "src."); ok {
+		names = append(names, rest)
+	}
+	return names
+}
+
+// pyImports returns what a Python file imports: project files where the
+// module resolves, top-level package names otherwise.
+func pyImports(file, content string, modules map[string]string) []string {
+	var imports []string
+	resolve := func(module string) {
+		for m := module; m != ""; {
+			if f, ok := modules[m]; ok {
+				imports = append(imports, f)
+				return
+			}
+			i := strings.LastIndex(m, ".")
+			if i < 0 {
+				break
+			}
+			m = m[:i]
+		}
+		first, _, _ := strings.Cut(module, ".")
+		imports = append(imports, first)
+	}
+
+	for _, m := range pyImportRe.FindAllStringSubmatch(content, -1) {
+		for _, part := range strings.Split(m[1], ",") {
+			if fields := strings.Fields(part); len(fields) > 0 {
+				resolve(fields[0])
+			}
+		}
+	}
+
+	pkg := strings.Split(pyModuleNames(file)[0], ".")
+	if !strings.HasSuffix(file, "__init__.py") {
+		pkg = pkg[:len(pkg)-1]
+	}
+	for _, m := range pyFromImportRe.FindAllStringSubmatch(content, -1) {
+		module := m[1]
+		if dots := len(module) - len(strings.TrimLeft(module, ".")); dots > 0 {
+			up := dots - 1
+			if up > len(pkg) {
+				continue
+			}
+			base := strings.Join(pkg[:len(pkg)-up], ".")
+			module = strings.Trim(base+"."+module[dots:], ".")
+		}
+		// "from pkg import mod" imports the submodule when there is one.
+		matched := false
+		for _, name := range strings.Split(m[2], ",") {
+			if fields := strings.Fields(name); len(fields) > 0 {
+				if f, ok := modules[strings.Trim(module+"."+fields[0], ".")]; ok {
+					imports = append(imports, f)
+					matched = true
+				}
+			}
+		}
+		if !matched && module != "" {
+			resolve(module)
+		}
+	}
+	return imports
+}
+
+// resolveJSImport resolves a relative specifier the way bundlers do, trying
+// extensions and index files. Bare specifiers become their package name.
+func resolveJSImport(file, spec string, known map[string]bool) string {
+	if !strings.HasPrefix(spec, ".") {
+		parts := strings.SplitN(spec, "/", 3)
+		if strings.HasPrefix(spec, "@") && len(parts) > 1 {
+			return parts[0] + "/" + parts[1]
+		}
+		return parts[0]
+	}
+	base := filepath.ToSlash(filepath.Join(filepath.Dir(file), spec))
+	candidates := []string{base}
+	// TypeScript sources import each other with the .js extension of the
+	// compiled output.
+	trimmed := strings.TrimSuffix(base, filepath.Ext(base))
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, base+ext, trimmed+ext, base+"/index"+ext)
+	}
+	for _, c := range candidates {
+		if known[c] {
+			return c
+		}
+	}
+	return base
+}
+
+// resolveFileTarget turns a file path, directory or Python module name into
+// a node of g. Anything else is taken to be an external package.
+func resolveFileTarget(g *depGraph, root, target string) string {
+	path := target
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		rel = filepath.ToSlash(rel)
+		candidates := []string{rel, rel + "/__init__.py"}
+		for _, ext := range jsExtensions {
+			candidates = append(candidates, rel+"/index"+ext)
+		}
+		for _, c := range candidates {
+			if _, ok := g.imports[c]; ok {
+				return c
+			}
+		}
+	}
+	if g.ecosystem == "python" {
+		for node := range g.imports {
+			if containsString(pyModuleNames(node), target) {
+				return node
+			}
+		}
+	}
+	return target
+}
+
+// ImportsOfTool is the tool definition for listing a package's imports.
+var ImportsOfTool = NewToolCtx[DepsInput](
+	"imports_of",
+	"List what a Go package, Python module or JavaScript/TypeScript file imports, grouped into project, standard library and third-party. Uses go list for Go and parses import statements for the others. Set transitive for the full dependency closure.",
+	ImportsOf,
+)
+
+// DependentsOfTool is the tool definition for finding what imports a package.
+var DependentsOfTool = NewToolCtx[DepsInput](
+	"dependents_of",
+	"List the packages or files in the project that import a Go package, Python module, JavaScript/TypeScript file or external package: the blast radius of changing it. Set transitive to include indirect dependents. Use this instead of grepping for import strings before a refactor.",
+	DependentsOf,
+)
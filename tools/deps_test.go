@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (path -> content) under a new temp directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func depsCall(t *testing.T, fn ToolFuncCtx, root, target string, transitive bool) string {
+	t.Helper()
+	input, _ := json.Marshal(DepsInput{Target: target, Root: root, Transitive: transitive})
+	result, err := fn(context.Background(), input)
+	if err != nil {
+		t.Fatalf("%s: %v", target, err)
+	}
+	return result
+}
+
+func TestDepsGo(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module example.com/m\n\ngo 1.21\n",
+		"main.go":     "package main\n\nimport _ \"example.com/m/a\"\n\nfunc main() {}\n",
+		"a/a.go":      "package a\n\nimport _ \"example.com/m/b\"\n",
+		"b/b.go":      "package b\n\nimport _ \"strings\"\n",
+		"c/c.go":      "package c\n",
+		"c/c_test.go": "package c\n\nimport _ \"example.com/m/b\"\n",
+	})
+
+	got := depsCall(t, DependentsOf, root, "b", false)
+	if !strings.Contains(got, "example.com/m/a\n") || !strings.Contains(got, "example.com/m/c (tests only)") || strings.Contains(got, "indirectly") {
+		t.Errorf("direct dependents of b:\n%s", got)
+	}
+	got = depsCall(t, DependentsOf, root, "example.com/m/b", true)
+	if !strings.Contains(got, "indirectly (1):\n  example.com/m\n") {
+		t.Errorf("transitive dependents of b:\n%s", got)
+	}
+	got = depsCall(t, ImportsOf, root, "a/a.go", true)
+	if !strings.Contains(got, "project (1):\n  example.com/m/b\n") || !strings.Contains(got, "standard library (1):\n  strings\n") {
+		t.Errorf("transitive imports of a:\n%s", got)
+	}
+}
+
+func TestDepsPython(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"pyproject.toml":  "",
+		"app/__init__.py": "",
+		"app/models.py":   "import requests\n",
+		"app/views.py":    "from . import models\nfrom .util import helper\n",
+		"app/util.py":     "import os, json as j\n",
+		"scripts/run.py":  "from app.views import index\n",
+		".venv/lib/x.py":  "import app.models\n",
+	})
+
+	got := depsCall(t, DependentsOf, root, "app/models.py", true)
+	if !strings.Contains(got, "directly (1):\n  app/views.py\n") || !strings.Contains(got, "indirectly (1):\n  scripts/run.py\n") {
+		t.Errorf("dependents of app/models.py:\n%s", got)
+	}
+	got = depsCall(t, ImportsOf, root, "app.views", false)
+	if !strings.Contains(got, "project (2):\n  app/models.py\n  app/util.py\n") {
+		t.Errorf("imports of app.views:\n%s", got)
+	}
+	got = depsCall(t, DependentsOf, root, "requests", false)
+	if !strings.Contains(got, "app/models.py") {
+		t.Errorf("dependents of requests:\n%s", got)
+	}
+}
+
+func TestDepsJavaScript(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"package.json":        "{}",
+		"src/main.tsx":        "import App from './App';\nimport { render } from 'react-dom/client';\n",
+		"src/App.tsx":         "import {\n  a,\n  b,\n} from './lib/util.js';\nimport '@scope/pkg/style.css';\n",
+		"src/lib/util.ts":     "export const a = 1;\n",
+		"src/lib/index.ts":    "const u = require('./util');\n",
+		"node_modules/x/y.js": "import '../../src/App';\n",
+	})
+
+	got := depsCall(t, DependentsOf, root, "src/lib/util.ts", true)
+	want := "directly (2):\n  src/App.tsx\n  src/lib/index.ts\nindirectly (1):\n  src/main.tsx\n"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("dependents of util.ts:\n%s\nwant suffix:\n%s", got, want)
+	}
+	got = depsCall(t, ImportsOf, root, "src/App.tsx", false)
+	if !strings.Contains(got, "external (1):\n  @scope/pkg\n") {
+		t.Errorf("imports of App.tsx:\n%s", got)
+	}
+}
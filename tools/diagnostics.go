@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDiagnostics are the checks run after edit_file changes a file, by
+// extension. Commands run in the edited file's directory; {file} expands to
+// the file's path and {dir} to its directory.
+var DefaultDiagnostics = map[string]string{
+	".go": "go vet .",
+}
+
+var (
+	diagnosticsMu sync.RWMutex
+	diagnostics   = DefaultDiagnostics
+)
+
+// diagnosticsTimeout bounds each checker run so a slow build can't stall
+// the edit that triggered it.
+const diagnosticsTimeout = 30 * time.Second
+
+// maxDiagnostics caps how many new diagnostic lines are appended to a result.
+const maxDiagnostics = 20
+
+// ConfigureDiagnostics overrides the checks for the given extensions on top
+// of DefaultDiagnostics. An empty command disables checking for that
+// extension.
+func ConfigureDiagnostics(overrides map[string]string) {
+	merged := make(map[string]string, len(DefaultDiagnostics)+len(overrides))
+	for ext, cmd := range DefaultDiagnostics {
+		merged[ext] = cmd
+	}
+	for ext, cmd := range overrides {
+		if cmd == "" {
+			delete(merged, ext)
+		} else {
+			merged[ext] = cmd
+		}
+	}
+
+	diagnosticsMu.Lock()
+	diagnostics = merged
+	diagnosticsMu.Unlock()
+}
+
+// diagnosticCheck compares a checker's output before and after an edit.
+// The zero value does nothing.
+type diagnosticCheck struct {
+	command  []string
+	dir      string
+	baseline map[string]bool
+}
+
+// startDiagnostics runs the checker for path, if there is one, to record
+// the problems that exist before the edit.
+func startDiagnostics(path string) diagnosticCheck {
+	diagnosticsMu.RLock()
+	template := diagnostics[strings.ToLower(filepath.Ext(path))]
+	diagnosticsMu.RUnlock()
+	if template == "" {
+		return diagnosticCheck{}
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return diagnosticCheck{}
+	}
+	dir := filepath.Dir(abs)
+	var command []string
+	for _, field := range strings.Fields(template) {
+		field = strings.ReplaceAll(field, "{file}", abs)
+		field = strings.ReplaceAll(field, "{dir}", dir)
+		command = append(command, field)
+	}
+
+	c := diagnosticCheck{command: command, dir: dir, baseline: map[string]bool{}}
+	lines, ok := c.run()
+	if !ok {
+		return diagnosticCheck{}
+	}
+	for _, line := range lines {
+		c.baseline[normalizeDiagnostic(line)] = true
+	}
+	return c
+}
+
+// report runs the checker again and formats the problems the edit
+// introduced, or returns "" if there are none.
+func (c diagnosticCheck) report() string {
+	if c.command == nil {
+		return ""
+	}
+	lines, ok := c.run()
+	if !ok {
+		return ""
+	}
+
+	var added []string
+	for _, line := range lines {
+		if !c.baseline[normalizeDiagnostic(line)] {
+			added = append(added, line)
+		}
+	}
+	if len(added) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nNew problems reported by `" + strings.Join(c.command, " ") + "` after this edit:\n")
+	for i, line := range added {
+		if i == maxDiagnostics {
+			b.WriteString("... and more\n")
+			break
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// run executes the checker and returns its output lines. Checkers signal
+// problems with a non-zero exit, so only a failure to run at all (missing
+// binary, timeout) counts as not ok.
+func (c diagnosticCheck) run() ([]string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Dir = c.dir
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, false
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimRight(line, "\r")
+		// "# pkg" headers from the go tool carry no diagnostic.
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, true
+}
+
+// positionRe matches the "line:col:" part of a diagnostic, after the file
+// name or at the start of the line. It shifts whenever lines are added above.
+var positionRe = regexp.MustCompile(`(^|:)\d+(:\d+)?:`)
+
+func normalizeDiagnostic(line string) string {
+	return positionRe.ReplaceAllString(strings.TrimSpace(line), ":")
+}
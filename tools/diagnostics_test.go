@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func editFile(t *testing.T, input EditFileInput) string {
+	t.Helper()
+	data, _ := json.Marshal(input)
+	out, err := EditFile(data)
+	if err != nil {
+		t.Fatalf("EditFile(%s): %v", data, err)
+	}
+	return out
+}
+
+// useChecker installs a grep-based checker for .txt files that reports
+// every line containing BAD.
+func useChecker(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("grep"); err != nil {
+		t.Skip("grep not available")
+	}
+	ConfigureDiagnostics(map[string]string{".txt": "grep -n BAD {file}"})
+	t.Cleanup(func() { ConfigureDiagnostics(nil) })
+}
+
+func TestEditFileReportsNewDiagnostics(t *testing.T) {
+	useChecker(t)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("fine\n"), 0644)
+
+	out := editFile(t, EditFileInput{Path: path, OldStr: "fine", NewStr: "fine\nBAD line"})
+	if !strings.HasPrefix(out, "OK\n\nNew problems reported by `grep -n BAD") || !strings.Contains(out, "2:BAD line") {
+		t.Errorf("result = %q, want the new BAD line reported", out)
+	}
+}
+
+func TestEditFileIgnoresExistingDiagnostics(t *testing.T) {
+	useChecker(t)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("BAD already\nfine\n"), 0644)
+
+	// The existing problem moves down a line; it must not count as new.
+	out := editFile(t, EditFileInput{Path: path, OldStr: "BAD already", NewStr: "header\nBAD already"})
+	if out != "OK" {
+		t.Errorf("result = %q, want OK", out)
+	}
+}
+
+func TestEditFileDiagnosticsOnCreate(t *testing.T) {
+	useChecker(t)
+	path := filepath.Join(t.TempDir(), "sub", "new.txt")
+
+	out := editFile(t, EditFileInput{Path: path, NewStr: "BAD start\n"})
+	if !strings.Contains(out, "1:BAD start") {
+		t.Errorf("result = %q, want the BAD line reported", out)
+	}
+}
+
+func TestConfigureDiagnosticsDisable(t *testing.T) {
+	ConfigureDiagnostics(map[string]string{".go": ""})
+	t.Cleanup(func() { ConfigureDiagnostics(nil) })
+
+	if c := startDiagnostics("main.go"); c.command != nil {
+		t.Errorf("check for .go still configured: %v", c.command)
+	}
+}
+
+func TestMissingCheckerIsSkipped(t *testing.T) {
+	ConfigureDiagnostics(map[string]string{".txt": "brutus-no-such-checker {file}"})
+	t.Cleanup(func() { ConfigureDiagnostics(nil) })
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if out := editFile(t, EditFileInput{Path: path, NewStr: "x"}); strings.Contains(out, "New problems") {
+		t.Errorf("result = %q, want no diagnostics", out)
+	}
+}
@@ -22,18 +22,22 @@ type EditFileInput struct {
 // - If file doesn't exist and old_str is empty, creates new file with new_str
 // - If old_str is empty on existing file, appends new_str
 // - old_str must match exactly ONE location (prevents ambiguous edits)
+// - New problems reported by a checker (go vet for .go files) are appended
 func EditFile(input json.RawMessage) (string, error) {
 	var args EditFileInput
-	if err := json.Unmarshal(input, &args); err != nil {
+	if err := decodeInput(input, &args); err != nil {
 		return "", err
 	}
 
 	if args.Path == "" {
-		return "", fmt.Errorf("path is required")
+		return "", NewError(ErrInvalidInput, "path is required")
+	}
+	if err := checkPath(args.Path); err != nil {
+		return "", err
 	}
 
 	if args.OldStr == args.NewStr {
-		return "", fmt.Errorf("old_str and new_str must be different")
+		return "", NewError(ErrInvalidInput, "old_str and new_str must be different")
 	}
 
 	content, err := os.ReadFile(args.Path)
@@ -43,15 +47,16 @@ func EditFile(input json.RawMessage) (string, error) {
 			dir := path.Dir(args.Path)
 			if dir != "." {
 				if err := os.MkdirAll(dir, 0755); err != nil {
-					return "", fmt.Errorf("failed to create directory: %w", err)
+					return "", WrapError(err, "failed to create directory").WithDetail("path", dir)
 				}
 			}
+			check := startDiagnostics(args.Path)
 			if err := os.WriteFile(args.Path, []byte(args.NewStr), 0644); err != nil {
-				return "", fmt.Errorf("failed to create file: %w", err)
+				return "", WrapError(err, "failed to create file").WithDetail("path", args.Path)
 			}
-			return fmt.Sprintf("Created file %s", args.Path), nil
+			return fmt.Sprintf("Created file %s", args.Path) + check.report(), nil
 		}
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return "", WrapError(err, "failed to read file").WithDetail("path", args.Path)
 	}
 
 	oldContent := string(content)
@@ -64,19 +69,22 @@ func EditFile(input json.RawMessage) (string, error) {
 		// Replace mode - must be unique
 		count := strings.Count(oldContent, args.OldStr)
 		if count == 0 {
-			return "", fmt.Errorf("old_str not found in file")
+			return "", NewError(ErrNotFound, "old_str not found in file").WithDetail("path", args.Path)
 		}
 		if count > 1 {
-			return "", fmt.Errorf("old_str found %d times, must be unique", count)
+			return "", NewError(ErrInvalidInput, "old_str found %d times, must be unique", count).
+				WithDetail("path", args.Path).
+				WithDetail("matches", count)
 		}
 		newContent = strings.Replace(oldContent, args.OldStr, args.NewStr, 1)
 	}
 
+	check := startDiagnostics(args.Path)
 	if err := os.WriteFile(args.Path, []byte(newContent), 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+		return "", WrapError(err, "failed to write file").WithDetail("path", args.Path)
 	}
 
-	return "OK", nil
+	return "OK" + check.report(), nil
 }
 
 // EditFileTool is the tool definition for file editing.
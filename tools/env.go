@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"os"
+	"sort"
+	"sync"
+)
+
+// commandEnv holds the variables a tool's commands get on top of BRUTUS's
+// own environment. Each agent's tools have their own, so one agent's
+// variables never reach another's commands or the BRUTUS process.
+type commandEnv struct {
+	mu   sync.RWMutex
+	vars []string // KEY=VALUE
+}
+
+func (e *commandEnv) set(vars []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.vars = vars
+}
+
+// extra returns the tool's own variables, without the inherited ones.
+func (e *commandEnv) extra() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]string(nil), e.vars...)
+}
+
+// environ returns the environment for a command, or nil, which exec takes
+// as the process's own, when no variables are set. Later entries win, so
+// the tool's variables override inherited ones.
+func (e *commandEnv) environ(extra ...string) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if len(e.vars) == 0 && len(extra) == 0 {
+		return nil
+	}
+	env := append(os.Environ(), extra...)
+	return append(env, e.vars...)
+}
+
+// SetEnv gives the tools that run commands (bash, run_tests, format and
+// python_exec) extra environment variables. common applies to all of them
+// and perTool, keyed by tool name, to one, overriding common. Tools
+// without a SetEnv are left alone; nil maps clear what was set before.
+func (r *Registry) SetEnv(common map[string]string, perTool map[string]map[string]string) {
+	for _, t := range r.All() {
+		if t.SetEnv == nil {
+			continue
+		}
+		merged := make(map[string]string, len(common)+len(perTool[t.Name]))
+		for k, v := range common {
+			merged[k] = v
+		}
+		for k, v := range perTool[t.Name] {
+			merged[k] = v
+		}
+		t.SetEnv(envList(merged))
+	}
+}
+
+// envList returns vars as sorted KEY=VALUE entries.
+func envList(vars map[string]string) []string {
+	if len(vars) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(vars))
+	for k, v := range vars {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistrySetEnv(t *testing.T) {
+	if _, err := os.Stat("/bin/bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	learned, _ := LoadLearned(filepath.Join(t.TempDir(), "learned.json"))
+	r := NewRegistry()
+	r.Register(NewBashTool(learned))
+	r.Register(NewRunTestsTool(learned))
+	r.SetEnv(
+		map[string]string{"BRUTUS_TEST_A": "common", "BRUTUS_TEST_B": "common"},
+		map[string]map[string]string{"run_tests": {"BRUTUS_TEST_B": "tests"}},
+	)
+
+	run := func(name string) string {
+		tool, _ := r.Get(name)
+		input, _ := json.Marshal(map[string]string{"command": "echo $BRUTUS_TEST_A-$BRUTUS_TEST_B"})
+		out, err := tool.Function(input)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return out
+	}
+	if got := run("bash"); got != "common-common" {
+		t.Errorf("bash saw %q, want common-common", got)
+	}
+	if got := run("run_tests"); !strings.HasSuffix(got, "common-tests") {
+		t.Errorf("run_tests saw %q, want the per-tool value", got)
+	}
+	if os.Getenv("BRUTUS_TEST_A") != "" {
+		t.Error("SetEnv changed the process environment")
+	}
+
+	r.SetEnv(nil, nil)
+	if got := run("bash"); got != "-" {
+		t.Errorf("bash saw %q after clearing, want -", got)
+	}
+}
+
+func TestRegistrySetEnvWithShell(t *testing.T) {
+	learned, _ := LoadLearned(filepath.Join(t.TempDir(), "learned.json"))
+	r := NewRegistry()
+	r.Register(NewBashTool(learned))
+	r.SetEnv(map[string]string{"BRUTUS_TEST_A": "x"}, nil)
+
+	var got []string
+	ctx := WithShell(context.Background(), func(ctx context.Context, command string, vars []string) (string, error) {
+		got = vars
+		return "", nil
+	})
+	tool, _ := r.Get("bash")
+	if _, err := tool.Call(ctx, json.RawMessage(`{"command": "env"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "BRUTUS_TEST_A=x" {
+		t.Errorf("shell got variables %q, want BRUTUS_TEST_A=x", got)
+	}
+}
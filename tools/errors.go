@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrorCode categorizes why a tool failed. Models recover much better from
+// a known category ("not_found": check the path) than from a raw Go error
+// string, and tests can assert on the code instead of the wording.
+type ErrorCode string
+
+const (
+	ErrNotFound         ErrorCode = "not_found"
+	ErrPermissionDenied ErrorCode = "permission_denied"
+	ErrTimeout          ErrorCode = "timeout"
+	ErrInvalidInput     ErrorCode = "invalid_input"
+	ErrTooLarge         ErrorCode = "too_large"
+	ErrCancelled        ErrorCode = "cancelled"
+
+	// ErrInternal covers failures that fit no other category.
+	ErrInternal ErrorCode = "internal"
+)
+
+// ToolError is the error type returned by tools. It is sent back to the
+// model as a small JSON envelope (see ErrorResult).
+type ToolError struct {
+	Code    ErrorCode      `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+
+	err error
+}
+
+// NewError creates a ToolError with a formatted message.
+func NewError(code ErrorCode, format string, args ...any) *ToolError {
+	return &ToolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WrapError creates a ToolError for err, picking the code from the error
+// itself (see CodeOf). The message is prefixed to err's text.
+func WrapError(err error, format string, args ...any) *ToolError {
+	return &ToolError{
+		Code:    CodeOf(err),
+		Message: fmt.Sprintf(format, args...) + ": " + err.Error(),
+		err:     err,
+	}
+}
+
+// WithDetail attaches a key/value pair to the error and returns it.
+func (e *ToolError) WithDetail(key string, value any) *ToolError {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.err
+}
+
+// interrupted reports that what was stopped because ctx ended: a timeout
+// if its deadline passed, otherwise a cancellation.
+func interrupted(ctx context.Context, what string) *ToolError {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return NewError(ErrTimeout, "%s timed out", what)
+	}
+	return NewError(ErrCancelled, "%s was cancelled", what)
+}
+
+// CodeOf returns the code of the first ToolError in err's chain, or infers
+// one from well-known standard library errors.
+func CodeOf(err error) ErrorCode {
+	var toolErr *ToolError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &toolErr):
+		return toolErr.Code
+	case errors.Is(err, os.ErrNotExist):
+		return ErrNotFound
+	case errors.Is(err, os.ErrPermission):
+		return ErrPermissionDenied
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrTimeout
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ErrInvalidInput
+	}
+	return ErrInternal
+}
+
+// ErrorResult renders err as the tool result the model sees:
+//
+//	{"error":{"code":"not_found","message":"...","details":{...}}}
+//
+// Errors that are not ToolErrors are categorized with CodeOf.
+func ErrorResult(err error) string {
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		toolErr = &ToolError{Code: CodeOf(err), Message: err.Error()}
+	}
+	data, mErr := json.Marshal(struct {
+		Error *ToolError `json:"error"`
+	}{toolErr})
+	if mErr != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// ParseErrorResult decodes a tool result produced by ErrorResult.
+func ParseErrorResult(result string) (*ToolError, bool) {
+	var envelope struct {
+		Error *ToolError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result), &envelope); err != nil || envelope.Error == nil || envelope.Error.Code == "" {
+		return nil, false
+	}
+	return envelope.Error, true
+}
+
+// decodeInput unmarshals a tool's JSON input, reporting failures as
+// invalid_input.
+func decodeInput(input json.RawMessage, v any) error {
+	if err := json.Unmarshal(input, v); err != nil {
+		return NewError(ErrInvalidInput, "invalid input: %v", err)
+	}
+	return nil
+}
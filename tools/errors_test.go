@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodeOf(t *testing.T) {
+	_, statErr := os.Stat(filepath.Join(t.TempDir(), "missing"))
+	var syntaxErr error = json.Unmarshal([]byte("{"), &struct{}{})
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"tool error", NewError(ErrTooLarge, "big"), ErrTooLarge},
+		{"wrapped tool error", fmt.Errorf("outer: %w", NewError(ErrTimeout, "slow")), ErrTimeout},
+		{"missing file", statErr, ErrNotFound},
+		{"permission", os.ErrPermission, ErrPermissionDenied},
+		{"bad json", syntaxErr, ErrInvalidInput},
+		{"other", fmt.Errorf("boom"), ErrInternal},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.want {
+			t.Errorf("%s: CodeOf() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestErrorResultRoundTrip(t *testing.T) {
+	result := ErrorResult(NewError(ErrInvalidInput, "old_str found %d times", 2).WithDetail("matches", 2))
+
+	toolErr, ok := ParseErrorResult(result)
+	if !ok {
+		t.Fatalf("ParseErrorResult(%s) failed", result)
+	}
+	if toolErr.Code != ErrInvalidInput || toolErr.Message != "old_str found 2 times" {
+		t.Errorf("got %+v", toolErr)
+	}
+	if toolErr.Details["matches"] != float64(2) {
+		t.Errorf("details = %v", toolErr.Details)
+	}
+
+	if _, ok := ParseErrorResult("plain output"); ok {
+		t.Error("ParseErrorResult accepted a non-error result")
+	}
+}
+
+func TestToolsReturnCodes(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f.txt")
+	os.WriteFile(file, []byte("a a"), 0644)
+
+	tests := []struct {
+		name  string
+		fn    ToolFunc
+		input string
+		want  ErrorCode
+	}{
+		{"read missing", ReadFile, fmt.Sprintf(`{"path": %q}`, filepath.Join(dir, "nope")), ErrNotFound},
+		{"read bad json", ReadFile, `{"path": 1}`, ErrInvalidInput},
+		{"read directory", ReadFile, fmt.Sprintf(`{"path": %q}`, dir), ErrInvalidInput},
+		{"edit no match", EditFile, fmt.Sprintf(`{"path": %q, "old_str": "b", "new_str": "c"}`, file), ErrNotFound},
+		{"edit ambiguous", EditFile, fmt.Sprintf(`{"path": %q, "old_str": "a", "new_str": "c"}`, file), ErrInvalidInput},
+		{"search no pattern", CodeSearchTool.Function, `{}`, ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		_, err := tt.fn(json.RawMessage(tt.input))
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if got := CodeOf(err); got != tt.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tt.name, got, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Forge is how the pull request and issue tools reach a code host.
+type Forge struct {
+	Type   string // "github" or "gitlab"; guessed from the host if empty
+	Token  string
+	APIURL string // API root, for self-hosted instances; derived from the host if empty
+}
+
+var (
+	forgesMu sync.RWMutex
+	forges   map[string]Forge // by host
+)
+
+// ConfigureForges sets the code hosts the pull request and issue tools may
+// use, keyed by host name ("github.com", "gitlab.example.com").
+func ConfigureForges(byHost map[string]Forge) {
+	forgesMu.Lock()
+	defer forgesMu.Unlock()
+	forges = byHost
+}
+
+// forgeTokenEnv are the environment variables read when the config has no
+// token for the public hosts, matching what gh and glab use.
+var forgeTokenEnv = map[string][]string{
+	"github.com": {"GITHUB_TOKEN", "GH_TOKEN"},
+	"gitlab.com": {"GITLAB_TOKEN"},
+}
+
+// forgeTimeout bounds each API request and git command.
+const forgeTimeout = 30 * time.Second
+
+// forgeRepo is a repository on a code host. Path is "owner/name", or
+// "group/subgroup/name" on GitLab.
+type forgeRepo struct {
+	Host string
+	Path string
+}
+
+// resolveRepo picks the repository a tool call is about: repo if given,
+// as "owner/name" or "host/owner/name", otherwise the origin remote of the
+// current directory.
+func resolveRepo(repo string) (forgeRepo, error) {
+	repo = strings.Trim(strings.TrimSpace(repo), "/")
+	if repo != "" {
+		parts := strings.Split(repo, "/")
+		if len(parts) < 2 {
+			return forgeRepo{}, NewError(ErrInvalidInput, "repo must be owner/name or host/owner/name, got %q", repo)
+		}
+		if len(parts) > 2 && strings.Contains(parts[0], ".") {
+			return forgeRepo{Host: parts[0], Path: strings.Join(parts[1:], "/")}, nil
+		}
+		host := "github.com"
+		if origin, err := originRepo(); err == nil {
+			host = origin.Host
+		}
+		return forgeRepo{Host: host, Path: repo}, nil
+	}
+	return originRepo()
+}
+
+func originRepo() (forgeRepo, error) {
+	out, err := git("remote", "get-url", "origin")
+	if err != nil {
+		return forgeRepo{}, NewError(ErrNotFound, "no repo given and no origin remote here: %v", err)
+	}
+	r, ok := parseRemote(out)
+	if !ok {
+		return forgeRepo{}, NewError(ErrInvalidInput, "cannot tell the host and repository from origin %q; pass repo", out)
+	}
+	return r, nil
+}
+
+// parseRemote understands https://host/owner/name.git, ssh://git@host:22/owner/name
+// and the scp-like git@host:owner/name.git.
+func parseRemote(remote string) (forgeRepo, bool) {
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return forgeRepo{}, false
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		at := strings.LastIndex(remote, "@")
+		colon := strings.Index(remote, ":")
+		if colon < 0 || colon < at {
+			return forgeRepo{}, false
+		}
+		host, path = remote[at+1:colon], remote[colon+1:]
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return forgeRepo{}, false
+	}
+	return forgeRepo{Host: host, Path: path}, true
+}
+
+// forgeClient talks to the API of one code host.
+type forgeClient struct {
+	kind  string
+	token string
+	api   string
+	host  string
+}
+
+func newForgeClient(host string) (*forgeClient, error) {
+	forgesMu.RLock()
+	f := forges[host]
+	forgesMu.RUnlock()
+
+	if f.Token == "" {
+		for _, env := range forgeTokenEnv[host] {
+			if f.Token = os.Getenv(env); f.Token != "" {
+				break
+			}
+		}
+	}
+	if f.Token == "" {
+		return nil, NewError(ErrPermissionDenied, "no token for %s; set forges[%q].token in the config", host, host).WithDetail("host", host)
+	}
+
+	if f.Type == "" {
+		switch {
+		case strings.Contains(host, "github"):
+			f.Type = "github"
+		case strings.Contains(host, "gitlab"):
+			f.Type = "gitlab"
+		default:
+			return nil, NewError(ErrInvalidInput, "cannot tell whether %s is GitHub or GitLab; set forges[%q].type in the config", host, host)
+		}
+	}
+	if f.Type != "github" && f.Type != "gitlab" {
+		return nil, NewError(ErrInvalidInput, "unknown forge type %q for %s", f.Type, host)
+	}
+	if f.APIURL == "" {
+		switch {
+		case f.Type == "github" && host == "github.com":
+			f.APIURL = "https://api.github.com"
+		case f.Type == "github":
+			f.APIURL = "https://" + host + "/api/v3"
+		default:
+			f.APIURL = "https://" + host + "/api/v4"
+		}
+	}
+	return &forgeClient{kind: f.Type, token: f.Token, api: strings.TrimSuffix(f.APIURL, "/"), host: host}, nil
+}
+
+// repoPath returns the API path prefix for r.
+func (c *forgeClient) repoPath(r forgeRepo) string {
+	if c.kind == "gitlab" {
+		return "/projects/" + url.PathEscape(r.Path)
+	}
+	return "/repos/" + r.Path
+}
+
+// do sends a JSON request and decodes the JSON response into out. HTTP
+// failures are mapped to error codes the model can act on.
+func (c *forgeClient) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return WrapError(err, "failed to encode request")
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), forgeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.api+path, reader)
+	if err != nil {
+		return WrapError(err, "failed to build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.kind == "gitlab" {
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return WrapError(err, "%s request failed", c.host)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+
+	if resp.StatusCode >= 300 {
+		code := ErrInternal
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			code = ErrPermissionDenied
+		case http.StatusNotFound:
+			code = ErrNotFound
+		case http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity:
+			code = ErrInvalidInput
+		}
+		return NewError(code, "%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data))).
+			WithDetail("status", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return NewError(ErrInternal, "unexpected response from %s: %v", c.host, err)
+		}
+	}
+	return nil
+}
+
+// git runs a git command in the current directory and returns its trimmed
+// output.
+func git(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), forgeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,437 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+)
+
+const (
+	// gitTimeout bounds each git command the git tool runs.
+	gitTimeout = time.Minute
+
+	// maxGitPatch caps the patch text diff and show return, in characters.
+	maxGitPatch = 100 << 10
+
+	// defaultGitLog is how many commits log returns when no limit is given.
+	defaultGitLog = 20
+)
+
+// GitInput defines the parameters for the git tool.
+type GitInput struct {
+	Op           string   `json:"op" jsonschema:"enum=status,enum=diff,enum=log,enum=show,enum=add,enum=commit,enum=branch,enum=push" jsonschema_description:"The git operation to run."`
+	Paths        []string `json:"paths,omitempty" jsonschema_description:"Files to add (required for add), or to limit status, diff and log to."`
+	Staged       bool     `json:"staged,omitempty" jsonschema_description:"diff: show staged changes instead of unstaged ones."`
+	Ref          string   `json:"ref,omitempty" jsonschema_description:"diff: commit to compare the working tree with. log: where to start. show: the commit to show (default HEAD). branch: start point of a new branch."`
+	Limit        int      `json:"limit,omitempty" jsonschema_description:"log: how many commits to list (default 20)."`
+	Message      string   `json:"message,omitempty" jsonschema_description:"commit: the commit message."`
+	UserApproved bool     `json:"user_approved,omitempty" jsonschema_description:"commit: set only when the user has explicitly asked for or agreed to this commit."`
+	Name         string   `json:"name,omitempty" jsonschema_description:"branch: the branch to switch to, created if it doesn't exist. Omit to list branches. push: the branch to push (default the current one)."`
+	Remote       string   `json:"remote,omitempty" jsonschema_description:"push: the remote (default origin)."`
+	Force        bool     `json:"force,omitempty" jsonschema_description:"push: force-pushing is always refused."`
+}
+
+type gitFile struct {
+	Path      string `json:"path"`
+	From      string `json:"from,omitempty"` // The old path of a rename or copy
+	Staged    string `json:"staged,omitempty"`
+	Unstaged  string `json:"unstaged,omitempty"`
+	Untracked bool   `json:"untracked,omitempty"`
+}
+
+type gitStatus struct {
+	Branch   string    `json:"branch"`
+	Upstream string    `json:"upstream,omitempty"`
+	Ahead    int       `json:"ahead,omitempty"`
+	Behind   int       `json:"behind,omitempty"`
+	Clean    bool      `json:"clean"`
+	Files    []gitFile `json:"files,omitempty"`
+}
+
+type gitFileStat struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`   // -1 for binary files
+	Deleted int    `json:"deleted"` // -1 for binary files
+}
+
+type gitDiff struct {
+	Files     []gitFileStat `json:"files"`
+	Patch     string        `json:"patch"`
+	Truncated bool          `json:"truncated,omitempty"`
+}
+
+type gitCommit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+	Body    string `json:"body,omitempty"`
+}
+
+type gitBranches struct {
+	Current  string   `json:"current"`
+	Branches []string `json:"branches"`
+}
+
+// Git runs one git operation in the current directory and returns its
+// result as JSON. It refuses to force-push, and to commit unless the call
+// says the user approved the commit.
+func Git(ctx context.Context, input json.RawMessage) (string, error) {
+	var args GitInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if err := checkGitNames(args); err != nil {
+		return "", err
+	}
+
+	var result any
+	var err error
+	switch args.Op {
+	case "status":
+		result, err = gitStatusOf(ctx, args.Paths)
+	case "diff":
+		result, err = gitDiffOf(ctx, args)
+	case "log":
+		result, err = gitLog(ctx, args)
+	case "show":
+		result, err = gitShow(ctx, args.Ref)
+	case "add":
+		if len(args.Paths) == 0 {
+			return "", NewError(ErrInvalidInput, "add needs paths")
+		}
+		if _, err := runGit(ctx, append([]string{"add", "--"}, args.Paths...)...); err != nil {
+			return "", err
+		}
+		result, err = gitStatusOf(ctx, nil)
+	case "commit":
+		result, err = gitCommitStaged(ctx, args)
+	case "branch":
+		result, err = gitBranch(ctx, args)
+	case "push":
+		result, err = gitPush(ctx, args)
+	case "":
+		return "", NewError(ErrInvalidInput, "op is required")
+	default:
+		return "", NewError(ErrInvalidInput, "unknown op %q; use status, diff, log, show, add, commit, branch or push", args.Op)
+	}
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", WrapError(err, "failed to encode result")
+	}
+	return string(data), nil
+}
+
+// checkGitNames refuses refs, branch names and remotes that git would take
+// for options, such as a remote of "--force" or "--receive-pack=<cmd>", or
+// a ref of "--output=<file>". Paths always follow "--", so are safe.
+func checkGitNames(args GitInput) error {
+	for _, field := range []struct{ name, value string }{
+		{"ref", args.Ref},
+		{"name", args.Name},
+		{"remote", args.Remote},
+	} {
+		if strings.HasPrefix(field.value, "-") {
+			return NewError(ErrInvalidInput, "%s %q looks like an option; refs, branches and remotes can't start with \"-\"", field.name, field.value)
+		}
+	}
+	return nil
+}
+
+// runGit runs git and returns its standard output. Failures carry git's
+// error message. It gives up after gitTimeout, or sooner if ctx ends.
+func runGit(ctx context.Context, args ...string) (string, error) {
+	runCtx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, "git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", interrupted(ctx, "git "+args[0])
+		}
+		if runCtx.Err() != nil {
+			return "", NewError(ErrTimeout, "git %s timed out after %s", args[0], gitTimeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", NewError(ErrInternal, "git %s failed: %s", args[0], msg).WithDetail("command", "git "+strings.Join(args, " "))
+	}
+	return stdout.String(), nil
+}
+
+// statusNames spells out porcelain status letters.
+var statusNames = map[byte]string{
+	'M': "modified",
+	'T': "type changed",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+	'U': "unmerged",
+}
+
+func gitStatusOf(ctx context.Context, paths []string) (*gitStatus, error) {
+	out, err := runGit(ctx, append([]string{"status", "--porcelain=v1", "--branch", "-z", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	status := &gitStatus{}
+	entries := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if strings.HasPrefix(entry, "## ") {
+			parseBranchLine(status, entry[3:])
+			continue
+		}
+		if len(entry) < 4 {
+			continue
+		}
+		f := gitFile{Path: entry[3:]}
+		x, y := entry[0], entry[1]
+		if x == '?' {
+			f.Untracked = true
+		} else {
+			f.Staged, f.Unstaged = statusNames[x], statusNames[y]
+		}
+		if (x == 'R' || x == 'C') && i+1 < len(entries) {
+			i++
+			f.From = entries[i]
+		}
+		status.Files = append(status.Files, f)
+	}
+	status.Clean = len(status.Files) == 0
+	return status, nil
+}
+
+// parseBranchLine reads "main...origin/main [ahead 1, behind 2]".
+func parseBranchLine(status *gitStatus, line string) {
+	line, counts, _ := strings.Cut(line, " [")
+	status.Branch, status.Upstream, _ = strings.Cut(line, "...")
+	status.Branch = strings.TrimPrefix(status.Branch, "No commits yet on ")
+	for _, part := range strings.Split(strings.TrimSuffix(counts, "]"), ", ") {
+		key, value, _ := strings.Cut(part, " ")
+		n, _ := strconv.Atoi(value)
+		switch key {
+		case "ahead":
+			status.Ahead = n
+		case "behind":
+			status.Behind = n
+		}
+	}
+}
+
+func gitDiffOf(ctx context.Context, args GitInput) (*gitDiff, error) {
+	base := []string{"diff"}
+	if args.Staged {
+		base = append(base, "--cached")
+	}
+	if args.Ref != "" {
+		base = append(base, args.Ref)
+	}
+	base = append(base, "--")
+	base = append(base, args.Paths...)
+	return diffOutput(ctx, base)
+}
+
+// diffOutput runs a diff-like command twice: for the per-file counts and
+// for the patch, which is cut if it is very large.
+func diffOutput(ctx context.Context, command []string) (*gitDiff, error) {
+	stat, err := runGit(ctx, insertArg(command, "--numstat")...)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := runGit(ctx, command...)
+	if err != nil {
+		return nil, err
+	}
+	d := &gitDiff{Files: parseNumstat(stat), Patch: patch}
+	if cut := text.Head(d.Patch, maxGitPatch); cut != d.Patch {
+		d.Patch, d.Truncated = cut, true
+	}
+	return d, nil
+}
+
+// insertArg adds arg after the git subcommand.
+func insertArg(command []string, arg string) []string {
+	return append([]string{command[0], arg}, command[1:]...)
+}
+
+func parseNumstat(out string) []gitFileStat {
+	files := []gitFileStat{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, err := strconv.Atoi(fields[0])
+		if err != nil {
+			added = -1
+		}
+		deleted, err := strconv.Atoi(fields[1])
+		if err != nil {
+			deleted = -1
+		}
+		files = append(files, gitFileStat{Path: fields[2], Added: added, Deleted: deleted})
+	}
+	return files
+}
+
+// commitFormat separates a commit's fields with unit separators.
+const commitFormat = "%H%x1f%an <%ae>%x1f%aI%x1f%s%x1f%b%x1e"
+
+func parseCommits(out string) []gitCommit {
+	commits := []gitCommit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimLeft(record, "\n"), "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		commits = append(commits, gitCommit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    fields[2],
+			Subject: fields[3],
+			Body:    strings.TrimSpace(fields[4]),
+		})
+	}
+	return commits
+}
+
+func gitLog(ctx context.Context, args GitInput) ([]gitCommit, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultGitLog
+	}
+	command := []string{"log", "-n", strconv.Itoa(limit), "--format=" + commitFormat}
+	if args.Ref != "" {
+		command = append(command, args.Ref)
+	}
+	command = append(command, "--")
+	out, err := runGit(ctx, append(command, args.Paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommits(out), nil
+}
+
+func gitShow(ctx context.Context, ref string) (any, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	out, err := runGit(ctx, "show", "-s", "--format="+commitFormat, ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	commits := parseCommits(out)
+	if len(commits) == 0 {
+		return nil, NewError(ErrNotFound, "%s is not a commit", ref)
+	}
+	diff, err := diffOutput(ctx, []string{"show", "--format=", ref, "--"})
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		gitCommit
+		*gitDiff
+	}{commits[0], diff}, nil
+}
+
+func gitCommitStaged(ctx context.Context, args GitInput) (*gitCommit, error) {
+	if !args.UserApproved {
+		return nil, NewError(ErrPermissionDenied, "commits need the user's approval; ask the user, then call again with user_approved set")
+	}
+	if strings.TrimSpace(args.Message) == "" {
+		return nil, NewError(ErrInvalidInput, "commit needs a message")
+	}
+	if _, err := runGit(ctx, "commit", "-m", args.Message); err != nil {
+		return nil, err
+	}
+	out, err := runGit(ctx, "show", "-s", "--format="+commitFormat, "HEAD", "--")
+	if err != nil {
+		return nil, err
+	}
+	commits := parseCommits(out)
+	if len(commits) == 0 {
+		return nil, NewError(ErrInternal, "committed, but cannot read the new commit back")
+	}
+	return &commits[0], nil
+}
+
+func gitBranch(ctx context.Context, args GitInput) (*gitBranches, error) {
+	if args.Name != "" {
+		command := []string{"switch", args.Name}
+		if _, err := runGit(ctx, "rev-parse", "--verify", "--quiet", "refs/heads/"+args.Name); err != nil {
+			command = []string{"switch", "-c", args.Name}
+			if args.Ref != "" {
+				command = append(command, args.Ref)
+			}
+		}
+		if _, err := runGit(ctx, command...); err != nil {
+			return nil, err
+		}
+	}
+	out, err := runGit(ctx, "branch", "--format=%(HEAD)%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+	branches := &gitBranches{Branches: []string{}}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		name := line[1:]
+		if line[0] == '*' {
+			branches.Current = name
+		}
+		branches.Branches = append(branches.Branches, name)
+	}
+	return branches, nil
+}
+
+func gitPush(ctx context.Context, args GitInput) (map[string]string, error) {
+	if args.Force || strings.HasPrefix(args.Name, "+") {
+		return nil, NewError(ErrPermissionDenied, "force-pushing is not allowed; the user can do it themselves if they mean to")
+	}
+	// A refspec with nothing before the colon deletes the remote branch.
+	if strings.HasPrefix(args.Name, ":") {
+		return nil, NewError(ErrPermissionDenied, "deleting remote branches is not allowed; the user can do it themselves if they mean to")
+	}
+	remote := args.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	branch := args.Name
+	if branch == "" {
+		out, err := runGit(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		branch = strings.TrimSpace(out)
+	}
+	if _, err := runGit(ctx, "push", "-u", remote, branch); err != nil {
+		return nil, err
+	}
+	return map[string]string{"remote": remote, "branch": branch}, nil
+}
+
+// GitTool is the tool definition for structured git operations.
+var GitTool = NewToolCtx[GitInput](
+	"git",
+	fmt.Sprintf(`Run a git operation in the current repository and get the result as JSON.
+Operations: status (branch, upstream, ahead/behind and changed files), diff (per-file line counts and the patch; staged, or against ref), log (the last commits, %d by default), show (a commit and its patch), add (stage paths), commit (commit what is staged), branch (list branches, or switch to name, creating it if needed), push.
+Committing requires user_approved, which you may only set when the user has asked for or agreed to the commit. Force-pushing is always refused.`, defaultGitLog),
+	Git,
+)
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func runGitTool(t *testing.T, args GitInput) (string, error) {
+	t.Helper()
+	input, _ := json.Marshal(args)
+	return Git(context.Background(), input)
+}
+
+func TestGitTool(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	if out, err := exec.Command("git", "init", "-q", "-b", "main").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	os.WriteFile("a.txt", []byte("one\n"), 0644)
+
+	out, err := runGitTool(t, GitInput{Op: "status"})
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	var status gitStatus
+	json.Unmarshal([]byte(out), &status)
+	if status.Branch != "main" || len(status.Files) != 1 || !status.Files[0].Untracked {
+		t.Errorf("status = %s", out)
+	}
+
+	if _, err := runGitTool(t, GitInput{Op: "add", Paths: []string{"a.txt"}}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	_, err = runGitTool(t, GitInput{Op: "commit", Message: "Add a"})
+	if code := CodeOf(err); code != ErrPermissionDenied {
+		t.Fatalf("commit without approval: code %q, err %v", code, err)
+	}
+	out, err = runGitTool(t, GitInput{Op: "commit", Message: "Add a", UserApproved: true})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	var commit gitCommit
+	json.Unmarshal([]byte(out), &commit)
+	if commit.Subject != "Add a" || len(commit.Hash) != 40 {
+		t.Errorf("commit = %s", out)
+	}
+
+	os.WriteFile("a.txt", []byte("one\ntwo\n"), 0644)
+	out, err = runGitTool(t, GitInput{Op: "diff"})
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	var diff gitDiff
+	json.Unmarshal([]byte(out), &diff)
+	if len(diff.Files) != 1 || diff.Files[0].Added != 1 || !strings.Contains(diff.Patch, "+two") {
+		t.Errorf("diff = %s", out)
+	}
+
+	out, err = runGitTool(t, GitInput{Op: "log"})
+	if err != nil {
+		t.Fatalf("log: %v", err)
+	}
+	var log []gitCommit
+	json.Unmarshal([]byte(out), &log)
+	if len(log) != 1 || log[0].Hash != commit.Hash {
+		t.Errorf("log = %s", out)
+	}
+
+	out, err = runGitTool(t, GitInput{Op: "branch", Name: "feature"})
+	if err != nil {
+		t.Fatalf("branch: %v", err)
+	}
+	var branches gitBranches
+	json.Unmarshal([]byte(out), &branches)
+	if branches.Current != "feature" || len(branches.Branches) != 2 {
+		t.Errorf("branch = %s", out)
+	}
+
+	_, err = runGitTool(t, GitInput{Op: "push", Force: true})
+	if code := CodeOf(err); code != ErrPermissionDenied {
+		t.Errorf("force push: code %q, err %v", code, err)
+	}
+}
+
+func TestGitToolRejectsOptions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, args := range []GitInput{
+		{Op: "diff", Ref: "--output=/tmp/x"},
+		{Op: "log", Ref: "--output=/tmp/x"},
+		{Op: "show", Ref: "--output=/tmp/x"},
+		{Op: "branch", Name: "-D"},
+		{Op: "branch", Name: "feature", Ref: "--orphan"},
+		{Op: "push", Remote: "--force", Name: "main"},
+		{Op: "push", Remote: "--receive-pack=touch pwned"},
+		{Op: "push", Name: "--delete"},
+	} {
+		_, err := runGitTool(t, args)
+		if code := CodeOf(err); code != ErrInvalidInput {
+			t.Errorf("%+v: code %q, err %v", args, code, err)
+		}
+	}
+	if _, err := os.Stat("pwned"); err == nil {
+		t.Error("--receive-pack ran its command")
+	}
+}
+
+func TestGitPushRefusesDeletes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, name := range []string{":main", ":refs/heads/main", ":"} {
+		_, err := runGitTool(t, GitInput{Op: "push", Name: name})
+		if code := CodeOf(err); code != ErrPermissionDenied {
+			t.Errorf("push %q: code %q, err %v", name, code, err)
+		}
+	}
+}
+
+func TestGitDiffCutsPatchByRune(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	for _, args := range [][]string{{"init", "-q"}, {"commit", "-q", "--allow-empty", "-m", "init"}} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	// One of the three prefixes puts any fixed byte offset inside a
+	// three-byte character.
+	for _, prefix := range []string{"", "a", "ab"} {
+		os.WriteFile("wide.txt", []byte(prefix+strings.Repeat("€", maxGitPatch)+"\n"), 0644)
+		exec.Command("git", "add", "wide.txt").Run()
+
+		out, err := runGitTool(t, GitInput{Op: "diff", Staged: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var diff gitDiff
+		json.Unmarshal([]byte(out), &diff)
+		// JSON encoding turns a split character into U+FFFD.
+		if !diff.Truncated || strings.ContainsRune(diff.Patch, utf8.RuneError) {
+			t.Errorf("prefix %q: truncated %v, patch ends %q; want a clean cut", prefix, diff.Truncated, diff.Patch[len(diff.Patch)-12:])
+		}
+	}
+}
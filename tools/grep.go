@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// binaryProbe is how much of a file is checked for NUL bytes to decide it
+// is binary, as git and ripgrep do.
+const binaryProbe = 8000
+
+// maxSearchFile is the largest file searchFiles reads; larger ones are
+// data, not code.
+const maxSearchFile = 10 << 20
+
+// searchFiles is code_search without ripgrep: it walks searchPath as
+// ripgrep would, skipping ignored, hidden and binary files, and matches
+// the pattern in several files at once. Matches come out as ripgrep
+// prints them, path:line:text, ordered by path.
+func searchFiles(ctx context.Context, args CodeSearchInput, searchPath string, ignore *ignoreSet) (string, error) {
+	pattern := args.Pattern
+	if !args.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", NewError(ErrInvalidInput, "search failed: bad pattern: %v", err)
+	}
+
+	paths := make(chan string)
+	var (
+		mu      sync.Mutex
+		matches = map[string][]string{}
+		wg      sync.WaitGroup
+	)
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if lines := grepFile(path, re); len(lines) > 0 {
+					mu.Lock()
+					matches[path] = lines
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	walkErr := filepath.WalkDir(searchPath, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if path == searchPath {
+				return err
+			}
+			return nil // Unreadable entries are skipped, as rg does.
+		}
+		if path != searchPath {
+			hidden := strings.HasPrefix(d.Name(), ".") && !ignore.all
+			if hidden || ignore.ignored(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.Type().IsRegular() && (args.FileType == "" || strings.TrimPrefix(filepath.Ext(path), ".") == args.FileType) {
+			paths <- path
+		}
+		return nil
+	})
+	close(paths)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return "", interrupted(ctx, "the search")
+	}
+	if walkErr != nil {
+		return "", NewError(ErrInvalidInput, "search failed: %v", walkErr).WithDetail("path", searchPath)
+	}
+
+	if len(matches) == 0 {
+		return "No matches found", nil
+	}
+	files := make([]string, 0, len(matches))
+	for path := range matches {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	var out []string
+	for _, path := range files {
+		out = append(out, matches[path]...)
+	}
+	return limitResults(strings.Join(out, "\n"), 50), nil
+}
+
+// grepFile returns the lines of path that match re, as path:line:text. It
+// returns nothing for binary, oversized and unreadable files.
+func grepFile(path string, re *regexp.Regexp) []string {
+	if info, err := os.Stat(path); err != nil || info.Size() > maxSearchFile {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	if bytes.IndexByte(data[:min(len(data), binaryProbe)], 0) >= 0 {
+		return nil
+	}
+	if !re.Match(data) {
+		return nil
+	}
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, maxSearchFile)
+	for n := 1; scanner.Scan(); n++ {
+		if line := scanner.Bytes(); re.Match(line) {
+			lines = append(lines, fmt.Sprintf("%s:%d:%s", path, n, strings.TrimSuffix(string(line), "\r")))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// defaultHistoryResults is how many matches search_history returns unless
+// asked for more.
+const defaultHistoryResults = 10
+
+// HistorySearchFunc searches past sessions and returns the matches as text.
+type HistorySearchFunc func(query string, limit int) (string, error)
+
+// SearchHistoryInput defines the parameters for search_history.
+type SearchHistoryInput struct {
+	Query string `json:"query" jsonschema_description:"Words to look for; a message matches when it contains all of them, in any case."`
+	Limit int    `json:"limit,omitempty" jsonschema_description:"Maximum number of matches to return. Defaults to 10."`
+}
+
+// NewSearchHistoryTool returns search_history, which looks through earlier
+// sessions with search, so the agent can recall how a problem was solved
+// before instead of working it out again.
+func NewSearchHistoryTool(search HistorySearchFunc) Tool {
+	return NewTool[SearchHistoryInput](
+		"search_history",
+		"Search the transcripts and summaries of past sessions. Use this when a problem looks familiar (a flaky test, a build error, a design decision) to find how it was handled before. Returns snippets with their session IDs.",
+		func(input json.RawMessage) (string, error) {
+			var args SearchHistoryInput
+			if err := decodeInput(input, &args); err != nil {
+				return "", err
+			}
+			query := strings.TrimSpace(args.Query)
+			if query == "" {
+				return "", NewError(ErrInvalidInput, "query is required")
+			}
+			limit := args.Limit
+			if limit <= 0 {
+				limit = defaultHistoryResults
+			}
+			result, err := search(query, limit)
+			if err != nil {
+				return "", WrapError(err, "failed to search past sessions")
+			}
+			return result, nil
+		},
+	)
+}
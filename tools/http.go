@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+)
+
+const (
+	// defaultHTTPTimeout and maxHTTPTimeout bound http_request's timeout
+	// parameter, in seconds.
+	defaultHTTPTimeout = 30
+	maxHTTPTimeout     = 300
+
+	// maxHTTPResponse caps the response body http_request returns; the
+	// rest is cut and the result says how large the body was.
+	maxHTTPResponse = 50000
+
+	// maxHTTPRead caps how much of a response body is read at all.
+	maxHTTPRead = 4 << 20
+)
+
+// HTTPRequestInput defines the parameters for the http_request tool.
+type HTTPRequestInput struct {
+	Method  string            `json:"method,omitempty" jsonschema_description:"The HTTP method (default GET)."`
+	URL     string            `json:"url" jsonschema_description:"The full http or https URL, e.g. http://localhost:8080/api/users?limit=5."`
+	Headers map[string]string `json:"headers,omitempty" jsonschema_description:"Request headers. Authorization and other credential headers are sent but never logged."`
+	Body    string            `json:"body,omitempty" jsonschema_description:"The request body. Content-Type defaults to application/json when the body looks like JSON."`
+	Timeout int               `json:"timeout,omitempty" jsonschema_description:"Seconds to wait for the response (default 30, at most 300)."`
+}
+
+// sensitiveHeaders are headers whose values RedactInput hides.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+	"api-key":             true,
+	"private-token":       true,
+	"x-auth-token":        true,
+}
+
+// RedactInput returns a tool call's input for logs and prompts, with the
+// values of credential headers such as Authorization in a "headers"
+// object replaced. Inputs without one are returned as they are.
+func RedactInput(input json.RawMessage) string {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(input, &fields) != nil || fields["headers"] == nil {
+		return string(input)
+	}
+	var headers map[string]any
+	if json.Unmarshal(fields["headers"], &headers) != nil {
+		return string(input)
+	}
+	redacted := false
+	for name := range headers {
+		if sensitiveHeaders[strings.ToLower(name)] {
+			headers[name] = "[redacted]"
+			redacted = true
+		}
+	}
+	if !redacted {
+		return string(input)
+	}
+	fields["headers"], _ = json.Marshal(headers)
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return string(input)
+	}
+	return string(data)
+}
+
+// HTTPRequest sends one HTTP request and returns the status line, the
+// response headers and the body, cut to maxHTTPResponse. Error statuses
+// are results, not tool errors: they are often what is being tested.
+func HTTPRequest(ctx context.Context, input json.RawMessage) (string, error) {
+	var args HTTPRequestInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	u, err := url.Parse(args.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", NewError(ErrInvalidInput, "url must be an absolute http or https URL").WithDetail("url", args.URL)
+	}
+	method := strings.ToUpper(args.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	timeout := defaultHTTPTimeout
+	if args.Timeout > 0 {
+		timeout = min(args.Timeout, maxHTTPTimeout)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	var body io.Reader
+	if args.Body != "" {
+		body = strings.NewReader(args.Body)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, u.String(), body)
+	if err != nil {
+		return "", WrapError(err, "failed to build request")
+	}
+	for name, value := range args.Headers {
+		req.Header.Set(name, value)
+	}
+	if args.Body != "" && req.Header.Get("Content-Type") == "" && json.Valid([]byte(args.Body)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", interrupted(ctx, method+" "+u.Redacted())
+		}
+		if reqCtx.Err() != nil {
+			return "", NewError(ErrTimeout, "%s %s got no response within %ds", method, u.Redacted(), timeout)
+		}
+		return "", WrapError(err, "%s %s failed", method, u.Redacted())
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPRead))
+	if err != nil {
+		return "", WrapError(err, "failed to read the response body")
+	}
+	elapsed := time.Since(start)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s (%s)\n", resp.Proto, resp.Status, elapsed.Round(time.Millisecond))
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+	if len(data) > maxHTTPResponse {
+		b.WriteString(text.Head(string(data), maxHTTPResponse))
+		size := fmt.Sprintf("%d bytes", len(data))
+		if len(data) == maxHTTPRead {
+			size = fmt.Sprintf("over %d bytes", maxHTTPRead)
+		}
+		fmt.Fprintf(&b, "\n[Body cut: it is %s; %d shown.]", size, maxHTTPResponse)
+	} else {
+		b.Write(data)
+	}
+	return b.String(), nil
+}
+
+// HTTPRequestTool is the tool definition for calling HTTP APIs.
+var HTTPRequestTool = NewToolCtx[HTTPRequestInput](
+	"http_request",
+	`Send an HTTP request and get the status, response headers and body, e.g. to try out an API you are writing or to call a REST service. Prefer this to curl through bash.
+Error statuses such as 404 or 500 are returned as results. Bodies over 50000 characters are cut. Credential headers like Authorization are sent but kept out of logs.`,
+	HTTPRequest,
+)
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(strings.Repeat(string(body), maxHTTPResponse)))
+	}))
+	defer server.Close()
+
+	input, _ := json.Marshal(HTTPRequestInput{
+		Method:  "post",
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Body:    `{}`,
+	})
+	out, err := HTTPRequest(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"201 Created", "X-Method: POST", "X-Type: application/json", "[Body cut: it is 100000 bytes; 50000 shown.]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("result lacks %q:\n%s", want, out[:200])
+		}
+	}
+
+	input, _ = json.Marshal(HTTPRequestInput{URL: server.URL})
+	if out, err := HTTPRequest(context.Background(), input); err != nil || !strings.Contains(out, "401 Unauthorized") {
+		t.Errorf("error status: %v, %q", err, out)
+	}
+
+	input, _ = json.Marshal(HTTPRequestInput{URL: "ftp://example.com"})
+	if _, err := HTTPRequest(context.Background(), input); CodeOf(err) != ErrInvalidInput {
+		t.Errorf("ftp URL: %v", err)
+	}
+}
+
+func TestRedactInput(t *testing.T) {
+	got := RedactInput(json.RawMessage(`{"url":"http://x","headers":{"authorization":"Bearer secret","Accept":"text/plain"}}`))
+	if strings.Contains(got, "secret") || !strings.Contains(got, "[redacted]") || !strings.Contains(got, "text/plain") {
+		t.Errorf("RedactInput = %s", got)
+	}
+	plain := `{"path": "a.go"}`
+	if got := RedactInput(json.RawMessage(plain)); got != plain {
+		t.Errorf("RedactInput changed %s to %s", plain, got)
+	}
+}
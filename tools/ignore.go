@@ -0,0 +1,264 @@
+package tools
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFiles are read in every directory list_files and code_search walk,
+// and in the directories above it up to the repository root. Rules in
+// .brutusignore hide files from the agent that git still tracks.
+var ignoreFiles = []string{".gitignore", ".brutusignore"}
+
+// ignoreRule is one line of an ignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes what an earlier rule ignored
+	dirOnly bool // "pattern/" only matches directories
+	base    bool // No slash in the pattern: it matches a name at any depth
+}
+
+// ignoreSet answers whether paths below a directory are ignored, following
+// gitignore rules: deeper files override shallower ones, later lines
+// override earlier ones, and a path in an ignored directory is ignored
+// with it. The directories in skipDirs are ignored too, and .git always is.
+type ignoreSet struct {
+	top   string                  // Highest directory whose ignore files apply
+	depth int                     // Directories between top and the walk's root
+	rules map[string][]ignoreRule // By directory, read on first use
+	dirs  map[string]bool         // Whether each directory checked is ignored, by path below top
+	all   bool                    // include_ignored: only .git is skipped
+}
+
+// newIgnoreSet returns the ignore rules for walking root, which may be a
+// file. Ignore files above root count up to the enclosing git repository's
+// root; outside a repository only root and below do.
+func newIgnoreSet(root string, includeIgnored bool) *ignoreSet {
+	s := &ignoreSet{rules: map[string][]ignoreRule{}, dirs: map[string]bool{}, all: includeIgnored}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+		abs = filepath.Dir(abs)
+	}
+	s.top = abs
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			s.top = dir
+			break
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	if rel, err := filepath.Rel(s.top, abs); err == nil && rel != "." {
+		s.depth = strings.Count(filepath.ToSlash(rel), "/") + 1
+	}
+	return s
+}
+
+// WalkProject calls fn for each regular file below root that list_files
+// and code_search would show: hidden files and directories, and those the
+// project's ignore files or skipDirs exclude, are skipped. Unreadable
+// entries are skipped too. fn may return filepath.SkipAll to stop.
+func WalkProject(root string, fn func(path string, d fs.DirEntry) error) error {
+	ignore := newIgnoreSet(root, false)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if path != root && (strings.HasPrefix(d.Name(), ".") || ignore.ignored(path, d.IsDir())) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
+
+// ignored reports whether path, relative to the working directory or
+// absolute, is ignored.
+func (s *ignoreSet) ignored(path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(s.top, abs)
+	if err != nil || !within(abs, s.top) || rel == "." {
+		return filepath.Base(abs) == ".git"
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	// Git cannot re-include a file whose directory is excluded, so an
+	// ignored parent settles it. The walk's root and the directories above
+	// it were asked for by name, so they count as not ignored.
+	for k := s.depth + 1; k < len(parts); k++ {
+		if s.dirIgnored(parts[:k]) {
+			return true
+		}
+	}
+	return s.match(parts, isDir)
+}
+
+// dirIgnored reports whether the directory at parts is ignored by itself,
+// remembering the answer for the rest of the walk.
+func (s *ignoreSet) dirIgnored(parts []string) bool {
+	key := strings.Join(parts, "/")
+	if ignored, ok := s.dirs[key]; ok {
+		return ignored
+	}
+	ignored := s.match(parts, true)
+	s.dirs[key] = ignored
+	return ignored
+}
+
+// match applies the rules of top and each directory below it on the way
+// to parts, each to the path relative to its own directory. The last rule
+// to match decides.
+func (s *ignoreSet) match(parts []string, isDir bool) bool {
+	name := parts[len(parts)-1]
+	if isDir && name == ".git" {
+		return true
+	}
+	if s.all {
+		return false
+	}
+	if isDir && skipDirs[name] {
+		return true
+	}
+	ignored := false
+	dir := s.top
+	for i := range parts {
+		rel := strings.Join(parts[i:], "/")
+		for _, rule := range s.load(dir) {
+			if rule.matches(rel, name, isDir) {
+				ignored = !rule.negate
+			}
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return ignored
+}
+
+// load returns dir's rules, reading its ignore files the first time.
+func (s *ignoreSet) load(dir string) []ignoreRule {
+	if rules, ok := s.rules[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	for _, name := range ignoreFiles {
+		rules = append(rules, readIgnoreFile(filepath.Join(dir, name))...)
+	}
+	s.rules[dir] = rules
+	return rules
+}
+
+// readIgnoreFile parses an ignore file; a missing file has no rules.
+func readIgnoreFile(path string) []ignoreRule {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnoreLine turns one gitignore line into a rule. Blank lines and
+// comments give none.
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // \# and \! escape a leading # or !
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	rule.base = !strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	re, err := regexp.Compile("^" + globRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globRegexp translates a gitignore glob to a regular expression: * and ?
+// stay within one path segment, and ** spans any number of them.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// matches reports whether the rule matches a path, given relative to the
+// rule's directory, with the given base name.
+func (r ignoreRule) matches(rel, name string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base {
+		return r.re.MatchString(name)
+	}
+	return r.re.MatchString(rel)
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// maxImageSize is the largest image read_image sends. Base64 makes it a
+// third larger, which keeps it under the 5 MB the Anthropic API takes.
+const maxImageSize = 3_750_000
+
+// imageMarker starts a line of a tool result that carries an image, as a
+// data URL; see ImageResult.
+const imageMarker = "\x00image:"
+
+// Image is a picture for the model to look at, base64 encoded.
+type Image struct {
+	MediaType string `json:"media_type"` // image/png, image/jpeg, image/gif or image/webp
+	Data      string `json:"data"`
+}
+
+// imageTypes are the formats both the OpenAI and Anthropic APIs accept.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// LoadImage reads the image file at path, within the sandbox.
+func LoadImage(path string) (Image, error) {
+	if err := checkPath(path); err != nil {
+		return Image{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Image{}, WrapError(err, "failed to read image").WithDetail("path", path)
+	}
+	if info.IsDir() {
+		return Image{}, NewError(ErrInvalidInput, "%s is a directory", path).WithDetail("path", path)
+	}
+	if info.Size() > maxImageSize {
+		return Image{}, NewError(ErrTooLarge, "%s is %d bytes; images over %d bytes can't be sent", path, info.Size(), maxImageSize).
+			WithDetail("path", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, WrapError(err, "failed to read image").WithDetail("path", path)
+	}
+	mediaType := http.DetectContentType(data)
+	if !imageTypes[mediaType] {
+		return Image{}, NewError(ErrInvalidInput, "%s is %s, not a PNG, JPEG, GIF or WebP image", path, mediaType).WithDetail("path", path)
+	}
+	return Image{MediaType: mediaType, Data: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+// ImageResult returns a tool result of text followed by images. Agents
+// take the images out with SplitImages and send them to the model as
+// images, where it can see them, rather than as text.
+func ImageResult(text string, images ...Image) string {
+	var b strings.Builder
+	b.WriteString(text)
+	for _, img := range images {
+		fmt.Fprintf(&b, "\n%sdata:%s;base64,%s", imageMarker, img.MediaType, img.Data)
+	}
+	return b.String()
+}
+
+// SplitImages takes the images ImageResult put in result out of it.
+func SplitImages(result string) (string, []Image) {
+	if !strings.Contains(result, imageMarker) {
+		return result, nil
+	}
+	var images []Image
+	var text []string
+	for _, line := range strings.Split(result, "\n") {
+		url, ok := strings.CutPrefix(line, imageMarker+"data:")
+		mediaType, data, isImage := strings.Cut(url, ";base64,")
+		if ok && isImage && imageTypes[mediaType] {
+			images = append(images, Image{MediaType: mediaType, Data: data})
+			continue
+		}
+		text = append(text, line)
+	}
+	return strings.Join(text, "\n"), images
+}
+
+// ReadImageInput defines the parameters for the read_image tool.
+type ReadImageInput struct {
+	Path string `json:"path" jsonschema_description:"The relative or absolute path to a PNG, JPEG, GIF or WebP image."`
+}
+
+// ReadImage loads an image file for the model to look at.
+func ReadImage(input json.RawMessage) (string, error) {
+	var args ReadImageInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if args.Path == "" {
+		return "", NewError(ErrInvalidInput, "path is required")
+	}
+	img, err := LoadImage(args.Path)
+	if err != nil {
+		return "", err
+	}
+	size := base64.StdEncoding.DecodedLen(len(img.Data))
+	return ImageResult(fmt.Sprintf("%s (%s, about %d KB) is attached.", args.Path, img.MediaType, size/1000), img), nil
+}
+
+// ReadImageTool is the tool definition for looking at images.
+var ReadImageTool = NewTool[ReadImageInput](
+	"read_image",
+	`Look at an image file: a screenshot, diagram, mockup or photo of an error, as PNG, JPEG, GIF or WebP up to 3.75 MB. The image is shown to you with the result. Use read_file for SVG and other text formats. Models that can't see images get only a note that it was left out.`,
+	ReadImage,
+)
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestReadImage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shot.png")
+	os.WriteFile(path, pngHeader, 0644)
+
+	input, _ := json.Marshal(ReadImageInput{Path: path})
+	result, err := ReadImage(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, images := SplitImages(result)
+	if !strings.Contains(text, "shot.png (image/png") || strings.Contains(text, "base64") {
+		t.Errorf("text = %q", text)
+	}
+	if len(images) != 1 || images[0].MediaType != "image/png" || images[0].Data != base64.StdEncoding.EncodeToString(pngHeader) {
+		t.Errorf("images = %+v", images)
+	}
+
+	notes := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notes, []byte("not an image"), 0644)
+	input, _ = json.Marshal(ReadImageInput{Path: notes})
+	if _, err := ReadImage(input); err == nil {
+		t.Error("expected a text file to be refused")
+	}
+}
+
+func TestSplitImagesPlainText(t *testing.T) {
+	for _, result := range []string{"plain output", "data:image/png;base64,AAAA"} {
+		if text, images := SplitImages(result); text != result || images != nil {
+			t.Errorf("%q split into %q and %v", result, text, images)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"brutus/internal/text"
+)
+
+// LearnedPath is where a project's learned commands are kept, relative to
+// the project root.
+var LearnedPath = filepath.Join(".brutus", "learned.json")
+
+// Kinds of command LearnedCommands remembers.
+const (
+	KindBuild  = "build"
+	KindTest   = "test"
+	KindLint   = "lint"
+	KindFormat = "format"
+)
+
+// maxLearnedPerKind caps how many commands of each kind are kept; the
+// least used are dropped first.
+const maxLearnedPerKind = 3
+
+// commandKinds recognizes build, test, lint and format commands by how
+// they start. Order matters: "make test" is a test, not a build.
+var commandKinds = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{KindTest, regexp.MustCompile(`^(go test|cargo test|(python3? -m )?pytest|(npm|yarn|pnpm|bun) (run )?test|make (test|check)|mvn test|(gradle|\./gradlew) test|jest|vitest|dotnet test|mix test|(bundle exec )?rspec|ctest)\b`)},
+	{KindFormat, regexp.MustCompile(`^(gofmt|go fmt|goimports|prettier|npx prettier|black|ruff format|cargo fmt|(npm|yarn|pnpm) (run )?(format|fmt)|make (fmt|format)|clang-format|rustfmt)\b`)},
+	{KindLint, regexp.MustCompile(`^(go vet|golangci-lint|staticcheck|eslint|npx eslint|(npm|yarn|pnpm) (run )?lint|ruff( check)?|flake8|pylint|mypy|cargo clippy|make lint|shellcheck)\b`)},
+	{KindBuild, regexp.MustCompile(`^(go build|cargo build|(npm|yarn|pnpm) (run )?build|make( (all|build))?$|mvn (package|compile|install)|(gradle|\./gradlew) build|tsc|dotnet build|cmake --build)\b`)},
+}
+
+// ClassifyCommand returns the kind of a shell command line, and the part
+// of it worth remembering: the trailing output filters and redirections
+// models add ("2>&1 | tail -20") are dropped. kind is empty for commands
+// of no known kind.
+func ClassifyCommand(command string) (kind, learned string) {
+	learned, _, _ = strings.Cut(strings.TrimSpace(command), " | ")
+	learned = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(learned), "2>&1"))
+	if learned == "" || strings.ContainsAny(learned, "\n;") {
+		return "", ""
+	}
+	// The command proper is the last step of "cd dir && ..." chains.
+	step := learned
+	if i := strings.LastIndex(step, "&&"); i >= 0 {
+		step = strings.TrimSpace(step[i+2:])
+	}
+	for _, k := range commandKinds {
+		if k.pattern.MatchString(step) {
+			return k.kind, learned
+		}
+	}
+	return "", ""
+}
+
+// LearnedCommand is a command line that has worked in the project.
+type LearnedCommand struct {
+	Kind      string    `json:"kind"`
+	Command   string    `json:"command"`
+	Successes int       `json:"successes"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// LearnedCommands remembers the build, test, lint and format commands
+// that have succeeded in a project, so the agent doesn't have to work out
+// how to run the tests again every session. It is safe for concurrent use.
+type LearnedCommands struct {
+	mu       sync.Mutex
+	path     string
+	commands []LearnedCommand
+}
+
+type learnedFile struct {
+	Commands []LearnedCommand `json:"commands"`
+}
+
+// LoadLearned reads the learned commands at path. A missing file is an
+// empty memory that Observe will create.
+func LoadLearned(path string) (*LearnedCommands, error) {
+	l := &LearnedCommands{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	var f learnedFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return l, fmt.Errorf("%s: %w", path, err)
+	}
+	l.commands = f.Commands
+	return l, nil
+}
+
+// Observe records that command succeeded, if it is a build, test, lint or
+// format command, and saves the memory.
+func (l *LearnedCommands) Observe(command string) error {
+	kind, command := ClassifyCommand(command)
+	if kind == "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	found := false
+	for i := range l.commands {
+		if l.commands[i].Command == command {
+			l.commands[i].Successes++
+			l.commands[i].LastUsed = now
+			found = true
+			break
+		}
+	}
+	if !found {
+		l.commands = append(l.commands, LearnedCommand{Kind: kind, Command: command, Successes: 1, LastUsed: now})
+	}
+	l.sortLocked()
+
+	// Keep the most used few of each kind.
+	kept := l.commands[:0]
+	perKind := map[string]int{}
+	for _, c := range l.commands {
+		if perKind[c.Kind] < maxLearnedPerKind {
+			perKind[c.Kind]++
+			kept = append(kept, c)
+		}
+	}
+	l.commands = kept
+	return l.saveLocked()
+}
+
+// sortLocked orders commands by kind, then most successes, then most
+// recently used.
+func (l *LearnedCommands) sortLocked() {
+	sort.SliceStable(l.commands, func(i, j int) bool {
+		a, b := l.commands[i], l.commands[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Successes != b.Successes {
+			return a.Successes > b.Successes
+		}
+		return a.LastUsed.After(b.LastUsed)
+	})
+}
+
+func (l *LearnedCommands) saveLocked() error {
+	data, err := json.MarshalIndent(learnedFile{Commands: l.commands}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// Best returns the command of the given kind that has worked most often,
+// or "" if none has.
+func (l *LearnedCommands) Best(kind string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range l.commands {
+		if c.Kind == kind {
+			return c.Command
+		}
+	}
+	return ""
+}
+
+// Prompt describes the learned commands for the system prompt, or returns
+// "" if there are none yet.
+func (l *LearnedCommands) Prompt() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.commands) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("# Commands that have worked in this project\n\n")
+	b.WriteString("These succeeded in earlier sessions; prefer them over working out how to build or test again.\n\n")
+	for _, c := range l.commands {
+		fmt.Fprintf(&b, "- %s: `%s`\n", c.Kind, c.Command)
+	}
+	return b.String()
+}
+
+// runShell runs command the way the bash tool does, with env as its
+// environment (nil inherits BRUTUS's), reporting whether it succeeded.
+// If ctx ends first the command is killed and the error says why.
+func runShell(ctx context.Context, command string, env []string) (string, bool, error) {
+	cmd := ShellCommand(ctx, command)
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return "", false, interrupted(ctx, "the command").WithDetail("output", text.HeadTail(string(output), 2000))
+	}
+	if err != nil {
+		// Return both the error and output - often useful for debugging
+		return fmt.Sprintf("Command failed: %s\nOutput: %s", err.Error(), string(output)), false, nil
+	}
+	return strings.TrimSpace(string(output)), true, nil
+}
+
+// NewBashTool returns the bash tool, remembering the build, test, lint and
+// format commands that succeed in learned.
+func NewBashTool(learned *LearnedCommands) Tool {
+	env := &commandEnv{}
+	t := BashTool
+	t.SetEnv = env.set
+	run := func(ctx context.Context, input json.RawMessage) (string, error) {
+		var args BashInput
+		if err := decodeInput(input, &args); err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(args.Command) == "" {
+			return "", NewError(ErrInvalidInput, "command is required")
+		}
+		if shell := shellFrom(ctx); shell != nil {
+			// Whether it succeeded isn't known, so nothing is learned.
+			return shell(ctx, args.Command, env.extra())
+		}
+		output, ok, err := runShell(ctx, args.Command, env.environ())
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			// Failing to save only costs the memory, not the command.
+			learned.Observe(args.Command)
+		}
+		return output, nil
+	}
+	t.FunctionCtx = run
+	t.Function = func(input json.RawMessage) (string, error) {
+		return run(context.Background(), input)
+	}
+	return t
+}
+
+// LearnedCommandInput defines parameters for format.
+type LearnedCommandInput struct {
+	Command string `json:"command,omitempty" jsonschema_description:"Command line to run. Defaults to the one that has worked in this project before."`
+}
+
+// NewFormatTool returns format, which formats the project's code with the
+// format command learned from earlier sessions unless given another.
+func NewFormatTool(learned *LearnedCommands) Tool {
+	return newLearnedCommandTool(learned, "format", KindFormat,
+		"Format the project's code. Without a command, runs the format command that has worked in this project before.")
+}
+
+func newLearnedCommandTool(learned *LearnedCommands, name, kind, description string) Tool {
+	env := &commandEnv{}
+	t := NewToolCtx[LearnedCommandInput](name, description, func(ctx context.Context, input json.RawMessage) (string, error) {
+		var args LearnedCommandInput
+		if err := decodeInput(input, &args); err != nil {
+			return "", err
+		}
+		command := strings.TrimSpace(args.Command)
+		if command == "" {
+			command = learned.Best(kind)
+		}
+		if command == "" {
+			return "", NewError(ErrInvalidInput, "no %s command has worked in this project yet; pass one in command", kind)
+		}
+		output, ok, err := runShell(ctx, command, env.environ())
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			learned.Observe(command)
+		}
+		return fmt.Sprintf("$ %s\n%s", command, output), nil
+	})
+	t.SetEnv = env.set
+	return t
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyCommand(t *testing.T) {
+	tests := []struct {
+		command, kind, learned string
+	}{
+		{"go test ./... 2>&1 | tail -20", KindTest, "go test ./..."},
+		{"cd web && npm run test", KindTest, "cd web && npm run test"},
+		{"make test", KindTest, "make test"},
+		{"make", KindBuild, "make"},
+		{"gofmt -l .", KindFormat, "gofmt -l ."},
+		{"ruff format src", KindFormat, "ruff format src"},
+		{"ruff check .", KindLint, "ruff check ."},
+		{"go vet ./...", KindLint, "go vet ./..."},
+		{"ls -la", "", ""},
+		{"go test ./...; rm -rf /tmp/x", "", ""},
+		{"gotestsum", "", ""},
+	}
+	for _, tt := range tests {
+		kind, learned := ClassifyCommand(tt.command)
+		if kind != tt.kind || learned != tt.learned {
+			t.Errorf("ClassifyCommand(%q) = %q, %q; want %q, %q", tt.command, kind, learned, tt.kind, tt.learned)
+		}
+	}
+}
+
+func TestLearnedCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".brutus", "learned.json")
+	l, err := LoadLearned(path)
+	if err != nil {
+		t.Fatalf("LoadLearned of a missing file: %v", err)
+	}
+	if l.Prompt() != "" || l.Best(KindTest) != "" {
+		t.Fatal("expected an empty memory")
+	}
+
+	l.Observe("go test -run TestOne ./agent/")
+	l.Observe("go test ./...")
+	l.Observe("go test ./... 2>&1 | head")
+	l.Observe("echo hi")
+	for i := range maxLearnedPerKind {
+		l.Observe("go test ./pkg" + string(rune('a'+i)))
+	}
+
+	l, err = LoadLearned(path)
+	if err != nil {
+		t.Fatalf("LoadLearned: %v", err)
+	}
+	if got := l.Best(KindTest); got != "go test ./..." {
+		t.Errorf("expected the most used test command, got %q", got)
+	}
+	if n := strings.Count(l.Prompt(), "- test:"); n != maxLearnedPerKind {
+		t.Errorf("expected %d test commands kept, got %d:\n%s", maxLearnedPerKind, n, l.Prompt())
+	}
+
+	tool := NewRunTestsTool(l)
+	if _, err := NewFormatTool(l).Function(json.RawMessage(`{}`)); CodeOf(err) != ErrInvalidInput {
+		t.Errorf("format without a learned command: expected invalid_input, got %v", err)
+	}
+	out, err := tool.Function(json.RawMessage(`{"command": "go version"}`))
+	if err != nil || !strings.HasPrefix(out, "$ go version\n") {
+		t.Errorf("run_tests with a command: got %q, %v", out, err)
+	}
+}
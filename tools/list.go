@@ -3,41 +3,73 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+// defaultMaxEntries caps list_files output when the caller sets no limit.
+// A monorepo root can hold hundreds of thousands of files, far more than
+// is useful to put in a context window.
+const defaultMaxEntries = 1000
+
 // ListFilesInput defines parameters for the list_files tool.
 type ListFilesInput struct {
-	Path string `json:"path,omitempty" jsonschema_description:"The directory path to list. Defaults to current directory if not provided."`
+	Path           string `json:"path,omitempty" jsonschema_description:"The directory path to list. Defaults to current directory if not provided."`
+	Recursive      bool   `json:"recursive,omitempty" jsonschema_description:"List everything below path, not just its top level. Setting max_depth implies it. Default: false."`
+	MaxDepth       int    `json:"max_depth,omitempty" jsonschema_description:"How many directory levels to descend (1 lists only the top level). Default: 1, or unlimited when recursive."`
+	MaxEntries     int    `json:"max_entries,omitempty" jsonschema_description:"Maximum number of entries to return. Default: 1000."`
+	Summary        bool   `json:"summary,omitempty" jsonschema_description:"Show directories at max_depth with their file count, e.g. 'src/ (1,204 files)', instead of just the name."`
+	IncludeIgnored bool   `json:"include_ignored,omitempty" jsonschema_description:"Also list files that .gitignore or .brutusignore exclude, and dependency directories such as node_modules."`
+}
+
+// Directories to skip (not useful for code exploration) even when no
+// ignore file names them.
+var skipDirs = map[string]bool{
+	".git":         true,
+	".devenv":      true,
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+	".venv":        true,
 }
 
-// ListFiles enumerates files and directories, skipping common non-code directories.
-// This helps the agent understand project structure.
+// ListFiles enumerates files and directories, skipping what the project's
+// ignore files exclude and common non-code directories.
+// This helps the agent understand project structure. Entries come back in
+// lexical order, directories marked with a trailing slash. Unless asked to
+// recurse, only the top level is listed, each directory with its file
+// count, so a first look at a big tree stays small.
 func ListFiles(input json.RawMessage) (string, error) {
 	var args ListFilesInput
-	if err := json.Unmarshal(input, &args); err != nil {
+	if err := decodeInput(input, &args); err != nil {
 		return "", err
 	}
+	if args.MaxDepth < 0 || args.MaxEntries < 0 {
+		return "", NewError(ErrInvalidInput, "max_depth and max_entries must not be negative")
+	}
 
 	dir := "."
 	if args.Path != "" {
 		dir = args.Path
 	}
-
-	// Directories to skip (not useful for code exploration)
-	skipDirs := map[string]bool{
-		".git":         true,
-		".devenv":      true,
-		"node_modules": true,
-		"vendor":       true,
-		"__pycache__":  true,
-		".venv":        true,
+	if err := checkPath(dir); err != nil {
+		return "", err
+	}
+	maxEntries := args.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultMaxEntries
+	}
+	maxDepth, summary := args.MaxDepth, args.Summary
+	if !args.Recursive && maxDepth == 0 {
+		maxDepth, summary = 1, true
 	}
 
-	var files []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	ignore := newIgnoreSet(dir, args.IncludeIgnored)
+	files := []string{}
+	truncated := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -46,26 +78,46 @@ func ListFiles(input json.RawMessage) (string, error) {
 		if err != nil {
 			return err
 		}
+		if relPath == "." {
+			return nil
+		}
 
-		// Skip ignored directories
-		if info.IsDir() {
-			if skipDirs[relPath] || strings.HasPrefix(relPath, ".git/") {
+		if ignore.ignored(path, d.IsDir()) {
+			if d.IsDir() {
 				return filepath.SkipDir
 			}
+			return nil
 		}
 
-		if relPath != "." {
-			if info.IsDir() {
-				files = append(files, relPath+"/")
-			} else {
-				files = append(files, relPath)
-			}
+		if len(files) == maxEntries {
+			truncated = true
+			return filepath.SkipAll
+		}
+
+		relPath = filepath.ToSlash(relPath)
+		if !d.IsDir() {
+			files = append(files, relPath)
+			return nil
+		}
+
+		atLimit := maxDepth > 0 && strings.Count(relPath, "/")+1 >= maxDepth
+		if atLimit && summary {
+			files = append(files, fmt.Sprintf("%s/ (%s files)", relPath, formatCount(countFiles(path, ignore))))
+		} else {
+			files = append(files, relPath+"/")
+		}
+		if atLimit {
+			return filepath.SkipDir
 		}
 		return nil
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("failed to list files: %w", err)
+		return "", WrapError(err, "failed to list files").WithDetail("path", dir)
+	}
+
+	if truncated {
+		files = append(files, fmt.Sprintf("... (stopped after %d entries; use max_depth, summary or a narrower path)", maxEntries))
 	}
 
 	result, err := json.Marshal(files)
@@ -76,9 +128,41 @@ func ListFiles(input json.RawMessage) (string, error) {
 	return string(result), nil
 }
 
+// countFiles counts the files below dir that ignore lets through.
+// Unreadable subdirectories are left out of the count.
+func countFiles(dir string, ignore *ignoreSet) int {
+	count := 0
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != dir && ignore.ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// formatCount renders n with thousands separators.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
 // ListFilesTool is the tool definition for listing files.
 var ListFilesTool = NewTool[ListFilesInput](
 	"list_files",
-	"List files and directories at a given path. Use this to explore project structure and find relevant files.",
+	`List files and directories at a given path. Use this to explore project structure and find relevant files.
+By default only the top level is listed, with the number of files in each directory. Set recursive to list everything below, or max_depth to go a few levels down (with summary true to see how big the directories at that depth are).`,
 	ListFiles,
-)
+).WithRetry(transientRetry)
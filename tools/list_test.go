@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func listFiles(t *testing.T, input ListFilesInput) []string {
+	t.Helper()
+	data, _ := json.Marshal(input)
+	out, err := ListFiles(data)
+	if err != nil {
+		t.Fatalf("ListFiles(%s): %v", data, err)
+	}
+	var entries []string
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatalf("output is not a JSON array: %s", out)
+	}
+	return entries
+}
+
+func makeTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestListFilesSortedAndSkipsIgnored(t *testing.T) {
+	root := makeTree(t, "b.go", "a.go", "src/z.go", "src/node_modules/x.js", ".git/HEAD")
+
+	got := listFiles(t, ListFilesInput{Path: root, Recursive: true})
+	want := []string{"a.go", "b.go", "src/", "src/z.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestListFilesMaxDepthSummary(t *testing.T) {
+	root := makeTree(t, "main.go", "src/a.go", "src/b.go", "src/deep/c.go", "docs/x.md")
+
+	got := listFiles(t, ListFilesInput{Path: root, MaxDepth: 1})
+	want := []string{"docs/", "main.go", "src/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("max_depth 1: got %v, want %v", got, want)
+	}
+
+	got = listFiles(t, ListFilesInput{Path: root, MaxDepth: 1, Summary: true})
+	want = []string{"docs/ (1 files)", "main.go", "src/ (3 files)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summary: got %v, want %v", got, want)
+	}
+
+	got = listFiles(t, ListFilesInput{Path: root, MaxDepth: 2, Summary: true})
+	want = []string{"docs/", "docs/x.md", "main.go", "src/", "src/a.go", "src/b.go", "src/deep/ (1 files)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("max_depth 2: got %v, want %v", got, want)
+	}
+}
+
+func TestListFilesTopLevelByDefault(t *testing.T) {
+	root := makeTree(t, "main.go", "src/a.go", "src/deep/b.go", "docs/x.md", "node_modules/y.js")
+
+	got := listFiles(t, ListFilesInput{Path: root})
+	want := []string{"docs/ (1 files)", "main.go", "src/ (2 files)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestListFilesMaxEntries(t *testing.T) {
+	var files []string
+	for i := 0; i < 10; i++ {
+		files = append(files, fmt.Sprintf("f%d.txt", i))
+	}
+	root := makeTree(t, files...)
+
+	got := listFiles(t, ListFilesInput{Path: root, MaxEntries: 3})
+	if len(got) != 4 || got[2] != "f2.txt" {
+		t.Fatalf("got %v, want 3 entries and a truncation note", got)
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1204: "1,204", 1234567: "1,234,567"} {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestListFilesIgnoreFiles(t *testing.T) {
+	root := makeTree(t, ".git/HEAD", "main.go", "debug.log", "keep.log", "build/out.bin",
+		"src/a.go", "src/gen/b.go", "src/secret.env", "docs/x.md")
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("# build output\n*.log\n!keep.log\n/build/\n"), 0644)
+	os.WriteFile(filepath.Join(root, ".brutusignore"), []byte("docs\n"), 0644)
+	os.WriteFile(filepath.Join(root, "src", ".gitignore"), []byte("gen/\n*.env\n"), 0644)
+
+	got := listFiles(t, ListFilesInput{Path: root, Recursive: true})
+	want := []string{".brutusignore", ".gitignore", "keep.log", "main.go", "src/", "src/.gitignore", "src/a.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Rules above the listed directory still apply, but the directory
+	// itself was asked for by name.
+	got = listFiles(t, ListFilesInput{Path: filepath.Join(root, "src"), Recursive: true})
+	if want := []string{".gitignore", "a.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("src: got %v, want %v", got, want)
+	}
+	got = listFiles(t, ListFilesInput{Path: filepath.Join(root, "build")})
+	if want := []string{"out.bin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("build: got %v, want %v", got, want)
+	}
+
+	got = listFiles(t, ListFilesInput{Path: root, Recursive: true, IncludeIgnored: true})
+	if len(got) != 15 {
+		t.Errorf("include_ignored: got %v", got)
+	}
+}
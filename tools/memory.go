@@ -0,0 +1,315 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMemories caps how many notes a project keeps; the least used
+	// are dropped first.
+	maxMemories = 200
+
+	// maxMemoryText caps one note.
+	maxMemoryText = 2000
+
+	// maxPromptMemories and maxPromptMemoryBytes bound the notes put in
+	// the system prompt; recall finds the rest.
+	maxPromptMemories    = 20
+	maxPromptMemoryBytes = 4000
+
+	// maxRecalled caps how many notes one recall returns.
+	maxRecalled = 10
+)
+
+// MemoryDir returns the directory long-term memories are kept in, one
+// file per working directory.
+func MemoryDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "brutus-memory")
+	}
+	return filepath.Join(home, ".brutus", "memory")
+}
+
+// MemoryPath returns the file the memories of the project in workDir are
+// kept in. The name starts with the directory's base name, for people
+// browsing the directory, and ends with a hash of its absolute path, so
+// two checkouts called "api" don't share notes.
+func MemoryPath(workDir string) string {
+	abs, err := filepath.Abs(workDir)
+	if err != nil {
+		abs = workDir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	base := memoryNameRe.ReplaceAllString(filepath.Base(abs), "_")
+	return filepath.Join(MemoryDir(), base+"-"+hex.EncodeToString(sum[:])[:12]+".json")
+}
+
+var memoryNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Memory is one note the agent saved about a project.
+type Memory struct {
+	ID    int       `json:"id"`
+	Text  string    `json:"text"`
+	Tags  []string  `json:"tags,omitempty"`
+	Saved time.Time `json:"saved"`
+	Used  int       `json:"used"` // How often recall has returned it
+}
+
+// Memories are the notes the agent keeps about one project across
+// sessions: build quirks, conventions, gotchas. The most used go into the
+// system prompt at startup and the memory tool saves and recalls the rest.
+// It is safe for concurrent use.
+type Memories struct {
+	mu    sync.Mutex
+	path  string
+	notes []Memory
+}
+
+type memoryFile struct {
+	Memories []Memory `json:"memories"`
+}
+
+// LoadMemories reads the memories at path. A missing file is an empty
+// memory that the first save creates.
+func LoadMemories(path string) (*Memories, error) {
+	m := &Memories{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	var f memoryFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	m.notes = f.Memories
+	return m, nil
+}
+
+// Save adds a note and returns its ID. Saving the text of an existing
+// note again updates its tags instead of adding a copy.
+func (m *Memories) Save(text string, tags []string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.notes {
+		if m.notes[i].Text == text {
+			m.notes[i].Tags = tags
+			m.notes[i].Saved = time.Now()
+			return m.notes[i].ID, m.saveLocked()
+		}
+	}
+	id := 1
+	for _, n := range m.notes {
+		id = max(id, n.ID+1)
+	}
+	m.notes = append(m.notes, Memory{ID: id, Text: text, Tags: tags, Saved: time.Now()})
+	if len(m.notes) > maxMemories {
+		m.sortLocked()
+		m.notes = m.notes[:maxMemories]
+	}
+	return id, m.saveLocked()
+}
+
+// Forget removes a note, reporting whether there was one with that ID.
+func (m *Memories) Forget(id int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, n := range m.notes {
+		if n.ID == id {
+			m.notes = append(m.notes[:i], m.notes[i+1:]...)
+			return true, m.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// Recall returns the notes that share the most words with query, best
+// first, and counts them as used.
+func (m *Memories) Recall(query string) ([]Memory, error) {
+	words := strings.Fields(strings.ToLower(query))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type scored struct {
+		index, score int
+	}
+	var matches []scored
+	for i, n := range m.notes {
+		haystack := strings.ToLower(n.Text + " " + strings.Join(n.Tags, " "))
+		score := 0
+		for _, w := range words {
+			if strings.Contains(haystack, w) {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return m.notes[matches[i].index].Saved.After(m.notes[matches[j].index].Saved)
+	})
+	if len(matches) > maxRecalled {
+		matches = matches[:maxRecalled]
+	}
+	found := make([]Memory, len(matches))
+	for i, match := range matches {
+		m.notes[match.index].Used++
+		found[i] = m.notes[match.index]
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return found, m.saveLocked()
+}
+
+// All returns every note, most used first.
+func (m *Memories) All() []Memory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sortLocked()
+	return append([]Memory{}, m.notes...)
+}
+
+// sortLocked orders notes by how often they were recalled, then most
+// recently saved.
+func (m *Memories) sortLocked() {
+	sort.SliceStable(m.notes, func(i, j int) bool {
+		a, b := m.notes[i], m.notes[j]
+		if a.Used != b.Used {
+			return a.Used > b.Used
+		}
+		return a.Saved.After(b.Saved)
+	})
+}
+
+func (m *Memories) saveLocked() error {
+	data, err := json.MarshalIndent(memoryFile{Memories: m.notes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// Prompt lists the most used notes for the system prompt, or returns ""
+// if there are none yet.
+func (m *Memories) Prompt() string {
+	notes := m.All()
+	if len(notes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("# Notes from earlier sessions in this project\n\n")
+	b.WriteString("You saved these with the memory tool. Trust them unless the code says otherwise, and forget any that turn out to be wrong.\n\n")
+	for i, n := range notes {
+		line := fmt.Sprintf("- [%d] %s\n", n.ID, strings.ReplaceAll(n.Text, "\n", " "))
+		if i == maxPromptMemories || b.Len()+len(line) > maxPromptMemoryBytes {
+			fmt.Fprintf(&b, "\n%d more can be found with the memory tool's recall.\n", len(notes)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// MemoryInput defines the parameters for the memory tool.
+type MemoryInput struct {
+	Op    string   `json:"op" jsonschema:"enum=save,enum=recall,enum=list,enum=forget" jsonschema_description:"save stores a note; recall finds notes about a topic; list shows them all; forget deletes one that is wrong or out of date."`
+	Text  string   `json:"text,omitempty" jsonschema_description:"For save: the note, self-contained, e.g. 'Integration tests need docker compose up -d first'."`
+	Tags  []string `json:"tags,omitempty" jsonschema_description:"For save: a few keywords that help recall find the note."`
+	Query string   `json:"query,omitempty" jsonschema_description:"For recall: words describing what you want to know."`
+	ID    int      `json:"id,omitempty" jsonschema_description:"For forget: the note's ID."`
+}
+
+// NewMemoryTool returns the memory tool, which keeps notes in memories.
+func NewMemoryTool(memories *Memories) Tool {
+	return NewTool[MemoryInput](
+		"memory",
+		`Keep notes about this project that outlast the session: how to build or test it, conventions, pitfalls you ran into and how you got past them. The most used notes are in your instructions at the start of each session; recall looks up the rest.
+Save a note when you learn something a later session would otherwise have to work out again. Don't save what the code or docs already say plainly.`,
+		func(input json.RawMessage) (string, error) {
+			var args MemoryInput
+			if err := decodeInput(input, &args); err != nil {
+				return "", err
+			}
+			switch args.Op {
+			case "save":
+				text := strings.TrimSpace(args.Text)
+				if text == "" {
+					return "", NewError(ErrInvalidInput, "text is required for save")
+				}
+				if len(text) > maxMemoryText {
+					return "", NewError(ErrTooLarge, "the note is %d bytes; keep it under %d", len(text), maxMemoryText)
+				}
+				id, err := memories.Save(text, args.Tags)
+				if err != nil {
+					return "", WrapError(err, "failed to save the note")
+				}
+				return fmt.Sprintf("Saved as note %d.", id), nil
+			case "recall":
+				if strings.TrimSpace(args.Query) == "" {
+					return "", NewError(ErrInvalidInput, "query is required for recall")
+				}
+				found, err := memories.Recall(args.Query)
+				if err != nil {
+					return "", WrapError(err, "failed to update the notes")
+				}
+				if len(found) == 0 {
+					return "No notes match.", nil
+				}
+				return formatMemories(found), nil
+			case "list":
+				all := memories.All()
+				if len(all) == 0 {
+					return "No notes saved for this project yet.", nil
+				}
+				return formatMemories(all), nil
+			case "forget":
+				ok, err := memories.Forget(args.ID)
+				if err != nil {
+					return "", WrapError(err, "failed to save the notes")
+				}
+				if !ok {
+					return "", NewError(ErrNotFound, "no note %d", args.ID)
+				}
+				return fmt.Sprintf("Forgot note %d.", args.ID), nil
+			}
+			return "", NewError(ErrInvalidInput, "unknown op %q; use save, recall, list or forget", args.Op)
+		},
+	)
+}
+
+func formatMemories(notes []Memory) string {
+	var b strings.Builder
+	for _, n := range notes {
+		fmt.Fprintf(&b, "[%d] %s", n.ID, n.Text)
+		if len(n.Tags) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(n.Tags, ", "))
+		}
+		fmt.Fprintf(&b, " — saved %s\n", n.Saved.Format("2006-01-02"))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
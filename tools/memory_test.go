@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	m, err := LoadMemories(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewMemoryTool(m)
+	run := func(input string) string {
+		t.Helper()
+		out, err := tool.Function(json.RawMessage(input))
+		if err != nil {
+			t.Fatalf("memory %s: %v", input, err)
+		}
+		return out
+	}
+
+	run(`{"op": "save", "text": "Integration tests need docker compose up -d first", "tags": ["tests"]}`)
+	run(`{"op": "save", "text": "Generated files in api/gen are not edited by hand"}`)
+	// Saving a note again doesn't duplicate it.
+	run(`{"op": "save", "text": "Generated files in api/gen are not edited by hand", "tags": ["codegen"]}`)
+
+	got := run(`{"op": "recall", "query": "how to run the integration tests"}`)
+	if !strings.HasPrefix(got, "[1] Integration tests") || strings.Contains(got, "api/gen") {
+		t.Errorf("recall = %q", got)
+	}
+	if got := run(`{"op": "recall", "query": "deploy"}`); got != "No notes match." {
+		t.Errorf("recall with no match = %q", got)
+	}
+
+	// Notes survive the session; the recalled one comes first.
+	reloaded, err := LoadMemories(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := reloaded.All()
+	if len(all) != 2 || all[0].ID != 1 || all[0].Used != 1 || strings.Join(all[1].Tags, ",") != "codegen" {
+		t.Errorf("reloaded notes = %+v", all)
+	}
+	if prompt := reloaded.Prompt(); !strings.Contains(prompt, "- [1] Integration tests") || !strings.Contains(prompt, "- [2] Generated files") {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	run(`{"op": "forget", "id": 2}`)
+	if _, err := tool.Function(json.RawMessage(`{"op": "forget", "id": 2}`)); CodeOf(err) != ErrNotFound {
+		t.Errorf("forgetting twice: %v", err)
+	}
+	if got := run(`{"op": "list"}`); strings.Contains(got, "api/gen") {
+		t.Errorf("forgotten note listed: %q", got)
+	}
+}
+
+func TestMemoryPath(t *testing.T) {
+	a, b := MemoryPath("/work/one/api"), MemoryPath("/work/two/api")
+	if a == b || !strings.HasPrefix(filepath.Base(a), "api-") {
+		t.Errorf("MemoryPath: %q, %q", a, b)
+	}
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"brutus/internal/text"
+)
+
+// Middleware wraps a tool's function to add behavior every tool should
+// have, such as logging or a cap on result size, without changing the
+// tools themselves. Register middleware with Registry.Use. It sees the
+// context of each call; tools without a FunctionCtx are adapted to it.
+type Middleware func(next ToolFuncCtx) ToolFuncCtx
+
+// ToolMiddleware builds the Middleware for one tool, for middleware that
+// needs to know which tool it wraps. Register it with Registry.UseFor.
+type ToolMiddleware func(t Tool) Middleware
+
+// Use adds middleware that wraps the function of every tool in the
+// registry, including tools registered later, as Get and All return them. Middleware added first runs
+// first, on the outside of the chain; retries run the chain again.
+func (r *Registry) Use(mw ...Middleware) {
+	for _, m := range mw {
+		r.UseFor(func(Tool) Middleware { return m })
+	}
+}
+
+// UseFor adds middleware built for each tool by mw, as Use does.
+func (r *Registry) UseFor(mw ToolMiddleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, mw)
+	r.sorted = nil
+}
+
+// wrap returns t with the registry's middleware around its function.
+// r.mu must be held.
+func (r *Registry) wrap(t Tool) Tool {
+	if len(r.middleware) == 0 || (t.Function == nil && t.FunctionCtx == nil) {
+		return t
+	}
+	mw := make([]Middleware, 0, len(r.middleware))
+	for _, m := range r.middleware {
+		if m := m(t); m != nil {
+			mw = append(mw, m)
+		}
+	}
+	return wrapTool(t, mw)
+}
+
+// wrapTool returns t with mw around its function, the first outermost,
+// both as FunctionCtx and as Function.
+func wrapTool(t Tool, mw []Middleware) Tool {
+	fn := t.FunctionCtx
+	if fn == nil {
+		plain := t.Function
+		fn = func(_ context.Context, input json.RawMessage) (string, error) {
+			return plain(input)
+		}
+	}
+	for i := len(mw) - 1; i >= 0; i-- {
+		fn = mw[i](fn)
+	}
+	t.FunctionCtx = fn
+	t.Function = func(input json.RawMessage) (string, error) {
+		return fn(context.Background(), input)
+	}
+	return t
+}
+
+// LogCalls logs each tool call at debug level and its outcome at info
+// level, or warn when it fails, with how long it took.
+func LogCalls(logger *slog.Logger) ToolMiddleware {
+	return func(t Tool) Middleware {
+		return func(next ToolFuncCtx) ToolFuncCtx {
+			return func(ctx context.Context, input json.RawMessage) (string, error) {
+				logger.Debug("tool call", "tool", t.Name, "input", text.Head(RedactInput(input), 500))
+				start := time.Now()
+				result, err := next(ctx, input)
+				if err != nil {
+					logger.Warn("tool failed", "tool", t.Name, "duration_ms", time.Since(start).Milliseconds(), "error", err)
+				} else {
+					logger.Info("tool finished", "tool", t.Name, "duration_ms", time.Since(start).Milliseconds(), "result_bytes", len(result))
+				}
+				return result, err
+			}
+		}
+	}
+}
+
+// LimitResult keeps the start and end of results longer than max
+// characters, noting how much of the middle was left out.
+func LimitResult(max int) Middleware {
+	return func(next ToolFuncCtx) ToolFuncCtx {
+		return func(ctx context.Context, input json.RawMessage) (string, error) {
+			result, err := next(ctx, input)
+			return text.HeadTail(result, max), err
+		}
+	}
+}
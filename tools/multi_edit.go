@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MultiEditInput defines parameters for the multi_edit tool.
+type MultiEditInput struct {
+	Path  string          `json:"path" jsonschema_description:"The path to the file to edit."`
+	Edits []EditOperation `json:"edits" jsonschema_description:"The replacements to make, in order. Each one sees the file as the ones before it left it."`
+}
+
+// EditOperation is one replacement of a multi_edit call.
+type EditOperation struct {
+	OldStr string `json:"old_str" jsonschema_description:"The exact text to find and replace. Must be unique in the file."`
+	NewStr string `json:"new_str" jsonschema_description:"The replacement text."`
+}
+
+// MultiEdit makes several replacements in one file, all or none: they are
+// applied in memory, in order, and the file is written only if every one
+// of them matches exactly one location. A failure names the edit that
+// failed, so the model can fix that one and resend the batch.
+func MultiEdit(input json.RawMessage) (string, error) {
+	var args MultiEditInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if args.Path == "" {
+		return "", NewError(ErrInvalidInput, "path is required")
+	}
+	if err := checkPath(args.Path); err != nil {
+		return "", err
+	}
+	if len(args.Edits) == 0 {
+		return "", NewError(ErrInvalidInput, "edits is empty")
+	}
+
+	content, err := os.ReadFile(args.Path)
+	if err != nil {
+		return "", WrapError(err, "failed to read file").WithDetail("path", args.Path)
+	}
+
+	text := string(content)
+	for i, edit := range args.Edits {
+		fail := func(code ErrorCode, format string, args ...any) (string, error) {
+			return "", NewError(code, "edit %d: "+format+"; no edits were made", append([]any{i + 1}, args...)...).
+				WithDetail("edit", i+1)
+		}
+		switch count := strings.Count(text, edit.OldStr); {
+		case edit.OldStr == "":
+			return fail(ErrInvalidInput, "old_str is empty")
+		case edit.OldStr == edit.NewStr:
+			return fail(ErrInvalidInput, "old_str and new_str must be different")
+		case count == 0:
+			return fail(ErrNotFound, "old_str not found in file")
+		case count > 1:
+			return fail(ErrInvalidInput, "old_str found %d times, must be unique", count)
+		}
+		text = strings.Replace(text, edit.OldStr, edit.NewStr, 1)
+	}
+
+	check := startDiagnostics(args.Path)
+	if err := os.WriteFile(args.Path, []byte(text), 0644); err != nil {
+		return "", WrapError(err, "failed to write file").WithDetail("path", args.Path)
+	}
+	return fmt.Sprintf("OK: %d edits", len(args.Edits)) + check.report(), nil
+}
+
+// MultiEditTool is the tool definition for batched edits to one file.
+var MultiEditTool = NewTool[MultiEditInput](
+	"multi_edit",
+	`Make several replacements in one file at once. Each edit's old_str must match exactly one location in the file as the earlier edits left it.
+The edits are all-or-nothing: if any one fails, the file is left untouched and the error says which edit failed.
+Prefer this to several edit_file calls when changing a file in more than one place.`,
+	MultiEdit,
+)
@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMultiEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("one two three"), 0644)
+
+	run := func(edits ...EditOperation) error {
+		input, _ := json.Marshal(MultiEditInput{Path: path, Edits: edits})
+		_, err := MultiEdit(input)
+		return err
+	}
+
+	err := run(EditOperation{OldStr: "one", NewStr: "1"}, EditOperation{OldStr: "four", NewStr: "4"})
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Code != ErrNotFound || toolErr.Details["edit"] != 2 {
+		t.Fatalf("expected edit 2 not found, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "one two three" {
+		t.Fatalf("a failed batch changed the file: %q", data)
+	}
+
+	// Later edits see the earlier ones.
+	if err := run(EditOperation{OldStr: "one", NewStr: "1"}, EditOperation{OldStr: "1 two", NewStr: "1 2"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "1 2 three" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
@@ -0,0 +1,438 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"brutus/internal/text"
+)
+
+// ApplyPatchInput defines parameters for the apply_patch tool.
+type ApplyPatchInput struct {
+	Patch  string `json:"patch" jsonschema_description:"A unified diff, as diff -u or git diff write it. It may change, create (--- /dev/null), delete (+++ /dev/null) or rename several files."`
+	DryRun bool   `json:"dry_run,omitempty" jsonschema_description:"Check that the patch applies and report how, without changing any file."`
+}
+
+// maxPatchFuzz is how many context lines at each end of a hunk may fail to
+// match, as with patch's fuzz factor.
+const maxPatchFuzz = 2
+
+// patchLine is one line of a hunk: ' ' for context, '-' removed, '+' added.
+type patchLine struct {
+	op   byte
+	text string
+}
+
+// hunk is one @@ section of a file patch.
+type hunk struct {
+	oldStart int // 1-based line the hunk starts at in the old file; 0 if unknown
+	lines    []patchLine
+}
+
+// filePatch is the part of a patch for one file.
+type filePatch struct {
+	oldPath, newPath string // "" for /dev/null
+	hunks            []hunk
+	eol              *bool // whether the new file ends in a newline, if the patch says
+}
+
+// ApplyPatch applies a unified diff to one or more files. Every hunk is
+// located and applied in memory first, so a patch that does not apply
+// changes nothing. Hunks are looked for near the line they name, then
+// anywhere after the previous hunk; failing an exact match, whitespace is
+// ignored, and then up to maxPatchFuzz context lines at either end may
+// differ. The result says where any hunk needed that.
+func ApplyPatch(input json.RawMessage) (string, error) {
+	var args ApplyPatchInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	patches, err := parsePatch(args.Patch)
+	if err != nil {
+		return "", err
+	}
+
+	type change struct {
+		patch   *filePatch
+		content string
+	}
+	var changes []change
+	var report []string
+	for i := range patches {
+		p := &patches[i]
+		for _, path := range []string{p.oldPath, p.newPath} {
+			if path == "" {
+				continue
+			}
+			if err := checkPath(path); err != nil {
+				return "", err
+			}
+		}
+		content, notes, err := p.apply()
+		if err != nil {
+			return "", err
+		}
+		changes = append(changes, change{p, content})
+		report = append(report, p.describe()+notes)
+	}
+
+	if args.DryRun {
+		return "Dry run; the patch applies and nothing was written:\n" + strings.Join(report, "\n"), nil
+	}
+
+	var checks []diagnosticCheck
+	for _, c := range changes {
+		p := c.patch
+		if p.newPath == "" {
+			if err := os.Remove(p.oldPath); err != nil {
+				return "", WrapError(err, "failed to delete file").WithDetail("path", p.oldPath)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p.newPath), 0755); err != nil {
+			return "", WrapError(err, "failed to create directory").WithDetail("path", p.newPath)
+		}
+		check := startDiagnostics(p.newPath)
+		if err := os.WriteFile(p.newPath, []byte(c.content), 0644); err != nil {
+			return "", WrapError(err, "failed to write file").WithDetail("path", p.newPath)
+		}
+		checks = append(checks, check)
+		if p.oldPath != "" && p.oldPath != p.newPath {
+			if err := os.Remove(p.oldPath); err != nil {
+				return "", WrapError(err, "failed to remove renamed file").WithDetail("path", p.oldPath)
+			}
+		}
+	}
+
+	result := "Applied:\n" + strings.Join(report, "\n")
+	for _, check := range checks {
+		result += check.report()
+	}
+	return result, nil
+}
+
+// describe names what the patch does to its file.
+func (p *filePatch) describe() string {
+	switch {
+	case p.oldPath == "":
+		return "created " + p.newPath
+	case p.newPath == "":
+		return "deleted " + p.oldPath
+	case p.oldPath != p.newPath:
+		return fmt.Sprintf("renamed %s to %s, %d hunks", p.oldPath, p.newPath, len(p.hunks))
+	}
+	return fmt.Sprintf("%s: %d hunks", p.newPath, len(p.hunks))
+}
+
+// apply returns the file's new content and notes on hunks that did not
+// apply cleanly where the patch said.
+func (p *filePatch) apply() (string, string, error) {
+	var lines []string
+	eol := true
+	if p.oldPath == "" {
+		if _, err := os.Stat(p.newPath); err == nil {
+			return "", "", NewError(ErrInvalidInput, "the patch creates %s, which already exists", p.newPath).WithDetail("path", p.newPath)
+		}
+	} else {
+		data, err := os.ReadFile(p.oldPath)
+		if err != nil {
+			return "", "", WrapError(err, "failed to read file").WithDetail("path", p.oldPath)
+		}
+		lines, eol = splitLines(string(data))
+	}
+	if p.newPath == "" {
+		return "", "", nil
+	}
+
+	var notes []string
+	// delta maps the patch's old line numbers to lines as they are now,
+	// including how far earlier hunks were from where they said; grown
+	// counts only the lines earlier hunks added.
+	from, delta, grown := 0, 0, 0
+	for i, h := range p.hunks {
+		hint := from
+		if h.oldStart > 0 {
+			hint = h.oldStart - 1 + delta
+		}
+		m, ok := h.locate(lines, from, hint)
+		if !ok {
+			return "", "", NewError(ErrNotFound, "%s: hunk %d does not apply; its lines were not found, so no file was changed", p.oldPath, i+1).
+				WithDetail("path", p.oldPath).
+				WithDetail("hunk", i+1).
+				WithDetail("expected", text.Head(h.side('-'), 500))
+		}
+
+		var replaced []string
+		old := m.at
+		for _, l := range h.lines[m.head : len(h.lines)-m.tail] {
+			switch l.op {
+			case ' ':
+				// Keep the file's own line; it may differ in whitespace.
+				replaced = append(replaced, lines[old])
+				old++
+			case '-':
+				old++
+			case '+':
+				replaced = append(replaced, l.text)
+			}
+		}
+		lines = append(lines[:m.at], append(replaced, lines[old:]...)...)
+		from = m.at + len(replaced)
+		if h.oldStart > 0 {
+			delta = m.at - m.head - (h.oldStart - 1) + h.count(' ', '+') - h.count(' ', '-')
+		}
+
+		var how []string
+		if offset := m.at - m.head - (h.oldStart - 1 + grown); h.oldStart > 0 && offset != 0 {
+			how = append(how, fmt.Sprintf("%+d lines from where it said", offset))
+		}
+		if m.loose {
+			how = append(how, "ignoring whitespace")
+		}
+		if m.head+m.tail > 0 {
+			how = append(how, fmt.Sprintf("with %d context lines unmatched", m.head+m.tail))
+		}
+		if len(how) > 0 {
+			notes = append(notes, fmt.Sprintf("hunk %d %s", i+1, strings.Join(how, ", ")))
+		}
+		grown += h.count('+') - h.count('-')
+	}
+	if p.eol != nil {
+		eol = *p.eol
+	}
+	content := strings.Join(lines, "\n")
+	if eol && len(lines) > 0 {
+		content += "\n"
+	}
+	if len(notes) > 0 {
+		return content, " (" + strings.Join(notes, "; ") + ")", nil
+	}
+	return content, "", nil
+}
+
+// hunkMatch is where a hunk was found.
+type hunkMatch struct {
+	at         int  // Index of the first line matched
+	head, tail int  // Context lines left unmatched at each end
+	loose      bool // Whitespace was ignored
+}
+
+// locate finds where the hunk's old lines are in lines, at or after from
+// and as close to hint as possible, first exactly and then ignoring
+// whitespace, dropping more context lines from the hunk's ends each round.
+func (h hunk) locate(lines []string, from, hint int) (hunkMatch, bool) {
+	exact := func(a, b string) bool { return a == b }
+	trimmed := func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) }
+
+	leading, trailing := h.context()
+	for fuzz := 0; fuzz <= maxPatchFuzz; fuzz++ {
+		head, tail := min(fuzz, leading), min(fuzz, trailing)
+		if fuzz > 0 && head == min(fuzz-1, leading) && tail == min(fuzz-1, trailing) {
+			break // No more context to drop.
+		}
+		var want []string
+		for _, l := range h.lines[head : len(h.lines)-tail] {
+			if l.op != '+' {
+				want = append(want, l.text)
+			}
+		}
+		if len(want) == 0 {
+			if fuzz > 0 {
+				break
+			}
+			// Nothing to match: an insertion into an empty or new file.
+			return hunkMatch{at: min(max(hint, from), len(lines))}, true
+		}
+		for i, eq := range []func(a, b string) bool{exact, trimmed} {
+			if at := findLines(lines, want, from, hint+head, eq); at >= 0 {
+				return hunkMatch{at: at, head: head, tail: tail, loose: i > 0}, true
+			}
+		}
+	}
+	return hunkMatch{}, false
+}
+
+// context counts the context lines the hunk starts and ends with.
+func (h hunk) context() (leading, trailing int) {
+	for leading < len(h.lines) && h.lines[leading].op == ' ' {
+		leading++
+	}
+	for trailing < len(h.lines)-leading && h.lines[len(h.lines)-1-trailing].op == ' ' {
+		trailing++
+	}
+	return leading, trailing
+}
+
+// count returns how many of the hunk's lines have one of ops.
+func (h hunk) count(ops ...byte) int {
+	n := 0
+	for _, l := range h.lines {
+		if strings.IndexByte(string(ops), l.op) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// side returns the hunk's old ('-') or new ('+') text.
+func (h hunk) side(op byte) string {
+	var out []string
+	for _, l := range h.lines {
+		if l.op == ' ' || l.op == op {
+			out = append(out, l.text)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// findLines returns where want occurs in lines at or after from, nearest
+// to hint, or -1.
+func findLines(lines, want []string, from, hint int, eq func(a, b string) bool) int {
+	last := len(lines) - len(want)
+	matches := func(at int) bool {
+		for i, w := range want {
+			if !eq(lines[at+i], w) {
+				return false
+			}
+		}
+		return true
+	}
+	hint = min(max(hint, from), max(last, from))
+	for d := 0; hint-d >= from || hint+d <= last; d++ {
+		if at := hint - d; at >= from && at <= last && matches(at) {
+			return at
+		}
+		if at := hint + d; d > 0 && at >= from && at <= last && matches(at) {
+			return at
+		}
+	}
+	return -1
+}
+
+// splitLines splits content into lines and reports whether it ended in a
+// newline.
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, true
+	}
+	eol := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), eol
+}
+
+// parsePatch reads the file patches in a unified diff. Hunk line counts
+// are not trusted, since models often get them wrong: a hunk runs until
+// the next hunk or file header. A blank line inside a hunk is taken as
+// blank context whose leading space was lost.
+func parsePatch(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var patches []filePatch
+	var current *filePatch
+	var h *hunk
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			patches = append(patches, filePatch{
+				oldPath: patchPath(line[4:]),
+				newPath: patchPath(lines[i+1][4:]),
+			})
+			current, h = &patches[len(patches)-1], nil
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, NewError(ErrInvalidInput, "line %d: hunk before any --- / +++ file header", i+1)
+			}
+			current.hunks = append(current.hunks, hunk{oldStart: hunkStart(line)})
+			h = &current.hunks[len(current.hunks)-1]
+		case h == nil:
+			// diff --git, index and other lines between files.
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the line before.
+			if n := len(h.lines); n > 0 {
+				eol := h.lines[n-1].op == '-'
+				current.eol = &eol
+			}
+		case line == "":
+			h.lines = append(h.lines, patchLine{' ', ""})
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			h.lines = append(h.lines, patchLine{line[0], line[1:]})
+		default:
+			h = nil
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, NewError(ErrInvalidInput, "no file headers (--- and +++ lines) found in the patch")
+	}
+	for _, p := range patches {
+		if p.oldPath == "" && p.newPath == "" {
+			return nil, NewError(ErrInvalidInput, "a file patch has /dev/null on both sides")
+		}
+		if len(p.hunks) == 0 && p.newPath != "" {
+			return nil, NewError(ErrInvalidInput, "the patch for %s has no hunks", p.newPath).WithDetail("path", p.newPath)
+		}
+	}
+	stripGitPrefixes(patches)
+	return patches, nil
+}
+
+// patchPath reads a path from a --- or +++ header, dropping a trailing
+// timestamp. /dev/null is returned as "".
+func patchPath(header string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	return path
+}
+
+// stripGitPrefixes removes the a/ and b/ git puts before paths, when
+// every header has them.
+func stripGitPrefixes(patches []filePatch) {
+	for _, p := range patches {
+		if (p.oldPath != "" && !strings.HasPrefix(p.oldPath, "a/")) || (p.newPath != "" && !strings.HasPrefix(p.newPath, "b/")) {
+			return
+		}
+	}
+	for i := range patches {
+		if patches[i].oldPath != "" {
+			patches[i].oldPath = patches[i].oldPath[2:]
+		}
+		if patches[i].newPath != "" {
+			patches[i].newPath = patches[i].newPath[2:]
+		}
+	}
+}
+
+// hunkStart reads the old start line from "@@ -l,s +l,s @@", or 0 if the
+// header doesn't give one.
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], "-") {
+		return 0
+	}
+	start, _, _ := strings.Cut(fields[1][1:], ",")
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ApplyPatchTool is the tool definition for applying unified diffs.
+var ApplyPatchTool = NewTool[ApplyPatchInput](
+	"apply_patch",
+	`Apply a unified diff (as written by diff -u or git diff) to one or more files. Cheaper than edit_file for large or repeated changes.
+Hunks are found near the line numbers given, or elsewhere in the file if the code moved; whitespace differences and a couple of mismatched context lines at a hunk's edges are tolerated, and the result says when that happened.
+If any hunk does not apply, no file is changed. Set dry_run to check a patch without writing anything.`,
+	ApplyPatch,
+)
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func applyPatch(t *testing.T, patch string, dryRun bool) (string, error) {
+	t.Helper()
+	input, _ := json.Marshal(ApplyPatchInput{Patch: patch, DryRun: dryRun})
+	return ApplyPatch(input)
+}
+
+func TestApplyPatch(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.WriteFile("a.go", []byte("package a\n\n// moved down\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() int {\n    return 2\n}\n"), 0644)
+	os.WriteFile("old.txt", []byte("bye\n"), 0644)
+
+	// Both hunks' line numbers are off and the second hunk's
+	// context differs in indentation.
+	patch := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,5 +1,5 @@
+ func A() int {
+-	return 1
++	return 10
+ }
+ 
+@@ -7,3 +7,3 @@
+ func B() int {
+-	return 2
++	return 20
+ }
+--- a/new.txt
++++ /dev/null
+--- /dev/null
++++ b/created/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+`
+	// new.txt doesn't exist, so nothing may change.
+	if _, err := applyPatch(t, patch, false); err == nil {
+		t.Fatal("expected deleting a missing file to fail")
+	}
+	if data, _ := os.ReadFile("a.go"); strings.Contains(string(data), "10") {
+		t.Fatal("a failed patch changed a.go")
+	}
+
+	patch = strings.Replace(patch, "new.txt\n+++ /dev/null", "old.txt\n+++ /dev/null", 1)
+	out, err := applyPatch(t, patch, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !strings.Contains(out, "Dry run") || !strings.Contains(out, "hunk 1 +4 lines") || !strings.Contains(out, "hunk 2 +2 lines") || !strings.Contains(out, "ignoring whitespace") {
+		t.Errorf("unexpected dry run report:\n%s", out)
+	}
+	if _, err := os.Stat("old.txt"); err != nil {
+		t.Fatal("the dry run deleted a file")
+	}
+
+	if _, err := applyPatch(t, patch, false); err != nil {
+		t.Fatalf("patch failed: %v", err)
+	}
+	want := "package a\n\n// moved down\n\nfunc A() int {\n\treturn 10\n}\n\nfunc B() int {\n\treturn 20\n}\n"
+	if data, _ := os.ReadFile("a.go"); string(data) != want {
+		t.Errorf("a.go = %q, want %q", data, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join("created", "new.txt")); string(data) != "hello\nworld\n" {
+		t.Errorf("created file = %q", data)
+	}
+	if _, err := os.Stat("old.txt"); !os.IsNotExist(err) {
+		t.Error("old.txt was not deleted")
+	}
+}
@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ToolPolicy is how the user configured one tool. Zero fields leave the
+// tool as it was defined.
+type ToolPolicy struct {
+	// Disabled keeps the tool out of every registry.
+	Disabled bool
+
+	// Timeout stops a call that runs longer. Tools that take a context are
+	// cancelled; others are left to finish in the background, their
+	// result unused.
+	Timeout time.Duration
+
+	// MaxOutput caps the characters of a result, cutting from the middle.
+	MaxOutput int
+
+	// SandboxRoot confines the tool's path, paths and root arguments to
+	// this directory, relative to the working directory if not absolute.
+	// It narrows the workspace sandbox rather than widening it.
+	SandboxRoot string
+}
+
+// AllTools is the ConfigureTools key whose policy applies to every tool.
+const AllTools = "*"
+
+var (
+	policyMu sync.RWMutex
+	policies map[string]ToolPolicy
+)
+
+// ConfigureTools sets the policies Registry.Register applies to the tools
+// registered after it, keyed by tool name. The fields a tool's policy
+// leaves zero are taken from the AllTools policy, except Disabled. The CLI
+// and GUI configure it from the config file; programs built on the sdk
+// package, whose registries are filled the same way, call it themselves.
+func ConfigureTools(p map[string]ToolPolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policies = p
+}
+
+// PolicyFor returns the policy configured for the named tool.
+func PolicyFor(name string) ToolPolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	p := policies[name]
+	all := policies[AllTools]
+	if p.Timeout == 0 {
+		p.Timeout = all.Timeout
+	}
+	if p.MaxOutput == 0 {
+		p.MaxOutput = all.MaxOutput
+	}
+	if p.SandboxRoot == "" {
+		p.SandboxRoot = all.SandboxRoot
+	}
+	return p
+}
+
+// apply returns t with the policy's sandbox, timeout and output cap around
+// its function, in that order from the outside.
+func (p ToolPolicy) apply(t Tool) Tool {
+	var mw []Middleware
+	if p.SandboxRoot != "" {
+		mw = append(mw, confinePaths(p.SandboxRoot, t.Parameters()))
+	}
+	if p.Timeout > 0 {
+		mw = append(mw, timeoutAfter(t.Name, p.Timeout))
+	}
+	if p.MaxOutput > 0 {
+		mw = append(mw, LimitResult(p.MaxOutput))
+	}
+	if len(mw) == 0 || (t.Function == nil && t.FunctionCtx == nil) {
+		return t
+	}
+	return wrapTool(t, mw)
+}
+
+// timeoutAfter stops calls of the named tool that run longer than d.
+func timeoutAfter(name string, d time.Duration) Middleware {
+	return func(next ToolFuncCtx) ToolFuncCtx {
+		return func(ctx context.Context, input json.RawMessage) (string, error) {
+			callCtx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			type outcome struct {
+				result string
+				err    error
+			}
+			done := make(chan outcome, 1)
+			go func() {
+				result, err := next(callCtx, input)
+				done <- outcome{result, err}
+			}()
+			select {
+			case o := <-done:
+				if ctx.Err() == nil && callCtx.Err() != nil {
+					return "", NewError(ErrTimeout, "%s did not finish within %s", name, d).WithDetail("timeout", d.String())
+				}
+				return o.result, o.err
+			case <-callCtx.Done():
+				if ctx.Err() != nil {
+					return "", interrupted(ctx, name)
+				}
+				return "", NewError(ErrTimeout, "%s did not finish within %s", name, d).WithDetail("timeout", d.String())
+			}
+		}
+	}
+}
+
+// confinePaths refuses calls whose path, paths or root arguments lie
+// outside root. An argument in the tool's parameters that the call leaves
+// out means the working directory, as it does to the file tools.
+func confinePaths(root string, parameters json.RawMessage) Middleware {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	json.Unmarshal(parameters, &schema)
+	takes := func(name string) bool {
+		_, ok := schema.Properties[name]
+		return ok
+	}
+	return func(next ToolFuncCtx) ToolFuncCtx {
+		return func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args struct {
+				Path  string   `json:"path"`
+				Paths []string `json:"paths"`
+				Root  string   `json:"root"`
+			}
+			// Input that doesn't decode is left for the tool to reject.
+			json.Unmarshal(input, &args)
+			if args.Path == "" && takes("path") {
+				args.Path = "."
+			}
+			if args.Root == "" && takes("root") {
+				args.Root = "."
+			}
+			if len(args.Paths) == 0 && takes("paths") {
+				args.Paths = []string{"."}
+			}
+			for _, path := range append([]string{args.Path, args.Root}, args.Paths...) {
+				if path == "" {
+					continue
+				}
+				if err := confine(path, root, nil); err != nil {
+					return "", err
+				}
+			}
+			return next(ctx, input)
+		}
+	}
+}
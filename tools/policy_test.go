@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolPolicy(t *testing.T) {
+	ConfigureTools(map[string]ToolPolicy{
+		AllTools: {MaxOutput: 20, Disabled: true},
+		"bash":   {Disabled: true},
+		"slow":   {Timeout: 50 * time.Millisecond},
+		"long":   {MaxOutput: 40},
+	})
+	t.Cleanup(func() { ConfigureTools(nil) })
+
+	hang := make(chan struct{})
+	defer close(hang)
+	r := NewRegistry()
+	r.Register(stubTool("bash"))
+	r.Register(Tool{Name: "slow", Function: func(json.RawMessage) (string, error) {
+		<-hang
+		return "late", nil
+	}})
+	r.Register(Tool{Name: "long", Function: func(json.RawMessage) (string, error) {
+		return strings.Repeat("x", 100), nil
+	}})
+	r.Register(Tool{Name: "other", Function: func(json.RawMessage) (string, error) {
+		return strings.Repeat("x", 100), nil
+	}})
+
+	if _, ok := r.Get("bash"); ok {
+		t.Error("a disabled tool was registered")
+	}
+	if _, ok := r.Get("other"); !ok {
+		t.Error("Disabled was taken from the * policy")
+	}
+
+	slow, _ := r.Get("slow")
+	start := time.Now()
+	if _, err := slow.Call(context.Background(), nil); CodeOf(err) != ErrTimeout {
+		t.Errorf("slow: got %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("slow: returned after %v", elapsed)
+	}
+
+	long, _ := r.Get("long")
+	other, _ := r.Get("other")
+	a, _ := long.Function(nil)
+	b, _ := other.Function(nil)
+	if !strings.Contains(a, "omitted") || !strings.Contains(b, "omitted") || len(b) >= len(a) {
+		t.Errorf("results not cut to the tool's own limit:\n%q\n%q", a, b)
+	}
+
+	// A tool handed to another registry is not limited a second time.
+	again := NewRegistry()
+	again.Register(long)
+	if c, _ := again.Get("long"); c.Name != "long" {
+		t.Fatal("long was not registered again")
+	} else if got, _ := c.Function(nil); got != a {
+		t.Errorf("limited twice: %q", got)
+	}
+}
+
+func TestToolPolicySandboxRoot(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dir, "docs", "a.md"), []byte("a\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("s\n"), 0644)
+	t.Chdir(dir)
+
+	ConfigureTools(map[string]ToolPolicy{"read_file": {SandboxRoot: "docs"}, "list_files": {SandboxRoot: "docs"}})
+	t.Cleanup(func() { ConfigureTools(nil) })
+	r := NewRegistry()
+	r.Register(ReadFileTool)
+	r.Register(ListFilesTool)
+
+	read, _ := r.Get("read_file")
+	if _, err := read.Function(json.RawMessage(`{"path": "docs/a.md"}`)); err != nil {
+		t.Errorf("inside the root: %v", err)
+	}
+	if _, err := read.Function(json.RawMessage(`{"path": "secret.txt"}`)); CodeOf(err) != ErrPermissionDenied {
+		t.Errorf("outside the root: got %v", err)
+	}
+	list, _ := r.Get("list_files")
+	if _, err := list.Function(json.RawMessage(`{}`)); CodeOf(err) != ErrPermissionDenied {
+		t.Errorf("listing the working directory by default: got %v", err)
+	}
+}
+
+func TestToolPolicyWithShell(t *testing.T) {
+	ConfigureTools(map[string]ToolPolicy{"bash": {MaxOutput: 40}})
+	t.Cleanup(func() { ConfigureTools(nil) })
+
+	learned, _ := LoadLearned(filepath.Join(t.TempDir(), "learned.json"))
+	r := NewRegistry()
+	r.Register(NewBashTool(learned))
+
+	var got string
+	ctx := WithShell(context.Background(), func(ctx context.Context, command string, vars []string) (string, error) {
+		got = command
+		return strings.Repeat("out ", 100), nil
+	})
+	tool, _ := r.Get("bash")
+	out, err := tool.Call(ctx, json.RawMessage(`{"command": "make build"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "make build" {
+		t.Errorf("shell ran %q", got)
+	}
+	if len(out) >= 400 {
+		t.Errorf("the policy's output cap did not apply to the shell's result: %d bytes", len(out))
+	}
+}
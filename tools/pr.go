@@ -0,0 +1,271 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PRCreateInput defines the parameters for the gh_pr_create tool.
+type PRCreateInput struct {
+	Title string `json:"title" jsonschema_description:"Title of the pull request."`
+	Body  string `json:"body,omitempty" jsonschema_description:"Description in Markdown: what changed and why."`
+	Head  string `json:"head,omitempty" jsonschema_description:"Branch with the changes. Defaults to the current branch."`
+	Base  string `json:"base,omitempty" jsonschema_description:"Branch to merge into. Defaults to the repository's default branch."`
+	Draft bool   `json:"draft,omitempty" jsonschema_description:"Open the pull request as a draft."`
+	Push  bool   `json:"push,omitempty" jsonschema_description:"Push the head branch to origin first."`
+	Repo  string `json:"repo,omitempty" jsonschema_description:"owner/name or host/owner/name. Defaults to the origin remote."`
+}
+
+// PRCommentInput defines the parameters for the gh_pr_comment tool.
+type PRCommentInput struct {
+	Number int    `json:"number" jsonschema_description:"Pull request (or GitLab merge request) number."`
+	Body   string `json:"body" jsonschema_description:"Comment text in Markdown."`
+	Repo   string `json:"repo,omitempty" jsonschema_description:"owner/name or host/owner/name. Defaults to the origin remote."`
+}
+
+// IssueFetchInput defines the parameters for the issue_fetch tool.
+type IssueFetchInput struct {
+	Number   int    `json:"number" jsonschema_description:"Issue number."`
+	Comments bool   `json:"comments,omitempty" jsonschema_description:"Include the issue's comments."`
+	Repo     string `json:"repo,omitempty" jsonschema_description:"owner/name or host/owner/name. Defaults to the origin remote."`
+}
+
+// PRCreate opens a pull request on GitHub or a merge request on GitLab.
+func PRCreate(input json.RawMessage) (string, error) {
+	var args PRCreateInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args.Title) == "" {
+		return "", NewError(ErrInvalidInput, "title is required")
+	}
+
+	repo, err := resolveRepo(args.Repo)
+	if err != nil {
+		return "", err
+	}
+	client, err := newForgeClient(repo.Host)
+	if err != nil {
+		return "", err
+	}
+
+	if args.Head == "" {
+		if args.Head, err = git("rev-parse", "--abbrev-ref", "HEAD"); err != nil {
+			return "", WrapError(err, "cannot tell the current branch; pass head")
+		}
+	}
+	if args.Push {
+		if _, err := git("push", "-u", "origin", args.Head); err != nil {
+			return "", WrapError(err, "failed to push %s", args.Head).WithDetail("branch", args.Head)
+		}
+	}
+	if args.Base == "" {
+		var info struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := client.do("GET", client.repoPath(repo), nil, &info); err != nil {
+			return "", err
+		}
+		args.Base = info.DefaultBranch
+	}
+	if args.Base == args.Head {
+		return "", NewError(ErrInvalidInput, "head and base are both %s; commit to a new branch first", args.Head)
+	}
+
+	var created struct {
+		Number int    `json:"number"`
+		IID    int    `json:"iid"`
+		URL    string `json:"html_url"`
+		WebURL string `json:"web_url"`
+	}
+	if client.kind == "gitlab" {
+		title := args.Title
+		if args.Draft {
+			title = "Draft: " + title
+		}
+		err = client.do("POST", client.repoPath(repo)+"/merge_requests", map[string]any{
+			"source_branch": args.Head,
+			"target_branch": args.Base,
+			"title":         title,
+			"description":   args.Body,
+		}, &created)
+		created.Number, created.URL = created.IID, created.WebURL
+	} else {
+		err = client.do("POST", client.repoPath(repo)+"/pulls", map[string]any{
+			"title": args.Title,
+			"body":  args.Body,
+			"head":  args.Head,
+			"base":  args.Base,
+			"draft": args.Draft,
+		}, &created)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Opened #%d (%s into %s): %s", created.Number, args.Head, args.Base, created.URL), nil
+}
+
+// PRComment adds a comment to a pull request or merge request.
+func PRComment(input json.RawMessage) (string, error) {
+	var args PRCommentInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if args.Number <= 0 {
+		return "", NewError(ErrInvalidInput, "number is required")
+	}
+	if strings.TrimSpace(args.Body) == "" {
+		return "", NewError(ErrInvalidInput, "body is required")
+	}
+
+	repo, err := resolveRepo(args.Repo)
+	if err != nil {
+		return "", err
+	}
+	client, err := newForgeClient(repo.Host)
+	if err != nil {
+		return "", err
+	}
+
+	// Pull requests share the issue comment API on GitHub.
+	path := fmt.Sprintf("%s/issues/%d/comments", client.repoPath(repo), args.Number)
+	if client.kind == "gitlab" {
+		path = fmt.Sprintf("%s/merge_requests/%d/notes", client.repoPath(repo), args.Number)
+	}
+	var comment struct {
+		URL string `json:"html_url"`
+	}
+	if err := client.do("POST", path, map[string]string{"body": args.Body}, &comment); err != nil {
+		return "", err
+	}
+	result := fmt.Sprintf("Commented on #%d", args.Number)
+	if comment.URL != "" {
+		result += ": " + comment.URL
+	}
+	return result, nil
+}
+
+// forgeIssue holds the fields of a GitHub or GitLab issue that matter as
+// task context.
+type forgeIssue struct {
+	Title       string `json:"title"`
+	State       string `json:"state"`
+	Body        string `json:"body"`        // GitHub
+	Description string `json:"description"` // GitLab
+	URL         string `json:"html_url"`
+	WebURL      string `json:"web_url"`
+	User        struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	Labels json.RawMessage `json:"labels"` // objects on GitHub, strings on GitLab
+}
+
+type forgeComment struct {
+	Body string `json:"body"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	System bool `json:"system"` // GitLab's "changed the description" notes
+}
+
+// maxIssueComments caps how many comments issue_fetch includes.
+const maxIssueComments = 50
+
+// IssueFetch returns an issue's title, description and optionally its
+// comments as plain text.
+func IssueFetch(input json.RawMessage) (string, error) {
+	var args IssueFetchInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if args.Number <= 0 {
+		return "", NewError(ErrInvalidInput, "number is required")
+	}
+
+	repo, err := resolveRepo(args.Repo)
+	if err != nil {
+		return "", err
+	}
+	client, err := newForgeClient(repo.Host)
+	if err != nil {
+		return "", err
+	}
+
+	path := fmt.Sprintf("%s/issues/%d", client.repoPath(repo), args.Number)
+	var issue forgeIssue
+	if err := client.do("GET", path, nil, &issue); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s [%s]\n", args.Number, issue.Title, issue.State)
+	fmt.Fprintf(&b, "Author: %s\n", issue.User.Login+issue.Author.Username)
+	if labels := issueLabels(issue.Labels); len(labels) > 0 {
+		fmt.Fprintf(&b, "Labels: %s\n", strings.Join(labels, ", "))
+	}
+	fmt.Fprintf(&b, "URL: %s\n\n", issue.URL+issue.WebURL)
+	b.WriteString(strings.TrimSpace(issue.Body + issue.Description))
+	b.WriteString("\n")
+
+	if args.Comments {
+		commentsPath := fmt.Sprintf("%s/comments?per_page=%d", path, maxIssueComments)
+		if client.kind == "gitlab" {
+			commentsPath = fmt.Sprintf("%s/notes?sort=asc&per_page=%d", path, maxIssueComments)
+		}
+		var comments []forgeComment
+		if err := client.do("GET", commentsPath, nil, &comments); err != nil {
+			return "", err
+		}
+		for _, c := range comments {
+			if c.System {
+				continue
+			}
+			fmt.Fprintf(&b, "\n--- %s:\n%s\n", c.User.Login+c.Author.Username, strings.TrimSpace(c.Body))
+		}
+	}
+	return b.String(), nil
+}
+
+func issueLabels(raw json.RawMessage) []string {
+	var strs []string
+	if json.Unmarshal(raw, &strs) == nil {
+		return strs
+	}
+	var objects []struct {
+		Name string `json:"name"`
+	}
+	json.Unmarshal(raw, &objects)
+	var names []string
+	for _, o := range objects {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+// PRCreateTool is the tool definition for opening pull requests.
+var PRCreateTool = NewTool[PRCreateInput](
+	"gh_pr_create",
+	"Open a pull request (a merge request on GitLab) for a branch. Commit your changes to a branch other than the default one first, and set push to publish it. Returns the URL.",
+	PRCreate,
+)
+
+// PRCommentTool is the tool definition for commenting on pull requests.
+var PRCommentTool = NewTool[PRCommentInput](
+	"gh_pr_comment",
+	"Add a comment to a pull request (a merge request on GitLab).",
+	PRComment,
+)
+
+// IssueFetchTool is the tool definition for reading issues.
+var IssueFetchTool = NewTool[IssueFetchInput](
+	"issue_fetch",
+	"Fetch a GitHub or GitLab issue's title, description, labels and optionally comments, to use as context for a task.",
+	IssueFetch,
+).WithRetry(transientRetry)
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   forgeRepo
+	}{
+		{"https://github.com/jperrello/BRUTUS.git", forgeRepo{"github.com", "jperrello/BRUTUS"}},
+		{"git@github.com:jperrello/BRUTUS.git", forgeRepo{"github.com", "jperrello/BRUTUS"}},
+		{"ssh://git@gitlab.example.com:2222/group/sub/app.git", forgeRepo{"gitlab.example.com", "group/sub/app"}},
+		{"https://gitlab.com/group/app/", forgeRepo{"gitlab.com", "group/app"}},
+	}
+	for _, tt := range tests {
+		got, ok := parseRemote(tt.remote)
+		if !ok || got != tt.want {
+			t.Errorf("parseRemote(%q) = %+v, %v; want %+v", tt.remote, got, ok, tt.want)
+		}
+	}
+	for _, remote := range []string{"/srv/git/app.git", "https://github.com/onlyowner"} {
+		if got, ok := parseRemote(remote); ok {
+			t.Errorf("parseRemote(%q) = %+v, want failure", remote, got)
+		}
+	}
+}
+
+// fakeForge records requests and answers them from routes, keyed by
+// "METHOD path".
+func fakeForge(t *testing.T, host, kind string, routes map[string]string) *[]string {
+	t.Helper()
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		key := r.Method + " " + r.URL.EscapedPath()
+		seen = append(seen, key+" "+string(body))
+		if kind == "gitlab" && r.Header.Get("PRIVATE-TOKEN") != "secret" ||
+			kind == "github" && r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		resp, ok := routes[key]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	ConfigureForges(map[string]Forge{host: {Type: kind, Token: "secret", APIURL: srv.URL}})
+	t.Cleanup(func() { ConfigureForges(nil) })
+	return &seen
+}
+
+func TestPRCreateGitHub(t *testing.T) {
+	seen := fakeForge(t, "github.com", "github", map[string]string{
+		"GET /repos/o/r":        `{"default_branch":"main"}`,
+		"POST /repos/o/r/pulls": `{"number":7,"html_url":"https://github.com/o/r/pull/7"}`,
+	})
+
+	got, err := PRCreate(json.RawMessage(`{"title":"Fix it","head":"fix","repo":"github.com/o/r"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Opened #7 (fix into main): https://github.com/o/r/pull/7"; got != want {
+		t.Errorf("result = %q, want %q", got, want)
+	}
+	if last := (*seen)[len(*seen)-1]; !strings.Contains(last, `"base":"main"`) || !strings.Contains(last, `"head":"fix"`) {
+		t.Errorf("create request = %s", last)
+	}
+}
+
+func TestPRCommentGitLab(t *testing.T) {
+	fakeForge(t, "gitlab.example.com", "gitlab", map[string]string{
+		"POST /projects/group%2Fapp/merge_requests/3/notes": `{"id":1}`,
+	})
+
+	got, err := PRComment(json.RawMessage(`{"number":3,"body":"LGTM","repo":"gitlab.example.com/group/app"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Commented on #3" {
+		t.Errorf("result = %q", got)
+	}
+}
+
+func TestIssueFetch(t *testing.T) {
+	fakeForge(t, "github.com", "github", map[string]string{
+		"GET /repos/o/r/issues/12":          `{"title":"Crash on start","state":"open","body":"It panics.","html_url":"u","user":{"login":"ana"},"labels":[{"name":"bug"}]}`,
+		"GET /repos/o/r/issues/12/comments": `[{"body":"Same here","user":{"login":"bo"}}]`,
+	})
+
+	got, err := IssueFetch(json.RawMessage(`{"number":12,"comments":true,"repo":"github.com/o/r"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"#12 Crash on start [open]", "Author: ana", "Labels: bug", "It panics.", "--- bo:\nSame here"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("result missing %q:\n%s", want, got)
+		}
+	}
+
+	_, err = IssueFetch(json.RawMessage(`{"number":99,"repo":"github.com/o/r"}`))
+	if CodeOf(err) != ErrNotFound {
+		t.Errorf("missing issue: got %v, want not_found", err)
+	}
+}
+
+func TestForgeWithoutToken(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	ConfigureForges(nil)
+	_, err := IssueFetch(json.RawMessage(`{"number":1,"repo":"github.com/o/r"}`))
+	if CodeOf(err) != ErrPermissionDenied {
+		t.Errorf("got %v, want permission_denied", err)
+	}
+}
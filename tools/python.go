@@ -0,0 +1,345 @@
+package tools
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"brutus/internal/text"
+)
+
+// PythonExecInput defines the parameters for the python_exec tool.
+type PythonExecInput struct {
+	Code    string `json:"code" jsonschema_description:"Python code to run. Variables, imports and functions persist between calls. The value of a final expression is shown, as in a notebook."`
+	Timeout int    `json:"timeout_seconds,omitempty" jsonschema_description:"Seconds before the code is interrupted. Default 60, maximum 600."`
+	Reset   bool   `json:"reset,omitempty" jsonschema_description:"Start a fresh interpreter before running, discarding all state."`
+}
+
+const (
+	defaultPythonTimeout = 60 * time.Second
+	maxPythonTimeout     = 600 * time.Second
+
+	// pythonInterruptGrace is how long interrupted code has to stop before
+	// the interpreter is killed, losing its state.
+	pythonInterruptGrace = 5 * time.Second
+
+	// maxPythonOutput caps the printed output and result of one call.
+	maxPythonOutput = 20000
+
+	// maxPythonFigures caps how many matplotlib figures one call saves.
+	maxPythonFigures = 5
+)
+
+// pythonDriver runs inside the interpreter. It executes each request
+// against one namespace, captures what the code prints, saves matplotlib
+// figures, and answers on a line marked with the sentinel so output that
+// subprocesses write straight to the pipe can't be mistaken for it.
+const pythonDriver = `
+import ast, contextlib, io, json, os, signal, sys, traceback
+
+SENTINEL, FIGDIR, MAXFIG = sys.argv[1], sys.argv[2], int(sys.argv[3])
+proto_in, proto_out = sys.stdin, sys.stdout
+sys.stdin = io.StringIO()
+ns = {"__name__": "__main__"}
+saved = 0
+busy = False
+
+# Only interrupt user code; a late interrupt must not kill the loop.
+def on_interrupt(signum, frame):
+    if busy:
+        raise KeyboardInterrupt
+signal.signal(signal.SIGINT, on_interrupt)
+
+def figures():
+    global saved
+    plt = sys.modules.get("matplotlib.pyplot")
+    if plt is None:
+        return [], 0
+    paths, skipped = [], 0
+    for num in plt.get_fignums():
+        if len(paths) == MAXFIG:
+            skipped += 1
+            continue
+        saved += 1
+        path = os.path.join(FIGDIR, "figure-%d.png" % saved)
+        plt.figure(num).savefig(path)
+        paths.append(path)
+    plt.close("all")
+    return paths, skipped
+
+def run(code):
+    global busy
+    out = io.StringIO()
+    result = error = None
+    with contextlib.redirect_stdout(out), contextlib.redirect_stderr(out):
+        try:
+            busy = True
+            tree = ast.parse(code, "<cell>", "exec")
+            last = None
+            if tree.body and isinstance(tree.body[-1], ast.Expr):
+                last = ast.Expression(tree.body.pop().value)
+            exec(compile(tree, "<cell>", "exec"), ns)
+            if last is not None:
+                value = eval(compile(last, "<cell>", "eval"), ns)
+                if value is not None:
+                    result = repr(value)
+        except BaseException:
+            etype, value, tb = sys.exc_info()
+            error = "".join(traceback.format_exception(etype, value, tb.tb_next))
+        finally:
+            busy = False
+    paths, skipped = [], 0
+    try:
+        paths, skipped = figures()
+    except Exception as e:
+        error = (error or "") + "saving figures failed: %s\n" % e
+    return {"output": out.getvalue(), "result": result, "error": error, "figures": paths, "skipped_figures": skipped}
+
+for line in proto_in:
+    response = run(json.loads(line)["code"])
+    proto_out.write(SENTINEL + json.dumps(response) + "\n")
+    proto_out.flush()
+`
+
+// pythonResponse is the driver's answer to one request.
+type pythonResponse struct {
+	Output         string   `json:"output"`
+	Result         *string  `json:"result"`
+	Error          *string  `json:"error"`
+	Figures        []string `json:"figures"`
+	SkippedFigures int      `json:"skipped_figures"`
+}
+
+// PythonSession is a persistent Python interpreter, started on first use.
+// Each conversation gets its own through NewPythonExecTool.
+type PythonSession struct {
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan string   // interpreter output, closed when it exits
+	done     chan struct{} // closed by stop
+	sentinel string
+	figDir   string
+	env      commandEnv
+}
+
+// NewPythonExecTool returns a python_exec tool backed by a new session.
+// Its Close stops the interpreter.
+func NewPythonExecTool() Tool {
+	s := &PythonSession{}
+	t := NewTool[PythonExecInput](
+		"python_exec",
+		"Run Python code in a persistent interpreter, like a notebook cell: variables, imports and loaded data stay available to later calls in this conversation. Use it for data analysis and quick computations. Output is truncated to 20000 characters; matplotlib figures are saved as PNG files and their paths returned. Use bash for one-off scripts.",
+		s.Exec,
+	)
+	t.Close = s.Close
+	t.SetEnv = s.env.set
+	return t
+}
+
+// Exec runs one python_exec call.
+func (s *PythonSession) Exec(input json.RawMessage) (string, error) {
+	var args PythonExecInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args.Code) == "" {
+		return "", NewError(ErrInvalidInput, "code is required")
+	}
+	timeout := defaultPythonTimeout
+	if args.Timeout > 0 {
+		timeout = min(time.Duration(args.Timeout)*time.Second, maxPythonTimeout)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if args.Reset {
+		s.stop()
+	}
+	if s.cmd == nil {
+		if err := s.start(); err != nil {
+			return "", err
+		}
+	}
+
+	request, _ := json.Marshal(map[string]string{"code": args.Code})
+	if _, err := s.stdin.Write(append(request, '\n')); err != nil {
+		s.stop()
+		return "", WrapError(err, "python interpreter is gone; its state is lost, run again to start a new one")
+	}
+	return s.await(timeout)
+}
+
+// await collects output until the driver answers. Code that runs past
+// timeout is interrupted, and killed if that doesn't stop it.
+func (s *PythonSession) await(timeout time.Duration) (string, error) {
+	var raw strings.Builder
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	interrupted := false
+
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.stop()
+				return "", NewError(ErrInternal, "python interpreter exited; its state is lost. Output: %s", text.HeadTail(raw.String(), maxPythonOutput))
+			}
+			i := strings.Index(line, s.sentinel)
+			if i < 0 {
+				if raw.Len() < maxPythonOutput {
+					raw.WriteString(line)
+				}
+				continue
+			}
+			raw.WriteString(line[:i])
+			var resp pythonResponse
+			if err := json.Unmarshal([]byte(line[i+len(s.sentinel):]), &resp); err != nil {
+				return "", NewError(ErrInternal, "unexpected reply from the python driver: %v", err)
+			}
+			result := formatPythonResponse(raw.String(), resp)
+			if interrupted {
+				return "", NewError(ErrTimeout, "code ran longer than %s and was interrupted; the session state is kept. %s", timeout, result).
+					WithDetail("timeout_seconds", int(timeout/time.Second))
+			}
+			return result, nil
+
+		case <-timer.C:
+			if interrupted || runtime.GOOS == "windows" {
+				s.stop()
+				return "", NewError(ErrTimeout, "code ran longer than %s and would not stop; the interpreter was restarted and its state is lost. Output: %s",
+					timeout, text.HeadTail(raw.String(), maxPythonOutput)).WithDetail("timeout_seconds", int(timeout/time.Second))
+			}
+			interrupted = true
+			s.cmd.Process.Signal(os.Interrupt)
+			timer.Reset(pythonInterruptGrace)
+		}
+	}
+}
+
+func formatPythonResponse(raw string, resp pythonResponse) string {
+	var b strings.Builder
+	b.WriteString(raw)
+	b.WriteString(resp.Output)
+	if resp.Result != nil {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("Out: " + *resp.Result + "\n")
+	}
+	if resp.Error != nil {
+		b.WriteString("Error:\n" + *resp.Error)
+	}
+	result := text.HeadTail(strings.TrimRight(b.String(), "\n"), maxPythonOutput)
+
+	for _, path := range resp.Figures {
+		result += "\nFigure saved: " + path
+	}
+	if resp.SkippedFigures > 0 {
+		result += fmt.Sprintf("\n%d more figures not saved (limit %d per call)", resp.SkippedFigures, maxPythonFigures)
+	}
+	if result == "" {
+		result = "(no output)"
+	}
+	return result
+}
+
+// start launches the interpreter with the driver.
+func (s *PythonSession) start() error {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		if python, err = exec.LookPath("python"); err != nil {
+			return NewError(ErrNotFound, "python is not installed (looked for python3 and python)")
+		}
+	}
+	if s.figDir == "" {
+		if s.figDir, err = os.MkdirTemp("", "brutus-python-"); err != nil {
+			return WrapError(err, "cannot create a directory for figures")
+		}
+	}
+	var token [8]byte
+	rand.Read(token[:])
+	s.sentinel = "\x1e" + hex.EncodeToString(token[:]) + ":"
+
+	cmd := exec.Command(python, "-u", "-c", pythonDriver, s.sentinel, s.figDir, strconv.Itoa(maxPythonFigures))
+	// Variables set later apply when the interpreter next starts.
+	cmd.Env = s.env.environ("MPLBACKEND=Agg", "PYTHONIOENCODING=utf-8")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return WrapError(err, "cannot start python")
+	}
+	// One pipe for stdout and stderr, so what subprocesses print comes back
+	// in order. A file rather than a writer keeps Wait from waiting on
+	// subprocesses that outlive the interpreter.
+	output, pw, err := os.Pipe()
+	if err != nil {
+		return WrapError(err, "cannot start python")
+	}
+	cmd.Stdout, cmd.Stderr = pw, pw
+	err = cmd.Start()
+	pw.Close()
+	if err != nil {
+		output.Close()
+		return WrapError(err, "cannot start python")
+	}
+
+	lines := make(chan string, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(lines)
+		defer output.Close()
+		reader := bufio.NewReader(output)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				select {
+				case lines <- line:
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	s.cmd, s.stdin, s.lines, s.done = cmd, stdin, lines, done
+	return nil
+}
+
+// stop kills the interpreter, if one is running.
+func (s *PythonSession) stop() {
+	if s.cmd == nil {
+		return
+	}
+	close(s.done)
+	s.stdin.Close()
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+	s.cmd, s.stdin, s.lines, s.done = nil, nil, nil, nil
+}
+
+// Close stops the interpreter and removes saved figures.
+func (s *PythonSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	if s.figDir != "" {
+		err := os.RemoveAll(s.figDir)
+		s.figDir = ""
+		return err
+	}
+	return nil
+}
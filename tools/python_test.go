@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func pythonTool(t *testing.T) Tool {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	tool := NewPythonExecTool()
+	t.Cleanup(func() { tool.Close() })
+	return tool
+}
+
+func pythonRun(t *testing.T, tool Tool, in PythonExecInput) (string, error) {
+	t.Helper()
+	input, _ := json.Marshal(in)
+	return tool.Function(input)
+}
+
+func TestPythonExecKeepsState(t *testing.T) {
+	tool := pythonTool(t)
+
+	if result, err := pythonRun(t, tool, PythonExecInput{Code: "import math\nx = 20\nprint('set')"}); err != nil || result != "set" {
+		t.Fatalf("first call = %q, %v", result, err)
+	}
+	result, err := pythonRun(t, tool, PythonExecInput{Code: "x + math.floor(2.5)"})
+	if err != nil || result != "Out: 22" {
+		t.Fatalf("second call = %q, %v; want Out: 22", result, err)
+	}
+
+	result, err = pythonRun(t, tool, PythonExecInput{Code: "import os\nos.system('echo from-subprocess')\n1/0"})
+	if err != nil || !strings.Contains(result, "from-subprocess") || !strings.Contains(result, "ZeroDivisionError") {
+		t.Errorf("failing call = %q, %v; want subprocess output and the traceback", result, err)
+	}
+
+	result, err = pythonRun(t, tool, PythonExecInput{Code: "'x' in dir()", Reset: true})
+	if err != nil || result != "Out: False" {
+		t.Errorf("after reset = %q, %v; want Out: False", result, err)
+	}
+}
+
+func TestPythonExecTimeoutKeepsState(t *testing.T) {
+	tool := pythonTool(t)
+
+	_, err := pythonRun(t, tool, PythonExecInput{Code: "y = 1\nimport time\ntime.sleep(30)", Timeout: 1})
+	if CodeOf(err) != ErrTimeout || !strings.Contains(err.Error(), "KeyboardInterrupt") {
+		t.Fatalf("err = %v, want an interrupted timeout", err)
+	}
+	if result, err := pythonRun(t, tool, PythonExecInput{Code: "y"}); err != nil || result != "Out: 1" {
+		t.Errorf("after timeout = %q, %v; want the state kept", result, err)
+	}
+}
+
+func TestPythonExecTruncatesOutput(t *testing.T) {
+	tool := pythonTool(t)
+	result, err := pythonRun(t, tool, PythonExecInput{Code: "print('a' * 100000)"})
+	if err != nil || len(result) > maxPythonOutput+200 {
+		t.Errorf("result is %d bytes (err %v), want about %d", len(result), err, maxPythonOutput)
+	}
+}
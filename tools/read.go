@@ -1,35 +1,117 @@
 package tools
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+
+	"brutus/internal/text"
+)
+
+const (
+	// defaultReadLines is how many lines read_file returns when no limit
+	// is given.
+	defaultReadLines = 2000
+
+	// maxReadLineLength cuts lines longer than this many characters, as in
+	// minified files.
+	maxReadLineLength = 2000
 )
 
 // ReadFileInput defines the parameters for the read_file tool.
 // The jsonschema_description tag becomes the parameter description in the schema.
 type ReadFileInput struct {
-	Path string `json:"path" jsonschema_description:"The relative or absolute path to the file to read."`
+	Path   string `json:"path" jsonschema_description:"The relative or absolute path to the file to read."`
+	Offset int    `json:"offset,omitempty" jsonschema_description:"The line number to start reading from, counting from 1. Defaults to the start of the file."`
+	Limit  int    `json:"limit,omitempty" jsonschema_description:"The most lines to return. Defaults to 2000."`
 }
 
 // ReadFile reads and returns the contents of a file.
 // This is often the first tool an agent needs - you must understand code before modifying it.
+//
+// Lines are numbered, like cat -n, and at most Limit of them are returned
+// starting at Offset. When that isn't the whole file, a last line gives
+// the range shown and the file's line count, so the model can page on.
 func ReadFile(input json.RawMessage) (string, error) {
 	var args ReadFileInput
-	if err := json.Unmarshal(input, &args); err != nil {
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	if args.Path == "" {
+		return "", NewError(ErrInvalidInput, "path is required")
+	}
+	if err := checkPath(args.Path); err != nil {
 		return "", err
 	}
+	if args.Offset < 0 || args.Limit < 0 {
+		return "", NewError(ErrInvalidInput, "offset and limit must not be negative")
+	}
+	first := max(args.Offset, 1)
+	limit := args.Limit
+	if limit == 0 {
+		limit = defaultReadLines
+	}
 
-	content, err := os.ReadFile(args.Path)
+	info, err := os.Stat(args.Path)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return "", WrapError(err, "failed to read file").WithDetail("path", args.Path)
+	}
+	if info.IsDir() {
+		return "", NewError(ErrInvalidInput, "%s is a directory; use list_files", args.Path).WithDetail("path", args.Path)
 	}
-	return string(content), nil
+
+	f, err := os.Open(args.Path)
+	if err != nil {
+		return "", WrapError(err, "failed to read file").WithDetail("path", args.Path)
+	}
+	defer f.Close()
+
+	var out strings.Builder
+	r := bufio.NewReader(f)
+	total, last := 0, 0
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			total++
+			if total >= first && total < first+limit {
+				line = strings.TrimRight(line, "\r\n")
+				if cut := text.Head(line, maxReadLineLength); cut != line {
+					line = cut + " [line cut]"
+				}
+				fmt.Fprintf(&out, "%6d\t%s\n", total, line)
+				last = total
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", WrapError(err, "failed to read file").WithDetail("path", args.Path)
+		}
+	}
+
+	if first > total && total > 0 {
+		return "", NewError(ErrInvalidInput, "offset %d is past the end of the file, which has %d lines", first, total).
+			WithDetail("path", args.Path).
+			WithDetail("lines", total)
+	}
+	if first > 1 || last < total {
+		fmt.Fprintf(&out, "[Lines %d-%d of %d.", first, last, total)
+		if last < total {
+			fmt.Fprintf(&out, " Pass offset %d to read on.", last+1)
+		}
+		out.WriteString("]\n")
+	}
+	return out.String(), nil
 }
 
 // ReadFileTool is the tool definition for reading files.
 var ReadFileTool = NewTool[ReadFileInput](
 	"read_file",
-	"Read the contents of a file at the given path. Use this to examine source code, configuration files, or any text file.",
+	`Read the contents of a file at the given path. Use this to examine source code, configuration files, or any text file.
+Lines come numbered, up to 2000 at a time. For longer files the result ends with the range shown and the total line count; pass offset (and limit) to read further or to read just the part you need. The line numbers are not part of the file; leave them out of edit_file's old_str.`,
 	ReadFile,
-)
+).WithRetry(transientRetry).WithCache(readFileCacheKey)
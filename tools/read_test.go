@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestReadFilePages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+	read := func(offset, limit int) (string, error) {
+		input, _ := json.Marshal(ReadFileInput{Path: path, Offset: offset, Limit: limit})
+		return ReadFile(input)
+	}
+
+	whole, err := read(0, 0)
+	if err != nil || !strings.HasPrefix(whole, "     1\tline 1\n") || strings.Contains(whole, "[Lines") {
+		t.Errorf("whole file = %q, %v", whole, err)
+	}
+
+	page, err := read(4, 3)
+	want := "     4\tline 4\n     5\tline 5\n     6\tline 6\n[Lines 4-6 of 10. Pass offset 7 to read on.]\n"
+	if err != nil || page != want {
+		t.Errorf("page = %q, %v; want %q", page, err, want)
+	}
+
+	if _, err := read(11, 0); err == nil {
+		t.Error("expected an offset past the end to fail")
+	}
+}
+
+func TestReadFileLargePage(t *testing.T) {
+	// A page is bounded by its line count and line length, not by bytes.
+	path := filepath.Join(t.TempDir(), "big.txt")
+	line := strings.Repeat("x", 1000) + "\n"
+	os.WriteFile(path, []byte(strings.Repeat(line, 1500)), 0644)
+
+	input, _ := json.Marshal(ReadFileInput{Path: path})
+	out, err := ReadFile(input)
+	if err != nil || strings.Count(out, "\n") != 1500 || strings.Contains(out, "[Lines") {
+		t.Errorf("got %d lines, %v", strings.Count(out, "\n"), err)
+	}
+}
+
+func TestReadFileCutsLongLinesByRune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wide.txt")
+	os.WriteFile(path, []byte(strings.Repeat("€", maxReadLineLength+10)+"\n"), 0644)
+
+	input, _ := json.Marshal(ReadFileInput{Path: path})
+	out, err := ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(out) || !strings.Contains(out, "[line cut]") {
+		t.Errorf("got %d bytes, valid UTF-8 %v, want a cut marked as such", len(out), utf8.ValidString(out))
+	}
+	if n := strings.Count(out, "€"); n != maxReadLineLength {
+		t.Errorf("kept %d characters, want %d", n, maxReadLineLength)
+	}
+}
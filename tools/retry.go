@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how a failed tool call is retried before the error
+// is returned to the model. The zero value never retries, which is right
+// for tools with side effects such as bash and edit_file.
+type RetryPolicy struct {
+	MaxRetries int
+	Delay      time.Duration
+
+	// On lists the error codes worth retrying. Empty means ErrInternal and
+	// ErrTimeout; bad input or a missing file won't fix itself.
+	On []ErrorCode
+}
+
+var defaultRetryCodes = []ErrorCode{ErrInternal, ErrTimeout}
+
+// transientRetry is the policy of read-only tools: one more try shortly
+// after, to ride out hiccups like ripgrep exiting abnormally while files
+// change underneath it.
+var transientRetry = RetryPolicy{MaxRetries: 1, Delay: 100 * time.Millisecond}
+
+func (p RetryPolicy) retries(err error) bool {
+	codes := p.On
+	if len(codes) == 0 {
+		codes = defaultRetryCodes
+	}
+	return slices.Contains(codes, CodeOf(err))
+}
+
+// WithRetry returns a copy of t that is retried according to p.
+func (t Tool) WithRetry(p RetryPolicy) Tool {
+	t.Retry = p
+	return t
+}
+
+var (
+	retryMu        sync.RWMutex
+	retryOverrides map[string]RetryPolicy
+)
+
+// ConfigureRetries replaces the retry policies of the named tools,
+// overriding the defaults they were defined with.
+func ConfigureRetries(overrides map[string]RetryPolicy) {
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	retryOverrides = overrides
+}
+
+func (t Tool) retryPolicy() RetryPolicy {
+	retryMu.RLock()
+	defer retryMu.RUnlock()
+	if p, ok := retryOverrides[t.Name]; ok {
+		return p
+	}
+	return t.Retry
+}
+
+// Execute runs the tool, retrying failures its policy covers. If every
+// attempt fails, the last error is returned with an "attempts" detail.
+func (t Tool) Execute(ctx context.Context, input json.RawMessage) (string, error) {
+	policy := t.retryPolicy()
+
+	result, err := t.Call(ctx, input)
+	attempts := 1
+	for err != nil && attempts <= policy.MaxRetries && policy.retries(err) {
+		if policy.Delay > 0 {
+			timer := time.NewTimer(policy.Delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return result, err
+			}
+		} else if ctx.Err() != nil {
+			return result, err
+		}
+		result, err = t.Call(ctx, input)
+		attempts++
+	}
+
+	if err != nil && attempts > 1 {
+		var toolErr *ToolError
+		if !errors.As(err, &toolErr) {
+			toolErr = &ToolError{Code: CodeOf(err), Message: err.Error(), err: err}
+		}
+		err = toolErr.WithDetail("attempts", attempts)
+	}
+	return result, err
+}
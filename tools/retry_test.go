@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type flakyInput struct{}
+
+// flakyTool fails with errs in order, then succeeds.
+func flakyTool(policy RetryPolicy, errs ...error) (Tool, *int) {
+	calls := 0
+	tool := NewTool[flakyInput]("flaky", "fails a few times", func(json.RawMessage) (string, error) {
+		calls++
+		if calls <= len(errs) {
+			return "", errs[calls-1]
+		}
+		return "ok", nil
+	}).WithRetry(policy)
+	return tool, &calls
+}
+
+func TestExecuteRetriesTransientErrors(t *testing.T) {
+	tool, calls := flakyTool(RetryPolicy{MaxRetries: 1}, NewError(ErrInternal, "rg crashed"))
+	result, err := tool.Execute(context.Background(), nil)
+	if err != nil || result != "ok" || *calls != 2 {
+		t.Errorf("Execute = %q, %v after %d calls; want ok after 2", result, err, *calls)
+	}
+}
+
+func TestExecuteGivesUpAfterMaxRetries(t *testing.T) {
+	boom := errors.New("boom")
+	tool, calls := flakyTool(RetryPolicy{MaxRetries: 2}, boom, boom, boom, boom)
+	_, err := tool.Execute(context.Background(), nil)
+
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want ToolError wrapping boom", err)
+	}
+	if *calls != 3 || toolErr.Details["attempts"] != 3 || toolErr.Code != ErrInternal {
+		t.Errorf("calls = %d, details = %v, code = %s", *calls, toolErr.Details, toolErr.Code)
+	}
+}
+
+func TestExecuteSkipsNonRetryableCodes(t *testing.T) {
+	tool, calls := flakyTool(RetryPolicy{MaxRetries: 3}, NewError(ErrNotFound, "no such file"))
+	if _, err := tool.Execute(context.Background(), nil); CodeOf(err) != ErrNotFound || *calls != 1 {
+		t.Errorf("err = %v after %d calls, want not_found after 1", err, *calls)
+	}
+
+	tool, calls = flakyTool(RetryPolicy{MaxRetries: 1, On: []ErrorCode{ErrNotFound}}, NewError(ErrNotFound, "not yet"))
+	if _, err := tool.Execute(context.Background(), nil); err != nil || *calls != 2 {
+		t.Errorf("err = %v after %d calls, want success after 2", err, *calls)
+	}
+}
+
+func TestExecuteZeroPolicyNeverRetries(t *testing.T) {
+	tool, calls := flakyTool(RetryPolicy{}, NewError(ErrInternal, "exit 1"))
+	if _, err := tool.Execute(context.Background(), nil); err == nil || *calls != 1 {
+		t.Errorf("err = %v after %d calls, want failure after 1", err, *calls)
+	}
+	if BashTool.Retry.MaxRetries != 0 || EditFileTool.Retry.MaxRetries != 0 {
+		t.Error("tools with side effects must not retry by default")
+	}
+}
+
+func TestConfigureRetriesOverridesDefault(t *testing.T) {
+	ConfigureRetries(map[string]RetryPolicy{"flaky": {}})
+	t.Cleanup(func() { ConfigureRetries(nil) })
+
+	tool, calls := flakyTool(RetryPolicy{MaxRetries: 5}, NewError(ErrInternal, "once"))
+	if _, err := tool.Execute(context.Background(), nil); err == nil || *calls != 1 {
+		t.Errorf("err = %v after %d calls, want override to disable retries", err, *calls)
+	}
+}
+
+func TestExecuteStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tool, calls := flakyTool(RetryPolicy{MaxRetries: 3}, NewError(ErrInternal, "x"), NewError(ErrInternal, "x"))
+	if _, err := tool.Execute(ctx, nil); err == nil || *calls != 1 {
+		t.Errorf("err = %v after %d calls, want no retry once cancelled", err, *calls)
+	}
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The sandbox confines the file tools to a workspace. Until
+// ConfigureSandbox is called every path is allowed, as tests and embedders
+// of the package expect; the CLI and GUI always configure it.
+var (
+	sandboxMu    sync.RWMutex
+	sandboxOn    bool
+	sandboxRoot  string   // "" means the working directory at the time of the call
+	sandboxAllow []string // Further directories the tools may reach
+)
+
+// ConfigureSandbox confines read_file, list_files, code_search, edit_file,
+// multi_edit and apply_patch to root and the allow directories. An empty
+// root means the process's working directory, looked up on every call so
+// that a chdir moves the sandbox along. Relative allow entries are taken
+// relative to root.
+func ConfigureSandbox(root string, allow []string) {
+	sandboxMu.Lock()
+	defer sandboxMu.Unlock()
+	sandboxOn, sandboxRoot, sandboxAllow = true, root, allow
+}
+
+// checkPath returns a permission error unless path, resolved against the
+// working directory with symlinks followed, lies inside the sandbox. A
+// path that doesn't exist yet is judged by its nearest existing parent.
+func checkPath(path string) error {
+	sandboxMu.RLock()
+	on, root, allow := sandboxOn, sandboxRoot, sandboxAllow
+	sandboxMu.RUnlock()
+	if !on {
+		return nil
+	}
+	return confine(path, root, allow)
+}
+
+// confine returns a permission error unless path lies in root or one of
+// the allow directories, as checkPath describes. An empty root is the
+// working directory.
+func confine(path, root string, allow []string) error {
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return WrapError(err, "cannot find the working directory")
+		}
+		root = wd
+	}
+	root, err := resolvePath(root)
+	if err != nil {
+		return WrapError(err, "bad sandbox root").WithDetail("root", root)
+	}
+	target, err := resolvePath(path)
+	if err != nil {
+		return WrapError(err, "bad path").WithDetail("path", path)
+	}
+	if within(target, root) {
+		return nil
+	}
+	for _, dir := range allow {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		if dir, err := resolvePath(dir); err == nil && within(target, dir) {
+			return nil
+		}
+	}
+	return NewError(ErrPermissionDenied, "%s is outside the workspace %s; use paths inside it", path, root).
+		WithDetail("path", path)
+}
+
+// resolvePath makes path absolute and follows symlinks in the longest part
+// of it that exists.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if dir == filepath.Dir(dir) {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// within reports whether path is dir or lies below it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	dir := t.TempDir()
+	work, shared, outside := filepath.Join(dir, "work"), filepath.Join(dir, "shared"), filepath.Join(dir, "outside")
+	for _, d := range []string{work, shared, outside} {
+		os.Mkdir(d, 0755)
+		os.WriteFile(filepath.Join(d, "f.txt"), []byte("x\n"), 0644)
+	}
+	os.Symlink(outside, filepath.Join(work, "escape"))
+	t.Chdir(work)
+	ConfigureSandbox("", []string{"../shared"})
+	t.Cleanup(func() { sandboxOn = false })
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"f.txt", true},
+		{"new/dir/file.txt", true},
+		{filepath.Join(work, "f.txt"), true},
+		{"../shared/f.txt", true},
+		{"../outside/f.txt", false},
+		{filepath.Join(outside, "f.txt"), false},
+		{"escape/f.txt", false},
+		{"escape/new.txt", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		err := checkPath(tt.path)
+		if tt.ok && err != nil {
+			t.Errorf("checkPath(%q) = %v, want allowed", tt.path, err)
+		}
+		if !tt.ok && CodeOf(err) != ErrPermissionDenied {
+			t.Errorf("checkPath(%q) = %v, want permission denied", tt.path, err)
+		}
+	}
+
+	input, _ := json.Marshal(EditFileInput{Path: "../outside/f.txt", OldStr: "x", NewStr: "y"})
+	if _, err := EditFile(input); CodeOf(err) != ErrPermissionDenied {
+		t.Errorf("edit_file outside the workspace: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(outside, "f.txt")); string(data) != "x\n" {
+		t.Errorf("file outside the workspace was changed to %q", data)
+	}
+}
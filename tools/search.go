@@ -1,46 +1,59 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os/exec"
-	"runtime"
 	"strings"
 )
 
 // CodeSearchInput defines parameters for the code_search tool.
 type CodeSearchInput struct {
-	Pattern       string `json:"pattern" jsonschema_description:"The search pattern (regex supported with ripgrep)."`
-	Path          string `json:"path,omitempty" jsonschema_description:"Directory or file to search in. Defaults to current directory."`
-	FileType      string `json:"file_type,omitempty" jsonschema_description:"File extension to filter by (e.g., 'go', 'js', 'py')."`
-	CaseSensitive bool   `json:"case_sensitive,omitempty" jsonschema_description:"Whether the search is case sensitive. Default: false."`
+	Pattern        string `json:"pattern" jsonschema_description:"The search pattern, a regular expression."`
+	Path           string `json:"path,omitempty" jsonschema_description:"Directory or file to search in. Defaults to current directory."`
+	FileType       string `json:"file_type,omitempty" jsonschema_description:"File extension to filter by (e.g., 'go', 'js', 'py')."`
+	CaseSensitive  bool   `json:"case_sensitive,omitempty" jsonschema_description:"Whether the search is case sensitive. Default: false."`
+	IncludeIgnored bool   `json:"include_ignored,omitempty" jsonschema_description:"Also search files that .gitignore or .brutusignore exclude, and dependency directories such as node_modules."`
 }
 
-// CodeSearch finds patterns in code using ripgrep (or fallback).
+// CodeSearch finds patterns in code using ripgrep, or searchFiles where
+// ripgrep is not installed.
 // This is what ghuntley calls "the most sophisticated" tool - but it's just ripgrep.
 // The power comes from using existing tools, not building proprietary indexing.
-func CodeSearch(input json.RawMessage) (string, error) {
+func CodeSearch(ctx context.Context, input json.RawMessage) (string, error) {
 	var args CodeSearchInput
-	if err := json.Unmarshal(input, &args); err != nil {
+	if err := decodeInput(input, &args); err != nil {
 		return "", err
 	}
 
 	if args.Pattern == "" {
-		return "", fmt.Errorf("pattern is required")
+		return "", NewError(ErrInvalidInput, "pattern is required")
 	}
 
 	searchPath := "."
 	if args.Path != "" {
 		searchPath = args.Path
 	}
+	if err := checkPath(searchPath); err != nil {
+		return "", err
+	}
+
+	ignore := newIgnoreSet(searchPath, args.IncludeIgnored)
 
 	// Try ripgrep first (best option)
 	_, err := exec.LookPath("rg")
 	if err != nil {
-		return fallbackSearch(args.Pattern, searchPath, args.CaseSensitive)
+		return searchFiles(ctx, args, searchPath, ignore)
 	}
 
-	cmdArgs := []string{"--line-number", "--with-filename", "--color=never"}
+	// rg applies .gitignore itself; the output is filtered again so that
+	// .brutusignore and skipDirs count too, and so rules apply outside git
+	// repositories as list_files applies them.
+	cmdArgs := []string{"--line-number", "--with-filename", "--color=never", "--null", "--no-require-git"}
+	if args.IncludeIgnored {
+		cmdArgs = append(cmdArgs, "--no-ignore", "--hidden", "--glob", "!.git")
+	}
 
 	if !args.CaseSensitive {
 		cmdArgs = append(cmdArgs, "--ignore-case")
@@ -52,47 +65,50 @@ func CodeSearch(input json.RawMessage) (string, error) {
 
 	cmdArgs = append(cmdArgs, args.Pattern, searchPath)
 
-	cmd := exec.Command("rg", cmdArgs...)
+	cmd := exec.CommandContext(ctx, "rg", cmdArgs...)
 	output, err := cmd.Output()
 
+	if ctx.Err() != nil {
+		return "", interrupted(ctx, "the search")
+	}
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
 			return "No matches found", nil
 		}
-		return "", fmt.Errorf("search failed: %w", err)
+		return "", searchError(err)
 	}
 
-	return limitResults(string(output), 50), nil
+	return limitResults(dropIgnored(string(output), ignore), 50), nil
 }
 
-// fallbackSearch uses platform-native tools when ripgrep isn't available.
-func fallbackSearch(pattern, searchPath string, caseSensitive bool) (string, error) {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		args := []string{"/S", "/N"}
-		if !caseSensitive {
-			args = append(args, "/I")
+// dropIgnored removes matches in ignored files from search output whose
+// lines are "path\x00line:text", and puts the usual colon back after the
+// path.
+func dropIgnored(output string, ignore *ignoreSet) string {
+	var kept []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		path, rest, ok := strings.Cut(line, "\x00")
+		if !ok {
+			continue
 		}
-		args = append(args, "/C:"+pattern, searchPath+"\\*")
-		cmd = exec.Command("findstr", args...)
-	} else {
-		args := []string{"-r", "-n"}
-		if !caseSensitive {
-			args = append(args, "-i")
+		if !ignore.ignored(path, false) {
+			kept = append(kept, path+":"+rest)
 		}
-		args = append(args, pattern, searchPath)
-		cmd = exec.Command("grep", args...)
 	}
-
-	output, err := cmd.Output()
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
-			return "No matches found", nil
-		}
-		return "", fmt.Errorf("search failed: %w", err)
+	if len(kept) == 0 {
+		return "No matches found"
 	}
+	return strings.Join(kept, "\n")
+}
 
-	return limitResults(string(output), 50), nil
+// searchError reports a failed search. Exit status 2 from rg means
+// a bad pattern or path, which the model can fix.
+func searchError(err error) error {
+	if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 2 {
+		return NewError(ErrInvalidInput, "search failed: %s", strings.TrimSpace(string(exitError.Stderr))).
+			WithDetail("exit_code", 2)
+	}
+	return WrapError(err, "search failed")
 }
 
 // limitResults truncates output to a reasonable size.
@@ -109,9 +125,9 @@ func limitResults(output string, maxLines int) string {
 }
 
 // CodeSearchTool is the tool definition for code searching.
-var CodeSearchTool = NewTool[CodeSearchInput](
+var CodeSearchTool = NewToolCtx[CodeSearchInput](
 	"code_search",
 	`Search for patterns in code using ripgrep. Use this to find function definitions, variable usage, imports, or any text pattern across the codebase.
-Falls back to findstr on Windows if ripgrep is not available.`,
+Hidden files and files excluded by .gitignore or .brutusignore are skipped unless include_ignored is set; binary files always are.`,
 	CodeSearch,
-)
+).WithRetry(transientRetry).WithCache(codeSearchCacheKey)
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeSearchSkipsIgnored(t *testing.T) {
+	root := makeTree(t, ".git/HEAD")
+	for name, content := range map[string]string{
+		"main.go":             "needle\n",
+		"gen/out.go":          "needle\n",
+		"node_modules/x.js":   "needle\n",
+		"notes/private.md":    "needle\n",
+		".gitignore":          "gen/\n",
+		"notes/.brutusignore": "private.md\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	t.Chdir(root)
+
+	search := func(includeIgnored bool) string {
+		input, _ := json.Marshal(CodeSearchInput{Pattern: "needle", IncludeIgnored: includeIgnored})
+		out, err := CodeSearch(context.Background(), input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	if out := search(false); strings.Count(out, "needle") != 1 || !strings.Contains(out, "main.go:1:needle") {
+		t.Errorf("search found ignored files:\n%s", out)
+	}
+	if out := search(true); strings.Count(out, "needle") != 4 {
+		t.Errorf("include_ignored search:\n%s", out)
+	}
+}
+
+func TestSearchFiles(t *testing.T) {
+	root := makeTree(t)
+	for name, content := range map[string]string{
+		"a.go":         "func Needle() {}\nneedle := 1\n",
+		"b.txt":        "NEEDLE\n",
+		"bin.dat":      "needle\x00\x01",
+		".hidden/c.go": "needle\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	t.Chdir(root)
+
+	search := func(args CodeSearchInput) string {
+		out, err := searchFiles(context.Background(), args, ".", newIgnoreSet(".", args.IncludeIgnored))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	want := "a.go:1:func Needle() {}\na.go:2:needle := 1\nb.txt:1:NEEDLE"
+	if out := search(CodeSearchInput{Pattern: "needle"}); out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+	if out := search(CodeSearchInput{Pattern: `Needle\(`, CaseSensitive: true, FileType: "go"}); out != "a.go:1:func Needle() {}" {
+		t.Errorf("case-sensitive go search: %q", out)
+	}
+	if out := search(CodeSearchInput{Pattern: "needle", IncludeIgnored: true}); !strings.Contains(out, ".hidden/c.go:1:needle") || strings.Contains(out, "bin.dat") {
+		t.Errorf("include_ignored search:\n%s", out)
+	}
+	if _, err := searchFiles(context.Background(), CodeSearchInput{Pattern: "("}, ".", newIgnoreSet(".", false)); CodeOf(err) != ErrInvalidInput {
+		t.Errorf("bad pattern: %v", err)
+	}
+}
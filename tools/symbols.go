@@ -0,0 +1,292 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FindSymbolInput defines the parameters for the find_symbol tool.
+type FindSymbolInput struct {
+	Name string `json:"name" jsonschema_description:"The symbol to find, e.g. 'ReadFile', or 'Agent.Run' for a method of a particular type."`
+	Kind string `json:"kind,omitempty" jsonschema:"enum=function,enum=method,enum=type,enum=class" jsonschema_description:"Only return definitions of this kind."`
+	Path string `json:"path,omitempty" jsonschema_description:"Directory or file to search in. Defaults to current directory."`
+}
+
+// maxSymbols caps how many definitions find_symbol returns.
+const maxSymbols = 50
+
+// symbol is one definition.
+type symbol struct {
+	file      string
+	line      int
+	kind      string // function, method, type or class
+	name      string
+	receiver  string // The type a method belongs to, if known
+	signature string
+}
+
+// symbolLanguages maps file extensions to the pattern-based extractors for
+// languages other than Go, which is parsed properly with go/ast.
+var symbolLanguages = map[string][]symbolPattern{
+	".py": pySymbols,
+	".js": jsSymbols, ".jsx": jsSymbols, ".mjs": jsSymbols, ".cjs": jsSymbols,
+	".ts": jsSymbols, ".tsx": jsSymbols,
+	".rs": rustSymbols,
+}
+
+// symbolPattern finds one kind of definition on a line; the name is the
+// pattern's last group. An indented match is a method when method is set.
+// A pattern without a kind only names the type the methods below it
+// belong to, as Rust's impl blocks do.
+type symbolPattern struct {
+	re     *regexp.Regexp
+	kind   string
+	method bool
+}
+
+var (
+	pySymbols = []symbolPattern{
+		{regexp.MustCompile(`^(\s*)(?:async\s+)?def\s+(\w+)\s*\(`), "function", true},
+		{regexp.MustCompile(`^(\s*)class\s+(\w+)`), "class", false},
+	}
+	jsSymbols = []symbolPattern{
+		{regexp.MustCompile(`^(\s*)(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)`), "function", false},
+		{regexp.MustCompile(`^(\s*)(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(\w+)`), "class", false},
+		{regexp.MustCompile(`^(\s*)(?:export\s+)?(?:interface|type|enum)\s+(\w+)`), "type", false},
+		{regexp.MustCompile(`^(\s*)(?:export\s+)?(?:const|let|var)\s+(\w+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|\w+\s*=>)`), "function", false},
+		{regexp.MustCompile(`^(\s+)(?:(?:public|private|protected|static|async|get|set|readonly)\s+)*(\w+)\s*\([^)]*\)\s*(?::[^{]+)?\{\s*$`), "method", true},
+	}
+	rustSymbols = []symbolPattern{
+		{regexp.MustCompile(`^(\s*)(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(\w+)`), "function", true},
+		{regexp.MustCompile(`^(\s*)(?:pub(?:\([^)]*\))?\s+)?(?:struct|enum|trait|type|union)\s+(\w+)`), "type", false},
+		{regexp.MustCompile(`^()(?:unsafe\s+)?impl(?:<[^>]*>)?\s+(?:[\w:<>, ]+\s+for\s+)?(\w+)`), "", false},
+	}
+
+	// jsKeywords look like method definitions to jsSymbols' last pattern.
+	jsKeywords = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "catch": true, "function": true, "return": true}
+)
+
+// FindSymbol finds where functions, methods and types are defined. Go
+// files are parsed; Python, JavaScript/TypeScript and Rust are read line
+// by line, which finds ordinary definitions but can be fooled by unusual
+// layouts.
+func FindSymbol(input json.RawMessage) (string, error) {
+	var args FindSymbolInput
+	if err := decodeInput(input, &args); err != nil {
+		return "", err
+	}
+	args.Name = strings.TrimSpace(args.Name)
+	if args.Name == "" {
+		return "", NewError(ErrInvalidInput, "name is required")
+	}
+	root := "."
+	if args.Path != "" {
+		root = args.Path
+	}
+	if err := checkPath(root); err != nil {
+		return "", err
+	}
+	receiver, name, ok := strings.Cut(args.Name, ".")
+	if !ok {
+		receiver, name = "", args.Name
+	}
+
+	var found []symbol
+	ignore := newIgnoreSet(root, false)
+	scanned := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if path != root && (strings.HasPrefix(d.Name(), ".") || ignore.ignored(path, d.IsDir())) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != ".go" && symbolLanguages[ext] == nil {
+			return nil
+		}
+		if scanned++; scanned > maxDepsFiles {
+			return filepath.SkipAll
+		}
+		// Only read files that mention the name at all.
+		content, err := os.ReadFile(path)
+		if err != nil || len(content) > maxScanFileSize || !bytes.Contains(content, []byte(name)) {
+			return nil
+		}
+		var symbols []symbol
+		if ext == ".go" {
+			symbols = goSymbols(path, content)
+		} else {
+			symbols = patternSymbols(path, content, symbolLanguages[ext])
+		}
+		for _, s := range symbols {
+			if s.name == name && (receiver == "" || s.receiver == receiver) && (args.Kind == "" || s.kind == args.Kind) {
+				found = append(found, s)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", WrapError(err, "failed to search for symbols").WithDetail("path", root)
+	}
+
+	if len(found) == 0 {
+		return fmt.Sprintf("No definition of %s found. code_search can look for other mentions of it.", args.Name), nil
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].file != found[j].file {
+			return found[i].file < found[j].file
+		}
+		return found[i].line < found[j].line
+	})
+	var b strings.Builder
+	for i, s := range found {
+		if i == maxSymbols {
+			fmt.Fprintf(&b, "... (showing %d of %d definitions; pass a narrower path or a kind)\n", maxSymbols, len(found))
+			break
+		}
+		fmt.Fprintf(&b, "%s:%d: %s", filepath.ToSlash(s.file), s.line, s.signature)
+		if s.receiver != "" && !strings.Contains(s.signature, s.receiver) {
+			fmt.Fprintf(&b, " (in %s)", s.receiver)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// goSymbols parses a Go file and returns its functions, methods and types,
+// each with its signature as written.
+func goSymbols(path string, content []byte) []symbol {
+	fset := token.NewFileSet()
+	// A file with syntax errors still yields the declarations that parsed.
+	file, _ := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+	source := func(from, to token.Pos) string {
+		start, end := fset.Position(from).Offset, fset.Position(to).Offset
+		if start < 0 || end > len(content) || start >= end {
+			return ""
+		}
+		return strings.Join(strings.Fields(string(content[start:end])), " ")
+	}
+
+	var symbols []symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := symbol{file: path, line: fset.Position(d.Pos()).Line, kind: "function", name: d.Name.Name}
+			end := d.End()
+			if d.Body != nil {
+				end = d.Body.Lbrace
+			}
+			s.signature = source(d.Pos(), end)
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.kind = "method"
+				s.receiver = receiverName(d.Recv.List[0].Type)
+			}
+			symbols = append(symbols, s)
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				signature := "type " + source(ts.Pos(), ts.End())
+				switch ts.Type.(type) {
+				case *ast.StructType:
+					signature = "type " + ts.Name.Name + " struct"
+				case *ast.InterfaceType:
+					signature = "type " + ts.Name.Name + " interface"
+				}
+				symbols = append(symbols, symbol{file: path, line: fset.Position(ts.Pos()).Line, kind: "type", name: ts.Name.Name, signature: signature})
+			}
+		}
+	}
+	return symbols
+}
+
+// receiverName returns the type name of a method receiver such as *T or
+// T[K].
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// patternSymbols finds definitions line by line. The receiver of an
+// indented method is the class or type most recently defined at a lower
+// indentation.
+func patternSymbols(path string, content []byte, patterns []symbolPattern) []symbol {
+	var symbols []symbol
+	var container string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, maxScanFileSize)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		for _, p := range patterns {
+			m := p.re.FindStringSubmatch(line)
+			if m == nil || jsKeywords[m[len(m)-1]] {
+				continue
+			}
+			s := symbol{file: path, line: n, kind: p.kind, name: m[len(m)-1]}
+			s.signature = strings.TrimRight(strings.TrimSuffix(strings.TrimSpace(line), "{}"), "{:= ")
+			indented := m[1] != ""
+			switch {
+			case p.kind == "":
+				container = s.name
+			case indented && p.method:
+				s.kind, s.receiver = "method", container
+			case !indented:
+				container = ""
+				if p.kind == "class" || p.kind == "type" {
+					container = s.name
+				}
+			}
+			if s.kind != "" {
+				symbols = append(symbols, s)
+			}
+			break
+		}
+	}
+	return symbols
+}
+
+// FindSymbolTool is the tool definition for jumping to definitions.
+var FindSymbolTool = NewTool[FindSymbolInput](
+	"find_symbol",
+	`Find where a function, method, type or class is defined, with its file, line and signature. Faster and more precise than code_search for jumping to a definition.
+Go code is parsed; Python, JavaScript/TypeScript and Rust definitions are recognised by their usual forms. Use 'Type.Method' to pick one type's method.`,
+	FindSymbol,
+).WithRetry(transientRetry)
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func findSymbol(t *testing.T, args FindSymbolInput) string {
+	t.Helper()
+	input, _ := json.Marshal(args)
+	out, err := FindSymbol(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestFindSymbol(t *testing.T) {
+	root := makeTree(t)
+	for name, content := range map[string]string{
+		"agent.go":   "package a\n\ntype Agent struct{ n int }\n\n// Run runs.\nfunc (a *Agent) Run(ctx context.Context,\n\tinput string) error {\n\treturn nil\n}\n\nfunc Run() {}\n",
+		"tool.py":    "class Agent:\n    def run(self, x):\n        pass\n\ndef run():\n    pass\n",
+		"web/app.ts": "export class Agent {\n  async run(x: number): Promise<void> {\n    if (x) {\n    }\n  }\n}\nexport const run = async (x) => x\n",
+		"lib.rs":     "pub struct Agent;\n\nimpl Agent {\n    pub fn run(&self) {}\n}\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	t.Chdir(root)
+
+	tests := []struct {
+		args FindSymbolInput
+		want string
+	}{
+		{FindSymbolInput{Name: "Run"}, "agent.go:6: func (a *Agent) Run(ctx context.Context, input string) error\nagent.go:11: func Run()"},
+		{FindSymbolInput{Name: "Agent.Run"}, "agent.go:6: func (a *Agent) Run(ctx context.Context, input string) error"},
+		{FindSymbolInput{Name: "Agent", Kind: "type"}, "agent.go:3: type Agent struct\nlib.rs:1: pub struct Agent;"},
+		{FindSymbolInput{Name: "Agent.run"}, "lib.rs:4: pub fn run(&self) (in Agent)\ntool.py:2: def run(self, x) (in Agent)\nweb/app.ts:2: async run(x: number): Promise<void> (in Agent)"},
+		{FindSymbolInput{Name: "run", Kind: "function"}, "tool.py:5: def run()\nweb/app.ts:7: export const run = async (x) => x"},
+		{FindSymbolInput{Name: "Missing"}, "No definition of Missing found. code_search can look for other mentions of it."},
+	}
+	for _, tt := range tests {
+		if got := findSymbol(t, tt.args); got != tt.want {
+			t.Errorf("%+v:\ngot:\n%s\nwant:\n%s", tt.args, got, tt.want)
+		}
+	}
+}
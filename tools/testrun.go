@@ -0,0 +1,469 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"brutus/internal/text"
+)
+
+const (
+	// defaultTestTimeout and maxTestTimeout bound run_tests' timeout
+	// parameter, in seconds.
+	defaultTestTimeout = 600
+	maxTestTimeout     = 3600
+
+	// maxTestFailures caps how many failing tests run_tests details.
+	maxTestFailures = 20
+
+	// maxFailureOutput caps the output kept for each failing test, and
+	// maxTestOutput the raw output returned when no results are recognised.
+	maxFailureOutput = 2000
+	maxTestOutput    = 8000
+)
+
+// RunTestsInput defines the parameters for the run_tests tool.
+type RunTestsInput struct {
+	Command string `json:"command,omitempty" jsonschema_description:"Command line to run. Defaults to the test command that has worked in this project before, or else the usual one for the project: go test, cargo test, pytest or npm test."`
+	Timeout int    `json:"timeout,omitempty" jsonschema_description:"Seconds to let the tests run before stopping them (default 600, at most 3600)."`
+}
+
+// testReport is what was recognised in a test run's output.
+type testReport struct {
+	framework               string
+	passed, failed, skipped int
+	failures                []testFailure
+}
+
+// testFailure is one failing test and the output that explains it.
+type testFailure struct {
+	name   string
+	output string
+}
+
+// testParsers recognise the output of each supported test runner. The
+// first to recognise a summary is used.
+var testParsers = []func(string) (testReport, bool){
+	parseGoTestJSON,
+	parsePytest,
+	parseCargoTest,
+	parseJest,
+	parseVitest,
+}
+
+// NewRunTestsTool returns run_tests, which runs the project's tests and
+// reports counts, the failing tests and what each printed, rather than
+// the whole log. Without a command it runs the test command learned from
+// earlier sessions, or the usual one for the project.
+func NewRunTestsTool(learned *LearnedCommands) Tool {
+	env := &commandEnv{}
+	t := NewToolCtx[RunTestsInput](
+		"run_tests",
+		`Run the project's tests and get a summary: how many passed, failed and were skipped, which tests failed and the output of each failure.
+Without a command, runs the test command that has worked in this project before, or the usual one for the project (go test, cargo test, pytest, npm test). go test commands run with -json so that each test is counted.`,
+		func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args RunTestsInput
+			if err := decodeInput(input, &args); err != nil {
+				return "", err
+			}
+			command := strings.TrimSpace(args.Command)
+			if command == "" {
+				command = learned.Best(KindTest)
+			}
+			if command == "" {
+				command = detectTestCommand()
+			}
+			if command == "" {
+				return "", NewError(ErrInvalidInput, "no test command has worked in this project yet and none is known for it; pass one in command")
+			}
+			timeout := defaultTestTimeout
+			if args.Timeout > 0 {
+				timeout = min(args.Timeout, maxTestTimeout)
+			}
+
+			run := withGoTestJSON(command)
+			runCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+			defer cancel()
+			cmd := ShellCommand(runCtx, run)
+			cmd.Env = env.environ()
+			start := time.Now()
+			output, err := cmd.CombinedOutput()
+			elapsed := time.Since(start).Round(100 * time.Millisecond)
+
+			var status string
+			var exitErr *exec.ExitError
+			switch {
+			case ctx.Err() != nil:
+				return "", interrupted(ctx, "the tests").WithDetail("output", text.HeadTail(string(output), 2000))
+			case runCtx.Err() != nil:
+				status = fmt.Sprintf("TIMED OUT after %ds", timeout)
+			case err == nil:
+				status = "PASSED"
+				learned.Observe(command)
+			case errors.As(err, &exitErr):
+				status = fmt.Sprintf("FAILED (exit status %d)", exitErr.ExitCode())
+			default:
+				return "", WrapError(err, "failed to run %s", run)
+			}
+			return formatTestRun(run, status, elapsed, string(output)), nil
+		},
+	)
+	t.SetEnv = env.set
+	return t
+}
+
+// detectTestCommand guesses the test command from the project files in the
+// working directory, or returns "".
+func detectTestCommand() string {
+	exists := func(name string) bool {
+		_, err := os.Stat(name)
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return "go test ./..."
+	case exists("Cargo.toml"):
+		return "cargo test"
+	}
+	if data, err := os.ReadFile("package.json"); err == nil {
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		// npm init's placeholder script only fails.
+		if json.Unmarshal(data, &pkg) == nil && pkg.Scripts["test"] != "" && !strings.Contains(pkg.Scripts["test"], "no test specified") {
+			return "npm test"
+		}
+	}
+	for _, name := range []string{"pytest.ini", "pyproject.toml", "setup.cfg", "tox.ini", "conftest.py", "setup.py"} {
+		if exists(name) {
+			if runtime.GOOS == "windows" {
+				return "python -m pytest"
+			}
+			return "python3 -m pytest"
+		}
+	}
+	return ""
+}
+
+// goTestRe finds a go test command that could take -json: the last step
+// of a && chain, with no pipe after it.
+var goTestRe = regexp.MustCompile(`(^|&&\s*)go test\b`)
+
+// withGoTestJSON adds -json to a go test command so that each test's
+// result can be read, unless it has it or its output is piped elsewhere.
+func withGoTestJSON(command string) string {
+	if strings.Contains(command, "-json") || strings.Contains(command, "|") {
+		return command
+	}
+	loc := goTestRe.FindAllStringIndex(command, -1)
+	if len(loc) == 0 {
+		return command
+	}
+	end := loc[len(loc)-1][1]
+	return command[:end] + " -json" + command[end:]
+}
+
+// formatTestRun writes the result of a run: its status, the counts and
+// failures recognised, and the raw output when nothing explains a failure.
+func formatTestRun(command, status string, elapsed time.Duration, output string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ %s\n%s in %s", command, status, elapsed)
+
+	var report testReport
+	recognised := false
+	for _, parse := range testParsers {
+		if report, recognised = parse(output); recognised {
+			break
+		}
+	}
+	if recognised {
+		fmt.Fprintf(&b, ": %d passed, %d failed, %d skipped (%s)\n", report.passed, report.failed, report.skipped, report.framework)
+	} else {
+		b.WriteString("\n")
+	}
+
+	for i, f := range report.failures {
+		if i == maxTestFailures {
+			fmt.Fprintf(&b, "\n... and %d more failing tests\n", len(report.failures)-maxTestFailures)
+			break
+		}
+		fmt.Fprintf(&b, "\n--- FAIL %s\n", f.name)
+		if out := strings.TrimRight(strings.TrimLeft(f.output, "\r\n"), " \t\r\n"); out != "" {
+			b.WriteString(text.HeadTail(out, maxFailureOutput) + "\n")
+		}
+	}
+
+	if !recognised || (status != "PASSED" && len(report.failures) == 0) {
+		if !recognised {
+			b.WriteString("No test results recognised; the output follows.\n")
+		}
+		b.WriteString("\n" + text.HeadTail(strings.TrimSpace(output), maxTestOutput))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// addCounts adds "3 passed, 1 failed, 2 skipped" style counts to r.
+func (r *testReport) addCounts(summary string) {
+	for _, m := range testCountRe.FindAllStringSubmatch(summary, -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "passed", "passing", "xpassed":
+			r.passed += n
+		case "failed", "failing", "error", "errors":
+			r.failed += n
+		default:
+			r.skipped += n
+		}
+	}
+}
+
+var testCountRe = regexp.MustCompile(`(\d+) (passed|passing|xpassed|failed|failing|errors?|skipped|ignored|pending|todo|xfailed)\b`)
+
+// goTestEvent is a line of go test -json output.
+type goTestEvent struct {
+	Action     string
+	Package    string
+	ImportPath string // For build-output events
+	Test       string
+	Output     string
+}
+
+func parseGoTestJSON(output string) (testReport, bool) {
+	r := testReport{framework: "go test"}
+	outputs := map[string]*strings.Builder{} // By package, and by package and test
+	failedTests := map[string]bool{}         // Packages with a failing test
+	var stray strings.Builder                // Lines that aren't events, such as build errors
+	var packageFailures []string
+	seen := false
+	write := func(key, s string) {
+		if outputs[key] == nil {
+			outputs[key] = &strings.Builder{}
+		}
+		outputs[key].WriteString(s)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		var e goTestEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &e) != nil || e.Action == "" {
+			if strings.TrimSpace(line) != "" {
+				stray.WriteString(line + "\n")
+			}
+			continue
+		}
+		seen = true
+		key := e.Package
+		if e.Test != "" {
+			key += " " + e.Test
+		}
+		switch e.Action {
+		case "output":
+			write(key, e.Output)
+		case "build-output":
+			// "pkg [pkg.test]" builds pkg's tests.
+			pkg, _, _ := strings.Cut(e.ImportPath, " ")
+			write(pkg, e.Output)
+		case "pass":
+			if e.Test != "" {
+				r.passed++
+			}
+		case "skip":
+			if e.Test != "" {
+				r.skipped++
+			}
+		case "fail":
+			if e.Test == "" {
+				packageFailures = append(packageFailures, e.Package)
+				continue
+			}
+			r.failed++
+			failedTests[e.Package] = true
+			r.failures = append(r.failures, testFailure{name: key, output: goTestOutput(outputs[key])})
+		}
+	}
+	if !seen {
+		return r, false
+	}
+
+	// A failing subtest fails its parent too; only the subtest is shown.
+	var failures []testFailure
+	for _, f := range r.failures {
+		parent := false
+		for _, other := range r.failures {
+			if strings.HasPrefix(other.name, f.name+"/") {
+				parent = true
+			}
+		}
+		if !parent {
+			failures = append(failures, f)
+		}
+	}
+	// A package that failed with no failing test did not build, or
+	// failed outside its tests.
+	for _, pkg := range packageFailures {
+		if !failedTests[pkg] {
+			out := goTestOutput(outputs[pkg])
+			if out == "" {
+				out = stray.String()
+			}
+			failures = append(failures, testFailure{name: pkg, output: out})
+		}
+	}
+	r.failures = failures
+	return r, true
+}
+
+// goTestOutput returns what a test printed, without the === RUN and
+// similar lines go test adds.
+func goTestOutput(b *strings.Builder) string {
+	if b == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- FAIL") || trimmed == "FAIL" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	pytestSummaryRe = regexp.MustCompile(`(?m)^=+ (.*\d+ (?:passed|failed|skipped|errors?|xfailed|xpassed).*?) in [\d.]+s.*=+$`)
+	pytestFailedRe  = regexp.MustCompile(`(?m)^(?:FAILED|ERROR) (\S+)(?: - (.*))?$`)
+	pytestSectionRe = regexp.MustCompile(`(?m)^_{3,} (.+?) _{3,}$`)
+)
+
+func parsePytest(output string) (testReport, bool) {
+	r := testReport{framework: "pytest"}
+	m := pytestSummaryRe.FindStringSubmatch(output)
+	if m == nil {
+		return r, false
+	}
+	r.addCounts(m[1])
+
+	// Failure details come in sections headed "____ TestClass.test_x ____".
+	sections := map[string]string{}
+	locs := pytestSectionRe.FindAllStringSubmatchIndex(output, -1)
+	for i, loc := range locs {
+		end := len(output)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		body := output[loc[1]:end]
+		if cut := strings.Index(body, "\n=="); cut >= 0 {
+			body = body[:cut]
+		}
+		sections[output[loc[2]:loc[3]]] = body
+	}
+	for _, f := range pytestFailedRe.FindAllStringSubmatch(output, -1) {
+		id := f[1]
+		parts := strings.Split(id, "::")
+		detail := sections[strings.Join(parts[1:], ".")]
+		if detail == "" {
+			detail = f[2]
+		}
+		r.failures = append(r.failures, testFailure{name: id, output: detail})
+	}
+	return r, true
+}
+
+var (
+	cargoResultRe = regexp.MustCompile(`(?m)^test result: \w+\. (.*)$`)
+	cargoFailedRe = regexp.MustCompile(`(?m)^test (\S+) \.\.\. FAILED$`)
+	cargoOutputRe = regexp.MustCompile(`(?m)^---- (\S+) stdout ----$`)
+	cargoEndRe    = regexp.MustCompile(`(?m)^(----|failures:$)`)
+)
+
+func parseCargoTest(output string) (testReport, bool) {
+	r := testReport{framework: "cargo test"}
+	results := cargoResultRe.FindAllStringSubmatch(output, -1)
+	if results == nil {
+		return r, false
+	}
+	for _, m := range results {
+		r.addCounts(m[1])
+	}
+	sections := map[string]string{}
+	locs := cargoOutputRe.FindAllStringSubmatchIndex(output, -1)
+	for _, loc := range locs {
+		body := output[loc[1]:]
+		if cut := cargoEndRe.FindStringIndex(body); cut != nil {
+			body = body[:cut[0]]
+		}
+		sections[output[loc[2]:loc[3]]] = body
+	}
+	for _, m := range cargoFailedRe.FindAllStringSubmatch(output, -1) {
+		r.failures = append(r.failures, testFailure{name: m[1], output: sections[m[1]]})
+	}
+	return r, true
+}
+
+var (
+	jestSummaryRe = regexp.MustCompile(`(?m)^Tests:\s+(.*)\d+ total`)
+	jestFailureRe = regexp.MustCompile(`(?m)^\s*● (.+)$`)
+)
+
+func parseJest(output string) (testReport, bool) {
+	r := testReport{framework: "jest"}
+	m := jestSummaryRe.FindStringSubmatch(output)
+	if m == nil {
+		return r, false
+	}
+	r.addCounts(m[1])
+	locs := jestFailureRe.FindAllStringSubmatchIndex(output, -1)
+	for i, loc := range locs {
+		name := output[loc[2]:loc[3]]
+		if strings.HasPrefix(name, "Test suite failed to run") {
+			name = "(suite) " + name
+		}
+		end := len(output)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		body := output[loc[1]:end]
+		if cut := strings.Index(body, "\nTest Suites:"); cut >= 0 {
+			body = body[:cut]
+		}
+		r.failures = append(r.failures, testFailure{name: name, output: body})
+	}
+	return r, true
+}
+
+var (
+	vitestSummaryRe = regexp.MustCompile(`(?m)^\s*Tests\s+(.*)\(\d+\)\s*$`)
+	vitestFailureRe = regexp.MustCompile(`(?m)^\s*FAIL\s+(.+ > .+)$`)
+	vitestEndRe     = regexp.MustCompile(`(?m)^\s*(⎯{3,}|Test Files)`)
+)
+
+func parseVitest(output string) (testReport, bool) {
+	r := testReport{framework: "vitest"}
+	m := vitestSummaryRe.FindStringSubmatch(output)
+	if m == nil {
+		return r, false
+	}
+	r.addCounts(m[1])
+	locs := vitestFailureRe.FindAllStringSubmatchIndex(output, -1)
+	for i, loc := range locs {
+		end := len(output)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		body := output[loc[1]:end]
+		if cut := vitestEndRe.FindStringIndex(body); cut != nil {
+			body = body[:cut[0]]
+		}
+		r.failures = append(r.failures, testFailure{name: strings.TrimSpace(output[loc[2]:loc[3]]), output: body})
+	}
+	return r, true
+}
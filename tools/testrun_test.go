@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithGoTestJSON(t *testing.T) {
+	for command, want := range map[string]string{
+		"go test ./...":                  "go test -json ./...",
+		"cd sdk && go test -run TestX .": "cd sdk && go test -json -run TestX .",
+		"go test -json ./...":            "go test -json ./...",
+		"go test ./... | tail":           "go test ./... | tail",
+		"npm test":                       "npm test",
+	} {
+		if got := withGoTestJSON(command); got != want {
+			t.Errorf("withGoTestJSON(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestDetectTestCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if got := detectTestCommand(); got != "" {
+		t.Errorf("empty directory: %q", got)
+	}
+	os.WriteFile("package.json", []byte(`{"scripts": {"test": "echo \"Error: no test specified\" && exit 1"}}`), 0644)
+	if got := detectTestCommand(); got != "" {
+		t.Errorf("placeholder test script: %q", got)
+	}
+	os.WriteFile("package.json", []byte(`{"scripts": {"test": "jest"}}`), 0644)
+	if got := detectTestCommand(); got != "npm test" {
+		t.Errorf("package.json: %q", got)
+	}
+	os.WriteFile("go.mod", []byte("module x\n"), 0644)
+	if got := detectTestCommand(); got != "go test ./..." {
+		t.Errorf("go.mod: %q", got)
+	}
+}
+
+func TestFormatTestRun(t *testing.T) {
+	tests := []struct {
+		name, output string
+		want         []string
+	}{
+		{
+			"go",
+			`{"Action":"run","Package":"x","Test":"TestA"}
+{"Action":"pass","Package":"x","Test":"TestA"}
+{"Action":"run","Package":"x","Test":"TestB"}
+{"Action":"output","Package":"x","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Action":"run","Package":"x","Test":"TestB/sub"}
+{"Action":"output","Package":"x","Test":"TestB/sub","Output":"    x_test.go:9: got 1, want 2\n"}
+{"Action":"fail","Package":"x","Test":"TestB/sub"}
+{"Action":"fail","Package":"x","Test":"TestB"}
+{"Action":"skip","Package":"x","Test":"TestC"}
+{"Action":"fail","Package":"x"}
+{"Action":"build-output","ImportPath":"y [y.test]","Output":"y/y.go:3:1: syntax error\n"}
+{"Action":"fail","Package":"y"}`,
+			[]string{"1 passed, 2 failed, 1 skipped (go test)", "--- FAIL x TestB/sub\n    x_test.go:9: got 1, want 2", "--- FAIL y\ny/y.go:3:1: syntax error"},
+		},
+		{
+			"pytest",
+			`============================= FAILURES ==============================
+__________________________ TestMath.test_add __________________________
+    def test_add(self):
+>       assert add(1, 1) == 3
+E       assert 2 == 3
+====================== short test summary info ======================
+FAILED tests/test_math.py::TestMath::test_add - assert 2 == 3
+==================== 1 failed, 4 passed, 1 skipped in 0.12s ====================`,
+			[]string{"4 passed, 1 failed, 1 skipped (pytest)", "--- FAIL tests/test_math.py::TestMath::test_add\n    def test_add(self):\n>       assert add(1, 1) == 3"},
+		},
+		{
+			"cargo",
+			`running 3 tests
+test tests::ok ... ok
+test tests::bad ... FAILED
+
+failures:
+
+---- tests::bad stdout ----
+thread 'tests::bad' panicked at src/lib.rs:10:9:
+assertion failed
+
+failures:
+    tests::bad
+
+test result: FAILED. 2 passed; 1 failed; 0 ignored; 0 measured; 0 filtered out`,
+			[]string{"2 passed, 1 failed, 0 skipped (cargo test)", "--- FAIL tests::bad\nthread 'tests::bad' panicked at src/lib.rs:10:9:\nassertion failed"},
+		},
+		{
+			"jest",
+			`FAIL src/sum.test.js
+  ● sum › adds numbers
+
+    expect(received).toBe(expected)
+
+Test Suites: 1 failed, 1 total
+Tests:       1 failed, 3 passed, 4 total`,
+			[]string{"3 passed, 1 failed, 0 skipped (jest)", "--- FAIL sum › adds numbers\n    expect(received).toBe(expected)"},
+		},
+	}
+	for _, tt := range tests {
+		got := formatTestRun("cmd", "FAILED (exit status 1)", time.Second, tt.output)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: result lacks %q:\n%s", tt.name, want, got)
+			}
+		}
+	}
+
+	got := formatTestRun("make check", "FAILED (exit status 2)", time.Second, "make: *** No rule to make target 'check'.")
+	if !strings.Contains(got, "No test results recognised") || !strings.HasSuffix(got, "target 'check'.") {
+		t.Errorf("unrecognised output:\n%s", got)
+	}
+}
@@ -0,0 +1,319 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// PlansDir holds each session's plan, relative to the project root.
+var PlansDir = filepath.Join(".brutus", "plans")
+
+// SessionPlanPath is where a session's plan is kept.
+func SessionPlanPath(sessionID string) string {
+	return filepath.Join(PlansDir, sessionID+".json")
+}
+
+// Plan item statuses.
+const (
+	TodoPending    = "pending"
+	TodoInProgress = "in_progress"
+	TodoDone       = "done"
+)
+
+// TodoItem is one step of a plan.
+type TodoItem struct {
+	ID     int    `json:"id"`
+	Text   string `json:"text"`
+	Status string `json:"status"`
+}
+
+// PlanState is a plan's items and whether the user has approved it, as
+// saved and as shown to the GUI.
+type PlanState struct {
+	Items    []TodoItem `json:"items"`
+	Approved bool       `json:"approved"`
+}
+
+// Plan is the agent's task list for one session, saved after every change
+// so a resumed session picks it up again. In plan mode the user must
+// approve it before the agent may change anything; see RequireApproval.
+// A nil *Plan is empty. It is safe for concurrent use.
+type Plan struct {
+	mu       sync.Mutex
+	path     string
+	state    PlanState
+	ask      AskFunc
+	onChange func(PlanState)
+}
+
+// LoadPlan returns the plan saved at path, or an empty one that will be
+// saved there. A damaged file starts the plan over.
+func LoadPlan(path string) *Plan {
+	p := &Plan{path: path}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &p.state)
+	}
+	return p
+}
+
+// RequireApproval turns on plan mode: the plan must be submitted to the
+// user, through ask, and approved before Approved reports true.
+func (p *Plan) RequireApproval(ask AskFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ask = ask
+}
+
+// Gated reports whether the plan is in plan mode.
+func (p *Plan) Gated() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ask != nil
+}
+
+// Approved reports whether the user has approved the plan.
+func (p *Plan) Approved() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state.Approved
+}
+
+// OnChange calls fn with the new state after every change to the plan.
+func (p *Plan) OnChange(fn func(PlanState)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onChange = fn
+}
+
+// State returns a copy of the plan's items and approval.
+func (p *Plan) State() PlanState {
+	if p == nil {
+		return PlanState{Items: []TodoItem{}}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.copyState()
+}
+
+func (p *Plan) copyState() PlanState {
+	return PlanState{Items: append([]TodoItem{}, p.state.Items...), Approved: p.state.Approved}
+}
+
+// String renders the plan as a checklist, or "" if it has no items.
+func (p *Plan) String() string {
+	return formatPlan(p.State())
+}
+
+func formatPlan(state PlanState) string {
+	if len(state.Items) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, item := range state.Items {
+		mark := " "
+		switch item.Status {
+		case TodoDone:
+			mark = "x"
+		case TodoInProgress:
+			mark = "~"
+		}
+		fmt.Fprintf(&b, "[%s] %d. %s\n", mark, item.ID, item.Text)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// update applies change to the plan, saves it and reports the new state.
+// The plan is unchanged if change fails.
+func (p *Plan) update(change func(*PlanState) error) (PlanState, error) {
+	p.mu.Lock()
+	next := p.copyState()
+	if err := change(&next); err != nil {
+		p.mu.Unlock()
+		return PlanState{}, err
+	}
+	p.state = next
+	state := p.copyState()
+	onChange := p.onChange
+	err := p.save()
+	p.mu.Unlock()
+
+	if onChange != nil {
+		onChange(state)
+	}
+	return state, err
+}
+
+func (p *Plan) save() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0644)
+}
+
+// TodoInput defines the parameters for the todo tool.
+type TodoInput struct {
+	Op     string   `json:"op" jsonschema:"enum=add,enum=update,enum=check,enum=remove,enum=list,enum=submit" jsonschema_description:"add appends items; update changes an item's text or status; check marks an item done; remove drops one; list shows the plan; submit asks the user to approve it."`
+	Items  []string `json:"items,omitempty" jsonschema_description:"For add: the steps to append, in order."`
+	ID     int      `json:"id,omitempty" jsonschema_description:"For update, check and remove: the item's number."`
+	Text   string   `json:"text,omitempty" jsonschema_description:"For update: the item's new text."`
+	Status string   `json:"status,omitempty" jsonschema:"enum=pending,enum=in_progress,enum=done" jsonschema_description:"For update: the item's new status."`
+}
+
+// NewTodoTool returns the todo tool, which keeps plan.
+func NewTodoTool(plan *Plan) Tool {
+	return NewTool[TodoInput](
+		"todo",
+		`Keep a checklist of the steps of the current task: add steps, mark one in_progress as you start it and check it off when it is done, so you and the user can see what is left.
+Use it for any task of more than a couple of steps. When plan mode is on, write the plan first and submit it; tools that change anything are refused until the user approves it.`,
+		func(input json.RawMessage) (string, error) {
+			var args TodoInput
+			if err := decodeInput(input, &args); err != nil {
+				return "", err
+			}
+			var state PlanState
+			var err error
+			switch args.Op {
+			case "submit":
+				return plan.submit()
+			case "list":
+				state = plan.State()
+			default:
+				state, err = plan.update(func(s *PlanState) error {
+					return applyTodo(s, args)
+				})
+			}
+			if err != nil {
+				var toolErr *ToolError
+				if errors.As(err, &toolErr) {
+					return "", err
+				}
+				return "", WrapError(err, "failed to save the plan")
+			}
+			if len(state.Items) == 0 {
+				return "The plan is empty.", nil
+			}
+			return formatPlan(state), nil
+		},
+	)
+}
+
+// applyTodo makes the change args asks for to s.
+func applyTodo(s *PlanState, args TodoInput) error {
+	find := func() (*TodoItem, error) {
+		for i := range s.Items {
+			if s.Items[i].ID == args.ID {
+				return &s.Items[i], nil
+			}
+		}
+		return nil, NewError(ErrNotFound, "no item %d in the plan", args.ID)
+	}
+
+	switch args.Op {
+	case "add":
+		if len(args.Items) == 0 {
+			return NewError(ErrInvalidInput, "items is required for add")
+		}
+		next := 1
+		for _, item := range s.Items {
+			next = max(next, item.ID+1)
+		}
+		for _, text := range args.Items {
+			if text = strings.TrimSpace(text); text != "" {
+				s.Items = append(s.Items, TodoItem{ID: next, Text: text, Status: TodoPending})
+				next++
+			}
+		}
+	case "update":
+		item, err := find()
+		if err != nil {
+			return err
+		}
+		switch args.Status {
+		case "", TodoPending, TodoInProgress, TodoDone:
+		default:
+			return NewError(ErrInvalidInput, "status must be pending, in_progress or done, not %q", args.Status)
+		}
+		if text := strings.TrimSpace(args.Text); text != "" {
+			item.Text = text
+		}
+		if args.Status != "" {
+			item.Status = args.Status
+		}
+	case "check":
+		item, err := find()
+		if err != nil {
+			return err
+		}
+		item.Status = TodoDone
+	case "remove":
+		if _, err := find(); err != nil {
+			return err
+		}
+		kept := s.Items[:0]
+		for _, item := range s.Items {
+			if item.ID != args.ID {
+				kept = append(kept, item)
+			}
+		}
+		s.Items = kept
+	default:
+		return NewError(ErrInvalidInput, "unknown op %q; use add, update, check, remove, list or submit", args.Op)
+	}
+	return nil
+}
+
+// submit asks the user to approve the plan. Outside plan mode there is
+// nobody waiting on it.
+func (p *Plan) submit() (string, error) {
+	p.mu.Lock()
+	ask, state := p.ask, p.copyState()
+	p.mu.Unlock()
+
+	if len(state.Items) == 0 {
+		return "", NewError(ErrInvalidInput, "the plan is empty; add its steps before submitting it")
+	}
+	if ask == nil {
+		return "Plan mode is off, so the plan needs no approval; go ahead.", nil
+	}
+	if state.Approved {
+		return "The plan is already approved; go ahead.", nil
+	}
+
+	answer, ok, err := ask("Approve this plan?\n"+formatPlan(state), []string{"Approve", "Revise"})
+	if err != nil {
+		return "", WrapError(err, "failed to ask the user")
+	}
+	answer = strings.TrimSpace(resolveChoice(strings.TrimSpace(answer), []string{"Approve", "Revise"}))
+	switch strings.ToLower(answer) {
+	case "approve", "y", "yes":
+		if _, err := p.update(func(s *PlanState) error {
+			s.Approved = true
+			return nil
+		}); err != nil {
+			return "", WrapError(err, "failed to save the plan")
+		}
+		return "The user approved the plan. Work through it, checking off each step as it is done.", nil
+	}
+	if !ok || answer == "" || strings.EqualFold(answer, "revise") {
+		return "The user did not approve the plan. Ask what they would change, revise it and submit it again.", nil
+	}
+	return fmt.Sprintf("The user did not approve the plan and said: %s\nRevise it and submit it again.", answer), nil
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runTodo(t *testing.T, tool Tool, input string) string {
+	t.Helper()
+	out, err := tool.Function(json.RawMessage(input))
+	if err != nil {
+		t.Fatalf("todo %s: %v", input, err)
+	}
+	return out
+}
+
+func TestTodo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plan := LoadPlan(path)
+	tool := NewTodoTool(plan)
+
+	runTodo(t, tool, `{"op": "add", "items": ["Read the code", "Fix the bug", "Run the tests"]}`)
+	runTodo(t, tool, `{"op": "update", "id": 2, "status": "in_progress"}`)
+	runTodo(t, tool, `{"op": "check", "id": 1}`)
+	got := runTodo(t, tool, `{"op": "remove", "id": 3}`)
+	want := "[x] 1. Read the code\n[~] 2. Fix the bug"
+	if got != want {
+		t.Errorf("plan = %q, want %q", got, want)
+	}
+
+	if _, err := tool.Function(json.RawMessage(`{"op": "check", "id": 9}`)); CodeOf(err) != ErrNotFound {
+		t.Errorf("checking a missing item: %v", err)
+	}
+	if _, err := tool.Function(json.RawMessage(`{"op": "update", "id": 1, "status": "blocked"}`)); CodeOf(err) != ErrInvalidInput {
+		t.Errorf("unknown status: %v", err)
+	}
+
+	// The plan is saved for a resumed session.
+	if got := LoadPlan(path).String(); got != want {
+		t.Errorf("reloaded plan = %q, want %q", got, want)
+	}
+}
+
+func TestTodoSubmit(t *testing.T) {
+	plan := LoadPlan("")
+	tool := NewTodoTool(plan)
+	runTodo(t, tool, `{"op": "add", "items": ["Change it"]}`)
+
+	if got := runTodo(t, tool, `{"op": "submit"}`); !strings.Contains(got, "Plan mode is off") || plan.Approved() {
+		t.Errorf("submit outside plan mode: %q", got)
+	}
+
+	var answer string
+	var asked string
+	plan.RequireApproval(func(question string, choices []string) (string, bool, error) {
+		asked = question
+		return answer, true, nil
+	})
+	answer = "do the docs first"
+	if got := runTodo(t, tool, `{"op": "submit"}`); !strings.Contains(got, "do the docs first") || plan.Approved() {
+		t.Errorf("rejected plan: %q", got)
+	}
+	if !strings.Contains(asked, "[ ] 1. Change it") {
+		t.Errorf("question lacks the plan: %q", asked)
+	}
+	answer = "1"
+	runTodo(t, tool, `{"op": "submit"}`)
+	if !plan.Approved() {
+		t.Error("plan not approved")
+	}
+}
@@ -1,7 +1,12 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/invopop/jsonschema"
@@ -13,34 +18,132 @@ import (
 // To add a new tool:
 // 1. Create a new file (e.g., mytool.go)
 // 2. Define an input struct with json tags
-// 3. Create a function matching ToolFunc signature
-// 4. Create a Definition variable using NewTool()
+// 3. Create a function matching ToolFunc (ToolFuncCtx if it runs long)
+// 4. Create a Definition variable using NewTool() or NewToolCtx()
 // 5. Register it in the agent's tool list
 type Tool struct {
 	Name        string
 	Description string
 	InputSchema anthropic.ToolInputSchemaParam
-	Function    ToolFunc
+
+	// Function runs the tool without a context. Tools made by NewToolCtx
+	// have one that runs FunctionCtx with context.Background(), for
+	// callers that have no context to give.
+	Function ToolFunc
+
+	// FunctionCtx, if set, runs the tool with a context whose
+	// cancellation stops it. Call uses it in preference to Function.
+	FunctionCtx ToolFuncCtx
+
+	// Retry says how Execute retries failures. The zero value never does.
+	Retry RetryPolicy
+
+	// FromUser marks tools whose result is the user's own words, such as
+	// ask_user. Agents send those results on without the guard's wrapping,
+	// which would tell the model to treat them as untrusted data.
+	FromUser bool
+
+	// CacheKey, if set, lets a ResultCache reuse the tool's results. Only
+	// idempotent tools should have one.
+	CacheKey CacheKeyFunc
+
+	// Close, if set, releases state the tool keeps for its session, such
+	// as python_exec's interpreter. Tools with one are built per agent.
+	Close func() error
+
+	// SetEnv, if set, replaces the extra environment variables the
+	// commands the tool runs get; see Registry.SetEnv. Tools with one are
+	// built per agent.
+	SetEnv func(vars []string)
+
+	// policyApplied is set once Register has applied the tool's
+	// ToolPolicy, so a tool passed from one registry to another isn't
+	// limited twice.
+	policyApplied bool
+
+	// parameters is InputSchema rendered as a JSON Schema object, computed
+	// once so providers don't re-marshal it on every request.
+	parameters json.RawMessage
 }
 
 // ToolFunc is the signature for tool execution.
 // It receives JSON input and returns a string result or error.
 type ToolFunc func(input json.RawMessage) (string, error)
 
+// ToolFuncCtx is ToolFunc for tools that can be cancelled: when ctx is
+// done they stop, killing any command they started, and return.
+type ToolFuncCtx func(ctx context.Context, input json.RawMessage) (string, error)
+
 // NewTool creates a Tool definition with auto-generated JSON schema.
 // The generic type T should be your input struct.
 func NewTool[T any](name, description string, fn ToolFunc) Tool {
-	return Tool{
+	t := Tool{
 		Name:        name,
 		Description: description,
 		InputSchema: generateSchema[T](),
 		Function:    fn,
 	}
+	t.parameters = marshalParameters(t.InputSchema)
+	return t
+}
+
+// NewToolCtx is NewTool for a tool that takes a context.
+func NewToolCtx[T any](name, description string, fn ToolFuncCtx) Tool {
+	t := NewTool[T](name, description, func(input json.RawMessage) (string, error) {
+		return fn(context.Background(), input)
+	})
+	t.FunctionCtx = fn
+	return t
+}
+
+// Call runs the tool once with ctx, through FunctionCtx if it has one.
+// Tools with only a Function run to the end whatever ctx does.
+func (t Tool) Call(ctx context.Context, input json.RawMessage) (string, error) {
+	if t.FunctionCtx != nil {
+		return t.FunctionCtx(ctx, input)
+	}
+	return t.Function(input)
+}
+
+// Parameters returns the input schema as a JSON Schema object, the form
+// OpenAI-compatible APIs expect.
+func (t Tool) Parameters() json.RawMessage {
+	if t.parameters != nil {
+		return t.parameters
+	}
+	return marshalParameters(t.InputSchema)
+}
+
+func marshalParameters(schema anthropic.ToolInputSchemaParam) json.RawMessage {
+	params, _ := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": schema.Properties,
+	})
+	return params
+}
+
+// schemaCache holds generated schemas by input type, since reflection is
+// the expensive part and several tools may share an input struct.
+var schemaCache sync.Map // reflect.Type -> anthropic.ToolInputSchemaParam
+
+// CachedSchemas returns how many input schemas have been generated.
+func CachedSchemas() int {
+	n := 0
+	schemaCache.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
 }
 
 // generateSchema uses reflection to create a JSON schema from a struct.
 // This is how the LLM knows what parameters your tool accepts.
 func generateSchema[T any]() anthropic.ToolInputSchemaParam {
+	typ := reflect.TypeFor[T]()
+	if cached, ok := schemaCache.Load(typ); ok {
+		return cached.(anthropic.ToolInputSchemaParam)
+	}
+
 	reflector := jsonschema.Reflector{
 		AllowAdditionalProperties: false,
 		DoNotReference:            true,
@@ -48,9 +151,11 @@ func generateSchema[T any]() anthropic.ToolInputSchemaParam {
 	var v T
 	schema := reflector.Reflect(v)
 
-	return anthropic.ToolInputSchemaParam{
+	param := anthropic.ToolInputSchemaParam{
 		Properties: schema.Properties,
 	}
+	schemaCache.Store(typ, param)
+	return param
 }
 
 // ToAnthropic converts a Tool to the Anthropic SDK format.
@@ -65,36 +170,117 @@ func (t Tool) ToAnthropic() anthropic.ToolUnionParam {
 }
 
 // Registry holds all available tools.
-// Use this to organize tools and make them discoverable.
+// Use this to organize tools and make them discoverable. It is safe for
+// concurrent use, and All and Names return tools sorted by name so the
+// tool list sent to the LLM is the same on every request.
 type Registry struct {
+	mu    sync.RWMutex
 	tools map[string]Tool
+
+	// middleware wraps every tool's function; see Use.
+	middleware []ToolMiddleware
+
+	// sorted caches All's result until the next Register, Unregister or
+	// Use.
+	sorted []Tool
 }
 
 func NewRegistry() *Registry {
 	return &Registry{tools: make(map[string]Tool)}
 }
 
+// Register adds t, replacing any tool with the same name, with the policy
+// ConfigureTools set for it applied. A disabled tool is left out.
 func (r *Registry) Register(t Tool) {
+	if t.parameters == nil {
+		t.parameters = marshalParameters(t.InputSchema)
+	}
+	if !t.policyApplied {
+		policy := PolicyFor(t.Name)
+		if policy.Disabled {
+			return
+		}
+		t = policy.apply(t)
+		t.policyApplied = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.tools[t.Name] = t
+	r.sorted = nil
 }
 
+// Unregister removes the named tool and reports whether it was present.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.tools[name]
+	delete(r.tools, name)
+	r.sorted = nil
+	return ok
+}
+
+// Get returns the named tool with the registry's middleware around it.
+// Callers run tools only as Get and All return them, so no call bypasses
+// the middleware; WithShell redirects bash without going around it.
 func (r *Registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	t, ok := r.tools[name]
-	return t, ok
+	if !ok {
+		return t, false
+	}
+	return r.wrap(t), true
 }
 
+// All returns the registered tools sorted by name. The slice is shared
+// between calls (it is sent with every LLM request) and must not be
+// modified.
 func (r *Registry) All() []Tool {
+	r.mu.RLock()
+	sorted := r.sorted
+	r.mu.RUnlock()
+	if sorted != nil {
+		return sorted
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sorted != nil {
+		return r.sorted
+	}
 	result := make([]Tool, 0, len(r.tools))
 	for _, t := range r.tools {
-		result = append(result, t)
+		result = append(result, r.wrap(t))
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	r.sorted = result
 	return result
 }
 
+// Close closes every tool that keeps session state. The registry should
+// not be used afterwards.
+func (r *Registry) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var errs []error
+	for _, t := range r.tools {
+		if t.Close != nil {
+			errs = append(errs, t.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.tools))
 	for name := range r.tools {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
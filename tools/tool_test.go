@@ -0,0 +1,243 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func stubTool(name string) Tool {
+	return Tool{
+		Name:     name,
+		Function: func(json.RawMessage) (string, error) { return name, nil },
+	}
+}
+
+func TestRegistrySortedByName(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"read_file", "bash", "edit_file", "code_search"} {
+		r.Register(stubTool(name))
+	}
+
+	want := []string{"bash", "code_search", "edit_file", "read_file"}
+	if got := r.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	var got []string
+	for _, tool := range r.All() {
+		got = append(got, tool.Name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("All() order = %v, want %v", got, want)
+	}
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubTool("bash"))
+
+	if !r.Unregister("bash") {
+		t.Error("Unregister(bash) = false, want true")
+	}
+	if r.Unregister("bash") {
+		t.Error("second Unregister(bash) = true, want false")
+	}
+	if _, ok := r.Get("bash"); ok {
+		t.Error("bash still registered after Unregister")
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	r := NewRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				name := fmt.Sprintf("tool_%d_%d", i, j)
+				r.Register(stubTool(name))
+				r.All()
+				r.Get(name)
+				if j%2 == 0 {
+					r.Unregister(name)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(r.Names()); got != 8*50 {
+		t.Errorf("got %d tools, want %d", got, 8*50)
+	}
+}
+
+func TestRegistryAllCachedUntilChange(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubTool("b"))
+	r.Register(stubTool("a"))
+
+	first := r.All()
+	if &r.All()[0] != &first[0] {
+		t.Error("All() rebuilt the tool list without a change")
+	}
+
+	r.Register(stubTool("c"))
+	if got := len(r.All()); got != 3 {
+		t.Errorf("All() after Register has %d tools, want 3", got)
+	}
+	r.Unregister("a")
+	if got := r.All()[0].Name; got != "b" {
+		t.Errorf("All()[0] after Unregister = %s, want b", got)
+	}
+}
+
+func TestToolParameters(t *testing.T) {
+	params := ReadFileTool.Parameters()
+
+	var schema struct {
+		Type       string                     `json:"type"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(params, &schema); err != nil {
+		t.Fatalf("Parameters() is not valid JSON: %v", err)
+	}
+	if schema.Type != "object" || schema.Properties["path"] == nil {
+		t.Errorf("Parameters() = %s", params)
+	}
+
+	// Tools built without NewTool get their parameters on registration.
+	r := NewRegistry()
+	r.Register(Tool{Name: "raw", InputSchema: ReadFileTool.InputSchema})
+	raw, _ := r.Get("raw")
+	if string(raw.Parameters()) != string(params) {
+		t.Errorf("registered tool parameters = %s, want %s", raw.Parameters(), params)
+	}
+}
+
+func TestGenerateSchemaCached(t *testing.T) {
+	a := generateSchema[ReadFileInput]()
+	b := generateSchema[ReadFileInput]()
+	if a.Properties != b.Properties {
+		t.Error("generateSchema reflected the same type twice")
+	}
+}
+
+func TestRegistryMiddleware(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubTool("bash"))
+
+	var order []string
+	trace := func(label string) Middleware {
+		return func(next ToolFuncCtx) ToolFuncCtx {
+			return func(ctx context.Context, input json.RawMessage) (string, error) {
+				order = append(order, label)
+				return next(ctx, input)
+			}
+		}
+	}
+	r.Use(trace("outer"), trace("inner"))
+	r.UseFor(func(tool Tool) Middleware {
+		return func(next ToolFuncCtx) ToolFuncCtx {
+			return func(ctx context.Context, input json.RawMessage) (string, error) {
+				result, err := next(ctx, input)
+				return tool.Name + ": " + result, err
+			}
+		}
+	})
+	// Tools registered after Use are wrapped too.
+	r.Register(stubTool("read_file"))
+
+	for _, name := range []string{"bash", "read_file"} {
+		order = nil
+		tool, _ := r.Get(name)
+		result, err := tool.Function(nil)
+		if err != nil || result != name+": "+name {
+			t.Errorf("%s: got %q, %v", name, result, err)
+		}
+		if fmt.Sprint(order) != "[outer inner]" {
+			t.Errorf("%s: middleware ran in order %v", name, order)
+		}
+	}
+	if all := r.All(); len(all) != 2 {
+		t.Fatalf("All returned %d tools", len(all))
+	} else if result, _ := all[0].Function(nil); result != "bash: bash" {
+		t.Errorf("All's tools are not wrapped: %q", result)
+	}
+}
+
+func TestLimitResult(t *testing.T) {
+	long := func(context.Context, json.RawMessage) (string, error) { return strings.Repeat("x", 100), nil }
+	result, _ := LimitResult(20)(long)(context.Background(), nil)
+	if !strings.Contains(result, "characters omitted") || len(result) > 60 {
+		t.Errorf("got %q", result)
+	}
+}
+
+func TestToolCallContext(t *testing.T) {
+	type key struct{}
+	tool := NewToolCtx[BashInput]("ctx", "", func(ctx context.Context, _ json.RawMessage) (string, error) {
+		v, _ := ctx.Value(key{}).(string)
+		return v, nil
+	})
+	r := NewRegistry()
+	r.Use(LimitResult(100))
+	r.Register(tool)
+	wrapped, _ := r.Get("ctx")
+
+	ctx := context.WithValue(context.Background(), key{}, "seen")
+	if got, _ := wrapped.Call(ctx, nil); got != "seen" {
+		t.Errorf("Call did not pass the context through middleware: %q", got)
+	}
+	if got, _ := wrapped.Function(nil); got != "" {
+		t.Errorf("Function: got %q", got)
+	}
+	if got, _ := stubTool("plain").Call(ctx, nil); got != "plain" {
+		t.Errorf("Call on a tool without FunctionCtx: %q", got)
+	}
+}
+
+func TestBashCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := BashTool.Call(ctx, json.RawMessage(`{"command":"sleep 30 & sleep 30"}`))
+	if CodeOf(err) != ErrTimeout {
+		t.Errorf("got %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the command ran on for %v after its context ended", elapsed)
+	}
+}
+
+func TestRegistryMiddlewareWithShell(t *testing.T) {
+	learned, _ := LoadLearned(filepath.Join(t.TempDir(), "learned.json"))
+	r := NewRegistry()
+	r.Register(NewBashTool(learned))
+	var calls int
+	r.Use(func(next ToolFuncCtx) ToolFuncCtx {
+		return func(ctx context.Context, input json.RawMessage) (string, error) {
+			calls++
+			return next(ctx, input)
+		}
+	})
+
+	ctx := WithShell(context.Background(), func(ctx context.Context, command string, vars []string) (string, error) {
+		return "ran in the terminal", nil
+	})
+	tool, _ := r.Get("bash")
+	if got, _ := tool.Call(ctx, json.RawMessage(`{"command": "ls"}`)); got != "ran in the terminal" || calls != 1 {
+		t.Errorf("got %q after %d middleware calls", got, calls)
+	}
+}
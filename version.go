@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When unset they fall back to the VCS stamp the Go toolchain embeds.
+var (
+	commit    = ""
+	buildDate = ""
+)
+
+func versionString() string {
+	rev, date, dirty := commit, buildDate, false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if rev == "" {
+					rev = s.Value
+				}
+			case "vcs.time":
+				if date == "" {
+					date = s.Value
+				}
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+	}
+
+	if len(rev) > 12 {
+		rev = rev[:12]
+	}
+	if rev == "" {
+		rev = "unknown"
+	} else if dirty {
+		rev += "-dirty"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+
+	return fmt.Sprintf("BRUTUS v%s\ncommit: %s\nbuilt:  %s\ngo:     %s %s/%s",
+		Version, rev, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}